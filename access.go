@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// systemSchemas stay readable under allow lists, so catalog queries and
+// functions keep working. They can still be denied explicitly.
+var systemSchemas = map[string]bool{"pg_catalog": true, "information_schema": true}
+
+// accessListsConfigured reports whether any allow or deny list is set.
+func (s *serverState) accessListsConfigured() bool {
+	return len(s.config.AllowedSchemas) > 0 || len(s.config.DeniedSchemas) > 0 ||
+		len(s.config.AllowedTables) > 0 || len(s.config.DeniedTables) > 0
+}
+
+// matchesName reports whether a name matches one of the patterns, which may
+// use * and ? wildcards.
+func matchesName(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTable reports whether a relation matches one of the table patterns,
+// given as schema.table or as a bare table name matching in any schema.
+func matchesTable(patterns []string, schema, name string) bool {
+	for _, pattern := range patterns {
+		schemaPattern, tablePattern := "*", pattern
+		if i := strings.Index(pattern, "."); i >= 0 {
+			schemaPattern, tablePattern = pattern[:i], pattern[i+1:]
+		}
+		if matchesName([]string{schemaPattern}, schema) && matchesName([]string{tablePattern}, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaAllowed reports whether any relation of the schema can be accessible.
+func (s *serverState) schemaAllowed(schema string) bool {
+	if matchesName(s.config.DeniedSchemas, schema) {
+		return false
+	}
+	if systemSchemas[schema] {
+		return true
+	}
+	if len(s.config.AllowedSchemas) > 0 && !matchesName(s.config.AllowedSchemas, schema) {
+		return false
+	}
+	if len(s.config.AllowedTables) == 0 {
+		return true
+	}
+	for _, pattern := range s.config.AllowedTables {
+		if i := strings.Index(pattern, "."); i < 0 || matchesName([]string{pattern[:i]}, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationAllowed applies the allow and deny lists to a table, view,
+// sequence or other relation. Deny lists win over allow lists.
+func (s *serverState) relationAllowed(schema, name string) bool {
+	if !s.schemaAllowed(schema) || matchesTable(s.config.DeniedTables, schema, name) {
+		return false
+	}
+	if systemSchemas[schema] || len(s.config.AllowedTables) == 0 {
+		return true
+	}
+	return matchesTable(s.config.AllowedTables, schema, name)
+}
+
+// qualifiedAllowed is relationAllowed for "schema.table" names.
+func (s *serverState) qualifiedAllowed(name string) bool {
+	return s.relationAllowed(splitQualifiedName(name))
+}
+
+// returnNotAccessible answers requests for a schema or relation the lists
+// hide.
+func (s *serverState) returnNotAccessible(name string) (*mcp.CallToolResult, any, error) {
+	return s.returnErrorResult("%s is not accessible under the configured allow/deny lists", name)
+}
+
+// relationResolver is satisfied by the pool and by transactions, so names
+// are resolved with the search_path the statement will run with.
+type relationResolver interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// checkStatementAccess refuses statements that switch the session's role,
+// which would escape the role a call or ROLE runs as, COPY to or from the
+// server's files or programs, and statements referencing relations outside
+// the allow/deny lists. Referenced names are found with the SQL tokenizer
+// and resolved by the server, names that resolve to nothing (CTEs, typos)
+// are left to the statement itself. Views are checked by their own name,
+// not by the tables they read. With lists configured, functions that run
+// SQL given as text are refused, but functions the database defines can
+// still read hidden tables, so the lists are no security boundary.
+func (s *serverState) checkStatementAccess(ctx context.Context, resolver relationResolver, query string) error {
+	if changesRole(query) {
+		return errors.New(s.localize("Statements that change the role are refused, pass role to the tool instead"))
+	}
+	if copiesServerSide(query) {
+		return errors.New(s.localize("COPY to or from a server file or program is refused, use STDIN or STDOUT"))
+	}
+	if !s.accessListsConfigured() {
+		return nil
+	}
+	if calls := dynamicSQLCalls(query); len(calls) > 0 {
+		return fmt.Errorf(s.localize("The statement calls %s, which read relations the configured allow/deny lists cannot check"), strings.Join(calls, ", "))
+	}
+
+	references := statementRelations(query)
+	if utility := classifyUtility(query); utility != nil && len(utility.Target) > 0 {
+		references = append(references, utility.Target)
+	}
+	if len(references) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(references))
+	for _, name := range references {
+		// database.schema.table resolves like schema.table
+		if len(name) > 2 {
+			name = name[len(name)-2:]
+		}
+		names = append(names, pgx.Identifier(name).Sanitize())
+	}
+
+	rows, err := resolver.Query(ctx, `
+		SELECT DISTINCT n.nspname::text, c.relname::text
+		FROM unnest($1::text[]) AS r(name)
+		JOIN pg_class c ON c.oid = to_regclass(r.name)
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		ORDER BY 1, 2
+	`, names)
+	if err != nil {
+		return fmt.Errorf("failed to resolve referenced relations: %v", err)
+	}
+	defer rows.Close()
+
+	var denied []string
+	for rows.Next() {
+		var schema, name string
+		if err := rows.Scan(&schema, &name); err != nil {
+			return fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, name) {
+			denied = append(denied, schema+"."+name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if len(denied) > 0 {
+		return fmt.Errorf(s.localize("The statement references %s, which the configured allow/deny lists do not permit"), strings.Join(denied, ", "))
+	}
+	return nil
+}
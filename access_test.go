@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRelationAllowed(t *testing.T) {
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	testServer.config.AllowedSchemas = []string{"public", "team_*"}
+	testServer.config.DeniedSchemas = []string{"team_hr"}
+	testServer.config.AllowedTables = nil
+	testServer.config.DeniedTables = []string{"public.secrets", "*_audit"}
+
+	cases := []struct {
+		schema, name string
+		allowed      bool
+	}{
+		{"public", "users", true},
+		{"public", "secrets", false},
+		{"team_sales", "orders", true},
+		{"team_sales", "orders_audit", false},
+		{"team_hr", "salaries", false},
+		{"billing", "invoices", false},
+		{"pg_catalog", "pg_class", true},
+	}
+	for _, c := range cases {
+		if allowed := testServer.relationAllowed(c.schema, c.name); allowed != c.allowed {
+			t.Errorf("relationAllowed(%q, %q) = %t, expected %t", c.schema, c.name, allowed, c.allowed)
+		}
+	}
+
+	testServer.config.AllowedSchemas = nil
+	testServer.config.DeniedSchemas = nil
+	testServer.config.AllowedTables = []string{"users", "sales.*"}
+	testServer.config.DeniedTables = nil
+	if !testServer.relationAllowed("sales", "orders") || !testServer.relationAllowed("other", "users") || testServer.relationAllowed("public", "posts") {
+		t.Error("Expected ALLOWED_TABLES to allow only the listed tables")
+	}
+	if !testServer.schemaAllowed("public") || !testServer.schemaAllowed("sales") {
+		t.Error("Expected schemas holding allowed tables to stay visible")
+	}
+}
+
+func TestAccessLists(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.DeniedTables = []string{"public.posts"}
+
+	resultText := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+
+	t.Run("query refuses denied tables", func(t *testing.T) {
+		for _, query := range []string{
+			"SELECT * FROM posts",
+			"SELECT u.username FROM users u WHERE EXISTS (SELECT 1 FROM public.posts p WHERE p.user_id = u.id)",
+			`SELECT * FROM users, "posts"`,
+		} {
+			args := QueryArgs{Query: query}
+			result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+			if err != nil {
+				t.Fatalf("ExecuteQuery failed: %v", err)
+			}
+			if !result.IsError || !strings.Contains(resultText(result), "public.posts") {
+				t.Errorf("Expected %q to be refused, got %v", query, resultText(result))
+			}
+		}
+
+		args := QueryArgs{Query: "WITH posts AS (SELECT 1 AS id) SELECT count(*) FROM users"}
+		result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		if result.IsError {
+			t.Errorf("Expected a query on allowed tables to run, got %v", resultText(result))
+		}
+
+		args = QueryArgs{Query: "SELECT query_to_xml('select * from public.posts', true, false, '')"}
+		result, _, err = testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), "query_to_xml") {
+			t.Errorf("Expected SQL run through query_to_xml to be refused, got %v", resultText(result))
+		}
+	})
+
+	t.Run("explain refuses denied tables", func(t *testing.T) {
+		for _, query := range []string{"SELECT * FROM posts", "VACUUM posts"} {
+			args := ExplainAnalyzeArgs{Query: query}
+			result, _, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+			if err != nil {
+				t.Fatalf("ExplainAnalyze failed: %v", err)
+			}
+			if !result.IsError {
+				t.Errorf("Expected explaining %q to be refused", query)
+			}
+		}
+	})
+
+	t.Run("check expressions and probe queries refuse denied tables", func(t *testing.T) {
+		validateArgs := ValidateConstraintsArgs{TableName: "users", Type: "check", Check: "id IN (SELECT user_id FROM posts)"}
+		result, _, err := testServer.ValidateConstraints(ctx, createMockRequest(validateArgs), validateArgs)
+		if err != nil {
+			t.Fatalf("ValidateConstraints failed: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), "public.posts") {
+			t.Errorf("Expected the check reading posts to be refused, got %v", resultText(result))
+		}
+
+		testServer.config.AllowWrites = true
+		defer func() { testServer.config.AllowWrites = savedConfig.AllowWrites }()
+		analyzeArgs := RunAnalyzeArgs{Tables: []string{"users"}, ProbeQuery: "SELECT * FROM posts"}
+		result, _, err = testServer.RunAnalyze(ctx, createMockRequest(analyzeArgs), analyzeArgs)
+		if err != nil {
+			t.Fatalf("RunAnalyze failed: %v", err)
+		}
+		if !result.IsError || !strings.Contains(resultText(result), "public.posts") {
+			t.Errorf("Expected the probe query on posts to be refused, got %v", resultText(result))
+		}
+	})
+
+	t.Run("metadata tools skip denied tables", func(t *testing.T) {
+		args := TableListArgs{}
+		_, data, err := testServer.ListTables(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ListTables failed: %v", err)
+		}
+		for _, table := range data.([]map[string]interface{}) {
+			if table["table_name"] == "posts" {
+				t.Error("Expected posts to be left out of list_tables")
+			}
+		}
+
+		schemaArgs := TableSchemaArgs{TableName: "posts"}
+		result, _, err := testServer.GetTableSchema(ctx, createMockRequest(schemaArgs), schemaArgs)
+		if err != nil {
+			t.Fatalf("GetTableSchema failed: %v", err)
+		}
+		if !result.IsError {
+			t.Error("Expected get_table_schema to refuse posts")
+		}
+
+		edges, err := testServer.loadForeignKeys(ctx)
+		if err != nil {
+			t.Fatalf("loadForeignKeys failed: %v", err)
+		}
+		for _, edge := range edges {
+			if edge.FromTable == "public.posts" || edge.ToTable == "public.posts" {
+				t.Errorf("Expected join paths to avoid posts, got %s", edge.Name)
+			}
+		}
+	})
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxActivitySamples     = 100000
+	maxSampledQueryLength  = 2000
+	defaultActivitySamples = 20
+	activityTopEntries     = 10
+)
+
+type ActivityHistoryArgs struct {
+	At    string `json:"at,omitempty" jsonschema:"Show the sample closest to this time: RFC 3339, YYYY-MM-DD HH:MM[:SS] or HH:MM[:SS] (the most recent such time), in the server's time zone unless given"`
+	From  string `json:"from,omitempty" jsonschema:"Start of the range to summarize, in the same formats as at (default: the oldest sample kept)"`
+	To    string `json:"to,omitempty" jsonschema:"End of the range to summarize (default: now)"`
+	PID   int    `json:"pid,omitempty" jsonschema:"Only include this backend"`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum samples of the range to return in full, the most recent are kept (default: 20)"`
+}
+
+// activitySession is one non-idle backend as seen by a sample.
+type activitySession struct {
+	PID             int        `json:"pid"`
+	User            string     `json:"user,omitempty"`
+	Database        string     `json:"database,omitempty"`
+	ApplicationName string     `json:"application_name,omitempty"`
+	ClientAddr      string     `json:"client_addr,omitempty"`
+	BackendType     string     `json:"backend_type"`
+	State           string     `json:"state"`
+	WaitEventType   string     `json:"wait_event_type,omitempty"`
+	WaitEvent       string     `json:"wait_event,omitempty"`
+	QueryStart      *time.Time `json:"query_start,omitempty"`
+	XactStart       *time.Time `json:"xact_start,omitempty"`
+	Query           string     `json:"query"`
+	BlockedBy       []int      `json:"blocked_by,omitempty"`
+	WaitingLock     string     `json:"waiting_lock,omitempty"`
+}
+
+type activitySample struct {
+	Time     time.Time         `json:"time"`
+	Sessions []activitySession `json:"sessions"`
+}
+
+// activitySampleQuery snapshots every non-idle backend but our own, with the
+// backends blocking it and the first lock it is waiting for.
+var activitySampleQuery = fmt.Sprintf(`
+	SELECT
+		a.pid,
+		COALESCE(a.usename::text, ''),
+		COALESCE(a.datname::text, ''),
+		COALESCE(a.application_name, ''),
+		COALESCE(a.client_addr::text, ''),
+		COALESCE(a.backend_type, ''),
+		a.state,
+		COALESCE(a.wait_event_type, ''),
+		COALESCE(a.wait_event, ''),
+		a.query_start,
+		a.xact_start,
+		left(COALESCE(a.query, ''), %d),
+		pg_blocking_pids(a.pid),
+		COALESCE(l.mode || ' on ' || COALESCE(l.relation::regclass::text, l.locktype), '')
+	FROM pg_stat_activity a
+	LEFT JOIN LATERAL (
+		SELECT locktype, relation, mode FROM pg_locks
+		WHERE pid = a.pid AND NOT granted
+		LIMIT 1
+	) l ON true
+	WHERE a.state <> 'idle'
+		AND a.pid <> pg_backend_pid()
+	ORDER BY a.pid
+`, maxSampledQueryLength)
+
+// activitySampler records pg_stat_activity snapshots at a fixed interval into
+// a ring buffer, a lightweight active session history for the server's
+// lifetime. Nothing is written to the database.
+type activitySampler struct {
+	interval time.Duration
+	slow     *slowQueryLog
+
+	mu      sync.Mutex
+	samples []activitySample
+	next    int
+	full    bool
+	failed  int
+	lastErr error
+	stop    chan struct{}
+}
+
+// newActivitySampler keeps retention worth of samples taken every interval.
+func newActivitySampler(interval, retention time.Duration) *activitySampler {
+	capacity := 1
+	if retention > interval {
+		capacity = min(int(retention/interval), maxActivitySamples)
+	}
+	return &activitySampler{
+		interval: interval,
+		samples:  make([]activitySample, capacity),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (a *activitySampler) record(sample activitySample) {
+	if a.slow != nil {
+		a.slow.observe(sample)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.samples[a.next] = sample
+	a.next = (a.next + 1) % len(a.samples)
+	a.full = a.full || a.next == 0
+}
+
+// snapshot returns the samples kept, oldest first.
+func (a *activitySampler) snapshot() []activitySample {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.full {
+		return append([]activitySample(nil), a.samples[:a.next]...)
+	}
+	return append(append([]activitySample(nil), a.samples[a.next:]...), a.samples[:a.next]...)
+}
+
+// startActivitySampler samples until Close, skipping ticks while the
+// database is being reconnected to.
+func (s *serverState) startActivitySampler() {
+	sampler := s.activity
+	stop := sampler.stop
+	go func() {
+		ticker := time.NewTicker(sampler.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+			if s.reconnectingError() != nil {
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), min(sampler.interval, reconnectPingTimeout))
+			sample, err := s.sampleActivity(ctx)
+			cancel()
+			if err != nil {
+				sampler.mu.Lock()
+				if sampler.failed == 0 {
+					log.Printf("Activity sampling failed: %v", err)
+				}
+				sampler.failed++
+				sampler.lastErr = err
+				sampler.mu.Unlock()
+				continue
+			}
+			sampler.record(sample)
+		}
+	}()
+}
+
+// stopSampling ends the sampler, when the pool is closed.
+func (a *activitySampler) stopSampling() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stop != nil {
+		close(a.stop)
+		a.stop = nil
+	}
+}
+
+func (s *serverState) sampleActivity(ctx context.Context) (activitySample, error) {
+	sample := activitySample{Time: time.Now()}
+	rows, err := s.pool.Query(ctx, activitySampleQuery)
+	if err != nil {
+		return sample, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session activitySession
+		var blockedBy []int32
+		if err := rows.Scan(&session.PID, &session.User, &session.Database, &session.ApplicationName, &session.ClientAddr,
+			&session.BackendType, &session.State, &session.WaitEventType, &session.WaitEvent, &session.QueryStart,
+			&session.XactStart, &session.Query, &blockedBy, &session.WaitingLock); err != nil {
+			return sample, err
+		}
+		for _, pid := range blockedBy {
+			session.BlockedBy = append(session.BlockedBy, int(pid))
+		}
+		sample.Sessions = append(sample.Sessions, session)
+	}
+	return sample, rows.Err()
+}
+
+var sampleTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04"}
+
+// parseSampleTime reads a point in time in the local zone unless it names
+// one. A bare time of day is the most recent such time before now.
+func parseSampleTime(value string, now time.Time) (time.Time, error) {
+	for _, layout := range sampleTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err := time.Parse(layout, value); err == nil {
+			t := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+			if t.After(now) {
+				t = t.AddDate(0, 0, -1)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339, YYYY-MM-DD HH:MM[:SS] or HH:MM[:SS]", value)
+}
+
+// filterSample keeps the sessions of one backend, when pid is set.
+func filterSample(sample activitySample, pid int) activitySample {
+	if pid == 0 {
+		return sample
+	}
+	filtered := activitySample{Time: sample.Time}
+	for _, session := range sample.Sessions {
+		if session.PID == pid {
+			filtered.Sessions = append(filtered.Sessions, session)
+		}
+	}
+	return filtered
+}
+
+// summarizeActivity aggregates samples the way active session history is
+// read: average active sessions, and which waits and queries they were
+// spent on, by number of samples seen in.
+func summarizeActivity(samples []activitySample) map[string]interface{} {
+	waits := make(map[string]int)
+	queries := make(map[string]int)
+	sessions, blocked := 0, 0
+	for _, sample := range samples {
+		for _, session := range sample.Sessions {
+			sessions++
+			wait := "CPU"
+			if session.WaitEventType != "" {
+				wait = session.WaitEventType + ":" + session.WaitEvent
+			}
+			waits[wait]++
+			queries[session.Query]++
+			if len(session.BlockedBy) > 0 {
+				blocked++
+			}
+		}
+	}
+
+	top := func(counts map[string]int, label string) []map[string]interface{} {
+		keys := sortedKeys(counts)
+		sort.SliceStable(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+		var entries []map[string]interface{}
+		for _, key := range keys[:min(len(keys), activityTopEntries)] {
+			entries = append(entries, map[string]interface{}{label: key, "samples": counts[key]})
+		}
+		return entries
+	}
+
+	summary := map[string]interface{}{
+		"samples":          len(samples),
+		"session_samples":  sessions,
+		"blocked_samples":  blocked,
+		"top_wait_events":  top(waits, "wait_event"),
+		"top_queries":      top(queries, "query"),
+		"average_sessions": 0.0,
+	}
+	if len(samples) > 0 {
+		summary["average_sessions"] = float64(sessions) / float64(len(samples))
+	}
+	return summary
+}
+
+func (s *serverState) GetActivityHistory(ctx context.Context, req *mcp.CallToolRequest, args ActivityHistoryArgs) (*mcp.CallToolResult, any, error) {
+	if s.activity == nil {
+		return s.returnErrorResult("Activity sampling is off. Set ACTIVITY_SAMPLE_INTERVAL (e.g. 10s) to record pg_stat_activity snapshots")
+	}
+
+	samples := s.activity.snapshot()
+	if len(samples) == 0 {
+		return s.returnErrorResult("No activity has been sampled yet, the first sample is taken %s after startup", s.activity.interval)
+	}
+	s.activity.mu.Lock()
+	failed, lastErr := s.activity.failed, s.activity.lastErr
+	s.activity.mu.Unlock()
+
+	response := map[string]interface{}{
+		"interval": s.activity.interval.String(),
+		"kept":     len(samples),
+		"oldest":   samples[0].Time,
+		"newest":   samples[len(samples)-1].Time,
+	}
+	if failed > 0 {
+		response["failed_samples"] = failed
+		response["last_error"] = lastErr.Error()
+	}
+
+	now := time.Now()
+	if args.At != "" {
+		at, err := parseSampleTime(args.At, now)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		closest := samples[0]
+		for _, sample := range samples[1:] {
+			if sample.Time.Sub(at).Abs() < closest.Time.Sub(at).Abs() {
+				closest = sample
+			}
+		}
+		response["sample"] = filterSample(closest, args.PID)
+		response["offset"] = closest.Time.Sub(at).Round(time.Millisecond).String()
+
+		var warnings []string
+		if offset := closest.Time.Sub(at).Abs(); offset > 2*s.activity.interval {
+			warnings = append(warnings, fmt.Sprintf(s.localize("No sample was taken near %s, the closest is %s away"), at.Format(time.RFC3339), offset.Round(time.Second)))
+		}
+		result, data, err := returnJSONResult(response)
+		return s.withWarnings(result, warnings), data, err
+	}
+
+	from, to := samples[0].Time, now
+	var err error
+	if args.From != "" {
+		if from, err = parseSampleTime(args.From, now); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+	if args.To != "" {
+		if to, err = parseSampleTime(args.To, now); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+	var inRange []activitySample
+	for _, sample := range samples {
+		if !sample.Time.Before(from) && !sample.Time.After(to) {
+			inRange = append(inRange, filterSample(sample, args.PID))
+		}
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultActivitySamples
+	}
+	response["summary"] = summarizeActivity(inRange)
+	response["truncated"] = len(inRange) > limit
+	response["samples"] = inRange[max(0, len(inRange)-limit):]
+	return returnJSONResult(response)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestActivitySampler(t *testing.T) {
+	sampler := newActivitySampler(time.Second, 3*time.Second)
+	start := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		sampler.record(activitySample{Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	samples := sampler.snapshot()
+	if len(samples) != 3 {
+		t.Fatalf("Expected the ring buffer to keep 3 samples, got %d", len(samples))
+	}
+	for i, sample := range samples {
+		if expected := start.Add(time.Duration(i+2) * time.Second); !sample.Time.Equal(expected) {
+			t.Errorf("Expected sample %d at %s, got %s", i, expected, sample.Time)
+		}
+	}
+}
+
+func TestParseSampleTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2024-05-01T13:32:00+02:00": time.Date(2024, 5, 1, 11, 32, 0, 0, time.UTC),
+		"2024-05-01 13:32":          time.Date(2024, 5, 1, 13, 32, 0, 0, time.UTC),
+		"13:32":                     time.Date(2024, 5, 1, 13, 32, 0, 0, time.UTC),
+		"14:32:10":                  time.Date(2024, 4, 30, 14, 32, 10, 0, time.UTC),
+	}
+	for value, expected := range tests {
+		parsed, err := parseSampleTime(value, now)
+		if err != nil || !parsed.Equal(expected) {
+			t.Errorf("parseSampleTime(%q) = %s, %v, expected %s", value, parsed, err, expected)
+		}
+	}
+	if _, err := parseSampleTime("yesterday", now); err == nil {
+		t.Error("Expected an invalid time to be rejected")
+	}
+}
+
+func TestSummarizeActivity(t *testing.T) {
+	samples := []activitySample{
+		{Sessions: []activitySession{
+			{PID: 1, Query: "SELECT 1"},
+			{PID: 2, Query: "UPDATE t SET x = 1", WaitEventType: "Lock", WaitEvent: "transactionid", BlockedBy: []int{3}},
+		}},
+		{Sessions: []activitySession{
+			{PID: 2, Query: "UPDATE t SET x = 1", WaitEventType: "Lock", WaitEvent: "transactionid", BlockedBy: []int{3}},
+		}},
+	}
+
+	summary := summarizeActivity(samples)
+	if summary["average_sessions"] != 1.5 || summary["blocked_samples"] != 2 {
+		t.Errorf("Unexpected summary %v", summary)
+	}
+	waits := summary["top_wait_events"].([]map[string]interface{})
+	if waits[0]["wait_event"] != "Lock:transactionid" || waits[0]["samples"] != 2 {
+		t.Errorf("Expected the lock wait first, got %v", waits)
+	}
+}
+
+func TestGetActivityHistory(t *testing.T) {
+	ctx := context.Background()
+	sample, err := testServer.sampleActivity(ctx)
+	if err != nil {
+		t.Fatalf("sampleActivity failed: %v", err)
+	}
+
+	savedActivity := testServer.activity
+	defer func() { testServer.activity = savedActivity }()
+	testServer.activity = newActivitySampler(time.Second, time.Minute)
+	testServer.activity.record(sample)
+
+	args := ActivityHistoryArgs{At: sample.Time.Format(time.RFC3339)}
+	result, data, err := testServer.GetActivityHistory(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("GetActivityHistory failed: %v %v", err, result)
+	}
+	if found := data.(map[string]interface{})["sample"].(activitySample); !found.Time.Equal(sample.Time) {
+		t.Errorf("Expected the recorded sample, got %v", found)
+	}
+
+	testServer.activity = nil
+	result, _, _ = testServer.GetActivityHistory(ctx, createMockRequest(args), args)
+	if !result.IsError {
+		t.Error("Expected an error with sampling off")
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	sampler := newActivitySampler(time.Second, time.Minute)
+	sampler.slow = newSlowQueryLog(2 * time.Second)
+	start := time.Date(2024, 5, 1, 14, 30, 0, 0, time.UTC)
+	for i := 1; i <= 4; i++ {
+		sampler.record(activitySample{Time: start.Add(time.Duration(i) * time.Second), Sessions: []activitySession{
+			{PID: 1, State: "active", QueryStart: &start, Query: "SELECT pg_sleep(10)", WaitEventType: "Timeout", WaitEvent: "PgSleep"},
+			{PID: 2, State: "idle in transaction", QueryStart: &start, Query: "SELECT 1"},
+		}})
+	}
+
+	queries := sampler.slow.snapshot()
+	if len(queries) != 1 {
+		t.Fatalf("Expected one slow execution, got %v", queries)
+	}
+	query := queries[0]
+	if query.PID != 1 || query.Samples != 3 || query.DurationMs != 4000 || !query.FirstSeen.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Unexpected slow query %+v", query)
+	}
+	if query.WaitEvents["Timeout:PgSleep"] != 3 {
+		t.Errorf("Expected the wait event of every sample, got %v", query.WaitEvents)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// autoAnalyze refreshes planner statistics for the tables a committed write
+// modified, once it affected at least AUTO_ANALYZE_ROWS rows. Without it the
+// next queries plan against row estimates from before the bulk change.
+func (s *serverState) autoAnalyze(ctx context.Context, statement string, rowsAffected int64) ([]map[string]interface{}, error) {
+	threshold := s.config.AutoAnalyzeRows
+	// dry-run writes were rolled back, so there is nothing new to analyze
+	if threshold <= 0 || rowsAffected < threshold || s.config.DryRun {
+		return nil, nil
+	}
+
+	var analyzed []map[string]interface{}
+	seen := make(map[string]bool)
+	for _, target := range statementTargets(statement) {
+		name := pgx.Identifier(target.Name).Sanitize()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var qualified string
+		var before float64
+		err := s.pool.QueryRow(ctx, `
+			SELECT c.oid::regclass::text, c.reltuples::float8
+			FROM pg_class c
+			WHERE c.oid = to_regclass($1)
+		`, name).Scan(&qualified, &before)
+		if err == pgx.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return analyzed, fmt.Errorf("failed to look up %s: %v", strings.Join(target.Name, "."), err)
+		}
+
+		if _, err := s.pool.Exec(ctx, "ANALYZE "+name); err != nil {
+			return analyzed, fmt.Errorf("failed to analyze %s: %v", qualified, err)
+		}
+
+		var after float64
+		if err := s.pool.QueryRow(ctx, "SELECT reltuples::float8 FROM pg_class WHERE oid = to_regclass($1)", name).Scan(&after); err != nil {
+			return analyzed, fmt.Errorf("failed to read statistics for %s: %v", qualified, err)
+		}
+		analyzed = append(analyzed, map[string]interface{}{
+			"table":            qualified,
+			"reltuples_before": before,
+			"reltuples_after":  after,
+		})
+	}
+	return analyzed, nil
+}
+
+type RunAnalyzeArgs struct {
+	Tables     []string `json:"tables" jsonschema:"Tables to analyze, either bare names (in schema) or schema.table"`
+	Schema     string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	ProbeQuery string   `json:"probe_query,omitempty" jsonschema:"A query whose estimated plan is compared before and after, to see what the new statistics change"`
+}
+
+// analyzeState is what a table's statistics looked like at one moment.
+type analyzeState struct {
+	Reltuples   float64
+	LastAnalyze *time.Time
+}
+
+// RunAnalyze refreshes the planner statistics of tables, the usual fix for
+// a plan built on stale row estimates, and shows what changed: the row
+// estimates and, for a probe query, the estimated plan.
+func (s *serverState) RunAnalyze(ctx context.Context, req *mcp.CallToolRequest, args RunAnalyzeArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("run_analyze")
+	}
+	if len(args.Tables) == 0 {
+		return s.returnErrorResult("tables are required")
+	}
+
+	schema := getSchema(args.Schema)
+	var tables []string
+	for _, table := range args.Tables {
+		if !strings.Contains(table, ".") {
+			table = pgx.Identifier{schema}.Sanitize() + "." + table
+		}
+		tableSchema, tableName, err := s.resolveTableName(ctx, "", table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(tableSchema, tableName) {
+			return s.returnNotAccessible(qualifiedName(tableSchema, tableName))
+		}
+		if name := qualifiedName(tableSchema, tableName); !slices.Contains(tables, name) {
+			tables = append(tables, name)
+		}
+	}
+	statement := "ANALYZE " + strings.Join(tables, ", ")
+
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "run_analyze", "Analyze "+strings.Join(tables, ", "), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	// statistics are written in place, a rollback wouldn't undo them
+	if s.config.DryRun {
+		return returnJSONResult(s.labelDryRun(map[string]interface{}{"statement": statement}))
+	}
+
+	before, err := s.analyzeStates(ctx, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	var planBefore planSummary
+	if args.ProbeQuery != "" {
+		if planBefore, err = s.probePlan(ctx, req, args.ProbeQuery); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+	started := time.Now()
+	if _, err := tx.Exec(ctx, statement); err != nil {
+		return s.returnErrorResult("Analyze error: %v", err)
+	}
+	if err := s.finishWrite(ctx, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	elapsed := time.Since(started)
+
+	after, err := s.analyzeStates(ctx, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	analyzed := make([]map[string]interface{}, len(tables))
+	for i, table := range tables {
+		analyzed[i] = map[string]interface{}{
+			"table":               table,
+			"reltuples_before":    before[i].Reltuples,
+			"reltuples_after":     after[i].Reltuples,
+			"last_analyze_before": before[i].LastAnalyze,
+			"last_analyze_after":  after[i].LastAnalyze,
+		}
+	}
+	response := map[string]interface{}{
+		"statement":   statement,
+		"duration_ms": elapsed.Milliseconds(),
+		"tables":      analyzed,
+	}
+
+	if args.ProbeQuery != "" {
+		planAfter, err := s.probePlan(ctx, req, args.ProbeQuery)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		status, differences := comparePlans(planBefore, planAfter, defaultCostIncreaseRatio)
+		probe := map[string]interface{}{
+			"query":       args.ProbeQuery,
+			"before":      planBefore,
+			"after":       planAfter,
+			"plan_change": status != "ok",
+		}
+		if len(differences) > 0 {
+			probe["differences"] = differences
+		}
+		response["probe"] = probe
+	}
+
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, notices), data, err
+}
+
+// analyzeStates reads the row estimates and last analyze times of tables,
+// counting manual and automatic analyzes alike.
+func (s *serverState) analyzeStates(ctx context.Context, tables []string) ([]analyzeState, error) {
+	states := make([]analyzeState, len(tables))
+	for i, table := range tables {
+		err := s.pool.QueryRow(ctx, `
+			SELECT c.reltuples::float8, GREATEST(st.last_analyze, st.last_autoanalyze)
+			FROM pg_class c
+			LEFT JOIN pg_stat_all_tables st ON st.relid = c.oid
+			WHERE c.oid = $1::text::regclass
+		`, table).Scan(&states[i].Reltuples, &states[i].LastAnalyze)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read statistics for %s: %v", table, err)
+		}
+	}
+	return states, nil
+}
+
+// probePlan is the estimated plan of a probe query, explained in its own
+// read-only session so it sees the statistics committed so far. It is held
+// to the allow/deny lists like explain is.
+func (s *serverState) probePlan(ctx context.Context, req *mcp.CallToolRequest, query string) (planSummary, error) {
+	tx, _, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return planSummary{}, err
+	}
+	defer tx.Rollback(ctx)
+	if err := s.checkStatementAccess(ctx, tx, query); err != nil {
+		return planSummary{}, err
+	}
+	plan, err := s.explainInSavepoint(ctx, tx, query)
+	if err != nil {
+		return planSummary{}, err
+	}
+	return summarizePlan(plan), nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAutoAnalyze(t *testing.T) {
+	ctx := context.Background()
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.AutoAnalyzeRows = 100
+
+	if _, err := testServer.pool.Exec(ctx, "CREATE TABLE auto_analyze_items (id int)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE auto_analyze_items")
+
+	statement := "INSERT INTO auto_analyze_items SELECT generate_series(1, 500)"
+	tag, err := testServer.pool.Exec(ctx, statement)
+	if err != nil {
+		t.Fatalf("Failed to insert rows: %v", err)
+	}
+
+	t.Run("below threshold", func(t *testing.T) {
+		analyzed, err := testServer.autoAnalyze(ctx, statement, 99)
+		if err != nil || analyzed != nil {
+			t.Errorf("Expected no ANALYZE below the threshold, got %v, %v", analyzed, err)
+		}
+	})
+
+	t.Run("above threshold", func(t *testing.T) {
+		analyzed, err := testServer.autoAnalyze(ctx, statement, tag.RowsAffected())
+		if err != nil {
+			t.Fatalf("autoAnalyze failed: %v", err)
+		}
+
+		if len(analyzed) != 1 || analyzed[0]["table"] != "auto_analyze_items" {
+			t.Fatalf("Expected auto_analyze_items to be analyzed, got %v", analyzed)
+		}
+		if reltuples := analyzed[0]["reltuples_after"].(float64); reltuples != 500 {
+			t.Errorf("Expected reltuples 500 after ANALYZE, got %v", reltuples)
+		}
+	})
+}
+
+func TestRunAnalyze(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE analyzed_items (id int, kind int);
+		CREATE INDEX analyzed_items_kind ON analyzed_items (kind);
+		ANALYZE analyzed_items;
+		INSERT INTO analyzed_items SELECT i, i % 1000 FROM generate_series(1, 20000) i;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE analyzed_items")
+
+	args := RunAnalyzeArgs{Tables: []string{"analyzed_items"}, ProbeQuery: "SELECT * FROM analyzed_items WHERE kind = 7"}
+	if result, _, err := testServer.RunAnalyze(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+		t.Error("Expected run_analyze to be refused without ALLOW_WRITES")
+	}
+
+	testServer.config.AllowWrites = true
+	result, data, err := testServer.RunAnalyze(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RunAnalyze failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	table := response["tables"].([]map[string]interface{})[0]
+	if table["reltuples_after"] != float64(20000) || table["last_analyze_after"] == nil {
+		t.Errorf("Expected 20000 rows estimated after ANALYZE, got %v", table)
+	}
+	probe := response["probe"].(map[string]interface{})
+	if before, after := probe["before"].(planSummary), probe["after"].(planSummary); before.PlanRows == after.PlanRows {
+		t.Errorf("Expected the probe's row estimate to change, got %v both times", after.PlanRows)
+	}
+}
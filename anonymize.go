@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type AnonymizeTableArgs struct {
+	TableName   string   `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema      string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Columns     []string `json:"columns,omitempty" jsonschema:"Text columns to anonymize (default: the columns COLUMN_POLICY_FILE marks as masked or free text)"`
+	TargetTable string   `json:"target_table,omitempty" jsonschema:"Write the anonymized rows to this new table, created like the original, instead of rewriting the table in place"`
+	Commit      bool     `json:"commit,omitempty" jsonschema:"Run and commit the rewrite. Without it nothing is written and only the statement and the number of rows it would rewrite are reported (default: false)"`
+}
+
+// AnonymizeTable rewrites sensitive text columns with the deterministic
+// fakes export_fixture uses, in place or into a copy, so a production copy
+// can be handed to development. Equal values get equal fakes, in every
+// table, so values that matched across tables still match. Key columns are
+// never rewritten, which keeps references intact.
+func (s *serverState) AnonymizeTable(ctx context.Context, req *mcp.CallToolRequest, args AnonymizeTableArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if args.Commit && !s.writesEnabled() {
+		return s.returnWritesDisabled("anonymize_table")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	def, err := s.loadTableDef(ctx, schema+"."+tableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	keys, err := s.keyColumns(ctx, def)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up key columns: %v", err)
+	}
+
+	columns := make(map[string]columnDef)
+	for _, column := range def.Columns {
+		columns[column.Name] = column
+	}
+	names := args.Columns
+	if len(names) == 0 {
+		for _, column := range def.Columns {
+			if policy, ok := s.config.ColumnPolicies.lookup(schema, tableName, column.Name); ok && policy.sensitive() && column.TypeCategory == "S" {
+				names = append(names, column.Name)
+			}
+		}
+		if len(names) == 0 {
+			return s.returnErrorResult("COLUMN_POLICY_FILE marks no text column of %s as sensitive, list the columns to anonymize", def.Name)
+		}
+	}
+	fakes := make(map[string]string)
+	for _, name := range names {
+		column, ok := columns[name]
+		switch {
+		case !ok:
+			return s.returnErrorResult("column %q does not exist", name)
+		case column.TypeCategory != "S":
+			return s.returnErrorResult("%s is of type %s, only text columns can be anonymized", name, column.Type)
+		case column.Generated != "":
+			return s.returnErrorResult("%s is a generated column, anonymize the columns it is computed from", name)
+		case keys[name]:
+			return s.returnErrorResult("%s is part of a primary or foreign key, rewriting it would break references", name)
+		}
+		fakes[name] = anonymizeExpression(s.config.AnonymizeKey, column)
+	}
+	if colliding := collidingFakes(def, names); len(colliding) > 0 {
+		return s.returnErrorResult("%s are unique but too short for distinct fakes", strings.Join(colliding, ", "))
+	}
+
+	table := qualifiedName(schema, tableName)
+	var statement string
+	if args.TargetTable == "" {
+		assignments := make([]string, 0, len(fakes))
+		for _, name := range sortedKeys(fakes) {
+			assignments = append(assignments, quoteIdentifier(name)+" = "+fakes[name])
+		}
+		statement = fmt.Sprintf("UPDATE %s SET %s", table, strings.Join(assignments, ", "))
+	} else {
+		targetSchema, targetName, quoted := parseQualifiedName(args.TargetTable)
+		if !quoted {
+			// a new table's unquoted name folds to lower case, as in SQL
+			targetSchema, targetName = strings.ToLower(targetSchema), strings.ToLower(targetName)
+		}
+		if targetName == "" {
+			return s.returnErrorResult("target_table must name a table")
+		}
+		if targetSchema == "" {
+			targetSchema = schema
+		}
+		if !s.relationAllowed(targetSchema, targetName) {
+			return s.returnNotAccessible(qualifiedName(targetSchema, targetName))
+		}
+		target := qualifiedName(targetSchema, targetName)
+
+		var insertColumns, selects []string
+		for _, column := range def.insertableColumns() {
+			insertColumns = append(insertColumns, quoteIdentifier(column.Name))
+			if fake, ok := fakes[column.Name]; ok {
+				selects = append(selects, fake)
+			} else {
+				selects = append(selects, quoteIdentifier(column.Name))
+			}
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (%s)", target, strings.Join(insertColumns, ", "))
+		if def.hasIdentity() {
+			insert += " OVERRIDING SYSTEM VALUE"
+		}
+		statement = fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING ALL); %s SELECT %s FROM %s",
+			target, table, insert, strings.Join(selects, ", "), table)
+	}
+
+	if !args.Commit {
+		// the copy's CREATE TABLE and INSERT can't be explained together
+		return s.previewRowWrite(ctx, req, statement, "SELECT count(*) FROM "+table, args.TargetTable == "")
+	}
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "anonymize_table", fmt.Sprintf("Anonymize %s of %s", strings.Join(names, ", "), table), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	return s.runRowWrite(ctx, req, statement, -1)
+}
+
+// keyColumns returns the columns of a table in its primary key, in its
+// foreign keys or referenced by other tables' foreign keys.
+func (s *serverState) keyColumns(ctx context.Context, def *tableDef) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	for _, constraint := range def.Constraints {
+		if constraint.Type == "p" || constraint.Type == "f" {
+			for _, column := range constraint.Columns {
+				keys[column] = true
+			}
+		}
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT a.attname::text
+		FROM pg_constraint con
+		JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = ANY(con.confkey)
+		WHERE con.contype = 'f' AND con.confrelid = $1::text::regclass
+	`, sanitizeQualifiedName(def.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		keys[column] = true
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAnonymizeExpression(t *testing.T) {
+	ctx := context.Background()
+	columns := []columnDef{
+		{Name: "email", Type: "character varying(100)"},
+		{Name: "bio", Type: "text"},
+		{Name: "code", Type: "character varying(8)"},
+	}
+	// keys longer than the hash's block are hashed first
+	for _, key := range [][]byte{[]byte("secret"), []byte(strings.Repeat("long secret ", 10))} {
+		for _, column := range columns {
+			for _, value := range []string{"alice@example.org", "Ünïcode text", ""} {
+				var fake string
+				query := fmt.Sprintf("SELECT %s FROM (SELECT $1::text AS %s) t", anonymizeExpression(key, column), quoteIdentifier(column.Name))
+				if err := testServer.pool.QueryRow(ctx, query, value).Scan(&fake); err != nil {
+					t.Fatalf("Failed to anonymize %s: %v", column.Name, err)
+				}
+				if expected := anonymizeValue(key, column, value); fake != expected {
+					t.Errorf("Expected %s of %q to become %q as in fixtures, got %q", column.Name, value, expected, fake)
+				}
+			}
+		}
+	}
+}
+
+func TestAnonymizeTable(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	run := func(args AnonymizeTableArgs) (bool, map[string]interface{}) {
+		t.Helper()
+		result, data, err := testServer.AnonymizeTable(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("AnonymizeTable failed: %v", err)
+		}
+		response, _ := data.(map[string]interface{})
+		return result.IsError, response
+	}
+
+	if failed, _ := run(AnonymizeTableArgs{TableName: "users", Columns: []string{"id"}}); !failed {
+		t.Error("Expected a key column to be refused")
+	}
+	if failed, _ := run(AnonymizeTableArgs{TableName: "users"}); !failed {
+		t.Error("Expected an error without columns or column policies")
+	}
+
+	var users int64
+	if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&users); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	failed, response := run(AnonymizeTableArgs{TableName: "users", Columns: []string{"email"}})
+	if failed || response["matching_rows"] != users || response["committed"] != false {
+		t.Errorf("Expected a preview of every user's rewrite, got %v", response)
+	}
+	var rewritten int
+	if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM users WHERE email LIKE 'user\\_%@example.com'").Scan(&rewritten); err != nil {
+		t.Fatalf("Failed to check emails: %v", err)
+	}
+	if rewritten != 0 {
+		t.Error("Expected the emails to be untouched without commit")
+	}
+
+	testServer.config.AllowWrites = true
+	defer testServer.pool.Exec(ctx, "DROP TABLE IF EXISTS users_anonymized")
+	failed, response = run(AnonymizeTableArgs{TableName: "users", Columns: []string{"email", "username"}, TargetTable: "users_anonymized", Commit: true})
+	if failed || response["committed"] != true {
+		t.Fatalf("Expected the anonymized copy to be committed, got %v", response)
+	}
+	var copied, matching int64
+	if err := testServer.pool.QueryRow(ctx, `
+		SELECT count(*), count(*) FILTER (WHERE a.username = u.username OR a.email = u.email)
+		FROM users_anonymized a JOIN users u USING (id)
+	`).Scan(&copied, &matching); err != nil {
+		t.Fatalf("Failed to compare the copy: %v", err)
+	}
+	if copied != users || matching != 0 {
+		t.Errorf("Expected %d anonymized users, got %d with %d unchanged", users, copied, matching)
+	}
+	if !strings.HasPrefix(response["statement"].(string), "CREATE TABLE") {
+		t.Errorf("Unexpected statement %v", response["statement"])
+	}
+}
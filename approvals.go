@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	pendingChangeTTL = 24 * time.Hour
+	webhookTimeout   = 5 * time.Second
+	// maxResolvedChanges is how many decided or expired changes are kept
+	// for list_pending_changes; older ones are forgotten.
+	maxResolvedChanges = 1000
+)
+
+// pendingChange is a write statement waiting for a human to approve it.
+type pendingChange struct {
+	ID          string
+	Tool        string
+	Summary     string
+	Statement   string
+	CreatedAt   time.Time
+	Status      string
+	Result      string
+	Session     string
+	MaxRows     int64
+	ExactRows   bool
+	Role        string
+	token       string
+	Notified    bool
+	NotifyError string
+}
+
+// approvalQueue holds a server's pending changes. IDs are only unique per
+// queue, so changes can't be approved through another server instance.
+type approvalQueue struct {
+	mu      sync.Mutex
+	nextID  int
+	changes []*pendingChange
+}
+
+func newApprovalToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// notifyWebhook posts a change event to the configured webhook. Without a
+// webhook the approval token is written to the server log for the operator.
+func (s *serverState) notifyWebhook(ctx context.Context, event string, change *pendingChange, includeToken bool) error {
+	payload := map[string]interface{}{
+		"event":      event,
+		"change_id":  change.ID,
+		"tool":       change.Tool,
+		"summary":    change.Summary,
+		"statement":  change.Statement,
+		"status":     change.Status,
+		"created_at": change.CreatedAt,
+	}
+	if change.ExactRows {
+		payload["expected_rows"] = change.MaxRows
+	} else if change.MaxRows > 0 {
+		payload["max_rows"] = change.MaxRows
+	}
+	if change.Role != "" {
+		payload["role"] = change.Role
+	}
+	if includeToken {
+		payload["approval_token"] = change.token
+	}
+
+	if s.config.ApprovalWebhookURL == "" {
+		if includeToken {
+			log.Printf("Pending change %s (%s): %s -- approve with token %s", change.ID, change.Tool, change.Summary, change.token)
+		}
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.ApprovalWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// queueChange records a write for later approval and notifies the approver.
+func (s *serverState) queueChange(ctx context.Context, req *mcp.CallToolRequest, tool, summary, statement string) (*pendingChange, error) {
+	return s.queuePendingChange(ctx, req, &pendingChange{Tool: tool, Summary: summary, Statement: statement})
+}
+
+// queuePendingChange queues a write described by change's Tool, Summary and
+// Statement, and the MaxRows bound and Role it runs with when approved.
+// With ExactRows, MaxRows is the exact number of rows it must affect.
+func (s *serverState) queuePendingChange(ctx context.Context, req *mcp.CallToolRequest, change *pendingChange) (*pendingChange, error) {
+	token, err := newApprovalToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate approval token: %v", err)
+	}
+
+	s.approvals.mu.Lock()
+	s.approvals.nextID++
+	change.ID = fmt.Sprintf("change-%d", s.approvals.nextID)
+	change.CreatedAt = time.Now()
+	change.Status = "pending"
+	change.Session = sessionKey(req)
+	change.token = token
+	s.expireChanges()
+	s.approvals.changes = append(s.approvals.changes, change)
+	s.approvals.mu.Unlock()
+
+	err = s.notifyWebhook(ctx, "change_pending", change, true)
+
+	s.approvals.mu.Lock()
+	defer s.approvals.mu.Unlock()
+	if err != nil {
+		log.Printf("Failed to notify approval webhook for %s: %v", change.ID, err)
+		change.NotifyError = err.Error()
+	} else {
+		change.Notified = true
+	}
+	return change, nil
+}
+
+func returnQueuedChange(change *pendingChange) (*mcp.CallToolResult, any, error) {
+	response := map[string]interface{}{
+		"status":    "pending_approval",
+		"change_id": change.ID,
+		"summary":   change.Summary,
+		"message":   "The change was queued and will only run after a human approves it with approve_change",
+	}
+	if change.NotifyError != "" {
+		response["notify_error"] = change.NotifyError
+	}
+	return returnJSONResult(response)
+}
+
+func (s *serverState) findChange(id string) *pendingChange {
+	for _, change := range s.approvals.changes {
+		if change.ID == id {
+			return change
+		}
+	}
+	return nil
+}
+
+// expireChanges marks stale pending changes as expired and drops the oldest
+// resolved changes beyond maxResolvedChanges, callers hold s.approvals.mu.
+func (s *serverState) expireChanges() {
+	resolved := 0
+	for _, change := range s.approvals.changes {
+		if change.Status == "pending" && time.Since(change.CreatedAt) > pendingChangeTTL {
+			change.Status = "expired"
+		}
+		if change.Status != "pending" && change.Status != "executing" {
+			resolved++
+		}
+	}
+
+	kept := s.approvals.changes[:0]
+	for _, change := range s.approvals.changes {
+		if resolved > maxResolvedChanges && change.Status != "pending" && change.Status != "executing" {
+			resolved--
+			continue
+		}
+		kept = append(kept, change)
+	}
+	clear(s.approvals.changes[len(kept):])
+	s.approvals.changes = kept
+}
+
+type ListPendingChangesArgs struct {
+	IncludeResolved bool `json:"include_resolved,omitempty" jsonschema:"Also list approved, rejected and expired changes (default: false)"`
+}
+
+func (s *serverState) ListPendingChanges(ctx context.Context, req *mcp.CallToolRequest, args ListPendingChangesArgs) (*mcp.CallToolResult, any, error) {
+	s.approvals.mu.Lock()
+	defer s.approvals.mu.Unlock()
+	s.expireChanges()
+
+	var changes []map[string]interface{}
+	for _, change := range s.approvals.changes {
+		if change.Status != "pending" && !args.IncludeResolved {
+			continue
+		}
+		entry := map[string]interface{}{
+			"change_id":  change.ID,
+			"tool":       change.Tool,
+			"summary":    change.Summary,
+			"statement":  change.Statement,
+			"status":     change.Status,
+			"created_at": change.CreatedAt,
+			"notified":   change.Notified,
+		}
+		if change.ExactRows {
+			entry["expected_rows"] = change.MaxRows
+		} else if change.MaxRows > 0 {
+			entry["max_rows"] = change.MaxRows
+		}
+		if change.Role != "" {
+			entry["role"] = change.Role
+		}
+		if change.Result != "" {
+			entry["result"] = change.Result
+		}
+		changes = append(changes, entry)
+	}
+
+	return returnJSONResult(changes)
+}
+
+type ApproveChangeArgs struct {
+	ChangeID      string `json:"change_id" jsonschema:"ID of the pending change"`
+	ApprovalToken string `json:"approval_token" jsonschema:"Approval token delivered to the human approver"`
+}
+
+func (s *serverState) ApproveChange(ctx context.Context, req *mcp.CallToolRequest, args ApproveChangeArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	s.approvals.mu.Lock()
+	s.expireChanges()
+	change := s.findChange(args.ChangeID)
+	switch {
+	case change == nil:
+		s.approvals.mu.Unlock()
+		return s.returnErrorResult("Change %s not found", args.ChangeID)
+	case change.Status != "pending":
+		s.approvals.mu.Unlock()
+		return s.returnErrorResult("Change %s is %s and can no longer be approved", change.ID, change.Status)
+	case subtle.ConstantTimeCompare([]byte(args.ApprovalToken), []byte(change.token)) != 1:
+		s.approvals.mu.Unlock()
+		return s.returnErrorResult("Invalid approval token for change %s", change.ID)
+	}
+	// claim the change before running it so it can't be approved twice
+	change.Status = "executing"
+	s.approvals.mu.Unlock()
+
+	start := time.Now()
+	tag, err := s.executeChange(ctx, change)
+
+	s.approvals.mu.Lock()
+	switch {
+	case err != nil:
+		change.Status = "failed"
+		change.Result = err.Error()
+	case s.config.DryRun:
+		change.Status = "simulated"
+		change.Result = tag.String()
+	default:
+		change.Status = "executed"
+		change.Result = tag.String()
+	}
+	s.approvals.mu.Unlock()
+
+	if notifyErr := s.notifyWebhook(ctx, "change_"+change.Status, change, false); notifyErr != nil {
+		log.Printf("Failed to notify approval webhook for %s: %v", change.ID, notifyErr)
+	}
+
+	if err != nil {
+		return s.returnErrorResult("Approved change %s failed: %v", change.ID, err)
+	}
+	response := s.labelDryRun(map[string]interface{}{
+		"change_id":   change.ID,
+		"status":      change.Status,
+		"command_tag": tag.String(),
+		"duration_ms": time.Since(start).Milliseconds(),
+	})
+
+	analyzed, err := s.autoAnalyze(ctx, change.Statement, tag.RowsAffected())
+	if err != nil {
+		response["analyze_error"] = err.Error()
+	}
+	if len(analyzed) > 0 {
+		response["analyzed"] = analyzed
+	}
+	return returnJSONResult(response)
+}
+
+// executeChange runs an approved statement as the role it was queued with,
+// rolling it back when it affects more than its MaxRows rows, if set, or
+// any other number with ExactRows.
+func (s *serverState) executeChange(ctx context.Context, change *pendingChange) (pgconn.CommandTag, error) {
+	// VACUUM, CREATE and DROP DATABASE and the CONCURRENTLY commands refuse
+	// a transaction block, so they run as they are and dry-run mode can only
+	// skip them
+	if outsideTransaction(change.Statement) {
+		if change.Role != "" {
+			return pgconn.CommandTag{}, fmt.Errorf("role %s cannot be set for %s, which runs outside a transaction", change.Role, statementKeyword(change.Statement))
+		}
+		if s.config.DryRun {
+			return pgconn.CommandTag{}, nil
+		}
+		switch change.Tool {
+		case "clone_database":
+			// the pool's idle connections would keep a clone's template busy
+			s.closeIdleConnections(ctx)
+		case "drop_clone":
+			// and the clone's own pool the clone
+			s.detachClone(change.Statement)
+		}
+		return s.pool.Exec(ctx, change.Statement)
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.applyRole(ctx, tx, change.Role); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	tag, err := tx.Exec(ctx, change.Statement)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if change.ExactRows && tag.RowsAffected() != change.MaxRows {
+		return pgconn.CommandTag{}, fmt.Errorf("the statement affected %d rows instead of the expected %d and was rolled back", tag.RowsAffected(), change.MaxRows)
+	}
+	if change.MaxRows > 0 && tag.RowsAffected() > change.MaxRows {
+		return pgconn.CommandTag{}, fmt.Errorf("the statement affected %d rows, more than max_rows (%d), and was rolled back", tag.RowsAffected(), change.MaxRows)
+	}
+	return tag, s.finishWrite(ctx, tx)
+}
+
+type RejectChangeArgs struct {
+	ChangeID string `json:"change_id" jsonschema:"ID of the pending change"`
+	Reason   string `json:"reason,omitempty" jsonschema:"Why the change was rejected"`
+}
+
+func (s *serverState) RejectChange(ctx context.Context, req *mcp.CallToolRequest, args RejectChangeArgs) (*mcp.CallToolResult, any, error) {
+	s.approvals.mu.Lock()
+	change := s.findChange(args.ChangeID)
+	if change == nil || change.Status != "pending" {
+		s.approvals.mu.Unlock()
+		return s.returnErrorResult("No pending change %s", args.ChangeID)
+	}
+	change.Status = "rejected"
+	change.Result = args.Reason
+	s.approvals.mu.Unlock()
+
+	if err := s.notifyWebhook(ctx, "change_rejected", change, false); err != nil {
+		log.Printf("Failed to notify approval webhook for %s: %v", change.ID, err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"change_id": change.ID,
+		"status":    change.Status,
+	})
+}
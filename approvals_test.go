@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestApprovalQueue(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var events []map[string]interface{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		events = append(events, payload)
+		mu.Unlock()
+	}))
+	defer webhook.Close()
+
+	previous := testServer.config
+	testServer.config.AllowWrites = true
+	testServer.config.RequireApproval = true
+	testServer.config.ApprovalWebhookURL = webhook.URL
+	defer func() { testServer.config = previous }()
+
+	refreshArgs := RefreshMaterializedViewArgs{ViewName: "listing_category_stats"}
+	result, data, err := testServer.RefreshMaterializedView(ctx, createMockRequest(refreshArgs), refreshArgs)
+	if err != nil {
+		t.Fatalf("RefreshMaterializedView failed: %v", err)
+	}
+	if result == nil || result.IsError {
+		t.Fatalf("Expected change to be queued, got %v", result)
+	}
+
+	queued := data.(map[string]interface{})
+	if queued["status"] != "pending_approval" {
+		t.Fatalf("Expected pending_approval status, got %v", queued["status"])
+	}
+	changeID := queued["change_id"].(string)
+
+	mu.Lock()
+	if len(events) != 1 || events[0]["approval_token"] == nil {
+		mu.Unlock()
+		t.Fatalf("Expected webhook to receive the approval token, got %v", events)
+	}
+	token := events[0]["approval_token"].(string)
+	mu.Unlock()
+
+	t.Run("pending change is listed without its token", func(t *testing.T) {
+		args := ListPendingChangesArgs{}
+		_, data, err := testServer.ListPendingChanges(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ListPendingChanges failed: %v", err)
+		}
+
+		found := false
+		for _, change := range data.([]map[string]interface{}) {
+			if change["change_id"] == changeID {
+				found = true
+				if _, ok := change["approval_token"]; ok {
+					t.Error("Expected approval token to be hidden")
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s to be listed", changeID)
+		}
+	})
+
+	t.Run("wrong token is refused", func(t *testing.T) {
+		args := ApproveChangeArgs{ChangeID: changeID, ApprovalToken: "not-the-token"}
+		result, _, err := testServer.ApproveChange(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ApproveChange failed: %v", err)
+		}
+		if result == nil || !result.IsError {
+			t.Error("Expected approval with a wrong token to fail")
+		}
+	})
+
+	t.Run("approval executes the change once", func(t *testing.T) {
+		args := ApproveChangeArgs{ChangeID: changeID, ApprovalToken: token}
+		result, data, err := testServer.ApproveChange(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ApproveChange failed: %v", err)
+		}
+		if result == nil || result.IsError {
+			t.Fatalf("Expected approval to succeed, got %v", result)
+		}
+		if status := data.(map[string]interface{})["status"]; status != "executed" {
+			t.Errorf("Expected executed status, got %v", status)
+		}
+
+		result, _, _ = testServer.ApproveChange(ctx, createMockRequest(args), args)
+		if result == nil || !result.IsError {
+			t.Error("Expected a second approval to be refused")
+		}
+	})
+
+	t.Run("approved change runs as the queued role", func(t *testing.T) {
+		if _, err := testServer.pool.Exec(ctx, "CREATE ROLE mcp_no_writes NOLOGIN"); err != nil {
+			t.Fatalf("Failed to create role: %v", err)
+		}
+		defer testServer.pool.Exec(ctx, "DROP ROLE mcp_no_writes")
+
+		writeArgs := ExecuteWriteArgs{Statement: "UPDATE users SET bio = bio WHERE id = 1", Confirm: true, MaxRows: 1, Role: "mcp_no_writes"}
+		_, data, err := testServer.ExecuteWrite(ctx, createMockRequest(writeArgs), writeArgs)
+		if err != nil {
+			t.Fatalf("ExecuteWrite failed: %v", err)
+		}
+		changeID := data.(map[string]interface{})["change_id"].(string)
+
+		mu.Lock()
+		event := events[len(events)-1]
+		mu.Unlock()
+		if event["change_id"] != changeID || event["role"] != "mcp_no_writes" {
+			t.Fatalf("Expected the webhook to name the role, got %v", event)
+		}
+
+		args := ApproveChangeArgs{ChangeID: changeID, ApprovalToken: event["approval_token"].(string)}
+		result, _, err := testServer.ApproveChange(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ApproveChange failed: %v", err)
+		}
+		if result == nil || !result.IsError {
+			t.Error("Expected the approved update to fail without the role's privileges")
+		}
+	})
+
+	t.Run("approved delete must match expected_count exactly", func(t *testing.T) {
+		expected := int64(3)
+		deleteArgs := DeleteRowsArgs{TableName: "users", Where: []RowCondition{{Column: "id", Op: "in", Value: []any{1.0, 2.0}}}, ExpectedCount: &expected}
+		_, data, err := testServer.DeleteRows(ctx, createMockRequest(deleteArgs), deleteArgs)
+		if err != nil {
+			t.Fatalf("DeleteRows failed: %v", err)
+		}
+		changeID := data.(map[string]interface{})["change_id"].(string)
+
+		mu.Lock()
+		event := events[len(events)-1]
+		mu.Unlock()
+		if event["change_id"] != changeID || event["expected_rows"] != 3.0 {
+			t.Fatalf("Expected the webhook to name the exact count, got %v", event)
+		}
+
+		args := ApproveChangeArgs{ChangeID: changeID, ApprovalToken: event["approval_token"].(string)}
+		result, _, err := testServer.ApproveChange(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ApproveChange failed: %v", err)
+		}
+		if result == nil || !result.IsError {
+			t.Error("Expected a delete of fewer rows than expected to be rolled back")
+		}
+	})
+
+	t.Run("rejection", func(t *testing.T) {
+		_, data, _ := testServer.RefreshMaterializedView(ctx, createMockRequest(refreshArgs), refreshArgs)
+		changeID := data.(map[string]interface{})["change_id"].(string)
+
+		args := RejectChangeArgs{ChangeID: changeID, Reason: "not now"}
+		result, _, err := testServer.RejectChange(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("RejectChange failed: %v", err)
+		}
+		if result == nil || result.IsError {
+			t.Fatalf("Expected rejection to succeed, got %v", result)
+		}
+	})
+}
+
+func TestExpireChanges(t *testing.T) {
+	s := &serverState{}
+	stale := &pendingChange{ID: "change-0", Status: "pending", CreatedAt: time.Now().Add(-2 * pendingChangeTTL)}
+	s.approvals.changes = append(s.approvals.changes, stale)
+	for i := 1; i <= maxResolvedChanges+10; i++ {
+		s.approvals.changes = append(s.approvals.changes, &pendingChange{ID: fmt.Sprintf("change-%d", i), Status: "executed", CreatedAt: time.Now()})
+	}
+	fresh := &pendingChange{ID: "change-fresh", Status: "pending", CreatedAt: time.Now()}
+	s.approvals.changes = append(s.approvals.changes, fresh)
+
+	s.expireChanges()
+	if stale.Status != "expired" {
+		t.Errorf("Expected the stale change to expire, got %s", stale.Status)
+	}
+	if len(s.approvals.changes) != maxResolvedChanges+1 {
+		t.Fatalf("Expected %d resolved changes and the pending one to be kept, got %d", maxResolvedChanges, len(s.approvals.changes))
+	}
+	if s.findChange("change-0") != nil || s.findChange("change-10") != nil {
+		t.Error("Expected the oldest resolved changes to be dropped")
+	}
+	if s.findChange("change-11") == nil || s.findChange("change-fresh") == nil {
+		t.Error("Expected newer resolved and pending changes to be kept")
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// sqlRedactors decide how much of a statement reaches the audit log, picked
+// per deployment with LOG_SQL.
+var sqlRedactors = map[string]func(string) string{
+	"full":     func(query string) string { return query },
+	"redacted": redactSQLLiterals,
+	"none":     func(query string) string { return "" },
+}
+
+// sqlArgumentKeys hold statements and SQL expressions, valueArgumentKeys
+// hold data values that tools compare against or write. Both are redacted
+// unless LOG_SQL=full. plainArgumentKeys hold names and options, logged as
+// they are; other text arguments are redacted like values, so a tool whose
+// arguments were never registered can't leak what it was given.
+var (
+	sqlArgumentKeys = map[string]bool{
+		"query": true, "query_b": true, "queries": true, "statement": true,
+		"probe_query": true, "check": true, "using": true,
+	}
+	valueArgumentKeys = map[string]bool{
+		"root_value": true, "from_value": true, "to_value": true, "value": true,
+		"set": true, "where": true, "data": true, "approval_token": true,
+	}
+	plainArgumentKeys = map[string]bool{
+		"anomaly": true, "at": true, "binary_format": true, "change_id": true, "child_column": true,
+		"column": true, "columns": true, "command": true, "comment": true, "connection": true,
+		"dedupe_columns": true, "description": true, "exclude_columns": true, "fingerprint": true,
+		"format": true, "from": true, "from_column": true, "from_schema": true, "from_table": true,
+		"geometry_format": true, "index": true, "input_path": true, "interval_format": true,
+		"isolation_level": true, "json_format": true, "json_paths": true, "key_columns": true,
+		"kind": true, "match": true, "method": true, "name": true, "names": true, "new_type": true,
+		"numeric_format": true, "output_path": true, "parent_column": true, "reason": true,
+		"references_columns": true, "references_table": true, "render": true, "role": true,
+		"rules": true, "schema": true, "server": true, "settings_a": true, "settings_b": true,
+		"since": true, "table": true, "table_name": true, "tables": true, "target_table": true,
+		"to": true, "to_column": true, "to_schema": true, "to_table": true, "type": true,
+		"units": true, "view_name": true,
+	}
+)
+
+// redactSQLLiterals replaces string, dollar-quoted and numeric literals with
+// ? and drops comments, keeping the statement's shape, identifiers and $n
+// parameters.
+func redactSQLLiterals(query string) string {
+	runes := []rune(query)
+	var out []rune
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i = skipBlockComment(runes, i)
+
+		case r == '\'':
+			// drop the E of E'...' along with the literal
+			escapes := len(out) > 0 && (out[len(out)-1] == 'E' || out[len(out)-1] == 'e') &&
+				(len(out) == 1 || !isIdentifierRune(out[len(out)-2]))
+			if escapes {
+				out = out[:len(out)-1]
+			}
+			i = skipQuoted(runes, i, '\'', escapes)
+			out = append(out, '?')
+
+		case r == '"':
+			start := i
+			i = skipQuoted(runes, i, '"', false)
+			out = append(out, runes[start:i]...)
+
+		case r == '$':
+			j := i + 1
+			for j < len(runes) && isIdentifierRune(runes[j]) {
+				j++
+			}
+			if j < len(runes) && runes[j] == '$' && (j == i+1 || !unicode.IsDigit(runes[i+1])) {
+				i = skipDollarQuoted(runes, j+1, runes[i:j+1])
+				out = append(out, '?')
+			} else {
+				out = append(out, runes[i:j]...)
+				i = j
+			}
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (isIdentifierRune(runes[i]) || runes[i] == '$') {
+				i++
+			}
+			out = append(out, runes[start:i]...)
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				i++
+			}
+			out = append(out, '?')
+
+		default:
+			out = append(out, r)
+			i++
+		}
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// auditRecord is one tool call in the audit log. Results are never part of
+// it, only the arguments (redacted per LOG_SQL) and the outcome.
+type auditRecord struct {
+	Time       time.Time              `json:"time"`
+	Session    string                 `json:"session,omitempty"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+	IsError    bool                   `json:"is_error"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// auditWriter is where a server's audit records go, nil when disabled.
+type auditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// openAuditLog sets where audit records go: stderr (next to the server log)
+// or a file that records are appended to.
+func (s *serverState) openAuditLog(destination string) error {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+
+	switch destination {
+	case "":
+		s.audit.w = nil
+	case "stderr":
+		s.audit.w = os.Stderr
+	default:
+		file, err := os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		s.audit.w = file
+	}
+	return nil
+}
+
+// redactArguments applies the LOG_SQL policy to a tool call's arguments.
+func (s *serverState) redactArguments(arguments json.RawMessage) map[string]interface{} {
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(arguments, &decoded); err != nil {
+		return nil
+	}
+
+	redact, ok := sqlRedactors[s.config.LogSQL]
+	if !ok {
+		redact = redactSQLLiterals
+	}
+	for key, value := range decoded {
+		switch {
+		case s.config.LogSQL == "full", plainArgumentKeys[key]:
+		case sqlArgumentKeys[key]:
+			decoded[key] = redactStatements(value, redact)
+		case valueArgumentKeys[key]:
+			decoded[key] = "?"
+		default:
+			switch value.(type) {
+			case string, []interface{}, map[string]interface{}:
+				decoded[key] = "?"
+			}
+		}
+	}
+	return decoded
+}
+
+// redactStatements redacts a statement argument, or each of a list of them.
+func redactStatements(value interface{}, redact func(string) string) interface{} {
+	switch value := value.(type) {
+	case string:
+		return redact(value)
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = redactStatements(item, redact)
+		}
+		return redacted
+	}
+	return "?"
+}
+
+// writeAudit appends a tool call to the audit log, if one is configured.
+// Error messages can quote values, so they are only kept with LOG_SQL=full.
+func (s *serverState) writeAudit(session string, event sessionEvent) {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	if s.audit.w == nil {
+		return
+	}
+
+	record := auditRecord{
+		Time:       event.Time,
+		Session:    session,
+		Tool:       event.Tool,
+		Arguments:  s.redactArguments(event.Arguments),
+		DurationMs: event.DurationMs,
+		IsError:    event.IsError,
+	}
+	if event.IsError && s.config.LogSQL == "full" {
+		record.Error = strings.TrimPrefix(event.Summary, "error: ")
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Failed to encode audit record: %v", err)
+		return
+	}
+	// file destinations are encrypted line by line like other written files
+	if s.config.EncryptionKey != nil && s.audit.w != os.Stderr {
+		sealed, err := encryptArtifact(s.config.EncryptionKey, line)
+		if err != nil {
+			log.Printf("Failed to encrypt audit record: %v", err)
+			return
+		}
+		line = []byte(base64.StdEncoding.EncodeToString(sealed))
+	}
+	if _, err := fmt.Fprintf(s.audit.w, "%s\n", line); err != nil {
+		log.Printf("Failed to write audit record: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactSQLLiterals(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE email = 'a@example.com' AND id = 42":    "SELECT * FROM users WHERE email = ? AND id = ?",
+		"SELECT E'it\\'s', $$secret$$, $tag$x$tag$, 1.5e-3, .5 FROM t":     "SELECT ?, ?, ?, ?, ? FROM t",
+		`SELECT "col1", t2.col3 FROM "Tab'le" t2 WHERE x = $1 -- note 123`: `SELECT "col1", t2.col3 FROM "Tab'le" t2 WHERE x = $1`,
+		"UPDATE accounts /* ticket 99 */ SET balance = balance - 100.00":   "UPDATE accounts  SET balance = balance - ?",
+		"SELECT type FROM e WHERE name = 'it''s'":                          "SELECT type FROM e WHERE name = ?",
+	}
+	for query, expected := range tests {
+		if redacted := redactSQLLiterals(query); redacted != expected {
+			t.Errorf("redactSQLLiterals(%q) = %q, expected %q", query, redacted, expected)
+		}
+	}
+}
+
+func TestWriteAudit(t *testing.T) {
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	defer testServer.openAuditLog("")
+
+	event := sessionEvent{
+		Time:      time.Now(),
+		Tool:      "query",
+		Arguments: json.RawMessage(`{"query": "SELECT * FROM users WHERE email = 'a@example.com'", "expanded": true}`),
+		IsError:   true,
+		Summary:   "error: value 'a@example.com' is invalid",
+	}
+
+	readRecord := func(line string) auditRecord {
+		var record auditRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode audit record %q: %v", line, err)
+		}
+		return record
+	}
+
+	for _, test := range []struct {
+		mode  string
+		query string
+		error string
+	}{
+		{"redacted", "SELECT * FROM users WHERE email = ?", ""},
+		{"none", "", ""},
+		{"full", "SELECT * FROM users WHERE email = 'a@example.com'", "value 'a@example.com' is invalid"},
+	} {
+		var buf bytes.Buffer
+		testServer.audit.w = &buf
+		testServer.config.LogSQL = test.mode
+
+		testServer.writeAudit("session-1", event)
+		record := readRecord(strings.TrimSpace(buf.String()))
+		if record.Arguments["query"] != test.query || record.Error != test.error {
+			t.Errorf("LOG_SQL=%s: unexpected record %+v", test.mode, record)
+		}
+		if record.Arguments["expanded"] != true || record.Session != "session-1" {
+			t.Errorf("LOG_SQL=%s: expected other arguments to be kept, got %+v", test.mode, record)
+		}
+	}
+
+	t.Run("encrypted", func(t *testing.T) {
+		var buf bytes.Buffer
+		testServer.audit.w = &buf
+		testServer.config.LogSQL = "redacted"
+		testServer.config.EncryptionKey = bytes.Repeat([]byte{7}, 32)
+
+		testServer.writeAudit("", event)
+		sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(buf.String()))
+		if err != nil {
+			t.Fatalf("Expected a base64 line, got %q", buf.String())
+		}
+		line, err := decryptArtifact(testServer.config.EncryptionKey, sealed)
+		if err != nil {
+			t.Fatalf("Failed to decrypt audit record: %v", err)
+		}
+		if readRecord(string(line)).Tool != "query" {
+			t.Errorf("Unexpected decrypted record %s", line)
+		}
+	})
+}
+
+func TestRedactArguments(t *testing.T) {
+	s := &serverState{config: Config{LogSQL: "redacted"}}
+	arguments := s.redactArguments(json.RawMessage(`{
+		"statement": "UPDATE users SET email = 'a@example.com'",
+		"queries": ["SELECT 1", "SELECT * FROM users WHERE id = 7"],
+		"where": [{"column": "email", "op": "=", "value": "a@example.com"}],
+		"table_name": "users",
+		"unregistered": "a@example.com",
+		"confirm": true
+	}`))
+
+	if arguments["statement"] != "UPDATE users SET email = ?" {
+		t.Errorf("Expected the statement to be redacted, got %v", arguments["statement"])
+	}
+	if queries := arguments["queries"].([]interface{}); queries[0] != "SELECT ?" || queries[1] != "SELECT * FROM users WHERE id = ?" {
+		t.Errorf("Expected every query to be redacted, got %v", queries)
+	}
+	if arguments["where"] != "?" || arguments["unregistered"] != "?" {
+		t.Errorf("Expected values and unregistered arguments to be redacted, got %v", arguments)
+	}
+	if arguments["table_name"] != "users" || arguments["confirm"] != true {
+		t.Errorf("Expected names and flags to be kept, got %v", arguments)
+	}
+}
+
+// TestArgumentKeysRegistered makes every text argument of every tool pick
+// how the audit log treats it.
+func TestArgumentKeysRegistered(t *testing.T) {
+	tools, err := (&serverState{}).listTools(context.Background())
+	if err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	for _, tool := range tools {
+		data, _ := json.Marshal(tool.InputSchema)
+		var schema struct {
+			Properties map[string]struct {
+				Type interface{} `json:"type"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatalf("Failed to decode the input schema of %s: %v", tool.Name, err)
+		}
+		for name, property := range schema.Properties {
+			// optional numbers are typed ["null", "integer"]
+			propertyType := property.Type
+			if types, ok := propertyType.([]interface{}); ok && len(types) == 2 && types[0] == "null" {
+				propertyType = types[1]
+			}
+			switch propertyType {
+			case "boolean", "integer", "number":
+				continue
+			}
+			registered := 0
+			for _, keys := range []map[string]bool{sqlArgumentKeys, valueArgumentKeys, plainArgumentKeys} {
+				if keys[name] {
+					registered++
+				}
+			}
+			if registered != 1 {
+				t.Errorf("Argument %s of %s must be in exactly one of sqlArgumentKeys, valueArgumentKeys and plainArgumentKeys", name, tool.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var backupFormats = map[string]bool{"binary": true, "csv": true}
+
+// binaryCopySignature starts every COPY binary stream, which tells restores
+// of backups without metadata apart from CSV.
+var binaryCopySignature = []byte("PGCOPY\n\xff\r\n\x00")
+
+// backupSubfield is the gzip extra subfield ID holding a backup's metadata.
+var backupSubfield = [2]byte{'P', 'M'}
+
+type BackupTableArgs struct {
+	TableName  string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema     string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	OutputPath string `json:"output_path" jsonschema:"File in EXPORT_DIR to write the gzip-compressed backup to, relative to it"`
+	Format     string `json:"format,omitempty" jsonschema:"COPY format: binary (fast, for the same column types) or csv (portable, readable) (default: binary)"`
+}
+
+type RestoreTableArgs struct {
+	InputPath string `json:"input_path" jsonschema:"Backup file written by backup_table, relative to EXPORT_DIR"`
+	TableName string `json:"table_name,omitempty" jsonschema:"Table to restore into, optionally schema-qualified (default: the table backed up)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Truncate  bool   `json:"truncate,omitempty" jsonschema:"Empty the table before restoring into it, in the same transaction (default: false)"`
+}
+
+// backupMetadata describes a backup, stored in the gzip header so a restore
+// knows what it holds.
+type backupMetadata struct {
+	Table   string   `json:"table"`
+	Format  string   `json:"format"`
+	Columns []string `json:"columns"`
+}
+
+// encodeBackupMetadata renders metadata as a gzip extra field of one
+// subfield, as RFC 1952 lays them out.
+func encodeBackupMetadata(metadata backupMetadata) ([]byte, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0xffff-4 {
+		return nil, fmt.Errorf("the table has too many columns to describe in the backup")
+	}
+	extra := []byte{backupSubfield[0], backupSubfield[1], 0, 0}
+	binary.LittleEndian.PutUint16(extra[2:], uint16(len(data)))
+	return append(extra, data...), nil
+}
+
+// decodeBackupMetadata finds the metadata subfield of a gzip extra field.
+func decodeBackupMetadata(extra []byte) (*backupMetadata, error) {
+	for len(extra) >= 4 {
+		size := int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if [2]byte(extra[:2]) == backupSubfield {
+			var metadata backupMetadata
+			if err := json.Unmarshal(extra[4:4+size], &metadata); err != nil {
+				return nil, fmt.Errorf("invalid backup metadata: %v", err)
+			}
+			return &metadata, nil
+		}
+		extra = extra[4+size:]
+	}
+	return nil, nil
+}
+
+// copyOptions are the COPY options of a backup format. CSV backups carry a
+// header line, so they read as a spreadsheet too.
+func copyOptions(format string) string {
+	if format == "csv" {
+		return "(FORMAT csv, HEADER true)"
+	}
+	return "(FORMAT binary)"
+}
+
+// BackupTable copies a table's data to a gzip-compressed file with COPY,
+// as a safety copy to take before a risky change. The file records the
+// table, format and columns, so restore_table needs only its path. It
+// writes to the server's disk, so it is gated like the write tools.
+func (s *serverState) BackupTable(ctx context.Context, req *mcp.CallToolRequest, args BackupTableArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("backup_table")
+	}
+	if s.config.RequireApproval {
+		// a pending change is a statement, which can't write the file
+		return s.returnErrorResult("backup_table can't be queued for approval, back up the table with REQUIRE_APPROVAL off")
+	}
+	if args.OutputPath == "" {
+		return s.returnErrorResult("output_path is required")
+	}
+	path, err := s.exportPath(args.OutputPath)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	format := strings.ToLower(args.Format)
+	if format == "" {
+		format = "binary"
+	}
+	if !backupFormats[format] {
+		return s.returnErrorResult("Unknown format %q, use binary or csv", args.Format)
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	def, err := s.loadTableDef(ctx, schema+"."+tableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	// generated columns are computed again on restore
+	var columns, names []string
+	for _, column := range def.insertableColumns() {
+		columns = append(columns, column.Name)
+		names = append(names, quoteIdentifier(column.Name))
+	}
+	extra, err := encodeBackupMetadata(backupMetadata{Table: def.Name, Format: format, Columns: columns})
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	file, encrypted, err := s.createArtifact(path)
+	if err != nil {
+		return s.returnErrorResult("Failed to write backup: %v", err)
+	}
+	counter := &byteCounter{report: progressNotifier(ctx, req)}
+	compressor := gzip.NewWriter(countingWriter{file, counter})
+	compressor.Extra = extra
+
+	statement := fmt.Sprintf("COPY %s (%s) TO STDOUT %s", qualifiedName(schema, tableName), strings.Join(names, ", "), copyOptions(format))
+	tag, err := tx.Conn().PgConn().CopyTo(ctx, compressor, statement)
+	if err == nil {
+		err = compressor.Close()
+	}
+	if err != nil {
+		file.Discard()
+		return s.returnErrorResult("Backup failed: %v", err)
+	}
+	if err := file.Close(); err != nil {
+		return s.returnErrorResult("Backup failed: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"table":       def.Name,
+		"format":      format,
+		"rows":        tag.RowsAffected(),
+		"bytes":       counter.count,
+		"output_path": args.OutputPath,
+		"encrypted":   encrypted,
+	})
+	return s.withWarnings(result, notices), data, err
+}
+
+// RestoreTable copies a backup_table file back into its table, or into
+// another table with the same columns, in one transaction.
+func (s *serverState) RestoreTable(ctx context.Context, req *mcp.CallToolRequest, args RestoreTableArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("restore_table")
+	}
+	if s.config.RequireApproval {
+		// a pending change is a statement, which can't carry the file
+		return s.returnErrorResult("restore_table can't be queued for approval, restore the backup with REQUIRE_APPROVAL off")
+	}
+	if args.InputPath == "" {
+		return s.returnErrorResult("input_path is required")
+	}
+	path, err := s.exportPath(args.InputPath)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	file, size, err := s.openArtifact(path)
+	if err != nil {
+		return s.returnErrorResult("Failed to read backup: %v", err)
+	}
+	defer file.Close()
+	counter := &byteCounter{report: progressNotifier(ctx, req), total: size}
+	decompressor, err := gzip.NewReader(countingReader{file, counter})
+	if err != nil {
+		return s.returnErrorResult("%s is not a backup_table file: %v", args.InputPath, err)
+	}
+	metadata, err := decodeBackupMetadata(decompressor.Extra)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	contents := bufio.NewReader(decompressor)
+	if metadata == nil {
+		// COPY output compressed by hand, CSV with a header line or binary,
+		// restored into all columns
+		metadata = &backupMetadata{Format: "csv"}
+		if signature, _ := contents.Peek(len(binaryCopySignature)); bytes.Equal(signature, binaryCopySignature) {
+			metadata.Format = "binary"
+		}
+	}
+
+	tableName := args.TableName
+	if tableName == "" && args.Schema == "" && metadata.Table != "" {
+		tableName = sanitizeQualifiedName(metadata.Table)
+	}
+	if tableName == "" {
+		return s.returnErrorResult("table_name is required, the backup doesn't record its table")
+	}
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, tableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := qualifiedName(schema, tableName)
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if args.Truncate {
+		if _, err := tx.Exec(ctx, "TRUNCATE "+table); err != nil {
+			return s.returnErrorResult("Truncate failed: %v", err)
+		}
+	}
+	statement := "COPY " + table
+	if len(metadata.Columns) > 0 {
+		names := make([]string, len(metadata.Columns))
+		for i, column := range metadata.Columns {
+			names[i] = quoteIdentifier(column)
+		}
+		statement += " (" + strings.Join(names, ", ") + ")"
+	}
+	statement += " FROM STDIN " + copyOptions(metadata.Format)
+	tag, err := tx.Conn().PgConn().CopyFrom(ctx, contents, statement)
+	if err != nil {
+		return s.returnErrorResult("Restore failed: %v", err)
+	}
+	if err := s.finishWrite(ctx, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	response := s.labelDryRun(map[string]interface{}{
+		"table":     schema + "." + tableName,
+		"format":    metadata.Format,
+		"rows":      tag.RowsAffected(),
+		"truncated": args.Truncate,
+	})
+	if metadata.Table != "" {
+		response["backed_up_table"] = metadata.Table
+	}
+	// autoAnalyze finds the tables of data-modifying statements, which COPY isn't
+	if analyzed, err := s.autoAnalyze(ctx, "INSERT INTO "+table, tag.RowsAffected()); err != nil {
+		response["analyze_error"] = err.Error()
+	} else if len(analyzed) > 0 {
+		response["analyzed"] = analyzed
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, notices), data, err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupMetadata(t *testing.T) {
+	metadata := backupMetadata{Table: "public.Users", Format: "csv", Columns: []string{"id", "e-mail"}}
+	extra, err := encodeBackupMetadata(metadata)
+	if err != nil {
+		t.Fatalf("encodeBackupMetadata failed: %v", err)
+	}
+
+	// another subfield first, as other tools may add
+	decoded, err := decodeBackupMetadata(append([]byte{'X', 'Y', 1, 0, 0}, extra...))
+	if err != nil {
+		t.Fatalf("decodeBackupMetadata failed: %v", err)
+	}
+	if decoded == nil || decoded.Table != metadata.Table || decoded.Format != metadata.Format || len(decoded.Columns) != 2 || decoded.Columns[1] != "e-mail" {
+		t.Errorf("Expected %v back, got %v", metadata, decoded)
+	}
+
+	if decoded, err := decodeBackupMetadata(nil); decoded != nil || err != nil {
+		t.Errorf("Expected no metadata without an extra field, got %v, %v", decoded, err)
+	}
+}
+
+func TestBackupAndRestoreTable(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.AllowWrites = true
+	testServer.config.ExportDir = t.TempDir()
+
+	if _, err := testServer.pool.Exec(ctx, "CREATE TABLE users_restored (LIKE users INCLUDING ALL)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE users_restored")
+
+	var users int64
+	if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&users); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+
+	for _, format := range []string{"binary", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			path := format + "/users.gz"
+			os.Mkdir(filepath.Join(testServer.config.ExportDir, format), 0o700)
+			backupArgs := BackupTableArgs{TableName: "users", OutputPath: path, Format: format}
+			result, data, err := testServer.BackupTable(ctx, createMockRequest(backupArgs), backupArgs)
+			if err != nil {
+				t.Fatalf("BackupTable failed: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("Expected successful backup, got %v", result)
+			}
+			if rows := data.(map[string]interface{})["rows"]; rows != users {
+				t.Errorf("Expected %d rows backed up, got %v", users, rows)
+			}
+
+			restoreArgs := RestoreTableArgs{InputPath: path, TableName: "users_restored", Truncate: true}
+			result, data, err = testServer.RestoreTable(ctx, createMockRequest(restoreArgs), restoreArgs)
+			if err != nil {
+				t.Fatalf("RestoreTable failed: %v", err)
+			}
+			if result.IsError {
+				t.Fatalf("Expected successful restore, got %v", result)
+			}
+			response := data.(map[string]interface{})
+			if response["rows"] != users || response["format"] != format || response["backed_up_table"] != "public.users" {
+				t.Errorf("Unexpected response %v", response)
+			}
+
+			var differing int64
+			if err := testServer.pool.QueryRow(ctx, `
+				SELECT count(*) FROM (
+					(SELECT * FROM users EXCEPT SELECT * FROM users_restored)
+					UNION ALL
+					(SELECT * FROM users_restored EXCEPT SELECT * FROM users)
+				) d
+			`).Scan(&differing); err != nil {
+				t.Fatalf("Failed to compare tables: %v", err)
+			}
+			if differing != 0 {
+				t.Errorf("Expected the restored rows to match, %d differ", differing)
+			}
+		})
+	}
+
+	for _, outside := range []string{filepath.Join(t.TempDir(), "users.gz"), "../users.gz"} {
+		args := BackupTableArgs{TableName: "users", OutputPath: outside}
+		if result, _, err := testServer.BackupTable(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+			t.Errorf("Expected a backup to %s to be refused", outside)
+		}
+	}
+
+	testServer.config.AllowWrites = false
+	args := RestoreTableArgs{InputPath: "csv/users.gz"}
+	if result, _, err := testServer.RestoreTable(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+		t.Error("Expected a restore to be refused without ALLOW_WRITES")
+	}
+	backupArgs := BackupTableArgs{TableName: "users", OutputPath: "users.gz"}
+	if result, _, err := testServer.BackupTable(ctx, createMockRequest(backupArgs), backupArgs); err != nil || !result.IsError {
+		t.Error("Expected a backup to be refused without ALLOW_WRITES")
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxBatchQueries bounds a batch, whose results are all held at once.
+const maxBatchQueries = 50
+
+type BatchQueryArgs struct {
+	Queries []string `json:"queries" jsonschema:"Independent read-only SQL queries, run in one round trip. Results are returned in the same order"`
+	Role    string   `json:"role,omitempty" jsonschema:"Run the queries as this role (SET LOCAL ROLE)"`
+}
+
+// batchResult is the outcome of one query of a batch: its rows, or why it
+// failed.
+type batchResult struct {
+	Index    int                      `json:"index"`
+	RowCount int                      `json:"row_count"`
+	Rows     []map[string]interface{} `json:"rows,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+
+	fields []pgconn.FieldDescription
+}
+
+// BatchQuery sends several reads through pgx's pipeline in one read-only
+// transaction, so a model looking up a dozen small things pays for one
+// round trip rather than one per query. Values are returned as query
+// returns them by default. Only reads are run, not session statements.
+func (s *serverState) BatchQuery(ctx context.Context, req *mcp.CallToolRequest, args BatchQueryArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if len(args.Queries) == 0 {
+		return s.returnErrorResult("queries is required")
+	}
+	if len(args.Queries) > maxBatchQueries {
+		return s.returnErrorResult("A batch holds at most %d queries, got %d", maxBatchQueries, len(args.Queries))
+	}
+	// session statements (SET, RESET ROLE) would carry over to the queries
+	// after them in the shared transaction
+	for i, query := range args.Queries {
+		category := statementCategory(query)
+		if category != categoryRead || s.queryPolicyAction(category) == "block" {
+			return s.returnErrorResult("Query %d is a %s statement, batch_query only runs reads", i, category)
+		}
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, args.Role)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, query := range args.Queries {
+		if err := s.checkStatementAccess(ctx, tx, query); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		batch.Queue(query)
+	}
+
+	results := make([]*batchResult, len(args.Queries))
+	batchResults := tx.SendBatch(ctx, batch)
+	failed := -1
+	for i := range args.Queries {
+		result := &batchResult{Index: i}
+		results[i] = result
+		rows, err := batchResults.Query()
+		if err == nil {
+			// the connection reuses the descriptions for the next query
+			result.fields = slices.Clone(rows.FieldDescriptions())
+			result.Rows, err = collectRows(rows)
+		}
+		switch {
+		case err != nil && failed >= 0:
+			// the failure aborted the transaction, later queries didn't run
+			result.Error = fmt.Sprintf(s.localize("Not run, query %d failed first"), failed)
+		case err != nil:
+			result.Error = err.Error()
+			failed = i
+		}
+		result.RowCount = len(result.Rows)
+	}
+	if err := batchResults.Close(); err != nil && failed < 0 {
+		return nil, nil, fmt.Errorf("failed to run batch: %v", err)
+	}
+
+	// a failed query aborts the transaction, the rows read before it are
+	// finished with the pool
+	var resolver relationResolver = tx
+	typeMap := tx.Conn().TypeMap()
+	if failed >= 0 {
+		tx.Rollback(ctx)
+		resolver = s.pool
+	} else {
+		recordRowsScanned(ctx, tx)
+	}
+
+	policies := make(map[string]columnPolicy)
+	masked := make(map[string][]string)
+	var truncatedBinary, truncatedJSON []string
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		resultPolicies, err := s.resultColumnPolicies(ctx, resolver, result.fields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+		}
+		geometries, err := geometryColumns(ctx, resolver, typeMap, result.fields)
+		if err == nil {
+			err = renderGeometryRows(ctx, resolver, geometries, "geojson", result.Rows)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+		}
+		composites, err := compositeColumns(ctx, resolver, typeMap, result.fields)
+		if err == nil {
+			err = renderCompositeRows(ctx, resolver, composites, result.Rows)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to convert composite values: %v", err)
+		}
+
+		truncated, _ := encodeBinaryRows(result.Rows, "base64", s.config.MaxBinaryBytes)
+		truncatedBinary = append(truncatedBinary, truncated...)
+		truncated, err = shapeJSONRows(result.Rows, jsonColumns(result.fields), nil, "nested", s.config.MaxJSONBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		truncatedJSON = append(truncatedJSON, truncated...)
+		applyColumnPolicies(result.Rows, resultPolicies)
+		maps.Copy(policies, resultPolicies)
+		for column, kinds := range s.redactRows(result.Rows) {
+			masked[column] = append(masked[column], kinds...)
+		}
+		encodeNumericRows(result.Rows, "string")
+		encodeIntervalRows(result.Rows, "iso8601")
+	}
+
+	if failed < 0 {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}
+
+	result, data, err := returnJSONResult(results)
+	warnings := append(notices, s.binaryWarnings(truncatedBinary, nil)...)
+	warnings = append(warnings, s.jsonWarnings(truncatedJSON)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBatchQuery(t *testing.T) {
+	ctx := context.Background()
+	args := BatchQueryArgs{Queries: []string{"SELECT 1 AS one", "SELECT count(*) AS users FROM users", "SELECT 1.50::numeric AS price"}}
+	result, data, err := testServer.BatchQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("BatchQuery failed: %v %v", err, result)
+	}
+	results := data.([]*batchResult)
+	if len(results) != 3 || results[0].Rows[0]["one"] != int32(1) || results[1].RowCount != 1 || results[2].Rows[0]["price"] != "1.50" {
+		t.Errorf("Unexpected results %v", results)
+	}
+
+	args.Queries = []string{"SELECT 1", "SELECT * FROM no_such_table", "SELECT 2"}
+	_, data, err = testServer.BatchQuery(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results = data.([]*batchResult)
+	if results[0].Error != "" || results[0].RowCount != 1 || results[1].Error == "" || results[2].Error != "Not run, query 1 failed first" {
+		t.Errorf("Expected the second query to fail and the third not to run, got %+v %+v %+v", results[0], results[1], results[2])
+	}
+
+	args.Queries = []string{"SELECT 1", "DELETE FROM users"}
+	if result, _, _ := testServer.BatchQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected writes to be refused")
+	}
+
+	args.Queries = []string{"RESET ROLE", "SELECT count(*) FROM users"}
+	if result, _, _ := testServer.BatchQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a role change ahead of a read to be refused")
+	}
+
+	args.Queries = []string{"SET search_path TO pg_catalog", "SELECT 1"}
+	if result, _, _ := testServer.BatchQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected session statements to be refused")
+	}
+}
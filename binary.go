@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// binaryFormats are the ways bytea values can be returned: base64 or hex
+// with their length, or left out of the result.
+var binaryFormats = map[string]bool{"base64": true, "hex": true, "omit": true}
+
+// binaryValue is how a bytea value is returned. Length is that of the whole
+// value, the encoded data stops at MAX_BINARY_BYTES.
+type binaryValue struct {
+	Length    int    `json:"length"`
+	Base64    string `json:"base64,omitempty"`
+	Hex       string `json:"hex,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+func encodeBinary(value []byte, format string, maxBytes int) binaryValue {
+	encoded := binaryValue{Length: len(value)}
+	if maxBytes > 0 && len(value) > maxBytes {
+		value = value[:maxBytes]
+		encoded.Truncated = true
+	}
+	if format == "hex" {
+		encoded.Hex = `\x` + hex.EncodeToString(value)
+	} else {
+		encoded.Base64 = base64.StdEncoding.EncodeToString(value)
+	}
+	return encoded
+}
+
+// encodeBinaryRows replaces the bytea values of result rows in place,
+// including those of bytea arrays, or drops their columns with omit. It
+// returns the columns with truncated values and those left out.
+func encodeBinaryRows(rows []map[string]interface{}, format string, maxBytes int) (truncated, omitted []string) {
+	truncatedColumns := make(map[string]bool)
+	omittedColumns := make(map[string]bool)
+	var encode func(value interface{}, column string) (interface{}, bool)
+	encode = func(value interface{}, column string) (interface{}, bool) {
+		switch v := value.(type) {
+		case []byte:
+			encoded := encodeBinary(v, format, maxBytes)
+			if encoded.Truncated {
+				truncatedColumns[column] = true
+			}
+			return encoded, true
+		case []interface{}:
+			binary := false
+			for i, element := range v {
+				var isBinary bool
+				v[i], isBinary = encode(element, column)
+				binary = binary || isBinary
+			}
+			return v, binary
+		}
+		return value, false
+	}
+
+	for _, row := range rows {
+		for column, value := range row {
+			encoded, binary := encode(value, column)
+			switch {
+			case binary && format == "omit":
+				delete(row, column)
+				omittedColumns[column] = true
+			case binary:
+				row[column] = encoded
+			}
+		}
+	}
+	return sortedKeys(truncatedColumns), sortedKeys(omittedColumns)
+}
+
+// binaryWarnings reports the bytea columns a result shortened or left out,
+// which chunked results list once per chunk.
+func (s *serverState) binaryWarnings(truncated, omitted []string) []string {
+	slices.Sort(truncated)
+	slices.Sort(omitted)
+	truncated, omitted = slices.Compact(truncated), slices.Compact(omitted)
+	var warnings []string
+	if len(truncated) > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("Binary values longer than MAX_BINARY_BYTES (%d) were truncated in: %s"), s.config.MaxBinaryBytes, strings.Join(truncated, ", ")))
+	}
+	if len(omitted) > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("Binary columns were left out of the result: %s"), strings.Join(omitted, ", ")))
+	}
+	return warnings
+}
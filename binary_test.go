@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEncodeBinaryRows(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": 1, "data": []byte("hello world"), "parts": []interface{}{[]byte{0xde, 0xad}, nil}},
+	}
+	truncated, omitted := encodeBinaryRows(rows, "base64", 5)
+	data := rows[0]["data"].(binaryValue)
+	if data.Length != 11 || data.Base64 != "aGVsbG8=" || !data.Truncated {
+		t.Errorf("Expected the first 5 bytes in base64 with the full length, got %+v", data)
+	}
+	if part := rows[0]["parts"].([]interface{})[0].(binaryValue); part.Length != 2 || part.Truncated {
+		t.Errorf("Expected array elements to be encoded, got %+v", part)
+	}
+	if len(truncated) != 1 || truncated[0] != "data" || len(omitted) != 0 {
+		t.Errorf("Expected data to be reported truncated, got %v %v", truncated, omitted)
+	}
+
+	rows = []map[string]interface{}{{"id": 1, "data": []byte{0xde, 0xad}}}
+	encodeBinaryRows(rows, "hex", 0)
+	if data := rows[0]["data"].(binaryValue); data.Hex != `\xdead` {
+		t.Errorf("Expected hex, got %+v", data)
+	}
+
+	rows = []map[string]interface{}{{"id": 1, "data": []byte{0xde, 0xad}}}
+	if _, omitted := encodeBinaryRows(rows, "omit", 0); len(omitted) != 1 || len(rows[0]) != 1 {
+		t.Errorf("Expected the binary column to be left out, got %v %v", rows[0], omitted)
+	}
+}
+
+func TestQueryBinaryFormat(t *testing.T) {
+	ctx := context.Background()
+	args := QueryArgs{Query: `SELECT '\xdeadbeef'::bytea AS data`, BinaryFormat: "hex"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	rows := data.([]map[string]interface{})
+	if value := rows[0]["data"].(binaryValue); value.Hex != `\xdeadbeef` || value.Length != 4 {
+		t.Errorf("Expected the bytea in hex, got %+v", value)
+	}
+}
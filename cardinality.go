@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultTopValues = 5
+	// exactCardinalityRows is the largest table column_cardinality counts
+	// exactly unless asked to
+	exactCardinalityRows = 100000
+	// cardinalitySampleBytes is about how much of a large table is read
+	// for columns ANALYZE has no statistics for
+	cardinalitySampleBytes = 16 << 20
+)
+
+type ColumnCardinalityArgs struct {
+	TableName string   `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Columns   []string `json:"columns,omitempty" jsonschema:"Columns to analyze (default: all)"`
+	TopK      int      `json:"top_k,omitempty" jsonschema:"Most common values to report per column (default: 5)"`
+	Method    string   `json:"method,omitempty" jsonschema:"exact (count every row), estimate (planner statistics, or a sample for columns without them) or auto (exact up to 100000 rows) (default: auto)"`
+}
+
+// cardinalityColumn is a column and what pg_stats knows about it.
+type cardinalityColumn struct {
+	Name        string
+	Type        string
+	HasStats    bool
+	NullFrac    float64
+	NDistinct   float64
+	Values      []string
+	Frequencies []float64
+}
+
+// ColumnCardinality reports distinct counts, NULL fractions and the most
+// common values of a table's columns, for choosing index candidates and
+// join orders. Small tables are counted exactly, large ones are described
+// by their planner statistics, or by a sample of their pages for columns
+// ANALYZE hasn't covered.
+func (s *serverState) ColumnCardinality(ctx context.Context, req *mcp.CallToolRequest, args ColumnCardinalityArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	method := strings.ToLower(args.Method)
+	if method == "" {
+		method = "auto"
+	}
+	if method != "auto" && method != "exact" && method != "estimate" {
+		return s.returnErrorResult("Unknown method %q, use auto, exact or estimate", args.Method)
+	}
+	topK := args.TopK
+	if topK <= 0 {
+		topK = defaultTopValues
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := qualifiedName(schema, tableName)
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// estimated as estimate_row_count does
+	var estimatedRows, tableBytes int64
+	var statsMissing bool
+	err = tx.QueryRow(ctx, `
+		SELECT
+			CASE WHEN c.relkind = 'p' THEN (
+				SELECT COALESCE(SUM(GREATEST(ch.reltuples, 0)), 0)
+				FROM pg_inherits inh
+				JOIN pg_class ch ON ch.oid = inh.inhrelid
+				WHERE inh.inhparent = c.oid
+			) ELSE GREATEST(c.reltuples, 0) END::bigint,
+			c.reltuples < 0,
+			COALESCE((SELECT sum(pg_table_size(p.relid)) FROM pg_partition_tree(c.oid) p), 0)::bigint
+		FROM pg_class c
+		WHERE c.oid = $1::text::regclass
+	`, pgx.Identifier{schema, tableName}.Sanitize()).Scan(&estimatedRows, &statsMissing, &tableBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up %s: %v", table, err)
+	}
+
+	columns, err := s.cardinalityColumns(ctx, tx, schema, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args.Columns) > 0 {
+		byName := make(map[string]cardinalityColumn)
+		for _, column := range columns {
+			byName[column.Name] = column
+		}
+		columns = columns[:0]
+		for _, name := range args.Columns {
+			column, ok := byName[name]
+			if !ok {
+				return s.returnErrorResult("column %q does not exist in %s", name, table)
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	if method == "auto" {
+		method = "estimate"
+		if estimatedRows <= exactCardinalityRows && (!statsMissing || tableBytes <= cardinalitySampleBytes) {
+			method = "exact"
+		}
+	}
+
+	results := make([]map[string]interface{}, len(columns))
+	var counted []int
+	for i, column := range columns {
+		if method == "estimate" && column.HasStats {
+			results[i] = statisticsCardinality(column, estimatedRows, topK)
+		} else {
+			counted = append(counted, i)
+		}
+	}
+	if len(counted) > 0 {
+		// columns without statistics are described from a sample of pages,
+		// REPEATABLE so every query reads the same ones
+		sample := ""
+		if method == "estimate" && tableBytes > cardinalitySampleBytes {
+			percent := math.Max(100*float64(cardinalitySampleBytes)/float64(tableBytes), 0.01)
+			sample = fmt.Sprintf(" TABLESAMPLE SYSTEM (%g) REPEATABLE (0)", percent)
+		}
+		var countColumns []cardinalityColumn
+		for _, i := range counted {
+			countColumns = append(countColumns, columns[i])
+		}
+		counts, err := countCardinality(ctx, tx, table+sample, countColumns, topK)
+		if err != nil {
+			return s.returnErrorResult("Failed to count values: %v", err)
+		}
+		for j, i := range counted {
+			results[i] = counts[j]
+			if sample != "" {
+				results[i]["method"] = "sample"
+				results[i]["distinct_lower_bound"] = true
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	// the most common values are data, masked as the columns' rows would be
+	policies := make(map[string]columnPolicy)
+	masked := make(map[string][]string)
+	for i, column := range columns {
+		results[i]["column"] = column.Name
+		results[i]["type"] = column.Type
+		policy, hasPolicy := s.config.ColumnPolicies.lookup(schema, tableName, column.Name)
+		if hasPolicy && policy.masked() {
+			policies[column.Name] = policy
+		}
+		for _, value := range results[i]["top_values"].([]map[string]interface{}) {
+			if hasPolicy && policy.masked() {
+				value["value"] = policy.apply(value["value"])
+			}
+			row := []map[string]interface{}{{column.Name: value["value"]}}
+			for name, kinds := range s.redactRows(row) {
+				masked[name] = append(masked[name], kinds...)
+			}
+			value["value"] = row[0][column.Name]
+		}
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"table":          table,
+		"estimated_rows": estimatedRows,
+		"method":         method,
+		"columns":        results,
+	})
+	warnings := append(notices, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
+
+// cardinalityColumns lists a table's columns with their pg_stats entries.
+func (s *serverState) cardinalityColumns(ctx context.Context, tx pgx.Tx, schema, table string) ([]cardinalityColumn, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT a.attname::text, format_type(a.atttypid, a.atttypmod), s.attname IS NOT NULL,
+			COALESCE(s.null_frac, 0), COALESCE(s.n_distinct, 0),
+			COALESCE(s.most_common_vals::text::text[], '{}'), COALESCE(s.most_common_freqs, '{}')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		LEFT JOIN pg_stats s ON s.schemaname = $1 AND s.tablename = $2 AND s.attname = a.attname
+			AND s.inherited = (c.relkind = 'p')
+		WHERE a.attrelid = $3::text::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, schema, table, pgx.Identifier{schema, table}.Sanitize())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statistics: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []cardinalityColumn
+	for rows.Next() {
+		var column cardinalityColumn
+		if err := rows.Scan(&column.Name, &column.Type, &column.HasStats, &column.NullFrac, &column.NDistinct, &column.Values, &column.Frequencies); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return columns, nil
+}
+
+// statisticsCardinality describes a column from its pg_stats entry. A
+// negative n_distinct is a fraction of the rows, for columns whose distinct
+// count grows with the table.
+func statisticsCardinality(column cardinalityColumn, rows int64, topK int) map[string]interface{} {
+	distinct := column.NDistinct
+	if distinct < 0 {
+		distinct = -distinct * float64(rows)
+	}
+	top := []map[string]interface{}{}
+	for i, value := range column.Values {
+		if i >= topK || i >= len(column.Frequencies) {
+			break
+		}
+		top = append(top, map[string]interface{}{"value": value, "frequency": column.Frequencies[i]})
+	}
+	return map[string]interface{}{
+		"method":          "statistics",
+		"null_fraction":   column.NullFrac,
+		"distinct_values": int64(math.Round(distinct)),
+		"distinct_ratio":  ratio(distinct, float64(rows)),
+		"top_values":      top,
+	}
+}
+
+// countCardinality counts the NULLs and distinct values of columns in one
+// scan of from, then their most common values column by column. Values are
+// compared as text, so types without equality can be counted too.
+func countCardinality(ctx context.Context, tx pgx.Tx, from string, columns []cardinalityColumn, topK int) ([]map[string]interface{}, error) {
+	selects := []string{"count(*)"}
+	for _, column := range columns {
+		quoted := quoteIdentifier(column.Name)
+		selects = append(selects, fmt.Sprintf("count(%s), count(DISTINCT %s::text)", quoted, quoted))
+	}
+	counts := make([]int64, 1+2*len(columns))
+	targets := make([]any, len(counts))
+	for i := range counts {
+		targets[i] = &counts[i]
+	}
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), from)).Scan(targets...); err != nil {
+		return nil, err
+	}
+	total := counts[0]
+
+	results := make([]map[string]interface{}, len(columns))
+	for i, column := range columns {
+		nonNull, distinct := counts[1+2*i], counts[2+2*i]
+		quoted := quoteIdentifier(column.Name)
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s::text, count(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT %d",
+			quoted, from, quoted, topK))
+		if err != nil {
+			return nil, err
+		}
+		top := []map[string]interface{}{}
+		for rows.Next() {
+			var value string
+			var count int64
+			if err := rows.Scan(&value, &count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			top = append(top, map[string]interface{}{"value": value, "count": count, "frequency": ratio(float64(count), float64(total))})
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		results[i] = map[string]interface{}{
+			"method":          "exact",
+			"null_fraction":   ratio(float64(total-nonNull), float64(total)),
+			"distinct_values": distinct,
+			"distinct_ratio":  ratio(float64(distinct), float64(total)),
+			"top_values":      top,
+		}
+	}
+	return results, nil
+}
+
+// ratio is part/whole, 0 for an empty whole.
+func ratio(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestColumnCardinality(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE counted (id serial PRIMARY KEY, status text, note text);
+		INSERT INTO counted (status, note)
+		SELECT CASE WHEN i % 10 = 0 THEN 'closed' ELSE 'open' END, CASE WHEN i % 2 = 0 THEN 'n' || i END
+		FROM generate_series(1, 1000) i;
+		ANALYZE counted;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE counted")
+
+	for _, method := range []string{"exact", "estimate"} {
+		args := ColumnCardinalityArgs{TableName: "counted", Method: method}
+		result, data, err := testServer.ColumnCardinality(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ColumnCardinality failed: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+		columns := make(map[string]map[string]interface{})
+		for _, column := range data.(map[string]interface{})["columns"].([]map[string]interface{}) {
+			columns[column["column"].(string)] = column
+		}
+
+		status := columns["status"]
+		if status["distinct_values"] != int64(2) {
+			t.Errorf("%s: expected 2 distinct statuses, got %v", method, status["distinct_values"])
+		}
+		if top := status["top_values"].([]map[string]interface{}); len(top) == 0 || top[0]["value"] != "open" {
+			t.Errorf("%s: expected open as the most common status, got %v", method, top)
+		}
+		if nulls := columns["note"]["null_fraction"].(float64); nulls < 0.45 || nulls > 0.55 {
+			t.Errorf("%s: expected half the notes to be NULL, got %v", method, nulls)
+		}
+		if ratio := columns["id"]["distinct_ratio"].(float64); ratio < 0.99 {
+			t.Errorf("%s: expected id to be unique, got a distinct ratio of %v", method, ratio)
+		}
+	}
+}
@@ -0,0 +1,803 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultExactCountThreshold = 10000
+
+type EstimateRowCountArgs struct {
+	TableName      string `json:"table_name,omitempty" jsonschema:"Name of the table, optionally schema-qualified (default: all tables in the schema)"`
+	Schema         string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	ExactThreshold int    `json:"exact_threshold,omitempty" jsonschema:"Tables estimated below this many rows are counted exactly with COUNT(*), tables without statistics are estimated from their size (default: 10000)"`
+}
+
+func (s *serverState) EstimateRowCount(ctx context.Context, req *mcp.CallToolRequest, args EstimateRowCountArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	threshold := args.ExactThreshold
+	if threshold <= 0 {
+		threshold = defaultExactCountThreshold
+	}
+
+	// reltuples is -1 for tables that have never been vacuumed or analyzed (PG14+),
+	// partitioned parents hold no rows themselves so sum their direct children.
+	// Without statistics the rows are estimated from the table's size like the
+	// planner does: pages times the tuples of the columns' widths (32 bytes
+	// for variable-length types) that fit a page, with 28 bytes of tuple
+	// header and line pointer each.
+	query := `
+		SELECT
+			c.relname,
+			c.relkind::text,
+			CASE WHEN c.relkind = 'p' THEN (
+				SELECT COALESCE(SUM(GREATEST(ch.reltuples, 0)), 0)
+				FROM pg_inherits inh
+				JOIN pg_class ch ON ch.oid = inh.inhrelid
+				WHERE inh.inhparent = c.oid
+			) WHEN m.stats_missing THEN (
+				SELECT pg_relation_size(c.oid) / b.size
+					* ((b.size - 24) / (28 + COALESCE(SUM(CASE WHEN t.typlen > 0 THEN t.typlen ELSE 32 END), 0)))
+				FROM pg_attribute a
+				JOIN pg_type t ON t.oid = a.atttypid
+				WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+			) ELSE c.reltuples END::bigint AS estimated_rows,
+			m.stats_missing
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN LATERAL (SELECT c.relkind <> 'p' AND (c.reltuples < 0 OR (c.reltuples = 0 AND c.relpages = 0)) AS stats_missing) m
+		CROSS JOIN LATERAL (SELECT current_setting('block_size')::bigint AS size) b
+		WHERE n.nspname = $1
+			AND c.relkind IN ('r', 'p', 'm')
+			AND ($2 = '' OR c.relname = $2)
+		ORDER BY c.relname
+	`
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if tableName != "" && !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	if !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+	rows, err := s.pool.Query(ctx, query, schema, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to estimate row counts: %v", err)
+	}
+
+	var tables []map[string]interface{}
+	for rows.Next() {
+		var name, relKind string
+		var estimate int64
+		var statsMissing bool
+
+		if err := rows.Scan(&name, &relKind, &estimate, &statsMissing); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, name) {
+			continue
+		}
+
+		tables = append(tables, map[string]interface{}{
+			"table_name":     name,
+			"schema":         schema,
+			"qualified_name": qualifiedName(schema, name),
+			"estimated_rows": estimate,
+			"row_count":      estimate,
+			"is_exact":       false,
+			"stats_missing":  statsMissing,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if tableName != "" && len(tables) == 0 {
+		return s.returnErrorResult("Table %s not found", qualifiedName(schema, tableName))
+	}
+
+	// small tables are cheap enough to count exactly, never analyzed ones are
+	// judged by their size-based estimate. A table that can't be counted,
+	// for want of SELECT or an unpopulated materialized view, keeps its
+	// estimate with the error rather than failing the other tables.
+	for _, table := range tables {
+		if table["estimated_rows"].(int64) >= int64(threshold) {
+			continue
+		}
+
+		var count int64
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s", pgx.Identifier{schema, table["table_name"].(string)}.Sanitize())
+		if err := s.pool.QueryRow(ctx, countQuery).Scan(&count); err != nil {
+			table["error"] = fmt.Sprintf(s.localize("Failed to count rows: %v"), err)
+			continue
+		}
+		table["row_count"] = count
+		table["is_exact"] = true
+	}
+
+	return returnJSONResult(tables)
+}
+
+const defaultSequenceWarnPercent = 75.0
+
+// integerTypeMax is the largest value each integer column type can hold, a
+// bigint sequence feeding an integer column overflows at the column's limit.
+var integerTypeMax = map[string]int64{
+	"smallint": 32767,
+	"integer":  2147483647,
+	"bigint":   9223372036854775807,
+}
+
+type ListSequencesArgs struct {
+	Schema      string  `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	WarnPercent float64 `json:"warn_percent,omitempty" jsonschema:"Flag sequences that have consumed at least this percentage of their range (default: 75)"`
+}
+
+func (s *serverState) ListSequences(ctx context.Context, req *mcp.CallToolRequest, args ListSequencesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	warnPercent := args.WarnPercent
+	if warnPercent <= 0 {
+		warnPercent = defaultSequenceWarnPercent
+	}
+
+	// owning columns come from serial (auto) and identity (internal) dependencies
+	query := `
+		SELECT
+			s.sequencename::text,
+			s.data_type::text,
+			s.start_value,
+			s.min_value,
+			s.max_value,
+			s.increment_by,
+			s.cycle,
+			s.last_value,
+			tc.relname::text,
+			a.attname::text,
+			format_type(a.atttypid, a.atttypmod)
+		FROM pg_sequences s
+		JOIN pg_namespace sn ON sn.nspname = s.schemaname
+		JOIN pg_class sc ON sc.relname = s.sequencename AND sc.relnamespace = sn.oid
+		LEFT JOIN pg_depend d ON d.objid = sc.oid
+			AND d.classid = 'pg_class'::regclass
+			AND d.refclassid = 'pg_class'::regclass
+			AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class tc ON tc.oid = d.refobjid
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE s.schemaname = $1
+		ORDER BY s.sequencename
+	`
+
+	schema := getSchema(args.Schema)
+	if !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+	rows, err := s.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list sequences: %v", err)
+	}
+	defer rows.Close()
+
+	var sequences []map[string]interface{}
+	for rows.Next() {
+		var sequenceName, dataType string
+		var startValue, minValue, maxValue, incrementBy int64
+		var cycle bool
+		var lastValue *int64
+		var ownerTable, ownerColumn, columnType *string
+
+		if err := rows.Scan(&sequenceName, &dataType, &startValue, &minValue, &maxValue, &incrementBy, &cycle,
+			&lastValue, &ownerTable, &ownerColumn, &columnType); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		// owned sequences follow the table that owns them
+		owner := sequenceName
+		if ownerTable != nil {
+			owner = *ownerTable
+		}
+		if !s.relationAllowed(schema, owner) {
+			continue
+		}
+
+		sequence := map[string]interface{}{
+			"sequence_name": sequenceName,
+			"data_type":     dataType,
+			"start_value":   startValue,
+			"min_value":     minValue,
+			"max_value":     maxValue,
+			"increment_by":  incrementBy,
+			"cycle":         cycle,
+		}
+		addOptionalString(sequence, "owner_table", ownerTable)
+		addOptionalString(sequence, "owner_column", ownerColumn)
+		addOptionalString(sequence, "column_type", columnType)
+
+		// the effective ceiling is whichever runs out first, the sequence or the column
+		effectiveMax, effectiveMin := maxValue, minValue
+		if columnType != nil {
+			if columnMax, ok := integerTypeMax[*columnType]; ok {
+				effectiveMax = min(effectiveMax, columnMax)
+				effectiveMin = max(effectiveMin, -columnMax-1)
+			}
+		}
+		sequence["effective_max_value"] = effectiveMax
+		upper, lower := float64(effectiveMax), float64(effectiveMin)
+
+		status := "unused"
+		if lastValue != nil {
+			sequence["last_value"] = *lastValue
+			var consumed, remaining float64
+			if incrementBy > 0 {
+				consumed = (float64(*lastValue) - lower) / (upper - lower) * 100
+				remaining = (upper - float64(*lastValue)) / float64(incrementBy)
+			} else {
+				consumed = (upper - float64(*lastValue)) / (upper - lower) * 100
+				remaining = (float64(*lastValue) - lower) / float64(-incrementBy)
+			}
+			sequence["percent_consumed"] = math.Round(consumed*100) / 100
+			sequence["remaining_values"] = int64(remaining)
+
+			status = "ok"
+			if consumed >= warnPercent && !cycle {
+				status = "warning"
+			}
+			if consumed >= max(90, warnPercent) && !cycle {
+				status = "critical"
+			}
+		}
+		sequence["status"] = status
+		sequence["int4_overflow_risk"] = status != "ok" && status != "unused" &&
+			columnType != nil && (*columnType == "integer" || *columnType == "smallint")
+
+		sequences = append(sequences, sequence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(sequences)
+}
+
+type ListMaterializedViewsArgs struct {
+	Schema string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+func (s *serverState) ListMaterializedViews(ctx context.Context, req *mcp.CallToolRequest, args ListMaterializedViewsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	// REFRESH ... CONCURRENTLY needs a unique index without predicates or expressions
+	query := `
+		SELECT
+			m.matviewname::text,
+			m.ispopulated,
+			m.definition,
+			pg_total_relation_size(c.oid),
+			pg_size_pretty(pg_total_relation_size(c.oid)),
+			c.reltuples::bigint,
+			EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = c.oid AND i.indisunique AND i.indpred IS NULL AND i.indexprs IS NULL
+			)
+		FROM pg_matviews m
+		JOIN pg_namespace n ON n.nspname = m.schemaname
+		JOIN pg_class c ON c.relname = m.matviewname AND c.relnamespace = n.oid
+		WHERE m.schemaname = $1
+		ORDER BY m.matviewname
+	`
+
+	schema := getSchema(args.Schema)
+	if !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+	rows, err := s.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list materialized views: %v", err)
+	}
+	defer rows.Close()
+
+	var views []map[string]interface{}
+	for rows.Next() {
+		var viewName, definition, sizePretty string
+		var isPopulated, canRefreshConcurrently bool
+		var sizeBytes, estimatedRows int64
+
+		if err := rows.Scan(&viewName, &isPopulated, &definition, &sizeBytes, &sizePretty, &estimatedRows, &canRefreshConcurrently); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, viewName) {
+			continue
+		}
+
+		views = append(views, map[string]interface{}{
+			"view_name":                viewName,
+			"is_populated":             isPopulated,
+			"definition":               definition,
+			"size_bytes":               sizeBytes,
+			"size":                     sizePretty,
+			"estimated_rows":           estimatedRows,
+			"can_refresh_concurrently": canRefreshConcurrently,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(views)
+}
+
+type RefreshMaterializedViewArgs struct {
+	ViewName     string `json:"view_name" jsonschema:"Name of the materialized view, optionally schema-qualified"`
+	Schema       string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Concurrently bool   `json:"concurrently,omitempty" jsonschema:"Refresh without locking out readers; requires a unique index and a populated view (default: false)"`
+}
+
+func (s *serverState) RefreshMaterializedView(ctx context.Context, req *mcp.CallToolRequest, args RefreshMaterializedViewArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("refresh_materialized_view")
+	}
+
+	schema, viewName, err := s.resolveTableName(ctx, args.Schema, args.ViewName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, viewName) {
+		return s.returnNotAccessible(qualifiedName(schema, viewName))
+	}
+	var exists bool
+	if err := s.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_matviews WHERE schemaname = $1 AND matviewname = $2)",
+		schema, viewName).Scan(&exists); err != nil {
+		return nil, nil, fmt.Errorf("failed to look up materialized view: %v", err)
+	}
+	if !exists {
+		return s.returnErrorResult("Materialized view %s not found", qualifiedName(schema, viewName))
+	}
+
+	statement := "REFRESH MATERIALIZED VIEW "
+	if args.Concurrently {
+		statement += "CONCURRENTLY "
+	}
+	statement += pgx.Identifier{schema, viewName}.Sanitize()
+
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "refresh_materialized_view",
+			fmt.Sprintf("Refresh materialized view %s", qualifiedName(schema, viewName)), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	start := time.Now()
+	if _, err := tx.Exec(ctx, statement); err != nil {
+		return s.returnErrorResult("Refresh error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	var rowCount int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", pgx.Identifier{schema, viewName}.Sanitize())).Scan(&rowCount); err != nil {
+		return nil, nil, fmt.Errorf("failed to count refreshed rows: %v", err)
+	}
+
+	if err := s.finishWrite(ctx, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return returnJSONResult(s.labelDryRun(map[string]interface{}{
+		"view_name":      viewName,
+		"schema":         schema,
+		"qualified_name": qualifiedName(schema, viewName),
+		"concurrently":   args.Concurrently,
+		"duration_ms":    elapsed.Milliseconds(),
+		"row_count":      rowCount,
+	}))
+}
+
+type ViewDependenciesArgs struct {
+	Name   string `json:"name" jsonschema:"Name of the table or view, optionally schema-qualified"`
+	Schema string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+func (s *serverState) ViewDependencies(ctx context.Context, req *mcp.CallToolRequest, args ViewDependenciesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	// views reference their base relations through the pg_rewrite rule that
+	// implements them, so walk rule -> relation edges until nothing new turns up
+	query := `
+		WITH RECURSIVE deps AS (
+			SELECT r.ev_class AS view_oid, d.refobjid AS parent_oid, 1 AS depth
+			FROM pg_depend d
+			JOIN pg_rewrite r ON r.oid = d.objid
+			WHERE d.classid = 'pg_rewrite'::regclass
+				AND d.refclassid = 'pg_class'::regclass
+				AND d.refobjid = $1::text::regclass
+				AND r.ev_class <> d.refobjid
+			UNION
+			SELECT r.ev_class, d.refobjid, deps.depth + 1
+			FROM deps
+			JOIN pg_depend d ON d.refobjid = deps.view_oid
+				AND d.classid = 'pg_rewrite'::regclass
+				AND d.refclassid = 'pg_class'::regclass
+			JOIN pg_rewrite r ON r.oid = d.objid
+			WHERE r.ev_class <> deps.view_oid AND deps.depth < 100
+		)
+		SELECT
+			vn.nspname::text,
+			vc.relname::text,
+			CASE vc.relkind WHEN 'm' THEN 'materialized view' ELSE 'view' END,
+			deps.depth,
+			pn.nspname || '.' || pc.relname,
+			ARRAY(
+				SELECT DISTINCT a.attname::text
+				FROM pg_rewrite r
+				JOIN pg_depend d ON d.objid = r.oid AND d.classid = 'pg_rewrite'::regclass
+				JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+				WHERE r.ev_class = deps.view_oid AND d.refobjid = deps.parent_oid AND d.refobjsubid > 0
+				ORDER BY 1
+			)
+		FROM deps
+		JOIN pg_class vc ON vc.oid = deps.view_oid
+		JOIN pg_namespace vn ON vn.oid = vc.relnamespace
+		JOIN pg_class pc ON pc.oid = deps.parent_oid
+		JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		ORDER BY deps.depth, vn.nspname, vc.relname
+	`
+
+	schema, name, err := s.resolveTableName(ctx, args.Schema, args.Name)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, name) {
+		return s.returnNotAccessible(qualifiedName(schema, name))
+	}
+
+	rows, err := s.pool.Query(ctx, query, pgx.Identifier{schema, name}.Sanitize())
+	if err != nil {
+		return s.returnErrorResult("Failed to get view dependencies: %v", err)
+	}
+	defer rows.Close()
+
+	var dependents []map[string]interface{}
+	distinct := make(map[string]bool)
+	for rows.Next() {
+		var viewSchema, viewName, viewKind, dependsOn string
+		var depth int
+		var columns []string
+
+		if err := rows.Scan(&viewSchema, &viewName, &viewKind, &depth, &dependsOn, &columns); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(viewSchema, viewName) || !s.qualifiedAllowed(dependsOn) {
+			continue
+		}
+
+		distinct[viewSchema+"."+viewName] = true
+		dependents = append(dependents, map[string]interface{}{
+			"schema":       viewSchema,
+			"view_name":    viewName,
+			"kind":         viewKind,
+			"depth":        depth,
+			"depends_on":   dependsOn,
+			"columns_used": columns,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"object":          qualifiedName(schema, name),
+		"dependent_views": len(distinct),
+		"dependencies":    dependents,
+	})
+}
+
+var typeKinds = map[string]string{
+	"enum":      "e",
+	"composite": "c",
+	"domain":    "d",
+	"range":     "r",
+}
+
+type ListTypesArgs struct {
+	Schema string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Kind   string `json:"kind,omitempty" jsonschema:"Only list types of this kind: enum, composite, domain or range (default: all)"`
+}
+
+func (s *serverState) ListTypes(ctx context.Context, req *mcp.CallToolRequest, args ListTypesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	typtype := ""
+	if args.Kind != "" {
+		var ok bool
+		if typtype, ok = typeKinds[args.Kind]; !ok {
+			return s.returnErrorResult("Unknown kind %q, use enum, composite, domain or range", args.Kind)
+		}
+	}
+
+	// every table also has a composite row type, only standalone CREATE TYPE ... AS is listed
+	query := `
+		SELECT
+			t.typname::text,
+			t.typtype::text,
+			ARRAY(SELECT e.enumlabel::text FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder),
+			ARRAY(
+				SELECT a.attname::text FROM pg_attribute a
+				WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum
+			),
+			ARRAY(
+				SELECT format_type(a.atttypid, a.atttypmod) FROM pg_attribute a
+				WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum
+			),
+			CASE WHEN t.typtype = 'd' THEN format_type(t.typbasetype, t.typtypmod) END,
+			t.typnotnull,
+			t.typdefault,
+			ARRAY(SELECT pg_get_constraintdef(con.oid, true) FROM pg_constraint con WHERE con.contypid = t.oid ORDER BY con.conname),
+			(SELECT format_type(r.rngsubtype, NULL) FROM pg_range r WHERE r.rngtypid = t.oid),
+			obj_description(t.oid, 'pg_type')
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE n.nspname = $1
+			AND t.typtype IN ('e', 'c', 'd', 'r')
+			AND (t.typtype <> 'c' OR c.relkind = 'c')
+			AND ($2 = '' OR t.typtype = $2)
+		ORDER BY t.typtype, t.typname
+	`
+
+	if !s.schemaAllowed(getSchema(args.Schema)) {
+		return s.returnNotAccessible(getSchema(args.Schema))
+	}
+	rows, err := s.pool.Query(ctx, query, getSchema(args.Schema), typtype)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list types: %v", err)
+	}
+	defer rows.Close()
+
+	kindNames := make(map[string]string)
+	for name, code := range typeKinds {
+		kindNames[code] = name
+	}
+
+	var types []map[string]interface{}
+	for rows.Next() {
+		var typeName, typeType string
+		var labels, attributeNames, attributeTypes, checks []string
+		var baseType, typeDefault, subtype, description *string
+		var notNull bool
+
+		if err := rows.Scan(&typeName, &typeType, &labels, &attributeNames, &attributeTypes, &baseType,
+			&notNull, &typeDefault, &checks, &subtype, &description); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		typeInfo := map[string]interface{}{
+			"type_name": typeName,
+			"schema":    getSchema(args.Schema),
+			"kind":      kindNames[typeType],
+		}
+		addOptionalString(typeInfo, "description", description)
+
+		switch typeType {
+		case "e":
+			typeInfo["labels"] = labels
+		case "c":
+			var attributes []map[string]interface{}
+			for i, name := range attributeNames {
+				attributes = append(attributes, map[string]interface{}{
+					"name": name,
+					"type": attributeTypes[i],
+				})
+			}
+			typeInfo["attributes"] = attributes
+		case "d":
+			addOptionalString(typeInfo, "base_type", baseType)
+			addOptionalString(typeInfo, "default", typeDefault)
+			typeInfo["not_null"] = notNull
+			typeInfo["check_constraints"] = checks
+		case "r":
+			addOptionalString(typeInfo, "subtype", subtype)
+		}
+
+		types = append(types, typeInfo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(types)
+}
+
+var partitionStrategies = map[string]string{
+	"h": "hash",
+	"l": "list",
+	"r": "range",
+}
+
+type GetPartitionsArgs struct {
+	TableName string `json:"table_name" jsonschema:"Name of the partitioned table, optionally schema-qualified"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+func (s *serverState) GetPartitions(ctx context.Context, req *mcp.CallToolRequest, args GetPartitionsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := pgx.Identifier{schema, tableName}.Sanitize()
+
+	var strategy, keyDef string
+	var keyColumns []string
+	err = s.pool.QueryRow(ctx, `
+		SELECT
+			pt.partstrat::text,
+			pg_get_partkeydef(pt.partrelid),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(pt.partattrs::int2[]) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = pt.partrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)
+		FROM pg_partitioned_table pt
+		WHERE pt.partrelid = $1::text::regclass
+	`, table).Scan(&strategy, &keyDef, &keyColumns)
+	if err == pgx.ErrNoRows {
+		return s.returnErrorResult("%s is not a partitioned table", qualifiedName(schema, tableName))
+	}
+	if err != nil {
+		return s.returnErrorResult("Failed to get partitions: %v", err)
+	}
+
+	// pg_partition_tree walks sub-partitions too, the root itself is level 0
+	query := `
+		SELECT
+			n.nspname || '.' || c.relname,
+			COALESCE(pn.nspname || '.' || p.relname, ''),
+			tree.level,
+			tree.isleaf,
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), ''),
+			COALESCE(pt.partstrat::text, ''),
+			COALESCE(pg_get_partkeydef(c.oid), ''),
+			GREATEST(c.reltuples, 0)::bigint,
+			pg_total_relation_size(c.oid)
+		FROM pg_partition_tree($1::text::regclass) tree
+		JOIN pg_class c ON c.oid = tree.relid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_class p ON p.oid = tree.parentrelid
+		LEFT JOIN pg_namespace pn ON pn.oid = p.relnamespace
+		LEFT JOIN pg_partitioned_table pt ON pt.partrelid = c.oid
+		WHERE tree.level > 0
+		ORDER BY tree.level, n.nspname, c.relname
+	`
+
+	rows, err := s.pool.Query(ctx, query, table)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get partitions: %v", err)
+	}
+	defer rows.Close()
+
+	rootName := schema + "." + tableName
+	// partitioned nodes without a DEFAULT child reject rows outside their bounds,
+	// hash partitioning covers every value and cannot have a default
+	hasDefault := map[string]bool{}
+	partitionedNodes := []string{}
+	if strategy != "h" {
+		partitionedNodes = append(partitionedNodes, rootName)
+	}
+
+	var partitions []map[string]interface{}
+	var leafCount int
+	var totalRows, totalSize int64
+	for rows.Next() {
+		var name, parent, bound, subStrategy, subKeyDef string
+		var level int
+		var isLeaf bool
+		var estimate, size int64
+
+		if err := rows.Scan(&name, &parent, &level, &isLeaf, &bound, &subStrategy, &subKeyDef, &estimate, &size); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		isDefault := bound == "DEFAULT"
+		if isDefault {
+			hasDefault[parent] = true
+		}
+		if !s.qualifiedAllowed(name) {
+			continue
+		}
+
+		partition := map[string]interface{}{
+			"partition_name": name,
+			"parent":         parent,
+			"level":          level,
+			"is_leaf":        isLeaf,
+			"bound":          bound,
+			"is_default":     isDefault,
+			"estimated_rows": estimate,
+			"size_bytes":     size,
+		}
+		if subStrategy != "" {
+			partition["strategy"] = partitionStrategies[subStrategy]
+			partition["partition_key"] = subKeyDef
+			if subStrategy != "h" {
+				partitionedNodes = append(partitionedNodes, name)
+			}
+		}
+		if isLeaf {
+			leafCount++
+			totalRows += estimate
+			totalSize += size
+		}
+
+		partitions = append(partitions, partition)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	var missingDefault []string
+	for _, node := range partitionedNodes {
+		if !hasDefault[node] {
+			missingDefault = append(missingDefault, node)
+		}
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"table_name":            tableName,
+		"schema":                schema,
+		"qualified_name":        qualifiedName(schema, tableName),
+		"strategy":              partitionStrategies[strategy],
+		"partition_key":         keyDef,
+		"key_columns":           keyColumns,
+		"leaf_partitions":       leafCount,
+		"estimated_rows":        totalRows,
+		"total_size_bytes":      totalSize,
+		"has_default_partition": hasDefault[rootName],
+		"missing_default":       missingDefault,
+		"partitions":            partitions,
+	})
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEstimateRowCount(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("all tables in schema", func(t *testing.T) {
+		args := EstimateRowCountArgs{Schema: "public"}
+		result, data, err := testServer.EstimateRowCount(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("EstimateRowCount failed: %v", err)
+		}
+
+		if result == nil {
+			t.Fatal("Expected result, got nil")
+		}
+
+		tables := data.([]map[string]interface{})
+		tableNames := make(map[string]bool)
+		for _, table := range tables {
+			tableNames[table["table_name"].(string)] = true
+		}
+
+		for _, expectedTable := range []string{"users", "posts", "friendships", "listings", "comments"} {
+			if !tableNames[expectedTable] {
+				t.Errorf("Expected table %s not found", expectedTable)
+			}
+		}
+
+		// views have no storage and should not be reported
+		if tableNames["post_stats"] {
+			t.Error("Expected view post_stats to be excluded")
+		}
+	})
+
+	t.Run("small table is counted exactly", func(t *testing.T) {
+		args := EstimateRowCountArgs{TableName: "users"}
+		_, data, err := testServer.EstimateRowCount(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("EstimateRowCount failed: %v", err)
+		}
+
+		tables := data.([]map[string]interface{})
+		if len(tables) != 1 {
+			t.Fatalf("Expected 1 table, got %d", len(tables))
+		}
+
+		if !tables[0]["is_exact"].(bool) {
+			t.Error("Expected exact count for table below threshold")
+		}
+
+		if tables[0]["row_count"].(int64) != 1000 {
+			t.Errorf("Expected 1000 users, got %v", tables[0]["row_count"])
+		}
+	})
+
+	t.Run("large table without statistics is estimated from its size", func(t *testing.T) {
+		if _, err := testServer.pool.Exec(ctx, `
+			CREATE TABLE unanalyzed_rows (id int, payload text) WITH (autovacuum_enabled = false);
+			INSERT INTO unanalyzed_rows SELECT g, 'x' FROM generate_series(1, 50000) g
+		`); err != nil {
+			t.Fatalf("Failed to create table: %v", err)
+		}
+		defer testServer.pool.Exec(ctx, "DROP TABLE unanalyzed_rows")
+
+		args := EstimateRowCountArgs{TableName: "unanalyzed_rows", ExactThreshold: 1000}
+		_, data, err := testServer.EstimateRowCount(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("EstimateRowCount failed: %v", err)
+		}
+
+		table := data.([]map[string]interface{})[0]
+		if !table["stats_missing"].(bool) || table["is_exact"].(bool) {
+			t.Errorf("Expected an estimate flagged stats_missing, got %v", table)
+		}
+		if estimate := table["estimated_rows"].(int64); estimate < 1000 {
+			t.Errorf("Expected a size-based estimate above the threshold, got %d", estimate)
+		}
+	})
+
+	t.Run("table that can't be counted keeps its estimate", func(t *testing.T) {
+		if _, err := testServer.pool.Exec(ctx, "CREATE MATERIALIZED VIEW unpopulated_rows AS SELECT 1 AS id WITH NO DATA"); err != nil {
+			t.Fatalf("Failed to create materialized view: %v", err)
+		}
+		defer testServer.pool.Exec(ctx, "DROP MATERIALIZED VIEW unpopulated_rows")
+
+		args := EstimateRowCountArgs{Schema: "public"}
+		result, data, err := testServer.EstimateRowCount(ctx, createMockRequest(args), args)
+		if err != nil || result.IsError {
+			t.Fatalf("EstimateRowCount failed: %v %v", err, result)
+		}
+		counted := 0
+		for _, table := range data.([]map[string]interface{}) {
+			switch {
+			case table["table_name"] == "unpopulated_rows":
+				if table["error"] == nil || table["is_exact"].(bool) {
+					t.Errorf("Expected the unpopulated view to report its error, got %v", table)
+				}
+			case table["is_exact"].(bool):
+				counted++
+			}
+		}
+		if counted == 0 {
+			t.Error("Expected the other tables to be counted")
+		}
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		args := EstimateRowCountArgs{TableName: "does_not_exist"}
+		result, _, err := testServer.EstimateRowCount(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("EstimateRowCount failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for unknown table")
+		}
+	})
+}
+
+func TestListSequences(t *testing.T) {
+	ctx := context.Background()
+
+	args := ListSequencesArgs{Schema: "public"}
+	result, data, err := testServer.ListSequences(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ListSequences failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	sequences := data.([]map[string]interface{})
+	var usersSequence map[string]interface{}
+	for _, sequence := range sequences {
+		if sequence["sequence_name"] == "users_id_seq" {
+			usersSequence = sequence
+		}
+	}
+
+	if usersSequence == nil {
+		t.Fatal("Expected users_id_seq to be listed")
+	}
+
+	if usersSequence["owner_table"] != "users" || usersSequence["owner_column"] != "id" {
+		t.Errorf("Expected users_id_seq to be owned by users.id, got %v.%v", usersSequence["owner_table"], usersSequence["owner_column"])
+	}
+
+	if usersSequence["status"] != "ok" {
+		t.Errorf("Expected status ok, got %v", usersSequence["status"])
+	}
+
+	if usersSequence["effective_max_value"].(int64) != 2147483647 {
+		t.Errorf("Expected serial column to be capped at int4 max, got %v", usersSequence["effective_max_value"])
+	}
+}
+
+func TestListMaterializedViews(t *testing.T) {
+	ctx := context.Background()
+
+	args := ListMaterializedViewsArgs{}
+	result, data, err := testServer.ListMaterializedViews(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ListMaterializedViews failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Expected result, got nil")
+	}
+
+	views := data.([]map[string]interface{})
+	if len(views) != 1 {
+		t.Fatalf("Expected 1 materialized view, got %d", len(views))
+	}
+
+	if views[0]["view_name"] != "listing_category_stats" {
+		t.Errorf("Expected listing_category_stats, got %v", views[0]["view_name"])
+	}
+
+	if !views[0]["can_refresh_concurrently"].(bool) {
+		t.Error("Expected view with unique index to support concurrent refresh")
+	}
+}
+
+func TestRefreshMaterializedView(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("blocked when writes are disabled", func(t *testing.T) {
+		args := RefreshMaterializedViewArgs{ViewName: "listing_category_stats"}
+		result, _, err := testServer.RefreshMaterializedView(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("RefreshMaterializedView failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected refresh to be blocked without ALLOW_WRITES")
+		}
+	})
+
+	t.Run("concurrent refresh", func(t *testing.T) {
+		testServer.config.AllowWrites = true
+		defer func() { testServer.config.AllowWrites = false }()
+
+		args := RefreshMaterializedViewArgs{ViewName: "listing_category_stats", Concurrently: true}
+		result, data, err := testServer.RefreshMaterializedView(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("RefreshMaterializedView failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful refresh, got %v", result)
+		}
+
+		// 8 categories are seeded
+		if rowCount := data.(map[string]interface{})["row_count"].(int64); rowCount != 8 {
+			t.Errorf("Expected 8 rows after refresh, got %d", rowCount)
+		}
+	})
+
+	t.Run("dry run refresh is rolled back", func(t *testing.T) {
+		testServer.config.DryRun = true
+		defer func() { testServer.config.DryRun = false }()
+
+		if _, err := testServer.pool.Exec(ctx, "INSERT INTO listings (user_id, title, price, category) VALUES (1, 'Dry run', 1, 'dry_run_category')"); err != nil {
+			t.Fatalf("Failed to insert listing: %v", err)
+		}
+		defer testServer.pool.Exec(ctx, "DELETE FROM listings WHERE category = 'dry_run_category'")
+
+		args := RefreshMaterializedViewArgs{ViewName: "listing_category_stats"}
+		result, data, err := testServer.RefreshMaterializedView(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("RefreshMaterializedView failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected simulated refresh without ALLOW_WRITES, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		if response["simulated"] != true {
+			t.Error("Expected response to be labeled as simulated")
+		}
+		if rowCount := response["row_count"].(int64); rowCount != 9 {
+			t.Errorf("Expected 9 rows inside the simulated refresh, got %d", rowCount)
+		}
+
+		var persisted int64
+		if err := testServer.pool.QueryRow(ctx, "SELECT COUNT(*) FROM listing_category_stats").Scan(&persisted); err != nil {
+			t.Fatalf("Failed to count materialized view rows: %v", err)
+		}
+		if persisted != 8 {
+			t.Errorf("Expected the refresh to be rolled back, got %d rows", persisted)
+		}
+	})
+}
+
+func TestViewDependencies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("views depending on users", func(t *testing.T) {
+		args := ViewDependenciesArgs{Name: "users"}
+		result, data, err := testServer.ViewDependencies(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ViewDependencies failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		var postStats map[string]interface{}
+		for _, dependency := range response["dependencies"].([]map[string]interface{}) {
+			if dependency["view_name"] == "post_stats" {
+				postStats = dependency
+			}
+		}
+
+		if postStats == nil {
+			t.Fatal("Expected post_stats to depend on users")
+		}
+
+		columns := postStats["columns_used"].([]string)
+		if len(columns) != 2 || columns[0] != "id" || columns[1] != "username" {
+			t.Errorf("Expected post_stats to use users.id and users.username, got %v", columns)
+		}
+	})
+
+	t.Run("materialized view dependency", func(t *testing.T) {
+		args := ViewDependenciesArgs{Name: "listings"}
+		_, data, err := testServer.ViewDependencies(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ViewDependencies failed: %v", err)
+		}
+
+		dependencies := data.(map[string]interface{})["dependencies"].([]map[string]interface{})
+		if len(dependencies) != 1 || dependencies[0]["kind"] != "materialized view" {
+			t.Errorf("Expected listing_category_stats materialized view, got %v", dependencies)
+		}
+	})
+
+	t.Run("unknown object", func(t *testing.T) {
+		args := ViewDependenciesArgs{Name: "does_not_exist"}
+		result, _, err := testServer.ViewDependencies(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ViewDependencies failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for unknown object")
+		}
+	})
+}
+
+func TestListTypes(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("list all custom types", func(t *testing.T) {
+		args := ListTypesArgs{}
+		result, data, err := testServer.ListTypes(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ListTypes failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		byName := make(map[string]map[string]interface{})
+		for _, typeInfo := range data.([]map[string]interface{}) {
+			byName[typeInfo["type_name"].(string)] = typeInfo
+		}
+
+		// table row types must not be listed as composites
+		if _, ok := byName["users"]; ok {
+			t.Error("Expected table row type users to be excluded")
+		}
+
+		condition, ok := byName["listing_condition"]
+		if !ok || condition["kind"] != "enum" {
+			t.Fatalf("Expected enum listing_condition, got %v", condition)
+		}
+		labels := condition["labels"].([]string)
+		if len(labels) != 4 || labels[0] != "new" || labels[3] != "for_parts" {
+			t.Errorf("Expected enum labels in sort order, got %v", labels)
+		}
+
+		priceRange, ok := byName["price_range"]
+		if !ok || len(priceRange["attributes"].([]map[string]interface{})) != 2 {
+			t.Errorf("Expected composite price_range with 2 attributes, got %v", priceRange)
+		}
+
+		domain, ok := byName["positive_price"]
+		if !ok || domain["not_null"] != true || len(domain["check_constraints"].([]string)) != 1 {
+			t.Errorf("Expected NOT NULL domain positive_price with one check, got %v", domain)
+		}
+
+		rangeType, ok := byName["price_interval"]
+		if !ok || rangeType["subtype"] != "numeric" {
+			t.Errorf("Expected range price_interval over numeric, got %v", rangeType)
+		}
+	})
+
+	t.Run("filter by kind", func(t *testing.T) {
+		args := ListTypesArgs{Kind: "enum"}
+		_, data, err := testServer.ListTypes(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ListTypes failed: %v", err)
+		}
+
+		for _, typeInfo := range data.([]map[string]interface{}) {
+			if typeInfo["kind"] != "enum" {
+				t.Errorf("Expected only enums, got %v", typeInfo)
+			}
+		}
+	})
+
+	t.Run("unknown kind", func(t *testing.T) {
+		args := ListTypesArgs{Kind: "table"}
+		result, _, err := testServer.ListTypes(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ListTypes failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for unknown kind")
+		}
+	})
+}
+
+func TestGetPartitions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("partition hierarchy", func(t *testing.T) {
+		args := GetPartitionsArgs{TableName: "events"}
+		result, data, err := testServer.GetPartitions(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("GetPartitions failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		if response["strategy"] != "range" {
+			t.Errorf("Expected range partitioning, got %v", response["strategy"])
+		}
+
+		keyColumns := response["key_columns"].([]string)
+		if len(keyColumns) != 1 || keyColumns[0] != "occurred_on" {
+			t.Errorf("Expected key column occurred_on, got %v", keyColumns)
+		}
+
+		if response["leaf_partitions"].(int) != 3 {
+			t.Errorf("Expected 3 leaf partitions, got %v", response["leaf_partitions"])
+		}
+
+		partitions := response["partitions"].([]map[string]interface{})
+		if len(partitions) != 4 {
+			t.Fatalf("Expected 4 partitions including the sub-partitioned parent, got %d", len(partitions))
+		}
+
+		for _, partition := range partitions {
+			if partition["partition_name"] == "public.events_2025" && partition["strategy"] != "hash" {
+				t.Errorf("Expected events_2025 to be hash sub-partitioned, got %v", partition)
+			}
+		}
+
+		// hash sub-partitions cannot have a default, only the range root is flagged
+		missing := response["missing_default"].([]string)
+		if response["has_default_partition"] != false || len(missing) != 1 || missing[0] != "public.events" {
+			t.Errorf("Expected missing default on public.events only, got %v", missing)
+		}
+	})
+
+	t.Run("regular table", func(t *testing.T) {
+		args := GetPartitionsArgs{TableName: "users"}
+		result, _, err := testServer.GetPartitions(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("GetPartitions failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for a table that is not partitioned")
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readChunks runs a read through a cursor, fetching chunk_rows rows at a
+// time and encoding each chunk into its own content block as soon as it is
+// read, so only one chunk of rows is held at once. Reading stops early once
+// the encoded result reaches MAX_RESULT_BYTES. The structured result is a
+// summary: repeating the rows there would hold them twice.
+func (s *serverState) readChunks(ctx context.Context, tx pgx.Tx, args QueryArgs, query string, params []any, warnings []string) (*mcp.CallToolResult, any, error) {
+	if _, err := tx.Exec(ctx, "DECLARE chunked_result NO SCROLL CURSOR FOR "+query, params...); err != nil {
+		if serializationFailure(err) {
+			return nil, nil, err
+		}
+		return s.returnErrorResult("Query error: %v. chunk_rows only applies to statements returning rows, such as SELECT", err)
+	}
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM chunked_result", args.ChunkRows)
+
+	var content []mcp.Content
+	var policies map[string]columnPolicy
+	var geometries map[string]geometryColumn
+	var composites map[string]string
+	masked := make(map[string][]string)
+	var truncatedBinary, omittedBinary, truncatedJSON, jsonColumnNames []string
+	total, size, truncated := 0, 0, false
+	for first := true; ; first = false {
+		chunk, fields, err := fetchChunk(ctx, tx, fetch)
+		if serializationFailure(err) {
+			return nil, nil, err
+		} else if err != nil {
+			return s.returnErrorResult("Query error: %v", err)
+		}
+		// the cursor is idle between fetches, so the catalogs can be read
+		if first {
+			if policies, err = s.resultColumnPolicies(ctx, tx, fields); err != nil {
+				return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+			}
+			if geometries, err = geometryColumns(ctx, tx, tx.Conn().TypeMap(), fields); err != nil {
+				return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+			}
+			if composites, err = compositeColumns(ctx, tx, tx.Conn().TypeMap(), fields); err != nil {
+				return nil, nil, fmt.Errorf("failed to convert composite values: %v", err)
+			}
+			jsonColumnNames = jsonColumns(fields)
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		if err := renderGeometryRows(ctx, tx, geometries, args.GeometryFormat, chunk); err != nil {
+			return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+		}
+		if err := renderCompositeRows(ctx, tx, composites, chunk); err != nil {
+			return nil, nil, fmt.Errorf("failed to convert composite values: %v", err)
+		}
+		truncatedColumns, omittedColumns := encodeBinaryRows(chunk, args.BinaryFormat, s.config.MaxBinaryBytes)
+		truncatedBinary = append(truncatedBinary, truncatedColumns...)
+		omittedBinary = append(omittedBinary, omittedColumns...)
+		truncatedColumns, err = shapeJSONRows(chunk, jsonColumnNames, args.JSONPaths, args.JSONFormat, s.config.MaxJSONBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		truncatedJSON = append(truncatedJSON, truncatedColumns...)
+		applyColumnPolicies(chunk, policies)
+		for column, kinds := range s.redactRows(chunk) {
+			masked[column] = append(masked[column], kinds...)
+		}
+		encodeNumericRows(chunk, args.NumericFormat)
+		encodeIntervalRows(chunk, args.IntervalFormat)
+		encoded, err := json.MarshalIndent(chunk, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal results: %v", err)
+		}
+		content = append(content, &mcp.TextContent{Text: string(encoded)})
+		total += len(chunk)
+		size += len(encoded)
+		if len(chunk) < args.ChunkRows {
+			break
+		}
+		if s.config.MaxResultBytes > 0 && size >= s.config.MaxResultBytes {
+			truncated = true
+			break
+		}
+	}
+	recordRowsScanned(ctx, tx)
+
+	if err := tx.Commit(ctx); serializationFailure(err) {
+		return nil, nil, err
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	summary := map[string]interface{}{
+		"rows":       total,
+		"chunks":     len(content),
+		"chunk_rows": args.ChunkRows,
+		"truncated":  truncated,
+	}
+	encoded, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal results: %v", err)
+	}
+	result := &mcp.CallToolResult{Content: append(content, &mcp.TextContent{Text: string(encoded)})}
+
+	warnings = append(warnings, s.binaryWarnings(truncatedBinary, omittedBinary)...)
+	warnings = append(warnings, s.jsonWarnings(truncatedJSON)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	warnings = append(warnings, s.piiWarnings(masked)...)
+	if truncated {
+		warnings = append(warnings, fmt.Sprintf(s.localize("The result was cut off after %d rows, at the MAX_RESULT_BYTES limit of %d bytes. Add a LIMIT or narrow the query"), total, s.config.MaxResultBytes))
+	}
+	return s.withWarnings(result, warnings), summary, nil
+}
+
+// fetchChunk runs one FETCH and collects its rows.
+func fetchChunk(ctx context.Context, tx pgx.Tx, fetch string) ([]map[string]interface{}, []pgconn.FieldDescription, error) {
+	rows, err := tx.Query(ctx, fetch)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// the connection reuses the descriptions for the next statement
+	fields := slices.Clone(rows.FieldDescriptions())
+	var chunk []map[string]interface{}
+	for rows.Next() {
+		values, err := rowValues(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make(map[string]interface{}, len(fields))
+		for i, field := range fields {
+			row[field.Name] = values[i]
+		}
+		chunk = append(chunk, row)
+	}
+	return chunk, fields, rows.Err()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryChunks(t *testing.T) {
+	ctx := context.Background()
+	args := QueryArgs{Query: "SELECT n FROM generate_series(1, 25) AS n", ChunkRows: 10}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	summary := data.(map[string]interface{})
+	if summary["rows"] != 25 || summary["chunks"] != 3 || summary["truncated"] != false {
+		t.Errorf("Expected 25 rows in 3 chunks, got %v", summary)
+	}
+	if len(result.Content) != 4 {
+		t.Errorf("Expected 3 chunks and a summary, got %d content blocks", len(result.Content))
+	}
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.MaxResultBytes = 1
+	_, data, err = testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if summary := data.(map[string]interface{}); summary["rows"] != 10 || summary["truncated"] != true {
+		t.Errorf("Expected reading to stop after the first chunk, got %v", summary)
+	}
+
+	args = QueryArgs{Query: "SELECT 1", ChunkRows: 10, Expanded: true}
+	if result, _, _ := testServer.ExecuteQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected chunk_rows with expanded to be rejected")
+	}
+}
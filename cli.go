@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// configFlags mirror the environment variables read by loadConfig. A flag
+// that is set overrides its variable.
+var configFlags = []struct {
+	Env   string
+	Usage string
+}{
+	{"DATABASE_URL", "Connection string of the database"},
+	{"ALLOW_WRITES", "Enable tools that modify the database (true/false)"},
+	{"REQUIRE_APPROVAL", "Queue writes until approved with approve_change (true/false)"},
+	{"APPROVAL_WEBHOOK_URL", "Where approval tokens and change events are POSTed"},
+	{"DRY_RUN", "Roll back every write and label responses as simulated (true/false)"},
+	{"ENCRYPTION_KEY", "Key encrypting written files, 32 bytes as hex or base64"},
+	{"ANONYMIZE_KEY", "Key of the HMAC behind anonymized values, 32 bytes as hex or base64 (default: random per run)"},
+	{"EXPORT_DIR", "Directory tools write files to and read backups from, output_path and input_path being relative to it"},
+	{"CONNECT_RETRY_TIMEOUT", "How long startup retries to reach the database, e.g. 1m"},
+	{"LAZY_CONNECT", "Start even when the database is unreachable and connect in the background (true/false)"},
+	{"ACTIVITY_SAMPLE_INTERVAL", "Record pg_stat_activity snapshots this often for get_activity_history, e.g. 10s"},
+	{"ACTIVITY_SAMPLE_RETENTION", "How much activity history to keep in memory, e.g. 1h"},
+	{"METADATA_CACHE_INTERVAL", "Cache list_tables, get_table_schema, constraints and indexes, checking the catalogs for changes this often, e.g. 5s"},
+	{"SLOW_QUERY_THRESHOLD", "Record queries activity samples see running longer than this for recent_slow_queries (default 1s)"},
+	{"DB_MAX_CONNS", "Maximum pool connections"},
+	{"DB_MIN_CONNS", "Minimum pool connections"},
+	{"DB_MAX_CONN_LIFETIME", "Maximum lifetime of a pool connection, e.g. 1h"},
+	{"DB_MAX_CONN_IDLE_TIME", "Maximum idle time of a pool connection, e.g. 30m"},
+	{"SANDBOX_SCHEMA", "Schema for scratch tables of demonstration tools"},
+	{"AUTO_ANALYZE_ROWS", "Run ANALYZE after writes affecting at least this many rows"},
+	{"ROLE", "Role to switch to with SET ROLE after connecting"},
+	{"REDACT_PII", "Mask values looking like PII in returned rows (true/false)"},
+	{"LOCALE", "Language of tool descriptions and messages: en, es, de or ja"},
+	{"AUDIT_LOG", "Audit log destination, stderr or a file path"},
+	{"LOG_SQL", "How much SQL the audit log keeps: full, redacted or none"},
+	{"ALLOWED_SCHEMAS", "Comma-separated schemas the tools may access"},
+	{"DENIED_SCHEMAS", "Comma-separated schemas the tools may not access"},
+	{"ALLOWED_TABLES", "Comma-separated tables the tools may access"},
+	{"DENIED_TABLES", "Comma-separated tables the tools may not access"},
+	{"QUERY_POLICY", "Per-category query policy, e.g. dml=confirm,maintenance=allow"},
+	{"IN_LIST_THRESHOLD", "Send literal IN lists of this many values as an array parameter"},
+	{"MAX_BINARY_BYTES", "Bytes of each bytea value query results include (default 1024, 0 for all)"},
+	{"MAX_JSON_BYTES", "Cut off json and jsonb values in query results above this many bytes (default 64 KiB, 0 for no limit)"},
+	{"MAX_RESULT_BYTES", "Stop reading a chunk_rows query result at this many bytes of JSON (default 64 MiB, 0 for no limit)"},
+	{"SERIALIZATION_RETRIES", "Retries of query transactions that fail to serialize"},
+	{"TRANSPORT", "How clients connect: stdio, http or sse"},
+	{"HTTP_ADDR", "Listen address of the http and sse transports (default 127.0.0.1:8080)"},
+	{"HTTP_AUTH_TOKEN", "Bearer token clients of the http and sse transports must send"},
+	{"TLS_CERT_FILE", "Certificate to serve HTTPS with"},
+	{"TLS_KEY_FILE", "Key of the TLS certificate"},
+	{"TLS_CLIENT_CA_FILE", "CAs client certificates must be signed by (mutual TLS)"},
+	{"DB_TLS_CA_FILE", "CA certificates the database server certificate must be signed by"},
+	{"DB_TLS_CERT_FILE", "Client certificate to authenticate to the database with"},
+	{"DB_TLS_KEY_FILE", "Key of the client certificate"},
+	{"DB_TLS_VERIFY_FULL", "Also verify the database host name against its certificate (true/false)"},
+	{"DB_TLS_MIN_VERSION", "Lowest TLS version to the database: 1.2 or 1.3"},
+	{"ALLOW_INSECURE", "Allow database connections without TLS (true/false)"},
+	{"DB_SOCKET_DIR", "Connect through the Unix socket in this directory instead of TCP"},
+	{"PROFILES_FILE", "JSON file of named databases, each with its own safety policy"},
+	{"PROFILE", "Profile of PROFILES_FILE to use by default instead of DATABASE_URL"},
+	{"COLUMN_POLICY_FILE", "JSON file of semantic column types (email, money, ...) that drive masking and formatting"},
+	{"SAVED_QUERIES_FILE", "JSON file of named queries whose plans check_plan_regressions compares with their baselines"},
+	{"PLAN_STORE_FILE", "JSON file save_plan_baseline records plan baselines in, keyed by query fingerprint"},
+	{"DATA_CHECKS_FILE", "JSON file of named data quality rules run_data_checks evaluates"},
+	{"SERVER_LOG", "PostgreSQL log file or log directory recent_errors reads errors from"},
+}
+
+// commandDescriptions are listed by the usage message.
+var commandDescriptions = map[string]string{
+	"serve":            "Run the MCP server (default)",
+	"check-connection": "Validate the connection and credentials, print what the server can do and exit",
+	"list-tools":       "Print the available tools and exit, without connecting",
+}
+
+// flagName turns an environment variable into its flag, ALLOW_WRITES into
+// allow-writes.
+func flagName(env string) string {
+	return strings.ReplaceAll(strings.ToLower(env), "_", "-")
+}
+
+// newFlagSet creates a subcommand's flag set with every configuration flag.
+// The returned function copies flags that were set into the environment, for
+// loadConfig to read.
+func newFlagSet(command string) (*flag.FlagSet, func() error) {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	values := make(map[string]*string, len(configFlags))
+	for _, option := range configFlags {
+		values[option.Env] = fs.String(flagName(option.Env), "", fmt.Sprintf("%s (env %s)", option.Usage, option.Env))
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: postgres-mcp %s [flags]\n\n%s\n\nFlags:\n", command, commandDescriptions[command])
+		fs.PrintDefaults()
+	}
+
+	apply := func() error {
+		var err error
+		fs.Visit(func(f *flag.Flag) {
+			for env, value := range values {
+				if f.Name == flagName(env) && err == nil {
+					err = os.Setenv(env, *value)
+				}
+			}
+		})
+		return err
+	}
+	return fs, apply
+}
+
+// splitCommand picks the subcommand from the arguments. Without one the
+// server is started, so existing invocations (postgres-mcp -role x) work.
+func splitCommand(args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "serve", args, nil
+	}
+	if _, ok := commandDescriptions[args[0]]; !ok {
+		return "", nil, fmt.Errorf("unknown command %q", args[0])
+	}
+	return args[0], args[1:], nil
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: postgres-mcp <command> [flags]\n\nCommands:")
+	commands := sortedKeys(commandDescriptions)
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", command, commandDescriptions[command])
+	}
+	fmt.Fprintln(w, "\nRun postgres-mcp <command> -h for the flags of a command.")
+}
+
+// databaseConfig reads the connection string and configuration, after flags
+// were applied to the environment. With a profile selected its database is
+// used and DATABASE_URL is not needed.
+func databaseConfig() (Config, *pgxpool.Config, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if config.Profile != "" {
+		return config, nil, nil
+	}
+
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		connStr = os.Getenv("POSTGRES_URL")
+	}
+	if connStr == "" && config.DBSocketDir == "" {
+		return Config{}, nil, fmt.Errorf("DATABASE_URL or POSTGRES_URL environment variable (or -database-url, -db-socket-dir or -profile) must be set")
+	}
+	// without a URL, the database and user come from PGDATABASE and PGUSER
+	// or default to the operating system user, as peer authentication expects
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("failed to parse database URL: %v", err)
+	}
+	if config.DBSocketDir != "" {
+		if err := applySocketDir(&poolConfig.ConnConfig.Config, config.DBSocketDir); err != nil {
+			return Config{}, nil, err
+		}
+	}
+	return config, poolConfig, nil
+}
+
+// runCheckConnection connects, runs the verify_installation checks and
+// prints them. It fails when any check fails.
+func runCheckConnection(args []string, w io.Writer) error {
+	fs, apply := newFlagSet("check-connection")
+	fs.Parse(args)
+	if err := apply(); err != nil {
+		return err
+	}
+
+	config, poolConfig, err := databaseConfig()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	state, err := connectProfiles(ctx, config, poolConfig)
+	if err != nil {
+		return err
+	}
+	defer state.Close()
+
+	_, data, err := state.VerifyInstallation(ctx, nil, VerifyInstallationArgs{})
+	if err != nil {
+		return err
+	}
+	report := data.(map[string]interface{})
+	for _, check := range report["checks"].([]verifyCheck) {
+		fmt.Fprintf(w, "%-4s %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Detail)
+	}
+	if !report["passed"].(bool) {
+		return fmt.Errorf("some checks failed")
+	}
+	return nil
+}
+
+// runListTools prints every tool with its description in the configured
+// locale. No database connection is needed.
+func runListTools(args []string, w io.Writer) error {
+	fs, apply := newFlagSet("list-tools")
+	fs.Parse(args)
+	if err := apply(); err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %v", err)
+	}
+
+	tools, err := (&serverState{config: config}).listTools(context.Background())
+	if err != nil {
+		return err
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, tool := range tools {
+		fmt.Fprintf(tw, "%s\t%s\n", tool.Name, tool.Description)
+	}
+	return tw.Flush()
+}
+
+// listTools asks a server built from this state for its tools over an
+// in-memory connection, so the list matches what clients see.
+func (s *serverState) listTools(ctx context.Context) ([]*mcp.Tool, error) {
+	server := s.newMCPServer()
+	s.addTools(server)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "postgres-mcp", Version: "v1.0.0"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer clientSession.Close()
+
+	result, err := clientSession.ListTools(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tools, nil
+}
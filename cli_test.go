@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		rest    int
+	}{
+		{nil, "serve", 0},
+		{[]string{"-role", "reader"}, "serve", 2},
+		{[]string{"serve", "-self-test"}, "serve", 1},
+		{[]string{"check-connection"}, "check-connection", 0},
+		{[]string{"list-tools", "-locale", "de"}, "list-tools", 2},
+	}
+	for _, test := range tests {
+		command, rest, err := splitCommand(test.args)
+		if err != nil || command != test.command || len(rest) != test.rest {
+			t.Errorf("splitCommand(%v) = %q, %v, %v", test.args, command, rest, err)
+		}
+	}
+	if _, _, err := splitCommand([]string{"migrate"}); err == nil {
+		t.Error("Expected an unknown command to be rejected")
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	// t.Setenv restores the variables apply changes
+	t.Setenv("ALLOW_WRITES", "false")
+	t.Setenv("DENIED_TABLES", "")
+	t.Setenv("LOCALE", "es")
+
+	fs, apply := newFlagSet("serve")
+	if err := fs.Parse([]string{"-allow-writes", "true", "-denied-tables", "public.secrets"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := apply(); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if !config.AllowWrites || len(config.DeniedTables) != 1 || config.Locale != "es" {
+		t.Errorf("Expected flags to override the environment and unset flags to keep it, got %+v", config)
+	}
+}
+
+func TestListToolsCommand(t *testing.T) {
+	var out bytes.Buffer
+	if err := runListTools(nil, &out); err != nil {
+		t.Fatalf("runListTools failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	found := false
+	for _, line := range lines {
+		found = found || strings.HasPrefix(line, "query ")
+	}
+	if !found || len(lines) < 20 {
+		t.Errorf("Expected every tool to be listed, got:\n%s", out.String())
+	}
+}
+
+func TestCheckConnectionCommand(t *testing.T) {
+	t.Setenv("DATABASE_URL", testServer.pool.Config().ConnString())
+	t.Setenv("ALLOW_INSECURE", "true")
+
+	var out bytes.Buffer
+	if err := runCheckConnection(nil, &out); err != nil {
+		t.Fatalf("runCheckConnection failed: %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "PASS connectivity") {
+		t.Errorf("Expected a connectivity check, got:\n%s", out.String())
+	}
+}
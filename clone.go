@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxDatabaseName is PostgreSQL's identifier limit, NAMEDATALEN - 1.
+const maxDatabaseName = 63
+
+// databaseClones are the databases a state made with clone_database, and
+// the clone each MCP session's tool calls are sent to.
+type databaseClones struct {
+	mu      sync.Mutex
+	states  map[string]*serverState
+	targets map[string]string
+}
+
+type CloneDatabaseArgs struct {
+	Name   string `json:"name,omitempty" jsonschema:"Name of the new database (default: the current database's name with _sandbox appended)"`
+	Target bool   `json:"target,omitempty" jsonschema:"Run this session's later tool calls against the clone until drop_clone (default: true)"`
+}
+
+type DropCloneArgs struct {
+	Name string `json:"name" jsonschema:"Clone to drop, one made by clone_database"`
+}
+
+// cloneBase is the state a clone was made from, or the state itself.
+func (s *serverState) cloneBase() *serverState {
+	if s.cloneOf != nil {
+		return s.cloneOf
+	}
+	return s
+}
+
+// cloneFor returns the clone the call's session targets, or this state.
+func (s *serverState) cloneFor(req *mcp.CallToolRequest) *serverState {
+	s.clones.mu.Lock()
+	defer s.clones.mu.Unlock()
+	if name, ok := s.clones.targets[sessionKey(req)]; ok {
+		return s.clones.states[name]
+	}
+	return s
+}
+
+// CloneDatabase copies the database with CREATE DATABASE ... TEMPLATE and
+// points the session's later tool calls at the copy, so destructive
+// experiments run on a throwaway database. The clone keeps the original's
+// policy. With REQUIRE_APPROVAL the CREATE DATABASE is queued, and calling
+// again once it was approved connects to the clone.
+func (s *serverState) CloneDatabase(ctx context.Context, req *mcp.CallToolRequest, args CloneDatabaseArgs) (*mcp.CallToolResult, any, error) {
+	s = s.cloneBase()
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("clone_database")
+	}
+	target := getExplicitBool(getRawArgs(req), "target", args.Target, true)
+
+	var source string
+	if err := s.pool.QueryRow(ctx, "SELECT current_database()").Scan(&source); err != nil {
+		return nil, nil, fmt.Errorf("failed to look up the current database: %v", err)
+	}
+	name := args.Name
+	if name == "" {
+		name = source + "_sandbox"
+	}
+	if len(name) > maxDatabaseName {
+		return s.returnErrorResult("Database names are at most %d bytes, %q is %d", maxDatabaseName, name, len(name))
+	}
+	if name == source {
+		return s.returnErrorResult("The clone needs a name other than %s", source)
+	}
+
+	s.clones.mu.Lock()
+	_, exists := s.clones.states[name]
+	s.clones.mu.Unlock()
+	if !exists {
+		if s.config.DryRun {
+			return returnJSONResult(s.labelDryRun(map[string]interface{}{"database": name, "source": source}))
+		}
+		statement := fmt.Sprintf("CREATE DATABASE %s TEMPLATE %s", quoteIdentifier(name), quoteIdentifier(source))
+		var result *mcp.CallToolResult
+		var data any
+		var err error
+		switch {
+		case s.config.RequireApproval && s.cloneApproved(statement):
+			result, data, err = s.connectClone(ctx, name)
+		case s.config.RequireApproval:
+			change, err := s.queueChange(ctx, req, "clone_database", fmt.Sprintf("Clone %s as %s", source, name), statement)
+			if err != nil {
+				return nil, nil, err
+			}
+			return returnQueuedChange(change)
+		default:
+			result, data, err = s.createClone(ctx, source, statement)
+			if result == nil && err == nil {
+				result, data, err = s.connectClone(ctx, name)
+			}
+		}
+		if result != nil || err != nil {
+			return result, data, err
+		}
+	}
+
+	s.clones.mu.Lock()
+	if target {
+		if s.clones.targets == nil {
+			s.clones.targets = make(map[string]string)
+		}
+		s.clones.targets[sessionKey(req)] = name
+	}
+	s.clones.mu.Unlock()
+
+	return returnJSONResult(map[string]interface{}{
+		"database": name,
+		"source":   source,
+		"created":  !exists,
+		"targeted": target,
+	})
+}
+
+// cloneApproved reports whether the CREATE DATABASE of a clone was queued
+// and has been approved and run.
+func (s *serverState) cloneApproved(statement string) bool {
+	s.approvals.mu.Lock()
+	defer s.approvals.mu.Unlock()
+	for _, change := range s.approvals.changes {
+		if change.Tool == "clone_database" && change.Statement == statement && change.Status == "executed" {
+			return true
+		}
+	}
+	return false
+}
+
+// closeIdleConnections closes the pool's idle connections, which would
+// count as other sessions of the database, and leaves the ones in use by
+// other calls alone.
+func (s *serverState) closeIdleConnections(ctx context.Context) {
+	for _, conn := range s.pool.AcquireAllIdle(ctx) {
+		conn.Hijack().Close(ctx)
+	}
+}
+
+// createClone runs the CREATE DATABASE of a clone from the source database,
+// which must have no other sessions. It returns a result only when the
+// clone couldn't be made.
+func (s *serverState) createClone(ctx context.Context, source, statement string) (*mcp.CallToolResult, any, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+	s.closeIdleConnections(ctx)
+
+	var others int
+	var applications string
+	if err := conn.QueryRow(ctx, `
+		SELECT count(*), COALESCE(string_agg(DISTINCT NULLIF(application_name, ''), ', '), '')
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND pid <> pg_backend_pid()
+	`).Scan(&others, &applications); err != nil {
+		return nil, nil, fmt.Errorf("failed to count sessions: %v", err)
+	}
+	if others > 0 {
+		if applications != "" {
+			return s.returnErrorResult("%s has %d other sessions (%s). CREATE DATABASE ... TEMPLATE needs the source database to itself", source, others, applications)
+		}
+		return s.returnErrorResult("%s has %d other sessions. CREATE DATABASE ... TEMPLATE needs the source database to itself", source, others)
+	}
+
+	if _, err := conn.Exec(ctx, statement); err != nil {
+		return s.returnErrorResult("Clone failed: %v", err)
+	}
+	return nil, nil, nil
+}
+
+// connectClone connects to a clone and registers its state, which has this
+// state's configuration. It returns a result only when that failed.
+func (s *serverState) connectClone(ctx context.Context, name string) (*mcp.CallToolResult, any, error) {
+	poolConfig := s.pool.Config()
+	poolConfig.ConnConfig.Database = name
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err == nil {
+		err = pool.Ping(ctx)
+	}
+	if err != nil {
+		return s.returnErrorResult("Created %s but failed to connect to it: %v", name, err)
+	}
+
+	clone := &serverState{
+		config:     s.config,
+		pool:       pool,
+		queue:      newCallQueue(int(poolConfig.MaxConns)),
+		cloneOf:    s,
+		sessions:   s.sessions,
+		parameters: s.parameters,
+	}
+	// calls against the clone are audited to the original's log, redacted
+	// by the same LOG_SQL
+	s.audit.mu.Lock()
+	clone.audit.w = s.audit.w
+	s.audit.mu.Unlock()
+
+	s.clones.mu.Lock()
+	defer s.clones.mu.Unlock()
+	if s.clones.states == nil {
+		s.clones.states = make(map[string]*serverState)
+	}
+	s.clones.states[name] = clone
+	return nil, nil, nil
+}
+
+// dropCloneStatement is the statement drop_clone runs for a clone.
+func dropCloneStatement(name string) string {
+	return "DROP DATABASE " + quoteIdentifier(name)
+}
+
+// detachClone unregisters the clone a drop_clone statement drops, sending
+// the sessions that targeted it back to the original, and closes its pool,
+// whose connections would keep the database in use.
+func (s *serverState) detachClone(statement string) {
+	var clone *serverState
+	s.clones.mu.Lock()
+	for name, state := range s.clones.states {
+		if dropCloneStatement(name) != statement {
+			continue
+		}
+		clone = state
+		delete(s.clones.states, name)
+		for session, target := range s.clones.targets {
+			if target == name {
+				delete(s.clones.targets, session)
+			}
+		}
+	}
+	s.clones.mu.Unlock()
+	if clone != nil {
+		clone.Close()
+	}
+}
+
+// DropClone drops a database made by clone_database, sending the sessions
+// that targeted it back to the original. Like creating a clone it needs
+// ALLOW_WRITES, and with REQUIRE_APPROVAL the DROP DATABASE is queued and
+// the clone stays in use until it is approved.
+func (s *serverState) DropClone(ctx context.Context, req *mcp.CallToolRequest, args DropCloneArgs) (*mcp.CallToolResult, any, error) {
+	s = s.cloneBase()
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("drop_clone")
+	}
+
+	s.clones.mu.Lock()
+	_, ok := s.clones.states[args.Name]
+	s.clones.mu.Unlock()
+	if !ok {
+		return s.returnErrorResult("%q is not a clone made by clone_database", args.Name)
+	}
+
+	statement := dropCloneStatement(args.Name)
+	if s.config.DryRun {
+		return returnJSONResult(s.labelDryRun(map[string]interface{}{"database": args.Name, "statement": statement}))
+	}
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "drop_clone", fmt.Sprintf("Drop clone %s", args.Name), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+
+	s.detachClone(statement)
+	if _, err := s.pool.Exec(ctx, statement); err != nil {
+		return s.returnErrorResult("Drop failed: %v", err)
+	}
+	return returnJSONResult(map[string]interface{}{"database": args.Name, "dropped": true})
+}
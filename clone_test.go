@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCloneDatabase(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.AllowWrites = true
+
+	args := CloneDatabaseArgs{Name: "postgres_mcp_clone_test"}
+	result, data, err := testServer.CloneDatabase(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("CloneDatabase failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful clone, got %v", result)
+	}
+	if response := data.(map[string]interface{}); response["created"] != true || response["targeted"] != true {
+		t.Errorf("Unexpected response %v", response)
+	}
+
+	clone := testServer.cloneFor(nil)
+	if clone == testServer {
+		t.Fatal("Expected the session to target the clone")
+	}
+	if _, err := clone.pool.Exec(ctx, "DELETE FROM comments"); err != nil {
+		t.Fatalf("Failed to write to the clone: %v", err)
+	}
+	var comments int
+	if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM comments").Scan(&comments); err != nil {
+		t.Fatalf("Failed to count comments: %v", err)
+	}
+	if comments == 0 {
+		t.Error("Expected the original's comments to be untouched")
+	}
+
+	dropArgs := DropCloneArgs{Name: args.Name}
+	testServer.config.AllowWrites = false
+	if result, _, err := clone.DropClone(ctx, createMockRequest(dropArgs), dropArgs); err != nil || !result.IsError {
+		t.Error("Expected drop_clone to be refused without ALLOW_WRITES")
+	}
+	testServer.config.AllowWrites = true
+	result, _, err = clone.DropClone(ctx, createMockRequest(dropArgs), dropArgs)
+	if err != nil {
+		t.Fatalf("DropClone failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful drop, got %v", result)
+	}
+	if testServer.cloneFor(nil) != testServer {
+		t.Error("Expected the session to target the original again")
+	}
+
+	t.Run("approval", func(t *testing.T) {
+		testServer.config.RequireApproval = true
+		defer func() { testServer.config.RequireApproval = false }()
+
+		result, data, err := testServer.CloneDatabase(ctx, createMockRequest(args), args)
+		if err != nil || result.IsError {
+			t.Fatalf("CloneDatabase failed: %v %v", err, result)
+		}
+		response := data.(map[string]interface{})
+		if response["status"] != "pending_approval" {
+			t.Fatalf("Expected the clone to wait for approval, got %v", response)
+		}
+
+		testServer.approvals.mu.Lock()
+		change := testServer.findChange(response["change_id"].(string))
+		testServer.approvals.mu.Unlock()
+		approveArgs := ApproveChangeArgs{ChangeID: change.ID, ApprovalToken: change.token}
+		if result, _, err := testServer.ApproveChange(ctx, createMockRequest(approveArgs), approveArgs); err != nil || result.IsError {
+			t.Fatalf("ApproveChange failed: %v %v", err, result)
+		}
+
+		result, data, err = testServer.CloneDatabase(ctx, createMockRequest(args), args)
+		if err != nil || result.IsError {
+			t.Fatalf("CloneDatabase failed: %v %v", err, result)
+		}
+		clone := testServer.cloneFor(nil)
+		if clone == testServer || !clone.config.RequireApproval {
+			t.Error("Expected the session to target the approved clone, under the same policy")
+		}
+		dropArgs := DropCloneArgs{Name: args.Name}
+		result, data, err = clone.DropClone(ctx, createMockRequest(dropArgs), dropArgs)
+		if err != nil || result.IsError {
+			t.Fatalf("DropClone failed: %v %v", err, result)
+		}
+		response = data.(map[string]interface{})
+		if response["status"] != "pending_approval" || testServer.cloneFor(nil) != clone {
+			t.Fatalf("Expected the drop to wait for approval with the clone in use, got %v", response)
+		}
+
+		testServer.approvals.mu.Lock()
+		change = testServer.findChange(response["change_id"].(string))
+		testServer.approvals.mu.Unlock()
+		approveArgs = ApproveChangeArgs{ChangeID: change.ID, ApprovalToken: change.token}
+		if result, _, err := testServer.ApproveChange(ctx, createMockRequest(approveArgs), approveArgs); err != nil || result.IsError {
+			t.Fatalf("ApproveChange failed: %v %v", err, result)
+		}
+		if testServer.cloneFor(nil) != testServer {
+			t.Error("Expected the approved drop to send the session back to the original")
+		}
+	})
+
+	dropArgs = DropCloneArgs{Name: "postgres"}
+	if result, _, err := testServer.DropClone(ctx, createMockRequest(dropArgs), dropArgs); err != nil || !result.IsError {
+		t.Error("Expected only clones to be dropped")
+	}
+}
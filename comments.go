@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// commentTargets are the COMMENT ON object types of the relation kinds
+// set_comment documents.
+var commentTargets = map[string]string{
+	"r": "TABLE",
+	"p": "TABLE",
+	"v": "VIEW",
+	"m": "MATERIALIZED VIEW",
+	"f": "FOREIGN TABLE",
+}
+
+type SetCommentArgs struct {
+	TableName string `json:"table_name" jsonschema:"Table, view or materialized view to document, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Column    string `json:"column,omitempty" jsonschema:"Document this column instead of the relation itself"`
+	Comment   string `json:"comment" jsonschema:"The description to store with COMMENT ON. An empty comment removes it"`
+}
+
+// SetComment stores a description in the database with COMMENT ON, where
+// get_table_schema, list_tables and every other client reading
+// pg_description finds it.
+func (s *serverState) SetComment(ctx context.Context, req *mcp.CallToolRequest, args SetCommentArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("set_comment")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	var kind string
+	if err := s.pool.QueryRow(ctx, "SELECT relkind::text FROM pg_class WHERE oid = $1::text::regclass",
+		pgx.Identifier{schema, tableName}.Sanitize()).Scan(&kind); err != nil {
+		return nil, nil, fmt.Errorf("failed to look up %s: %v", qualifiedName(schema, tableName), err)
+	}
+	objectType, ok := commentTargets[kind]
+	if !ok {
+		return s.returnErrorResult("%s is not a table or view", qualifiedName(schema, tableName))
+	}
+
+	target := objectType + " " + qualifiedName(schema, tableName)
+	if args.Column != "" {
+		columns, err := s.tableColumnTypes(ctx, schema, tableName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up columns: %v", err)
+		}
+		if _, ok := columns[args.Column]; !ok {
+			return s.returnErrorResult("column %q does not exist", args.Column)
+		}
+		target = "COLUMN " + qualifiedName(schema, tableName) + "." + quoteIdentifier(args.Column)
+	}
+	comment := "NULL"
+	if args.Comment != "" {
+		comment = quoteLiteral(args.Comment)
+	}
+	statement := fmt.Sprintf("COMMENT ON %s IS %s", target, comment)
+
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "set_comment", "Comment on "+target, statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, statement); err != nil {
+		return s.returnErrorResult("Comment error: %v", err)
+	}
+	if err := s.finishWrite(ctx, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result, data, err := returnJSONResult(s.labelDryRun(map[string]interface{}{
+		"statement": statement,
+		"removed":   args.Comment == "",
+	}))
+	return s.withWarnings(result, notices), data, err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetComment(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	defer testServer.pool.Exec(ctx, "COMMENT ON TABLE users IS NULL; COMMENT ON COLUMN users.email IS NULL")
+
+	args := SetCommentArgs{TableName: "users", Comment: "Registered accounts"}
+	if result, _, err := testServer.SetComment(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+		t.Error("Expected set_comment to be refused without ALLOW_WRITES")
+	}
+
+	testServer.config.AllowWrites = true
+	for _, args := range []SetCommentArgs{args, {TableName: "users", Column: "email", Comment: "Where we send receipts, it's unique"}} {
+		result, _, err := testServer.SetComment(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("SetComment failed: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("Expected successful comment, got %v", result)
+		}
+	}
+	args = SetCommentArgs{TableName: "users", Column: "missing", Comment: "x"}
+	if result, _, _ := testServer.SetComment(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a missing column to be refused")
+	}
+
+	listArgs := TableListArgs{}
+	_, data, err := testServer.ListTables(ctx, createMockRequest(listArgs), listArgs)
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	for _, table := range data.([]map[string]interface{}) {
+		if table["table_name"] == "users" && table["comment"] != "Registered accounts" {
+			t.Errorf("Expected the table comment, got %v", table["comment"])
+		}
+	}
+
+	schemaArgs := TableSchemaArgs{TableName: "users"}
+	_, data, err = testServer.GetTableSchema(ctx, createMockRequest(schemaArgs), schemaArgs)
+	if err != nil {
+		t.Fatalf("GetTableSchema failed: %v", err)
+	}
+	for _, column := range data.([]map[string]interface{}) {
+		if column["column_name"] == "email" && column["comment"] != "Where we send receipts, it's unique" {
+			t.Errorf("Expected the column comment, got %v", column["comment"])
+		}
+	}
+
+	args = SetCommentArgs{TableName: "users"}
+	if _, data, err := testServer.SetComment(ctx, createMockRequest(args), args); err != nil || data.(map[string]interface{})["removed"] != true {
+		t.Errorf("Expected an empty comment to remove it, got %v, %v", data, err)
+	}
+}
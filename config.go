@@ -0,0 +1,385 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Config holds the server settings read from the environment at startup.
+type Config struct {
+	// AllowWrites enables tools that modify the database, such as refreshing
+	// materialized views. Everything else stays read-only regardless.
+	AllowWrites bool
+
+	// RequireApproval queues write operations as pending changes that only run
+	// once approved with the token sent to ApprovalWebhookURL (or the server log).
+	RequireApproval    bool
+	ApprovalWebhookURL string
+
+	// DryRun runs every write inside a transaction that is always rolled back
+	// and labels the responses as simulated, so workflows can be rehearsed
+	// against production data. Write tools are enabled in this mode.
+	DryRun bool
+
+	// EncryptionKey, when set, encrypts every file the server writes (exports,
+	// transcripts) with AES-256-GCM since they can contain query results.
+	EncryptionKey []byte
+
+	// AnonymizeKey keys the HMAC behind the fakes of export_fixture and
+	// anonymize_table. Without ANONYMIZE_KEY a random key is drawn at
+	// startup, so fakes only match within one run.
+	AnonymizeKey []byte
+
+	// ExportDir is the only directory tools write files to and read backups
+	// from, output_path and input_path being names inside it. Empty
+	// disables file output.
+	ExportDir string
+
+	// ConnectRetryTimeout is how long startup keeps retrying to reach the
+	// database, zero tries once.
+	ConnectRetryTimeout time.Duration
+
+	// LazyConnect starts the server even when the database can't be reached,
+	// tools report it unavailable until a background reconnect succeeds.
+	LazyConnect bool
+
+	// ActivitySampleInterval records a pg_stat_activity snapshot this often
+	// for get_activity_history, zero disables it. ActivitySampleRetention is
+	// how much history is kept in memory.
+	ActivitySampleInterval  time.Duration
+	ActivitySampleRetention time.Duration
+
+	// SlowQueryThreshold is how long a query must have been running when a
+	// sample sees it to be recorded for recent_slow_queries.
+	SlowQueryThreshold time.Duration
+
+	// MetadataCacheInterval caches the results of the schema exploration
+	// tools, checking the catalogs for changes at most this often. Zero
+	// disables the cache.
+	MetadataCacheInterval time.Duration
+
+	// MaxResultBytes stops reading a chunked query result once its encoded
+	// rows reach this size, 64 MiB by default. Zero reads every row.
+	MaxResultBytes int
+
+	// MaxBinaryBytes is how much of a bytea value query results include,
+	// zero for all of it.
+	MaxBinaryBytes int
+
+	// MaxJSONBytes is the largest json or jsonb value query results include
+	// whole, larger ones are cut off. Zero includes every value whole.
+	MaxJSONBytes int
+
+	// Pool settings, zero keeps the pgxpool default (or the value from the
+	// pool_* parameters in the connection string).
+	MaxConns        int32
+	MinConns        int32
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+
+	// SandboxSchema is where demonstration tools create their scratch tables.
+	SandboxSchema string
+
+	// AutoAnalyzeRows runs ANALYZE on the tables a write touched once it
+	// affected at least this many rows, zero disables it.
+	AutoAnalyzeRows int64
+
+	// RedactPII masks values that look like emails, phone numbers, card
+	// numbers or SSNs in returned rows.
+	RedactPII bool
+
+	// AuditLog is where every tool call is recorded ("stderr" or a file path,
+	// empty disables it). LogSQL picks how much of statements and compared
+	// values it keeps: full, redacted (literals replaced) or none. Results
+	// are never written to it.
+	AuditLog string
+	LogSQL   string
+
+	// Locale is the language of tool descriptions and guard messages, one of
+	// en, es, de or ja.
+	Locale string
+
+	// Allow and deny lists of schemas and tables (schema.table or a bare
+	// name in any schema, * and ? wildcards) that every tool enforces.
+	// Deny lists win, an empty allow list allows everything.
+	AllowedSchemas []string
+	DeniedSchemas  []string
+	AllowedTables  []string
+	DeniedTables   []string
+
+	// QueryPolicy maps each statement category of the query tool (read,
+	// dml, ddl, maintenance, other) to allow, confirm or block.
+	QueryPolicy map[string]string
+
+	// SerializationRetries is how often the query tool retries a transaction
+	// at repeatable_read or serializable isolation that failed to serialize.
+	SerializationRetries int
+
+	// InListThreshold is the number of literal values from which an IN list
+	// in the query tool is sent as an array parameter, zero disables it.
+	InListThreshold int
+
+	// Transport is how clients connect: stdio, http (streamable HTTP) or
+	// sse, the last two listening on HTTPAddr. TLS is served when a
+	// certificate and key are set, and TLSClientCAFile additionally requires
+	// client certificates signed by one of its CAs (mutual TLS).
+	// HTTPAuthToken, when set, must be sent as a bearer token.
+	Transport       string
+	HTTPAddr        string
+	HTTPAuthToken   string
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+
+	// TLS to the database beyond what the connection string can express: the
+	// CA server certificates must be signed by, a client certificate, host
+	// name verification and the lowest TLS version. Connections that could
+	// be made without TLS are refused unless AllowInsecure is set.
+	DBTLSCAFile     string
+	DBTLSCertFile   string
+	DBTLSKeyFile    string
+	DBTLSVerifyFull bool
+	DBTLSMinVersion string
+	AllowInsecure   bool
+
+	// DBSocketDir is the directory of the server's Unix socket, used instead
+	// of the hosts of the connection string, for local servers that don't
+	// listen on TCP at all.
+	DBSocketDir string
+
+	// Role is switched to with SET ROLE on every new connection, so the server
+	// runs with fewer privileges than its login credentials.
+	Role string
+
+	// Profiles are the named databases of ProfilesFile, each with its own
+	// policy, that tools reach with their connection argument. Profile picks
+	// the one used by default instead of DATABASE_URL.
+	ProfilesFile string
+	Profiles     map[string]profile
+	Profile      string
+
+	// ColumnPolicies are the semantic types of ColumnPolicyFile, which
+	// decide how tools mask, format and describe the columns they cover.
+	ColumnPolicyFile string
+	ColumnPolicies   columnPolicies
+
+	// SavedQueriesFile is the library of named queries whose plans
+	// check_plan_regressions compares against their recorded baselines.
+	SavedQueriesFile string
+
+	// PlanStoreFile keeps the plan baselines of save_plan_baseline, keyed by
+	// query fingerprint. Empty disables the plan store.
+	PlanStoreFile string
+
+	// DataChecksFile holds the named data quality rules run_data_checks
+	// evaluates.
+	DataChecksFile string
+
+	// ServerLog is the server's log file, or its log directory, that
+	// recent_errors reads errors from.
+	ServerLog string
+}
+
+func loadConfig() (Config, error) {
+	encryptionKey, err := parseEncryptionKey(os.Getenv("ENCRYPTION_KEY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ENCRYPTION_KEY: %v", err)
+	}
+	anonymizeKey, err := parseEncryptionKey(os.Getenv("ANONYMIZE_KEY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid ANONYMIZE_KEY: %v", err)
+	}
+	if anonymizeKey == nil {
+		anonymizeKey = make([]byte, 32)
+		if _, err := rand.Read(anonymizeKey); err != nil {
+			return Config{}, fmt.Errorf("failed to generate an anonymization key: %v", err)
+		}
+	}
+	queryPolicy, err := parseQueryPolicy(os.Getenv("QUERY_POLICY"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid QUERY_POLICY: %v", err)
+	}
+	profiles, err := loadProfiles(os.Getenv("PROFILES_FILE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid PROFILES_FILE: %v", err)
+	}
+	columnPolicies, err := loadColumnPolicies(os.Getenv("COLUMN_POLICY_FILE"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid COLUMN_POLICY_FILE: %v", err)
+	}
+	if _, ok := profiles[os.Getenv("PROFILE")]; os.Getenv("PROFILE") != "" && !ok {
+		return Config{}, fmt.Errorf("PROFILE %q is not defined in PROFILES_FILE", os.Getenv("PROFILE"))
+	}
+
+	config := Config{
+		AllowWrites:             envBool("ALLOW_WRITES", false),
+		RequireApproval:         envBool("REQUIRE_APPROVAL", false),
+		ApprovalWebhookURL:      os.Getenv("APPROVAL_WEBHOOK_URL"),
+		DryRun:                  envBool("DRY_RUN", false),
+		EncryptionKey:           encryptionKey,
+		AnonymizeKey:            anonymizeKey,
+		ExportDir:               os.Getenv("EXPORT_DIR"),
+		MaxConns:                int32(envInt("DB_MAX_CONNS", 0)),
+		MinConns:                int32(envInt("DB_MIN_CONNS", 0)),
+		ConnectRetryTimeout:     envDuration("CONNECT_RETRY_TIMEOUT", 30*time.Second),
+		LazyConnect:             envBool("LAZY_CONNECT", false),
+		ActivitySampleInterval:  envDuration("ACTIVITY_SAMPLE_INTERVAL", 0),
+		ActivitySampleRetention: envDuration("ACTIVITY_SAMPLE_RETENTION", time.Hour),
+		SlowQueryThreshold:      envDuration("SLOW_QUERY_THRESHOLD", time.Second),
+		MetadataCacheInterval:   envDuration("METADATA_CACHE_INTERVAL", 0),
+		MaxConnLifetime:         envDuration("DB_MAX_CONN_LIFETIME", 0),
+		MaxConnIdleTime:         envDuration("DB_MAX_CONN_IDLE_TIME", 0),
+		SandboxSchema:           envString("SANDBOX_SCHEMA", "mcp_sandbox"),
+		AutoAnalyzeRows:         int64(envInt("AUTO_ANALYZE_ROWS", 0)),
+		Role:                    os.Getenv("ROLE"),
+		RedactPII:               envBool("REDACT_PII", false),
+		Locale:                  parseLocale(os.Getenv("LOCALE")),
+		AuditLog:                os.Getenv("AUDIT_LOG"),
+		LogSQL:                  envChoice("LOG_SQL", "redacted", sqlRedactors),
+		AllowedSchemas:          envList("ALLOWED_SCHEMAS"),
+		DeniedSchemas:           envList("DENIED_SCHEMAS"),
+		AllowedTables:           envList("ALLOWED_TABLES"),
+		DeniedTables:            envList("DENIED_TABLES"),
+		QueryPolicy:             queryPolicy,
+		InListThreshold:         envInt("IN_LIST_THRESHOLD", 100),
+		MaxResultBytes:          envInt("MAX_RESULT_BYTES", 64<<20),
+		MaxBinaryBytes:          envInt("MAX_BINARY_BYTES", 1024),
+		MaxJSONBytes:            envInt("MAX_JSON_BYTES", 64<<10),
+		SerializationRetries:    envInt("SERIALIZATION_RETRIES", 5),
+		Transport:               envChoice("TRANSPORT", "stdio", transports),
+		HTTPAddr:                envString("HTTP_ADDR", "127.0.0.1:8080"),
+		HTTPAuthToken:           os.Getenv("HTTP_AUTH_TOKEN"),
+		TLSCertFile:             os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:              os.Getenv("TLS_KEY_FILE"),
+		TLSClientCAFile:         os.Getenv("TLS_CLIENT_CA_FILE"),
+		DBTLSCAFile:             os.Getenv("DB_TLS_CA_FILE"),
+		DBTLSCertFile:           os.Getenv("DB_TLS_CERT_FILE"),
+		DBTLSKeyFile:            os.Getenv("DB_TLS_KEY_FILE"),
+		DBTLSVerifyFull:         envBool("DB_TLS_VERIFY_FULL", false),
+		DBTLSMinVersion:         os.Getenv("DB_TLS_MIN_VERSION"),
+		AllowInsecure:           envBool("ALLOW_INSECURE", false),
+		DBSocketDir:             os.Getenv("DB_SOCKET_DIR"),
+		ProfilesFile:            os.Getenv("PROFILES_FILE"),
+		Profiles:                profiles,
+		Profile:                 os.Getenv("PROFILE"),
+		ColumnPolicyFile:        os.Getenv("COLUMN_POLICY_FILE"),
+		ColumnPolicies:          columnPolicies,
+		SavedQueriesFile:        os.Getenv("SAVED_QUERIES_FILE"),
+		PlanStoreFile:           os.Getenv("PLAN_STORE_FILE"),
+		DataChecksFile:          os.Getenv("DATA_CHECKS_FILE"),
+		ServerLog:               os.Getenv("SERVER_LOG"),
+	}
+	if err := config.validateTLS(); err != nil {
+		return Config{}, err
+	}
+	if err := config.validateListenAddr(); err != nil {
+		return Config{}, err
+	}
+	if err := config.validateDatabaseTLS(); err != nil {
+		return Config{}, err
+	}
+	if err := validateSocketDir(config.DBSocketDir); err != nil {
+		return Config{}, err
+	}
+	if err := validateExportDir(config.ExportDir); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+func envBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Ignoring invalid %s=%q, using %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func envString(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// envList splits a comma-separated value, dropping empty entries.
+func envList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func envChoice[T any](key string, defaultValue string, choices map[string]T) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if _, ok := choices[value]; !ok {
+		log.Printf("Ignoring invalid %s=%q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func envInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func envDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// applyPoolSettings overrides the pool configuration with the settings that
+// were set in the environment.
+func (c Config) applyPoolSettings(poolConfig *pgxpool.Config) {
+	if c.MaxConns > 0 {
+		poolConfig.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		poolConfig.MinConns = c.MinConns
+	}
+	if c.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.Role != "" {
+		poolConfig.AfterConnect = setConnectionRole(c.Role)
+		poolConfig.AfterRelease = checkConnectionRole(c.Role)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ConnectionInfoArgs struct{}
+
+// sslMode describes how a connection configuration uses TLS in sslmode
+// terms. It is derived from the TLS settings in effect rather than read from
+// the connection string, which the DB_TLS_* settings may have overridden.
+func sslMode(connConfig *pgconn.Config) string {
+	tlsFallback, plainFallback := false, false
+	for _, fallback := range connConfig.Fallbacks {
+		if fallback.Host == connConfig.Host && fallback.Port == connConfig.Port {
+			tlsFallback = tlsFallback || fallback.TLSConfig != nil
+			plainFallback = plainFallback || fallback.TLSConfig == nil
+		}
+	}
+
+	tlsConfig := connConfig.TLSConfig
+	switch {
+	case tlsConfig == nil && tlsFallback:
+		return "allow"
+	case tlsConfig == nil:
+		return "disable"
+	case plainFallback:
+		return "prefer"
+	case tlsConfig.ServerName != "" && !tlsConfig.InsecureSkipVerify:
+		return "verify-full"
+	case tlsConfig.VerifyPeerCertificate != nil:
+		return "verify-ca"
+	}
+	return "require"
+}
+
+// GetConnectionInfo reports where the server is connected, with only the
+// fields that are safe to show: never the password or the connection string.
+func (s *serverState) GetConnectionInfo(ctx context.Context, req *mcp.CallToolRequest, args ConnectionInfoArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	connConfig := s.pool.Config().ConnConfig
+	info := map[string]interface{}{
+		"host":     connConfig.Host,
+		"port":     connConfig.Port,
+		"database": connConfig.Database,
+		"user":     connConfig.User,
+		"sslmode":  sslMode(&connConfig.Config),
+	}
+	if network, _ := pgconn.NetworkAddress(connConfig.Host, connConfig.Port); network == "unix" {
+		info["unix_socket"] = true
+	}
+	var fallbackHosts []string
+	for _, fallback := range connConfig.Fallbacks {
+		host := fmt.Sprintf("%s:%d", fallback.Host, fallback.Port)
+		if (fallback.Host != connConfig.Host || fallback.Port != connConfig.Port) && !slices.Contains(fallbackHosts, host) {
+			fallbackHosts = append(fallbackHosts, host)
+		}
+	}
+	if len(fallbackHosts) > 0 {
+		info["fallback_hosts"] = fallbackHosts
+	}
+	if name := connConfig.RuntimeParams["application_name"]; name != "" {
+		info["application_name"] = name
+	}
+	if s.config.Profile != "" {
+		info["profile"] = s.config.Profile
+	}
+	if s.config.Role != "" {
+		info["role"] = s.config.Role
+	}
+
+	// what the server sees, which is what counts for TLS
+	var sslInUse bool
+	var tlsVersion *string
+	err := s.pool.QueryRow(ctx, "SELECT ssl, version FROM pg_stat_ssl WHERE pid = pg_backend_pid()").Scan(&sslInUse, &tlsVersion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read connection TLS status: %v", err)
+	}
+	info["ssl_in_use"] = sslInUse
+	if tlsVersion != nil {
+		info["tls_version"] = *tlsVersion
+	}
+
+	return returnJSONResult(info)
+}
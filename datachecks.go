@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// dataCheck is a data quality rule, an entry of DATA_CHECKS_FILE or one
+// passed to run_data_checks.
+type dataCheck struct {
+	Name              string   `json:"name,omitempty" jsonschema:"Name of the rule in the report"`
+	Table             string   `json:"table" jsonschema:"Table the rule checks, optionally schema-qualified"`
+	Type              string   `json:"type" jsonschema:"not_null, unique, range, regex or references"`
+	Columns           []string `json:"columns" jsonschema:"Columns the rule checks, range and regex take one"`
+	Min               any      `json:"min,omitempty" jsonschema:"Lowest value allowed (range)"`
+	Max               any      `json:"max,omitempty" jsonschema:"Highest value allowed (range)"`
+	Pattern           string   `json:"pattern,omitempty" jsonschema:"POSIX regular expression values must match (regex)"`
+	ReferencesTable   string   `json:"references_table,omitempty" jsonschema:"Table values must exist in (references)"`
+	ReferencesColumns []string `json:"references_columns,omitempty" jsonschema:"Referenced columns (references, default: the primary key)"`
+	MaxFailureRatio   float64  `json:"max_failure_ratio,omitempty" jsonschema:"Share of rows allowed to fail, between 0 and 1, such as the NULLs a not_null rule tolerates (default: 0)"`
+}
+
+func loadDataChecks(path string) (map[string]dataCheck, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var checks map[string]dataCheck
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&checks); err != nil {
+		return nil, err
+	}
+	for name, check := range checks {
+		check.Name = name
+		checks[name] = check
+	}
+	return checks, nil
+}
+
+type RunDataChecksArgs struct {
+	Rules []dataCheck `json:"rules,omitempty" jsonschema:"Rules to evaluate instead of those of DATA_CHECKS_FILE"`
+	Names []string    `json:"names,omitempty" jsonschema:"Rules of DATA_CHECKS_FILE to evaluate (default: all)"`
+}
+
+// RunDataChecks evaluates data quality rules, declared in DATA_CHECKS_FILE
+// or passed inline, and reports which pass. A rule fails when more of the
+// table's rows break it than its max_failure_ratio allows.
+func (s *serverState) RunDataChecks(ctx context.Context, req *mcp.CallToolRequest, args RunDataChecksArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	rules := args.Rules
+	for i := range rules {
+		if rules[i].Name == "" {
+			rules[i].Name = fmt.Sprintf("rule %d", i+1)
+		}
+	}
+	if len(rules) == 0 {
+		if s.config.DataChecksFile == "" {
+			return s.returnErrorResult("No rules, pass rules or set DATA_CHECKS_FILE to a JSON file of named rules")
+		}
+		checks, err := loadDataChecks(s.config.DataChecksFile)
+		if err != nil {
+			return s.returnErrorResult("Failed to read DATA_CHECKS_FILE: %v", err)
+		}
+		names := args.Names
+		if len(names) == 0 {
+			names = sortedKeys(checks)
+		}
+		for _, name := range names {
+			check, ok := checks[name]
+			if !ok {
+				return s.returnErrorResult("No rule named %q", name)
+			}
+			rules = append(rules, check)
+		}
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	report := []map[string]interface{}{}
+	counts := map[string]int{"pass": 0, "fail": 0, "error": 0}
+	for _, rule := range rules {
+		entry := map[string]interface{}{"name": rule.Name, "table": rule.Table, "type": rule.Type}
+		table, check, err := s.dataCheckConstraint(ctx, rule)
+		if err == nil {
+			entry["definition"] = check.Definition
+			// a rule that fails to run is reported, the others still run
+			var savepoint pgx.Tx
+			if savepoint, err = tx.Begin(ctx); err != nil {
+				return nil, nil, fmt.Errorf("failed to begin savepoint: %v", err)
+			}
+			var failing, checked int64
+			err = savepoint.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FILTER (WHERE %s), count(*) FROM %s AS v",
+				check.Violations, table)).Scan(&failing, &checked)
+			if err != nil {
+				savepoint.Rollback(ctx)
+			} else if err := savepoint.Commit(ctx); err != nil {
+				return nil, nil, fmt.Errorf("failed to release savepoint: %v", err)
+			}
+			if err == nil {
+				ratio := 0.0
+				if checked > 0 {
+					ratio = float64(failing) / float64(checked)
+				}
+				entry["status"] = "pass"
+				if ratio > rule.MaxFailureRatio {
+					entry["status"] = "fail"
+				}
+				entry["failing_rows"] = failing
+				entry["checked_rows"] = checked
+				entry["failure_ratio"] = ratio
+				entry["max_failure_ratio"] = rule.MaxFailureRatio
+			}
+		}
+		if err != nil {
+			entry["status"] = "error"
+			entry["error"] = err.Error()
+		}
+		counts[entry["status"].(string)]++
+		report = append(report, entry)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"passed": counts["pass"],
+		"failed": counts["fail"],
+		"errors": counts["error"],
+		"rules":  report,
+	})
+	return s.withWarnings(result, notices), data, err
+}
+
+// dataCheckConstraint turns a rule into the constraint its rows are checked
+// against, as validate_constraints would check it, and resolves its table.
+func (s *serverState) dataCheckConstraint(ctx context.Context, rule dataCheck) (string, constraintCheck, error) {
+	if rule.MaxFailureRatio < 0 || rule.MaxFailureRatio > 1 {
+		return "", constraintCheck{}, fmt.Errorf("max_failure_ratio must be between 0 and 1")
+	}
+	schema, tableName, err := s.resolveTableName(ctx, "", rule.Table)
+	if err != nil {
+		return "", constraintCheck{}, err
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return "", constraintCheck{}, fmt.Errorf("%s is not accessible under the configured allow/deny lists", qualifiedName(schema, tableName))
+	}
+
+	constraint := ValidateConstraintsArgs{
+		Type:              strings.ToLower(rule.Type),
+		Columns:           rule.Columns,
+		ReferencesTable:   rule.ReferencesTable,
+		ReferencesColumns: rule.ReferencesColumns,
+	}
+	switch constraint.Type {
+	case "not_null", "unique":
+	case "references":
+		constraint.Type = "foreign_key"
+	case "range", "regex":
+		if len(rule.Columns) != 1 {
+			return "", constraintCheck{}, fmt.Errorf("a %s rule takes one column", rule.Type)
+		}
+		types, err := s.tableColumnTypes(ctx, schema, tableName)
+		if err != nil {
+			return "", constraintCheck{}, fmt.Errorf("failed to look up columns: %v", err)
+		}
+		typeName, ok := types[rule.Columns[0]]
+		if !ok {
+			return "", constraintCheck{}, fmt.Errorf("column %q does not exist in %s", rule.Columns[0], qualifiedName(schema, tableName))
+		}
+		column := quoteIdentifier(rule.Columns[0])
+
+		var conditions []string
+		if constraint.Type == "regex" {
+			if rule.Pattern == "" {
+				return "", constraintCheck{}, fmt.Errorf("pattern is required for a regex rule")
+			}
+			conditions = append(conditions, fmt.Sprintf("%s::text ~ %s", column, quoteLiteral(rule.Pattern)))
+		}
+		for _, bound := range []struct {
+			value    any
+			operator string
+		}{{rule.Min, ">="}, {rule.Max, "<="}} {
+			if constraint.Type != "range" || bound.value == nil {
+				continue
+			}
+			literal, err := valueLiteral(bound.value, typeName)
+			if err != nil {
+				return "", constraintCheck{}, err
+			}
+			conditions = append(conditions, fmt.Sprintf("%s %s %s", column, bound.operator, literal))
+		}
+		if len(conditions) == 0 {
+			return "", constraintCheck{}, fmt.Errorf("min or max is required for a range rule")
+		}
+		constraint.Type = "check"
+		constraint.Check = strings.Join(conditions, " AND ")
+	default:
+		return "", constraintCheck{}, fmt.Errorf("unknown rule type %q, use not_null, unique, range, regex or references", rule.Type)
+	}
+
+	check, err := s.proposedConstraint(ctx, schema, tableName, constraint)
+	return qualifiedName(schema, tableName), check, err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunDataChecks(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE checked_orders (id serial PRIMARY KEY, user_id int, code text, total numeric, phone text);
+		INSERT INTO checked_orders (user_id, code, total, phone) VALUES
+			(1, 'A-1', 10, NULL), (1, 'A-2', 20, '555'), (999999, 'bad', -5, '556'), (NULL, 'A-3', 30, '557');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE checked_orders")
+
+	args := RunDataChecksArgs{Rules: []dataCheck{
+		{Name: "phones", Table: "checked_orders", Type: "not_null", Columns: []string{"phone"}, MaxFailureRatio: 0.25},
+		{Name: "users", Table: "checked_orders", Type: "not_null", Columns: []string{"user_id"}},
+		{Name: "codes unique", Table: "checked_orders", Type: "unique", Columns: []string{"code"}},
+		{Name: "totals", Table: "checked_orders", Type: "range", Columns: []string{"total"}, Min: float64(0)},
+		{Name: "code format", Table: "checked_orders", Type: "regex", Columns: []string{"code"}, Pattern: `^A-\d+$`},
+		{Name: "known users", Table: "checked_orders", Type: "references", Columns: []string{"user_id"}, ReferencesTable: "users"},
+		{Name: "broken", Table: "checked_orders", Type: "range", Columns: []string{"missing"}, Min: float64(0)},
+	}}
+	result, data, err := testServer.RunDataChecks(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RunDataChecks failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	expected := map[string]string{
+		"phones":       "pass",
+		"users":        "fail",
+		"codes unique": "pass",
+		"totals":       "fail",
+		"code format":  "fail",
+		"known users":  "fail",
+		"broken":       "error",
+	}
+	response := data.(map[string]interface{})
+	for _, rule := range response["rules"].([]map[string]interface{}) {
+		if rule["status"] != expected[rule["name"].(string)] {
+			t.Errorf("Expected %s to %s, got %v", rule["name"], expected[rule["name"].(string)], rule)
+		}
+	}
+	if response["passed"] != 2 || response["failed"] != 4 || response["errors"] != 1 {
+		t.Errorf("Unexpected totals in %v", response)
+	}
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	path := filepath.Join(t.TempDir(), "checks.json")
+	rules := `{"positive totals": {"table": "checked_orders", "type": "range", "columns": ["total"], "min": 0, "max_failure_ratio": 0.5}}`
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	testServer.config.DataChecksFile = path
+	args = RunDataChecksArgs{}
+	_, data, err = testServer.RunDataChecks(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RunDataChecks failed: %v", err)
+	}
+	report := data.(map[string]interface{})["rules"].([]map[string]interface{})
+	if len(report) != 1 || report[0]["name"] != "positive totals" || report[0]["status"] != "pass" || report[0]["failing_rows"] != int64(1) {
+		t.Errorf("Expected the file rule to pass with one failing row, got %v", report)
+	}
+}
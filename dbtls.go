@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// tlsVersions are the accepted DB_TLS_MIN_VERSION values.
+var tlsVersions = map[string]uint16{"1.2": tls.VersionTLS12, "1.3": tls.VersionTLS13}
+
+// databaseTLSConfigured reports whether any DB_TLS_* setting is set, in which
+// case they replace the sslmode and ssl* parameters of the connection string.
+func (c Config) databaseTLSConfigured() bool {
+	return c.DBTLSCAFile != "" || c.DBTLSCertFile != "" || c.DBTLSKeyFile != "" || c.DBTLSVerifyFull || c.DBTLSMinVersion != ""
+}
+
+// validateDatabaseTLS checks the DB_TLS_* settings that don't need files.
+func (c Config) validateDatabaseTLS() error {
+	switch {
+	case (c.DBTLSCertFile == "") != (c.DBTLSKeyFile == ""):
+		return fmt.Errorf("DB_TLS_CERT_FILE and DB_TLS_KEY_FILE must be set together")
+	case c.DBTLSMinVersion != "" && tlsVersions[c.DBTLSMinVersion] == 0:
+		return fmt.Errorf("invalid DB_TLS_MIN_VERSION %q, use 1.2 or 1.3", c.DBTLSMinVersion)
+	}
+	return nil
+}
+
+// applyDatabaseTLS makes every connection to a network host use TLS as the
+// DB_TLS_* settings describe: server certificates verified against
+// DB_TLS_CA_FILE (or the system roots), the host name checked too with
+// DB_TLS_VERIFY_FULL, a client certificate when one is set, and at least
+// DB_TLS_MIN_VERSION. Without a CA file or verify-full the connection is
+// encrypted but the server is not authenticated, like sslmode=require.
+func (c Config) applyDatabaseTLS(connConfig *pgconn.Config) error {
+	if !c.databaseTLSConfigured() {
+		return nil
+	}
+
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	if version := tlsVersions[c.DBTLSMinVersion]; version != 0 {
+		base.MinVersion = version
+	}
+	if c.DBTLSCAFile != "" {
+		pem, err := os.ReadFile(c.DBTLSCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read DB_TLS_CA_FILE: %v", err)
+		}
+		base.RootCAs = x509.NewCertPool()
+		if !base.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %s", c.DBTLSCAFile)
+		}
+	}
+	if c.DBTLSCertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.DBTLSCertFile, c.DBTLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load DB_TLS_CERT_FILE: %v", err)
+		}
+		base.Certificates = []tls.Certificate{certificate}
+	}
+
+	forHost := func(host string) *tls.Config {
+		if network, _ := pgconn.NetworkAddress(host, 0); network == "unix" {
+			return nil
+		}
+		config := base.Clone()
+		switch {
+		case c.DBTLSVerifyFull:
+			config.ServerName = host
+		case c.DBTLSCAFile != "":
+			// verify the chain but not the host name, like sslmode=verify-ca
+			config.InsecureSkipVerify = true
+			config.VerifyPeerCertificate = verifyChain(config.RootCAs)
+		default:
+			config.InsecureSkipVerify = true
+		}
+		return config
+	}
+
+	// sslmode=prefer and allow add plain text attempts for every host, keep
+	// one TLS attempt per host instead
+	seen := map[string]bool{connConfig.Host + ":" + strconv.Itoa(int(connConfig.Port)): true}
+	connConfig.TLSConfig = forHost(connConfig.Host)
+	var fallbacks []*pgconn.FallbackConfig
+	for _, fallback := range connConfig.Fallbacks {
+		key := fallback.Host + ":" + strconv.Itoa(int(fallback.Port))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fallbacks = append(fallbacks, &pgconn.FallbackConfig{Host: fallback.Host, Port: fallback.Port, TLSConfig: forHost(fallback.Host)})
+	}
+	connConfig.Fallbacks = fallbacks
+	return nil
+}
+
+// verifyChain checks the server certificate against roots without checking
+// the host name.
+func verifyChain(roots *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return fmt.Errorf("the server sent no certificate")
+		}
+		certificates := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			certificate, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("failed to parse the server certificate: %v", err)
+			}
+			certificates[i] = certificate
+		}
+		options := x509.VerifyOptions{Roots: roots, Intermediates: x509.NewCertPool()}
+		for _, intermediate := range certificates[1:] {
+			options.Intermediates.AddCert(intermediate)
+		}
+		_, err := certificates[0].Verify(options)
+		return err
+	}
+}
+
+// checkDatabaseTLS refuses connection settings under which a connection to
+// a network host could be made without TLS, such as sslmode=disable or
+// prefer, unless ALLOW_INSECURE is set. Unix sockets don't leave the machine
+// and are allowed.
+func (c Config) checkDatabaseTLS(connConfig *pgconn.Config) error {
+	if c.AllowInsecure {
+		return nil
+	}
+	attempts := append([]*pgconn.FallbackConfig{{Host: connConfig.Host, Port: connConfig.Port, TLSConfig: connConfig.TLSConfig}}, connConfig.Fallbacks...)
+	for _, attempt := range attempts {
+		network, _ := pgconn.NetworkAddress(attempt.Host, attempt.Port)
+		if network != "unix" && attempt.TLSConfig == nil {
+			return fmt.Errorf("the connection to %s may be made without TLS: use sslmode=require, verify-ca or verify-full, set the DB_TLS_* settings, or set ALLOW_INSECURE=true (--allow-insecure) to allow it", attempt.Host)
+		}
+	}
+	return nil
+}
+
+// libpqTLSEnv describes the TLS of a pool connection as libpq environment
+// variables, so programs such as pg_dump connect as securely as the pool
+// does. The DB_TLS_* settings map onto sslmode and the ssl* files; TLS
+// from the connection string keeps its verification level, read back from
+// tlsConfig.
+func (c Config) libpqTLSEnv(tlsConfig *tls.Config) []string {
+	if tlsConfig == nil {
+		return []string{"PGSSLMODE=disable"}
+	}
+	if !c.databaseTLSConfigured() {
+		switch {
+		case !tlsConfig.InsecureSkipVerify:
+			return []string{"PGSSLMODE=verify-full"}
+		case tlsConfig.VerifyPeerCertificate != nil:
+			return []string{"PGSSLMODE=verify-ca"}
+		default:
+			return []string{"PGSSLMODE=require"}
+		}
+	}
+
+	var env []string
+	switch {
+	case c.DBTLSVerifyFull:
+		env = append(env, "PGSSLMODE=verify-full")
+	case c.DBTLSCAFile != "":
+		env = append(env, "PGSSLMODE=verify-ca")
+	default:
+		env = append(env, "PGSSLMODE=require")
+	}
+	switch {
+	case c.DBTLSCAFile != "":
+		env = append(env, "PGSSLROOTCERT="+c.DBTLSCAFile)
+	case c.DBTLSVerifyFull:
+		// the pool verifies against the system roots, which libpq only
+		// does when told to
+		env = append(env, "PGSSLROOTCERT=system")
+	}
+	if c.DBTLSCertFile != "" {
+		env = append(env, "PGSSLCERT="+c.DBTLSCertFile, "PGSSLKEY="+c.DBTLSKeyFile)
+	}
+	if c.DBTLSMinVersion != "" {
+		env = append(env, "PGSSLMINPROTOCOLVERSION=TLSv"+c.DBTLSMinVersion)
+	}
+	return env
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestCheckDatabaseTLS(t *testing.T) {
+	tests := []struct {
+		connString    string
+		allowInsecure bool
+		allowed       bool
+	}{
+		{"postgres://app@db.example.com/app?sslmode=require", false, true},
+		{"postgres://app@db.example.com/app?sslmode=verify-full", false, true},
+		{"postgres://app@db.example.com/app?sslmode=disable", false, false},
+		{"postgres://app@db.example.com/app?sslmode=prefer", false, false},
+		{"postgres://app@db.example.com/app?sslmode=disable", true, true},
+		{"host=/var/run/postgresql dbname=app sslmode=disable", false, true},
+	}
+	for _, test := range tests {
+		connConfig, err := pgconn.ParseConfig(test.connString)
+		if err != nil {
+			t.Fatalf("ParseConfig(%s) failed: %v", test.connString, err)
+		}
+		err = Config{AllowInsecure: test.allowInsecure}.checkDatabaseTLS(connConfig)
+		if (err == nil) != test.allowed {
+			t.Errorf("checkDatabaseTLS(%s, allow_insecure=%t) = %v, expected allowed=%t", test.connString, test.allowInsecure, err, test.allowed)
+		}
+	}
+}
+
+func TestApplyDatabaseTLS(t *testing.T) {
+	dir := t.TempDir()
+	_, _, caFile, _ := testCertificate(t, dir, "ca", nil, nil)
+
+	connConfig, err := pgconn.ParseConfig("postgres://app@primary.example.com,replica.example.com/app?sslmode=prefer")
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	config := Config{DBTLSCAFile: caFile, DBTLSVerifyFull: true, DBTLSMinVersion: "1.3"}
+	if err := config.applyDatabaseTLS(connConfig); err != nil {
+		t.Fatalf("applyDatabaseTLS failed: %v", err)
+	}
+	if err := config.checkDatabaseTLS(connConfig); err != nil {
+		t.Errorf("Expected every attempt to use TLS, got %v", err)
+	}
+
+	if len(connConfig.Fallbacks) != 1 || connConfig.Fallbacks[0].Host != "replica.example.com" {
+		t.Fatalf("Expected one TLS attempt per host, got %+v", connConfig.Fallbacks)
+	}
+	for _, tlsConfig := range []*tls.Config{connConfig.TLSConfig, connConfig.Fallbacks[0].TLSConfig} {
+		if tlsConfig.InsecureSkipVerify || tlsConfig.RootCAs == nil || tlsConfig.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Expected verified TLS 1.3 against the CA, got %+v", tlsConfig)
+		}
+	}
+	if connConfig.TLSConfig.ServerName != "primary.example.com" || connConfig.Fallbacks[0].TLSConfig.ServerName != "replica.example.com" {
+		t.Error("Expected the host names to be verified")
+	}
+}
+
+func TestValidateDatabaseTLS(t *testing.T) {
+	for _, config := range []Config{{DBTLSCertFile: "client.crt"}, {DBTLSMinVersion: "1.1"}} {
+		if err := config.validateDatabaseTLS(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
+
+func TestLibpqTLSEnv(t *testing.T) {
+	for sslmode, expected := range map[string]string{"disable": "PGSSLMODE=disable", "require": "PGSSLMODE=require", "verify-ca": "PGSSLMODE=verify-ca", "verify-full": "PGSSLMODE=verify-full"} {
+		connConfig, err := pgconn.ParseConfig("postgres://app@db.example.com/app?sslmode=" + sslmode)
+		if err != nil {
+			t.Fatalf("ParseConfig failed: %v", err)
+		}
+		if env := (Config{}).libpqTLSEnv(connConfig.TLSConfig); len(env) != 1 || env[0] != expected {
+			t.Errorf("Expected %s for sslmode=%s, got %v", expected, sslmode, env)
+		}
+	}
+
+	config := Config{DBTLSCAFile: "ca.crt", DBTLSCertFile: "client.crt", DBTLSKeyFile: "client.key", DBTLSVerifyFull: true, DBTLSMinVersion: "1.3"}
+	env := strings.Join(config.libpqTLSEnv(&tls.Config{}), " ")
+	if env != "PGSSLMODE=verify-full PGSSLROOTCERT=ca.crt PGSSLCERT=client.crt PGSSLKEY=client.key PGSSLMINPROTOCOLVERSION=TLSv1.3" {
+		t.Errorf("Expected the DB_TLS_* settings as libpq variables, got %s", env)
+	}
+
+	config = Config{DBTLSCAFile: "ca.crt"}
+	if env := strings.Join(config.libpqTLSEnv(&tls.Config{}), " "); env != "PGSSLMODE=verify-ca PGSSLROOTCERT=ca.crt" {
+		t.Errorf("Expected verify-ca with a CA file, got %s", env)
+	}
+}
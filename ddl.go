@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type columnDef struct {
+	Name         string
+	Type         string
+	NotNull      bool
+	Default      *string
+	Identity     string
+	Generated    string
+	Sequence     *string
+	TypeCategory string
+}
+
+type constraintDef struct {
+	Name       string
+	Type       string
+	Definition string
+	Columns    []string
+	RefTable   string
+}
+
+// tableDef is enough of a table's catalog entry to recreate it with CREATE TABLE.
+type tableDef struct {
+	Name        string
+	Columns     []columnDef
+	Constraints []constraintDef
+	Indexes     []string
+	PrimaryKey  []string
+}
+
+func (s *serverState) loadTableDef(ctx context.Context, name string) (*tableDef, error) {
+	def := &tableDef{Name: name}
+	regclass := sanitizeQualifiedName(name)
+
+	columnQuery := `
+		SELECT
+			a.attname::text,
+			format_type(a.atttypid, a.atttypmod),
+			a.attnotnull,
+			pg_get_expr(d.adbin, d.adrelid),
+			a.attidentity::text,
+			a.attgenerated::text,
+			pg_get_serial_sequence($1, a.attname),
+			t.typcategory::text
+		FROM pg_attribute a
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1::text::regclass
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`
+
+	rows, err := s.pool.Query(ctx, columnQuery, regclass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load columns for %s: %v", name, err)
+	}
+	for rows.Next() {
+		var column columnDef
+		if err := rows.Scan(&column.Name, &column.Type, &column.NotNull, &column.Default, &column.Identity,
+			&column.Generated, &column.Sequence, &column.TypeCategory); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		def.Columns = append(def.Columns, column)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	constraintQuery := `
+		SELECT
+			con.conname::text,
+			con.contype::text,
+			pg_get_constraintdef(con.oid, true),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			COALESCE(rn.nspname || '.' || rc.relname, '')
+		FROM pg_constraint con
+		LEFT JOIN pg_class rc ON rc.oid = con.confrelid
+		LEFT JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE con.conrelid = $1::text::regclass
+		ORDER BY con.contype, con.conname
+	`
+
+	rows, err = s.pool.Query(ctx, constraintQuery, regclass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load constraints for %s: %v", name, err)
+	}
+	for rows.Next() {
+		var constraint constraintDef
+		if err := rows.Scan(&constraint.Name, &constraint.Type, &constraint.Definition, &constraint.Columns, &constraint.RefTable); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if constraint.Type == "p" {
+			def.PrimaryKey = constraint.Columns
+		}
+		def.Constraints = append(def.Constraints, constraint)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	// indexes backing primary key, unique and exclusion constraints are created with them
+	indexQuery := `
+		SELECT pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		WHERE i.indrelid = $1::text::regclass
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_constraint con
+				WHERE con.conrelid = i.indrelid AND con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x')
+			)
+		ORDER BY i.indexrelid::regclass::text
+	`
+
+	rows, err = s.pool.Query(ctx, indexQuery, regclass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexes for %s: %v", name, err)
+	}
+	for rows.Next() {
+		var indexDef string
+		if err := rows.Scan(&indexDef); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		def.Indexes = append(def.Indexes, indexDef)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if len(def.Columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", name)
+	}
+	return def, nil
+}
+
+// sequenceStatements creates the standalone sequences behind serial defaults,
+// identity sequences are created implicitly by the column definition.
+func (t *tableDef) sequenceStatements() []string {
+	var statements []string
+	for _, column := range t.Columns {
+		if column.Sequence != nil && column.Identity == "" {
+			statements = append(statements, fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s;", *column.Sequence))
+		}
+	}
+	return statements
+}
+
+func (t *tableDef) sequenceOwnershipStatements() []string {
+	var statements []string
+	for _, column := range t.Columns {
+		if column.Sequence != nil && column.Identity == "" {
+			statements = append(statements, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s;",
+				*column.Sequence, sanitizeQualifiedName(t.Name), pgx.Identifier{column.Name}.Sanitize()))
+		}
+	}
+	return statements
+}
+
+// createStatement renders CREATE TABLE with every constraint except foreign
+// keys, which are emitted separately so tables can be created in any order.
+func (t *tableDef) createStatement() string {
+	var lines []string
+	for _, column := range t.Columns {
+		line := fmt.Sprintf("    %s %s", pgx.Identifier{column.Name}.Sanitize(), column.Type)
+		switch {
+		case column.Generated == "s" && column.Default != nil:
+			line += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED", *column.Default)
+		case column.Identity == "a":
+			line += " GENERATED ALWAYS AS IDENTITY"
+		case column.Identity == "d":
+			line += " GENERATED BY DEFAULT AS IDENTITY"
+		case column.Default != nil:
+			line += " DEFAULT " + *column.Default
+		}
+		if column.NotNull {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+	}
+
+	for _, constraint := range t.Constraints {
+		if constraint.Type == "f" || constraint.Type == "n" {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("    CONSTRAINT %s %s", pgx.Identifier{constraint.Name}.Sanitize(), constraint.Definition))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", sanitizeQualifiedName(t.Name), strings.Join(lines, ",\n"))
+}
+
+func (t *tableDef) foreignKeyStatement(constraint constraintDef) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s",
+		sanitizeQualifiedName(t.Name), pgx.Identifier{constraint.Name}.Sanitize(), constraint.Definition)
+}
+
+// insertableColumns excludes generated columns, which cannot be written to.
+func (t *tableDef) insertableColumns() []columnDef {
+	var columns []columnDef
+	for _, column := range t.Columns {
+		if column.Generated == "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func (t *tableDef) hasIdentity() bool {
+	for _, column := range t.Columns {
+		if column.Identity != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortByForeignKeys orders tables so referenced tables come before the tables
+// referencing them. Self references and cycles fall back to the input order.
+func sortByForeignKeys(defs []*tableDef) []*tableDef {
+	byName := make(map[string]*tableDef)
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+
+	var sorted []*tableDef
+	done := make(map[string]bool)
+	visiting := make(map[string]bool)
+	var visit func(def *tableDef)
+	visit = func(def *tableDef) {
+		if done[def.Name] || visiting[def.Name] {
+			return
+		}
+		visiting[def.Name] = true
+		for _, constraint := range def.Constraints {
+			if ref, ok := byName[constraint.RefTable]; ok && constraint.Type == "f" && ref != def {
+				visit(ref)
+			}
+		}
+		visiting[def.Name] = false
+		done[def.Name] = true
+		sorted = append(sorted, def)
+	}
+
+	for _, def := range defs {
+		visit(def)
+	}
+	return sorted
+}
+
+// quoteLiteral renders a text value as a standard-conforming SQL string literal.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// dedupeCountColumn is added to each representative row with the size of
+// its group.
+const dedupeCountColumn = "_count"
+
+// normalizeText folds case and collapses punctuation and spacing, so values
+// differing only in formatting compare equal.
+func normalizeText(text string) string {
+	var out strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && out.Len() > 0 {
+				out.WriteByte(' ')
+			}
+			out.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return out.String()
+}
+
+// textSimilarity is 1 minus the Levenshtein distance relative to the longer
+// text: 1 for equal texts, 0 for entirely different ones.
+func textSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(max(len(ra), len(rb)))
+}
+
+// valuesSimilar compares one column of two rows. Texts are compared after
+// normalizeText when a similarity below 1 is asked for, other values exactly.
+func valuesSimilar(a, b interface{}, similarity float64) bool {
+	textA, okA := a.(string)
+	textB, okB := b.(string)
+	if !okA || !okB || similarity >= 1 {
+		return fmt.Sprint(a) == fmt.Sprint(b)
+	}
+	textA, textB = normalizeText(textA), normalizeText(textB)
+	if textA == textB {
+		return true
+	}
+	// the distance is at least the difference in length
+	shorter, longer := min(len(textA), len(textB)), max(len(textA), len(textB))
+	if float64(shorter)/float64(longer) < similarity {
+		return false
+	}
+	return textSimilarity(textA, textB) >= similarity
+}
+
+// dedupeRows groups rows agreeing on the given columns and returns the first
+// row of each group, in order, with the group's size in dedupeCountColumn.
+// Exact grouping is a hash lookup, fuzzy grouping compares each row with the
+// representatives found so far.
+func dedupeRows(rows []map[string]interface{}, columns []string, similarity float64) []map[string]interface{} {
+	var representatives []map[string]interface{}
+	var counts []int
+	exact := make(map[string]int)
+	for _, row := range rows {
+		group := -1
+		if similarity >= 1 {
+			var key strings.Builder
+			for _, column := range columns {
+				fmt.Fprintf(&key, "%T%q,", row[column], fmt.Sprint(row[column]))
+			}
+			if i, ok := exact[key.String()]; ok {
+				group = i
+			} else {
+				exact[key.String()] = len(representatives)
+			}
+		} else {
+			for i, representative := range representatives {
+				matches := true
+				for _, column := range columns {
+					if !valuesSimilar(representative[column], row[column], similarity) {
+						matches = false
+						break
+					}
+				}
+				if matches {
+					group = i
+					break
+				}
+			}
+		}
+
+		if group >= 0 {
+			counts[group]++
+			continue
+		}
+		representatives = append(representatives, row)
+		counts = append(counts, 1)
+	}
+
+	for i, row := range representatives {
+		row[dedupeCountColumn] = counts[i]
+	}
+	return representatives
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTextSimilarity(t *testing.T) {
+	if normalizeText("  ACME, Inc. ") != "acme inc" {
+		t.Errorf("Unexpected normalization %q", normalizeText("  ACME, Inc. "))
+	}
+	if similarity := textSimilarity("kitten", "sitting"); similarity < 0.57 || similarity > 0.58 {
+		t.Errorf("Expected 1 - 3/7, got %f", similarity)
+	}
+	if !valuesSimilar("Acme Inc.", "acme inc", 0.9) || valuesSimilar("Acme Inc.", "acme inc", 1) {
+		t.Error("Expected normalization to apply to fuzzy matching only")
+	}
+}
+
+func TestDedupeRows(t *testing.T) {
+	rows := func() []map[string]interface{} {
+		return []map[string]interface{}{
+			{"company": "Acme Inc.", "city": "Berlin"},
+			{"company": "ACME Inc", "city": "Berlin"},
+			{"company": "Acme Incorporated", "city": "Berlin"},
+			{"company": "Acme Inc.", "city": "Berlin"},
+			{"company": "Globex", "city": nil},
+		}
+	}
+
+	exact := dedupeRows(rows(), []string{"company", "city"}, 1)
+	if len(exact) != 4 || exact[0][dedupeCountColumn] != 2 {
+		t.Errorf("Expected the two identical rows grouped, got %v", exact)
+	}
+
+	fuzzy := dedupeRows(rows(), []string{"company"}, 0.8)
+	if len(fuzzy) != 3 || fuzzy[0][dedupeCountColumn] != 3 || fuzzy[0]["company"] != "Acme Inc." {
+		t.Errorf("Expected the spellings of Acme Inc grouped under the first, got %v", fuzzy)
+	}
+
+	byCity := dedupeRows(rows(), []string{"city"}, 1)
+	if len(byCity) != 2 || byCity[1][dedupeCountColumn] != 1 {
+		t.Errorf("Expected NULL to form its own group, got %v", byCity)
+	}
+}
+
+func TestQueryDedupe(t *testing.T) {
+	ctx := context.Background()
+
+	args := QueryArgs{Query: "SELECT u.username FROM users u JOIN posts p ON p.user_id = u.id", DedupeColumns: []string{"username"}}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	seen := make(map[interface{}]bool)
+	for _, row := range data.([]map[string]interface{}) {
+		if seen[row["username"]] || row[dedupeCountColumn].(int) < 1 {
+			t.Errorf("Expected one row per user with a count, got %v", row)
+		}
+		seen[row["username"]] = true
+	}
+
+	args = QueryArgs{Query: "SELECT id FROM users", DedupeColumns: []string{"username"}}
+	if result, _, _ := testServer.ExecuteQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a dedupe column missing from the result to be rejected")
+	}
+}
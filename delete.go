@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// deletePreviewRows is how many matching rows a delete_rows preview shows.
+const deletePreviewRows = 5
+
+type DeleteRowsArgs struct {
+	TableName     string         `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema        string         `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Where         []RowCondition `json:"where" jsonschema:"Conditions the rows must all meet, such as [{\"column\": \"id\", \"op\": \"=\", \"value\": 7}]. Required"`
+	ExpectedCount *int64         `json:"expected_count,omitempty" jsonschema:"The number of matching rows a preview reported. Set it to delete them; the delete is rolled back if it affects any other number of rows. Without it only the count and a preview are returned"`
+}
+
+// DeleteRows deletes in two steps: a call without expected_count counts the
+// rows matching a required structured where clause and previews a few, and
+// a call with the count deletes them, rolling back unless exactly that many
+// rows went.
+func (s *serverState) DeleteRows(ctx context.Context, req *mcp.CallToolRequest, args DeleteRowsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if len(args.Where) == 0 {
+		return s.returnErrorResult("where is required, delete_rows never deletes every row of a table")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	columns, err := s.tableColumnTypes(ctx, schema, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up columns: %v", err)
+	}
+	where, err := buildWhere(args.Where, columns)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	table := qualifiedName(schema, tableName)
+	statement := fmt.Sprintf("DELETE FROM %s WHERE %s", table, where)
+
+	if args.ExpectedCount == nil {
+		return s.previewDelete(ctx, req, table, where, statement)
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("delete_rows")
+	}
+	if *args.ExpectedCount <= 0 {
+		return s.returnErrorResult("expected_count must be the positive number of rows to delete")
+	}
+	if s.config.RequireApproval {
+		change, err := s.queuePendingChange(ctx, req, &pendingChange{
+			Tool:      "delete_rows",
+			Summary:   fmt.Sprintf("Delete %d rows of %s", *args.ExpectedCount, table),
+			Statement: statement,
+			MaxRows:   *args.ExpectedCount,
+			ExactRows: true,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	return s.runRowWrite(ctx, req, statement, *args.ExpectedCount)
+}
+
+// previewDelete counts the rows a delete would remove and returns the first
+// few, read the way query returns rows.
+func (s *serverState) previewDelete(ctx context.Context, req *mcp.CallToolRequest, table, where, statement string) (*mcp.CallToolResult, any, error) {
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var count int64
+	if err := tx.QueryRow(ctx, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", table, where)).Scan(&count); err != nil {
+		return s.returnErrorResult("Query error: %v", err)
+	}
+	rows, err := tx.Query(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT %d", table, where, deletePreviewRows))
+	if err != nil {
+		return s.returnErrorResult("Query error: %v", err)
+	}
+	fields := slices.Clone(rows.FieldDescriptions())
+	preview, err := collectRows(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	policies, err := s.resultColumnPolicies(ctx, tx, fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	truncated, _ := encodeBinaryRows(preview, "base64", s.config.MaxBinaryBytes)
+	applyColumnPolicies(preview, policies)
+	masked := s.redactRows(preview)
+	encodeNumericRows(preview, "string")
+	encodeIntervalRows(preview, "iso8601")
+
+	response := map[string]interface{}{
+		"statement":     statement,
+		"matching_rows": count,
+		"preview":       preview,
+	}
+	if count > 0 {
+		response["notice"] = fmt.Sprintf(s.localize("Nothing was deleted. Call again with expected_count set to %d to delete these rows"), count)
+	}
+	result, data, err := returnJSONResult(response)
+	warnings := append(notices, s.binaryWarnings(truncated, nil)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
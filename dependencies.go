@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxDependencyDepth bounds the recursive walk through dependents.
+const maxDependencyDepth = 10
+
+// dependencyTypes name pg_depend.deptype values, "auto" objects being
+// dropped along with what they depend on and "normal" ones needing CASCADE.
+var dependencyTypes = map[string]string{
+	"n": "normal",
+	"a": "auto",
+	"e": "extension",
+	"x": "auto extension",
+}
+
+// dependencySelf is an object together with the parts of it that carry its
+// dependencies: a relation's view rule, defaults, constraints, triggers,
+// indexes and policies. via describes the part, and is NULL for the object
+// itself and for a view's rule.
+const dependencySelf = `
+	WITH self(classid, objid, via, internal) AS (
+		SELECT $1::oid, $2::oid, NULL::text, false
+		UNION ALL
+		SELECT 'pg_rewrite'::regclass::oid, oid, NULL, false
+		FROM pg_rewrite WHERE $1::oid = 'pg_class'::regclass::oid AND ev_class = $2::oid
+		UNION ALL
+		SELECT 'pg_attrdef'::regclass::oid, oid, pg_describe_object('pg_attrdef'::regclass, oid, 0), false
+		FROM pg_attrdef WHERE $1::oid = 'pg_class'::regclass::oid AND adrelid = $2::oid
+		UNION ALL
+		SELECT 'pg_constraint'::regclass::oid, oid, pg_describe_object('pg_constraint'::regclass, oid, 0), false
+		FROM pg_constraint WHERE $1::oid = 'pg_class'::regclass::oid AND conrelid = $2::oid
+		UNION ALL
+		SELECT 'pg_trigger'::regclass::oid, oid, pg_describe_object('pg_trigger'::regclass, oid, 0), tgisinternal
+		FROM pg_trigger WHERE $1::oid = 'pg_class'::regclass::oid AND tgrelid = $2::oid
+		UNION ALL
+		SELECT 'pg_class'::regclass::oid, indexrelid, pg_describe_object('pg_class'::regclass, indexrelid, 0), false
+		FROM pg_index WHERE $1::oid = 'pg_class'::regclass::oid AND indrelid = $2::oid
+		UNION ALL
+		SELECT 'pg_policy'::regclass::oid, oid, pg_describe_object('pg_policy'::regclass, oid, 0), false
+		FROM pg_policy WHERE $1::oid = 'pg_class'::regclass::oid AND polrelid = $2::oid
+	)`
+
+type DependenciesArgs struct {
+	Name      string `json:"name" jsonschema:"Name of the object, optionally schema-qualified. Functions may include their argument types, as in my_func(integer)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public for relations, the search_path otherwise)"`
+	Kind      string `json:"kind,omitempty" jsonschema:"relation (a table, view, materialized view, sequence or foreign table), function or type (default: relation)"`
+	Recursive bool   `json:"recursive,omitempty" jsonschema:"Also list what depends on the dependents, everything a DROP ... CASCADE would reach (default: false)"`
+}
+
+// dependencyObject is an object in pg_depend's terms.
+type dependencyObject struct {
+	classID  uint32
+	objID    uint32
+	identity string
+}
+
+// GetDependencies reads pg_depend, through a relation's rules, defaults,
+// constraints, triggers and indexes, to report what an object depends on
+// and what depends on it, the impact of dropping or altering it.
+func (s *serverState) GetDependencies(ctx context.Context, req *mcp.CallToolRequest, args DependenciesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if args.Name == "" {
+		return s.returnErrorResult("name is required")
+	}
+
+	var catalog, cast, name string
+	switch strings.ToLower(args.Kind) {
+	case "", "relation":
+		schema, table, err := s.resolveTableName(ctx, args.Schema, args.Name)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(schema, table) {
+			return s.returnNotAccessible(qualifiedName(schema, table))
+		}
+		catalog, cast, name = "pg_class", "regclass", pgx.Identifier{schema, table}.Sanitize()
+	case "function":
+		catalog, cast, name = "pg_proc", "regproc", args.Name
+		if strings.Contains(args.Name, "(") {
+			cast = "regprocedure"
+		}
+	case "type":
+		catalog, cast, name = "pg_type", "regtype", args.Name
+	default:
+		return s.returnErrorResult("Unknown kind %q, use relation, function or type", args.Kind)
+	}
+	if catalog != "pg_class" && args.Schema != "" && !strings.Contains(strings.SplitN(name, "(", 2)[0], ".") {
+		name = pgx.Identifier{args.Schema}.Sanitize() + "." + name
+	}
+
+	var root dependencyObject
+	var objectType string
+	var objectSchema *string
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT $1::text::regclass::oid, o.objid, i.type, i.schema, i.identity
+		FROM (SELECT $2::text::%s::oid AS objid) o
+		CROSS JOIN LATERAL pg_identify_object($1::text::regclass, o.objid, 0) i
+	`, cast), catalog, name).Scan(&root.classID, &root.objID, &objectType, &objectSchema, &root.identity)
+	if err != nil {
+		return s.returnErrorResult("%s not found: %v", args.Name, err)
+	}
+	if objectSchema != nil && !s.schemaAllowed(*objectSchema) {
+		return s.returnNotAccessible(root.identity)
+	}
+
+	dependsOn, err := s.dependsOn(ctx, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// breadth first, so each dependent is reported at its shortest depth
+	dependents := []map[string]interface{}{}
+	visited := map[dependencyObject]bool{{classID: root.classID, objID: root.objID}: true}
+	level := []dependencyObject{root}
+	requiresCascade := false
+	for depth := 1; len(level) > 0 && depth <= maxDependencyDepth; depth++ {
+		var next []dependencyObject
+		for _, object := range level {
+			found, objects, err := s.dependentsOf(ctx, object)
+			if err != nil {
+				return nil, nil, err
+			}
+			for i, dependent := range found {
+				key := dependencyObject{classID: objects[i].classID, objID: objects[i].objID}
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				if depth == 1 && dependent["dependency"] == "normal" {
+					requiresCascade = true
+				}
+				dependent["depth"] = depth
+				if depth > 1 {
+					dependent["via"] = object.identity
+				}
+				dependents = append(dependents, dependent)
+				next = append(next, objects[i])
+			}
+		}
+		if !args.Recursive {
+			break
+		}
+		level = next
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"object":                root.identity,
+		"type":                  objectType,
+		"depends_on":            dependsOn,
+		"dependents":            dependents,
+		"drop_requires_cascade": requiresCascade,
+	})
+}
+
+// dependsOn lists the user-defined objects an object, or one of its parts,
+// depends on. Built-in objects and schemas are left out.
+func (s *serverState) dependsOn(ctx context.Context, object dependencyObject) ([]map[string]interface{}, error) {
+	rows, err := s.pool.Query(ctx, dependencySelf+`
+		SELECT DISTINCT i.type, i.schema, i.identity, d.deptype::text, self.via
+		FROM self
+		JOIN pg_depend d ON d.classid = self.classid AND d.objid = self.objid
+		CROSS JOIN LATERAL pg_identify_object(d.refclassid, d.refobjid, d.refobjsubid) i
+		WHERE d.deptype IN ('n', 'a', 'e', 'x')
+			AND d.refobjid >= 16384
+			AND d.refclassid <> 'pg_namespace'::regclass
+			AND NOT self.internal
+			AND NOT EXISTS (SELECT 1 FROM self part WHERE part.classid = d.refclassid AND part.objid = d.refobjid)
+		ORDER BY 3, 5
+	`, object.classID, object.objID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dependencies: %v", err)
+	}
+	defer rows.Close()
+
+	found := []map[string]interface{}{}
+	for rows.Next() {
+		var objectType, identity, deptype string
+		var schema, via *string
+		if err := rows.Scan(&objectType, &schema, &identity, &deptype, &via); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if schema != nil && !s.schemaAllowed(*schema) {
+			continue
+		}
+		dependency := map[string]interface{}{
+			"type":       objectType,
+			"object":     identity,
+			"dependency": dependencyTypes[deptype],
+		}
+		addOptionalString(dependency, "via", via)
+		found = append(found, dependency)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return found, nil
+}
+
+// dependentsOf lists the objects that depend on an object or one of its
+// parts, with views standing in for the rules that implement them.
+func (s *serverState) dependentsOf(ctx context.Context, object dependencyObject) ([]map[string]interface{}, []dependencyObject, error) {
+	rows, err := s.pool.Query(ctx, dependencySelf+`
+		SELECT DISTINCT n.classid, n.objid, i.type, i.schema, i.identity, d.deptype::text
+		FROM self
+		JOIN pg_depend d ON d.refclassid = self.classid AND d.refobjid = self.objid
+		LEFT JOIN pg_rewrite r ON d.classid = 'pg_rewrite'::regclass AND r.oid = d.objid
+		CROSS JOIN LATERAL (
+			SELECT
+				CASE WHEN r.oid IS NULL THEN d.classid ELSE 'pg_class'::regclass::oid END AS classid,
+				COALESCE(r.ev_class, d.objid) AS objid,
+				CASE WHEN r.oid IS NULL THEN d.objsubid ELSE 0 END AS objsubid
+		) n
+		CROSS JOIN LATERAL pg_identify_object(n.classid, n.objid, n.objsubid) i
+		WHERE d.deptype IN ('n', 'a', 'e', 'x')
+			AND NOT EXISTS (SELECT 1 FROM self part WHERE part.classid = n.classid AND part.objid = n.objid)
+		ORDER BY 5
+	`, object.classID, object.objID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read dependents: %v", err)
+	}
+	defer rows.Close()
+
+	var found []map[string]interface{}
+	var objects []dependencyObject
+	for rows.Next() {
+		var dependent dependencyObject
+		var objectType, deptype string
+		var schema *string
+		if err := rows.Scan(&dependent.classID, &dependent.objID, &objectType, &schema, &dependent.identity, &deptype); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if schema != nil && !s.schemaAllowed(*schema) {
+			continue
+		}
+		found = append(found, map[string]interface{}{
+			"type":       objectType,
+			"object":     dependent.identity,
+			"dependency": dependencyTypes[deptype],
+		})
+		objects = append(objects, dependent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return found, objects, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetDependencies(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE dep_base (id serial PRIMARY KEY, amount int);
+		CREATE FUNCTION dep_double(int) RETURNS int LANGUAGE sql IMMUTABLE AS 'SELECT $1 * 2';
+		CREATE VIEW dep_view AS SELECT id, dep_double(amount) AS doubled FROM dep_base;
+		CREATE VIEW dep_view_top AS SELECT id FROM dep_view;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create objects: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE dep_base CASCADE; DROP FUNCTION dep_double(int)")
+
+	dependents := func(args DependenciesArgs) map[string]map[string]interface{} {
+		t.Helper()
+		result, data, err := testServer.GetDependencies(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("GetDependencies failed: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+		byObject := make(map[string]map[string]interface{})
+		for _, dependent := range data.(map[string]interface{})["dependents"].([]map[string]interface{}) {
+			byObject[dependent["object"].(string)] = dependent
+		}
+		return byObject
+	}
+
+	found := dependents(DependenciesArgs{Name: "dep_base"})
+	if view := found["public.dep_view"]; view == nil || view["dependency"] != "normal" {
+		t.Errorf("Expected dep_view as a normal dependent, got %v", found)
+	}
+	if sequence := found["public.dep_base_id_seq"]; sequence == nil || sequence["dependency"] != "auto" {
+		t.Errorf("Expected the owned sequence as an auto dependent, got %v", found)
+	}
+	if found["public.dep_view_top"] != nil {
+		t.Error("Expected only direct dependents without recursive")
+	}
+
+	found = dependents(DependenciesArgs{Name: "dep_base", Recursive: true})
+	if top := found["public.dep_view_top"]; top == nil || top["depth"] != 2 || top["via"] != "public.dep_view" {
+		t.Errorf("Expected dep_view_top at depth 2 through dep_view, got %v", top)
+	}
+
+	found = dependents(DependenciesArgs{Name: "dep_double(integer)", Kind: "function"})
+	if found["public.dep_view"] == nil {
+		t.Errorf("Expected dep_view to depend on the function, got %v", found)
+	}
+
+	args := DependenciesArgs{Name: "dep_view"}
+	_, data, err := testServer.GetDependencies(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("GetDependencies failed: %v", err)
+	}
+	response := data.(map[string]interface{})
+	objects := make(map[string]bool)
+	for _, dependency := range response["depends_on"].([]map[string]interface{}) {
+		objects[dependency["object"].(string)] = true
+	}
+	if !objects["public.dep_base"] && !objects["public.dep_base.id"] || !objects["public.dep_double(integer)"] {
+		t.Errorf("Expected dep_view to depend on dep_base and dep_double, got %v", objects)
+	}
+	if response["drop_requires_cascade"] != true {
+		t.Error("Expected dropping dep_view to require CASCADE")
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// relationKinds name pg_class.relkind values.
+var relationKinds = map[string]string{
+	"r": "table",
+	"p": "partitioned table",
+	"v": "view",
+	"m": "materialized view",
+	"f": "foreign table",
+}
+
+type DescribeTableArgs struct {
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+// DescribeTable answers everything a model usually asks about a table
+// before querying it in one call: what get_table_schema,
+// get_table_constraints and get_table_indexes report, plus triggers,
+// row-level security, sizes, the row estimate and the comment. The
+// relation's own catalog entries are read in one batch.
+func (s *serverState) DescribeTable(ctx context.Context, req *mcp.CallToolRequest, args DescribeTableArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	schema, table, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, table) {
+		return s.returnNotAccessible(qualifiedName(schema, table))
+	}
+	regclass := pgx.Identifier{schema, table}.Sanitize()
+
+	batch := &pgx.Batch{}
+	var kind string
+	var comment *string
+	var estimatedRows, totalBytes, tableBytes, indexBytes int64
+	var statsMissing, rlsEnabled, rlsForced bool
+	// estimated as estimate_row_count does
+	batch.Queue(`
+		SELECT
+			c.relkind::text,
+			obj_description(c.oid, 'pg_class'),
+			CASE WHEN c.relkind = 'p' THEN (
+				SELECT COALESCE(SUM(GREATEST(ch.reltuples, 0)), 0)
+				FROM pg_inherits inh
+				JOIN pg_class ch ON ch.oid = inh.inhrelid
+				WHERE inh.inhparent = c.oid
+			) ELSE GREATEST(c.reltuples, 0) END::bigint,
+			c.relkind IN ('r', 'm') AND (c.reltuples < 0 OR (c.reltuples = 0 AND c.relpages = 0)),
+			pg_total_relation_size(c.oid),
+			pg_table_size(c.oid),
+			pg_indexes_size(c.oid),
+			c.relrowsecurity,
+			c.relforcerowsecurity
+		FROM pg_class c
+		WHERE c.oid = $1::text::regclass
+	`, regclass).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&kind, &comment, &estimatedRows, &statsMissing, &totalBytes, &tableBytes, &indexBytes, &rlsEnabled, &rlsForced)
+	})
+
+	triggers := []map[string]interface{}{}
+	batch.Queue(`
+		SELECT t.tgname::text, pg_get_triggerdef(t.oid, true), t.tgenabled <> 'D'
+		FROM pg_trigger t
+		WHERE t.tgrelid = $1::text::regclass AND NOT t.tgisinternal
+		ORDER BY t.tgname
+	`, regclass).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var name, definition string
+			var enabled bool
+			if err := rows.Scan(&name, &definition, &enabled); err != nil {
+				return err
+			}
+			triggers = append(triggers, map[string]interface{}{"name": name, "definition": definition, "enabled": enabled})
+		}
+		return rows.Err()
+	})
+
+	policies := []map[string]interface{}{}
+	batch.Queue(`
+		SELECT policyname::text, permissive, roles::text[], cmd, qual, with_check
+		FROM pg_policies
+		WHERE schemaname = $1 AND tablename = $2
+		ORDER BY policyname
+	`, schema, table).Query(func(rows pgx.Rows) error {
+		for rows.Next() {
+			var name, permissive, command string
+			var roles []string
+			var using, withCheck *string
+			if err := rows.Scan(&name, &permissive, &roles, &command, &using, &withCheck); err != nil {
+				return err
+			}
+			policy := map[string]interface{}{"name": name, "permissive": permissive == "PERMISSIVE", "roles": roles, "command": command}
+			addOptionalString(policy, "using", using)
+			addOptionalString(policy, "with_check", withCheck)
+			policies = append(policies, policy)
+		}
+		return rows.Err()
+	})
+
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to describe %s: %v", qualifiedName(schema, table), err)
+	}
+
+	response := map[string]interface{}{
+		"schema":         schema,
+		"name":           table,
+		"kind":           relationKinds[kind],
+		"estimated_rows": estimatedRows,
+		"size": map[string]interface{}{
+			"total_bytes": totalBytes,
+			"table_bytes": tableBytes,
+			"index_bytes": indexBytes,
+		},
+		"triggers": triggers,
+		"row_level_security": map[string]interface{}{
+			"enabled":  rlsEnabled,
+			"forced":   rlsForced,
+			"policies": policies,
+		},
+	}
+	addOptionalString(response, "comment", comment)
+	if statsMissing {
+		response["stats_missing"] = true
+	}
+
+	columnsResult, columns, err := s.GetTableSchema(ctx, req, TableSchemaArgs{TableName: table, Schema: schema})
+	if err != nil || columnsResult.IsError {
+		return columnsResult, nil, err
+	}
+	response["columns"] = columns
+
+	constraintsResult, constraints, err := s.GetTableConstraints(ctx, req, TableConstraintsArgs{TableName: table, Schema: schema})
+	if err != nil || constraintsResult.IsError {
+		return constraintsResult, nil, err
+	}
+	response["constraints"] = constraints
+
+	indexesResult, indexes, err := s.GetTableIndexes(ctx, req, TableIndexesArgs{TableName: table, Schema: schema})
+	if err != nil || indexesResult.IsError {
+		return indexesResult, nil, err
+	}
+	response["indexes"] = indexes
+
+	return returnJSONResult(response)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDescribeTable(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE described (id serial PRIMARY KEY, owner text NOT NULL, note text);
+		COMMENT ON TABLE described IS 'Notes per owner';
+		CREATE INDEX described_owner_idx ON described (owner);
+		CREATE FUNCTION described_touch() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN RETURN NEW; END $$;
+		CREATE TRIGGER described_touch BEFORE UPDATE ON described FOR EACH ROW EXECUTE FUNCTION described_touch();
+		ALTER TABLE described ENABLE ROW LEVEL SECURITY;
+		CREATE POLICY own_rows ON described USING (owner = current_user);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE described; DROP FUNCTION described_touch()")
+
+	args := DescribeTableArgs{TableName: "described"}
+	result, data, err := testServer.DescribeTable(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("DescribeTable failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	response := data.(map[string]interface{})
+	if response["kind"] != "table" || response["comment"] != "Notes per owner" {
+		t.Errorf("Unexpected kind or comment in %v", response)
+	}
+	if columns := response["columns"].([]map[string]interface{}); len(columns) != 3 {
+		t.Errorf("Expected 3 columns, got %d", len(columns))
+	}
+	if len(response["constraints"].([]map[string]interface{})) == 0 || len(response["indexes"].([]map[string]interface{})) == 0 {
+		t.Error("Expected the primary key among constraints and indexes")
+	}
+	if triggers := response["triggers"].([]map[string]interface{}); len(triggers) != 1 || triggers[0]["name"] != "described_touch" {
+		t.Errorf("Expected the trigger, got %v", triggers)
+	}
+	rls := response["row_level_security"].(map[string]interface{})
+	if policies := rls["policies"].([]map[string]interface{}); rls["enabled"] != true || len(policies) != 1 || policies[0]["using"] == nil {
+		t.Errorf("Expected row-level security with one policy, got %v", rls)
+	}
+	if size := response["size"].(map[string]interface{}); size["total_bytes"].(int64) <= 0 {
+		t.Errorf("Expected a size, got %v", size)
+	}
+}
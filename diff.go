@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultDiffLimit = 100
+	maxDatasetRows   = 50000
+)
+
+type DiffDatasetArgs struct {
+	TableName   string   `json:"table_name" jsonschema:"Table to compare the dataset with, optionally schema-qualified"`
+	Schema      string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Data        string   `json:"data" jsonschema:"The dataset: CSV with a header row, or a JSON array of objects. Column names must match the table's. Empty CSV fields are NULL"`
+	Format      string   `json:"format,omitempty" jsonschema:"csv or json (default: detected from the data)"`
+	KeyColumns  []string `json:"key_columns" jsonschema:"Columns identifying a row in both the dataset and the table, usually the primary key"`
+	IgnoreExtra bool     `json:"ignore_extra,omitempty" jsonschema:"Don't report (or delete) table rows the dataset doesn't have, for datasets covering part of the table"`
+	GenerateSQL bool     `json:"generate_sql,omitempty" jsonschema:"Also return the INSERT, UPDATE and DELETE statements that make the table match the dataset. They are not executed"`
+	Limit       int      `json:"limit,omitempty" jsonschema:"Maximum differences to list per kind (default: 100). Counts and generated SQL cover all of them"`
+	OutputPath  string   `json:"output_path,omitempty" jsonschema:"Write the generated SQL to this file in EXPORT_DIR, relative to it, instead of returning it inline"`
+}
+
+// parseDataset reads a CSV or JSON dataset into rows keyed by column name.
+// CSV values are strings, or nil for empty fields; JSON values are kept as
+// decoded, numbers as written.
+func parseDataset(format, data string) ([]string, []map[string]interface{}, error) {
+	if format == "" {
+		format = "csv"
+		if strings.HasPrefix(strings.TrimSpace(data), "[") {
+			format = "json"
+		}
+	}
+
+	var columns []string
+	var rows []map[string]interface{}
+	switch format {
+	case "csv":
+		reader := csv.NewReader(strings.NewReader(data))
+		header, err := reader.Read()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the CSV header: %v", err)
+		}
+		for _, column := range header {
+			column = strings.TrimSpace(column)
+			if slices.Contains(columns, column) {
+				return nil, nil, fmt.Errorf("column %q appears twice in the CSV header", column)
+			}
+			columns = append(columns, column)
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to read the CSV: %v", err)
+			}
+			row := make(map[string]interface{})
+			for i, value := range record {
+				if value == "" {
+					row[columns[i]] = nil
+				} else {
+					row[columns[i]] = value
+				}
+			}
+			rows = append(rows, row)
+		}
+
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&rows); err != nil {
+			return nil, nil, fmt.Errorf("the JSON dataset must be an array of objects: %v", err)
+		}
+		for _, row := range rows {
+			for column := range row {
+				if !slices.Contains(columns, column) {
+					columns = append(columns, column)
+				}
+			}
+		}
+
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q, expected csv or json", format)
+	}
+	return columns, rows, nil
+}
+
+// datasetKey renders a row's key values for duplicate detection, failing on
+// a missing key.
+func datasetKey(row map[string]interface{}, keyColumns []string) (string, error) {
+	var parts []string
+	for _, column := range keyColumns {
+		value := row[column]
+		if value == nil {
+			return "", fmt.Errorf("a dataset row has no value for key column %s", column)
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// comparableColumn is a column reference that can be compared with IS
+// DISTINCT FROM, for types without an equality operator.
+func comparableColumn(reference string, column columnDef) string {
+	switch column.Type {
+	case "json":
+		return reference + "::jsonb"
+	case "xml":
+		return reference + "::text"
+	}
+	return reference
+}
+
+// sqlValue renders a value for generated DML as an untyped literal, which
+// PostgreSQL casts to the column's type.
+func sqlValue(value *string) string {
+	if value == nil {
+		return "NULL"
+	}
+	return quoteLiteral(*value)
+}
+
+func textValue(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func keyCondition(keyColumns []string, keyValues []*string) string {
+	var conditions []string
+	for i, column := range keyColumns {
+		conditions = append(conditions, fmt.Sprintf("%s = %s", pgx.Identifier{column}.Sanitize(), sqlValue(keyValues[i])))
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// DiffDataset compares a dataset, such as a spreadsheet export, with a table
+// row by row on key columns: rows missing from the table, rows only the table
+// has, and rows whose values differ. Values are cast to the column types by
+// the database before comparing, so "1.50" matches a numeric 1.5.
+func (s *serverState) DiffDataset(ctx context.Context, req *mcp.CallToolRequest, args DiffDatasetArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if args.TableName == "" || args.Data == "" || len(args.KeyColumns) == 0 {
+		return s.returnErrorResult("table_name, data and key_columns are required")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultDiffLimit
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	def, err := s.loadTableDef(ctx, schema+"."+tableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	datasetColumns, datasetRows, err := parseDataset(args.Format, args.Data)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if len(datasetRows) > maxDatasetRows {
+		return s.returnErrorResult("The dataset has %d rows, more than the %d that can be compared at once", len(datasetRows), maxDatasetRows)
+	}
+	for _, column := range datasetColumns {
+		index := slices.IndexFunc(def.Columns, func(c columnDef) bool { return c.Name == column })
+		if index < 0 {
+			return s.returnErrorResult("Column %q of the dataset does not exist in %s", column, qualifiedName(schema, tableName))
+		}
+		if def.Columns[index].Generated != "" {
+			return s.returnErrorResult("Column %q is generated and cannot be compared with the dataset", column)
+		}
+	}
+	for _, column := range args.KeyColumns {
+		if !slices.Contains(datasetColumns, column) {
+			return s.returnErrorResult("Key column %q is not in the dataset", column)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, row := range datasetRows {
+		key, err := datasetKey(row, args.KeyColumns)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if seen[key] {
+			return s.returnErrorResult("The dataset has more than one row with key %s", strings.ReplaceAll(key, "\x00", ", "))
+		}
+		seen[key] = true
+	}
+
+	// compare in table column order, so reports and statements read like the table
+	var compared []columnDef
+	for _, column := range def.Columns {
+		if slices.Contains(datasetColumns, column.Name) && !slices.Contains(args.KeyColumns, column.Name) {
+			compared = append(compared, column)
+		}
+	}
+
+	// the dataset goes through jsonb_populate_recordset so every value is cast
+	// with the column's own input function, as an INSERT would
+	table := pgx.Identifier{schema, tableName}.Sanitize()
+	var joins, keys, datasetValues, tableValues, distinct []string
+	for _, column := range args.KeyColumns {
+		name := pgx.Identifier{column}.Sanitize()
+		joins = append(joins, fmt.Sprintf("(d.dr).%[1]s = (t.tr).%[1]s", name))
+		keys = append(keys, fmt.Sprintf("COALESCE((d.dr).%[1]s, (t.tr).%[1]s)::text", name))
+	}
+	for _, column := range compared {
+		name := pgx.Identifier{column.Name}.Sanitize()
+		datasetValues = append(datasetValues, fmt.Sprintf("(d.dr).%s::text", name))
+		tableValues = append(tableValues, fmt.Sprintf("(t.tr).%s::text", name))
+		distinct = append(distinct, fmt.Sprintf("%s IS DISTINCT FROM %s", comparableColumn("(d.dr)."+name, column), comparableColumn("(t.tr)."+name, column)))
+	}
+	join := "FULL JOIN"
+	if args.IgnoreExtra {
+		join = "LEFT JOIN"
+	}
+	where := "d.present IS NULL OR t.present IS NULL"
+	if len(distinct) > 0 {
+		where += " OR " + strings.Join(distinct, " OR ")
+	}
+	query := fmt.Sprintf(`
+		WITH d AS (SELECT r AS dr, true AS present FROM jsonb_populate_recordset(NULL::%[1]s, $1::jsonb) AS r),
+		t AS (SELECT r AS tr, true AS present FROM %[1]s AS r)
+		SELECT d.present IS NOT NULL, t.present IS NOT NULL, ARRAY[%[2]s]::text[],
+			ARRAY[%[3]s]::text[], ARRAY[%[4]s]::text[], ARRAY[%[5]s]::boolean[]
+		FROM d %[6]s t ON %[7]s
+		WHERE %[8]s
+		ORDER BY %[9]s
+	`, table, strings.Join(keys, ", "), strings.Join(datasetValues, ", "), strings.Join(tableValues, ", "),
+		strings.Join(distinct, ", "), join, strings.Join(joins, " AND "), where, strings.Join(keys, ", "))
+
+	payload, err := json.Marshal(datasetRows)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode the dataset: %v", err)
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, string(payload))
+	if err != nil {
+		return s.returnErrorResult("Failed to compare the dataset with %s: %v", qualifiedName(schema, tableName), err)
+	}
+	defer rows.Close()
+
+	keyMap := func(values []*string) map[string]interface{} {
+		key := make(map[string]interface{})
+		for i, column := range args.KeyColumns {
+			key[column] = textValue(values[i])
+		}
+		return key
+	}
+
+	target := sanitizeQualifiedName(schema + "." + tableName)
+	var insertColumns []string
+	overriding := false
+	for _, column := range def.Columns {
+		if slices.Contains(datasetColumns, column.Name) {
+			insertColumns = append(insertColumns, pgx.Identifier{column.Name}.Sanitize())
+			overriding = overriding || column.Identity == "a"
+		}
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s)", target, strings.Join(insertColumns, ", "))
+	if overriding {
+		insert += " OVERRIDING SYSTEM VALUE"
+	}
+
+	var inserts, updates, deletes []string
+	var missing, extra, differing []map[string]interface{}
+	var tableSide []map[string]interface{}
+	missingCount, extraCount, changedCount := 0, 0, 0
+	for rows.Next() {
+		var inDataset, inTable bool
+		var keyValues, newValues, oldValues []*string
+		var columnChanged []bool
+		if err := rows.Scan(&inDataset, &inTable, &keyValues, &newValues, &oldValues, &columnChanged); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		switch {
+		case !inTable:
+			missingCount++
+			if len(missing) < limit {
+				missing = append(missing, map[string]interface{}{"key": keyMap(keyValues)})
+			}
+			if args.GenerateSQL {
+				// keys and compared columns, back in table column order
+				byName := make(map[string]*string)
+				for i, column := range args.KeyColumns {
+					byName[column] = keyValues[i]
+				}
+				for i, column := range compared {
+					byName[column.Name] = newValues[i]
+				}
+				var values []string
+				for _, column := range def.Columns {
+					if slices.Contains(datasetColumns, column.Name) {
+						values = append(values, sqlValue(byName[column.Name]))
+					}
+				}
+				inserts = append(inserts, "("+strings.Join(values, ", ")+")")
+			}
+
+		case !inDataset:
+			extraCount++
+			if len(extra) < limit {
+				key := keyMap(keyValues)
+				extra = append(extra, map[string]interface{}{"key": key})
+				tableSide = append(tableSide, key)
+			}
+			if args.GenerateSQL {
+				deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE %s;", target, keyCondition(args.KeyColumns, keyValues)))
+			}
+
+		default:
+			changedCount++
+			var assignments []string
+			tableValues := make(map[string]interface{})
+			datasetValues := make(map[string]interface{})
+			for i, column := range compared {
+				if !columnChanged[i] {
+					continue
+				}
+				tableValues[column.Name] = textValue(oldValues[i])
+				datasetValues[column.Name] = textValue(newValues[i])
+				assignments = append(assignments, fmt.Sprintf("%s = %s", pgx.Identifier{column.Name}.Sanitize(), sqlValue(newValues[i])))
+			}
+			if len(differing) < limit {
+				differing = append(differing, map[string]interface{}{
+					"key":     keyMap(keyValues),
+					"table":   tableValues,
+					"dataset": datasetValues,
+				})
+				tableSide = append(tableSide, tableValues)
+			}
+			if args.GenerateSQL {
+				updates = append(updates, fmt.Sprintf("UPDATE %s SET %s WHERE %s;", target, strings.Join(assignments, ", "), keyCondition(args.KeyColumns, keyValues)))
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return s.returnErrorResult("Failed to compare the dataset with %s: %v", qualifiedName(schema, tableName), err)
+	}
+
+	// only values read from the table are masked, the dataset came from the caller
+	policies := s.tableColumnPolicies(schema, tableName, tableSide)
+	applyColumnPolicies(tableSide, policies)
+	masked := s.redactRows(tableSide)
+
+	response := map[string]interface{}{
+		"table":         qualifiedName(schema, tableName),
+		"dataset_rows":  len(datasetRows),
+		"missing_count": missingCount,
+		"changed_count": changedCount,
+		"missing":       missing,
+		"changed":       differing,
+		"in_sync":       missingCount+extraCount+changedCount == 0,
+	}
+	if !args.IgnoreExtra {
+		response["extra_count"] = extraCount
+		response["extra"] = extra
+	}
+	if len(datasetColumns) < len(def.insertableColumns()) {
+		var ignored []string
+		for _, column := range def.insertableColumns() {
+			if !slices.Contains(datasetColumns, column.Name) {
+				ignored = append(ignored, column.Name)
+			}
+		}
+		response["columns_not_compared"] = ignored
+	}
+
+	if args.GenerateSQL {
+		var out strings.Builder
+		fmt.Fprintf(&out, "-- Reconcile %s with the dataset, generated by postgres-mcp\n", qualifiedName(schema, tableName))
+		out.WriteString("BEGIN;\n\n")
+		for start := 0; start < len(inserts); start += fixtureInsertBatch {
+			batch := inserts[start:min(start+fixtureInsertBatch, len(inserts))]
+			fmt.Fprintf(&out, "%s VALUES\n    %s;\n", insert, strings.Join(batch, ",\n    "))
+		}
+		for _, statement := range append(updates, deletes...) {
+			out.WriteString(statement + "\n")
+		}
+		out.WriteString("\nCOMMIT;\n")
+		sql := out.String()
+
+		switch {
+		case args.OutputPath != "":
+			path, err := s.exportPath(args.OutputPath)
+			if err != nil {
+				return s.returnErrorResult("%v", err)
+			}
+			encrypted, err := s.writeArtifact(path, []byte(sql))
+			if err != nil {
+				return s.returnErrorResult("Failed to write the SQL: %v", err)
+			}
+			response["output_path"] = args.OutputPath
+			response["encrypted"] = encrypted
+		case len(sql) > maxInlineFixtureBytes:
+			return s.returnErrorResult("The SQL is %d bytes, which is too large to return inline. Set output_path", len(sql))
+		default:
+			response["sql"] = sql
+		}
+	}
+
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, append(s.columnPolicyWarnings(policies), s.piiWarnings(masked)...)), data, err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseDataset(t *testing.T) {
+	columns, rows, err := parseDataset("", "id,name\n1,alice\n2,\n")
+	if err != nil {
+		t.Fatalf("parseDataset failed: %v", err)
+	}
+	if strings.Join(columns, ",") != "id,name" || len(rows) != 2 || rows[0]["name"] != "alice" || rows[1]["name"] != nil {
+		t.Errorf("Unexpected CSV dataset %v %v", columns, rows)
+	}
+
+	columns, rows, err = parseDataset("", `[{"id": 1, "price": 1.50}, {"id": 2, "price": null}]`)
+	if err != nil {
+		t.Fatalf("parseDataset failed: %v", err)
+	}
+	if len(columns) != 2 || len(rows) != 2 || fmt.Sprint(rows[0]["price"]) != "1.50" {
+		t.Errorf("Unexpected JSON dataset %v %v", columns, rows)
+	}
+
+	for _, data := range []string{"id,id\n1,2\n", `{"id": 1}`} {
+		if _, _, err := parseDataset("", data); err == nil {
+			t.Errorf("Expected %q to be rejected", data)
+		}
+	}
+	if _, err := datasetKey(map[string]interface{}{"id": nil}, []string{"id"}); err == nil {
+		t.Error("Expected a missing key to be rejected")
+	}
+}
+
+func TestDiffDataset(t *testing.T) {
+	ctx := context.Background()
+
+	var firstID, secondID int
+	var firstName, secondName string
+	err := testServer.pool.QueryRow(ctx, `
+		SELECT min(id), max(id), (array_agg(username ORDER BY id))[1], (array_agg(username ORDER BY id DESC))[1]
+		FROM (SELECT id, username FROM users ORDER BY id LIMIT 2) u
+	`).Scan(&firstID, &secondID, &firstName, &secondName)
+	if err != nil {
+		t.Fatalf("Failed to read users: %v", err)
+	}
+
+	// one unchanged row, one changed row and one the table doesn't have
+	data := fmt.Sprintf("id,username\n%d,%s\n%d,renamed_user\n999999,new_user\n", firstID, firstName, secondID)
+	args := DiffDatasetArgs{TableName: "users", Data: data, KeyColumns: []string{"id"}, IgnoreExtra: true, GenerateSQL: true}
+	result, response, err := testServer.DiffDataset(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("DiffDataset failed: %v %v", err, result)
+	}
+
+	diff := response.(map[string]interface{})
+	if diff["missing_count"] != 1 || diff["changed_count"] != 1 {
+		t.Errorf("Expected one missing and one changed row, got %v", diff)
+	}
+	if _, ok := diff["extra_count"]; ok {
+		t.Error("Expected extra rows to be ignored")
+	}
+	changed := diff["changed"].([]map[string]interface{})[0]
+	if changed["table"].(map[string]interface{})["username"] != secondName || changed["dataset"].(map[string]interface{})["username"] != "renamed_user" {
+		t.Errorf("Unexpected change %v", changed)
+	}
+
+	sql := diff["sql"].(string)
+	for _, expected := range []string{
+		`INSERT INTO "public"."users" ("id", "username") VALUES`,
+		fmt.Sprintf(`UPDATE "public"."users" SET "username" = 'renamed_user' WHERE "id" = '%d';`, secondID),
+	} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("Expected the SQL to contain %q, got %s", expected, sql)
+		}
+	}
+	if strings.Contains(sql, "DELETE") {
+		t.Errorf("Expected no deletes with ignore_extra, got %s", sql)
+	}
+
+	t.Run("extra rows", func(t *testing.T) {
+		args := DiffDatasetArgs{TableName: "users", Data: fmt.Sprintf(`[{"id": %d}]`, firstID), KeyColumns: []string{"id"}}
+		_, response, err := testServer.DiffDataset(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("DiffDataset failed: %v", err)
+		}
+		var total int
+		if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM users").Scan(&total); err != nil {
+			t.Fatalf("Failed to count users: %v", err)
+		}
+		if extra := response.(map[string]interface{})["extra_count"]; extra != total-1 {
+			t.Errorf("Expected %d extra rows, got %v", total-1, extra)
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		args := DiffDatasetArgs{TableName: "users", Data: "id,nickname\n1,x\n", KeyColumns: []string{"id"}}
+		result, _, _ := testServer.DiffDataset(ctx, createMockRequest(args), args)
+		if result == nil || !result.IsError {
+			t.Error("Expected a column the table doesn't have to be rejected")
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const maxHistogramBuckets = 100
+
+type ColumnDistributionArgs struct {
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Column    string `json:"column" jsonschema:"Column to describe"`
+	Buckets   int    `json:"buckets,omitempty" jsonschema:"Also count the rows of a numeric, date or time column into this many equal-width buckets, scanning the table (at most 100, default: no histogram)"`
+}
+
+// ColumnDistribution exposes what the planner believes about a column,
+// its pg_stats histogram bounds, most common values and physical
+// correlation, so skewed data behind a bad estimate can be spotted. An
+// equal-width histogram counted from the rows can be added for numeric and
+// temporal columns.
+func (s *serverState) ColumnDistribution(ctx context.Context, req *mcp.CallToolRequest, args ColumnDistributionArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if args.Buckets < 0 || args.Buckets > maxHistogramBuckets {
+		return s.returnErrorResult("buckets must be between 1 and %d", maxHistogramBuckets)
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := qualifiedName(schema, tableName)
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var typeName, category string
+	var hasStats bool
+	var nullFrac, nDistinct float64
+	var avgWidth int32
+	var correlation *float64
+	var values, bounds []string
+	var frequencies []float64
+	err = tx.QueryRow(ctx, `
+		SELECT format_type(a.atttypid, a.atttypmod), t.typcategory::text, s.attname IS NOT NULL,
+			COALESCE(s.null_frac, 0), COALESCE(s.avg_width, 0), COALESCE(s.n_distinct, 0), s.correlation,
+			COALESCE(s.most_common_vals::text::text[], '{}'), COALESCE(s.most_common_freqs, '{}'),
+			COALESCE(s.histogram_bounds::text::text[], '{}')
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_stats s ON s.schemaname = $1 AND s.tablename = $2 AND s.attname = a.attname
+			AND s.inherited = (c.relkind = 'p')
+		WHERE a.attrelid = $3::text::regclass AND a.attname = $4 AND a.attnum > 0 AND NOT a.attisdropped
+	`, schema, tableName, pgx.Identifier{schema, tableName}.Sanitize(), args.Column).Scan(&typeName, &category, &hasStats,
+		&nullFrac, &avgWidth, &nDistinct, &correlation, &values, &frequencies, &bounds)
+	if err == pgx.ErrNoRows {
+		return s.returnErrorResult("column %q does not exist in %s", args.Column, table)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read statistics: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"table":  table,
+		"column": args.Column,
+		"type":   typeName,
+	}
+	var warnings []string
+	mostCommon := []map[string]interface{}{}
+	for i, value := range values {
+		if i < len(frequencies) {
+			mostCommon = append(mostCommon, map[string]interface{}{"value": value, "frequency": frequencies[i]})
+		}
+	}
+	histogramBounds := make([]map[string]interface{}, len(bounds))
+	for i, bound := range bounds {
+		histogramBounds[i] = map[string]interface{}{"value": bound}
+	}
+	if hasStats {
+		statistics := map[string]interface{}{
+			"null_fraction":      nullFrac,
+			"average_width":      avgWidth,
+			"n_distinct":         nDistinct,
+			"most_common_values": mostCommon,
+			"histogram_bounds":   histogramBounds,
+		}
+		if correlation != nil {
+			statistics["correlation"] = *correlation
+		}
+		response["statistics"] = statistics
+	} else {
+		response["stats_missing"] = true
+		warnings = append(warnings, fmt.Sprintf(s.localize("%s has no planner statistics for this column, run ANALYZE on it to collect them"), table))
+	}
+
+	var histogram []map[string]interface{}
+	if args.Buckets > 0 {
+		// dates and times are bucketed by their epoch, like numbers
+		var position string
+		switch category {
+		case "N":
+			position = "v::float8"
+		case "D", "T":
+			position = "extract(epoch FROM v)::float8"
+		default:
+			return s.returnErrorResult("A histogram needs a numeric, date or time column, %s is %s", args.Column, typeName)
+		}
+		rows, err := tx.Query(ctx, fmt.Sprintf(`
+			WITH data AS (SELECT %s AS v FROM %s WHERE %s IS NOT NULL),
+			bounds AS (SELECT min(%s) AS lo, max(%s) AS hi FROM data)
+			SELECT bucket, min(v)::text, max(v)::text, count(*)
+			FROM (
+				SELECT v, CASE WHEN hi = lo THEN 1 ELSE LEAST(width_bucket(%s, lo, hi, %d), %d) END AS bucket
+				FROM data, bounds
+			) b
+			GROUP BY bucket
+			ORDER BY bucket
+		`, quoteIdentifier(args.Column), table, quoteIdentifier(args.Column), position, position, position, args.Buckets, args.Buckets))
+		if err != nil {
+			return s.returnErrorResult("Failed to count the histogram: %v", err)
+		}
+		var total int64
+		for rows.Next() {
+			var bucket int
+			var low, high string
+			var count int64
+			if err := rows.Scan(&bucket, &low, &high, &count); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+			}
+			total += count
+			histogram = append(histogram, map[string]interface{}{"bucket": bucket, "min": low, "max": high, "count": count})
+		}
+		if err := rows.Err(); err != nil {
+			return s.returnErrorResult("Failed to count the histogram: %v", err)
+		}
+		for _, bucket := range histogram {
+			bucket["frequency"] = ratio(float64(bucket["count"].(int64)), float64(total))
+		}
+		response["histogram"] = histogram
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	// values from the column are masked as its rows would be
+	policies := make(map[string]columnPolicy)
+	if policy, ok := s.config.ColumnPolicies.lookup(schema, tableName, args.Column); ok && policy.masked() {
+		policies[args.Column] = policy
+		for _, value := range append(mostCommon, histogramBounds...) {
+			value["value"] = policy.apply(value["value"])
+		}
+		for _, bucket := range histogram {
+			bucket["min"], bucket["max"] = policy.apply(bucket["min"]), policy.apply(bucket["max"])
+		}
+	}
+	masked := make(map[string][]string)
+	if s.config.RedactPII {
+		for _, value := range append(mostCommon, histogramBounds...) {
+			var kinds []string
+			value["value"], kinds = redactValue(value["value"])
+			masked[args.Column] = append(masked[args.Column], kinds...)
+		}
+		if len(masked[args.Column]) == 0 {
+			delete(masked, args.Column)
+		}
+	}
+
+	result, data, err := returnJSONResult(response)
+	warnings = append(append(notices, warnings...), s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
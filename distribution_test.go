@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestColumnDistribution(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE distributed (id serial PRIMARY KEY, amount int, created date, label text);
+		INSERT INTO distributed (amount, created, label)
+		SELECT CASE WHEN i <= 900 THEN 1 ELSE i END, DATE '2024-01-01' + i, 'l' || (i % 3)
+		FROM generate_series(1, 1000) i;
+		ANALYZE distributed;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE distributed")
+
+	args := ColumnDistributionArgs{TableName: "distributed", Column: "amount", Buckets: 10}
+	result, data, err := testServer.ColumnDistribution(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ColumnDistribution failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	statistics := response["statistics"].(map[string]interface{})
+	if common := statistics["most_common_values"].([]map[string]interface{}); len(common) == 0 || common[0]["value"] != "1" {
+		t.Errorf("Expected 1 as the most common amount, got %v", common)
+	}
+	histogram := response["histogram"].([]map[string]interface{})
+	if len(histogram) == 0 || histogram[0]["count"] != int64(900) {
+		t.Errorf("Expected the skewed first bucket to hold 900 rows, got %v", histogram)
+	}
+
+	args = ColumnDistributionArgs{TableName: "distributed", Column: "created", Buckets: 4}
+	_, data, err = testServer.ColumnDistribution(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ColumnDistribution failed: %v", err)
+	}
+	if histogram := data.(map[string]interface{})["histogram"].([]map[string]interface{}); len(histogram) != 4 {
+		t.Errorf("Expected 4 date buckets, got %v", histogram)
+	}
+
+	args = ColumnDistributionArgs{TableName: "distributed", Column: "label", Buckets: 4}
+	if result, _, _ := testServer.ColumnDistribution(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a histogram of a text column to be refused")
+	}
+}
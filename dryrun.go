@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const dryRunNotice = "DRY_RUN is enabled: this ran inside a transaction that was rolled back, nothing was persisted"
+
+// writesEnabled reports whether write tools may run. Dry-run mode enables
+// them too, since every write it performs is rolled back.
+func (s *serverState) writesEnabled() bool {
+	return s.config.AllowWrites || s.config.DryRun
+}
+
+// finishWrite commits a write transaction, or rolls it back in dry-run mode.
+// A committed write may have changed the schema, so the metadata cache is
+// dropped.
+func (s *serverState) finishWrite(ctx context.Context, tx pgx.Tx) error {
+	if s.config.DryRun {
+		return tx.Rollback(ctx)
+	}
+	if s.metadata != nil {
+		defer s.metadata.invalidate()
+	}
+	return tx.Commit(ctx)
+}
+
+// labelDryRun marks a write tool response as simulated in dry-run mode.
+func (s *serverState) labelDryRun(response map[string]interface{}) map[string]interface{} {
+	if s.config.DryRun {
+		response["simulated"] = true
+		response["notice"] = s.localize(dryRunNotice)
+	}
+	return response
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const maxInlineDumpBytes = 1 << 20
+
+type DumpSchemaArgs struct {
+	Schema     string   `json:"schema,omitempty" jsonschema:"Schema to dump, with its types, tables, views and functions (default: public)"`
+	Tables     []string `json:"tables,omitempty" jsonschema:"Only dump these tables, either bare names (in schema) or schema.table, quoting mixed-case names"`
+	OutputPath string   `json:"output_path,omitempty" jsonschema:"Write the dump to this file in EXPORT_DIR, relative to it, instead of returning it inline"`
+	UsePgDump  bool     `json:"use_pg_dump,omitempty" jsonschema:"Run pg_dump --schema-only when it is installed, instead of rebuilding the DDL from the catalogs (default: false)"`
+}
+
+// dumpedRelation is a table, view or materialized view of a dumped schema,
+// with its partitioning: the parent and bound of a partition, the key of a
+// partitioned table.
+type dumpedRelation struct {
+	Name      string
+	Kind      string
+	Parent    string
+	Bound     string
+	Partition string
+}
+
+// DumpSchema produces schema-only SQL for a schema or a few of its tables.
+// The DDL is rebuilt from the catalogs with the statements export_fixture
+// uses, which covers tables, their constraints and indexes, and in schema
+// mode the schema's types, views and functions. pg_dump gives a complete
+// dump where it is installed.
+func (s *serverState) DumpSchema(ctx context.Context, req *mcp.CallToolRequest, args DumpSchemaArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	schema := getSchema(args.Schema)
+	var tables []string
+	for _, table := range args.Tables {
+		if !strings.Contains(table, ".") {
+			table = pgx.Identifier{schema}.Sanitize() + "." + table
+		}
+		name, err := s.resolveQualifiedTable(ctx, table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.qualifiedAllowed(name) {
+			return s.returnNotAccessible(name)
+		}
+		if !slices.Contains(tables, name) {
+			tables = append(tables, name)
+		}
+	}
+	if len(tables) == 0 && !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+
+	var warnings []string
+	var dump, source string
+	if args.UsePgDump {
+		switch path, err := exec.LookPath("pg_dump"); {
+		case err != nil:
+			warnings = append(warnings, s.localize("pg_dump is not installed, the dump was rebuilt from the catalogs"))
+		case s.accessListsConfigured():
+			// pg_dump can't leave out what the allow/deny lists hide
+			warnings = append(warnings, s.localize("pg_dump is not used while allow/deny lists are configured, the dump was rebuilt from the catalogs"))
+		default:
+			if dump, err = s.runPgDump(ctx, path, schema, tables); err != nil {
+				return s.returnErrorResult("pg_dump failed: %v", err)
+			}
+			source = "pg_dump"
+		}
+	}
+	if source == "" {
+		var skipped []string
+		var err error
+		dump, skipped, err = s.catalogDump(ctx, schema, tables)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(skipped) > 0 {
+			warnings = append(warnings, fmt.Sprintf(s.localize("Skipped relations hidden by the allow/deny lists: %s"), strings.Join(skipped, ", ")))
+		}
+		source = "catalog"
+	}
+
+	response := map[string]interface{}{
+		"schema": schema,
+		"source": source,
+		"bytes":  len(dump),
+	}
+	if len(tables) > 0 {
+		response["tables"] = tables
+	}
+	if args.OutputPath != "" {
+		path, err := s.exportPath(args.OutputPath)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		encrypted, err := s.writeArtifact(path, []byte(dump))
+		if err != nil {
+			return s.returnErrorResult("Failed to write dump: %v", err)
+		}
+		response["output_path"] = args.OutputPath
+		response["encrypted"] = encrypted
+		result, data, err := returnJSONResult(response)
+		return s.withWarnings(result, warnings), data, err
+	}
+
+	if len(dump) > maxInlineDumpBytes {
+		return s.returnErrorResult("Dump is %d bytes, which is too large to return inline. Select fewer tables or set output_path", len(dump))
+	}
+	response["sql"] = dump
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
+
+// runPgDump runs pg_dump against the server's connection, verifying the
+// server the way the pool does and as ROLE when it is set. The password is
+// passed in the environment, never on the command line.
+func (s *serverState) runPgDump(ctx context.Context, path, schema string, tables []string) (string, error) {
+	config := s.pool.Config().ConnConfig
+	cmdArgs := []string{"--schema-only", "--no-owner", "--no-privileges"}
+	if len(tables) == 0 {
+		cmdArgs = append(cmdArgs, "--schema="+pgx.Identifier{schema}.Sanitize())
+	}
+	for _, table := range tables {
+		cmdArgs = append(cmdArgs, "--table="+sanitizeQualifiedName(table))
+	}
+
+	// run as ROLE like the pool's connections, not with the login's privileges
+	if s.config.Role != "" {
+		cmdArgs = append(cmdArgs, "--role="+s.config.Role)
+	}
+
+	cmd := exec.CommandContext(ctx, path, cmdArgs...)
+	cmd.Env = append(os.Environ(),
+		"PGHOST="+config.Host,
+		"PGPORT="+strconv.Itoa(int(config.Port)),
+		"PGDATABASE="+config.Database,
+		"PGUSER="+config.User,
+		"PGPASSWORD="+config.Password,
+	)
+	cmd.Env = append(cmd.Env, s.config.libpqTLSEnv(config.TLSConfig)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s", message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// catalogDump rebuilds schema-only SQL from the catalogs. It returns the
+// relations of the schema that were left out for the allow/deny lists.
+func (s *serverState) catalogDump(ctx context.Context, schema string, tables []string) (string, []string, error) {
+	relations, err := s.dumpRelations(ctx, schema, tables)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var skipped []string
+	var defs []*tableDef
+	var views []dumpedRelation
+	tableRelations := make(map[string]dumpedRelation)
+	for _, relation := range relations {
+		if !s.qualifiedAllowed(relation.Name) {
+			skipped = append(skipped, relation.Name)
+			continue
+		}
+		if relation.Kind == "v" || relation.Kind == "m" {
+			views = append(views, relation)
+			continue
+		}
+		def, err := s.loadTableDef(ctx, relation.Name)
+		if err != nil {
+			return "", nil, err
+		}
+		tableRelations[relation.Name] = relation
+		defs = append(defs, def)
+	}
+	// partitions are created after their parents, whatever their keys
+	isPartition := func(def *tableDef) bool {
+		_, ok := tableRelations[tableRelations[def.Name].Parent]
+		return ok
+	}
+	var ordered, partitions []*tableDef
+	for _, def := range sortByForeignKeys(defs) {
+		if isPartition(def) {
+			partitions = append(partitions, def)
+		} else {
+			ordered = append(ordered, def)
+		}
+	}
+	defs = append(ordered, partitions...)
+
+	var out strings.Builder
+	out.WriteString("-- Schema dump generated by postgres-mcp\n")
+	// functions and views may refer to each other either way round
+	out.WriteString("SET check_function_bodies = false;\n\n")
+
+	schemas := make(map[string]bool)
+	for _, def := range defs {
+		tableSchema, _ := splitQualifiedName(def.Name)
+		schemas[tableSchema] = true
+	}
+	if len(tables) == 0 {
+		schemas[schema] = true
+	}
+	for _, name := range sortedKeys(schemas) {
+		fmt.Fprintf(&out, "CREATE SCHEMA IF NOT EXISTS %s;\n", pgx.Identifier{name}.Sanitize())
+	}
+	out.WriteString("\n")
+
+	if len(tables) == 0 {
+		types, err := s.dumpTypes(ctx, schema)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, statement := range types {
+			out.WriteString(statement + "\n\n")
+		}
+	}
+
+	for _, def := range defs {
+		relation := tableRelations[def.Name]
+		statement := def.createStatement()
+		if isPartition(def) {
+			// columns, constraints and indexes come from the parent
+			statement = fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s;",
+				sanitizeQualifiedName(def.Name), sanitizeQualifiedName(relation.Parent), relation.Bound)
+		}
+		if relation.Partition != "" {
+			statement = strings.TrimSuffix(statement, ";") + " PARTITION BY " + relation.Partition + ";"
+		}
+		for _, sequence := range def.sequenceStatements() {
+			out.WriteString(sequence + "\n")
+		}
+		out.WriteString(statement + "\n")
+		for _, ownership := range def.sequenceOwnershipStatements() {
+			out.WriteString(ownership + "\n")
+		}
+		out.WriteString("\n")
+	}
+
+	for _, def := range defs {
+		if isPartition(def) {
+			continue
+		}
+		for _, constraint := range def.Constraints {
+			if constraint.Type == "f" {
+				out.WriteString(def.foreignKeyStatement(constraint) + ";\n")
+			}
+		}
+		for _, index := range def.Indexes {
+			// an index on a partitioned table is created on its partitions too
+			out.WriteString(strings.Replace(index, " ON ONLY ", " ON ", 1) + ";\n")
+		}
+	}
+	out.WriteString("\n")
+
+	if len(tables) == 0 {
+		functions, err := s.dumpFunctions(ctx, schema)
+		if err != nil {
+			return "", nil, err
+		}
+		for _, statement := range functions {
+			out.WriteString(statement + ";\n\n")
+		}
+	}
+
+	for _, view := range views {
+		statement, err := s.viewStatement(ctx, view)
+		if err != nil {
+			return "", nil, err
+		}
+		out.WriteString(statement + "\n\n")
+	}
+	return out.String(), skipped, nil
+}
+
+// dumpRelations lists the tables and views of a schema, or the given tables,
+// in creation order: tables first, then views as they were created.
+func (s *serverState) dumpRelations(ctx context.Context, schema string, tables []string) ([]dumpedRelation, error) {
+	query := `
+		SELECT
+			n.nspname || '.' || c.relname,
+			c.relkind::text,
+			COALESCE(pn.nspname || '.' || pc.relname, ''),
+			COALESCE(pg_get_expr(c.relpartbound, c.oid), ''),
+			COALESCE(pg_get_partkeydef(c.oid), '')
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_inherits i ON i.inhrelid = c.oid AND c.relispartition
+		LEFT JOIN pg_class pc ON pc.oid = i.inhparent
+		LEFT JOIN pg_namespace pn ON pn.oid = pc.relnamespace
+		WHERE c.relkind IN ('r', 'p', 'v', 'm')
+			AND CASE WHEN cardinality($2::text[]) > 0
+				THEN n.nspname || '.' || c.relname = ANY($2)
+				ELSE n.nspname = $1 END
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e'
+			)
+		ORDER BY c.relkind IN ('v', 'm'), c.relispartition, c.oid
+	`
+	if tables == nil {
+		tables = []string{}
+	}
+	rows, err := s.pool.Query(ctx, query, schema, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relations: %v", err)
+	}
+	defer rows.Close()
+
+	var relations []dumpedRelation
+	for rows.Next() {
+		var relation dumpedRelation
+		if err := rows.Scan(&relation.Name, &relation.Kind, &relation.Parent, &relation.Bound, &relation.Partition); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		relations = append(relations, relation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return relations, nil
+}
+
+func (s *serverState) viewStatement(ctx context.Context, view dumpedRelation) (string, error) {
+	var definition string
+	if err := s.pool.QueryRow(ctx, "SELECT pg_get_viewdef($1::text::regclass, true)", sanitizeQualifiedName(view.Name)).Scan(&definition); err != nil {
+		return "", fmt.Errorf("failed to load view %s: %v", view.Name, err)
+	}
+	definition = strings.TrimSuffix(strings.TrimSpace(definition), ";")
+	if view.Kind == "m" {
+		return fmt.Sprintf("CREATE MATERIALIZED VIEW %s AS\n%s\nWITH NO DATA;", sanitizeQualifiedName(view.Name), definition), nil
+	}
+	return fmt.Sprintf("CREATE VIEW %s AS\n%s;", sanitizeQualifiedName(view.Name), definition), nil
+}
+
+// dumpTypes renders the enums, domains and composite types of a schema.
+// Types created by extensions are left to CREATE EXTENSION.
+func (s *serverState) dumpTypes(ctx context.Context, schema string) ([]string, error) {
+	query := `
+		SELECT
+			format('%I.%I', n.nspname, t.typname),
+			t.typtype::text,
+			ARRAY(SELECT quote_literal(e.enumlabel) FROM pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder),
+			ARRAY(
+				SELECT format('%I %s', a.attname, format_type(a.atttypid, a.atttypmod))
+				FROM pg_attribute a
+				WHERE a.attrelid = t.typrelid AND a.attnum > 0 AND NOT a.attisdropped
+				ORDER BY a.attnum
+			),
+			COALESCE(format_type(t.typbasetype, t.typtypmod), ''),
+			t.typnotnull,
+			t.typdefault,
+			ARRAY(
+				SELECT format('CONSTRAINT %I %s', con.conname, pg_get_constraintdef(con.oid, true))
+				FROM pg_constraint con
+				WHERE con.contypid = t.oid AND con.contype = 'c'
+				ORDER BY con.conname
+			)
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		LEFT JOIN pg_class c ON c.oid = t.typrelid
+		WHERE n.nspname = $1
+			AND (t.typtype IN ('e', 'd') OR (t.typtype = 'c' AND c.relkind = 'c'))
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e'
+			)
+		ORDER BY t.oid
+	`
+	rows, err := s.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list types: %v", err)
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var name, kind, baseType string
+		var labels, attributes, checks []string
+		var notNull bool
+		var defaultValue *string
+		if err := rows.Scan(&name, &kind, &labels, &attributes, &baseType, &notNull, &defaultValue, &checks); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		switch kind {
+		case "e":
+			statements = append(statements, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);", name, strings.Join(labels, ", ")))
+		case "c":
+			statements = append(statements, fmt.Sprintf("CREATE TYPE %s AS (\n    %s\n);", name, strings.Join(attributes, ",\n    ")))
+		case "d":
+			statement := fmt.Sprintf("CREATE DOMAIN %s AS %s", name, baseType)
+			if defaultValue != nil {
+				statement += " DEFAULT " + *defaultValue
+			}
+			if notNull {
+				statement += " NOT NULL"
+			}
+			for _, check := range checks {
+				statement += " " + check
+			}
+			statements = append(statements, statement+";")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return statements, nil
+}
+
+// dumpFunctions renders the functions and procedures of a schema, apart from
+// aggregates and those belonging to extensions.
+func (s *serverState) dumpFunctions(ctx context.Context, schema string) ([]string, error) {
+	query := `
+		SELECT pg_get_functiondef(p.oid)
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1
+			AND p.prokind IN ('f', 'p')
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_proc'::regclass AND d.objid = p.oid AND d.deptype = 'e'
+			)
+		ORDER BY p.proname, p.oid
+	`
+	rows, err := s.pool.Query(ctx, query, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list functions: %v", err)
+	}
+	defer rows.Close()
+
+	var statements []string
+	for rows.Next() {
+		var definition string
+		if err := rows.Scan(&definition); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		statements = append(statements, strings.TrimSpace(definition))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return statements, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpSchema(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("dumped schema recreates itself", func(t *testing.T) {
+		_, err := testServer.pool.Exec(ctx, `
+			CREATE SCHEMA dump_src;
+			CREATE TYPE dump_src.mood AS ENUM ('sad', 'ok', 'happy');
+			CREATE DOMAIN dump_src.positive AS integer CHECK (VALUE > 0);
+			CREATE TABLE dump_src.people (id serial PRIMARY KEY, mood dump_src.mood, age dump_src.positive);
+			CREATE TABLE dump_src.visits (id bigint, person_id integer REFERENCES dump_src.people(id), day date) PARTITION BY RANGE (day);
+			CREATE TABLE dump_src.visits_2024 PARTITION OF dump_src.visits FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+			CREATE INDEX ON dump_src.visits (day);
+			CREATE FUNCTION dump_src.happy_count() RETURNS bigint LANGUAGE sql AS $$ SELECT count(*) FROM dump_src.people WHERE mood = 'happy' $$;
+			CREATE VIEW dump_src.happy AS SELECT * FROM dump_src.people WHERE mood = 'happy';
+		`)
+		if err != nil {
+			t.Fatalf("Failed to create schema: %v", err)
+		}
+		defer testServer.pool.Exec(ctx, "DROP SCHEMA IF EXISTS dump_src CASCADE")
+
+		args := DumpSchemaArgs{Schema: "dump_src"}
+		result, data, err := testServer.DumpSchema(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("DumpSchema failed: %v", err)
+		}
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+		response := data.(map[string]interface{})
+		if response["source"] != "catalog" {
+			t.Errorf("Expected a catalog dump, got %v", response["source"])
+		}
+		dump := response["sql"].(string)
+		for _, expected := range []string{"CREATE TYPE", "CREATE DOMAIN", "PARTITION BY RANGE", "PARTITION OF", "CREATE VIEW", "CREATE OR REPLACE FUNCTION"} {
+			if !strings.Contains(dump, expected) {
+				t.Errorf("Expected dump to contain %q", expected)
+			}
+		}
+
+		if _, err := testServer.pool.Exec(ctx, "DROP SCHEMA dump_src CASCADE"); err != nil {
+			t.Fatalf("Failed to drop schema: %v", err)
+		}
+		if _, err := testServer.pool.Exec(ctx, dump); err != nil {
+			t.Fatalf("Failed to restore the dump: %v\n%s", err, dump)
+		}
+		var indexes int
+		if err := testServer.pool.QueryRow(ctx, "SELECT count(*) FROM pg_indexes WHERE tablename = 'visits_2024'").Scan(&indexes); err != nil {
+			t.Fatalf("Failed to count indexes: %v", err)
+		}
+		if indexes != 1 {
+			t.Errorf("Expected the partition to get the parent's index, found %d indexes", indexes)
+		}
+	})
+
+	t.Run("selected tables to file", func(t *testing.T) {
+		savedConfig := testServer.config
+		defer func() { testServer.config = savedConfig }()
+		testServer.config.ExportDir = t.TempDir()
+
+		outputPath := filepath.Join(testServer.config.ExportDir, "schema.sql")
+		args := DumpSchemaArgs{Tables: []string{"posts", "users"}, OutputPath: "schema.sql"}
+		result, _, err := testServer.DumpSchema(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("DumpSchema failed: %v", err)
+		}
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		contents, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read dump: %v", err)
+		}
+		dump := string(contents)
+		usersAt := strings.Index(dump, `CREATE TABLE "public"."users"`)
+		postsAt := strings.Index(dump, `CREATE TABLE "public"."posts"`)
+		if usersAt < 0 || postsAt < 0 || usersAt > postsAt {
+			t.Errorf("Expected users to be created before posts")
+		}
+		if strings.Contains(dump, `"public"."comments"`) {
+			t.Error("Expected only the selected tables to be dumped")
+		}
+	})
+}
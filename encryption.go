@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// encryptedArtifactMagic prefixes every encrypted file, followed by the
+// AES-GCM nonce and the sealed contents.
+var encryptedArtifactMagic = []byte("PGMCPENC1\n")
+
+// parseEncryptionKey accepts a 256-bit key as 64 hex characters or base64.
+func parseEncryptionKey(value string) ([]byte, error) {
+	if value == "" {
+		return nil, nil
+	}
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes encoded as hex or base64")
+	}
+	return key, nil
+}
+
+func encryptArtifact(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, encryptedArtifactMagic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, encryptedArtifactMagic), nil
+}
+
+func decryptArtifact(key, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedArtifactMagic) {
+		return nil, fmt.Errorf("not an encrypted postgres-mcp artifact")
+	}
+	data = data[len(encryptedArtifactMagic):]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted artifact is truncated")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], encryptedArtifactMagic)
+}
+
+// writeArtifact writes a file produced by a tool, encrypting it when an
+// encryption key is configured so query results never land on disk in
+// plaintext. It reports whether the file was encrypted.
+func (s *serverState) writeArtifact(path string, contents []byte) (bool, error) {
+	if len(s.config.EncryptionKey) == 0 {
+		return false, replaceFile(path, contents)
+	}
+
+	sealed, err := encryptArtifact(s.config.EncryptionKey, contents)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt %s: %v", path, err)
+	}
+	return true, replaceFile(path, sealed)
+}
+
+// replaceFile writes contents to a new file next to path and renames it
+// over path, so a failed write leaves an existing file as it was and a
+// symlink at path is replaced rather than followed.
+func replaceFile(path string, contents []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return nil
+}
+
+// artifactFile is a file being streamed by createArtifact. Plaintext is
+// written to a temporary file as it goes, encrypted files are held in
+// memory since they are sealed as one message. Close puts the file in
+// place, Discard drops it and leaves whatever was at its path alone.
+type artifactFile struct {
+	s      *serverState
+	path   string
+	temp   *os.File
+	buffer bytes.Buffer
+}
+
+func (a *artifactFile) Write(p []byte) (int, error) {
+	if a.temp != nil {
+		return a.temp.Write(p)
+	}
+	return a.buffer.Write(p)
+}
+
+func (a *artifactFile) Close() error {
+	if a.temp == nil {
+		_, err := a.s.writeArtifact(a.path, a.buffer.Bytes())
+		return err
+	}
+	if err := a.temp.Close(); err != nil {
+		os.Remove(a.temp.Name())
+		return err
+	}
+	if err := os.Rename(a.temp.Name(), a.path); err != nil {
+		os.Remove(a.temp.Name())
+		return err
+	}
+	return nil
+}
+
+func (a *artifactFile) Discard() {
+	if a.temp != nil {
+		a.temp.Close()
+		os.Remove(a.temp.Name())
+	}
+}
+
+// createArtifact is writeArtifact for files streamed as they are produced.
+func (s *serverState) createArtifact(path string) (*artifactFile, bool, error) {
+	if len(s.config.EncryptionKey) > 0 {
+		return &artifactFile{s: s, path: path}, true, nil
+	}
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return nil, false, err
+	}
+	return &artifactFile{s: s, path: path, temp: temp}, false, nil
+}
+
+// artifactReader reads a plaintext file as it is.
+type artifactReader struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// openArtifact reads a file written by writeArtifact or createArtifact,
+// decrypting it if it was encrypted. It also returns the size of the file
+// on disk.
+func (s *serverState) openArtifact(path string) (io.ReadCloser, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	reader := bufio.NewReader(file)
+	if prefix, _ := reader.Peek(len(encryptedArtifactMagic)); !bytes.Equal(prefix, encryptedArtifactMagic) {
+		return artifactReader{reader, file}, info.Size(), nil
+	}
+
+	defer file.Close()
+	if len(s.config.EncryptionKey) == 0 {
+		return nil, 0, fmt.Errorf("%s is encrypted and no encryption key is configured", path)
+	}
+	sealed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	contents, err := decryptArtifact(s.config.EncryptionKey, sealed)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decrypt %s: %v", path, err)
+	}
+	return io.NopCloser(bytes.NewReader(contents)), info.Size(), nil
+}
+
+// readArtifact reads a whole file written by writeArtifact, or a plaintext
+// one written by hand.
+func (s *serverState) readArtifact(path string) ([]byte, error) {
+	file, _, err := s.openArtifact(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEncryptionKey(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	if key, err := parseEncryptionKey(hexKey); err != nil || len(key) != 32 {
+		t.Errorf("Expected hex key to parse, got %v", err)
+	}
+
+	if key, err := parseEncryptionKey("q83vq83vq83vq83vq83vq83vq83vq83vq83vq83vq80="); err != nil || len(key) != 32 {
+		t.Errorf("Expected base64 key to parse, got %v", err)
+	}
+
+	if _, err := parseEncryptionKey("too-short"); err == nil {
+		t.Error("Expected short key to be rejected")
+	}
+
+	if key, err := parseEncryptionKey(""); err != nil || key != nil {
+		t.Error("Expected empty key to disable encryption")
+	}
+}
+
+func TestEncryptedExports(t *testing.T) {
+	ctx := context.Background()
+
+	key, _ := parseEncryptionKey(strings.Repeat("01", 32))
+	previous := testServer.config
+	testServer.config.EncryptionKey = key
+	testServer.config.ExportDir = t.TempDir()
+	defer func() { testServer.config = previous }()
+
+	path := filepath.Join(testServer.config.ExportDir, "fixture.sql.enc")
+	args := ExportFixtureArgs{Tables: []string{"users"}, RowLimit: 5, OutputPath: "fixture.sql.enc"}
+	result, data, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ExportFixture failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful export, got %v", result)
+	}
+
+	if data.(map[string]interface{})["encrypted"] != true {
+		t.Error("Expected response to report the file as encrypted")
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	if strings.Contains(string(contents), "CREATE TABLE") {
+		t.Fatal("Expected fixture to be encrypted at rest")
+	}
+
+	plaintext, err := decryptArtifact(key, contents)
+	if err != nil {
+		t.Fatalf("Failed to decrypt fixture: %v", err)
+	}
+	if !strings.Contains(string(plaintext), "CREATE TABLE") {
+		t.Error("Expected decrypted fixture to contain the schema")
+	}
+
+	wrongKey, _ := parseEncryptionKey(strings.Repeat("02", 32))
+	if _, err := decryptArtifact(wrongKey, contents); err == nil {
+		t.Error("Expected decryption with the wrong key to fail")
+	}
+}
+
+func TestEncryptedStateFiles(t *testing.T) {
+	key, _ := parseEncryptionKey(strings.Repeat("01", 32))
+	s := &serverState{}
+	s.config.EncryptionKey = key
+
+	path := filepath.Join(t.TempDir(), "plans.json")
+	store := map[string]planBaseline{"abc": {Query: "SELECT secret FROM users"}}
+	if err := s.writeJSONFile(path, store); err != nil {
+		t.Fatalf("writeJSONFile failed: %v", err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read plan store: %v", err)
+	}
+	if strings.Contains(string(contents), "secret") {
+		t.Fatal("Expected the plan store to be encrypted at rest")
+	}
+	loaded, err := s.loadPlanStore(path)
+	if err != nil || loaded["abc"].Query != "SELECT secret FROM users" {
+		t.Errorf("Expected the plan store to read back, got %v %v", loaded, err)
+	}
+
+	// hand-written saved queries stay readable
+	path = filepath.Join(t.TempDir(), "queries.json")
+	os.WriteFile(path, []byte(`{"recent": {"query": "SELECT 1"}}`), 0o600)
+	if queries, err := s.loadSavedQueries(path); err != nil || queries["recent"].Query != "SELECT 1" {
+		t.Errorf("Expected plaintext saved queries to load, got %v %v", queries, err)
+	}
+}
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultRecentErrors = 50
+	maxRecentErrors     = 1000
+	// errorLogBytes is how much of the end of the server log recent_errors
+	// reads, across the newest files of a log directory
+	errorLogBytes = 16 << 20
+)
+
+// errorSeverities are the log levels recent_errors reports.
+var errorSeverities = map[string]bool{"ERROR": true, "FATAL": true, "PANIC": true}
+
+// logTimeLayouts are the formats of %m and %t in log_line_prefix and of
+// the csvlog and jsonlog timestamps.
+var logTimeLayouts = []string{
+	"2006-01-02 15:04:05.000 MST",
+	"2006-01-02 15:04:05 MST",
+	"2006-01-02 15:04:05.000 -07",
+	"2006-01-02 15:04:05 -07",
+}
+
+var (
+	// logLinePattern finds the severity of a plain-text log line after
+	// whatever log_line_prefix put before it
+	logLinePattern = regexp.MustCompile(`^(.*?)\b(DEBUG[1-5]?|LOG|INFO|NOTICE|WARNING|ERROR|FATAL|PANIC|DETAIL|HINT|QUERY|CONTEXT|STATEMENT|LOCATION):  (.*)$`)
+	logTimePattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? ([A-Za-z]+|[+-]\d+)`)
+	logPIDPattern  = regexp.MustCompile(`\[(\d+)\]`)
+	csvRecordStart = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`)
+	// logQuotedName finds the relations, constraints and indexes a log
+	// message names
+	logQuotedName   = regexp.MustCompile(`"([^"]+)"`)
+	deadlockMessage = "deadlock detected"
+)
+
+// logError is an ERROR, FATAL or PANIC entry of the server log.
+type logError struct {
+	Time        *time.Time `json:"time,omitempty"`
+	Severity    string     `json:"severity"`
+	SQLState    string     `json:"sqlstate,omitempty"`
+	Message     string     `json:"message"`
+	Detail      string     `json:"detail,omitempty"`
+	Hint        string     `json:"hint,omitempty"`
+	Context     string     `json:"context,omitempty"`
+	Statement   string     `json:"statement,omitempty"`
+	User        string     `json:"user,omitempty"`
+	Database    string     `json:"database,omitempty"`
+	Application string     `json:"application_name,omitempty"`
+	PID         int        `json:"pid,omitempty"`
+	Deadlock    bool       `json:"deadlock,omitempty"`
+}
+
+func parseLogTime(value string) *time.Time {
+	for _, layout := range logTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// finish marks deadlocks, by their SQLSTATE or, in plain-text logs without
+// one, their message.
+func (e *logError) finish() {
+	e.Deadlock = e.SQLState == "40P01" || strings.HasPrefix(e.Message, deadlockMessage)
+}
+
+// logPrefixPattern turns log_line_prefix into a pattern finding the
+// database in the prefix of plain-text log lines, nil when the prefix
+// doesn't log it. Everything after %q is left out by processes without a
+// session.
+func logPrefixPattern(prefix string) *regexp.Regexp {
+	if !strings.Contains(prefix, "%d") {
+		return nil
+	}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	optional := false
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] != '%' {
+			pattern.WriteString(regexp.QuoteMeta(prefix[i : i+1]))
+			continue
+		}
+		// padding, as in %-10d
+		for i++; i < len(prefix) && (prefix[i] == '-' || prefix[i] >= '0' && prefix[i] <= '9'); i++ {
+		}
+		if i == len(prefix) {
+			break
+		}
+		switch prefix[i] {
+		case '%':
+			pattern.WriteString("%")
+		case 'd':
+			pattern.WriteString(`(?P<database>.*?)`)
+		case 'q':
+			pattern.WriteString("(?:")
+			optional = true
+		default:
+			pattern.WriteString(".*?")
+		}
+	}
+	if optional {
+		pattern.WriteString(")?")
+	}
+	pattern.WriteString(`\s*$`)
+	compiled, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil
+	}
+	return compiled
+}
+
+// parseCSVLog reads csvlog records, whose columns are fixed by the server:
+// log_time, user_name, database_name, process_id, connection_from,
+// session_id, session_line_num, command_tag, session_start_time,
+// virtual_transaction_id, transaction_id, error_severity, sql_state_code,
+// message, detail, hint, internal_query, internal_query_pos, context, query,
+// query_pos, location, application_name and later additions.
+func parseCSVLog(r io.Reader) []logError {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var entries []logError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		// a record cut by where reading started, skip it
+		if err != nil || len(record) < 23 || !errorSeverities[record[11]] {
+			continue
+		}
+		pid, _ := strconv.Atoi(record[3])
+		entry := logError{
+			Time:        parseLogTime(record[0]),
+			Severity:    record[11],
+			SQLState:    record[12],
+			Message:     record[13],
+			Detail:      record[14],
+			Hint:        record[15],
+			Context:     record[18],
+			Statement:   record[19],
+			User:        record[1],
+			Database:    record[2],
+			Application: record[22],
+			PID:         pid,
+		}
+		entry.finish()
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseJSONLog reads jsonlog lines.
+func parseJSONLog(r io.Reader) []logError {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	var entries []logError
+	for scanner.Scan() {
+		var record struct {
+			Timestamp   string `json:"timestamp"`
+			User        string `json:"user"`
+			Database    string `json:"dbname"`
+			PID         int    `json:"pid"`
+			Severity    string `json:"error_severity"`
+			SQLState    string `json:"state_code"`
+			Message     string `json:"message"`
+			Detail      string `json:"detail"`
+			Hint        string `json:"hint"`
+			Context     string `json:"context"`
+			Statement   string `json:"statement"`
+			Application string `json:"application_name"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &record) != nil || !errorSeverities[record.Severity] {
+			continue
+		}
+		entry := logError{
+			Time:        parseLogTime(record.Timestamp),
+			Severity:    record.Severity,
+			SQLState:    record.SQLState,
+			Message:     record.Message,
+			Detail:      record.Detail,
+			Hint:        record.Hint,
+			Context:     record.Context,
+			Statement:   record.Statement,
+			User:        record.User,
+			Database:    record.Database,
+			Application: record.Application,
+			PID:         record.PID,
+		}
+		entry.finish()
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseTextLog reads stderr-style logs. The DETAIL, HINT, CONTEXT and
+// STATEMENT lines that follow an error belong to it, and lines without a
+// severity continue the one before them. With a prefix pattern from
+// logPrefixPattern the database of each error is read from its prefix.
+func parseTextLog(r io.Reader, prefixPattern *regexp.Regexp) []logError {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	var entries []logError
+	var current *logError
+	var field *string
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := logLinePattern.FindStringSubmatch(line)
+		if match == nil {
+			if field != nil && strings.HasPrefix(line, "\t") {
+				*field += "\n" + strings.TrimPrefix(line, "\t")
+			}
+			continue
+		}
+		prefix, severity, text := match[1], match[2], match[3]
+		if errorSeverities[severity] {
+			if current != nil {
+				current.finish()
+				entries = append(entries, *current)
+			}
+			current = &logError{Severity: severity, Message: text}
+			if stamp := logTimePattern.FindString(prefix); stamp != "" {
+				current.Time = parseLogTime(stamp)
+			}
+			if pid := logPIDPattern.FindStringSubmatch(prefix); pid != nil {
+				current.PID, _ = strconv.Atoi(pid[1])
+			}
+			if prefixPattern != nil {
+				if match := prefixPattern.FindStringSubmatch(prefix); match != nil {
+					current.Database = strings.TrimSpace(match[prefixPattern.SubexpIndex("database")])
+				}
+			}
+			field = &current.Message
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		switch severity {
+		case "DETAIL":
+			current.Detail, field = text, &current.Detail
+		case "HINT":
+			current.Hint, field = text, &current.Hint
+		case "CONTEXT":
+			current.Context, field = text, &current.Context
+		case "STATEMENT":
+			current.Statement, field = text, &current.Statement
+		case "QUERY", "LOCATION":
+			field = nil
+		default:
+			// the next message, so the error is complete
+			current.finish()
+			entries = append(entries, *current)
+			current, field = nil, nil
+		}
+	}
+	if current != nil {
+		current.finish()
+		entries = append(entries, *current)
+	}
+	return entries
+}
+
+// logFiles are the files of a log path to read, oldest first: the path
+// itself, or the newest files of a directory up to errorLogBytes between
+// them, with the offset to start reading the oldest from.
+func logFiles(path string) ([]string, int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.IsDir() {
+		return []string{path}, max(info.Size()-errorLogBytes, 0), nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	type logFile struct {
+		name     string
+		size     int64
+		modified time.Time
+	}
+	var files []logFile
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, logFile{filepath.Join(path, entry.Name()), info.Size(), info.ModTime()})
+	}
+	// newest first
+	sort.Slice(files, func(i, j int) bool { return files[i].modified.After(files[j].modified) })
+
+	// with log_destination 'stderr,csvlog' each file is written twice,
+	// reading the structured one is enough
+	for _, extension := range []string{".json", ".csv"} {
+		var structured []logFile
+		for _, file := range files {
+			if filepath.Ext(file.name) == extension {
+				structured = append(structured, file)
+			}
+		}
+		if len(structured) > 0 {
+			files = structured
+			break
+		}
+	}
+
+	var paths []string
+	var total, offset int64
+	for _, file := range files {
+		paths = append([]string{file.name}, paths...)
+		total += file.size
+		if total >= errorLogBytes {
+			offset = total - errorLogBytes
+			break
+		}
+	}
+	return paths, offset, nil
+}
+
+// readErrorLog parses the errors at the end of the server log.
+func readErrorLog(path string, prefixPattern *regexp.Regexp) ([]logError, []string, error) {
+	paths, offset, err := logFiles(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []logError
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		// start the oldest file at a line boundary
+		if i == 0 && offset > 0 {
+			data = data[offset:]
+			if newline := bytes.IndexByte(data, '\n'); newline >= 0 {
+				data = data[newline+1:]
+			}
+		}
+		switch filepath.Ext(path) {
+		case ".csv":
+			// a record split at the start may carry on over several lines
+			for len(data) > 0 && !csvRecordStart.Match(data) {
+				newline := bytes.IndexByte(data, '\n')
+				if newline < 0 {
+					data = nil
+					break
+				}
+				data = data[newline+1:]
+			}
+			entries = append(entries, parseCSVLog(bytes.NewReader(data))...)
+		case ".json":
+			entries = append(entries, parseJSONLog(bytes.NewReader(data))...)
+		default:
+			entries = append(entries, parseTextLog(bytes.NewReader(data), prefixPattern)...)
+		}
+	}
+	return entries, paths, nil
+}
+
+// names are the names an entry mentions, as the relations its statement
+// references and the quoted names in its messages.
+func (e *logError) names() []string {
+	var names []string
+	for _, name := range statementRelations(e.Statement) {
+		if len(name) > 2 {
+			name = name[len(name)-2:]
+		}
+		names = append(names, pgx.Identifier(name).Sanitize())
+	}
+	for _, text := range []string{e.Message, e.Detail, e.Hint, e.Context} {
+		for _, match := range logQuotedName.FindAllStringSubmatch(text, -1) {
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// hiddenLogNames resolves the names log entries mention, as relations or as
+// constraints, and returns those outside the allow/deny lists.
+func (s *serverState) hiddenLogNames(ctx context.Context, entries []logError) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	var relations, constraints []string
+	for i := range entries {
+		for _, name := range entries[i].names() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if strings.HasPrefix(name, `"`) {
+				relations = append(relations, name)
+			} else {
+				relations = append(relations, pgx.Identifier{name}.Sanitize())
+				constraints = append(constraints, name)
+			}
+		}
+	}
+	if len(relations) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT r.name, n.nspname::text, c.relname::text
+		FROM unnest($1::text[]) AS r(name)
+		JOIN pg_class c ON c.oid = to_regclass(r.name)
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		UNION ALL
+		SELECT r.name, n.nspname::text, c.relname::text
+		FROM unnest($2::text[]) AS r(name)
+		JOIN pg_constraint k ON k.conname = r.name
+		JOIN pg_class c ON c.oid = k.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+	`, relations, constraints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the relations in the log: %v", err)
+	}
+	defer rows.Close()
+
+	hidden := make(map[string]bool)
+	for rows.Next() {
+		var name, schema, relation string
+		if err := rows.Scan(&name, &schema, &relation); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, relation) {
+			hidden[name] = true
+			// the quoted name is looked up both ways
+			hidden[strings.Trim(name, `"`)] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return hidden, nil
+}
+
+type RecentErrorsArgs struct {
+	Since         string `json:"since,omitempty" jsonschema:"Only return log errors after this time: RFC 3339, YYYY-MM-DD HH:MM[:SS] or HH:MM[:SS]"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum log errors to return, the newest first (default: 50, at most 1000)"`
+	DeadlocksOnly bool   `json:"deadlocks_only,omitempty" jsonschema:"Only return deadlocks from the log (default: false)"`
+}
+
+// RecentErrors reports the deadlock and rollback counters of
+// pg_stat_database and, when SERVER_LOG points at the server's log, the
+// recent ERROR, FATAL and PANIC entries in it with their details. Only the
+// entries of the current database are reported, and under allow/deny lists
+// those naming hidden relations are left out. Log lines can quote row
+// values, so they go through the same PII redaction as rows.
+func (s *serverState) RecentErrors(ctx context.Context, req *mcp.CallToolRequest, args RecentErrorsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	var since time.Time
+	if args.Since != "" {
+		var err error
+		if since, err = parseSampleTime(args.Since, time.Now()); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultRecentErrors
+	}
+	limit = min(limit, maxRecentErrors)
+
+	var deadlocks, rollbacks, allDeadlocks int64
+	var statsReset *time.Time
+	var database, logLinePrefix string
+	err := s.pool.QueryRow(ctx, `
+		SELECT d.deadlocks, d.xact_rollback, d.stats_reset,
+			(SELECT COALESCE(sum(deadlocks), 0)::bigint FROM pg_stat_database),
+			d.datname::text, current_setting('log_line_prefix')
+		FROM pg_stat_database d
+		WHERE d.datname = current_database()
+	`).Scan(&deadlocks, &rollbacks, &statsReset, &allDeadlocks, &database, &logLinePrefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read database statistics: %v", err)
+	}
+	response := map[string]interface{}{
+		"database": map[string]interface{}{
+			"deadlocks":   deadlocks,
+			"rollbacks":   rollbacks,
+			"stats_reset": statsReset,
+		},
+		"deadlocks_all_databases": allDeadlocks,
+		"log_configured":          s.config.ServerLog != "",
+	}
+	if s.config.ServerLog == "" {
+		result, data, err := returnJSONResult(response)
+		return s.withWarnings(result, []string{s.localize("Set SERVER_LOG to the server's log file or log directory to include the errors logged")}), data, err
+	}
+
+	logged, files, err := readErrorLog(s.config.ServerLog, logPrefixPattern(logLinePrefix))
+	if err != nil {
+		return s.returnErrorResult("Failed to read the server log: %v", err)
+	}
+	var entries []logError
+	var warnings []string
+	unattributed := 0
+	for _, entry := range logged {
+		if entry.Database == "" {
+			unattributed++
+		}
+		if entry.Database == database {
+			entries = append(entries, entry)
+		}
+	}
+	if unattributed > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("%d log errors don't name their database and were left out, add %%d to log_line_prefix or log to csvlog or jsonlog to include them"), unattributed))
+	}
+	if s.accessListsConfigured() {
+		hidden, err := s.hiddenLogNames(ctx, entries)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = slices.DeleteFunc(entries, func(entry logError) bool {
+			return slices.ContainsFunc(entry.names(), func(name string) bool { return hidden[name] })
+		})
+	}
+
+	var matched []logError
+	bySQLState := make(map[string]int)
+	for _, entry := range entries {
+		if !since.IsZero() && (entry.Time == nil || entry.Time.Before(since)) {
+			continue
+		}
+		if args.DeadlocksOnly && !entry.Deadlock {
+			continue
+		}
+		matched = append(matched, entry)
+		if entry.SQLState != "" {
+			bySQLState[entry.SQLState]++
+		}
+	}
+	// newest first, the log being in time order
+	slices.Reverse(matched)
+	count := len(matched)
+	truncated := len(matched) > limit
+	if truncated {
+		matched = matched[:limit]
+	}
+
+	masked := make(map[string][]string)
+	for i := range matched {
+		entry := &matched[i]
+		for name, field := range map[string]*string{"message": &entry.Message, "detail": &entry.Detail, "context": &entry.Context, "statement": &entry.Statement} {
+			*field = connectionSecrets.redact(*field)
+			if s.config.RedactPII {
+				var kinds []string
+				*field, kinds = redactText(*field)
+				masked[name] = append(masked[name], kinds...)
+			}
+		}
+	}
+	for name, kinds := range masked {
+		if len(kinds) == 0 {
+			delete(masked, name)
+		}
+	}
+
+	response["log"] = map[string]interface{}{
+		"files":        files,
+		"errors":       matched,
+		"by_sqlstate":  bySQLState,
+		"matched":      count,
+		"total_errors": len(entries),
+		"truncated":    truncated,
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTextLog(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01 12:00:00.123 UTC [4242] LOG:  checkpoint starting: time",
+		"2024-05-01 12:00:01.500 UTC [4243] ERROR:  deadlock detected",
+		"2024-05-01 12:00:01.500 UTC [4243] DETAIL:  Process 4243 waits for ShareLock on transaction 700; blocked by process 4244.",
+		"\tProcess 4244 waits for ShareLock on transaction 701; blocked by process 4243.",
+		"2024-05-01 12:00:01.500 UTC [4243] HINT:  See server log for query details.",
+		"2024-05-01 12:00:01.500 UTC [4243] STATEMENT:  UPDATE accounts SET balance = 0",
+		"\tWHERE id = 1",
+		"2024-05-01 12:00:02.000 UTC [4245] ERROR:  relation \"missing\" does not exist at character 15",
+		"2024-05-01 12:00:03.000 UTC [4246] LOG:  connection received",
+	}, "\n")
+	entries := parseTextLog(strings.NewReader(log), nil)
+	if len(entries) != 2 {
+		t.Fatalf("Expected two errors, got %v", entries)
+	}
+	deadlock := entries[0]
+	if !deadlock.Deadlock || deadlock.PID != 4243 || deadlock.Time == nil || deadlock.Time.Second() != 1 {
+		t.Errorf("Unexpected deadlock entry %+v", deadlock)
+	}
+	if !strings.Contains(deadlock.Detail, "Process 4244 waits") || deadlock.Statement != "UPDATE accounts SET balance = 0\nWHERE id = 1" {
+		t.Errorf("Expected the detail and statement continued over their lines, got %+v", deadlock)
+	}
+	if entries[1].Deadlock || entries[1].Hint != "" {
+		t.Errorf("Unexpected second entry %+v", entries[1])
+	}
+}
+
+func TestLogPrefixPattern(t *testing.T) {
+	if logPrefixPattern("%m [%p] ") != nil {
+		t.Error("Expected no pattern for a prefix without the database")
+	}
+	log := strings.Join([]string{
+		"2024-05-01 12:00:01.500 UTC [4243] app@shop ERROR:  deadlock detected",
+		"2024-05-01 12:00:02.000 UTC [4244] ERROR:  could not open file",
+	}, "\n")
+	entries := parseTextLog(strings.NewReader(log), logPrefixPattern("%m [%p] %q%u@%d "))
+	if len(entries) != 2 || entries[0].Database != "shop" || entries[1].Database != "" {
+		t.Errorf("Expected the database of the session's error only, got %+v", entries)
+	}
+}
+
+func TestParseCSVLog(t *testing.T) {
+	log := `2024-05-01 12:00:01.500 UTC,"app","shop",4243,"[local]",66323a3c.1093,3,"UPDATE",2024-05-01 11:59:00 UTC,3/7,700,ERROR,40P01,"deadlock detected","Process 4243 waits for ShareLock on transaction 700.
+Process 4244 waits for ShareLock on transaction 701.","See server log for query details.",,,"while updating tuple (0,1) in relation ""accounts""","UPDATE accounts SET balance = 0",,,"psql","client backend",,0
+2024-05-01 12:00:02.000 UTC,"app","shop",4245,"[local]",66323a3c.1094,1,"SELECT",2024-05-01 11:59:00 UTC,3/8,0,LOG,00000,"duration: 1.0 ms",,,,,,,,,"psql","client backend",,0
+`
+	entries := parseCSVLog(strings.NewReader(log))
+	if len(entries) != 1 {
+		t.Fatalf("Expected one error, got %v", entries)
+	}
+	entry := entries[0]
+	if !entry.Deadlock || entry.SQLState != "40P01" || entry.Database != "shop" || entry.Application != "psql" || entry.PID != 4243 {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if !strings.Contains(entry.Context, `relation "accounts"`) {
+		t.Errorf("Expected the quoted context to be unescaped, got %q", entry.Context)
+	}
+}
+
+func TestParseJSONLog(t *testing.T) {
+	log := `{"timestamp":"2024-05-01 12:00:01.500 UTC","user":"app","dbname":"shop","pid":4243,"error_severity":"FATAL","state_code":"53300","message":"sorry, too many clients already"}
+{"timestamp":"2024-05-01 12:00:02.000 UTC","pid":4245,"error_severity":"LOG","message":"checkpoint complete"}
+`
+	entries := parseJSONLog(strings.NewReader(log))
+	if len(entries) != 1 || entries[0].Severity != "FATAL" || entries[0].SQLState != "53300" || entries[0].User != "app" {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+}
+
+func TestRecentErrors(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	testServer.config.ServerLog = ""
+	args := RecentErrorsArgs{}
+	result, data, err := testServer.RecentErrors(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RecentErrors failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	if _, ok := response["database"].(map[string]interface{})["deadlocks"].(int64); !ok || response["log_configured"] != false {
+		t.Errorf("Expected the deadlock counter without a log, got %v", response)
+	}
+
+	var database string
+	if err := testServer.pool.QueryRow(ctx, "SELECT current_database()").Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	quote := func(value string) string { return `"` + strings.ReplaceAll(value, `"`, `""`) + `"` }
+	csvLine := func(database, state, message, detail string) string {
+		return fmt.Sprintf(`2024-05-01 12:00:00.000 UTC,"app",%s,1,"[local]",66323a3c.1,1,"",2024-05-01 11:59:00 UTC,3/7,0,ERROR,%s,%s,%s,,,,,,,,"psql","client backend",,0`+"\n", quote(database), state, quote(message), quote(detail))
+	}
+	dir := t.TempDir()
+	log := csvLine(database, "23505", `duplicate key value violates unique constraint "users_email_key"`, "Key (email)=(jane@example.com) already exists.") +
+		csvLine("other", "42P01", `relation "payroll" does not exist`, "") +
+		csvLine(database, "40P01", "deadlock detected", "")
+	if err := os.WriteFile(filepath.Join(dir, "postgresql.csv"), []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	testServer.config.ServerLog = dir
+	testServer.config.RedactPII = true
+	_, data, err = testServer.RecentErrors(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RecentErrors failed: %v", err)
+	}
+	entries := data.(map[string]interface{})["log"].(map[string]interface{})["errors"].([]logError)
+	if len(entries) != 2 || !entries[0].Deadlock {
+		t.Fatalf("Expected the deadlock first of the two errors of this database, got %+v", entries)
+	}
+	if strings.Contains(entries[1].Detail, "jane@example.com") {
+		t.Errorf("Expected the email in the detail to be redacted, got %q", entries[1].Detail)
+	}
+
+	testServer.config.DeniedTables = []string{"users"}
+	_, data, _ = testServer.RecentErrors(ctx, createMockRequest(args), args)
+	if entries := data.(map[string]interface{})["log"].(map[string]interface{})["errors"].([]logError); len(entries) != 1 || !entries[0].Deadlock {
+		t.Errorf("Expected the error naming a constraint of a hidden table to be left out, got %+v", entries)
+	}
+	testServer.config.DeniedTables = nil
+
+	args = RecentErrorsArgs{DeadlocksOnly: true}
+	_, data, _ = testServer.RecentErrors(ctx, createMockRequest(args), args)
+	if entries := data.(map[string]interface{})["log"].(map[string]interface{})["errors"].([]logError); len(entries) != 1 {
+		t.Errorf("Expected only the deadlock, got %+v", entries)
+	}
+}
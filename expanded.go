@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// expandedValue renders a value in full for the expanded layout.
+func expandedValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case pgtype.Interval:
+		return formatInterval(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	if encoded, err := json.Marshal(value); err == nil {
+		return string(encoded)
+	}
+	return fmt.Sprint(value)
+}
+
+// expandedRow returns a single row in psql's expanded (\x) layout, one
+// column per line with every value included in full.
+func (s *serverState) expandedRow(ctx context.Context, tx pgx.Tx, rows pgx.Rows, geometryFormat string) (*mcp.CallToolResult, any, error) {
+	fieldDescriptions := rows.FieldDescriptions()
+	typeMap := tx.Conn().TypeMap()
+
+	var values []interface{}
+	count := 0
+	for rows.Next() {
+		count++
+		if count > 1 {
+			rows.Close()
+			return s.returnErrorResult("Expanded output shows a single row but the query returned more, add a WHERE clause or LIMIT 1")
+		}
+		var err error
+		if values, err = rowValues(rows); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return s.returnErrorResult("Query error: %v", err)
+	}
+	recordRowsScanned(ctx, tx)
+
+	if count == 0 {
+		return s.returnErrorResult("The query returned no rows")
+	}
+
+	policies, err := s.resultColumnPolicies(ctx, tx, fieldDescriptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+	}
+	geometries, err := geometryColumns(ctx, tx, typeMap, fieldDescriptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+	}
+	for i, field := range fieldDescriptions {
+		if column, ok := geometries[field.Name]; ok {
+			rendered, err := renderGeometries(ctx, tx, column, geometryFormat, values[i:i+1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+			}
+			values[i] = rendered[0]
+		}
+	}
+	for i, field := range fieldDescriptions {
+		if policy, ok := policies[field.Name]; ok {
+			values[i] = policy.apply(values[i])
+		}
+	}
+
+	masked := make(map[string][]string)
+	if s.config.RedactPII {
+		for i, field := range fieldDescriptions {
+			var kinds []string
+			if values[i], kinds = redactValue(values[i]); len(kinds) > 0 {
+				masked[field.Name] = append(masked[field.Name], kinds...)
+			}
+		}
+	}
+
+	width := 0
+	for _, field := range fieldDescriptions {
+		width = max(width, len(field.Name))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "-[ RECORD 1 ]%s\n", strings.Repeat("-", width+3))
+	record := make([]map[string]interface{}, 0, len(fieldDescriptions))
+	for i, field := range fieldDescriptions {
+		typeName := fmt.Sprintf("oid %d", field.DataTypeOID)
+		if dataType, ok := typeMap.TypeForOID(field.DataTypeOID); ok {
+			typeName = dataType.Name
+		}
+
+		text := expandedValue(values[i])
+		// continuation lines line up under the first line of the value
+		text = strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", width)+" | ")
+		fmt.Fprintf(&out, "%-*s | %s\n", width, field.Name, text)
+
+		record = append(record, map[string]interface{}{
+			"column": field.Name,
+			"type":   typeName,
+			"value":  values[i],
+		})
+	}
+
+	return s.withWarnings(&mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: out.String()},
+		},
+	}, append(s.columnPolicyWarnings(policies), s.piiWarnings(masked)...)), record, nil
+}
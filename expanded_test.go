@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestExpandedQuery(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("single row", func(t *testing.T) {
+		args := QueryArgs{Query: "SELECT *, repeat('x', 10000) AS long_text FROM users WHERE id = 1", Expanded: true}
+		result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		record := data.([]map[string]interface{})
+		if len(record) != 8 || record[0]["column"] != "id" || record[0]["type"] != "int4" {
+			t.Errorf("Expected columns in query order with types, got %v", record)
+		}
+
+		text := result.Content[0].(*mcp.TextContent).Text
+		if !strings.HasPrefix(text, "-[ RECORD 1 ]") {
+			t.Errorf("Expected psql expanded layout, got %s", text)
+		}
+		if !strings.Contains(text, strings.Repeat("x", 10000)) {
+			t.Error("Expected long values to be included in full")
+		}
+	})
+
+	t.Run("multiple rows", func(t *testing.T) {
+		args := QueryArgs{Query: "SELECT * FROM users LIMIT 2", Expanded: true}
+		result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result when more than one row is returned")
+		}
+	})
+
+	t.Run("value rendering", func(t *testing.T) {
+		if value := expandedValue([]byte{0xde, 0xad}); value != `\xdead` {
+			t.Errorf("Expected bytea hex, got %q", value)
+		}
+		if value := expandedValue(map[string]interface{}{"a": 1}); value != `{"a":1}` {
+			t.Errorf("Expected JSON, got %q", value)
+		}
+		if value := expandedValue(nil); value != "" {
+			t.Errorf("Expected empty NULL, got %q", value)
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// explainVersionOptions are the EXPLAIN options of newer servers than the
+// oldest supported one, with the version that introduced them. GENERIC_PLAN
+// comes first since it turns ANALYZE off, which WAL depends on.
+var explainVersionOptions = []struct {
+	Name    string
+	Option  string
+	Version int
+}{
+	{"generic_plan", "GENERIC_PLAN", 160000},
+	{"settings", "SETTINGS", 120000},
+	{"wal", "WAL", 130000},
+	{"memory", "MEMORY", 170000},
+}
+
+// versionedExplainOptions returns the requested options the server supports,
+// and ANALYZE as they leave it. Options the server is too old for, or that
+// conflict with the others, are left out with a warning.
+func (s *serverState) versionedExplainOptions(ctx context.Context, args ExplainAnalyzeArgs, analyze bool) ([]string, bool, []string, error) {
+	requested := map[string]bool{
+		"generic_plan": args.GenericPlan,
+		"settings":     args.Settings,
+		"wal":          args.WAL,
+		"memory":       args.Memory,
+	}
+	if !args.GenericPlan && !args.Settings && !args.WAL && !args.Memory {
+		return nil, analyze, nil, nil
+	}
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, analyze, nil, fmt.Errorf("failed to get server version: %v", err)
+	}
+
+	var options, warnings []string
+	for _, option := range explainVersionOptions {
+		if !requested[option.Name] {
+			continue
+		}
+		if versionNum < option.Version {
+			warnings = append(warnings, fmt.Sprintf(s.localize("%s needs PostgreSQL %d or later and was left out, the server runs version %d"), option.Name, option.Version/10000, versionNum/10000))
+			continue
+		}
+		switch {
+		case option.Name == "generic_plan" && analyze:
+			// ANALYZE is on by default, only an explicit request is worth a warning
+			analyze = false
+			if args.Analyze {
+				warnings = append(warnings, s.localize("generic_plan cannot be combined with ANALYZE, only the estimated plan is shown"))
+			}
+		case option.Name == "wal" && !analyze:
+			warnings = append(warnings, s.localize("wal is only reported with ANALYZE and was left out"))
+			continue
+		}
+		options = append(options, option.Option+" true")
+	}
+	return options, analyze, warnings, nil
+}
+
+// explainLines runs an EXPLAIN statement and returns its output rows. A
+// generic plan's statement keeps its $1 parameters without values, which the
+// extended protocol would bind, so it is sent as a simple query instead.
+func explainLines(ctx context.Context, tx pgx.Tx, query string, genericPlan bool) ([][]byte, error) {
+	var lines [][]byte
+	if genericPlan {
+		results, err := tx.Conn().PgConn().Exec(ctx, query).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			for _, row := range result.Rows {
+				lines = append(lines, row[0])
+			}
+		}
+		return lines, nil
+	}
+
+	rows, err := tx.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var line []byte
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
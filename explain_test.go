@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestExplainAnalyzeVersionedOptions(t *testing.T) {
+	ctx := context.Background()
+	versionNum, err := testServer.serverVersionNum(ctx)
+	if err != nil {
+		t.Fatalf("serverVersionNum failed: %v", err)
+	}
+
+	t.Run("settings", func(t *testing.T) {
+		args := ExplainAnalyzeArgs{Query: "SELECT * FROM users", Settings: true, Render: "tree"}
+		testServer.parameters.set(sessionKey(nil), "enable_seqscan", "off")
+		defer testServer.parameters.reset(sessionKey(nil), "")
+
+		result, data, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+		if err != nil || result.IsError {
+			t.Fatalf("ExplainAnalyze failed: %v %v", err, result)
+		}
+		if !strings.Contains(data.(string), "enable_seqscan=off") {
+			t.Errorf("Expected the changed setting in the plan, got:\n%s", data)
+		}
+	})
+
+	t.Run("generic plan", func(t *testing.T) {
+		args := ExplainAnalyzeArgs{Query: "SELECT * FROM users WHERE id = $1", GenericPlan: true, Format: "text"}
+		result, data, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExplainAnalyze failed: %v", err)
+		}
+		if versionNum < 160000 {
+			if !result.IsError && len(result.Content) < 2 {
+				t.Errorf("Expected generic_plan to be reported as unsupported, got %v", result)
+			}
+			return
+		}
+		if result.IsError || !strings.Contains(data.(string), "$1") {
+			t.Errorf("Expected a generic plan with its parameter, got %v %v", result, data)
+		}
+	})
+
+	t.Run("wal without analyze", func(t *testing.T) {
+		arguments, _ := json.Marshal(map[string]interface{}{"query": "SELECT 1", "wal": true, "analyze": false})
+		req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "explain_analyze", Arguments: arguments}}
+		args := ExplainAnalyzeArgs{Query: "SELECT 1", WAL: true}
+		result, _, err := testServer.ExplainAnalyze(ctx, req, args)
+		if err != nil || result.IsError {
+			t.Fatalf("ExplainAnalyze failed: %v %v", err, result)
+		}
+		if len(result.Content) < 2 {
+			t.Errorf("Expected a warning that wal was left out, got %v", result.Content)
+		}
+	})
+}
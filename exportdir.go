@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// validateExportDir checks EXPORT_DIR, the one directory tools may write
+// files to and read backups from.
+func validateExportDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("EXPORT_DIR must be an absolute path, got %q", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid EXPORT_DIR: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid EXPORT_DIR: %s is not a directory", dir)
+	}
+	return nil
+}
+
+// exportPath resolves a file name passed to a tool inside EXPORT_DIR. Names
+// are relative to it and can't leave it: absolute paths, .. and symlinks
+// below EXPORT_DIR are refused, so a tool call can't reach other files the
+// server's user may write.
+func (s *serverState) exportPath(name string) (string, error) {
+	if s.config.ExportDir == "" {
+		return "", errors.New(s.localize("No export directory, set EXPORT_DIR to the directory tools may write files to"))
+	}
+	if filepath.IsAbs(name) || !filepath.IsLocal(name) {
+		return "", fmt.Errorf(s.localize("%s must be a file name relative to EXPORT_DIR, without .."), name)
+	}
+
+	path := s.config.ExportDir
+	for _, part := range strings.Split(filepath.Clean(name), string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf(s.localize("%s is a symlink, which tools don't follow out of EXPORT_DIR"), name)
+		}
+	}
+	return filepath.Join(s.config.ExportDir, name), nil
+}
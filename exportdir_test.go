@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportPath(t *testing.T) {
+	dir := t.TempDir()
+	s := &serverState{}
+
+	if _, err := s.exportPath("dump.sql"); err == nil {
+		t.Error("Expected file output to be refused without EXPORT_DIR")
+	}
+
+	s.config.ExportDir = dir
+	if path, err := s.exportPath("nested/dump.sql"); err != nil || path != filepath.Join(dir, "nested", "dump.sql") {
+		t.Errorf("Expected a relative name to resolve inside EXPORT_DIR, got %q, %v", path, err)
+	}
+	for _, name := range []string{"", "/etc/passwd", "../dump.sql", "nested/../../dump.sql"} {
+		if _, err := s.exportPath(name); err == nil {
+			t.Errorf("Expected %q to be refused", name)
+		}
+	}
+
+	os.Symlink("/etc", filepath.Join(dir, "etc"))
+	os.Symlink("/etc/passwd", filepath.Join(dir, "passwd"))
+	for _, name := range []string{"etc/passwd", "passwd"} {
+		if _, err := s.exportPath(name); err == nil {
+			t.Errorf("Expected the symlink in %q to be refused", name)
+		}
+	}
+}
+
+func TestDiscardedArtifactKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.gz")
+	os.WriteFile(path, []byte("earlier backup"), 0o600)
+	s := &serverState{}
+
+	file, _, err := s.createArtifact(path)
+	if err != nil {
+		t.Fatalf("createArtifact failed: %v", err)
+	}
+	file.Write([]byte("partial"))
+	file.Discard()
+	if contents, _ := os.ReadFile(path); string(contents) != "earlier backup" {
+		t.Errorf("Expected a discarded write to leave the file alone, got %q", contents)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be removed, got %v", entries)
+	}
+
+	file, _, _ = s.createArtifact(path)
+	file.Write([]byte("new backup"))
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if contents, _ := os.ReadFile(path); string(contents) != "new backup" {
+		t.Errorf("Expected the new backup in place, got %q", contents)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectionLostCodes are the SQLSTATEs of a server going away: connection
+// exceptions (class 08) and administrator, crash and startup shutdowns.
+var connectionLostCodes = map[string]bool{"57P01": true, "57P02": true, "57P03": true}
+
+// connectionLost reports whether err means the connection is gone, as after
+// a failover or restart, rather than the statement failing.
+func connectionLost(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return strings.HasPrefix(pgErr.Code, "08") || connectionLostCodes[pgErr.Code]
+	}
+	var netErr net.Error
+	return pgconn.SafeToRetry(err) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// beginSession starts a tool call's transaction and applies the calling
+// session's declared state: its session parameters and the requested role.
+// Pooled connections can outlive the server they were opened to, so when
+// the connection turns out to be lost the pool is reset and everything is
+// replayed once on a new connection. The returned notices tell the client
+// what was restored and what could not be.
+func (s *serverState) beginSession(ctx context.Context, req *mcp.CallToolRequest, options pgx.TxOptions, role string) (pgx.Tx, []string, error) {
+	tx, err := s.setUpSession(ctx, req, options, role)
+	if err == nil || !connectionLost(err) {
+		return tx, nil, err
+	}
+
+	log.Printf("Connection lost, resetting the pool: %v", err)
+	s.pool.Reset()
+	tx, retryErr := s.setUpSession(ctx, req, options, role)
+	if retryErr != nil {
+		if connectionLost(retryErr) {
+			s.startReconnecting(retryErr)
+		}
+		return nil, nil, fmt.Errorf(s.localize("The connection to the database was lost (%v) and could not be re-established: %v"), err, retryErr)
+	}
+	return tx, s.reconnectNotices(req, role, err), nil
+}
+
+func (s *serverState) setUpSession(ctx context.Context, req *mcp.CallToolRequest, options pgx.TxOptions, role string) (pgx.Tx, error) {
+	tx, err := s.pool.BeginTx(ctx, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := s.applySessionParameters(ctx, req, tx); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	if err := s.applyRole(ctx, tx, role); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// reconnectNotices describes a replay after a lost connection.
+func (s *serverState) reconnectNotices(req *mcp.CallToolRequest, role string, cause error) []string {
+	var restored []string
+	for _, name := range sortedKeys(s.parameters.get(sessionKey(req))) {
+		restored = append(restored, name)
+	}
+	if s.config.Role != "" {
+		restored = append(restored, "ROLE "+s.config.Role)
+	}
+	if role != "" {
+		restored = append(restored, "role "+role)
+	}
+
+	notices := []string{fmt.Sprintf(s.localize("The connection to the database was lost (%v) and re-established on a new connection"), cause)}
+	if len(restored) > 0 {
+		notices = append(notices, fmt.Sprintf(s.localize("Restored session state: %s"), strings.Join(restored, ", ")))
+	}
+	return append(notices, s.localize("Temporary tables, prepared statements, advisory locks and settings changed with SET on the lost connection were not restored"))
+}
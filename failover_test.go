@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestConnectionLost(t *testing.T) {
+	tests := []struct {
+		err  error
+		lost bool
+	}{
+		{&pgconn.PgError{Code: "08006"}, true},
+		{&pgconn.PgError{Code: "57P01"}, true},
+		{fmt.Errorf("failed to begin transaction: %w", &pgconn.PgError{Code: "57P03"}), true},
+		{&pgconn.PgError{Code: "42P01"}, false},
+		{io.EOF, true},
+		{context.Canceled, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if lost := connectionLost(test.err); lost != test.lost {
+			t.Errorf("connectionLost(%v) = %t, expected %t", test.err, lost, test.lost)
+		}
+	}
+}
+
+func TestSessionSurvivesTerminatedConnection(t *testing.T) {
+	ctx := context.Background()
+	poolConfig := testServer.pool.Config()
+	poolConfig.MinConns = 0
+	poolConfig.MaxConns = 1
+	state, err := newServerState(ctx, Config{AllowInsecure: true}, poolConfig)
+	if err != nil {
+		t.Fatalf("newServerState failed: %v", err)
+	}
+	defer state.Close()
+
+	state.parameters.set("", "statement_timeout", "5s")
+
+	var pid int
+	if err := state.pool.QueryRow(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatalf("Failed to read backend pid: %v", err)
+	}
+	// The timeout makes pg_terminate_backend wait until the backend is gone
+	if _, err := testServer.pool.Exec(ctx, "SELECT pg_terminate_backend($1, 5000)", pid); err != nil {
+		t.Fatalf("Failed to terminate backend: %v", err)
+	}
+
+	args := QueryArgs{Query: "SELECT current_setting('statement_timeout') AS timeout"}
+	result, data, err := state.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected the query to run on a new connection, got %v", result.Content)
+	}
+	rows := data.([]map[string]interface{})
+	if rows[0]["timeout"] != "5s" {
+		t.Errorf("Expected statement_timeout to be replayed, got %v", rows[0]["timeout"])
+	}
+
+	var warnings string
+	for _, content := range result.Content {
+		if text, ok := content.(*mcp.TextContent); ok {
+			warnings += text.Text
+		}
+	}
+	if !strings.Contains(warnings, "re-established") || !strings.Contains(warnings, "statement_timeout") {
+		t.Errorf("Expected the reconnect to be reported, got %q", warnings)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// secretOptionWords mark FDW options whose values are credentials, some
+// wrappers taking them on the server rather than the user mapping.
+var secretOptionWords = []string{"password", "passfile", "secret", "token", "key"}
+
+// fdwOptions reads FDW options into a map, redacting credentials.
+func fdwOptions(options []string) map[string]string {
+	parsed := parseReloptions(options)
+	for name := range parsed {
+		lower := strings.ToLower(name)
+		for _, word := range secretOptionWords {
+			if strings.Contains(lower, word) {
+				parsed[name] = redactedSecret
+				break
+			}
+		}
+	}
+	return parsed
+}
+
+type ListForeignTablesArgs struct {
+	Schema string `json:"schema,omitempty" jsonschema:"Only list the foreign tables of this schema (default: every schema)"`
+	Server string `json:"server,omitempty" jsonschema:"Only list this foreign server and its tables (default: every server)"`
+}
+
+// ListForeignTables lists the foreign servers with their wrapper and
+// options, the user mappings for them and the foreign tables they serve.
+// User mappings only name their options, since they hold the remote
+// passwords, and credential-like server options are redacted too.
+func (s *serverState) ListForeignTables(ctx context.Context, req *mcp.CallToolRequest, args ListForeignTablesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT fs.srvname::text, w.fdwname::text, pg_get_userbyid(fs.srvowner)::text,
+			COALESCE(fs.srvtype, ''), COALESCE(fs.srvversion, ''), COALESCE(fs.srvoptions, '{}')
+		FROM pg_foreign_server fs
+		JOIN pg_foreign_data_wrapper w ON w.oid = fs.srvfdw
+		WHERE $1 = '' OR fs.srvname = $1
+		ORDER BY fs.srvname
+	`, args.Server)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list foreign servers: %v", err)
+	}
+	servers := []map[string]interface{}{}
+	byName := make(map[string]map[string]interface{})
+	for rows.Next() {
+		var name, wrapper, owner, serverType, version string
+		var options []string
+		if err := rows.Scan(&name, &wrapper, &owner, &serverType, &version, &options); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		server := map[string]interface{}{
+			"name":          name,
+			"wrapper":       wrapper,
+			"owner":         owner,
+			"options":       fdwOptions(options),
+			"user_mappings": []map[string]interface{}{},
+		}
+		if serverType != "" {
+			server["type"] = serverType
+		}
+		if version != "" {
+			server["version"] = version
+		}
+		servers = append(servers, server)
+		byName[name] = server
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	if args.Server != "" && len(servers) == 0 {
+		return s.returnErrorResult("Foreign server %s not found", args.Server)
+	}
+
+	// umoptions is NULL for mappings the current user may not read
+	rows, err = s.pool.Query(ctx, `
+		SELECT srvname::text, usename::text, umoptions IS NOT NULL,
+			ARRAY(SELECT split_part(o, '=', 1) FROM unnest(COALESCE(umoptions, '{}')) o)
+		FROM pg_user_mappings
+		ORDER BY srvname, usename
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list user mappings: %v", err)
+	}
+	for rows.Next() {
+		var serverName, user string
+		var readable bool
+		var optionNames []string
+		if err := rows.Scan(&serverName, &user, &readable, &optionNames); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		server := byName[serverName]
+		if server == nil {
+			continue
+		}
+		mapping := map[string]interface{}{"user": user, "options_visible": readable}
+		if readable {
+			sort.Strings(optionNames)
+			mapping["option_names"] = optionNames
+		}
+		server["user_mappings"] = append(server["user_mappings"].([]map[string]interface{}), mapping)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	rows, err = s.pool.Query(ctx, `
+		SELECT n.nspname::text, c.relname::text, fs.srvname::text, COALESCE(ft.ftoptions, '{}'),
+			(SELECT count(*) FROM pg_attribute a WHERE a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped)
+		FROM pg_foreign_table ft
+		JOIN pg_class c ON c.oid = ft.ftrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_foreign_server fs ON fs.oid = ft.ftserver
+		WHERE ($1 = '' OR n.nspname = $1) AND ($2 = '' OR fs.srvname = $2)
+		ORDER BY n.nspname, c.relname
+	`, args.Schema, args.Server)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list foreign tables: %v", err)
+	}
+	defer rows.Close()
+	tables := []map[string]interface{}{}
+	for rows.Next() {
+		var schema, name, server string
+		var options []string
+		var columns int64
+		if err := rows.Scan(&schema, &name, &server, &options, &columns); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, name) {
+			continue
+		}
+		tables = append(tables, map[string]interface{}{
+			"name":    qualifiedName(schema, name),
+			"server":  server,
+			"options": fdwOptions(options),
+			"columns": columns,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"servers":        servers,
+		"foreign_tables": tables,
+	})
+}
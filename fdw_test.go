@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListForeignTables(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE FOREIGN DATA WRAPPER test_wrapper;
+		CREATE SERVER test_remote FOREIGN DATA WRAPPER test_wrapper OPTIONS (host 'remote.example', password 'hunter22');
+		CREATE USER MAPPING FOR CURRENT_USER SERVER test_remote OPTIONS (user 'reader', password 'hunter22');
+		CREATE FOREIGN TABLE remote_orders (id int OPTIONS (column_name 'order_id'), total numeric)
+			SERVER test_remote OPTIONS (table_name 'orders');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create foreign table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP FOREIGN DATA WRAPPER test_wrapper CASCADE")
+
+	args := ListForeignTablesArgs{Server: "test_remote"}
+	result, data, err := testServer.ListForeignTables(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ListForeignTables failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	server := response["servers"].([]map[string]interface{})[0]
+	options := server["options"].(map[string]string)
+	if options["host"] != "remote.example" || options["password"] != redactedSecret {
+		t.Errorf("Expected the host shown and the password redacted, got %v", options)
+	}
+	mappings := server["user_mappings"].([]map[string]interface{})
+	if len(mappings) != 1 || len(mappings[0]["option_names"].([]string)) != 2 {
+		t.Errorf("Expected one mapping naming two options, got %v", mappings)
+	}
+	tables := response["foreign_tables"].([]map[string]interface{})
+	if len(tables) != 1 || tables[0]["options"].(map[string]string)["table_name"] != "orders" {
+		t.Errorf("Expected remote_orders with its table_name option, got %v", tables)
+	}
+
+	schemaArgs := TableSchemaArgs{TableName: "remote_orders"}
+	_, columns, err := testServer.GetTableSchema(ctx, createMockRequest(schemaArgs), schemaArgs)
+	if err != nil {
+		t.Fatalf("GetTableSchema failed: %v", err)
+	}
+	id := columns.([]map[string]interface{})[0]
+	if id["fdw_options"].(map[string]string)["column_name"] != "order_id" {
+		t.Errorf("Expected the column_name option of id, got %v", id)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// normalizedConstant replaces every constant and parameter of a normalized
+// query.
+const normalizedConstant = "?"
+
+// normalizeQuery reduces a statement to its shape: comments and formatting
+// dropped, unquoted names and keywords lower-cased, and string, number and
+// parameter values replaced by ?. Lists of constants collapse to a single ?,
+// so IN lists of any length normalize alike.
+func normalizeQuery(query string) string {
+	var parts []string
+	for _, token := range sqlTokens(strings.TrimRight(strings.TrimSpace(query), ";")) {
+		text := token.Text
+		switch {
+		case token.Word:
+			text = strings.ToLower(token.Raw)
+		case text == "'" || text == "$$" || strings.HasPrefix(text, "$") || (text[0] >= '0' && text[0] <= '9'):
+			text = normalizedConstant
+		}
+		// a minus sign before a constant is part of it, not an operator
+		if text == normalizedConstant && len(parts) >= 1 && parts[len(parts)-1] == "-" && (len(parts) == 1 || !operandEnd(parts[len(parts)-2])) {
+			parts = parts[:len(parts)-1]
+		}
+		// ?, ? collapses into ?
+		if text == normalizedConstant && len(parts) >= 2 && parts[len(parts)-1] == "," && parts[len(parts)-2] == normalizedConstant {
+			parts = parts[:len(parts)-1]
+			continue
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, " ")
+}
+
+// operandEnd reports whether a normalized token can end an operand, making a
+// following minus a subtraction.
+func operandEnd(part string) bool {
+	return part == normalizedConstant || part == ")" || strings.HasPrefix(part, `"`) || (part[0] >= 'a' && part[0] <= 'z') || part[0] == '_'
+}
+
+// queryFingerprint identifies the normalized form of a statement: queries
+// differing only in constants, formatting or comments share it.
+func queryFingerprint(query string) string {
+	sum := sha256.Sum256([]byte(normalizeQuery(query)))
+	return hex.EncodeToString(sum[:8])
+}
+
+type NormalizeQueryArgs struct {
+	Query           string `json:"query" jsonschema:"SQL statement to normalize, such as a query from a log or a pg_stat_statements entry"`
+	MatchStatements bool   `json:"match_statements,omitempty" jsonschema:"Also list the pg_stat_statements entries of the current database with the same fingerprint, among the most expensive (default: false)"`
+}
+
+func (s *serverState) NormalizeQuery(ctx context.Context, req *mcp.CallToolRequest, args NormalizeQueryArgs) (*mcp.CallToolResult, any, error) {
+	if strings.TrimSpace(args.Query) == "" {
+		return s.returnErrorResult("query is required")
+	}
+	normalized := normalizeQuery(args.Query)
+	response := map[string]interface{}{
+		"normalized":  normalized,
+		"fingerprint": queryFingerprint(args.Query),
+	}
+	if !args.MatchStatements {
+		return returnJSONResult(response)
+	}
+
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	statements, warning, err := s.expensiveStatements(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var warnings []string
+	if warning != "" {
+		warnings = append(warnings, s.localize("pg_stat_statements is not installed, no statements were matched"))
+	}
+	matches := []map[string]interface{}{}
+	for _, statement := range statements {
+		if normalizeQuery(statement.Query) != normalized {
+			continue
+		}
+		match := map[string]interface{}{
+			"query":         statement.Query,
+			"calls":         statement.Calls,
+			"total_time_ms": round(statement.TotalTimeMs, 3),
+		}
+		if statement.Calls > 0 {
+			match["mean_time_ms"] = round(statement.TotalTimeMs/float64(statement.Calls), 3)
+		}
+		matches = append(matches, match)
+	}
+	response["statements"] = matches
+	response["statements_searched"] = len(statements)
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE id = 42", "select * from users where id = ?"},
+		{"select *\n  from Users -- lookup\n where id=$1;", "select * from users where id = ?"},
+		{`SELECT "Name" FROM t WHERE status IN ('a', 'b', 'c')`, `select "Name" from t where status in ( ? )`},
+		{"SELECT * FROM t WHERE x > -5", "select * from t where x > ?"},
+		{"SELECT a - 1 FROM t", "select a - ? from t"},
+	}
+	for _, test := range tests {
+		if got := normalizeQuery(test.query); got != test.expected {
+			t.Errorf("normalizeQuery(%q) = %q, expected %q", test.query, got, test.expected)
+		}
+	}
+}
+
+func TestQueryFingerprint(t *testing.T) {
+	a := queryFingerprint("SELECT * FROM posts WHERE user_id = 42 AND title = 'x'")
+	b := queryFingerprint("select * from posts /* other */ where user_id = 7 and title = 'hello'")
+	if a != b {
+		t.Errorf("Expected queries differing in constants to share a fingerprint, got %s and %s", a, b)
+	}
+	if len(a) != 16 {
+		t.Errorf("Expected a 16 character fingerprint, got %q", a)
+	}
+	if c := queryFingerprint("SELECT * FROM posts WHERE user_id = 42 OR title = 'x'"); c == a {
+		t.Errorf("Expected a different query to have a different fingerprint")
+	}
+}
+
+func TestNormalizeQueryTool(t *testing.T) {
+	args := NormalizeQueryArgs{Query: "SELECT * FROM posts WHERE user_id = $1"}
+	result, data, err := testServer.NormalizeQuery(context.Background(), createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("NormalizeQuery failed: %v %v", err, result)
+	}
+	response := data.(map[string]interface{})
+	if response["normalized"] != "select * from posts where user_id = ?" || response["fingerprint"] != queryFingerprint("select * from posts where user_id = 42") {
+		t.Errorf("Unexpected normalization %v", response)
+	}
+}
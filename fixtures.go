@@ -0,0 +1,547 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultFixtureRows    = 100
+	fixtureInsertBatch    = 100
+	maxInlineFixtureBytes = 1 << 20
+)
+
+var varcharLengthPattern = regexp.MustCompile(`^(?:character varying|character|varchar|char)\((\d+)\)`)
+
+type ExportFixtureArgs struct {
+	Tables     []string `json:"tables" jsonschema:"Tables to export, either bare names (public schema) or schema.table, quoting mixed-case names"`
+	RowLimit   int      `json:"row_limit,omitempty" jsonschema:"Maximum rows to export per table (default: 100)"`
+	Anonymize  bool     `json:"anonymize,omitempty" jsonschema:"Replace free-text values with deterministic fakes (default: true)"`
+	OutputPath string   `json:"output_path,omitempty" jsonschema:"Write the fixture to this file in EXPORT_DIR, relative to it, instead of returning it inline"`
+
+	Columns        []string `json:"columns,omitempty" jsonschema:"Only export these columns, as column (any exported table) or table.column. Key and NOT NULL columns without a default are always kept. Tables without a listed column are exported in full"`
+	ExcludeColumns []string `json:"exclude_columns,omitempty" jsonschema:"Columns to leave out of the exported rows, as column (any exported table) or table.column. Omitted columns get their default or NULL"`
+}
+
+// fakeHashLength is how many hex digits of a value's hash its fake keeps,
+// which is what keeps the fakes of distinct values distinct.
+const fakeHashLength = 10
+
+// fakeParts returns what surrounds the hash in a column's fakes, and how
+// much of the hash they keep. When a char or varchar limit is too short for
+// the whole fake, the suffix and then the prefix give way to the hash, which
+// is only cut when the limit is below fakeHashLength.
+func fakeParts(column columnDef) (prefix, suffix string, hashLength int) {
+	prefix, suffix, hashLength = column.Name+"_", "", fakeHashLength
+	if strings.Contains(strings.ToLower(column.Name), "email") {
+		prefix, suffix = "user_", "@example.com"
+	}
+
+	match := varcharLengthPattern.FindStringSubmatch(column.Type)
+	if match == nil {
+		return prefix, suffix, hashLength
+	}
+	limit, err := strconv.Atoi(match[1])
+	if err != nil || utf8.RuneCountInString(prefix)+hashLength+len(suffix) <= limit {
+		return prefix, suffix, hashLength
+	}
+	hashLength = min(hashLength, limit)
+	prefixRunes := []rune(prefix)
+	return string(prefixRunes[:min(len(prefixRunes), limit-hashLength)]), "", hashLength
+}
+
+// anonymizeValue replaces a text value with a deterministic fake, so equal
+// inputs stay equal (and unique values stay unique) across tables. The hash
+// is an HMAC-SHA256 under ANONYMIZE_KEY: the fakes are pseudonyms, which
+// can't be reversed by hashing guesses without the key, but anyone holding
+// it can.
+func anonymizeValue(key []byte, column columnDef, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	prefix, suffix, hashLength := fakeParts(column)
+	return prefix + hex.EncodeToString(mac.Sum(nil))[:hashLength] + suffix
+}
+
+// hmacExpression is HMAC-SHA256 under key of a text SQL expression, built
+// from the server's sha256() as in RFC 2104 so pgcrypto isn't needed.
+func hmacExpression(key []byte, expression string) string {
+	if len(key) > sha256.BlockSize {
+		sum := sha256.Sum256(key)
+		key = sum[:]
+	}
+	inner, outer := make([]byte, sha256.BlockSize), make([]byte, sha256.BlockSize)
+	copy(inner, key)
+	copy(outer, key)
+	for i := range inner {
+		inner[i] ^= 0x36
+		outer[i] ^= 0x5c
+	}
+	return fmt.Sprintf("sha256(decode('%x', 'hex') || sha256(decode('%x', 'hex') || convert_to(%s, 'UTF8')))", outer, inner, expression)
+}
+
+// anonymizeExpression is anonymizeValue as a SQL expression over the column,
+// so anonymize_table rewrites values to the fakes export_fixture produces.
+func anonymizeExpression(key []byte, column columnDef) string {
+	prefix, suffix, hashLength := fakeParts(column)
+	fake := fmt.Sprintf("left(encode(%s, 'hex'), %d)", hmacExpression(key, pgx.Identifier{column.Name}.Sanitize()+"::text"), hashLength)
+	if prefix != "" {
+		fake = quoteLiteral(prefix) + " || " + fake
+	}
+	if suffix != "" {
+		fake += " || " + quoteLiteral(suffix)
+	}
+	return fake
+}
+
+// collidingFakes returns the columns of a unique constraint among names
+// whose length limit cuts the hash of their fakes, so distinct values could
+// get equal fakes and break the constraint.
+func collidingFakes(def *tableDef, names []string) []string {
+	unique := make(map[string]bool)
+	for _, constraint := range def.Constraints {
+		if constraint.Type == "u" || constraint.Type == "p" {
+			for _, column := range constraint.Columns {
+				unique[column] = true
+			}
+		}
+	}
+	var colliding []string
+	for _, column := range def.Columns {
+		if _, _, hashLength := fakeParts(column); unique[column.Name] && hashLength < fakeHashLength && slices.Contains(names, column.Name) {
+			colliding = append(colliding, column.Name)
+		}
+	}
+	return colliding
+}
+
+// anonymizableColumns picks string columns whose values are safe to rewrite.
+// Key columns must keep matching their references and CHECK constrained columns
+// are usually enumerations, apart from emails which get an email-shaped fake.
+func anonymizableColumns(def *tableDef) map[string]bool {
+	protected := make(map[string]bool)
+	for _, constraint := range def.Constraints {
+		if constraint.Type == "f" || constraint.Type == "c" || constraint.Type == "p" {
+			for _, column := range constraint.Columns {
+				protected[column] = true
+			}
+		}
+	}
+
+	columns := make(map[string]bool)
+	for _, column := range def.Columns {
+		isEmail := strings.Contains(strings.ToLower(column.Name), "email")
+		if column.TypeCategory == "S" && (!protected[column.Name] || isEmail) {
+			columns[column.Name] = true
+		}
+	}
+	return columns
+}
+
+// fixtureSampleQueries builds a sampling query per table, restricting child
+// tables to rows whose foreign keys point at rows sampled from their parents.
+func fixtureSampleQueries(defs []*tableDef, rowLimit int) map[string]string {
+	samples := make(map[string]string)
+	for _, def := range defs {
+		var conditions []string
+		for _, constraint := range def.Constraints {
+			parentSample, ok := samples[constraint.RefTable]
+			if constraint.Type != "f" || !ok || constraint.RefTable == def.Name {
+				continue
+			}
+
+			// the referenced columns are the ones named after REFERENCES in the definition
+			refColumns := referencedColumns(constraint.Definition)
+			if len(refColumns) != len(constraint.Columns) {
+				continue
+			}
+
+			var localColumns, parentColumns, nullChecks []string
+			for i, column := range constraint.Columns {
+				localColumns = append(localColumns, "t."+pgx.Identifier{column}.Sanitize())
+				parentColumns = append(parentColumns, pgx.Identifier{refColumns[i]}.Sanitize())
+				nullChecks = append(nullChecks, "t."+pgx.Identifier{column}.Sanitize()+" IS NULL")
+			}
+			conditions = append(conditions, fmt.Sprintf("((%s) IN (SELECT %s FROM (%s) AS parent_sample) OR %s)",
+				strings.Join(localColumns, ", "), strings.Join(parentColumns, ", "), parentSample, strings.Join(nullChecks, " OR ")))
+		}
+
+		query := fmt.Sprintf("SELECT t.* FROM %s t", sanitizeQualifiedName(def.Name))
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		if len(def.PrimaryKey) > 0 {
+			var orderColumns []string
+			for _, column := range def.PrimaryKey {
+				orderColumns = append(orderColumns, "t."+pgx.Identifier{column}.Sanitize())
+			}
+			query += " ORDER BY " + strings.Join(orderColumns, ", ")
+		}
+		samples[def.Name] = fmt.Sprintf("%s LIMIT %d", query, rowLimit)
+	}
+	return samples
+}
+
+var referencesPattern = regexp.MustCompile(`REFERENCES\s+.+?\(([^)]*)\)`)
+
+// referencedColumns extracts the referenced column list from a foreign key
+// definition as produced by pg_get_constraintdef.
+func referencedColumns(definition string) []string {
+	match := referencesPattern.FindStringSubmatch(definition)
+	if match == nil {
+		return nil
+	}
+	var columns []string
+	for _, column := range strings.Split(match[1], ",") {
+		column = strings.TrimSpace(column)
+		if unquoted, err := strconv.Unquote(column); err == nil && strings.HasPrefix(column, `"`) {
+			column = unquoted
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// columnSelection is a columns/exclude_columns entry resolved against the
+// exported tables. A bare column name applies to every table that has it.
+type columnSelection struct {
+	Table  string
+	Column string
+}
+
+func (c columnSelection) matches(def *tableDef, column string) bool {
+	return (c.Table == "" || c.Table == def.Name) && c.Column == column
+}
+
+// parseColumnSelections validates entries against the catalog definitions,
+// so a typo is reported instead of silently exporting everything.
+func parseColumnSelections(defs []*tableDef, entries []string) ([]columnSelection, error) {
+	var selections []columnSelection
+	for _, entry := range entries {
+		selection := columnSelection{Column: entry}
+		if i := strings.LastIndex(entry, "."); i >= 0 {
+			selection = columnSelection{Table: qualifyTableName(entry[:i]), Column: entry[i+1:]}
+		}
+
+		found := false
+		for _, def := range defs {
+			for _, column := range def.Columns {
+				found = found || selection.matches(def, column.Name)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q does not exist in any exported table", entry)
+		}
+		selections = append(selections, selection)
+	}
+	return selections, nil
+}
+
+// omittedColumns works out which columns of a table are left out of the
+// exported rows. Columns that keys or NOT NULL constraints depend on are kept
+// when they were merely not listed, and rejected when excluded by name.
+func omittedColumns(def *tableDef, columns, exclude []columnSelection) (map[string]bool, error) {
+	required := make(map[string]bool)
+	for _, constraint := range def.Constraints {
+		if constraint.Type == "p" || constraint.Type == "f" || constraint.Type == "u" {
+			for _, column := range constraint.Columns {
+				required[column] = true
+			}
+		}
+	}
+	for _, column := range def.Columns {
+		if column.NotNull && column.Default == nil && column.Identity == "" && column.Generated == "" {
+			required[column.Name] = true
+		}
+	}
+
+	listed := make(map[string]bool)
+	for _, column := range def.Columns {
+		for _, selection := range columns {
+			if selection.matches(def, column.Name) {
+				listed[column.Name] = true
+			}
+		}
+	}
+
+	omitted := make(map[string]bool)
+	for _, column := range def.Columns {
+		if len(listed) > 0 && !listed[column.Name] && !required[column.Name] {
+			omitted[column.Name] = true
+		}
+		for _, selection := range exclude {
+			if !selection.matches(def, column.Name) {
+				continue
+			}
+			if required[column.Name] {
+				return nil, fmt.Errorf("column %s.%s is needed for keys or NOT NULL constraints and cannot be excluded", def.Name, column.Name)
+			}
+			omitted[column.Name] = true
+		}
+	}
+	return omitted, nil
+}
+
+func (s *serverState) ExportFixture(ctx context.Context, req *mcp.CallToolRequest, args ExportFixtureArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if len(args.Tables) == 0 {
+		return s.returnErrorResult("at least one table is required")
+	}
+
+	rowLimit := args.RowLimit
+	if rowLimit <= 0 {
+		rowLimit = defaultFixtureRows
+	}
+	anonymize := getExplicitBool(getRawArgs(req), "anonymize", args.Anonymize, true)
+
+	var defs []*tableDef
+	included := make(map[string]bool)
+	for _, table := range args.Tables {
+		name, err := s.resolveQualifiedTable(ctx, table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if included[name] {
+			continue
+		}
+		if !s.qualifiedAllowed(name) {
+			return s.returnNotAccessible(name)
+		}
+		def, err := s.loadTableDef(ctx, name)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		defs = append(defs, def)
+		included[name] = true
+	}
+	defs = sortByForeignKeys(defs)
+
+	columnSelections, err := parseColumnSelections(defs, args.Columns)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	excludeSelections, err := parseColumnSelections(defs, args.ExcludeColumns)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	omitted := make(map[string]map[string]bool)
+	for _, def := range defs {
+		if omitted[def.Name], err = omittedColumns(def, columnSelections, excludeSelections); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+
+	// sample everything from one snapshot so parent and child rows line up
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var out strings.Builder
+	out.WriteString("-- Test fixture generated by postgres-mcp\n")
+	out.WriteString("BEGIN;\n\n")
+
+	schemas := make(map[string]bool)
+	for _, def := range defs {
+		schema, _ := splitQualifiedName(def.Name)
+		if !schemas[schema] {
+			schemas[schema] = true
+			fmt.Fprintf(&out, "CREATE SCHEMA IF NOT EXISTS %s;\n", pgx.Identifier{schema}.Sanitize())
+		}
+	}
+	out.WriteString("\n")
+
+	for _, def := range defs {
+		for _, statement := range def.sequenceStatements() {
+			out.WriteString(statement + "\n")
+		}
+		out.WriteString(def.createStatement() + "\n")
+		for _, statement := range def.sequenceOwnershipStatements() {
+			out.WriteString(statement + "\n")
+		}
+		out.WriteString("\n")
+	}
+
+	samples := fixtureSampleQueries(defs, rowLimit)
+	var summary []map[string]interface{}
+	for _, def := range defs {
+		var columns []columnDef
+		var omittedNames []string
+		for _, column := range def.insertableColumns() {
+			if omitted[def.Name][column.Name] {
+				omittedNames = append(omittedNames, column.Name)
+				continue
+			}
+			columns = append(columns, column)
+		}
+		if len(columns) == 0 {
+			return s.returnErrorResult("No columns of %s are left to export", def.Name)
+		}
+		anonymized := make(map[string]bool)
+		if anonymize {
+			anonymized = anonymizableColumns(def)
+			// text columns COLUMN_POLICY_FILE masks or marks as free text are never exported as is
+			schema, table := splitQualifiedName(def.Name)
+			for _, column := range def.Columns {
+				if policy, ok := s.config.ColumnPolicies.lookup(schema, table, column.Name); ok && policy.sensitive() && column.TypeCategory == "S" {
+					anonymized[column.Name] = true
+				}
+			}
+			var exported []string
+			for _, column := range columns {
+				if anonymized[column.Name] {
+					exported = append(exported, column.Name)
+				}
+			}
+			if colliding := collidingFakes(def, exported); len(colliding) > 0 {
+				return s.returnErrorResult("%s of %s are unique but too short for distinct fakes, leave them out with exclude_columns", strings.Join(colliding, ", "), def.Name)
+			}
+		}
+
+		var selects, names []string
+		for _, column := range columns {
+			selects = append(selects, fmt.Sprintf("s.%s::text", pgx.Identifier{column.Name}.Sanitize()))
+			names = append(names, pgx.Identifier{column.Name}.Sanitize())
+		}
+
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT %s FROM (%s) AS s", strings.Join(selects, ", "), samples[def.Name]))
+		if err != nil {
+			return s.returnErrorResult("Failed to sample %s: %v", def.Name, err)
+		}
+
+		insert := fmt.Sprintf("INSERT INTO %s (%s)", sanitizeQualifiedName(def.Name), strings.Join(names, ", "))
+		if def.hasIdentity() {
+			insert += " OVERRIDING SYSTEM VALUE"
+		}
+
+		var batch []string
+		rowCount := 0
+		flush := func() {
+			if len(batch) > 0 {
+				fmt.Fprintf(&out, "%s VALUES\n    %s;\n", insert, strings.Join(batch, ",\n    "))
+				batch = batch[:0]
+			}
+		}
+		for rows.Next() {
+			values := make([]*string, len(columns))
+			targets := make([]interface{}, len(columns))
+			for i := range values {
+				targets[i] = &values[i]
+			}
+			if err := rows.Scan(targets...); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+			}
+
+			literals := make([]string, len(columns))
+			for i, value := range values {
+				switch {
+				case value == nil:
+					literals[i] = "NULL"
+				case anonymized[columns[i].Name]:
+					literals[i] = quoteLiteral(anonymizeValue(s.config.AnonymizeKey, columns[i], *value))
+				default:
+					literals[i] = quoteLiteral(*value)
+				}
+			}
+			batch = append(batch, "("+strings.Join(literals, ", ")+")")
+			rowCount++
+			if len(batch) == fixtureInsertBatch {
+				flush()
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("row iteration error: %v", err)
+		}
+		flush()
+		out.WriteString("\n")
+
+		var anonymizedNames []string
+		for _, column := range columns {
+			if anonymized[column.Name] {
+				anonymizedNames = append(anonymizedNames, column.Name)
+			}
+		}
+		summary = append(summary, map[string]interface{}{
+			"table":              def.Name,
+			"rows":               rowCount,
+			"anonymized_columns": anonymizedNames,
+			"omitted_columns":    omittedNames,
+		})
+	}
+
+	var skippedForeignKeys []string
+	for _, def := range defs {
+		for _, constraint := range def.Constraints {
+			switch {
+			case constraint.Type != "f":
+			case !included[constraint.RefTable]:
+				skippedForeignKeys = append(skippedForeignKeys, constraint.Name)
+			case constraint.RefTable == def.Name:
+				// sampled rows may point at parents outside the sample
+				out.WriteString(def.foreignKeyStatement(constraint) + " NOT VALID;\n")
+			default:
+				out.WriteString(def.foreignKeyStatement(constraint) + ";\n")
+			}
+		}
+		for _, index := range def.Indexes {
+			out.WriteString(index + ";\n")
+		}
+	}
+	out.WriteString("\n")
+
+	// move every sequence past the highest exported key so test inserts don't collide
+	for _, def := range defs {
+		for _, column := range def.Columns {
+			if column.Sequence == nil {
+				continue
+			}
+			fmt.Fprintf(&out, "SELECT setval(pg_get_serial_sequence(%s, %s), COALESCE(MAX(%s), 0) + 1, false) FROM %s;\n",
+				quoteLiteral(sanitizeQualifiedName(def.Name)), quoteLiteral(column.Name),
+				pgx.Identifier{column.Name}.Sanitize(), sanitizeQualifiedName(def.Name))
+		}
+	}
+	out.WriteString("\nCOMMIT;\n")
+
+	fixture := out.String()
+	response := map[string]interface{}{
+		"tables":               summary,
+		"skipped_foreign_keys": skippedForeignKeys,
+		"bytes":                len(fixture),
+	}
+
+	if args.OutputPath != "" {
+		path, err := s.exportPath(args.OutputPath)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		encrypted, err := s.writeArtifact(path, []byte(fixture))
+		if err != nil {
+			return s.returnErrorResult("Failed to write fixture: %v", err)
+		}
+		response["output_path"] = args.OutputPath
+		response["encrypted"] = encrypted
+		return returnJSONResult(response)
+	}
+
+	if len(fixture) > maxInlineFixtureBytes {
+		return s.returnErrorResult("Fixture is %d bytes, which is too large to return inline. Lower row_limit or set output_path", len(fixture))
+	}
+	response["sql"] = fixture
+	return returnJSONResult(response)
+}
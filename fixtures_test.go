@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportFixture(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("inline fixture with anonymized data", func(t *testing.T) {
+		args := ExportFixtureArgs{Tables: []string{"posts", "users"}, RowLimit: 10}
+		result, data, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportFixture failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		fixture := response["sql"].(string)
+
+		// users is referenced by posts, so it has to be created first
+		usersAt := strings.Index(fixture, `CREATE TABLE "public"."users"`)
+		postsAt := strings.Index(fixture, `CREATE TABLE "public"."posts"`)
+		if usersAt < 0 || postsAt < 0 || usersAt > postsAt {
+			t.Errorf("Expected users to be created before posts")
+		}
+
+		for _, expected := range []string{`INSERT INTO "public"."users"`, "ADD CONSTRAINT", "setval(", "COMMIT;"} {
+			if !strings.Contains(fixture, expected) {
+				t.Errorf("Expected fixture to contain %q", expected)
+			}
+		}
+
+		var username string
+		if err := testServer.pool.QueryRow(ctx, "SELECT username FROM users ORDER BY id LIMIT 1").Scan(&username); err != nil {
+			t.Fatalf("Failed to read username: %v", err)
+		}
+		if strings.Contains(fixture, username) {
+			t.Error("Expected usernames to be anonymized")
+		}
+
+		for _, table := range response["tables"].([]map[string]interface{}) {
+			if table["rows"].(int) > 10 {
+				t.Errorf("Expected at most 10 rows for %v, got %v", table["table"], table["rows"])
+			}
+		}
+	})
+
+	t.Run("write fixture to file", func(t *testing.T) {
+		savedConfig := testServer.config
+		defer func() { testServer.config = savedConfig }()
+		testServer.config.ExportDir = t.TempDir()
+
+		args := ExportFixtureArgs{Tables: []string{"comments"}, RowLimit: 5, OutputPath: "/tmp/fixture.sql"}
+		if result, _, err := testServer.ExportFixture(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+			t.Error("Expected an absolute output_path to be refused")
+		}
+
+		args.OutputPath = "fixture.sql"
+		_, data, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportFixture failed: %v", err)
+		}
+
+		contents, err := os.ReadFile(filepath.Join(testServer.config.ExportDir, "fixture.sql"))
+		if err != nil {
+			t.Fatalf("Expected fixture file to be written: %v", err)
+		}
+
+		// posts and users were not exported, so their foreign keys are left out
+		skipped := data.(map[string]interface{})["skipped_foreign_keys"].([]string)
+		if len(skipped) != 2 {
+			t.Errorf("Expected 2 skipped foreign keys, got %v", skipped)
+		}
+		if strings.Contains(string(contents), "REFERENCES") {
+			t.Error("Expected no foreign keys to tables outside the fixture")
+		}
+	})
+}
+
+func TestReferencedColumns(t *testing.T) {
+	columns := referencedColumns(`FOREIGN KEY (user_id, "Tenant") REFERENCES accounts(id, "Tenant") ON DELETE CASCADE`)
+	if len(columns) != 2 || columns[0] != "id" || columns[1] != "Tenant" {
+		t.Errorf("Unexpected referenced columns: %v", columns)
+	}
+}
+
+func TestExportFixtureColumns(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("exclude columns", func(t *testing.T) {
+		args := ExportFixtureArgs{Tables: []string{"users", "posts"}, RowLimit: 5, ExcludeColumns: []string{"users.bio", "created_at"}}
+		result, data, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportFixture failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		fixture := response["sql"].(string)
+		if !strings.Contains(fixture, `INSERT INTO "public"."users" ("id", "username", "email", "first_name", "last_name") VALUES`) {
+			t.Error("Expected bio and created_at to be left out of the users rows")
+		}
+		if !strings.Contains(fixture, `INSERT INTO "public"."posts" ("id", "user_id", "title", "content") VALUES`) {
+			t.Error("Expected created_at to be left out of the posts rows")
+		}
+
+		for _, table := range response["tables"].([]map[string]interface{}) {
+			if table["table"] == "public.users" && len(table["omitted_columns"].([]string)) != 2 {
+				t.Errorf("Expected 2 omitted users columns, got %v", table["omitted_columns"])
+			}
+		}
+	})
+
+	t.Run("columns keep required columns", func(t *testing.T) {
+		args := ExportFixtureArgs{Tables: []string{"users"}, RowLimit: 5, Columns: []string{"first_name"}}
+		result, data, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportFixture failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		fixture := data.(map[string]interface{})["sql"].(string)
+		if !strings.Contains(fixture, `INSERT INTO "public"."users" ("id", "username", "email", "first_name") VALUES`) {
+			t.Error("Expected the listed column plus key and NOT NULL columns")
+		}
+	})
+
+	t.Run("invalid selections", func(t *testing.T) {
+		for _, args := range []ExportFixtureArgs{
+			{Tables: []string{"users"}, Columns: []string{"no_such_column"}},
+			{Tables: []string{"users"}, ExcludeColumns: []string{"posts.content"}},
+			{Tables: []string{"posts"}, ExcludeColumns: []string{"content"}},
+		} {
+			result, _, err := testServer.ExportFixture(ctx, createMockRequest(args), args)
+
+			if err != nil {
+				t.Fatalf("ExportFixture failed: %v", err)
+			}
+
+			if result == nil || !result.IsError {
+				t.Errorf("Expected error result for %+v", args)
+			}
+		}
+	})
+}
+
+func TestAnonymizeValueKeepsHash(t *testing.T) {
+	key := []byte("fixture key")
+	long := anonymizeValue(key, columnDef{Name: "username", Type: "text"}, "alice")
+	hash := strings.TrimPrefix(long, "username_")
+	if len(hash) != fakeHashLength {
+		t.Fatalf("Expected username_ and a %d digit hash, got %q", fakeHashLength, long)
+	}
+
+	cases := map[string]string{
+		"character varying(12)": "us" + hash,
+		"character varying(10)": hash,
+		"character(4)":          hash[:4],
+	}
+	for columnType, expected := range cases {
+		if fake := anonymizeValue(key, columnDef{Name: "username", Type: columnType}, "alice"); fake != expected {
+			t.Errorf("Expected %q for %s, got %q", expected, columnType, fake)
+		}
+	}
+	if fake := anonymizeValue(key, columnDef{Name: "email", Type: "character varying(20)"}, "alice"); len(fake) != 15 || !strings.HasPrefix(fake, "user_") {
+		t.Errorf("Expected the email's domain to give way to the hash, got %q", fake)
+	}
+	if fake := anonymizeValue([]byte("other key"), columnDef{Name: "username", Type: "text"}, "alice"); fake == long {
+		t.Error("Expected another key to give another fake")
+	}
+
+	def := &tableDef{
+		Columns:     []columnDef{{Name: "code", Type: "character(4)"}, {Name: "handle", Type: "character varying(12)"}},
+		Constraints: []constraintDef{{Type: "u", Columns: []string{"code"}}, {Type: "u", Columns: []string{"handle"}}},
+	}
+	if colliding := collidingFakes(def, []string{"code", "handle"}); len(colliding) != 1 || colliding[0] != "code" {
+		t.Errorf("Expected only code to be too short for distinct fakes, got %v", colliding)
+	}
+}
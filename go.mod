@@ -3,14 +3,14 @@ module github.com/lczm/postgres-mcp
 go 1.24.1
 
 require (
+	github.com/fergusstrange/embedded-postgres v1.32.0
+	github.com/go-faker/faker/v4 v4.7.0
+	github.com/google/jsonschema-go v0.3.0
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/modelcontextprotocol/go-sdk v1.0.0
 )
 
 require (
-	github.com/fergusstrange/embedded-postgres v1.32.0 // indirect
-	github.com/go-faker/faker/v4 v4.7.0 // indirect
-	github.com/google/jsonschema-go v0.3.0 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var plainIdentifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedIdentifiers are common table and column names that are reserved
+// keywords, so they need quoting even though they are lower case.
+var reservedIdentifiers = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "both": true, "case": true, "cast": true, "check": true, "collate": true, "column": true,
+	"constraint": true, "create": true, "default": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true, "for": true, "foreign": true,
+	"from": true, "grant": true, "group": true, "having": true, "in": true, "into": true, "limit": true,
+	"not": true, "null": true, "offset": true, "on": true, "only": true, "or": true, "order": true,
+	"primary": true, "references": true, "select": true, "table": true, "then": true, "to": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true, "when": true,
+	"where": true, "window": true, "with": true,
+}
+
+// quoteIdentifier quotes a name only when PostgreSQL would not read it back
+// unchanged, like quote_ident.
+func quoteIdentifier(name string) string {
+	if plainIdentifierPattern.MatchString(name) && !reservedIdentifiers[name] {
+		return name
+	}
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// qualifiedName renders schema.table for output, quoted where needed.
+func qualifiedName(schema, name string) string {
+	return quoteIdentifier(schema) + "." + quoteIdentifier(name)
+}
+
+// parseQualifiedName splits user input such as users, public.users or
+// "Sales"."Order Items" into its schema (empty when not given) and name.
+// Quoted parts are taken verbatim and reported through quoted, unquoted
+// parts are kept as written for resolveTableName to match.
+func parseQualifiedName(input string) (schema, name string, quoted bool) {
+	var parts []string
+	var current strings.Builder
+	inQuotes := false
+	runes := []rune(strings.TrimSpace(input))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' && inQuotes && i+1 < len(runes) && runes[i+1] == '"':
+			current.WriteRune('"')
+			i++
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case r == '.' && !inQuotes:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	parts = append(parts, current.String())
+
+	if len(parts) == 1 {
+		return "", parts[0], quoted
+	}
+	// database.schema.table keeps the last two parts
+	return parts[len(parts)-2], parts[len(parts)-1], quoted
+}
+
+// resolveTableName turns a table argument (bare or schema-qualified) and an
+// optional schema argument into the schema and name stored in the catalog.
+// Unquoted names that only match with different case resolve to the single
+// relation they match, as PostgreSQL's case folding would for lower case
+// names. Names that match nothing are returned as given, leaving the
+// not-found error to the tool.
+func (s *serverState) resolveTableName(ctx context.Context, schema, table string) (string, string, error) {
+	parsedSchema, name, quoted := parseQualifiedName(table)
+	if parsedSchema != "" {
+		if schema != "" && schema != parsedSchema {
+			return "", "", fmt.Errorf("%s names schema %s but schema is set to %s", table, parsedSchema, schema)
+		}
+		schema = parsedSchema
+	}
+	schema = getSchema(schema)
+	if quoted || name == "" || s.pool == nil {
+		return schema, name, nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT n.nspname::text, c.relname::text
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE lower(n.nspname) = lower($1)
+			AND lower(c.relname) = lower($2)
+			AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		ORDER BY n.nspname = $1 AND c.relname = $2 DESC, n.nspname, c.relname
+	`, schema, name)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var candidates []string
+	for rows.Next() {
+		var candidateSchema, candidateName string
+		if err := rows.Scan(&candidateSchema, &candidateName); err != nil {
+			return "", "", fmt.Errorf("failed to scan row: %v", err)
+		}
+		if candidateSchema == schema && candidateName == name {
+			return schema, name, nil
+		}
+		candidates = append(candidates, qualifiedName(candidateSchema, candidateName))
+		schema, name = candidateSchema, candidateName
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", fmt.Errorf("row iteration error: %v", err)
+	}
+
+	if len(candidates) > 1 {
+		return "", "", fmt.Errorf("%s matches %s, quote the name to pick one", table, strings.Join(candidates, " and "))
+	}
+	return schema, name, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseQualifiedName(t *testing.T) {
+	cases := []struct {
+		input, schema, name string
+		quoted              bool
+	}{
+		{"users", "", "users", false},
+		{"public.users", "public", "users", false},
+		{`"Sales"."Order Items"`, "Sales", "Order Items", true},
+		{`sales."a.b"`, "sales", "a.b", true},
+		{`"say ""hi"""`, "", `say "hi"`, true},
+		{"db.public.users", "public", "users", false},
+	}
+	for _, c := range cases {
+		schema, name, quoted := parseQualifiedName(c.input)
+		if schema != c.schema || name != c.name || quoted != c.quoted {
+			t.Errorf("parseQualifiedName(%q) = %q, %q, %t, expected %q, %q, %t", c.input, schema, name, quoted, c.schema, c.name, c.quoted)
+		}
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	cases := map[[2]string]string{
+		{"public", "users"}:       "public.users",
+		{"Sales", "Orders"}:       `"Sales"."Orders"`,
+		{"public", "user"}:        `public."user"`,
+		{"public", "order items"}: `public."order items"`,
+	}
+	for parts, expected := range cases {
+		if got := qualifiedName(parts[0], parts[1]); got != expected {
+			t.Errorf("qualifiedName(%q, %q) = %s, expected %s", parts[0], parts[1], got, expected)
+		}
+	}
+}
+
+func TestResolveTableName(t *testing.T) {
+	ctx := context.Background()
+	if _, err := testServer.pool.Exec(ctx, `
+		CREATE SCHEMA "Sales";
+		CREATE TABLE "Sales"."Orders" (id serial PRIMARY KEY, total numeric);
+		CREATE TABLE public."Users" (id serial PRIMARY KEY);
+	`); err != nil {
+		t.Fatalf("Failed to create mixed-case tables: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, `DROP SCHEMA "Sales" CASCADE; DROP TABLE public."Users"`)
+
+	cases := []struct {
+		schema, table          string
+		expectSchema, expected string
+	}{
+		{"", "Sales.Orders", "Sales", "Orders"},
+		{"", "sales.orders", "Sales", "Orders"},
+		{"Sales", "orders", "Sales", "Orders"},
+		{"", `"Sales"."Orders"`, "Sales", "Orders"},
+		{"", "public.posts", "public", "posts"},
+		{"", "missing", "public", "missing"},
+	}
+	for _, c := range cases {
+		schema, table, err := testServer.resolveTableName(ctx, c.schema, c.table)
+		if err != nil {
+			t.Errorf("resolveTableName(%q, %q) failed: %v", c.schema, c.table, err)
+			continue
+		}
+		if schema != c.expectSchema || table != c.expected {
+			t.Errorf("resolveTableName(%q, %q) = %s.%s, expected %s.%s", c.schema, c.table, schema, table, c.expectSchema, c.expected)
+		}
+	}
+
+	// users and Users both exist, the exact spelling picks one
+	if _, table, err := testServer.resolveTableName(ctx, "", "Users"); err != nil || table != "Users" {
+		t.Errorf("Expected Users to resolve to itself, got %q, %v", table, err)
+	}
+	if _, table, err := testServer.resolveTableName(ctx, "", "users"); err != nil || table != "users" {
+		t.Errorf("Expected users to resolve to itself, got %q, %v", table, err)
+	}
+	if _, _, err := testServer.resolveTableName(ctx, "", "USERS"); err == nil {
+		t.Error("Expected USERS to be ambiguous between users and Users")
+	}
+	// a quoted name is never folded
+	if _, table, _ := testServer.resolveTableName(ctx, "", `"ORDERS"`); table != "ORDERS" {
+		t.Errorf("Expected a quoted name to be kept verbatim, got %q", table)
+	}
+	if _, _, err := testServer.resolveTableName(ctx, "other", "public.users"); err == nil {
+		t.Error("Expected conflicting schemas to be rejected")
+	}
+
+	args := TableSchemaArgs{TableName: "sales.orders"}
+	result, data, err := testServer.GetTableSchema(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("GetTableSchema failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected sales.orders to resolve to \"Sales\".\"Orders\"")
+	}
+	if columns := data.([]map[string]interface{}); len(columns) != 2 {
+		t.Errorf("Expected the two columns of \"Sales\".\"Orders\", got %d", len(columns))
+	}
+
+	listArgs := TableListArgs{Schema: "Sales"}
+	_, data, err = testServer.ListTables(ctx, createMockRequest(listArgs), listArgs)
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	tables := data.([]map[string]interface{})
+	if len(tables) != 1 || tables[0]["qualified_name"] != `"Sales"."Orders"` {
+		t.Errorf("Expected a quoted qualified name, got %v", tables)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// inListRewrite is one literal IN list replaced by an array parameter.
+type inListRewrite struct {
+	Values    int
+	Parameter int
+}
+
+// rewriteInLists replaces literal IN lists of at least threshold values with
+// = ANY($n) (NOT IN with <> ALL($n)), returning the statement and the array
+// literals to bind. Thousands of literals make the parser and planner slow,
+// a single array parameter does not. Lists mixing numbers and strings, or
+// holding anything but literals, are left alone, as are statements that
+// already use parameters or hold several statements.
+func rewriteInLists(query string, threshold int) (string, []any, []inListRewrite) {
+	if threshold <= 0 {
+		return query, nil, nil
+	}
+	tokens := sqlTokens(query)
+	for i, token := range tokens {
+		if token.Text == ";" && i < len(tokens)-1 {
+			return query, nil, nil
+		}
+		if strings.HasPrefix(token.Text, "$") && len(token.Text) > 1 && unicode.IsDigit(rune(token.Text[1])) {
+			return query, nil, nil
+		}
+	}
+
+	runes := []rune(query)
+	var out []rune
+	var params []any
+	var rewrites []inListRewrite
+	notStart := -1
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			start := i
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			out = append(out, runes[start:i]...)
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			start := i
+			i = skipBlockComment(runes, i)
+			out = append(out, runes[start:i]...)
+
+		case r == '\'' || r == '"':
+			start := i
+			escapes := r == '\'' && i > 0 && (runes[i-1] == 'E' || runes[i-1] == 'e') &&
+				(i == 1 || !isIdentifierRune(runes[i-2]))
+			i = skipQuoted(runes, i, r, escapes)
+			out = append(out, runes[start:i]...)
+
+		case r == '$':
+			j := i + 1
+			for j < len(runes) && isIdentifierRune(runes[j]) {
+				j++
+			}
+			start := i
+			if j < len(runes) && runes[j] == '$' && (j == i+1 || !unicode.IsDigit(runes[i+1])) {
+				i = skipDollarQuoted(runes, j+1, runes[i:j+1])
+			} else {
+				i = j
+			}
+			out = append(out, runes[start:i]...)
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (isIdentifierRune(runes[i]) || runes[i] == '$') {
+				i++
+			}
+			word := strings.ToUpper(string(runes[start:i]))
+			if word == "IN" {
+				literal, count, end, ok := parseLiteralList(runes, i)
+				if ok && count >= threshold {
+					operator := "= ANY"
+					if notStart >= 0 {
+						out = out[:notStart]
+						operator = "<> ALL"
+					}
+					params = append(params, literal)
+					rewrites = append(rewrites, inListRewrite{Values: count, Parameter: len(params)})
+					out = append(out, []rune(fmt.Sprintf("%s($%d)", operator, len(params)))...)
+					i = end
+					notStart = -1
+					continue
+				}
+			}
+			if word == "NOT" {
+				notStart = len(out)
+			} else {
+				notStart = -1
+			}
+			out = append(out, runes[start:i]...)
+			continue
+
+		default:
+			out = append(out, r)
+			i++
+		}
+		if !unicode.IsSpace(r) {
+			notStart = -1
+		}
+	}
+
+	if len(params) == 0 {
+		return query, nil, nil
+	}
+	return string(out), params, rewrites
+}
+
+// parseLiteralList reads "(1, 2, 3)" or "('a', 'b')" after an IN keyword and
+// returns it as an array literal, with the number of values and the index
+// just past the closing parenthesis.
+func parseLiteralList(runes []rune, i int) (string, int, int, bool) {
+	skipSpace := func() {
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+	}
+
+	skipSpace()
+	if i >= len(runes) || runes[i] != '(' {
+		return "", 0, 0, false
+	}
+	i++
+
+	var elements []string
+	kind := ""
+	for {
+		skipSpace()
+		if i >= len(runes) {
+			return "", 0, 0, false
+		}
+
+		switch r := runes[i]; {
+		case r == '\'':
+			if kind == "number" {
+				return "", 0, 0, false
+			}
+			kind = "string"
+			end := skipQuoted(runes, i, '\'', false)
+			if end > len(runes) || runes[end-1] != '\'' {
+				return "", 0, 0, false
+			}
+			value := strings.ReplaceAll(string(runes[i+1:end-1]), "''", "'")
+			value = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+			elements = append(elements, `"`+value+`"`)
+			i = end
+
+		case unicode.IsDigit(r) || r == '-' || r == '+' || r == '.':
+			if kind == "string" {
+				return "", 0, 0, false
+			}
+			kind = "number"
+			start := i
+			if r == '-' || r == '+' {
+				i++
+			}
+			digits := 0
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E' ||
+				((runes[i] == '+' || runes[i] == '-') && (runes[i-1] == 'e' || runes[i-1] == 'E'))) {
+				if unicode.IsDigit(runes[i]) {
+					digits++
+				}
+				i++
+			}
+			if digits == 0 {
+				return "", 0, 0, false
+			}
+			elements = append(elements, string(runes[start:i]))
+
+		case i+4 <= len(runes) && strings.EqualFold(string(runes[i:i+4]), "NULL") &&
+			(i+4 == len(runes) || !isIdentifierRune(runes[i+4])):
+			elements = append(elements, "NULL")
+			i += 4
+
+		default:
+			return "", 0, 0, false
+		}
+
+		skipSpace()
+		if i >= len(runes) {
+			return "", 0, 0, false
+		}
+		switch runes[i] {
+		case ',':
+			i++
+		case ')':
+			if kind == "" {
+				return "", 0, 0, false
+			}
+			return "{" + strings.Join(elements, ",") + "}", len(elements), i + 1, true
+		default:
+			return "", 0, 0, false
+		}
+	}
+}
+
+// inListWarnings tells the caller which IN lists were rewritten.
+func (s *serverState) inListWarnings(rewrites []inListRewrite) []string {
+	var warnings []string
+	for _, rewrite := range rewrites {
+		warnings = append(warnings, fmt.Sprintf(s.localize("An IN list of %d literal values was sent as the array parameter $%d"), rewrite.Values, rewrite.Parameter))
+	}
+	return warnings
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRewriteInLists(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		params   []any
+	}{
+		{"SELECT * FROM users WHERE id IN (1, 2, 3)", "SELECT * FROM users WHERE id = ANY($1)", []any{"{1,2,3}"}},
+		{"SELECT * FROM users WHERE id NOT IN (1,2,-3)", "SELECT * FROM users WHERE id <> ALL($1)", []any{"{1,2,-3}"}},
+		{
+			`SELECT * FROM users WHERE username in ('a', 'it''s', 'say "hi"', NULL) AND id IN (4, 5, 6)`,
+			"SELECT * FROM users WHERE username = ANY($1) AND id = ANY($2)",
+			[]any{`{"a","it's","say \"hi\"",NULL}`, "{4,5,6}"},
+		},
+		// below the threshold, not literals, mixed or already parameterized
+		{"SELECT * FROM users WHERE id IN (1, 2)", "SELECT * FROM users WHERE id IN (1, 2)", nil},
+		{"SELECT * FROM users WHERE id IN (SELECT user_id FROM posts)", "SELECT * FROM users WHERE id IN (SELECT user_id FROM posts)", nil},
+		{"SELECT * FROM users WHERE id IN (1, 2, id)", "SELECT * FROM users WHERE id IN (1, 2, id)", nil},
+		{"SELECT * FROM users WHERE id IN (1, '2', 3)", "SELECT * FROM users WHERE id IN (1, '2', 3)", nil},
+		{"SELECT * FROM users WHERE id IN (1, 2, 3) AND id > $1", "SELECT * FROM users WHERE id IN (1, 2, 3) AND id > $1", nil},
+		{"SELECT 'x IN (1, 2, 3)' -- IN (1, 2, 3)", "SELECT 'x IN (1, 2, 3)' -- IN (1, 2, 3)", nil},
+	}
+
+	for _, test := range tests {
+		query, params, _ := rewriteInLists(test.query, 3)
+		if query != test.expected || !reflect.DeepEqual(params, test.params) {
+			t.Errorf("rewriteInLists(%q) = %q, %v, expected %q, %v", test.query, query, params, test.expected, test.params)
+		}
+	}
+
+	if query, _, _ := rewriteInLists(tests[0].query, 0); query != tests[0].query {
+		t.Error("Expected a zero threshold to disable the rewrite")
+	}
+}
+
+func TestQueryRewritesInLists(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.InListThreshold = 3
+
+	ids := make([]string, 2000)
+	for i := range ids {
+		ids[i] = "-1"
+	}
+	ids[0], ids[1] = "1", "2"
+
+	args := QueryArgs{Query: "SELECT id FROM users WHERE id IN (" + strings.Join(ids, ", ") + ") ORDER BY id"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Query failed: %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if rows := data.([]map[string]interface{}); len(rows) != 2 {
+		t.Errorf("Expected users 1 and 2, got %v", rows)
+	}
+	if len(result.Content) < 2 || !strings.Contains(result.Content[1].(*mcp.TextContent).Text, "2000 literal values") {
+		t.Error("Expected a warning naming the rewritten IN list")
+	}
+
+	args = QueryArgs{Query: "SELECT count(*) AS n FROM users WHERE username NOT IN ('nobody', 'no one', 'nothing')"}
+	result, _, err = testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if result.IsError {
+		t.Errorf("Expected a NOT IN list of strings to run, got %v", result.Content[0].(*mcp.TextContent).Text)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// intervalFormats are the ways interval values can be returned: ISO-8601
+// durations, or their length in seconds.
+var intervalFormats = map[string]bool{"iso8601": true, "seconds": true}
+
+// formatInterval writes an interval as an ISO-8601 duration, the way
+// PostgreSQL does with intervalstyle iso_8601: months, days and time are
+// kept apart, as they don't convert into each other, and each part carries
+// its own sign, as in P1M-2D.
+func formatInterval(interval pgtype.Interval) string {
+	var out strings.Builder
+	out.WriteString("P")
+	if years := interval.Months / 12; years != 0 {
+		fmt.Fprintf(&out, "%dY", years)
+	}
+	if months := interval.Months % 12; months != 0 {
+		fmt.Fprintf(&out, "%dM", months)
+	}
+	if interval.Days != 0 {
+		fmt.Fprintf(&out, "%dD", interval.Days)
+	}
+
+	micros := interval.Microseconds
+	if micros != 0 {
+		out.WriteString("T")
+		if hours := micros / 3_600_000_000; hours != 0 {
+			fmt.Fprintf(&out, "%dH", hours)
+			micros -= hours * 3_600_000_000
+		}
+		if minutes := micros / 60_000_000; minutes != 0 {
+			fmt.Fprintf(&out, "%dM", minutes)
+			micros -= minutes * 60_000_000
+		}
+		if micros != 0 {
+			if micros < 0 {
+				out.WriteString("-")
+				micros = -micros
+			}
+			fmt.Fprintf(&out, "%d", micros/1_000_000)
+			if fraction := micros % 1_000_000; fraction != 0 {
+				out.WriteString(strings.TrimRight(fmt.Sprintf(".%06d", fraction), "0"))
+			}
+			out.WriteString("S")
+		}
+	}
+	if out.Len() == 1 {
+		return "PT0S"
+	}
+	return out.String()
+}
+
+// intervalSeconds is an interval's length in seconds, counting a month as
+// 30 days and a year as 365.25, as EXTRACT(epoch FROM interval) does.
+func intervalSeconds(interval pgtype.Interval) float64 {
+	days := float64(interval.Months/12)*365.25 + float64(interval.Months%12)*30 + float64(interval.Days)
+	return days*86400 + float64(interval.Microseconds)/1e6
+}
+
+// encodeIntervalRows replaces the interval values of result rows in place,
+// including those in arrays.
+func encodeIntervalRows(rows []map[string]interface{}, format string) {
+	for _, row := range rows {
+		for column, value := range row {
+			row[column] = encodeInterval(value, format)
+		}
+	}
+}
+
+func encodeInterval(value interface{}, format string) interface{} {
+	switch v := value.(type) {
+	case pgtype.Interval:
+		if !v.Valid {
+			return nil
+		}
+		if format == "seconds" {
+			return intervalSeconds(v)
+		}
+		return formatInterval(v)
+	case []interface{}:
+		for i, element := range v {
+			v[i] = encodeInterval(element, format)
+		}
+	}
+	return value
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFormatInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval pgtype.Interval
+		expected string
+		seconds  float64
+	}{
+		{pgtype.Interval{Valid: true}, "PT0S", 0},
+		{pgtype.Interval{Months: 14, Days: 3, Microseconds: 4*3_600_000_000 + 5*60_000_000 + 6_500_000, Valid: true}, "P1Y2M3DT4H5M6.5S", 31557600 + 2*2592000 + 3*86400 + 14706.5},
+		{pgtype.Interval{Months: 1, Days: -2, Valid: true}, "P1M-2D", 2592000 - 2*86400},
+		{pgtype.Interval{Microseconds: -1_500_000, Valid: true}, "PT-1.5S", -1.5},
+		{pgtype.Interval{Microseconds: 1, Valid: true}, "PT0.000001S", 0.000001},
+		{pgtype.Interval{Months: -12, Microseconds: -61_000_000, Valid: true}, "P-1YT-1M-1S", -31557600 - 61},
+	} {
+		if formatted := formatInterval(tc.interval); formatted != tc.expected {
+			t.Errorf("Expected %s, got %s", tc.expected, formatted)
+		}
+		if seconds := intervalSeconds(tc.interval); seconds != tc.seconds {
+			t.Errorf("Expected %v seconds for %s, got %v", tc.seconds, tc.expected, seconds)
+		}
+	}
+}
+
+func TestQueryIntervalFormat(t *testing.T) {
+	ctx := context.Background()
+	args := QueryArgs{Query: "SELECT interval '1 year 2 months 3 days 04:05:06.5' AS span, ARRAY[interval '1 day'] AS spans"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	row := data.([]map[string]interface{})[0]
+	if row["span"] != "P1Y2M3DT4H5M6.5S" || row["spans"].([]interface{})[0] != "P1D" {
+		t.Errorf("Expected ISO-8601 durations, got %v", row)
+	}
+
+	args.IntervalFormat = "seconds"
+	if _, data, _ = testServer.ExecuteQuery(ctx, createMockRequest(args), args); data.([]map[string]interface{})[0]["span"] != 37015506.5 {
+		t.Errorf("Expected the total seconds, got %v", data)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// jsonFormats are the ways json and jsonb values can be returned: decoded
+// into the result, or as their JSON text.
+var jsonFormats = map[string]bool{"nested": true, "string": true}
+
+// jsonPreview replaces a json value larger than MAX_JSON_BYTES: its size
+// and the start of its text.
+type jsonPreview struct {
+	Length    int    `json:"length"`
+	Preview   string `json:"preview"`
+	Truncated bool   `json:"truncated"`
+}
+
+// jsonColumns are the result columns of type json or jsonb.
+func jsonColumns(fields []pgconn.FieldDescription) []string {
+	var columns []string
+	for _, field := range fields {
+		if field.DataTypeOID == pgtype.JSONOID || field.DataTypeOID == pgtype.JSONBOID {
+			columns = append(columns, field.Name)
+		}
+	}
+	return columns
+}
+
+// parseJSONPath reads a path such as $.items[0].id or $["key with spaces"]
+// into its steps: strings for object keys, ints for array elements.
+func parseJSONPath(path string) ([]interface{}, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON path %q, paths start with $", path)
+	}
+	var steps []interface{}
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("invalid JSON path %q, empty key", path)
+			}
+			steps = append(steps, key)
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q, unclosed [", path)
+			}
+			inner := rest[1:end]
+			if key, err := strconv.Unquote(inner); err == nil {
+				steps = append(steps, key)
+			} else if index, err := strconv.Atoi(inner); err == nil && index >= 0 {
+				steps = append(steps, index)
+			} else {
+				return nil, fmt.Errorf("invalid JSON path %q, expected an array index or a quoted key in []", path)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q, expected . or [ after %q", path, strings.TrimSuffix(path, rest))
+		}
+	}
+	return steps, nil
+}
+
+// jsonPathValue follows steps into a decoded JSON value, nil when the path
+// leads nowhere.
+func jsonPathValue(value interface{}, steps []interface{}) interface{} {
+	for _, step := range steps {
+		switch key := step.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[key]
+		case int:
+			array, ok := value.([]interface{})
+			if !ok || key >= len(array) {
+				return nil
+			}
+			value = array[key]
+		}
+	}
+	return value
+}
+
+// shapeJSONRows applies json_paths, MAX_JSON_BYTES and json_format to the
+// json columns of result rows in place, and returns the columns with
+// truncated values.
+func shapeJSONRows(rows []map[string]interface{}, columns []string, paths []string, format string, maxBytes int) ([]string, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	steps := make([][]interface{}, len(paths))
+	for i, path := range paths {
+		var err error
+		if steps[i], err = parseJSONPath(path); err != nil {
+			return nil, err
+		}
+	}
+
+	truncated := make(map[string]bool)
+	for _, row := range rows {
+		for _, column := range columns {
+			value, ok := row[column]
+			if !ok || value == nil {
+				continue
+			}
+			if len(paths) > 0 {
+				projected := make(map[string]interface{}, len(paths))
+				for i, path := range paths {
+					projected[path] = jsonPathValue(value, steps[i])
+				}
+				value = projected
+			}
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal %s: %v", column, err)
+			}
+			switch {
+			case maxBytes > 0 && len(encoded) > maxBytes:
+				preview := encoded[:maxBytes]
+				for len(preview) > 0 && !utf8.Valid(preview) {
+					preview = preview[:len(preview)-1]
+				}
+				row[column] = jsonPreview{Length: len(encoded), Preview: string(preview), Truncated: true}
+				truncated[column] = true
+			case format == "string":
+				row[column] = string(encoded)
+			default:
+				row[column] = value
+			}
+		}
+	}
+	return sortedKeys(truncated), nil
+}
+
+// jsonWarnings reports the json columns a result shortened, which chunked
+// results list once per chunk.
+func (s *serverState) jsonWarnings(truncated []string) []string {
+	slices.Sort(truncated)
+	truncated = slices.Compact(truncated)
+	if len(truncated) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf(s.localize("JSON values larger than MAX_JSON_BYTES (%d) were truncated in: %s"), s.config.MaxJSONBytes, strings.Join(truncated, ", "))}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	steps, err := parseJSONPath(`$.items[1]["first name"].id`)
+	if err != nil || len(steps) != 4 || steps[0] != "items" || steps[1] != 1 || steps[2] != "first name" || steps[3] != "id" {
+		t.Errorf("Unexpected steps %v, %v", steps, err)
+	}
+	for _, path := range []string{"items", "$.", "$[x]", "$[0", "$x"} {
+		if _, err := parseJSONPath(path); err == nil {
+			t.Errorf("Expected %q to be rejected", path)
+		}
+	}
+}
+
+func TestShapeJSONRows(t *testing.T) {
+	document := func() map[string]interface{} {
+		return map[string]interface{}{"id": 7.0, "status": "open", "items": []interface{}{map[string]interface{}{"sku": "a"}}}
+	}
+
+	rows := []map[string]interface{}{{"doc": document()}}
+	if _, err := shapeJSONRows(rows, []string{"doc"}, []string{"$.id", "$.items[0].sku", "$.missing"}, "nested", 0); err != nil {
+		t.Fatal(err)
+	}
+	projected := rows[0]["doc"].(map[string]interface{})
+	if projected["$.id"] != 7.0 || projected["$.items[0].sku"] != "a" || projected["$.missing"] != nil {
+		t.Errorf("Unexpected projection %v", projected)
+	}
+
+	rows = []map[string]interface{}{{"doc": document()}}
+	shapeJSONRows(rows, []string{"doc"}, nil, "string", 0)
+	if rows[0]["doc"] != `{"id":7,"items":[{"sku":"a"}],"status":"open"}` {
+		t.Errorf("Expected the JSON text, got %v", rows[0]["doc"])
+	}
+
+	rows = []map[string]interface{}{{"doc": document()}}
+	truncated, _ := shapeJSONRows(rows, []string{"doc"}, nil, "nested", 10)
+	if preview, ok := rows[0]["doc"].(jsonPreview); !ok || preview.Preview != `{"id":7,"i` || preview.Length != 46 || len(truncated) != 1 {
+		t.Errorf("Expected a 10 byte preview, got %v %v", rows[0]["doc"], truncated)
+	}
+}
+
+func TestQueryJSONPaths(t *testing.T) {
+	ctx := context.Background()
+	args := QueryArgs{Query: `SELECT '{"id": 1, "status": "open", "notes": "long"}'::jsonb AS doc`, JSONPaths: []string{"$.status"}}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	doc := data.([]map[string]interface{})[0]["doc"].(map[string]interface{})
+	if len(doc) != 1 || doc["$.status"] != "open" {
+		t.Errorf("Expected only the status path, got %v", doc)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const dryRunInstructions = "This server runs in dry-run mode. Every write is rolled back and its response is labeled as simulated."
+
+var supportedLocales = map[string]bool{"en": true, "es": true, "de": true, "ja": true}
+
+// parseLocale reduces values such as de_DE.UTF-8 to a supported language,
+// falling back to English.
+func parseLocale(value string) string {
+	if value == "" {
+		return "en"
+	}
+	language := strings.ToLower(strings.FieldsFunc(value, func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})[0])
+	if !supportedLocales[language] {
+		log.Printf("Unsupported LOCALE=%q, using en", value)
+		return "en"
+	}
+	return language
+}
+
+// localize returns the translation of an English message or format string
+// for the configured locale. Messages without a translation stay in English.
+func (s *serverState) localize(message string) string {
+	if translated, ok := messageTranslations[s.config.Locale][message]; ok {
+		return translated
+	}
+	return message
+}
+
+// localeMiddleware serves tool descriptions in the configured locale. Tools
+// are copied, the registered definitions stay in English.
+func (s *serverState) localeMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		result, err := next(ctx, method, req)
+		descriptions := toolDescriptions[s.config.Locale]
+		list, ok := result.(*mcp.ListToolsResult)
+		if method != "tools/list" || !ok || len(descriptions) == 0 {
+			return result, err
+		}
+
+		localized := *list
+		localized.Tools = make([]*mcp.Tool, len(list.Tools))
+		for i, tool := range list.Tools {
+			localized.Tools[i] = tool
+			if description, ok := descriptions[tool.Name]; ok {
+				copied := *tool
+				copied.Description = description
+				localized.Tools[i] = &copied
+			}
+		}
+		return &localized, err
+	}
+}
+
+var messageTranslations = map[string]map[string]string{
+	"es": {
+		"%s modifies the database and is disabled. Set ALLOW_WRITES=true to enable it": "%s modifica la base de datos y está deshabilitada. Establezca ALLOW_WRITES=true para habilitarla",
+		dryRunNotice:       "DRY_RUN está activado: esto se ejecutó dentro de una transacción que se revirtió, no se guardó nada",
+		dryRunInstructions: "Este servidor se ejecuta en modo de simulación. Cada escritura se revierte y su respuesta se marca como simulada.",
+		"Warnings:":        "Advertencias:",
+		"REDACT_PII is enabled, values looking like PII were masked in: %s": "REDACT_PII está activado, se enmascararon valores que parecen datos personales en: %s",
+		"The statement modifies data, so ANALYZE was skipped and only the estimated plan is shown. Even though it would be rolled back, running it fires triggers and takes locks. Set allow_write_analyze to true to run it anyway": "La sentencia modifica datos, por lo que se omitió ANALYZE y solo se muestra el plan estimado. Aunque se revertiría, ejecutarla dispara triggers y toma bloqueos. Establezca allow_write_analyze en true para ejecutarla de todos modos",
+		"at least one table is required":                         "se requiere al menos una tabla",
+		"at least two tables are required":                       "se requieren al menos dos tablas",
+		"Change %s not found":                                    "No se encontró el cambio %s",
+		"Change %s is %s and can no longer be approved":          "El cambio %s está en estado %s y ya no se puede aprobar",
+		"Invalid approval token for change %s":                   "Token de aprobación no válido para el cambio %s",
+		"No pending change %s":                                   "No hay ningún cambio pendiente %s",
+		"Approved change %s failed: %v":                          "El cambio aprobado %s falló: %v",
+		"The query returned no rows":                             "La consulta no devolvió filas",
+		"Parameter %q cannot be set, allowed parameters are: %s": "No se puede establecer el parámetro %q, los parámetros permitidos son: %s",
+		"name is required unless reset is set":                   "name es obligatorio salvo que se use reset",
+		"Invalid value for %s: %v":                               "Valor no válido para %s: %v",
+		"Expanded output shows a single row but the query returned more, add a WHERE clause or LIMIT 1":      "La salida expandida muestra una sola fila pero la consulta devolvió más, añada una cláusula WHERE o LIMIT 1",
+		"Fixture is %d bytes, which is too large to return inline. Lower row_limit or set output_path":       "El fixture ocupa %d bytes, demasiado para devolverlo directamente. Reduzca row_limit o use output_path",
+		"%s is not accessible under the configured allow/deny lists":                                         "%s no es accesible según las listas de permitidos y denegados configuradas",
+		"The statement references %s, which the configured allow/deny lists do not permit":                   "La sentencia hace referencia a %s, que las listas de permitidos y denegados configuradas no permiten",
+		"%s statements are blocked by QUERY_POLICY, the query tool only runs them when the policy allows it": "Las sentencias %s están bloqueadas por QUERY_POLICY, la herramienta query solo las ejecuta si la política lo permite",
+		"An IN list of %d literal values was sent as the array parameter $%d":                                "Una lista IN de %d valores literales se envió como el parámetro de array $%d",
+		"The connection to the database was lost (%v) and could not be re-established: %v":                   "Se perdió la conexión con la base de datos (%v) y no se pudo restablecer: %v",
+		"The connection to the database was lost (%v) and re-established on a new connection":                "Se perdió la conexión con la base de datos (%v) y se restableció en una conexión nueva",
+		"Restored session state: %s": "Estado de sesión restaurado: %s",
+		"Temporary tables, prepared statements, advisory locks and settings changed with SET on the lost connection were not restored":      "No se restauraron las tablas temporales, sentencias preparadas, bloqueos consultivos ni los ajustes cambiados con SET en la conexión perdida",
+		"The connection to the database was lost while the statement ran (%v). Run it again to use a new connection":                        "Se perdió la conexión con la base de datos mientras se ejecutaba la sentencia (%v). Vuelva a ejecutarla para usar una conexión nueva",
+		"The transaction still failed to serialize after %d retries: %v":                                                                    "La transacción siguió sin poder serializarse después de %d reintentos: %v",
+		"Retried %d times after serialization failures":                                                                                     "Se reintentó %d veces tras fallos de serialización",
+		"The database is unavailable and the server is reconnecting (%d attempts so far, last error: %v). Try again shortly":                "La base de datos no está disponible y el servidor se está reconectando (%d intentos hasta ahora, último error: %v). Vuelva a intentarlo en breve",
+		"No sample was taken near %s, the closest is %s away":                                                                               "No se tomó ninguna muestra cerca de %s, la más cercana está a %s",
+		"COLUMN_POLICY_FILE masked or formatted: %s":                                                                                        "COLUMN_POLICY_FILE enmascaró o formateó: %s",
+		"Inactive replication slots are retaining WAL and growing the WAL directory: %s":                                                    "Hay slots de replicación inactivos que retienen WAL y hacen crecer el directorio de WAL: %s",
+		"wal_level is %s, publications only replicate with wal_level = logical":                                                             "wal_level es %s, las publicaciones solo replican con wal_level = logical",
+		"%d of %d saved queries have plan regressions":                                                                                      "%d de %d consultas guardadas tienen regresiones de plan",
+		"pg_stat_statements is not installed, suggestions only use table statistics and foreign keys":                                       "pg_stat_statements no está instalado, las sugerencias solo usan las estadísticas de tablas y las claves foráneas",
+		"Some tables are mostly read by sequential scans, include_statements finds the columns their queries filter on":                     "Algunas tablas se leen sobre todo con recorridos secuenciales, include_statements encuentra las columnas por las que filtran sus consultas",
+		"A variant modifies data, so both plans are estimated without ANALYZE":                                                              "Una variante modifica datos, así que ambos planes se estiman sin ANALYZE",
+		"%s needs PostgreSQL %d or later and was left out, the server runs version %d":                                                      "%s requiere PostgreSQL %d o posterior y se omitió, el servidor ejecuta la versión %d",
+		"generic_plan cannot be combined with ANALYZE, only the estimated plan is shown":                                                    "generic_plan no se puede combinar con ANALYZE, solo se muestra el plan estimado",
+		"wal is only reported with ANALYZE and was left out":                                                                                "wal solo se informa con ANALYZE y se omitió",
+		"%d of %d checked queries have plan regressions":                                                                                    "%d de %d consultas comprobadas tienen regresiones de plan",
+		"ACTIVITY_SAMPLE_INTERVAL (%s) is longer than SLOW_QUERY_THRESHOLD (%s), queries shorter than the interval are mostly missed":       "ACTIVITY_SAMPLE_INTERVAL (%s) es mayor que SLOW_QUERY_THRESHOLD (%s), la mayoría de las consultas más cortas que el intervalo no se registran",
+		"pg_stat_statements is not installed, no statements were matched":                                                                   "pg_stat_statements no está instalado, no se buscaron sentencias coincidentes",
+		"The result was cut off after %d rows, at the MAX_RESULT_BYTES limit of %d bytes. Add a LIMIT or narrow the query":                  "El resultado se cortó tras %d filas, en el límite MAX_RESULT_BYTES de %d bytes. Añada un LIMIT o acote la consulta",
+		"Binary values longer than MAX_BINARY_BYTES (%d) were truncated in: %s":                                                             "Los valores binarios de más de MAX_BINARY_BYTES (%d) se truncaron en: %s",
+		"Binary columns were left out of the result: %s":                                                                                    "Las columnas binarias se omitieron del resultado: %s",
+		"JSON values larger than MAX_JSON_BYTES (%d) were truncated in: %s":                                                                 "Los valores JSON de más de MAX_JSON_BYTES (%d) se truncaron en: %s",
+		"Not run, query %d failed first":                                                                                                    "No se ejecutó, la consulta %d falló antes",
+		"dry_run: this ran inside a transaction that was rolled back, nothing was persisted":                                                "dry_run: se ejecutó dentro de una transacción que se revirtió, no se guardó nada",
+		"Nothing was changed. Call again with commit set to true to apply the statement":                                                    "No se modificó nada. Vuelva a llamar con commit en true para aplicar la sentencia",
+		"Nothing was deleted. Call again with expected_count set to %d to delete these rows":                                                "No se eliminó nada. Vuelva a llamar con expected_count en %d para eliminar estas filas",
+		"pg_dump is not installed, the dump was rebuilt from the catalogs":                                                                  "pg_dump no está instalado, el volcado se reconstruyó desde los catálogos",
+		"pg_dump is not used while allow/deny lists are configured, the dump was rebuilt from the catalogs":                                 "pg_dump no se usa mientras haya listas de permitidos/denegados configuradas, el volcado se reconstruyó desde los catálogos",
+		"Skipped relations hidden by the allow/deny lists: %s":                                                                              "Se omitieron relaciones ocultas por las listas de permitidos/denegados: %s",
+		"Only the first %d of %d tables were searched, list tables to search the others":                                                    "Solo se buscó en las primeras %d de %d tablas, indica las tablas para buscar en las demás",
+		"%s has no planner statistics for this column, run ANALYZE on it to collect them":                                                   "%s no tiene estadísticas del planificador para esta columna, ejecute ANALYZE sobre ella para recopilarlas",
+		"Only %.0f%% of the updates to %s were HOT, a fillfactor below 100 leaves room on each page for the new row versions":               "Solo el %.0f%% de las actualizaciones de %s fueron HOT, un fillfactor inferior a 100 deja espacio en cada página para las nuevas versiones de las filas",
+		"Set SERVER_LOG to the server's log file or log directory to include the errors logged":                                             "Configure SERVER_LOG con el archivo o directorio de log del servidor para incluir los errores registrados",
+		"Statements that change the role are refused, pass role to the tool instead":                                                        "Las sentencias que cambian el rol se rechazan, pase role a la herramienta en su lugar",
+		"COPY to or from a server file or program is refused, use STDIN or STDOUT":                                                          "COPY hacia o desde un archivo o programa del servidor se rechaza, use STDIN o STDOUT",
+		"The statement calls %s, which read relations the configured allow/deny lists cannot check":                                         "La sentencia llama a %s, que leen relaciones que las listas de permitidos/denegados configuradas no pueden comprobar",
+		"No export directory, set EXPORT_DIR to the directory tools may write files to":                                                     "No hay directorio de exportación, establezca EXPORT_DIR en el directorio en el que las herramientas pueden escribir archivos",
+		"%s must be a file name relative to EXPORT_DIR, without ..":                                                                         "%s debe ser un nombre de archivo relativo a EXPORT_DIR, sin ..",
+		"%s is a symlink, which tools don't follow out of EXPORT_DIR":                                                                       "%s es un enlace simbólico, que las herramientas no siguen fuera de EXPORT_DIR",
+		"dry_run still runs the statement, which needs approval here like the write itself. Call again without dry_run to queue it":         "dry_run sigue ejecutando la sentencia, que aquí necesita aprobación igual que la escritura. Vuelva a llamar sin dry_run para ponerla en cola",
+		"%d log errors don't name their database and were left out, add %%d to log_line_prefix or log to csvlog or jsonlog to include them": "%d errores del registro no indican su base de datos y se omitieron, añada %%d a log_line_prefix o use csvlog o jsonlog para incluirlos",
+		"Failed to count rows: %v": "No se pudieron contar las filas: %v",
+	},
+	"de": {
+		"%s modifies the database and is disabled. Set ALLOW_WRITES=true to enable it": "%s verändert die Datenbank und ist deaktiviert. Setzen Sie ALLOW_WRITES=true, um es zu aktivieren",
+		dryRunNotice:       "DRY_RUN ist aktiv: Dies lief in einer Transaktion, die zurückgerollt wurde, es wurde nichts gespeichert",
+		dryRunInstructions: "Dieser Server läuft im Probelaufmodus. Jeder Schreibvorgang wird zurückgerollt und seine Antwort als simuliert gekennzeichnet.",
+		"Warnings:":        "Warnungen:",
+		"REDACT_PII is enabled, values looking like PII were masked in: %s": "REDACT_PII ist aktiv, Werte, die wie personenbezogene Daten aussehen, wurden maskiert in: %s",
+		"The statement modifies data, so ANALYZE was skipped and only the estimated plan is shown. Even though it would be rolled back, running it fires triggers and takes locks. Set allow_write_analyze to true to run it anyway": "Die Anweisung verändert Daten, daher wurde ANALYZE übersprungen und nur der geschätzte Plan angezeigt. Auch wenn sie zurückgerollt würde, löst ihre Ausführung Trigger aus und setzt Sperren. Setzen Sie allow_write_analyze auf true, um sie trotzdem auszuführen",
+		"at least one table is required":                         "mindestens eine Tabelle ist erforderlich",
+		"at least two tables are required":                       "mindestens zwei Tabellen sind erforderlich",
+		"Change %s not found":                                    "Änderung %s nicht gefunden",
+		"Change %s is %s and can no longer be approved":          "Änderung %s hat den Status %s und kann nicht mehr genehmigt werden",
+		"Invalid approval token for change %s":                   "Ungültiges Genehmigungstoken für Änderung %s",
+		"No pending change %s":                                   "Keine ausstehende Änderung %s",
+		"Approved change %s failed: %v":                          "Genehmigte Änderung %s ist fehlgeschlagen: %v",
+		"The query returned no rows":                             "Die Abfrage hat keine Zeilen geliefert",
+		"Parameter %q cannot be set, allowed parameters are: %s": "Parameter %q kann nicht gesetzt werden, erlaubte Parameter sind: %s",
+		"name is required unless reset is set":                   "name ist erforderlich, sofern reset nicht gesetzt ist",
+		"Invalid value for %s: %v":                               "Ungültiger Wert für %s: %v",
+		"Expanded output shows a single row but the query returned more, add a WHERE clause or LIMIT 1":      "Die erweiterte Ausgabe zeigt eine einzelne Zeile, die Abfrage lieferte aber mehr. Ergänzen Sie eine WHERE-Klausel oder LIMIT 1",
+		"Fixture is %d bytes, which is too large to return inline. Lower row_limit or set output_path":       "Die Fixture ist %d Bytes groß und zu groß für die direkte Rückgabe. Verringern Sie row_limit oder setzen Sie output_path",
+		"%s is not accessible under the configured allow/deny lists":                                         "%s ist laut den konfigurierten Zulassungs- und Sperrlisten nicht zugänglich",
+		"The statement references %s, which the configured allow/deny lists do not permit":                   "Die Anweisung verweist auf %s, was die konfigurierten Zulassungs- und Sperrlisten nicht erlauben",
+		"%s statements are blocked by QUERY_POLICY, the query tool only runs them when the policy allows it": "%s-Anweisungen sind durch QUERY_POLICY gesperrt, das query-Werkzeug führt sie nur aus, wenn die Richtlinie es erlaubt",
+		"An IN list of %d literal values was sent as the array parameter $%d":                                "Eine IN-Liste mit %d Literalwerten wurde als Array-Parameter $%d gesendet",
+		"The connection to the database was lost (%v) and could not be re-established: %v":                   "Die Verbindung zur Datenbank ging verloren (%v) und konnte nicht wiederhergestellt werden: %v",
+		"The connection to the database was lost (%v) and re-established on a new connection":                "Die Verbindung zur Datenbank ging verloren (%v) und wurde über eine neue Verbindung wiederhergestellt",
+		"Restored session state: %s": "Wiederhergestellter Sitzungszustand: %s",
+		"Temporary tables, prepared statements, advisory locks and settings changed with SET on the lost connection were not restored":      "Temporäre Tabellen, vorbereitete Anweisungen, Advisory Locks und mit SET geänderte Einstellungen der verlorenen Verbindung wurden nicht wiederhergestellt",
+		"The connection to the database was lost while the statement ran (%v). Run it again to use a new connection":                        "Die Verbindung zur Datenbank ging während der Ausführung verloren (%v). Führen Sie die Anweisung erneut aus, um eine neue Verbindung zu verwenden",
+		"The transaction still failed to serialize after %d retries: %v":                                                                    "Die Transaktion konnte auch nach %d Wiederholungen nicht serialisiert werden: %v",
+		"Retried %d times after serialization failures":                                                                                     "Nach Serialisierungsfehlern %d Mal wiederholt",
+		"The database is unavailable and the server is reconnecting (%d attempts so far, last error: %v). Try again shortly":                "Die Datenbank ist nicht verfügbar und der Server verbindet sich neu (bisher %d Versuche, letzter Fehler: %v). Versuchen Sie es in Kürze erneut",
+		"No sample was taken near %s, the closest is %s away":                                                                               "In der Nähe von %s wurde keine Stichprobe genommen, die nächste liegt %s entfernt",
+		"COLUMN_POLICY_FILE masked or formatted: %s":                                                                                        "COLUMN_POLICY_FILE hat maskiert oder formatiert: %s",
+		"Inactive replication slots are retaining WAL and growing the WAL directory: %s":                                                    "Inaktive Replikationsslots halten WAL zurück und lassen das WAL-Verzeichnis wachsen: %s",
+		"wal_level is %s, publications only replicate with wal_level = logical":                                                             "wal_level ist %s, Publikationen replizieren nur mit wal_level = logical",
+		"%d of %d saved queries have plan regressions":                                                                                      "%d von %d gespeicherten Abfragen haben Planregressionen",
+		"pg_stat_statements is not installed, suggestions only use table statistics and foreign keys":                                       "pg_stat_statements ist nicht installiert, die Vorschläge beruhen nur auf Tabellenstatistiken und Fremdschlüsseln",
+		"Some tables are mostly read by sequential scans, include_statements finds the columns their queries filter on":                     "Einige Tabellen werden überwiegend sequenziell gelesen, include_statements findet die Spalten, nach denen ihre Abfragen filtern",
+		"A variant modifies data, so both plans are estimated without ANALYZE":                                                              "Eine Variante verändert Daten, daher werden beide Pläne ohne ANALYZE geschätzt",
+		"%s needs PostgreSQL %d or later and was left out, the server runs version %d":                                                      "%s benötigt PostgreSQL %d oder neuer und wurde ausgelassen, der Server läuft mit Version %d",
+		"generic_plan cannot be combined with ANALYZE, only the estimated plan is shown":                                                    "generic_plan lässt sich nicht mit ANALYZE kombinieren, es wird nur der geschätzte Plan angezeigt",
+		"wal is only reported with ANALYZE and was left out":                                                                                "wal wird nur mit ANALYZE ausgegeben und wurde ausgelassen",
+		"%d of %d checked queries have plan regressions":                                                                                    "%d von %d geprüften Abfragen haben Planregressionen",
+		"ACTIVITY_SAMPLE_INTERVAL (%s) is longer than SLOW_QUERY_THRESHOLD (%s), queries shorter than the interval are mostly missed":       "ACTIVITY_SAMPLE_INTERVAL (%s) ist länger als SLOW_QUERY_THRESHOLD (%s), Abfragen, die kürzer als das Intervall laufen, werden meist verpasst",
+		"pg_stat_statements is not installed, no statements were matched":                                                                   "pg_stat_statements ist nicht installiert, es wurden keine Anweisungen abgeglichen",
+		"The result was cut off after %d rows, at the MAX_RESULT_BYTES limit of %d bytes. Add a LIMIT or narrow the query":                  "Das Ergebnis wurde nach %d Zeilen an der MAX_RESULT_BYTES-Grenze von %d Bytes abgeschnitten. Fügen Sie ein LIMIT hinzu oder schränken Sie die Abfrage ein",
+		"Binary values longer than MAX_BINARY_BYTES (%d) were truncated in: %s":                                                             "Binärwerte länger als MAX_BINARY_BYTES (%d) wurden gekürzt in: %s",
+		"Binary columns were left out of the result: %s":                                                                                    "Binärspalten wurden aus dem Ergebnis ausgelassen: %s",
+		"JSON values larger than MAX_JSON_BYTES (%d) were truncated in: %s":                                                                 "JSON-Werte größer als MAX_JSON_BYTES (%d) wurden gekürzt in: %s",
+		"Not run, query %d failed first":                                                                                                    "Nicht ausgeführt, Abfrage %d ist vorher fehlgeschlagen",
+		"dry_run: this ran inside a transaction that was rolled back, nothing was persisted":                                                "dry_run: Dies lief in einer Transaktion, die zurückgerollt wurde, nichts wurde gespeichert",
+		"Nothing was changed. Call again with commit set to true to apply the statement":                                                    "Es wurde nichts geändert. Erneut mit commit auf true aufrufen, um die Anweisung anzuwenden",
+		"Nothing was deleted. Call again with expected_count set to %d to delete these rows":                                                "Es wurde nichts gelöscht. Erneut mit expected_count auf %d aufrufen, um diese Zeilen zu löschen",
+		"pg_dump is not installed, the dump was rebuilt from the catalogs":                                                                  "pg_dump ist nicht installiert, der Dump wurde aus den Katalogen rekonstruiert",
+		"pg_dump is not used while allow/deny lists are configured, the dump was rebuilt from the catalogs":                                 "pg_dump wird bei konfigurierten Allow-/Deny-Listen nicht verwendet, der Dump wurde aus den Katalogen rekonstruiert",
+		"Skipped relations hidden by the allow/deny lists: %s":                                                                              "Durch die Allow-/Deny-Listen verborgene Relationen übersprungen: %s",
+		"Only the first %d of %d tables were searched, list tables to search the others":                                                    "Nur die ersten %d von %d Tabellen wurden durchsucht, gib Tabellen an, um die übrigen zu durchsuchen",
+		"%s has no planner statistics for this column, run ANALYZE on it to collect them":                                                   "%s hat keine Planer-Statistiken für diese Spalte, führen Sie ANALYZE darauf aus, um sie zu erheben",
+		"Only %.0f%% of the updates to %s were HOT, a fillfactor below 100 leaves room on each page for the new row versions":               "Nur %.0f%% der Updates von %s waren HOT, ein fillfactor unter 100 lässt auf jeder Seite Platz für die neuen Zeilenversionen",
+		"Set SERVER_LOG to the server's log file or log directory to include the errors logged":                                             "Setzen Sie SERVER_LOG auf die Logdatei oder das Logverzeichnis des Servers, um die protokollierten Fehler einzubeziehen",
+		"Statements that change the role are refused, pass role to the tool instead":                                                        "Anweisungen, die die Rolle wechseln, werden abgelehnt, übergeben Sie stattdessen role an das Tool",
+		"COPY to or from a server file or program is refused, use STDIN or STDOUT":                                                          "COPY in oder aus einer Datei oder einem Programm auf dem Server wird abgelehnt, verwenden Sie STDIN oder STDOUT",
+		"The statement calls %s, which read relations the configured allow/deny lists cannot check":                                         "Die Anweisung ruft %s auf, die Relationen lesen, die die konfigurierten Allow-/Deny-Listen nicht prüfen können",
+		"No export directory, set EXPORT_DIR to the directory tools may write files to":                                                     "Kein Exportverzeichnis, setzen Sie EXPORT_DIR auf das Verzeichnis, in das Tools Dateien schreiben dürfen",
+		"%s must be a file name relative to EXPORT_DIR, without ..":                                                                         "%s muss ein Dateiname relativ zu EXPORT_DIR sein, ohne ..",
+		"%s is a symlink, which tools don't follow out of EXPORT_DIR":                                                                       "%s ist ein symbolischer Link, dem Tools nicht aus EXPORT_DIR heraus folgen",
+		"dry_run still runs the statement, which needs approval here like the write itself. Call again without dry_run to queue it":         "dry_run führt die Anweisung trotzdem aus, die hier wie der Schreibvorgang selbst eine Genehmigung braucht. Rufen Sie ohne dry_run erneut auf, um sie einzureihen",
+		"%d log errors don't name their database and were left out, add %%d to log_line_prefix or log to csvlog or jsonlog to include them": "%d Protokollfehler nennen ihre Datenbank nicht und wurden ausgelassen, fügen Sie %%d zu log_line_prefix hinzu oder protokollieren Sie mit csvlog oder jsonlog, um sie einzubeziehen",
+		"Failed to count rows: %v": "Zeilen konnten nicht gezählt werden: %v",
+	},
+	"ja": {
+		"%s modifies the database and is disabled. Set ALLOW_WRITES=true to enable it": "%s はデータベースを変更するため無効になっています。有効にするには ALLOW_WRITES=true を設定してください",
+		dryRunNotice:       "DRY_RUN が有効です: ロールバックされるトランザクション内で実行されたため、何も保存されていません",
+		dryRunInstructions: "このサーバーはドライランモードで動作しています。すべての書き込みはロールバックされ、応答にはシミュレーションであることが示されます。",
+		"Warnings:":        "警告:",
+		"REDACT_PII is enabled, values looking like PII were masked in: %s": "REDACT_PII が有効です。個人情報と思われる値を次の列でマスクしました: %s",
+		"The statement modifies data, so ANALYZE was skipped and only the estimated plan is shown. Even though it would be rolled back, running it fires triggers and takes locks. Set allow_write_analyze to true to run it anyway": "この文はデータを変更するため ANALYZE を省略し、推定プランのみを表示しています。ロールバックされる場合でも、実行するとトリガーが起動しロックが取得されます。それでも実行するには allow_write_analyze を true に設定してください",
+		"at least one table is required":                         "テーブルを 1 つ以上指定してください",
+		"at least two tables are required":                       "テーブルを 2 つ以上指定してください",
+		"Change %s not found":                                    "変更 %s が見つかりません",
+		"Change %s is %s and can no longer be approved":          "変更 %s は %s 状態のため、承認できません",
+		"Invalid approval token for change %s":                   "変更 %s の承認トークンが無効です",
+		"No pending change %s":                                   "保留中の変更 %s はありません",
+		"Approved change %s failed: %v":                          "承認された変更 %s の実行に失敗しました: %v",
+		"The query returned no rows":                             "クエリは行を返しませんでした",
+		"Parameter %q cannot be set, allowed parameters are: %s": "パラメータ %q は設定できません。設定可能なパラメータ: %s",
+		"name is required unless reset is set":                   "reset を指定しない場合は name が必要です",
+		"Invalid value for %s: %v":                               "%s の値が無効です: %v",
+		"Expanded output shows a single row but the query returned more, add a WHERE clause or LIMIT 1":      "拡張表示は 1 行のみを表示しますが、クエリは複数行を返しました。WHERE 句か LIMIT 1 を追加してください",
+		"Fixture is %d bytes, which is too large to return inline. Lower row_limit or set output_path":       "フィクスチャは %d バイトあり、直接返すには大きすぎます。row_limit を減らすか output_path を指定してください",
+		"%s is not accessible under the configured allow/deny lists":                                         "%s は設定された許可リスト・拒否リストによりアクセスできません",
+		"The statement references %s, which the configured allow/deny lists do not permit":                   "この文は %s を参照していますが、設定された許可リスト・拒否リストでは許可されていません",
+		"%s statements are blocked by QUERY_POLICY, the query tool only runs them when the policy allows it": "%s 文は QUERY_POLICY によりブロックされています。query ツールはポリシーで許可された場合のみ実行します",
+		"An IN list of %d literal values was sent as the array parameter $%d":                                "%d 個のリテラル値を持つ IN リストを配列パラメータ $%d として送信しました",
+		"The connection to the database was lost (%v) and could not be re-established: %v":                   "データベースへの接続が失われ (%v)、再確立できませんでした: %v",
+		"The connection to the database was lost (%v) and re-established on a new connection":                "データベースへの接続が失われ (%v)、新しい接続で再確立しました",
+		"Restored session state: %s": "復元したセッション状態: %s",
+		"Temporary tables, prepared statements, advisory locks and settings changed with SET on the lost connection were not restored":      "失われた接続上の一時テーブル、プリペアドステートメント、アドバイザリロック、SET で変更した設定は復元されていません",
+		"The connection to the database was lost while the statement ran (%v). Run it again to use a new connection":                        "文の実行中にデータベースへの接続が失われました (%v)。新しい接続を使うにはもう一度実行してください",
+		"The transaction still failed to serialize after %d retries: %v":                                                                    "%d 回再試行してもトランザクションを直列化できませんでした: %v",
+		"Retried %d times after serialization failures":                                                                                     "直列化の失敗により %d 回再試行しました",
+		"The database is unavailable and the server is reconnecting (%d attempts so far, last error: %v). Try again shortly":                "データベースが利用できず、サーバーは再接続中です (これまでの試行 %d 回、最後のエラー: %v)。しばらくしてから再試行してください",
+		"No sample was taken near %s, the closest is %s away":                                                                               "%s 付近のサンプルはありません。最も近いサンプルは %s 離れています",
+		"COLUMN_POLICY_FILE masked or formatted: %s":                                                                                        "COLUMN_POLICY_FILE によりマスクまたは整形された列: %s",
+		"Inactive replication slots are retaining WAL and growing the WAL directory: %s":                                                    "非アクティブなレプリケーションスロットが WAL を保持し、WAL ディレクトリを増大させています: %s",
+		"wal_level is %s, publications only replicate with wal_level = logical":                                                             "wal_level が %s です。パブリケーションは wal_level = logical の場合のみ複製されます",
+		"%d of %d saved queries have plan regressions":                                                                                      "保存済みクエリ %d 件（全 %d 件中）でプランが劣化しています",
+		"pg_stat_statements is not installed, suggestions only use table statistics and foreign keys":                                       "pg_stat_statements がインストールされていないため、提案はテーブル統計と外部キーのみに基づきます",
+		"Some tables are mostly read by sequential scans, include_statements finds the columns their queries filter on":                     "主にシーケンシャルスキャンで読まれているテーブルがあります。include_statements でそれらのクエリが絞り込みに使う列を見つけられます",
+		"A variant modifies data, so both plans are estimated without ANALYZE":                                                              "データを変更するバリアントがあるため、両方のプランを ANALYZE なしで推定します",
+		"%s needs PostgreSQL %d or later and was left out, the server runs version %d":                                                      "%s には PostgreSQL %d 以降が必要なため省略しました。サーバーのバージョンは %d です",
+		"generic_plan cannot be combined with ANALYZE, only the estimated plan is shown":                                                    "generic_plan は ANALYZE と併用できないため、推定プランのみを表示します",
+		"wal is only reported with ANALYZE and was left out":                                                                                "wal は ANALYZE 指定時のみ出力されるため省略しました",
+		"%d of %d checked queries have plan regressions":                                                                                    "確認したクエリ %d 件（全 %d 件中）でプランが劣化しています",
+		"ACTIVITY_SAMPLE_INTERVAL (%s) is longer than SLOW_QUERY_THRESHOLD (%s), queries shorter than the interval are mostly missed":       "ACTIVITY_SAMPLE_INTERVAL (%s) が SLOW_QUERY_THRESHOLD (%s) より長いため、間隔より短いクエリの多くは記録されません",
+		"pg_stat_statements is not installed, no statements were matched":                                                                   "pg_stat_statements がインストールされていないため、一致する文は検索されませんでした",
+		"The result was cut off after %d rows, at the MAX_RESULT_BYTES limit of %d bytes. Add a LIMIT or narrow the query":                  "結果は %d 行で MAX_RESULT_BYTES の上限 %d バイトに達したため打ち切られました。LIMIT を追加するか、クエリを絞り込んでください",
+		"Binary values longer than MAX_BINARY_BYTES (%d) were truncated in: %s":                                                             "MAX_BINARY_BYTES (%d) より長いバイナリ値を切り詰めた列: %s",
+		"Binary columns were left out of the result: %s":                                                                                    "結果から除外したバイナリ列: %s",
+		"JSON values larger than MAX_JSON_BYTES (%d) were truncated in: %s":                                                                 "MAX_JSON_BYTES (%d) より大きい JSON 値を切り詰めた列: %s",
+		"Not run, query %d failed first":                                                                                                    "未実行です。先にクエリ %d が失敗しました",
+		"dry_run: this ran inside a transaction that was rolled back, nothing was persisted":                                                "dry_run: ロールバックされたトランザクション内で実行されたため、何も保存されていません",
+		"Nothing was changed. Call again with commit set to true to apply the statement":                                                    "何も変更されていません。文を適用するには commit を true にして再度呼び出してください",
+		"Nothing was deleted. Call again with expected_count set to %d to delete these rows":                                                "何も削除されていません。これらの行を削除するには expected_count を %d にして再度呼び出してください",
+		"pg_dump is not installed, the dump was rebuilt from the catalogs":                                                                  "pg_dump がインストールされていないため、ダンプはカタログから再構築されました",
+		"pg_dump is not used while allow/deny lists are configured, the dump was rebuilt from the catalogs":                                 "許可/拒否リストが設定されている間は pg_dump を使用しないため、ダンプはカタログから再構築されました",
+		"Skipped relations hidden by the allow/deny lists: %s":                                                                              "許可/拒否リストで隠されたリレーションをスキップしました: %s",
+		"Only the first %d of %d tables were searched, list tables to search the others":                                                    "最初の %d 個のテーブルのみ検索しました (全 %d 個)。残りを検索するにはテーブルを指定してください",
+		"%s has no planner statistics for this column, run ANALYZE on it to collect them":                                                   "%s にはこの列のプランナー統計がありません。収集するには ANALYZE を実行してください",
+		"Only %.0f%% of the updates to %s were HOT, a fillfactor below 100 leaves room on each page for the new row versions":               "更新のうち HOT だったのは %.0f%% のみです（%s）。fillfactor を 100 未満にすると、各ページに新しい行バージョンの空きが残ります",
+		"Set SERVER_LOG to the server's log file or log directory to include the errors logged":                                             "記録されたエラーを含めるには、SERVER_LOG にサーバーのログファイルまたはログディレクトリを設定してください",
+		"Statements that change the role are refused, pass role to the tool instead":                                                        "ロールを変更する文は拒否されます。代わりにツールに role を指定してください",
+		"COPY to or from a server file or program is refused, use STDIN or STDOUT":                                                          "サーバー上のファイルやプログラムとの COPY は拒否されます。STDIN または STDOUT を使用してください",
+		"The statement calls %s, which read relations the configured allow/deny lists cannot check":                                         "この文は %s を呼び出しており、設定された許可/拒否リストでは確認できないリレーションを読み取ります",
+		"No export directory, set EXPORT_DIR to the directory tools may write files to":                                                     "エクスポートディレクトリがありません。ツールがファイルを書き込めるディレクトリを EXPORT_DIR に設定してください",
+		"%s must be a file name relative to EXPORT_DIR, without ..":                                                                         "%s は .. を含まない、EXPORT_DIR からの相対ファイル名である必要があります",
+		"%s is a symlink, which tools don't follow out of EXPORT_DIR":                                                                       "%s はシンボリックリンクです。ツールは EXPORT_DIR の外へシンボリックリンクをたどりません",
+		"dry_run still runs the statement, which needs approval here like the write itself. Call again without dry_run to queue it":         "dry_run でもステートメントは実行されるため、ここでは書き込みと同様に承認が必要です。キューに入れるには dry_run なしで再度呼び出してください",
+		"%d log errors don't name their database and were left out, add %%d to log_line_prefix or log to csvlog or jsonlog to include them": "%d 件のログエラーはデータベース名がないため除外されました。含めるには log_line_prefix に %%d を追加するか、csvlog または jsonlog で記録してください",
+		"Failed to count rows: %v": "行数を数えられませんでした: %v",
+	},
+}
+
+var toolDescriptions = map[string]map[string]string{
+	"es": {
+		"get_table_schema":          "Obtiene la información del esquema (columnas, tipos de datos, comentarios, etc.) de una tabla",
+		"query":                     "Ejecuta una consulta SQL contra la base de datos PostgreSQL y devuelve los resultados como JSON. Las sentencias se clasifican para QUERY_POLICY por su propio texto, por lo que no se ve el SQL que las funciones construyen y ejecutan. Se rechazan las sentencias que cambian el rol y COPY hacia o desde archivos o programas del servidor",
+		"list_tables":               "Lista todas las tablas del esquema indicado (por defecto: public), con sus comentarios",
+		"get_table_constraints":     "Obtiene todas las restricciones (clave primaria, clave foránea, única, check) de una tabla",
+		"get_table_indexes":         "Obtiene todos los índices de una tabla, incluido el tipo de índice y sus columnas, distinguiendo columnas clave, columnas INCLUDE y expresiones, con los predicados de índices parciales, tamaños e índices no válidos",
+		"explain_analyze":           "Ejecuta EXPLAIN ANALYZE sobre una consulta para obtener el plan de ejecución y métricas de rendimiento. Admite opciones de analyze, verbose, costs, buffers, timing, summary, generic_plan, settings, wal, memory y formato de salida (text, json, xml, yaml), o un árbol compacto del plan con render=tree",
+		"estimate_row_count":        "Estima rápidamente el número de filas a partir de las estadísticas del planificador (reltuples) para una o todas las tablas de un esquema. Las tablas pequeñas o nunca analizadas se cuentan exactamente",
+		"traverse_hierarchy":        "Recorre una tabla autorreferenciada (organigramas, categorías, amistades) desde una clave raíz con un CTE recursivo. Devuelve cada fila alcanzable con su profundidad y ruta, con protección contra ciclos",
+		"find_row_path":             "Averigua cómo se conectan dos filas de tablas distintas mediante claves foráneas. Devuelve las cadenas de joins candidatas (la más corta primero) junto con las filas que las conectan",
+		"list_sequences":            "Lista las secuencias de un esquema con su valor actual, valor máximo, columna propietaria y porcentaje consumido. Señala las columnas serial/identity que se acercan al desbordamiento (sobre todo int4)",
+		"infer_joins":               "Dado un conjunto de tablas, calcula los caminos de join más cortos por claves foráneas y devuelve una cláusula FROM/JOIN lista para usar con alias, las tablas intermedias necesarias y las alternativas ambiguas",
+		"export_fixture":            "Exporta un subconjunto de tablas como fixture SQL autocontenido para tests: sentencias CREATE TABLE, una muestra de filas referencialmente consistente con el texto libre anonimizado, índices, claves foráneas y reinicio de secuencias",
+		"list_materialized_views":   "Lista las vistas materializadas de un esquema con su tamaño, si están pobladas, su definición y si pueden refrescarse de forma concurrente",
+		"refresh_materialized_view": "Refresca una vista materializada, opcionalmente CONCURRENTLY para no bloquear a los lectores. Solo disponible con las escrituras habilitadas (ALLOW_WRITES=true)",
+		"view_dependencies":         "Dada una tabla o vista, devuelve todas las vistas y vistas materializadas que dependen de ella (recursivamente) con las columnas que usa cada una. Sirve para evaluar el alcance de un cambio de esquema",
+		"list_types":                "Lista los enums definidos por el usuario con sus etiquetas, los tipos compuestos con sus atributos, los dominios con su tipo base y checks, y los tipos de rango de un esquema. Sirve para resolver columnas que get_table_schema muestra como USER-DEFINED",
+		"get_partitions":            "Para una tabla particionada, devuelve la estrategia de particionado, las columnas clave, cada partición hija (incluidas las subparticiones) con sus límites, filas estimadas y tamaño, y qué tablas particionadas no tienen partición por defecto",
+		"export_session":            "Exporta una transcripción de todo lo hecho en esta sesión: consultas ejecutadas con resúmenes de resultados, planes capturados y cambios aplicados. Se devuelve en markdown o JSON, o se escribe en un fichero",
+		"list_pending_changes":      "Lista las operaciones de escritura en espera de aprobación humana (con REQUIRE_APPROVAL=true), con sus sentencias y estado",
+		"approve_change":            "Ejecuta una operación de escritura en cola. Requiere el token de aprobación enviado al aprobador por webhook o por el log del servidor",
+		"reject_change":             "Rechaza una operación de escritura en cola para que nunca pueda ejecutarse",
+		"get_usage":                 "Informa del tiempo de base de datos, filas leídas y bytes devueltos acumulados en la sesión MCP actual, en total y por herramienta. Use all_sessions para informar de todas las sesiones del servidor",
+		"pool_stats":                "Muestra estadísticas del pool de conexiones (conexiones en uso, libres y máximas, número de adquisiciones y tiempos de espera) y la configuración del pool en vigor",
+		"demonstrate_anomaly":       "Demuestra una anomalía de concurrencia (lost_update, non_repeatable_read, phantom_read, write_skew) bajo un nivel de aislamiento, con dos sesiones sobre una tabla temporal en el esquema sandbox. Devuelve cada paso de cada sesión y si la anomalía ocurrió (requiere ALLOW_WRITES=true)",
+		"meta_command":              "Ejecuta un metacomando al estilo de psql: \\dt, \\dv, \\dm, \\di, \\ds, \\dn, \\df y \\l listan objetos (con un patrón opcional como public.user*), \\d nombre describe una relación y un + final añade tamaños y descripciones",
+		"set_session_parameter":     "Establece un parámetro del planificador o de recursos (work_mem, enable_seqscan, random_page_cost, statement_timeout, ...) para las siguientes llamadas a query y explain_analyze de esta sesión, para experimentar con planes sin cambiar la configuración del servidor. Use reset para volver al valor por defecto",
+		"get_event_timeline":        "Cronología de los eventos recientes relevantes de la base de datos para revisar incidentes: reinicios, recargas de configuración (con parámetros pendientes de reinicio), ejecuciones de autovacuum/autoanalyze y de mantenimiento manual, conexiones de réplicas, fallos de archivado, reinicios de estadísticas y cambios aplicados a través de este servidor, además de contadores de checkpoints",
+		"get_memory_usage":          "Atribuye el uso de memoria y de ficheros temporales a las consultas activas: ficheros y bytes temporales por backend (de pg_ls_tmpdir), work_mem y temp_file_limit, los mayores contextos de memoria de esta conexión y, opcionalmente, pide a otro backend que registre sus contextos de memoria (PostgreSQL 14+)",
+		"verify_installation":       "Valida esta instalación e informa de cada comprobación como correcta, aviso o fallo: conectividad y rol en uso, si las consultas al catálogo de cada herramienta se ejecutan con los privilegios actuales, extensiones opcionales, que la herramienta query rechaza escrituras y si las herramientas de escritura están habilitadas",
+		"get_connection_info":       "Muestra a dónde está conectado el servidor: host, puerto, base de datos, usuario, sslmode y si la conexión usa realmente TLS. Nunca incluye la contraseña ni la cadena de conexión",
+		"diff_dataset":              "Compara un conjunto de datos CSV o JSON (por ejemplo, una hoja de cálculo exportada) con una tabla por columnas clave: filas que faltan en la tabla, filas sobrantes en la tabla y filas con valores distintos, convirtiendo los valores a los tipos de las columnas antes de comparar. Opcionalmente genera las sentencias INSERT, UPDATE y DELETE que reconcilian la tabla, para revisarlas; no se ejecuta nada",
+		"get_activity_history":      "Revisa lo que se estaba ejecutando: la instantánea de pg_stat_activity más cercana a una hora (\"qué se ejecutaba a las 14:32\") o un resumen de un intervalo con sesiones activas medias, principales eventos de espera y consultas, incluidos los backends que bloquean y los bloqueos esperados. Requiere ACTIVITY_SAMPLE_INTERVAL, que muestrea en segundo plano y guarda el historial en memoria",
+		"estimate_type_change":      "Evalúa un cambio de tipo de columna antes de ejecutarlo: si ALTER COLUMN ... TYPE reescribe la tabla, el tiempo estimado de reescritura según el tamaño de la tabla y el rendimiento de lectura medido, qué índices se reconstruyen, las vistas y conversiones ausentes que lo harían fallar, las claves foráneas implicadas y los riesgos de conversión que notarían las aplicaciones (desbordamiento, redondeo, relleno, zonas horarias). No se modifica nada",
+		"vector_index_info":         "Inspecciona los índices ivfflat y HNSW de pgvector: parámetros (lists, m, ef_construction), tamaño, clase de operadores y el ORDER BY que necesita una búsqueda para usarlos, con valores recomendados de ivfflat.probes y hnsw.ef_search. Opcionalmente aplica las recomendaciones a las siguientes llamadas a query de la sesión",
+		"spatial_info":              "Lista las columnas geometry y geography de PostGIS con su tipo de geometría, SRID y dimensiones, y los índices espaciales (GiST, SP-GiST, BRIN) sobre ellas. La herramienta query devuelve estas columnas como GeoJSON, o como WKT con geometry_format",
+		"logical_replication_info":  "Inspecciona la replicación: slots con el WAL que retienen (señalando los slots inactivos que hacen crecer el WAL), publicaciones con sus tablas y suscripciones con el estado de sus procesos de aplicación",
+		"check_plan_regressions":    "Compara los planes EXPLAIN actuales de las consultas de SAVED_QUERIES_FILE con sus planes de referencia registrados e informa de regresiones: índices que ya no se usan, nuevos recorridos secuenciales y saltos del coste estimado. Los planes son estimados, no se ejecuta nada. Opcionalmente registra los planes actuales como nuevas referencias",
+		"suggest_indexes":           "Sugiere índices que faltan, ordenados, como sentencias CREATE INDEX con su justificación: claves foráneas sin índice sobre sus columnas, tablas leídas sobre todo con recorridos secuenciales y, opcionalmente, las columnas por las que filtran las entradas más costosas de pg_stat_statements. No se crea nada",
+		"compare_plans":             "Ejecuta EXPLAIN sobre dos variantes de una consulta, o la misma consulta con distintos parámetros de sesión, y compara los planes: cambios de forma, nodos presentes solo en un plan, coste y tiempos, con los nodos que se volvieron más lentos o más rápidos. Ambas se ejecutan en transacciones que se revierten",
+		"save_plan_baseline":        "Registra el plan EXPLAIN actual de una consulta como plan de referencia de su huella en PLAN_STORE_FILE. Las consultas que solo difieren en constantes, formato o comentarios comparten huella. El plan es estimado, no se ejecuta nada",
+		"check_plan_regression":     "Compara el plan EXPLAIN actual de una consulta, de una huella guardada o de todas las referencias de PLAN_STORE_FILE con el plan de referencia registrado e informa de regresiones: índices que ya no se usan, nuevos recorridos secuenciales y saltos del coste estimado. Los planes son estimados, no se ejecuta nada",
+		"recent_slow_queries":       "Lista las consultas recientes que el muestreador de pg_stat_activity en segundo plano vio ejecutándose más de SLOW_QUERY_THRESHOLD, las más largas primero, con cuándo empezaron y cuándo se vieron y los eventos de espera en que se muestrearon. Funciona sin pg_stat_statements. Requiere ACTIVITY_SAMPLE_INTERVAL",
+		"normalize_query":           "Normaliza una sentencia SQL a su forma canónica y su huella: sin comentarios ni formato, palabras clave y nombres en minúsculas, literales y parámetros sustituidos por ?. Las consultas de registros, de pg_stat_statements y el SQL generado que solo difieren en constantes comparten huella. Opcionalmente lista las entradas de pg_stat_statements que coinciden",
+		"batch_query":               "Ejecuta varias consultas de solo lectura independientes en un solo viaje de ida y vuelta mediante un lote en pipeline y devuelve sus resultados por índice, en el orden dado. Una consulta que falla anula las siguientes, que se informan como no ejecutadas",
+		"execute_write":             "Ejecuta una sentencia INSERT, UPDATE, DELETE, DDL o de mantenimiento, al margen de query y su QUERY_POLICY. Requiere confirm: true y max_rows, el máximo de filas que puede afectar; una sentencia que afecta a más se revierte. Devuelve el número de filas afectadas y las filas de RETURNING. Requiere ALLOW_WRITES",
+		"update_rows":               "Actualiza las filas de una tabla que cumplen una cláusula where estructurada obligatoria (condiciones columna/op/valor) con los valores de set. Sin commit en true no ejecuta el UPDATE y solo informa de las filas que coinciden. Confirmar requiere ALLOW_WRITES",
+		"delete_rows":               "Elimina en dos pasos las filas de una tabla que cumplen una cláusula where estructurada obligatoria: sin expected_count, cuenta las filas coincidentes y muestra algunas; con él, las elimina, revirtiendo salvo que se eliminen exactamente esas filas. Eliminar requiere ALLOW_WRITES",
+		"dump_schema":               "Genera SQL solo de esquema para un esquema (tipos, tablas, restricciones, índices, vistas, funciones) o para tablas seleccionadas, reconstruido desde los catálogos o con pg_dump si use_pg_dump está activado y está instalado. Se devuelve en línea hasta 1 MiB o se escribe en output_path dentro de EXPORT_DIR",
+		"backup_table":              "Copia los datos de una tabla a un archivo comprimido con gzip mediante COPY, en formato binary o csv, como copia de seguridad antes de un cambio arriesgado. El archivo se escribe en output_path dentro de EXPORT_DIR y registra la tabla y las columnas para restore_table. Requiere ALLOW_WRITES. Envía notificaciones de progreso cuando el cliente las pide",
+		"restore_table":             "Restaura un archivo de backup_table en la tabla de la que se tomó u otra tabla con las mismas columnas, en una sola transacción, opcionalmente vaciándola antes. input_path es relativo a EXPORT_DIR. Requiere ALLOW_WRITES. Envía notificaciones de progreso cuando el cliente las pide",
+		"clone_database":            "Copia la base de datos con CREATE DATABASE ... TEMPLATE, que no admite otras sesiones conectadas a ella, y ejecuta las siguientes llamadas de herramientas de esta sesión contra la copia, para que los experimentos destructivos ocurran en una base de datos desechable. Con REQUIRE_APPROVAL la copia queda pendiente de aprobación, vuelva a llamar cuando se haya ejecutado para conectarse. Requiere ALLOW_WRITES",
+		"drop_clone":                "Elimina una base de datos creada por clone_database y devuelve a la base de datos original las sesiones que la usaban. Con REQUIRE_APPROVAL el DROP DATABASE se pone en cola para aprobación. Requiere ALLOW_WRITES",
+		"anonymize_table":           "Reescribe columnas de texto sensibles (las indicadas, o las que COLUMN_POLICY_FILE marca como enmascaradas o texto libre) con los valores ficticios deterministas de export_fixture, en su lugar o en una nueva target_table. Valores iguales reciben el mismo valor ficticio en todas las tablas y las columnas clave nunca se reescriben, así que las referencias siguen funcionando. Sin commit en true no escribe nada y solo informa de las filas que reescribiría. Confirmar requiere ALLOW_WRITES",
+		"set_comment":               "Guarda en la base de datos la descripción de una tabla, vista o columna con COMMENT ON, donde get_table_schema y list_tables la muestran. Un comentario vacío la elimina. Requiere ALLOW_WRITES",
+		"describe_table":            "Describe una tabla en una sola llamada: columnas, restricciones, índices, triggers, seguridad a nivel de fila y sus políticas, tamaños, estimación de filas y comentarios. Úsala en lugar de llamar a get_table_schema, get_table_constraints y get_table_indexes una por una",
+		"search_data":               "Busca un valor literal en todas las columnas text, varchar y JSON de una tabla, una lista de tablas o un esquema entero, y devuelve las filas coincidentes y las columnas donde se encontró el valor. Limita las filas por tabla y puede muestrear tablas grandes",
+		"get_dependencies":          "Lista de qué depende una tabla, vista, secuencia, función o tipo y qué depende de ello (vistas, claves foráneas, valores por defecto, triggers, funciones), a través de pg_depend. Úsala antes de sugerir DROP o ALTER para ver el impacto y si un borrado necesita CASCADE",
+		"validate_constraints":      "Encuentra las filas que incumplen una restricción antes de añadirla: indica una restricción unique, foreign_key, check o not_null propuesta y obtén el número y una muestra de las filas que la incumplen. Sin ella, comprueba las restricciones NOT VALID de la tabla o lista las de un esquema. Devuelve la sentencia ALTER TABLE cuando nada la incumple",
+		"run_data_checks":           "Evalúa reglas de calidad de datos e informa si cada regla pasa o falla: not_null con una proporción de NULL permitida, unique, range, formato regex y referencias a otra tabla. Las reglas vienen de DATA_CHECKS_FILE o se pasan directamente. Cada regla informa de sus filas que fallan y de las comprobadas",
+		"column_cardinality":        "Informa de los valores distintos, la fracción de NULL y los valores más comunes de las columnas de una tabla, para valorar candidatos a índice y estrategias de join. Las tablas de hasta 100000 filas se cuentan exactamente, las más grandes se describen con las estadísticas del planificador o una muestra de páginas",
+		"column_distribution":       "Muestra lo que el planificador sabe de la distribución de una columna según pg_stats: límites del histograma, valores más comunes con sus frecuencias, fracción de NULL, estimación de valores distintos y correlación física. Opcionalmente cuenta un histograma de anchura fija de una columna numérica, de fecha o de hora a partir de sus filas. Úsala para razonar sobre el sesgo detrás de una mala estimación de filas",
+		"run_analyze":               "Ejecuta ANALYZE sobre tablas para renovar estadísticas del planificador obsoletas e informa de las estimaciones de filas y de la fecha del último análisis antes y después. Con probe_query, también compara su plan estimado antes y después. Requiere ALLOW_WRITES",
+		"run_vacuum":                "Ejecuta VACUUM sobre tablas, opcionalmente FULL, FREEZE o con ANALYZE, siguiendo pg_stat_progress_vacuum mientras se ejecuta e informa de las filas muertas y los tamaños antes y después. Requiere ALLOW_WRITES",
+		"run_reindex":               "Reconstruye un índice, o todos los índices de una tabla, opcionalmente CONCURRENTLY para no bloquear las escrituras, siguiendo pg_stat_progress_create_index mientras se ejecuta e informa de los tamaños de los índices antes y después. Requiere ALLOW_WRITES",
+		"storage_info":              "Muestra cómo se almacena una tabla: tamaños del heap, de TOAST y de los índices, la estrategia de almacenamiento (plain, main, external, extended) y la compresión de cada columna, y el fillfactor y demás reloptions de la tabla, de su tabla TOAST y de sus índices, con la proporción de actualizaciones HOT. Úsala para diagnosticar el bloat de tablas con muchas actualizaciones",
+		"list_tablespaces":          "Lista los tablespaces con su ubicación, tamaño y opciones de coste, y las tablas, vistas materializadas e índices más grandes de la base de datos actual almacenados en cada uno. Úsala cuando los datos calientes y fríos se reparten entre distintos almacenamientos",
+		"list_foreign_tables":       "Lista los servidores de foreign data wrappers con sus opciones, los mapeos de usuario (solo los nombres de las opciones, nunca contraseñas) y las tablas foráneas que sirven con sus opciones. get_table_schema describe las tablas foráneas como cualquier otra, incluidas las opciones de columna",
+		"list_event_triggers":       "Lista los event triggers, que se ejecutan con DDL como CREATE, ALTER y DROP en lugar de sobre filas, con su evento, etiquetas de comando, función y lo que hace la función. Revísalos cuando un cambio de esquema se rechace o se comporte de forma inesperada",
+		"list_rules":                "Lista las reglas de reescritura sobre tablas y vistas (no las que implementan vistas), con su evento, si sustituyen la sentencia (INSTEAD), su definición y lo que hacen sus acciones. Revísalas cuando una escritura afecte a otras filas o tablas de las esperadas, o a ninguna",
+		"recent_errors":             "Informa de los interbloqueos y rollbacks contados en pg_stat_database y, cuando SERVER_LOG apunta al archivo o directorio de log del servidor, de las entradas ERROR, FATAL y PANIC recientes como registros estructurados con SQLSTATE, detalle, pista y sentencia, las más recientes primero. Lee los formatos stderr, csvlog y jsonlog",
+	},
+	"de": {
+		"get_table_schema":          "Liefert die Schemainformationen (Spalten, Datentypen, Kommentare usw.) einer Tabelle",
+		"query":                     "Führt eine SQL-Abfrage gegen die PostgreSQL-Datenbank aus und liefert die Ergebnisse als JSON. Anweisungen werden für QUERY_POLICY nach ihrem eigenen Text eingestuft, SQL, das Funktionen zusammensetzen und ausführen, bleibt daher unsichtbar. Anweisungen, die die Rolle wechseln, und COPY in oder aus Dateien oder Programmen auf dem Server werden abgelehnt",
+		"list_tables":               "Listet alle Tabellen im angegebenen Schema (Standard: public) mit ihren Kommentaren auf",
+		"get_table_constraints":     "Liefert alle Constraints (Primärschlüssel, Fremdschlüssel, Unique, Check) einer Tabelle",
+		"get_table_indexes":         "Liefert alle Indizes einer Tabelle mit Indextyp und Spalten, unterscheidet Schlüsselspalten, INCLUDE-Spalten und Ausdrücke, mit Prädikaten partieller Indizes, Größen und ungültigen Indizes",
+		"explain_analyze":           "Führt EXPLAIN ANALYZE für eine Abfrage aus und liefert den Ausführungsplan und Leistungskennzahlen. Unterstützt die Optionen analyze, verbose, costs, buffers, timing, summary, generic_plan, settings, wal, memory und das Ausgabeformat (text, json, xml, yaml) oder einen kompakten Planbaum mit render=tree",
+		"estimate_row_count":        "Schnelle Zeilenzahlschätzung aus den Planerstatistiken (reltuples) für eine oder alle Tabellen eines Schemas. Kleine oder nie analysierte Tabellen werden exakt gezählt",
+		"traverse_hierarchy":        "Durchläuft eine selbstreferenzierende Tabelle (Organigramme, Kategorien, Freundschaften) ab einem Wurzelschlüssel mit einem rekursiven CTE. Liefert jede erreichbare Zeile mit Tiefe und Pfad, mit Zyklenschutz",
+		"find_row_path":             "Findet heraus, wie zwei Zeilen in verschiedenen Tabellen über Fremdschlüssel verbunden sind. Liefert mögliche Join-Ketten (kürzeste zuerst) mit den verbindenden Zeilen",
+		"list_sequences":            "Listet die Sequenzen eines Schemas mit aktuellem Wert, Maximalwert, zugehöriger Spalte und verbrauchtem Anteil. Markiert serial/identity-Spalten, die sich einem Überlauf nähern (insbesondere int4)",
+		"infer_joins":               "Berechnet für eine Menge von Tabellen die kürzesten Join-Pfade über Fremdschlüssel und liefert eine fertige FROM/JOIN-Klausel mit Aliasen, benötigten Zwischentabellen und mehrdeutigen Alternativen",
+		"export_fixture":            "Exportiert eine Auswahl von Tabellen als eigenständige SQL-Fixture für Testsuiten: CREATE TABLE-Anweisungen, eine referenziell konsistente Stichprobe mit anonymisiertem Freitext, Indizes, Fremdschlüssel und zurückgesetzte Sequenzen",
+		"list_materialized_views":   "Listet die materialisierten Sichten eines Schemas mit Größe, Befüllungsstatus, Definition und ob sie nebenläufig aktualisiert werden können",
+		"refresh_materialized_view": "Aktualisiert eine materialisierte Sicht, optional CONCURRENTLY, damit Leser nicht blockiert werden. Nur verfügbar, wenn Schreibzugriffe aktiviert sind (ALLOW_WRITES=true)",
+		"view_dependencies":         "Liefert zu einer Tabelle oder Sicht alle (rekursiv) davon abhängigen Sichten und materialisierten Sichten mit den jeweils verwendeten Spalten. Hilft, die Auswirkungen einer Schemaänderung abzuschätzen",
+		"list_types":                "Listet benutzerdefinierte Enums mit ihren Werten, zusammengesetzte Typen mit ihren Attributen, Domänen mit Basistyp und Checks sowie Bereichstypen eines Schemas. Hilft, Spalten aufzulösen, die get_table_schema als USER-DEFINED meldet",
+		"get_partitions":            "Liefert für eine partitionierte Tabelle die Partitionierungsstrategie, die Schlüsselspalten, jede Kindpartition (einschließlich Unterpartitionen) mit Grenzen, geschätzter Zeilenzahl und Größe sowie partitionierte Tabellen ohne Default-Partition",
+		"export_session":            "Exportiert ein Protokoll dieser Sitzung: ausgeführte Abfragen mit Ergebniszusammenfassungen, erfasste Pläne und angewendete Änderungen. Als Markdown oder JSON zurückgegeben oder in eine Datei geschrieben",
+		"list_pending_changes":      "Listet Schreibvorgänge, die auf menschliche Genehmigung warten (bei REQUIRE_APPROVAL=true), mit Anweisungen und Status",
+		"approve_change":            "Führt einen wartenden Schreibvorgang aus. Erfordert das Genehmigungstoken, das per Webhook oder Serverlog an den Genehmiger gesendet wurde",
+		"reject_change":             "Lehnt einen wartenden Schreibvorgang ab, sodass er nie ausgeführt werden kann",
+		"get_usage":                 "Meldet die kumulierte Datenbankzeit, gelesene Zeilen und zurückgegebene Bytes der aktuellen MCP-Sitzung, insgesamt und pro Tool. Mit all_sessions werden alle Sitzungen des Servers gemeldet",
+		"pool_stats":                "Zeigt Statistiken des Verbindungspools (belegte, freie und maximale Verbindungen, Anzahl der Anforderungen und Wartezeiten) und die geltenden Pool-Einstellungen",
+		"demonstrate_anomaly":       "Demonstriert eine Nebenläufigkeitsanomalie (lost_update, non_repeatable_read, phantom_read, write_skew) unter einer Isolationsstufe mit zwei Sitzungen auf einer Hilfstabelle im Sandbox-Schema. Liefert jeden Schritt jeder Sitzung und ob die Anomalie auftrat (erfordert ALLOW_WRITES=true)",
+		"meta_command":              "Führt einen Metabefehl im psql-Stil aus: \\dt, \\dv, \\dm, \\di, \\ds, \\dn, \\df und \\l listen Objekte auf (mit optionalem Muster wie public.user*), \\d name beschreibt eine Relation und ein angehängtes + ergänzt Größen und Beschreibungen",
+		"set_session_parameter":     "Setzt einen Planer- oder Ressourcenparameter (work_mem, enable_seqscan, random_page_cost, statement_timeout, ...) für nachfolgende query- und explain_analyze-Aufrufe dieser Sitzung, um mit Plänen zu experimentieren, ohne die Serverkonfiguration zu ändern. Mit reset gilt wieder der Serverstandard",
+		"get_event_timeline":        "Chronologische Übersicht der letzten wichtigen Datenbankereignisse zur Vorfallsanalyse: Serverneustarts, Konfigurationsneuladungen (mit Einstellungen, die einen Neustart erfordern), Autovacuum/Autoanalyze- und manuelle Wartungsläufe, Replikatverbindungen, Archivierungsfehler, Statistik-Resets und über diesen Server angewendete Änderungen sowie Checkpoint-Zähler",
+		"get_memory_usage":          "Ordnet Speicher- und Temporärdateinutzung aktiven Abfragen zu: temporäre Dateien und Bytes pro Backend (aus pg_ls_tmpdir), work_mem und temp_file_limit, die größten Speicherkontexte dieser Verbindung und optional das Protokollieren der Speicherkontexte eines anderen Backends (PostgreSQL 14+)",
+		"verify_installation":       "Prüft diese Installation und meldet je Prüfung bestanden, Warnung oder fehlgeschlagen: Verbindung und verwendete Rolle, ob die Katalogabfragen jedes Werkzeugs mit den aktuellen Rechten laufen, optionale Erweiterungen, dass das query-Werkzeug Schreibzugriffe ablehnt und ob Schreibwerkzeuge aktiviert sind",
+		"get_connection_info":       "Zeigt, wohin der Server verbunden ist: Host, Port, Datenbank, Benutzer, sslmode und ob die Verbindung tatsächlich TLS verwendet. Enthält nie das Passwort oder die Verbindungszeichenfolge",
+		"diff_dataset":              "Vergleicht einen CSV- oder JSON-Datensatz (etwa einen Tabellenkalkulationsexport) anhand von Schlüsselspalten mit einer Tabelle: in der Tabelle fehlende Zeilen, überzählige Zeilen in der Tabelle und Zeilen mit geänderten Werten, wobei die Werte vor dem Vergleich in die Spaltentypen umgewandelt werden. Erzeugt optional die INSERT-, UPDATE- und DELETE-Anweisungen zum Abgleich der Tabelle zur Prüfung; ausgeführt wird nichts",
+		"get_activity_history":      "Zeigt rückblickend, was lief: den pg_stat_activity-Schnappschuss, der einem Zeitpunkt am nächsten liegt (\"was lief um 14:32\"), oder eine Zusammenfassung eines Zeitraums mit durchschnittlich aktiven Sitzungen, häufigsten Wait-Events und Abfragen, einschließlich blockierender Backends und erwarteter Sperren. Benötigt ACTIVITY_SAMPLE_INTERVAL, das im Hintergrund Stichproben nimmt und den Verlauf im Speicher hält",
+		"estimate_type_change":      "Bewertet eine geplante Typänderung einer Spalte vor der Ausführung: ob ALTER COLUMN ... TYPE die Tabelle neu schreibt, die geschätzte Dauer aus Tabellengröße und gemessenem Lesedurchsatz, welche Indizes neu aufgebaut werden, Sichten und fehlende Casts, an denen sie scheitert, beteiligte Fremdschlüssel und Umwandlungsrisiken, die Anwendungen bemerken würden (Überlauf, Rundung, Auffüllen, Zeitzonen). Es wird nichts geändert",
+		"vector_index_info":         "Untersucht ivfflat- und HNSW-Indizes von pgvector: Parameter (lists, m, ef_construction), Größe, Operatorklasse und das ORDER BY, das eine Suche zu ihrer Nutzung braucht, mit empfohlenen Werten für ivfflat.probes und hnsw.ef_search. Wendet die Empfehlungen optional auf spätere query-Aufrufe der Sitzung an",
+		"spatial_info":              "Listet die geometry- und geography-Spalten von PostGIS mit Geometrietyp, SRID und Dimensionen sowie die räumlichen Indizes (GiST, SP-GiST, BRIN) darauf. Das Werkzeug query liefert diese Spalten als GeoJSON, oder mit geometry_format als WKT",
+		"logical_replication_info":  "Untersucht die Replikation: Slots mit dem WAL, das sie zurückhalten (inaktive Slots, die das WAL wachsen lassen, werden markiert), Publikationen mit ihren Tabellen und Subskriptionen mit dem Zustand ihrer Apply-Worker",
+		"check_plan_regressions":    "Vergleicht die aktuellen EXPLAIN-Pläne der Abfragen aus SAVED_QUERIES_FILE mit ihren aufgezeichneten Referenzplänen und meldet Regressionen: nicht mehr genutzte Indizes, neue sequenzielle Scans und Sprünge der geschätzten Kosten. Die Pläne werden nur geschätzt, nichts wird ausgeführt. Speichert die aktuellen Pläne optional als neue Referenzen",
+		"suggest_indexes":           "Schlägt fehlende Indizes vor, nach Rang geordnet, als CREATE-INDEX-Anweisungen mit Begründung: Fremdschlüssel ohne Index auf ihren Spalten, Tabellen, die überwiegend sequenziell gelesen werden, und optional die Spalten, nach denen die teuersten Einträge von pg_stat_statements filtern. Es wird nichts angelegt",
+		"compare_plans":             "Führt EXPLAIN für zwei Varianten einer Abfrage oder dieselbe Abfrage mit unterschiedlichen Sitzungsparametern aus und vergleicht die Pläne: Änderungen der Struktur, Knoten, die nur in einem Plan vorkommen, Kosten und Laufzeiten sowie die Knoten, die langsamer oder schneller wurden. Beide laufen in Transaktionen, die zurückgerollt werden",
+		"save_plan_baseline":        "Speichert den aktuellen EXPLAIN-Plan einer Abfrage als Referenzplan ihres Fingerabdrucks in PLAN_STORE_FILE. Abfragen, die sich nur in Konstanten, Formatierung oder Kommentaren unterscheiden, teilen einen Fingerabdruck. Der Plan wird nur geschätzt, nichts wird ausgeführt",
+		"check_plan_regression":     "Vergleicht den aktuellen EXPLAIN-Plan einer Abfrage, eines gespeicherten Fingerabdrucks oder aller Referenzen aus PLAN_STORE_FILE mit dem aufgezeichneten Referenzplan und meldet Regressionen: nicht mehr genutzte Indizes, neue sequenzielle Scans und Sprünge der geschätzten Kosten. Die Pläne werden nur geschätzt, nichts wird ausgeführt",
+		"recent_slow_queries":       "Listet kürzlich ausgeführte Abfragen, die der Hintergrund-Sampler von pg_stat_activity länger als SLOW_QUERY_THRESHOLD laufen sah, die längsten zuerst, mit Startzeit, Erfassungszeiten und den Wait-Events, in denen sie erfasst wurden. Funktioniert ohne pg_stat_statements. Benötigt ACTIVITY_SAMPLE_INTERVAL",
+		"normalize_query":           "Normalisiert eine SQL-Anweisung zu ihrer kanonischen Form und ihrem Fingerabdruck: ohne Kommentare und Formatierung, Schlüsselwörter und Namen in Kleinbuchstaben, Literale und Parameter durch ? ersetzt. Abfragen aus Logs, pg_stat_statements und generiertes SQL, die sich nur in Konstanten unterscheiden, teilen einen Fingerabdruck. Listet optional die passenden Einträge von pg_stat_statements",
+		"batch_query":               "Führt mehrere unabhängige schreibgeschützte Abfragen in einem einzigen Roundtrip als Pipeline-Batch aus und gibt ihre Ergebnisse nach Index in der angegebenen Reihenfolge zurück. Eine fehlschlagende Abfrage bricht die folgenden ab, die als nicht ausgeführt gemeldet werden",
+		"execute_write":             "Führt eine INSERT-, UPDATE-, DELETE-, DDL- oder Wartungsanweisung aus, getrennt von query und dessen QUERY_POLICY. Erfordert confirm: true und max_rows, die höchste Zahl betroffener Zeilen; eine Anweisung, die mehr betrifft, wird zurückgerollt. Gibt die Zahl der betroffenen Zeilen und die RETURNING-Zeilen zurück. Erfordert ALLOW_WRITES",
+		"update_rows":               "Aktualisiert die Zeilen einer Tabelle, die eine verpflichtende strukturierte where-Klausel (Bedingungen aus Spalte/op/Wert) erfüllen, mit den Werten aus set. Ohne commit auf true wird das UPDATE nicht ausgeführt und nur die Zahl der passenden Zeilen gemeldet. Zum Festschreiben ist ALLOW_WRITES nötig",
+		"delete_rows":               "Löscht die Zeilen einer Tabelle, die eine verpflichtende strukturierte where-Klausel erfüllen, in zwei Schritten: ohne expected_count werden die passenden Zeilen gezählt und einige als Vorschau gezeigt; mit ihm werden sie gelöscht und zurückgerollt, sofern nicht genau so viele Zeilen gelöscht wurden. Zum Löschen ist ALLOW_WRITES nötig",
+		"dump_schema":               "Erzeugt reines Schema-SQL für ein Schema (Typen, Tabellen, Constraints, Indizes, Views, Funktionen) oder ausgewählte Tabellen, aus den Katalogen rekonstruiert oder mit pg_dump, wenn use_pg_dump gesetzt und es installiert ist. Wird bis 1 MiB direkt zurückgegeben oder in output_path in EXPORT_DIR geschrieben",
+		"backup_table":              "Kopiert die Daten einer Tabelle mit COPY im Format binary oder csv in eine gzip-komprimierte Datei, als Sicherungskopie vor einer riskanten Änderung. Die Datei wird nach output_path in EXPORT_DIR geschrieben und vermerkt Tabelle und Spalten für restore_table. Erfordert ALLOW_WRITES. Sendet Fortschrittsbenachrichtigungen, wenn der Client sie anfordert",
+		"restore_table":             "Stellt eine backup_table-Datei in der Tabelle, aus der sie stammt, oder einer anderen Tabelle mit denselben Spalten in einer Transaktion wieder her, optional nach vorherigem Leeren. input_path ist relativ zu EXPORT_DIR. Erfordert ALLOW_WRITES. Sendet Fortschrittsbenachrichtigungen, wenn der Client sie anfordert",
+		"clone_database":            "Kopiert die Datenbank mit CREATE DATABASE ... TEMPLATE, wozu keine anderen Sitzungen mit ihr verbunden sein dürfen, und führt die späteren Tool-Aufrufe dieser Sitzung auf der Kopie aus, damit destruktive Experimente in einer Wegwerf-Datenbank stattfinden. Mit REQUIRE_APPROVAL wartet die Kopie auf Freigabe, nach der Ausführung erneut aufrufen, um sich zu verbinden. Erfordert ALLOW_WRITES",
+		"drop_clone":                "Löscht eine mit clone_database erstellte Datenbank und leitet die Sitzungen, die sie verwendeten, zur ursprünglichen Datenbank zurück. Mit REQUIRE_APPROVAL wird das DROP DATABASE zur Genehmigung eingereiht. Erfordert ALLOW_WRITES",
+		"anonymize_table":           "Überschreibt sensible Textspalten (die angegebenen oder die, die COLUMN_POLICY_FILE als maskiert oder Freitext markiert) mit den deterministischen Platzhaltern von export_fixture, direkt oder in eine neue target_table. Gleiche Werte erhalten in allen Tabellen gleiche Platzhalter und Schlüsselspalten werden nie überschrieben, sodass Referenzen weiter funktionieren. Ohne commit auf true wird nichts geschrieben und nur die Zahl der zu überschreibenden Zeilen gemeldet. Das Festschreiben erfordert ALLOW_WRITES",
+		"set_comment":               "Speichert mit COMMENT ON eine Beschreibung einer Tabelle, View oder Spalte in der Datenbank, wo get_table_schema und list_tables sie anzeigen. Ein leerer Kommentar entfernt sie. Erfordert ALLOW_WRITES",
+		"describe_table":            "Beschreibt eine Tabelle in einem Aufruf: Spalten, Constraints, Indizes, Trigger, Row-Level-Security und ihre Policies, Größen, Zeilenschätzung und Kommentare. Verwende es, statt get_table_schema, get_table_constraints und get_table_indexes einzeln aufzurufen",
+		"search_data":               "Sucht einen literalen Wert in allen text-, varchar- und JSON-Spalten einer Tabelle, einer Tabellenliste oder eines ganzen Schemas und gibt die passenden Zeilen und die Spalten zurück, in denen der Wert gefunden wurde. Begrenzt die Zeilen pro Tabelle und kann große Tabellen stichprobenartig durchsuchen",
+		"get_dependencies":          "Listet auf, wovon eine Tabelle, View, Sequenz, Funktion oder ein Typ abhängt und was davon abhängt (Views, Fremdschlüssel, Defaults, Trigger, Funktionen), über pg_depend. Verwende es, bevor du DROP oder ALTER vorschlägst, um die Auswirkungen zu sehen und ob ein Löschen CASCADE benötigt",
+		"validate_constraints":      "Findet die Zeilen, die einen Constraint verletzen, bevor er hinzugefügt wird: gib einen vorgeschlagenen unique-, foreign_key-, check- oder not_null-Constraint an und erhalte Anzahl und Stichprobe der verletzenden Zeilen. Ohne ihn werden die NOT VALID-Constraints der Tabelle geprüft oder die eines Schemas aufgelistet. Gibt die ALTER TABLE-Anweisung zurück, wenn nichts ihn verletzt",
+		"run_data_checks":           "Wertet Datenqualitätsregeln aus und meldet pro Regel Erfolg oder Fehlschlag: not_null mit erlaubtem NULL-Anteil, unique, range, Regex-Format und Verweise auf eine andere Tabelle. Die Regeln stammen aus DATA_CHECKS_FILE oder werden direkt übergeben. Jede Regel meldet ihre fehlschlagenden und geprüften Zeilen",
+		"column_cardinality":        "Meldet die unterschiedlichen Werte, den NULL-Anteil und die häufigsten Werte der Spalten einer Tabelle, um Indexkandidaten und Join-Strategien zu beurteilen. Tabellen bis 100000 Zeilen werden exakt gezählt, größere werden anhand der Planer-Statistiken oder einer Seitenstichprobe beschrieben",
+		"column_distribution":       "Zeigt, was der Planer aus pg_stats über die Verteilung einer Spalte weiß: Histogrammgrenzen, häufigste Werte mit ihren Häufigkeiten, NULL-Anteil, Schätzung der unterschiedlichen Werte und physische Korrelation. Zählt optional ein Histogramm mit gleich breiten Klassen für eine numerische, Datums- oder Zeitspalte aus ihren Zeilen. Verwende es, um die Schiefe hinter einer schlechten Zeilenschätzung zu verstehen",
+		"run_analyze":               "Führt ANALYZE auf Tabellen aus, um veraltete Planer-Statistiken zu erneuern, und meldet Zeilenschätzungen und Zeitpunkt der letzten Analyse davor und danach. Mit probe_query wird zusätzlich deren geschätzter Plan davor und danach verglichen. Erfordert ALLOW_WRITES",
+		"run_vacuum":                "Führt VACUUM auf Tabellen aus, optional FULL, FREEZE oder mit ANALYZE, verfolgt dabei pg_stat_progress_vacuum und meldet tote Zeilen und Größen davor und danach. Erfordert ALLOW_WRITES",
+		"run_reindex":               "Baut einen Index oder alle Indizes einer Tabelle neu auf, optional CONCURRENTLY, damit Schreibzugriffe nicht blockiert werden, verfolgt dabei pg_stat_progress_create_index und meldet die Indexgrößen davor und danach. Erfordert ALLOW_WRITES",
+		"storage_info":              "Zeigt, wie eine Tabelle gespeichert ist: Heap-, TOAST- und Indexgrößen, Speicherstrategie (plain, main, external, extended) und Komprimierung jeder Spalte sowie fillfactor und weitere reloptions der Tabelle, ihrer TOAST-Tabelle und ihrer Indizes, mit dem Anteil der HOT-Updates. Zur Diagnose von Bloat bei update-lastigen Tabellen",
+		"list_tablespaces":          "Listet Tablespaces mit Speicherort, Größe und Kostenoptionen sowie den größten Tabellen, materialisierten Sichten und Indizes der aktuellen Datenbank in jedem davon. Für Umgebungen, die heiße und kalte Daten auf verschiedene Speicher verteilen",
+		"list_foreign_tables":       "Listet Foreign-Data-Wrapper-Server mit ihren Optionen, die Benutzerzuordnungen dafür (nur Optionsnamen, nie Passwörter) und die bereitgestellten Fremdtabellen mit ihren Optionen. get_table_schema beschreibt Fremdtabellen wie jede andere Tabelle, einschließlich der Spaltenoptionen",
+		"list_event_triggers":       "Listet Event-Trigger, die bei DDL wie CREATE, ALTER und DROP statt bei Zeilen ausgelöst werden, mit Ereignis, Befehls-Tags, Funktion und dem, was die Funktion tut. Prüfen Sie sie, wenn eine Schemaänderung abgelehnt wird oder sich unerwartet verhält",
+		"list_rules":                "Listet Rewrite-Regeln auf Tabellen und Sichten (nicht die, die Sichten implementieren), mit Ereignis, ob sie die Anweisung ersetzen (INSTEAD), Definition und dem, was ihre Aktionen tun. Prüfen Sie sie, wenn ein Schreibvorgang andere Zeilen oder Tabellen als erwartet betrifft oder gar keine",
+		"recent_errors":             "Meldet Deadlock- und Rollback-Zähler aus pg_stat_database und, wenn SERVER_LOG auf die Logdatei oder das Logverzeichnis des Servers zeigt, die letzten ERROR-, FATAL- und PANIC-Einträge darin als strukturierte Datensätze mit SQLSTATE, Detail, Hinweis und Anweisung, die neuesten zuerst. Liest die Formate stderr, csvlog und jsonlog",
+	},
+	"ja": {
+		"get_table_schema":          "テーブルのスキーマ情報（列、データ型、コメントなど）を取得します",
+		"query":                     "PostgreSQL データベースに対して SQL クエリを実行し、結果を JSON で返します。文は QUERY_POLICY のためにその文自体のテキストで分類されるため、関数が組み立てて実行する SQL は検出されません。ロールを切り替える文と、サーバー上のファイルやプログラムとの COPY は拒否されます",
+		"list_tables":               "指定したスキーマ（既定: public）のテーブルをコメントとともにすべて一覧表示します",
+		"get_table_constraints":     "テーブルのすべての制約（主キー、外部キー、一意、チェック）を取得します",
+		"get_table_indexes":         "テーブルのすべてのインデックスを、インデックスの種類と列を含めて取得します。キー列、INCLUDE 列、式を区別し、部分インデックスの条件、サイズ、無効なインデックスも示します",
+		"explain_analyze":           "クエリに対して EXPLAIN ANALYZE を実行し、実行計画とパフォーマンス指標を取得します。analyze、verbose、costs、buffers、timing、summary、generic_plan、settings、wal、memory と出力形式（text、json、xml、yaml）のオプション、および render=tree によるコンパクトなプランツリーに対応しています",
+		"estimate_row_count":        "プランナー統計（reltuples）から、スキーマ内の 1 つまたはすべてのテーブルの行数を高速に推定します。小さなテーブルや一度も解析されていないテーブルは正確に数えます",
+		"traverse_hierarchy":        "自己参照テーブル（組織図、カテゴリ、友人関係）をルートキーから再帰 CTE でたどります。到達可能なすべての行を深さとパス付きで返し、循環を防止します",
+		"find_row_path":             "異なるテーブルの 2 行が外部キーでどのようにつながっているかを調べます。結合経路の候補（短い順）と、それぞれをつなぐ行を返します",
+		"list_sequences":            "スキーマ内のシーケンスを、現在値、最大値、所有列、消費率とともに一覧表示します。オーバーフローに近づいている serial/identity 列（特に int4）を警告します",
+		"infer_joins":               "テーブルの集合について外部キーによる最短の結合経路を計算し、別名付きのすぐに使える FROM/JOIN 句、必要な中間テーブル、曖昧な代替経路を返します",
+		"export_fixture":            "テーブルの一部をテスト用の自己完結した SQL フィクスチャとしてエクスポートします: CREATE TABLE 文、自由記述が匿名化された参照整合性のある行サンプル、インデックス、外部キー、シーケンスのリセット",
+		"list_materialized_views":   "スキーマ内のマテリアライズドビューを、サイズ、データ投入済みかどうか、定義、並行リフレッシュの可否とともに一覧表示します",
+		"refresh_materialized_view": "マテリアライズドビューをリフレッシュします。読み取りをブロックしないよう CONCURRENTLY も指定できます。書き込みが有効な場合（ALLOW_WRITES=true）のみ利用できます",
+		"view_dependencies":         "テーブルまたはビューに（再帰的に）依存するすべてのビューとマテリアライズドビューを、それぞれが使う列とともに返します。スキーマ変更の影響範囲の確認に使います",
+		"list_types":                "スキーマ内のユーザー定義の列挙型とラベル、複合型と属性、ドメインと基本型・チェック、範囲型を一覧表示します。get_table_schema が USER-DEFINED と報告する列の解決に使います",
+		"get_partitions":            "パーティションテーブルについて、パーティション方式、キー列、各子パーティション（サブパーティションを含む）の境界・推定行数・サイズ、既定パーティションがないテーブルを返します",
+		"export_session":            "このセッションで行ったすべての記録をエクスポートします: 実行したクエリと結果の要約、取得したプラン、適用した変更。markdown または JSON で返すか、ファイルに書き出します",
+		"list_pending_changes":      "人による承認を待っている書き込み操作（REQUIRE_APPROVAL=true の場合）を、文と状態とともに一覧表示します",
+		"approve_change":            "キューに入った書き込み操作を実行します。Webhook またはサーバーログで承認者に送られた承認トークンが必要です",
+		"reject_change":             "キューに入った書き込み操作を却下し、実行されないようにします",
+		"get_usage":                 "現在の MCP セッションの累積データベース時間、読み取り行数、返却バイト数を合計とツール別に報告します。all_sessions を指定するとサーバー上のすべてのセッションを報告します",
+		"pool_stats":                "コネクションプールの統計（使用中・アイドル・最大接続数、取得回数、待機時間）と有効なプール設定を表示します",
+		"demonstrate_anomaly":       "分離レベルごとの並行実行の異常（lost_update、non_repeatable_read、phantom_read、write_skew）を、サンドボックススキーマの作業用テーブル上で 2 つのセッションを使って再現します。各セッションの全手順と異常が発生したかを返します（ALLOW_WRITES=true が必要）",
+		"meta_command":              "psql 形式のメタコマンドを実行します: \\dt、\\dv、\\dm、\\di、\\ds、\\dn、\\df、\\l はオブジェクトを一覧表示し（public.user* のようなパターンも指定可）、\\d 名前 はリレーションを説明し、末尾の + でサイズと説明を追加します",
+		"set_session_parameter":     "このセッションの以降の query と explain_analyze 呼び出しに対してプランナーやリソースのパラメータ（work_mem、enable_seqscan、random_page_cost、statement_timeout など）を設定し、サーバー設定を変えずに実行計画を試せます。reset でサーバーの既定値に戻します",
+		"get_event_timeline":        "障害調査のため、最近の主なデータベースイベントを時系列で表示します: サーバー再起動、設定の再読み込み（再起動待ちの設定を含む）、autovacuum/autoanalyze と手動メンテナンスの実行、レプリカの接続、アーカイブの失敗、統計のリセット、このサーバー経由で適用された変更、およびチェックポイントのカウンタ",
+		"get_memory_usage":          "メモリと一時ファイルの使用量を実行中のクエリごとに示します: バックエンドごとの一時ファイル数とバイト数（pg_ls_tmpdir から）、work_mem と temp_file_limit、この接続の大きなメモリコンテキスト、さらに任意で他のバックエンドのメモリコンテキストをログに出力させます（PostgreSQL 14 以降）",
+		"verify_installation":       "このデプロイを検証し、チェックごとに合格・警告・失敗を報告します: 接続と使用中のロール、各ツールのカタログクエリが現在の権限で実行できるか、オプションの拡張機能、query ツールが書き込みを拒否するか、書き込みツールが有効かどうか",
+		"get_connection_info":       "サーバーの接続先を表示します: ホスト、ポート、データベース、ユーザー、sslmode、接続が実際に TLS を使っているか。パスワードや接続文字列は含みません",
+		"diff_dataset":              "CSV または JSON のデータセット (スプレッドシートのエクスポートなど) をキー列でテーブルと比較します: テーブルにない行、テーブルにだけある行、値が異なる行を、値を列の型に変換してから比較します。テーブルを一致させる INSERT、UPDATE、DELETE 文をレビュー用に生成することもできます。何も実行はしません",
+		"get_activity_history":      "実行されていた内容を振り返ります: 指定時刻に最も近い pg_stat_activity のスナップショット (「14:32 に何が実行されていたか」)、または期間の要約 (平均アクティブセッション数、上位の待機イベントとクエリ、ブロックしているバックエンドと待機中のロック)。ACTIVITY_SAMPLE_INTERVAL が必要で、バックグラウンドでサンプリングし履歴をメモリに保持します",
+		"estimate_type_change":      "列の型変更を実行前に評価します: ALTER COLUMN ... TYPE がテーブルを書き換えるか、テーブルサイズと計測した読み取りスループットからの推定所要時間、再構築されるインデックス、失敗の原因となるビューや不足しているキャスト、関係する外部キー、アプリケーションが気付く変換のリスク (オーバーフロー、丸め、パディング、タイムゾーン)。何も変更しません",
+		"vector_index_info":         "pgvector の ivfflat / HNSW インデックスを調べます: パラメータ (lists、m、ef_construction)、サイズ、演算子クラス、インデックスを使うために検索に必要な ORDER BY、推奨される ivfflat.probes と hnsw.ef_search。推奨値をセッションの以降の query 呼び出しに適用することもできます",
+		"spatial_info":              "PostGIS の geometry / geography 列を、ジオメトリ型、SRID、次元数、およびそれらに対する空間インデックス (GiST、SP-GiST、BRIN) とともに一覧表示します。query ツールはこれらの列を GeoJSON として、geometry_format を指定すると WKT として返します",
+		"logical_replication_info":  "レプリケーションを調べます: 保持している WAL 量付きのレプリケーションスロット (WAL の増加を引き起こしている非アクティブなスロットを指摘)、パブリケーションとそのテーブル、サブスクリプションと適用ワーカーの状態",
+		"check_plan_regressions":    "SAVED_QUERIES_FILE のクエリの現在の EXPLAIN プランを記録済みのベースラインと比較し、使われなくなったインデックス、新たなシーケンシャルスキャン、推定コストの急増といった劣化を報告します。プランは推定のみで、何も実行しません。現在のプランを新しいベースラインとして記録することもできます",
+		"suggest_indexes":           "不足しているインデックスを、根拠付きの CREATE INDEX 文として順位付けして提案します: 列にインデックスのない外部キー、主にシーケンシャルスキャンで読まれるテーブル、オプションで pg_stat_statements の最も高コストなエントリが絞り込みに使う列。何も作成しません",
+		"compare_plans":             "クエリの2つのバリアント、または異なるセッション設定での同じクエリに対して EXPLAIN を実行し、プランの差分を返します: 構造の変化、片方のプランにしかないノード、コストと実行時間、遅くなったノードと速くなったノード。どちらもロールバックされるトランザクションで実行されます",
+		"save_plan_baseline":        "クエリの現在の EXPLAIN プランを、そのフィンガープリントのベースラインとして PLAN_STORE_FILE に記録します。定数、書式、コメントだけが異なるクエリは同じフィンガープリントになります。プランは推定のみで、何も実行しません",
+		"check_plan_regression":     "クエリ、保存済みのフィンガープリント、または PLAN_STORE_FILE のすべてのベースラインについて、現在の EXPLAIN プランを記録済みのベースラインと比較し、使われなくなったインデックス、新たなシーケンシャルスキャン、推定コストの急増といった劣化を報告します。プランは推定のみで、何も実行しません",
+		"recent_slow_queries":       "バックグラウンドの pg_stat_activity サンプラーが SLOW_QUERY_THRESHOLD より長く実行中と記録した最近のクエリを、長い順に、開始時刻、検出時刻、サンプリング時の待機イベントとともに一覧表示します。pg_stat_statements なしで動作します。ACTIVITY_SAMPLE_INTERVAL が必要です",
+		"normalize_query":           "SQL 文を正規形とフィンガープリントに正規化します: コメントと書式を除き、キーワードと名前を小文字にし、リテラルとパラメータを ? に置き換えます。ログ、pg_stat_statements、生成された SQL のうち定数だけが異なるクエリは同じフィンガープリントになります。一致する pg_stat_statements のエントリを一覧表示することもできます",
+		"batch_query":               "複数の独立した読み取り専用クエリをパイプライン化したバッチで 1 回の往復で実行し、結果を指定順にインデックスごとに返します。失敗したクエリ以降のクエリは中断され、未実行として報告されます",
+		"execute_write":             "INSERT、UPDATE、DELETE、DDL、またはメンテナンス文を query とその QUERY_POLICY とは別に実行します。confirm: true と、影響を与えてよい最大行数 max_rows が必要です。それを超える行に影響した文はロールバックされます。影響行数と RETURNING の行を返します。ALLOW_WRITES が必要です",
+		"update_rows":               "必須の構造化 where 句（列/op/値の条件）に一致するテーブルの行を set の値で更新します。commit が true でない限り UPDATE は実行せず、一致する行数だけを報告します。コミットには ALLOW_WRITES が必要です",
+		"delete_rows":               "必須の構造化 where 句に一致するテーブルの行を 2 段階で削除します。expected_count なしでは一致する行を数えて一部をプレビューし、指定すると削除して、ちょうどその行数が削除されなかった場合はロールバックします。削除には ALLOW_WRITES が必要です",
+		"dump_schema":               "スキーマ（型、テーブル、制約、インデックス、ビュー、関数）または選択したテーブルのスキーマのみの SQL を、カタログから再構築するか、use_pg_dump が設定されインストールされていれば pg_dump で生成します。1 MiB までインラインで返すか、EXPORT_DIR 内の output_path に書き込みます",
+		"backup_table":              "リスクのある変更の前の安全なコピーとして、テーブルのデータを COPY で binary または csv 形式の gzip 圧縮ファイルにコピーします。ファイルは EXPORT_DIR 内の output_path に書き込まれ、restore_table 用にテーブルと列が記録されます。ALLOW_WRITES が必要です。クライアントが要求すると進捗通知を送信します",
+		"restore_table":             "backup_table のファイルを、取得元のテーブルまたは同じ列を持つ別のテーブルに 1 つのトランザクションで復元し、必要に応じて先に空にします。input_path は EXPORT_DIR からの相対パスです。ALLOW_WRITES が必要です。クライアントが要求すると進捗通知を送信します",
+		"clone_database":            "CREATE DATABASE ... TEMPLATE でデータベースをコピーし（他のセッションが接続していない必要があります）、このセッションの以降のツール呼び出しをコピーに対して実行し、破壊的な実験を使い捨てのデータベースで行えるようにします。REQUIRE_APPROVAL ではコピーは承認待ちになり、実行後に再度呼び出すと接続します。ALLOW_WRITES が必要です",
+		"drop_clone":                "clone_database で作成したデータベースを削除し、それを使っていたセッションを元のデータベースに戻します。REQUIRE_APPROVAL では DROP DATABASE を承認待ちのキューに入れます。ALLOW_WRITES が必要です",
+		"anonymize_table":           "機密のテキスト列（指定した列、または COLUMN_POLICY_FILE がマスクまたは自由テキストとする列）を export_fixture と同じ決定的な偽の値で、その場でまたは新しい target_table に書き換えます。同じ値はすべてのテーブルで同じ偽の値になり、キー列は書き換えないため参照は保たれます。commit が true でない限り何も書き込まず、書き換える行数だけを報告します。コミットには ALLOW_WRITES が必要です",
+		"set_comment":               "COMMENT ON でテーブル、ビュー、列の説明をデータベースに保存し、get_table_schema と list_tables で表示されるようにします。空のコメントは説明を削除します。ALLOW_WRITES が必要です",
+		"describe_table":            "1 回の呼び出しでテーブルを説明します: 列、制約、インデックス、トリガー、行レベルセキュリティとそのポリシー、サイズ、推定行数、コメント。get_table_schema、get_table_constraints、get_table_indexes を個別に呼び出す代わりに使用してください",
+		"search_data":               "テーブル、テーブルのリスト、またはスキーマ全体のすべての text、varchar、JSON 列でリテラル値を検索し、一致した行と値が見つかった列を返します。テーブルごとの行数を制限し、大きなテーブルはサンプリングできます",
+		"get_dependencies":          "テーブル、ビュー、シーケンス、関数、型が何に依存し、何がそれに依存しているか (ビュー、外部キー、デフォルト値、トリガー、関数) を pg_depend から一覧表示します。DROP や ALTER を提案する前に、影響と削除に CASCADE が必要かどうかを確認するために使用してください",
+		"validate_constraints":      "制約を追加する前に違反する行を見つけます: 提案する unique、foreign_key、check、not_null 制約を指定すると、違反する行の数とサンプルを返します。指定しない場合は、テーブルの NOT VALID 制約を検査するか、スキーマの NOT VALID 制約を一覧表示します。違反がなければ ALTER TABLE 文を返します",
+		"run_data_checks":           "データ品質ルールを評価し、ルールごとに合否を報告します: 許容 NULL 比率付きの not_null、unique、range、正規表現による形式、別テーブルへの参照。ルールは DATA_CHECKS_FILE から読み込むか、直接渡します。各ルールは失敗した行数と検査した行数を報告します",
+		"column_cardinality":        "テーブルの列ごとに異なる値の数、NULL の割合、最頻値を報告し、インデックス候補や結合戦略の判断に役立てます。100000 行までのテーブルは正確に数え、それより大きいテーブルはプランナー統計またはページのサンプルから求めます",
+		"column_distribution":       "pg_stats からプランナーが把握している列の分布を表示します: ヒストグラムの境界、最頻値とその頻度、NULL の割合、異なる値の推定数、物理的な相関。数値、日付、時刻の列については、行から等幅ヒストグラムを集計することもできます。行数推定の誤りの背後にある偏りを考えるために使用してください",
+		"run_analyze":               "テーブルに ANALYZE を実行して古くなったプランナー統計を更新し、実行前後の推定行数と最終分析時刻を報告します。probe_query を指定すると、その推定プランも実行前後で比較します。ALLOW_WRITES が必要です",
+		"run_vacuum":                "テーブルに VACUUM を実行します。FULL、FREEZE、ANALYZE 付きも指定できます。実行中は pg_stat_progress_vacuum を追跡し、実行前後のデッド行数とサイズを報告します。ALLOW_WRITES が必要です",
+		"run_reindex":               "インデックス、またはテーブルのすべてのインデックスを再構築します。書き込みをブロックしないよう CONCURRENTLY も指定できます。実行中は pg_stat_progress_create_index を追跡し、実行前後のインデックスサイズを報告します。ALLOW_WRITES が必要です",
+		"storage_info":              "テーブルの格納方法を表示します。ヒープ、TOAST、インデックスのサイズ、各列のストレージ戦略（plain、main、external、extended）と圧縮方式、テーブル・TOAST テーブル・インデックスの fillfactor などの reloptions、HOT 更新の割合を報告します。更新の多いテーブルの肥大化の診断に使います",
+		"list_tablespaces":          "テーブルスペースを場所、サイズ、コストオプションとともに一覧表示し、それぞれに格納されている現在のデータベースの最大のテーブル、マテリアライズドビュー、インデックスを示します。ホットデータとコールドデータを別のストレージに分けている環境で使います",
+		"list_foreign_tables":       "外部データラッパーのサーバーとそのオプション、ユーザーマッピング（オプション名のみ。パスワードは表示しません）、各サーバーが提供する外部テーブルとそのオプションを一覧表示します。get_table_schema は外部テーブルも列オプションを含めて他のテーブルと同様に説明します",
+		"list_event_triggers":       "行ではなく CREATE、ALTER、DROP などの DDL で実行されるイベントトリガーを、イベント、コマンドタグ、関数、その関数の処理内容とともに一覧表示します。スキーマ変更が拒否されたり予期しない動作をしたりするときに確認します",
+		"list_rules":                "テーブルとビューの書き換えルール（ビューを実装するものを除く）を、イベント、文を置き換えるか（INSTEAD）、定義、アクションの処理内容とともに一覧表示します。書き込みが想定外の行やテーブルに影響したり、何も影響しなかったりするときに確認します",
+		"recent_errors":             "pg_stat_database のデッドロック数とロールバック数を報告し、SERVER_LOG がサーバーのログファイルまたはログディレクトリを指している場合は、そこに記録された最近の ERROR、FATAL、PANIC を SQLSTATE、詳細、ヒント、文を含む構造化レコードとして新しい順に返します。stderr、csvlog、jsonlog 形式を読み取れます",
+	},
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := map[string]string{
+		"":            "en",
+		"de_DE.UTF-8": "de",
+		"ja-JP":       "ja",
+		"ES":          "es",
+		"fr_FR":       "en",
+	}
+	for value, expected := range tests {
+		if locale := parseLocale(value); locale != expected {
+			t.Errorf("parseLocale(%q) = %q, expected %q", value, locale, expected)
+		}
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	testServer.config.Locale = "de"
+	result, _, _ := testServer.returnWritesDisabled("refresh_materialized_view")
+	text := result.Content[0].(*mcp.TextContent).Text
+	if text != "refresh_materialized_view verändert die Datenbank und ist deaktiviert. Setzen Sie ALLOW_WRITES=true, um es zu aktivieren" {
+		t.Errorf("Expected German guard message, got %q", text)
+	}
+
+	// untranslated messages fall back to English
+	if message := testServer.localize("no translation for this"); message != "no translation for this" {
+		t.Errorf("Expected English fallback, got %q", message)
+	}
+
+	// every translated format has to keep the verbs of the original
+	for locale, messages := range messageTranslations {
+		for message, translated := range messages {
+			if verbs(message) != verbs(translated) {
+				t.Errorf("%s translation of %q changes the format verbs", locale, message)
+			}
+		}
+	}
+}
+
+func verbs(format string) string {
+	var found []byte
+	for i := 0; i+1 < len(format); i++ {
+		if format[i] == '%' {
+			found = append(found, format[i+1])
+		}
+	}
+	return string(found)
+}
+
+func TestLocaleMiddleware(t *testing.T) {
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.Locale = "ja"
+
+	registered := &mcp.Tool{Name: "query", Description: "Execute a SQL query"}
+	custom := &mcp.Tool{Name: "custom_tool", Description: "Not translated"}
+	next := func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		return &mcp.ListToolsResult{Tools: []*mcp.Tool{registered, custom}}, nil
+	}
+
+	result, err := testServer.localeMiddleware(next)(context.Background(), "tools/list", nil)
+	if err != nil {
+		t.Fatalf("localeMiddleware failed: %v", err)
+	}
+
+	tools := result.(*mcp.ListToolsResult).Tools
+	if tools[0].Description != toolDescriptions["ja"]["query"] {
+		t.Errorf("Expected Japanese description, got %q", tools[0].Description)
+	}
+	if tools[1].Description != "Not translated" {
+		t.Errorf("Expected tools without a translation to keep their description, got %q", tools[1].Description)
+	}
+	if registered.Description != "Execute a SQL query" {
+		t.Error("Expected the registered tool definition to stay in English")
+	}
+}
@@ -2,77 +2,427 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"os"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func main() {
-	connStr := os.Getenv("DATABASE_URL")
-	if connStr == "" {
-		connStr = os.Getenv("POSTGRES_URL")
+	log.SetOutput(redactingWriter{os.Stderr})
+
+	command, args, err := splitCommand(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		printUsage(os.Stderr)
+		os.Exit(2)
 	}
-	if connStr == "" {
-		log.Fatal("DATABASE_URL or POSTGRES_URL environment variable must be set")
+
+	switch command {
+	case "check-connection":
+		err = runCheckConnection(args, os.Stdout)
+	case "list-tools":
+		err = runListTools(args, os.Stdout)
+	default:
+		err = runServe(args)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runServe(args []string) error {
+	fs, apply := newFlagSet("serve")
+	selfTest := fs.Bool("self-test", false, "Check the tools' catalog queries against the connected server, report and exit")
+	fs.Parse(args)
+	if err := apply(); err != nil {
+		return err
 	}
 
-	config, err := pgxpool.ParseConfig(connStr)
+	config, poolConfig, err := databaseConfig()
 	if err != nil {
-		log.Fatalf("Failed to parse database URL: %v", err)
+		return err
 	}
 
 	ctx := context.Background()
-	pool, err = pgxpool.NewWithConfig(ctx, config)
+	state, err := connectProfiles(ctx, config, poolConfig)
 	if err != nil {
-		log.Fatalf("Failed to create connection pool: %v", err)
+		return fmt.Errorf("failed to start: %v", err)
 	}
-	defer pool.Close()
+	defer state.Close()
 
-	// try to connect, otherwise fail
-	if err := pool.Ping(ctx); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
-		return
+	// catch catalog queries the server doesn't support now instead of at first use
+	var failures []selfTestFailure
+	if err := state.reconnectingError(); err != nil {
+		if *selfTest {
+			return err
+		}
+		log.Println("LAZY_CONNECT: the database is unavailable, serving without the self-test")
+	} else if failures, err = state.runSelfTest(ctx); err != nil {
+		return fmt.Errorf("self-test failed to run: %v", err)
+	}
+	if *selfTest {
+		for _, failure := range failures {
+			fmt.Printf("FAIL %s: %s\n    %s\n", failure.Tool, failure.Error, failure.Query)
+		}
+		if len(failures) > 0 {
+			return fmt.Errorf("%d catalog queries are not supported by this server", len(failures))
+		}
+		fmt.Println("All catalog queries are supported by this server")
+		return nil
+	}
+	for _, failure := range failures {
+		log.Printf("Tool %s may not work on this server: %s", failure.Tool, failure.Error)
 	}
 
-	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "postgres-mcp",
-		Version: "v1.0.0",
-	}, nil)
+	if config.DryRun {
+		log.Println("DRY_RUN is enabled, all writes will be rolled back")
+	}
 
-	// tools that are available
-	mcp.AddTool(server, &mcp.Tool{
+	server := state.newMCPServer()
+	state.addTools(server)
+	addPrompts(server)
+
+	err = state.serve(ctx, server)
+	state.logUsageTotals()
+	return err
+}
+
+// addTools registers every tool, bound to this state.
+func (s *serverState) addTools(server *mcp.Server) {
+	addTool(s, server, &mcp.Tool{
 		Name:        "get_table_schema",
-		Description: "Get the schema information (columns, data types, etc.) for a specific table",
-	}, GetTableSchema)
+		Description: "Get the schema information (columns, data types, comments, etc.) for a specific table",
+	}, withMetadataCache("get_table_schema", (*serverState).GetTableSchema))
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(s, server, &mcp.Tool{
 		Name:        "query",
-		Description: "Execute a SQL query against the PostgreSQL database and return results as JSON",
-	}, ExecuteQuery)
+		Description: "Execute a SQL query against the PostgreSQL database and return results as JSON. Statements are classified for QUERY_POLICY by their own text, so SQL that functions build and run is not seen. Statements that switch the role and COPY to or from server files or programs are refused",
+	}, (*serverState).ExecuteQuery)
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(s, server, &mcp.Tool{
 		Name:        "list_tables",
-		Description: "List all tables in the specified schema (default: public)",
-	}, ListTables)
+		Description: "List all tables in the specified schema (default: public), with their comments",
+	}, withMetadataCache("list_tables", (*serverState).ListTables))
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(s, server, &mcp.Tool{
 		Name:        "get_table_constraints",
 		Description: "Get all constraints (primary key, foreign key, unique, check) for a specific table",
-	}, GetTableConstraints)
+	}, withMetadataCache("get_table_constraints", (*serverState).GetTableConstraints))
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(s, server, &mcp.Tool{
 		Name:        "get_table_indexes",
-		Description: "Get all indexes for a specific table including index type and columns",
-	}, GetTableIndexes)
+		Description: "Get all indexes for a specific table including index type and columns, telling key columns from INCLUDE columns and expressions, with partial index predicates, sizes and invalid indexes",
+	}, withMetadataCache("get_table_indexes", (*serverState).GetTableIndexes))
 
-	mcp.AddTool(server, &mcp.Tool{
+	addTool(s, server, &mcp.Tool{
 		Name:        "explain_analyze",
-		Description: "Run EXPLAIN ANALYZE on a query to get the query execution plan and performance metrics. Supports options for analyze, verbose, costs, buffers, timing, summary, and output format (text, json, xml, yaml)",
-	}, ExplainAnalyze)
+		Description: "Run EXPLAIN ANALYZE on a query to get the query execution plan and performance metrics. Supports options for analyze, verbose, costs, buffers, timing, summary, generic_plan, settings, wal, memory, and output format (text, json, xml, yaml), or a compact plan tree with render=tree",
+	}, (*serverState).ExplainAnalyze)
 
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Fatal(err)
-	}
+	addTool(s, server, &mcp.Tool{
+		Name:        "estimate_row_count",
+		Description: "Get fast row count estimates from planner statistics (reltuples) for one or all tables in a schema. Small or never-analyzed tables are counted exactly",
+	}, (*serverState).EstimateRowCount)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "traverse_hierarchy",
+		Description: "Traverse a self-referencing table (org charts, categories, friendships) from a root key using a recursive CTE. Returns every reachable row with its depth and path, with cycle protection",
+	}, (*serverState).TraverseHierarchy)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "find_row_path",
+		Description: "Find how two rows in different tables are connected via foreign keys. Returns candidate join chains (shortest first) along with the connecting rows for each chain",
+	}, (*serverState).FindRowPath)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_sequences",
+		Description: "List sequences in a schema with current value, max value, owning column and percentage consumed. Flags serial/identity columns approaching overflow (especially int4)",
+	}, (*serverState).ListSequences)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "infer_joins",
+		Description: "Given a set of tables, compute the shortest foreign key join paths connecting them and return a ready-to-use FROM/JOIN clause with aliases, any intermediate tables required, and ambiguous alternatives",
+	}, (*serverState).InferJoins)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "export_fixture",
+		Description: "Export a subset of tables as a self-contained SQL fixture for test suites: CREATE TABLE statements, a referentially consistent sample of rows with free-text values anonymized, indexes, foreign keys and sequence resets",
+	}, (*serverState).ExportFixture)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_materialized_views",
+		Description: "List materialized views in a schema with their size, populated flag, definition and whether they can be refreshed concurrently",
+	}, (*serverState).ListMaterializedViews)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "refresh_materialized_view",
+		Description: "Refresh a materialized view, optionally CONCURRENTLY so readers are not blocked. Only available when writes are enabled (ALLOW_WRITES=true)",
+	}, (*serverState).RefreshMaterializedView)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "view_dependencies",
+		Description: "Given a table or view, return all views and materialized views that depend on it (recursively), with the columns each one uses. Use it to assess the blast radius of a schema change",
+	}, (*serverState).ViewDependencies)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_types",
+		Description: "List user-defined enums with their labels, composite types with their attributes, domains with their base type and checks, and range types in a schema. Use it to resolve columns that get_table_schema reports as USER-DEFINED",
+	}, (*serverState).ListTypes)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_partitions",
+		Description: "For a partitioned table, return the partitioning strategy, key columns, every child partition (including sub-partitions) with its bounds, row estimate and size, and which partitioned tables are missing a default partition",
+	}, (*serverState).GetPartitions)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "export_session",
+		Description: "Export a transcript of everything done in this session: queries run with result summaries, plans captured and changes applied. Returned as markdown or JSON, or written to a file",
+	}, (*serverState).ExportSession)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_pending_changes",
+		Description: "List write operations queued for human approval (when REQUIRE_APPROVAL=true), with their statements and status",
+	}, (*serverState).ListPendingChanges)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "approve_change",
+		Description: "Execute a queued write operation. Requires the approval token that was sent to the human approver via webhook or server log",
+	}, (*serverState).ApproveChange)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "reject_change",
+		Description: "Reject a queued write operation so it can never be executed",
+	}, (*serverState).RejectChange)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_usage",
+		Description: "Report cumulative database time, rows scanned and bytes returned for the current MCP session, in total and per tool. Set all_sessions to report every session on the server",
+	}, s.GetUsage)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "pool_stats",
+		Description: "Show connection pool statistics (acquired, idle and max connections, acquire counts and wait durations) and the pool settings in effect",
+	}, (*serverState).PoolStats)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "demonstrate_anomaly",
+		Description: "Demonstrate a concurrency anomaly (lost_update, non_repeatable_read, phantom_read, write_skew) under an isolation level, using two sessions on a scratch table in the sandbox schema. Returns every step each session ran and whether the anomaly occurred (requires ALLOW_WRITES=true)",
+	}, (*serverState).DemonstrateAnomaly)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "meta_command",
+		Description: "Run a psql-style meta-command: \\dt, \\dv, \\dm, \\di, \\ds, \\dn, \\df and \\l list objects (with an optional pattern such as public.user*), \\d name describes a relation, and a trailing + adds sizes and descriptions",
+	}, (*serverState).MetaCommand)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "set_session_parameter",
+		Description: "Set a planner or resource parameter (work_mem, enable_seqscan, random_page_cost, statement_timeout, ...) for subsequent query and explain_analyze calls in this session, to experiment with plans without changing server configuration. Set reset to go back to the server default",
+	}, (*serverState).SetSessionParameter)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_event_timeline",
+		Description: "Chronological timeline of recent notable database events for incident review: server restarts, configuration reloads (with settings pending restart), autovacuum/autoanalyze and manual maintenance runs, replica connections, archive failures, statistics resets and changes applied through this server, plus checkpoint counters",
+	}, (*serverState).GetEventTimeline)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_memory_usage",
+		Description: "Attribute memory and temporary file usage to active queries: temp files and bytes per backend (from pg_ls_tmpdir), work_mem and temp_file_limit, this connection's largest memory contexts, and optionally ask another backend to log its memory contexts (PostgreSQL 14+)",
+	}, (*serverState).GetMemoryUsage)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "verify_installation",
+		Description: "Validate this deployment and report pass/warn/fail per check: connectivity and the role in use, whether every tool's catalog queries run with the current privileges, optional extensions, that the query tool rejects writes, and whether write tools are enabled",
+	}, (*serverState).VerifyInstallation)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_connection_info",
+		Description: "Show where the server is connected: host, port, database, user, sslmode and whether the connection actually uses TLS. Never includes the password or connection string",
+	}, (*serverState).GetConnectionInfo)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "diff_dataset",
+		Description: "Compare a CSV or JSON dataset (such as a spreadsheet export) with a table on key columns: rows missing from the table, extra rows in the table and rows with changed values, with values cast to the column types before comparing. Optionally generates the INSERT, UPDATE and DELETE statements that reconcile the table, for review; nothing is executed",
+	}, (*serverState).DiffDataset)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_activity_history",
+		Description: "Look back at what was running: the pg_stat_activity snapshot closest to a time (\"what was running at 14:32\"), or a summary of a time range with average active sessions, top wait events and top queries, including blocking backends and awaited locks. Needs ACTIVITY_SAMPLE_INTERVAL, which samples in the background and keeps the history in memory",
+	}, (*serverState).GetActivityHistory)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "estimate_type_change",
+		Description: "Assess a proposed column type change before running it: whether ALTER COLUMN ... TYPE rewrites the table, the estimated rewrite time from the table size and measured read throughput, which indexes are rebuilt, views and missing casts that make it fail, foreign keys involved, and casting risks applications would notice (overflow, rounding, padding, time zones). Nothing is changed",
+	}, (*serverState).EstimateTypeChange)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "vector_index_info",
+		Description: "Inspect pgvector ivfflat and HNSW indexes: parameters (lists, m, ef_construction), size, operator class and the ORDER BY a search needs to use them, with recommended ivfflat.probes and hnsw.ef_search settings. Optionally applies the recommendations to later query calls of the session",
+	}, (*serverState).VectorIndexInfo)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "spatial_info",
+		Description: "List PostGIS geometry and geography columns with their geometry type, SRID and dimensions, and the spatial (GiST, SP-GiST, BRIN) indexes on them. The query tool returns these columns as GeoJSON, or WKT with geometry_format",
+	}, (*serverState).SpatialInfo)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "logical_replication_info",
+		Description: "Inspect replication: slots with the WAL they retain (flagging inactive slots pinning WAL growth), publications with their tables, and subscriptions with the state of their apply workers",
+	}, (*serverState).LogicalReplicationInfo)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "check_plan_regressions",
+		Description: "Compare the current EXPLAIN plans of the queries in SAVED_QUERIES_FILE with their recorded baselines and report regressions: indexes no longer used, new sequential scans and estimated cost jumps. Plans are estimated, nothing is executed. Optionally records the current plans as the new baselines",
+	}, (*serverState).CheckPlanRegressions)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "suggest_indexes",
+		Description: "Suggest missing indexes, ranked, as CREATE INDEX statements with their rationale: foreign keys without an index on their columns, tables mostly read by sequential scans and, optionally, the columns the most expensive pg_stat_statements entries filter on. Nothing is created",
+	}, (*serverState).SuggestIndexes)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "compare_plans",
+		Description: "EXPLAIN two variants of a query, or the same query under different session settings, and diff the plans: shape changes, nodes only in one plan, cost and timing, with the nodes that got slower or faster. Both run in rolled back transactions",
+	}, (*serverState).ComparePlans)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "save_plan_baseline",
+		Description: "Record the current EXPLAIN plan of a query as the baseline of its fingerprint in PLAN_STORE_FILE. Queries differing only in constants, formatting or comments share a fingerprint. The plan is estimated, nothing is executed",
+	}, (*serverState).SavePlanBaseline)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "check_plan_regression",
+		Description: "Compare the current EXPLAIN plan of a query, a stored fingerprint or every baseline in PLAN_STORE_FILE with the recorded baseline and report regressions: indexes no longer used, new sequential scans and estimated cost jumps. Plans are estimated, nothing is executed",
+	}, (*serverState).CheckPlanRegression)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "recent_slow_queries",
+		Description: "List recent queries seen running longer than SLOW_QUERY_THRESHOLD by the background pg_stat_activity sampler, the longest first, with when they started and were seen and the wait events they were sampled in. Works without pg_stat_statements. Needs ACTIVITY_SAMPLE_INTERVAL",
+	}, (*serverState).RecentSlowQueries)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "normalize_query",
+		Description: "Normalize a SQL statement to its canonical form and fingerprint: comments and formatting dropped, keywords and names lower-cased, literals and parameters replaced by ?. Queries from logs, pg_stat_statements and generated SQL that differ only in constants share a fingerprint. Optionally lists the matching pg_stat_statements entries",
+	}, (*serverState).NormalizeQuery)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "batch_query",
+		Description: "Run several independent read-only queries in one round trip through a pipelined batch and return their results by index, in the order given. A failing query aborts those after it, which are reported as not run",
+	}, (*serverState).BatchQuery)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "execute_write",
+		Description: "Run an INSERT, UPDATE, DELETE, DDL or maintenance statement, separately from query and its QUERY_POLICY. Requires confirm: true and max_rows, the most rows it may affect; a statement affecting more is rolled back. Returns the affected row count and RETURNING rows. Requires ALLOW_WRITES",
+	}, (*serverState).ExecuteWrite)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "update_rows",
+		Description: "Update the rows of a table matching a required structured where clause (column/op/value conditions) with the values of set. Without commit set to true the UPDATE doesn't run, only the matching rows are counted. Committing requires ALLOW_WRITES",
+	}, (*serverState).UpdateRows)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "delete_rows",
+		Description: "Delete the rows of a table matching a required structured where clause in two steps: without expected_count, count the matching rows and preview a few; with it, delete them, rolling back unless exactly that many rows were deleted. Deleting requires ALLOW_WRITES",
+	}, (*serverState).DeleteRows)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "dump_schema",
+		Description: "Produce schema-only SQL for a schema (types, tables, constraints, indexes, views, functions) or selected tables, rebuilt from the catalogs or with pg_dump when use_pg_dump is set and it is installed. Returned inline up to 1 MiB, or written to output_path in EXPORT_DIR",
+	}, (*serverState).DumpSchema)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "backup_table",
+		Description: "Copy a table's data to a gzip-compressed file with COPY, in binary or csv format, as a safety copy before a risky change. The file is written to output_path inside EXPORT_DIR and records the table and columns for restore_table. Requires ALLOW_WRITES. Sends progress notifications when the client asks for them",
+	}, (*serverState).BackupTable)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "restore_table",
+		Description: "Restore a backup_table file into the table it was taken from or another table with the same columns, in one transaction, optionally truncating it first. input_path is relative to EXPORT_DIR. Requires ALLOW_WRITES. Sends progress notifications when the client asks for them",
+	}, (*serverState).RestoreTable)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "clone_database",
+		Description: "Copy the database with CREATE DATABASE ... TEMPLATE, which needs no other sessions connected to it, and run this session's later tool calls against the copy, so destructive experiments happen on a throwaway database. With REQUIRE_APPROVAL the copy is queued for approval, call again once it ran to connect. Requires ALLOW_WRITES",
+	}, (*serverState).CloneDatabase)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "drop_clone",
+		Description: "Drop a database made by clone_database, sending the sessions that targeted it back to the original database. With REQUIRE_APPROVAL the DROP DATABASE is queued for approval. Requires ALLOW_WRITES",
+	}, (*serverState).DropClone)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "anonymize_table",
+		Description: "Rewrite sensitive text columns (those listed, or those COLUMN_POLICY_FILE marks as masked or free text) with the deterministic fakes export_fixture uses, in place or into a new target_table. Equal values get equal fakes across tables and key columns are never rewritten, so references keep working. Without commit set to true nothing is written, only the rows to rewrite are counted. Committing requires ALLOW_WRITES",
+	}, (*serverState).AnonymizeTable)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "set_comment",
+		Description: "Store a description of a table, view or column in the database with COMMENT ON, where get_table_schema and list_tables report it. An empty comment removes it. Requires ALLOW_WRITES",
+	}, (*serverState).SetComment)
+
+	addTool(s, server, &mcp.Tool{
+		Name:        "describe_table",
+		Description: "Describe a table in one call: columns, constraints, indexes, triggers, row-level security and its policies, sizes, row estimate and comments. Use it instead of calling get_table_schema, get_table_constraints and get_table_indexes one by one",
+	}, (*serverState).DescribeTable)
+	addTool(s, server, &mcp.Tool{
+		Name:        "search_data",
+		Description: "Search for a literal value in every text, varchar and JSON column of a table, a list of tables or a whole schema, returning the matching rows and the columns the value was found in. Limits rows per table and can sample large tables",
+	}, (*serverState).SearchData)
+	addTool(s, server, &mcp.Tool{
+		Name:        "get_dependencies",
+		Description: "List what a table, view, sequence, function or type depends on and what depends on it (views, foreign keys, defaults, triggers, functions), through pg_depend. Use it before suggesting DROP or ALTER to see the impact, and whether a drop needs CASCADE",
+	}, (*serverState).GetDependencies)
+	addTool(s, server, &mcp.Tool{
+		Name:        "validate_constraints",
+		Description: "Find the rows that break a constraint before adding it: give a proposed unique, foreign_key, check or not_null constraint and get the count and a sample of offending rows. Without one, checks the table's NOT VALID constraints, or lists those of a schema. Returns the ALTER TABLE statement when nothing violates it",
+	}, (*serverState).ValidateConstraints)
+	addTool(s, server, &mcp.Tool{
+		Name:        "run_data_checks",
+		Description: "Evaluate data quality rules and report pass or fail per rule: not_null with an allowed NULL ratio, unique, range, regex format and references to another table. Rules come from DATA_CHECKS_FILE or are passed inline. Each rule reports its failing and checked rows",
+	}, (*serverState).RunDataChecks)
+	addTool(s, server, &mcp.Tool{
+		Name:        "column_cardinality",
+		Description: "Report the distinct values, NULL fraction and most common values of a table's columns, to judge index candidates and join strategies. Tables up to 100000 rows are counted exactly, larger ones are described from planner statistics or a sample of pages",
+	}, (*serverState).ColumnCardinality)
+	addTool(s, server, &mcp.Tool{
+		Name:        "column_distribution",
+		Description: "Show what the planner knows about a column's distribution from pg_stats: histogram bounds, most common values with their frequencies, NULL fraction, distinct estimate and physical correlation. Optionally counts an equal-width histogram of a numeric, date or time column from its rows. Use it to reason about skew behind a bad row estimate",
+	}, (*serverState).ColumnDistribution)
+	addTool(s, server, &mcp.Tool{
+		Name:        "run_analyze",
+		Description: "Run ANALYZE on tables to refresh stale planner statistics, reporting the row estimates and last analyze times before and after. With a probe_query, also compares its estimated plan before and after. Requires ALLOW_WRITES",
+	}, (*serverState).RunAnalyze)
+	addTool(s, server, &mcp.Tool{
+		Name:        "run_vacuum",
+		Description: "Run VACUUM on tables, optionally FULL, FREEZE or with ANALYZE, following pg_stat_progress_vacuum while it runs and reporting dead rows and sizes before and after. Requires ALLOW_WRITES",
+	}, (*serverState).RunVacuum)
+	addTool(s, server, &mcp.Tool{
+		Name:        "run_reindex",
+		Description: "Rebuild an index, or every index of a table, optionally CONCURRENTLY so writes are not blocked, following pg_stat_progress_create_index while it runs and reporting index sizes before and after. Requires ALLOW_WRITES",
+	}, (*serverState).RunReindex)
+	addTool(s, server, &mcp.Tool{
+		Name:        "storage_info",
+		Description: "Show how a table is stored: heap, TOAST and index sizes, each column's storage strategy (plain, main, external, extended) and compression, and the fillfactor and other reloptions of the table, its TOAST table and its indexes, with the share of HOT updates. Use it to diagnose bloat on update-heavy tables",
+	}, (*serverState).StorageInfo)
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_tablespaces",
+		Description: "List tablespaces with their location, size and cost options, and the largest tables, materialized views and indexes of the current database stored in each. Use it where hot and cold data are split across storage",
+	}, (*serverState).ListTablespaces)
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_foreign_tables",
+		Description: "List foreign data wrapper servers with their options, the user mappings for them (option names only, never passwords) and the foreign tables they serve with their options. get_table_schema describes foreign tables like any other, including column options",
+	}, (*serverState).ListForeignTables)
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_event_triggers",
+		Description: "List event triggers, which run on DDL such as CREATE, ALTER and DROP rather than on rows, with their event, command tags, function and what the function does. Check them when a schema change is refused or behaves unexpectedly",
+	}, (*serverState).ListEventTriggers)
+	addTool(s, server, &mcp.Tool{
+		Name:        "list_rules",
+		Description: "List rewrite rules on tables and views (not the ones implementing views), with their event, whether they replace the statement (INSTEAD), their definition and what their actions do. Check them when a write affects other rows or tables than expected, or nothing at all",
+	}, (*serverState).ListRules)
+	addTool(s, server, &mcp.Tool{
+		Name:        "recent_errors",
+		Description: "Report deadlock and rollback counts from pg_stat_database and, when SERVER_LOG points at the server's log file or log directory, the recent ERROR, FATAL and PANIC entries in it as structured records with SQLSTATE, detail, hint and statement, newest first. Reads stderr, csvlog and jsonlog formats",
+	}, (*serverState).RecentErrors)
 }
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maintenanceProgressInterval is how often the progress view of a running
+// VACUUM or REINDEX is read.
+const maintenanceProgressInterval = time.Second
+
+// progressCounters name the columns of each progress view that say how far
+// the current phase has got, as done and total.
+var progressCounters = map[string][2]string{
+	"pg_stat_progress_vacuum":       {"heap_blks_scanned", "heap_blks_total"},
+	"pg_stat_progress_cluster":      {"heap_blks_scanned", "heap_blks_total"},
+	"pg_stat_progress_create_index": {"blocks_done", "blocks_total"},
+}
+
+// outsideTransaction reports whether a statement refuses to run inside a
+// transaction block, so it can only be run as it is and never rolled back.
+func outsideTransaction(statement string) bool {
+	if tokens := sqlTokens(statement); len(tokens) > 1 && (tokens[0].Text == "CREATE" || tokens[0].Text == "DROP") && tokens[1].Text == "DATABASE" {
+		return true
+	}
+	utility := classifyUtility(statement)
+	if utility == nil {
+		return false
+	}
+	switch utility.Command {
+	case "VACUUM", "VACUUM FULL", "REINDEX CONCURRENTLY", "CREATE INDEX CONCURRENTLY":
+		return true
+	}
+	return false
+}
+
+type RunVacuumArgs struct {
+	Tables  []string `json:"tables" jsonschema:"Tables to vacuum, either bare names (in schema) or schema.table"`
+	Schema  string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Full    bool     `json:"full,omitempty" jsonschema:"Rewrite the tables to give their free space back to the operating system, locking out every other access meanwhile (default: false)"`
+	Analyze bool     `json:"analyze,omitempty" jsonschema:"Also refresh the tables' planner statistics (default: false)"`
+	Freeze  bool     `json:"freeze,omitempty" jsonschema:"Freeze every row, as an anti-wraparound vacuum does (default: false)"`
+}
+
+// RunVacuum vacuums tables, reporting the progress view as it runs and the
+// dead rows and sizes before and after. VACUUM can't run in a transaction,
+// so unlike other writes it can't be simulated in dry-run mode.
+func (s *serverState) RunVacuum(ctx context.Context, req *mcp.CallToolRequest, args RunVacuumArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("run_vacuum")
+	}
+	if len(args.Tables) == 0 {
+		return s.returnErrorResult("tables are required")
+	}
+
+	schema := getSchema(args.Schema)
+	var tables []string
+	for _, table := range args.Tables {
+		if !strings.Contains(table, ".") {
+			table = pgx.Identifier{schema}.Sanitize() + "." + table
+		}
+		tableSchema, tableName, err := s.resolveTableName(ctx, "", table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(tableSchema, tableName) {
+			return s.returnNotAccessible(qualifiedName(tableSchema, tableName))
+		}
+		if name := qualifiedName(tableSchema, tableName); !slices.Contains(tables, name) {
+			tables = append(tables, name)
+		}
+	}
+
+	var options []string
+	if args.Full {
+		options = append(options, "FULL")
+	}
+	if args.Freeze {
+		options = append(options, "FREEZE")
+	}
+	if args.Analyze {
+		options = append(options, "ANALYZE")
+	}
+	statement := "VACUUM "
+	if len(options) > 0 {
+		statement += "(" + strings.Join(options, ", ") + ") "
+	}
+	statement += strings.Join(tables, ", ")
+	utility := classifyUtility(statement)
+
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "run_vacuum", "Vacuum "+strings.Join(tables, ", "), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	if s.config.DryRun {
+		return returnJSONResult(s.labelDryRun(map[string]interface{}{
+			"statement":   statement,
+			"lock_mode":   utility.LockMode,
+			"lock_impact": lockImpact[utility.LockMode],
+		}))
+	}
+
+	before, err := s.vacuumStates(ctx, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	elapsed, progress, err := s.runMaintenance(ctx, req, statement, utility.ProgressView)
+	if err != nil {
+		return s.returnErrorResult("Vacuum error: %v", err)
+	}
+	after, err := s.vacuumStates(ctx, tables)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vacuumed := make([]map[string]interface{}, len(tables))
+	for i, table := range tables {
+		vacuumed[i] = map[string]interface{}{
+			"table":              table,
+			"dead_tuples_before": before[i].DeadTuples,
+			"dead_tuples_after":  after[i].DeadTuples,
+			"size_before_bytes":  before[i].Size,
+			"size_after_bytes":   after[i].Size,
+		}
+	}
+	return returnJSONResult(map[string]interface{}{
+		"statement":   statement,
+		"lock_mode":   utility.LockMode,
+		"duration_ms": elapsed.Milliseconds(),
+		"tables":      vacuumed,
+		"progress":    progress.report(),
+	})
+}
+
+// vacuumState is a table's dead rows and total size at one moment.
+type vacuumState struct {
+	DeadTuples int64
+	Size       int64
+}
+
+func (s *serverState) vacuumStates(ctx context.Context, tables []string) ([]vacuumState, error) {
+	states := make([]vacuumState, len(tables))
+	for i, table := range tables {
+		err := s.pool.QueryRow(ctx, `
+			SELECT COALESCE(st.n_dead_tup, 0), pg_total_relation_size(c.oid)
+			FROM pg_class c
+			LEFT JOIN pg_stat_all_tables st ON st.relid = c.oid
+			WHERE c.oid = $1::text::regclass
+		`, table).Scan(&states[i].DeadTuples, &states[i].Size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read statistics for %s: %v", table, err)
+		}
+	}
+	return states, nil
+}
+
+type RunReindexArgs struct {
+	Table        string `json:"table,omitempty" jsonschema:"Rebuild every index of this table, optionally schema-qualified"`
+	Index        string `json:"index,omitempty" jsonschema:"Rebuild this index, optionally schema-qualified"`
+	Schema       string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Concurrently bool   `json:"concurrently,omitempty" jsonschema:"Rebuild without blocking writes, taking longer and leaving an invalid index behind if it fails (default: false)"`
+}
+
+// RunReindex rebuilds a bloated or corrupted index, or every index of a
+// table, reporting the progress view as it runs and the index sizes before
+// and after.
+func (s *serverState) RunReindex(ctx context.Context, req *mcp.CallToolRequest, args RunReindexArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("run_reindex")
+	}
+	if (args.Table == "") == (args.Index == "") {
+		return s.returnErrorResult("Give either table or index")
+	}
+
+	kind, target, sizeQuery := "TABLE", "", "SELECT pg_indexes_size($1::text::regclass)"
+	if args.Table != "" {
+		schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.Table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(schema, tableName) {
+			return s.returnNotAccessible(qualifiedName(schema, tableName))
+		}
+		target = qualifiedName(schema, tableName)
+	} else {
+		indexSchema, indexName, quoted := parseQualifiedName(args.Index)
+		if indexSchema != "" && args.Schema != "" && indexSchema != args.Schema {
+			return s.returnErrorResult("%s names schema %s but schema is set to %s", args.Index, indexSchema, args.Schema)
+		}
+		if indexSchema == "" {
+			indexSchema = getSchema(args.Schema)
+		}
+		// unquoted names match as resolveTableName matches tables
+		var schema, tableName string
+		err := s.pool.QueryRow(ctx, `
+			SELECT n.nspname::text, c.relname::text, tn.nspname::text, t.relname::text
+			FROM pg_index i
+			JOIN pg_class c ON c.oid = i.indexrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_class t ON t.oid = i.indrelid
+			JOIN pg_namespace tn ON tn.oid = t.relnamespace
+			WHERE CASE WHEN $3 THEN n.nspname = $1 AND c.relname = $2
+				ELSE lower(n.nspname) = lower($1) AND lower(c.relname) = lower($2) END
+			ORDER BY n.nspname = $1 AND c.relname = $2 DESC, n.nspname, c.relname
+			LIMIT 1
+		`, indexSchema, indexName, quoted).Scan(&indexSchema, &indexName, &schema, &tableName)
+		if err == pgx.ErrNoRows {
+			return s.returnErrorResult("Index %s not found", qualifiedName(indexSchema, indexName))
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up index: %v", err)
+		}
+		if !s.relationAllowed(schema, tableName) {
+			return s.returnNotAccessible(qualifiedName(schema, tableName))
+		}
+		kind, target, sizeQuery = "INDEX", qualifiedName(indexSchema, indexName), "SELECT pg_relation_size($1::text::regclass)"
+	}
+
+	statement := "REINDEX " + kind + " "
+	if args.Concurrently {
+		statement += "CONCURRENTLY "
+	}
+	statement += target
+	utility := classifyUtility(statement)
+
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "run_reindex", fmt.Sprintf("Reindex %s %s", strings.ToLower(kind), target), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	// REINDEX CONCURRENTLY can't run in a transaction, so neither form is
+	// simulated
+	if s.config.DryRun {
+		return returnJSONResult(s.labelDryRun(map[string]interface{}{
+			"statement":   statement,
+			"lock_mode":   utility.LockMode,
+			"lock_impact": lockImpact[utility.LockMode],
+		}))
+	}
+
+	var before, after int64
+	if err := s.pool.QueryRow(ctx, sizeQuery, target).Scan(&before); err != nil {
+		return nil, nil, fmt.Errorf("failed to measure %s: %v", target, err)
+	}
+	elapsed, progress, err := s.runMaintenance(ctx, req, statement, utility.ProgressView)
+	if err != nil {
+		return s.returnErrorResult("Reindex error: %v", err)
+	}
+	if err := s.pool.QueryRow(ctx, sizeQuery, target).Scan(&after); err != nil {
+		return nil, nil, fmt.Errorf("failed to measure %s: %v", target, err)
+	}
+
+	response := map[string]interface{}{
+		"statement":         statement,
+		"lock_mode":         utility.LockMode,
+		"duration_ms":       elapsed.Milliseconds(),
+		"size_before_bytes": before,
+		"size_after_bytes":  after,
+		"progress":          progress.report(),
+	}
+	response[strings.ToLower(kind)] = target
+	return returnJSONResult(response)
+}
+
+// maintenanceProgress collects what a progress view showed while a
+// maintenance command ran.
+type maintenanceProgress struct {
+	mu      sync.Mutex
+	phases  []string
+	last    map[string]interface{}
+	samples int
+}
+
+func (p *maintenanceProgress) record(snapshot map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.samples++
+	p.last = snapshot
+	if phase, ok := snapshot["phase"].(string); ok && !slices.Contains(p.phases, phase) {
+		p.phases = append(p.phases, phase)
+	}
+}
+
+// report is nil when the command finished before the view was first read.
+func (p *maintenanceProgress) report() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.samples == 0 {
+		return nil
+	}
+	return map[string]interface{}{
+		"samples": p.samples,
+		"phases":  p.phases,
+		"last":    p.last,
+	}
+}
+
+// runMaintenance runs a statement that can't be in a transaction block on a
+// connection of its own, reading its progress view from another one and
+// passing each reading on as a progress notification.
+func (s *serverState) runMaintenance(ctx context.Context, req *mcp.CallToolRequest, statement, view string) (time.Duration, *maintenanceProgress, error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+	pid := conn.Conn().PgConn().PID()
+
+	progress := &maintenanceProgress{}
+	report := progressNotifier(ctx, req)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(maintenanceProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// monitoring is best effort, a busy pool only costs a reading
+			snapshot, err := s.progressSnapshot(ctx, view, pid)
+			if err != nil || snapshot == nil {
+				continue
+			}
+			progress.record(snapshot)
+			counters := progressCounters[view]
+			current, _ := snapshot[counters[0]].(float64)
+			total, _ := snapshot[counters[1]].(float64)
+			message, _ := snapshot["phase"].(string)
+			if table, ok := snapshot["table"].(string); ok {
+				message = table + ": " + message
+			}
+			report(current, total, message)
+		}
+	}()
+
+	started := time.Now()
+	_, err = conn.Exec(ctx, statement)
+	elapsed := time.Since(started)
+	close(done)
+	wg.Wait()
+	return elapsed, progress, err
+}
+
+// progressSnapshot reads the progress view row of a backend, nil when it
+// has none at the moment.
+func (s *serverState) progressSnapshot(ctx context.Context, view string, pid uint32) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, maintenanceProgressInterval)
+	defer cancel()
+	var table *string
+	var snapshot map[string]interface{}
+	err := s.pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT p.relid::regclass::text, to_jsonb(p) - 'pid' - 'datid' - 'datname' - 'relid'
+		FROM %s p
+		WHERE p.pid = $1
+	`, view), int64(pid)).Scan(&table, &snapshot)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if table != nil {
+		snapshot["table"] = *table
+	}
+	return snapshot, nil
+}
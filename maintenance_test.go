@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunVacuum(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE vacuumed_items (id int PRIMARY KEY, note text);
+		INSERT INTO vacuumed_items SELECT i, repeat('x', 100) FROM generate_series(1, 5000) i;
+		DELETE FROM vacuumed_items WHERE id % 2 = 0;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE vacuumed_items")
+
+	args := RunVacuumArgs{Tables: []string{"vacuumed_items"}, Full: true, Analyze: true}
+	testServer.config.AllowWrites = false
+	if result, _, _ := testServer.RunVacuum(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected run_vacuum to be refused without ALLOW_WRITES")
+	}
+
+	testServer.config.AllowWrites = true
+	result, data, err := testServer.RunVacuum(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("RunVacuum failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	if response["statement"] != "VACUUM (FULL, ANALYZE) public.vacuumed_items" || response["lock_mode"] != "ACCESS EXCLUSIVE" {
+		t.Errorf("Unexpected statement in %v", response)
+	}
+	table := response["tables"].([]map[string]interface{})[0]
+	if table["size_after_bytes"].(int64) >= table["size_before_bytes"].(int64) {
+		t.Errorf("Expected VACUUM FULL to shrink the table, got %v", table)
+	}
+
+	reindex := RunReindexArgs{Index: "vacuumed_items_pkey", Concurrently: true}
+	result, data, err = testServer.RunReindex(ctx, createMockRequest(reindex), reindex)
+	if err != nil {
+		t.Fatalf("RunReindex failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	if statement := data.(map[string]interface{})["statement"]; statement != "REINDEX INDEX CONCURRENTLY public.vacuumed_items_pkey" {
+		t.Errorf("Unexpected statement %v", statement)
+	}
+
+	testServer.config.DryRun = true
+	if _, data, _ := testServer.RunVacuum(ctx, createMockRequest(args), args); data.(map[string]interface{})["simulated"] != true {
+		t.Errorf("Expected a simulated vacuum in dry-run mode, got %v", data)
+	}
+}
+
+func TestOutsideTransaction(t *testing.T) {
+	for statement, expected := range map[string]bool{
+		"VACUUM (FULL) items":                   true,
+		"vacuum items":                          true,
+		"REINDEX INDEX CONCURRENTLY items_pkey": true,
+		"REINDEX TABLE items":                   false,
+		"ANALYZE items":                         false,
+		"UPDATE items SET a = 1":                false,
+		"CREATE DATABASE copy TEMPLATE items":   true,
+		"DROP DATABASE copy":                    true,
+		"CREATE TABLE items_copy (a int)":       false,
+	} {
+		if got := outsideTransaction(statement); got != expected {
+			t.Errorf("outsideTransaction(%q) = %v, expected %v", statement, got, expected)
+		}
+	}
+}
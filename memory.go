@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const maxMemoryContexts = 20
+
+type MemoryUsageArgs struct {
+	LogMemoryContextsPid int `json:"log_memory_contexts_pid,omitempty" jsonschema:"Ask this backend to write its memory context breakdown to the server log with pg_log_backend_memory_contexts (PostgreSQL 14+, needs superuser or an explicit grant)"`
+}
+
+// tempUsageQuery attributes temporary files to backends through their names,
+// which pg_ls_tmpdir reports as pgsql_tmp<pid>.<n> while a query spills.
+const tempUsageQuery = `
+	WITH temp_files AS (
+		SELECT substring(name FROM '^pgsql_tmp([0-9]+)')::int AS pid, count(*) AS files, sum(size) AS bytes
+		FROM pg_ls_tmpdir()
+		GROUP BY 1
+	)
+	SELECT
+		a.pid,
+		a.usename::text,
+		a.datname::text,
+		a.state,
+		a.wait_event_type,
+		a.wait_event,
+		EXTRACT(EPOCH FROM now() - a.query_start)::float8 AS query_seconds,
+		COALESCE(t.files, 0)::bigint,
+		COALESCE(t.bytes, 0)::bigint,
+		a.query
+	FROM pg_stat_activity a
+	LEFT JOIN temp_files t ON t.pid = a.pid
+	WHERE a.backend_type = 'client backend'
+		AND a.state <> 'idle'
+		AND a.pid <> pg_backend_pid()
+	ORDER BY COALESCE(t.bytes, 0) DESC, a.query_start
+`
+
+// activityQuery is the fallback when pg_ls_tmpdir is not granted.
+const activityQuery = `
+	SELECT
+		a.pid,
+		a.usename::text,
+		a.datname::text,
+		a.state,
+		a.wait_event_type,
+		a.wait_event,
+		EXTRACT(EPOCH FROM now() - a.query_start)::float8 AS query_seconds,
+		NULL::bigint,
+		NULL::bigint,
+		a.query
+	FROM pg_stat_activity a
+	WHERE a.backend_type = 'client backend'
+		AND a.state <> 'idle'
+		AND a.pid <> pg_backend_pid()
+	ORDER BY a.query_start
+`
+
+func (s *serverState) GetMemoryUsage(ctx context.Context, req *mcp.CallToolRequest, args MemoryUsageArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read server version: %v", err)
+	}
+
+	var notes []string
+	var canListTemp bool
+	if err := s.pool.QueryRow(ctx, "SELECT has_function_privilege('pg_ls_tmpdir()', 'EXECUTE')").Scan(&canListTemp); err != nil {
+		return nil, nil, fmt.Errorf("failed to check privileges: %v", err)
+	}
+	activity := tempUsageQuery
+	if !canListTemp {
+		activity = activityQuery
+		notes = append(notes, "Temporary file usage per query is unavailable, pg_ls_tmpdir needs the pg_monitor role")
+	}
+
+	rows, err := s.pool.Query(ctx, activity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query activity: %v", err)
+	}
+	defer rows.Close()
+
+	var queries []map[string]interface{}
+	for rows.Next() {
+		var pid int
+		var user, database, state, waitEventType, waitEvent, query *string
+		var seconds *float64
+		var tempFiles, tempBytes *int64
+		if err := rows.Scan(&pid, &user, &database, &state, &waitEventType, &waitEvent, &seconds, &tempFiles, &tempBytes, &query); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		entry := map[string]interface{}{
+			"pid":           pid,
+			"query_seconds": seconds,
+		}
+		addOptionalString(entry, "user", user)
+		addOptionalString(entry, "database", database)
+		addOptionalString(entry, "state", state)
+		addOptionalString(entry, "wait_event_type", waitEventType)
+		addOptionalString(entry, "wait_event", waitEvent)
+		addOptionalString(entry, "query", query)
+		if tempBytes != nil {
+			entry["temp_files"] = *tempFiles
+			entry["temp_bytes"] = *tempBytes
+		}
+		queries = append(queries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	var workMem, tempFileLimit string
+	var dbTempFiles, dbTempBytes int64
+	err = s.pool.QueryRow(ctx, `
+		SELECT current_setting('work_mem'), current_setting('temp_file_limit'), temp_files, temp_bytes
+		FROM pg_stat_database
+		WHERE datname = current_database()
+	`).Scan(&workMem, &tempFileLimit, &dbTempFiles, &dbTempBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query settings: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"active_queries": queries,
+		"settings": map[string]interface{}{
+			"work_mem":        workMem,
+			"temp_file_limit": tempFileLimit,
+		},
+		"database_temp_files": dbTempFiles,
+		"database_temp_bytes": dbTempBytes,
+	}
+
+	// other backends' memory contexts can only be read from the server log
+	if versionNum < 140000 {
+		notes = append(notes, "Memory contexts need PostgreSQL 14 or newer")
+	} else {
+		contexts, err := s.ownMemoryContexts(ctx)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("Memory contexts are unavailable: %v", err))
+		} else {
+			response["own_memory_contexts"] = contexts
+		}
+
+		if args.LogMemoryContextsPid != 0 {
+			var logged bool
+			err := s.pool.QueryRow(ctx, "SELECT pg_log_backend_memory_contexts($1)", args.LogMemoryContextsPid).Scan(&logged)
+			switch {
+			case err != nil:
+				notes = append(notes, fmt.Sprintf("Could not log memory contexts of pid %d: %v", args.LogMemoryContextsPid, err))
+			case !logged:
+				notes = append(notes, fmt.Sprintf("pid %d is not a PostgreSQL backend", args.LogMemoryContextsPid))
+			default:
+				response["logged_memory_contexts_pid"] = args.LogMemoryContextsPid
+				notes = append(notes, fmt.Sprintf("The memory contexts of pid %d were written to the server log at LOG level", args.LogMemoryContextsPid))
+			}
+		}
+	}
+
+	if len(notes) > 0 {
+		response["notes"] = notes
+	}
+	return returnJSONResult(response)
+}
+
+// ownMemoryContexts lists the largest memory contexts of this connection's
+// backend, the only one pg_backend_memory_contexts can see.
+func (s *serverState) ownMemoryContexts(ctx context.Context) ([]map[string]interface{}, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT name, ident, parent, level, total_bytes, used_bytes
+		FROM pg_backend_memory_contexts
+		ORDER BY total_bytes DESC
+		LIMIT $1
+	`, maxMemoryContexts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contexts []map[string]interface{}
+	for rows.Next() {
+		var name string
+		var ident, parent *string
+		var level int
+		var totalBytes, usedBytes int64
+		if err := rows.Scan(&name, &ident, &parent, &level, &totalBytes, &usedBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		entry := map[string]interface{}{
+			"name":        name,
+			"level":       level,
+			"total_bytes": totalBytes,
+			"used_bytes":  usedBytes,
+		}
+		addOptionalString(entry, "ident", ident)
+		addOptionalString(entry, "parent", parent)
+		contexts = append(contexts, entry)
+	}
+	return contexts, rows.Err()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetMemoryUsage(t *testing.T) {
+	ctx := context.Background()
+
+	var pid int
+	if err := testServer.pool.QueryRow(ctx, "SELECT pg_backend_pid()").Scan(&pid); err != nil {
+		t.Fatalf("Failed to read backend pid: %v", err)
+	}
+
+	args := MemoryUsageArgs{LogMemoryContextsPid: pid}
+	result, data, err := testServer.GetMemoryUsage(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("GetMemoryUsage failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	response := data.(map[string]interface{})
+	contexts, ok := response["own_memory_contexts"].([]map[string]interface{})
+	if !ok || len(contexts) == 0 {
+		t.Fatalf("Expected memory contexts of the own backend, notes: %v", response["notes"])
+	}
+	if contexts[0]["total_bytes"].(int64) <= 0 {
+		t.Errorf("Expected largest context to have a size, got %v", contexts[0])
+	}
+
+	if response["logged_memory_contexts_pid"] != pid {
+		t.Errorf("Expected memory contexts of pid %d to be logged, notes: %v", pid, response["notes"])
+	}
+
+	settings := response["settings"].(map[string]interface{})
+	if settings["work_mem"] == "" {
+		t.Error("Expected work_mem to be reported")
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// metaRelationKinds maps the psql relation listing commands to pg_class.relkind.
+var metaRelationKinds = map[string][]string{
+	`\d`:  {"r", "p", "v", "m", "S", "f"},
+	`\dt`: {"r", "p"},
+	`\dv`: {"v"},
+	`\dm`: {"m"},
+	`\di`: {"i", "I"},
+	`\ds`: {"S"},
+}
+
+const supportedMetaCommands = `\d [name], \d+ name, \dt, \dv, \dm, \di, \ds, \dn, \df and \l (each with an optional pattern and + for more detail)`
+
+// systemSchemaFilter hides catalog schemas unless a schema pattern is given.
+const systemSchemaFilter = `(CASE WHEN $1 = '' THEN n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname !~ '^pg_toast'
+	ELSE n.nspname LIKE $1 END)`
+
+type MetaCommandArgs struct {
+	Command string `json:"command" jsonschema:"A psql meta-command such as \\dt, \\d+ users, \\di public.*, \\dn, \\df or \\l"`
+}
+
+// metaPattern converts a psql pattern ([schema.]name with * and ? wildcards)
+// into LIKE patterns for the schema and the object name.
+func metaPattern(pattern string) (schemaLike, nameLike string) {
+	convert := func(part string) string {
+		if strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) && len(part) >= 2 {
+			part = part[1 : len(part)-1]
+		} else {
+			part = strings.ToLower(part)
+		}
+		part = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(part)
+		return strings.NewReplacer("*", "%", "?", "_").Replace(part)
+	}
+
+	if pattern == "" {
+		return "", ""
+	}
+	if index := strings.LastIndex(pattern, "."); index >= 0 {
+		return convert(pattern[:index]), convert(pattern[index+1:])
+	}
+	return "", convert(pattern)
+}
+
+// collectRows reads every row into a map keyed by column name.
+func collectRows(rows pgx.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+
+	var results []map[string]interface{}
+	fieldDescriptions := rows.FieldDescriptions()
+	for rows.Next() {
+		values, err := rowValues(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		row := make(map[string]interface{})
+		for i, field := range fieldDescriptions {
+			row[string(field.Name)] = values[i]
+		}
+		results = append(results, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return results, nil
+}
+
+// metaQuery runs a listing query, keeping the rows visible reports as
+// allowed by the allow/deny lists.
+func (s *serverState) metaQuery(ctx context.Context, visible func(row map[string]interface{}) bool, query string, args ...interface{}) (*mcp.CallToolResult, any, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return s.returnErrorResult("Meta-command failed: %v", err)
+	}
+	results, err := collectRows(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	var listed []map[string]interface{}
+	for _, row := range results {
+		if visible(row) {
+			listed = append(listed, row)
+		}
+	}
+	return returnJSONResult(listed)
+}
+
+// visibleRelation checks indexes by the table they belong to.
+func (s *serverState) visibleRelation(row map[string]interface{}) bool {
+	schema, _ := row["schema"].(string)
+	name, _ := row["name"].(string)
+	if table, ok := row["table_name"].(string); ok {
+		name = table
+	}
+	return s.relationAllowed(schema, name)
+}
+
+func (s *serverState) visibleSchema(row map[string]interface{}) bool {
+	schema, _ := row["schema"].(string)
+	return s.schemaAllowed(schema)
+}
+
+func (s *serverState) visibleSchemaName(row map[string]interface{}) bool {
+	name, _ := row["name"].(string)
+	return s.schemaAllowed(name)
+}
+
+func visibleAll(row map[string]interface{}) bool {
+	return true
+}
+
+func (s *serverState) MetaCommand(ctx context.Context, req *mcp.CallToolRequest, args MetaCommandArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	fields := strings.Fields(args.Command)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], `\`) {
+		return s.returnErrorResult("Expected a meta-command starting with a backslash, supported: %s", supportedMetaCommands)
+	}
+	command := strings.TrimSuffix(fields[0], "+")
+	verbose := strings.HasSuffix(fields[0], "+")
+	pattern := strings.Join(fields[1:], " ")
+	schemaLike, nameLike := metaPattern(pattern)
+
+	// \d with a name describes that relation, without one it lists relations
+	if command == `\d` && pattern != "" {
+		return s.describeRelation(ctx, req, pattern, verbose)
+	}
+
+	if relkinds, ok := metaRelationKinds[command]; ok {
+		extra := ""
+		if verbose {
+			extra = `,
+				pg_total_relation_size(c.oid) AS size_bytes,
+				obj_description(c.oid, 'pg_class') AS description`
+		}
+		return s.metaQuery(ctx, s.visibleRelation, fmt.Sprintf(`
+			SELECT
+				n.nspname::text AS schema,
+				c.relname::text AS name,
+				CASE c.relkind
+					WHEN 'r' THEN 'table' WHEN 'p' THEN 'partitioned table' WHEN 'v' THEN 'view'
+					WHEN 'm' THEN 'materialized view' WHEN 'i' THEN 'index' WHEN 'I' THEN 'partitioned index'
+					WHEN 'S' THEN 'sequence' WHEN 'f' THEN 'foreign table'
+				END AS type,
+				pg_get_userbyid(c.relowner)::text AS owner,
+				ic.relname::text AS table_name%s
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			LEFT JOIN pg_index i ON i.indexrelid = c.oid
+			LEFT JOIN pg_class ic ON ic.oid = i.indrelid
+			WHERE %s
+				AND ($2 = '' OR c.relname LIKE $2)
+				AND c.relkind::text = ANY($3)
+			ORDER BY 1, 2
+		`, extra, systemSchemaFilter), schemaLike, nameLike, relkinds)
+	}
+
+	switch command {
+	case `\dn`:
+		extra := ""
+		if verbose {
+			extra = `,
+				n.nspacl::text AS access_privileges,
+				obj_description(n.oid, 'pg_namespace') AS description`
+		}
+		// schema patterns name the schema itself
+		if nameLike == "" {
+			nameLike = schemaLike
+		}
+		return s.metaQuery(ctx, s.visibleSchemaName, fmt.Sprintf(`
+			SELECT n.nspname::text AS name, pg_get_userbyid(n.nspowner)::text AS owner%s
+			FROM pg_namespace n
+			WHERE CASE WHEN $1 = '' THEN n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'
+				ELSE n.nspname LIKE $1 END
+			ORDER BY 1
+		`, extra), nameLike)
+
+	case `\df`:
+		extra := ""
+		if verbose {
+			extra = `,
+				l.lanname::text AS language,
+				CASE p.provolatile WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END AS volatility,
+				obj_description(p.oid, 'pg_proc') AS description`
+		}
+		return s.metaQuery(ctx, s.visibleSchema, fmt.Sprintf(`
+			SELECT
+				n.nspname::text AS schema,
+				p.proname::text AS name,
+				pg_get_function_result(p.oid) AS result_type,
+				pg_get_function_arguments(p.oid) AS arguments,
+				CASE p.prokind WHEN 'a' THEN 'aggregate' WHEN 'w' THEN 'window' WHEN 'p' THEN 'procedure' ELSE 'function' END AS type%s
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			JOIN pg_language l ON l.oid = p.prolang
+			WHERE %s
+				AND ($2 = '' OR p.proname LIKE $2)
+			ORDER BY 1, 2, 4
+		`, extra, systemSchemaFilter), schemaLike, nameLike)
+
+	case `\l`:
+		extra := ""
+		if verbose {
+			extra = `,
+				CASE WHEN has_database_privilege(d.oid, 'CONNECT') THEN pg_database_size(d.oid) END AS size_bytes,
+				shobj_description(d.oid, 'pg_database') AS description`
+		}
+		return s.metaQuery(ctx, visibleAll, fmt.Sprintf(`
+			SELECT
+				d.datname::text AS name,
+				pg_get_userbyid(d.datdba)::text AS owner,
+				pg_encoding_to_char(d.encoding)::text AS encoding,
+				d.datcollate::text AS collate,
+				d.datctype::text AS ctype,
+				d.datacl::text AS access_privileges%s
+			FROM pg_database d
+			WHERE $1 = '' OR d.datname LIKE $1
+			ORDER BY 1
+		`, extra), nameLike)
+	}
+
+	return s.returnErrorResult("Unsupported meta-command %s, supported: %s", fields[0], supportedMetaCommands)
+}
+
+// describeRelation implements \d name by combining the table tools.
+func (s *serverState) describeRelation(ctx context.Context, req *mcp.CallToolRequest, name string, verbose bool) (*mcp.CallToolResult, any, error) {
+	var schema, table, kind string
+	var sizeBytes int64
+	var description, viewDefinition *string
+	err := s.pool.QueryRow(ctx, `
+		SELECT
+			n.nspname::text,
+			c.relname::text,
+			c.relkind::text,
+			pg_total_relation_size(c.oid),
+			obj_description(c.oid, 'pg_class'),
+			CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid, true) END
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.oid = to_regclass($1)
+	`, name).Scan(&schema, &table, &kind, &sizeBytes, &description, &viewDefinition)
+	if err == pgx.ErrNoRows {
+		return s.returnErrorResult("Did not find any relation named %q", name)
+	}
+	if err != nil {
+		return s.returnErrorResult("Meta-command failed: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"schema": schema,
+		"name":   table,
+	}
+
+	columnsResult, columns, err := s.GetTableSchema(ctx, req, TableSchemaArgs{TableName: table, Schema: schema})
+	if err != nil || columnsResult.IsError {
+		return columnsResult, nil, err
+	}
+	response["columns"] = columns
+
+	indexesResult, indexes, err := s.GetTableIndexes(ctx, req, TableIndexesArgs{TableName: table, Schema: schema})
+	if err != nil || indexesResult.IsError {
+		return indexesResult, nil, err
+	}
+	response["indexes"] = indexes
+
+	constraintsResult, constraints, err := s.GetTableConstraints(ctx, req, TableConstraintsArgs{TableName: table, Schema: schema})
+	if err != nil || constraintsResult.IsError {
+		return constraintsResult, nil, err
+	}
+	response["constraints"] = constraints
+
+	if verbose {
+		response["size_bytes"] = sizeBytes
+		addOptionalString(response, "description", description)
+		addOptionalString(response, "view_definition", viewDefinition)
+	}
+
+	return returnJSONResult(response)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMetaPattern(t *testing.T) {
+	cases := []struct {
+		pattern, schema, name string
+	}{
+		{"", "", ""},
+		{"users", "", "users"},
+		{"Public.User*", "public", "user%"},
+		{`"Mixed".t?`, "Mixed", "t_"},
+		{"post_stats", "", `post\_stats`},
+	}
+
+	for _, c := range cases {
+		schema, name := metaPattern(c.pattern)
+		if schema != c.schema || name != c.name {
+			t.Errorf("metaPattern(%q) = %q, %q, expected %q, %q", c.pattern, schema, name, c.schema, c.name)
+		}
+	}
+}
+
+func TestMetaCommand(t *testing.T) {
+	ctx := context.Background()
+
+	run := func(t *testing.T, command string) interface{} {
+		args := MetaCommandArgs{Command: command}
+		result, data, err := testServer.MetaCommand(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("MetaCommand(%q) failed: %v", command, err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result for %q, got %v", command, result)
+		}
+		return data
+	}
+
+	names := func(rows []map[string]interface{}) map[string]bool {
+		found := make(map[string]bool)
+		for _, row := range rows {
+			found[row["name"].(string)] = true
+		}
+		return found
+	}
+
+	t.Run(`\dt`, func(t *testing.T) {
+		tables := names(run(t, `\dt`).([]map[string]interface{}))
+		if !tables["users"] || !tables["events"] || tables["post_stats"] {
+			t.Errorf("Expected tables and partitioned tables without views, got %v", tables)
+		}
+	})
+
+	t.Run(`\dt+ with pattern`, func(t *testing.T) {
+		rows := run(t, `\dt+ public.post*`).([]map[string]interface{})
+		if len(rows) != 1 || rows[0]["name"] != "posts" || rows[0]["size_bytes"] == nil {
+			t.Errorf("Expected only posts with its size, got %v", rows)
+		}
+	})
+
+	t.Run(`\di`, func(t *testing.T) {
+		rows := run(t, `\di idx_users_*`).([]map[string]interface{})
+		if len(rows) != 2 {
+			t.Fatalf("Expected 2 users indexes, got %d", len(rows))
+		}
+		if rows[0]["table_name"] != "users" {
+			t.Errorf("Expected indexes to name their table, got %v", rows[0])
+		}
+	})
+
+	t.Run(`\d+ table`, func(t *testing.T) {
+		description := run(t, `\d+ users`).(map[string]interface{})
+		if len(description["columns"].([]map[string]interface{})) != 7 {
+			t.Errorf("Expected 7 columns, got %v", description["columns"])
+		}
+		if description["size_bytes"] == nil {
+			t.Error("Expected \\d+ to include the size")
+		}
+	})
+
+	t.Run(`\dn`, func(t *testing.T) {
+		if schemas := names(run(t, `\dn`).([]map[string]interface{})); !schemas["public"] || schemas["pg_catalog"] {
+			t.Errorf("Expected public without system schemas, got %v", schemas)
+		}
+	})
+
+	t.Run(`\df`, func(t *testing.T) {
+		rows := run(t, `\df pg_catalog.lower`).([]map[string]interface{})
+		if len(rows) == 0 || rows[0]["result_type"] != "text" {
+			t.Errorf("Expected pg_catalog.lower returning text, got %v", rows)
+		}
+	})
+
+	t.Run(`\l`, func(t *testing.T) {
+		if databases := names(run(t, `\l`).([]map[string]interface{})); !databases["testdb"] {
+			t.Errorf("Expected the testdb database, got %v", databases)
+		}
+	})
+
+	t.Run("unsupported command", func(t *testing.T) {
+		args := MetaCommandArgs{Command: `\watch 5`}
+		result, _, err := testServer.MetaCommand(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("MetaCommand failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for unsupported command")
+		}
+	})
+}
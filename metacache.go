@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// catalogVersionQuery summarizes the catalogs the cached tools read. DDL
+// writes new catalog rows, so it changes their count or the sum of their
+// xmin, and statistics updates are done in place, so they don't.
+const catalogVersionQuery = `
+	SELECT concat_ws('/',
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_namespace),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_class),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_attribute),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_attrdef),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_constraint),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_index),
+		(SELECT count(*) || ':' || COALESCE(sum(xmin::text::bigint), 0) FROM pg_description)
+	)
+`
+
+// metadataCache keeps the results of the schema exploration tools, so a
+// model walking a large schema doesn't query the catalogs for every table
+// twice. Entries are valid for one catalog version, checked at most once per
+// interval; writes through this server drop them right away.
+type metadataCache struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	version   string
+	checkedAt time.Time
+	entries   map[string]any
+	hits      int64
+	misses    int64
+}
+
+func newMetadataCache(interval time.Duration) *metadataCache {
+	return &metadataCache{interval: interval, entries: make(map[string]any)}
+}
+
+// current returns the catalog version, querying it when the last check is
+// older than the interval and dropping the entries when it changed.
+func (c *metadataCache) current(ctx context.Context, s *serverState) (string, error) {
+	c.mu.Lock()
+	version, fresh := c.version, c.version != "" && time.Since(c.checkedAt) < c.interval
+	c.mu.Unlock()
+	if fresh {
+		return version, nil
+	}
+
+	if err := s.pool.QueryRow(ctx, catalogVersionQuery).Scan(&version); err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version != c.version {
+		c.entries = make(map[string]any)
+		c.version = version
+	}
+	c.checkedAt = time.Now()
+	return version, nil
+}
+
+func (c *metadataCache) lookup(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[key]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return data, ok
+}
+
+// store keeps a result loaded at version, unless the catalogs changed while
+// it was loaded.
+func (c *metadataCache) store(key, version string, data any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version == c.version {
+		c.entries[key] = data
+	}
+}
+
+func (c *metadataCache) stats() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return map[string]interface{}{
+		"interval": c.interval.String(),
+		"entries":  len(c.entries),
+		"hits":     c.hits,
+		"misses":   c.misses,
+	}
+}
+
+// invalidate drops every entry, after a write that may have changed the
+// schema.
+func (c *metadataCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]any)
+	c.version = ""
+}
+
+// withMetadataCache serves a schema exploration tool from the metadata
+// cache, keyed by the tool and its arguments. Results are rebuilt from the
+// cached data on every call, since middleware rewrites their content.
+func withMetadataCache[In any](tool string, handler func(*serverState, context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error)) func(*serverState, context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error) {
+	return func(s *serverState, ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		if s.metadata == nil || s.pool == nil {
+			return handler(s, ctx, req, args)
+		}
+		encoded, err := json.Marshal(args)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal arguments: %v", err)
+		}
+		key := tool + " " + string(encoded)
+
+		version, err := s.metadata.current(ctx, s)
+		if err != nil {
+			return handler(s, ctx, req, args)
+		}
+		if data, ok := s.metadata.lookup(key); ok {
+			return returnJSONResult(data)
+		}
+		result, data, err := handler(s, ctx, req, args)
+		if err == nil && result != nil && !result.IsError {
+			s.metadata.store(key, version, data)
+		}
+		return result, data, err
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMetadataCacheStore(t *testing.T) {
+	cache := newMetadataCache(time.Minute)
+	cache.version = "v1"
+	cache.store("list_tables {}", "v1", []string{"users"})
+	cache.store("get_table_schema {}", "v0", []string{"stale"})
+
+	if _, ok := cache.lookup("list_tables {}"); !ok {
+		t.Error("Expected a result of the current version to be cached")
+	}
+	if _, ok := cache.lookup("get_table_schema {}"); ok {
+		t.Error("Expected a result loaded before a catalog change not to be cached")
+	}
+	cache.invalidate()
+	if _, ok := cache.lookup("list_tables {}"); ok {
+		t.Error("Expected invalidate to drop the entries")
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	ctx := context.Background()
+	savedMetadata := testServer.metadata
+	defer func() { testServer.metadata = savedMetadata }()
+	testServer.metadata = newMetadataCache(time.Nanosecond)
+
+	listTables := withMetadataCache("list_tables", (*serverState).ListTables)
+	count := func() int {
+		args := TableListArgs{}
+		_, data, err := listTables(testServer, ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ListTables failed: %v", err)
+		}
+		tables, _ := data.([]map[string]interface{})
+		return len(tables)
+	}
+
+	before := count()
+	count()
+	if stats := testServer.metadata.stats(); stats["hits"] != int64(1) {
+		t.Errorf("Expected the second call to be served from the cache, got %v", stats)
+	}
+
+	if _, err := testServer.pool.Exec(ctx, "CREATE TABLE metadata_cache_probe (id int)"); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE metadata_cache_probe")
+	if after := count(); after != before+1 {
+		t.Errorf("Expected the new table to be listed once the catalogs changed, got %d tables, had %d", after, before)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// numericFormats are the ways numeric values can be returned: as exact
+// strings, or as floats, which lose digits beyond about 15 significant ones.
+var numericFormats = map[string]bool{"string": true, "float": true}
+
+// encodeNumericRows replaces the numeric values of result rows in place,
+// including those in arrays and ranges. NaN and infinite values stay strings
+// as JSON has no numbers for them.
+func encodeNumericRows(rows []map[string]interface{}, format string) {
+	for _, row := range rows {
+		for column, value := range row {
+			row[column] = encodeNumeric(value, format)
+		}
+	}
+}
+
+func encodeNumeric(value interface{}, format string) interface{} {
+	switch v := value.(type) {
+	case pgtype.Numeric:
+		if format == "float" && v.Valid && !v.NaN && v.InfinityModifier == pgtype.Finite {
+			if float, err := v.Float64Value(); err == nil {
+				return float.Float64
+			}
+		}
+		text, err := v.Value()
+		if err != nil {
+			return nil
+		}
+		return text
+	case []interface{}:
+		for i, element := range v {
+			v[i] = encodeNumeric(element, format)
+		}
+	case map[string]interface{}:
+		for key, element := range v {
+			v[key] = encodeNumeric(element, format)
+		}
+	}
+	return value
+}
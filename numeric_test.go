@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestEncodeNumericRows(t *testing.T) {
+	row := func() map[string]interface{} {
+		return map[string]interface{}{
+			"price":   pgtype.Numeric{Int: big.NewInt(1234567890123456789), Exp: -2, Valid: true},
+			"missing": pgtype.Numeric{NaN: true, Valid: true},
+			"prices":  []interface{}{pgtype.Numeric{Int: big.NewInt(150), Exp: -2, Valid: true}},
+			"id":      int32(1),
+		}
+	}
+
+	rows := []map[string]interface{}{row()}
+	encodeNumericRows(rows, "string")
+	encoded, _ := json.Marshal(rows[0])
+	if string(encoded) != `{"id":1,"missing":"NaN","price":"12345678901234567.89","prices":["1.50"]}` {
+		t.Errorf("Expected exact strings, got %s", encoded)
+	}
+
+	rows = []map[string]interface{}{row()}
+	encodeNumericRows(rows, "float")
+	if price, ok := rows[0]["price"].(float64); !ok || price != 12345678901234567.89 {
+		t.Errorf("Expected a float, got %#v", rows[0]["price"])
+	}
+	if rows[0]["missing"] != "NaN" {
+		t.Errorf("Expected NaN to stay a string, got %#v", rows[0]["missing"])
+	}
+}
+
+func TestQueryNumericFormat(t *testing.T) {
+	ctx := context.Background()
+	args := QueryArgs{Query: "SELECT 12345678901234567.89::numeric AS amount, 10.50::numeric(12,2) AS price"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	row := data.([]map[string]interface{})[0]
+	if row["amount"] != "12345678901234567.89" || row["price"] != "10.50" {
+		t.Errorf("Expected exact strings, got %v", row)
+	}
+
+	args.NumericFormat = "float"
+	if _, data, _ = testServer.ExecuteQuery(ctx, createMockRequest(args), args); data.([]map[string]interface{})[0]["price"] != 10.5 {
+		t.Errorf("Expected a float, got %v", data)
+	}
+}
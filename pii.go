@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// piiPatterns are checked in order, so card numbers and SSNs are claimed
+// before the looser phone number pattern sees their digits.
+var piiPatterns = []struct {
+	Kind    string
+	Pattern *regexp.Regexp
+	Valid   func(string) bool
+}{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), nil},
+	{"credit_card", regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), luhnValid},
+	{"ssn", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), nil},
+	{"phone", regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`), nil},
+}
+
+// luhnValid filters out long digit runs (ids, timestamps) that are not card
+// numbers.
+func luhnValid(candidate string) bool {
+	sum, digits := 0, 0
+	for i := len(candidate) - 1; i >= 0; i-- {
+		c := candidate[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// redactText replaces every PII match in text and returns the kinds found.
+func redactText(text string) (string, []string) {
+	var kinds []string
+	for _, pii := range piiPatterns {
+		found := false
+		text = pii.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if pii.Valid != nil && !pii.Valid(match) {
+				return match
+			}
+			found = true
+			return "[REDACTED " + pii.Kind + "]"
+		})
+		if found {
+			kinds = append(kinds, pii.Kind)
+		}
+	}
+	return text, kinds
+}
+
+// redactValue redacts strings, including those nested in JSON values.
+func redactValue(value interface{}) (interface{}, []string) {
+	switch v := value.(type) {
+	case string:
+		return redactText(v)
+	case map[string]interface{}:
+		var kinds []string
+		for key, nested := range v {
+			var nestedKinds []string
+			v[key], nestedKinds = redactValue(nested)
+			kinds = append(kinds, nestedKinds...)
+		}
+		return v, kinds
+	case []interface{}:
+		var kinds []string
+		for i, nested := range v {
+			var nestedKinds []string
+			v[i], nestedKinds = redactValue(nested)
+			kinds = append(kinds, nestedKinds...)
+		}
+		return v, kinds
+	}
+	return value, nil
+}
+
+// redactRows masks PII in result rows in place when REDACT_PII is on, and
+// returns the kinds of PII found per column.
+func (s *serverState) redactRows(rows []map[string]interface{}) map[string][]string {
+	if !s.config.RedactPII {
+		return nil
+	}
+
+	found := make(map[string]map[string]bool)
+	for _, row := range rows {
+		for column, value := range row {
+			var kinds []string
+			row[column], kinds = redactValue(value)
+			for _, kind := range kinds {
+				if found[column] == nil {
+					found[column] = make(map[string]bool)
+				}
+				found[column][kind] = true
+			}
+		}
+	}
+
+	masked := make(map[string][]string)
+	for column, kinds := range found {
+		for kind := range kinds {
+			masked[column] = append(masked[column], kind)
+		}
+		sort.Strings(masked[column])
+	}
+	return masked
+}
+
+// piiWarnings reports the masked columns alongside a result.
+func (s *serverState) piiWarnings(masked map[string][]string) []string {
+	if len(masked) == 0 {
+		return nil
+	}
+	columns := make([]string, 0, len(masked))
+	for column, kinds := range masked {
+		// the same column can be reported by several result sets
+		unique := make(map[string]bool)
+		var names []string
+		for _, kind := range kinds {
+			if !unique[kind] {
+				unique[kind] = true
+				names = append(names, kind)
+			}
+		}
+		sort.Strings(names)
+		columns = append(columns, fmt.Sprintf("%s (%s)", column, strings.Join(names, ", ")))
+	}
+	sort.Strings(columns)
+	return []string{fmt.Sprintf(s.localize("REDACT_PII is enabled, values looking like PII were masked in: %s"), strings.Join(columns, "; "))}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		kinds    []string
+	}{
+		{"contact jane.doe@example.com today", "contact [REDACTED email] today", []string{"email"}},
+		{"card 4111 1111 1111 1111", "card [REDACTED credit_card]", []string{"credit_card"}},
+		{"ssn 123-45-6789", "ssn [REDACTED ssn]", []string{"ssn"}},
+		{"call (555) 123-4567 or +1 555 123 4567", "call [REDACTED phone] or [REDACTED phone]", []string{"phone"}},
+		// digit runs that fail the Luhn check and timestamps are left alone
+		{"order 1234567890123456", "order 1234567890123456", nil},
+		{"at 2024-01-15 10:30:00", "at 2024-01-15 10:30:00", nil},
+	}
+
+	for _, test := range tests {
+		text, kinds := redactText(test.input)
+		if text != test.expected {
+			t.Errorf("redactText(%q) = %q, expected %q", test.input, text, test.expected)
+		}
+		if strings.Join(kinds, ",") != strings.Join(test.kinds, ",") {
+			t.Errorf("redactText(%q) found %v, expected %v", test.input, kinds, test.kinds)
+		}
+	}
+}
+
+func TestRedactRows(t *testing.T) {
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	rows := []map[string]interface{}{
+		{"id": 1, "profile": map[string]interface{}{"email": "a@example.com", "tags": []interface{}{"ssn 123-45-6789"}}},
+	}
+
+	testServer.config.RedactPII = false
+	if masked := testServer.redactRows(rows); masked != nil {
+		t.Errorf("Expected nothing to be redacted with REDACT_PII off, got %v", masked)
+	}
+
+	testServer.config.RedactPII = true
+	masked := testServer.redactRows(rows)
+	if strings.Join(masked["profile"], ",") != "email,ssn" {
+		t.Errorf("Expected email and ssn in nested JSON, got %v", masked)
+	}
+	profile := rows[0]["profile"].(map[string]interface{})
+	if profile["email"] != "[REDACTED email]" {
+		t.Errorf("Expected nested email to be redacted, got %v", profile["email"])
+	}
+}
+
+func TestExecuteQueryRedactsPII(t *testing.T) {
+	ctx := context.Background()
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.RedactPII = true
+
+	args := QueryArgs{Query: "SELECT id, email FROM users ORDER BY id LIMIT 5"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	for _, row := range data.([]map[string]interface{}) {
+		if row["email"] != "[REDACTED email]" {
+			t.Errorf("Expected email to be redacted, got %v", row["email"])
+		}
+	}
+
+	if len(result.Content) != 2 || !strings.Contains(result.Content[1].(*mcp.TextContent).Text, "email (email)") {
+		t.Error("Expected a warning naming the masked column")
+	}
+}
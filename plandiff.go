@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// nodeTimeNoise is the self time difference, in milliseconds, below which a
+// node is not reported as slower whatever the ratio.
+const nodeTimeNoise = 0.1
+
+type ComparePlansArgs struct {
+	Query     string            `json:"query" jsonschema:"Query to explain: variant a"`
+	QueryB    string            `json:"query_b,omitempty" jsonschema:"Query of variant b (default: query again, to compare settings_a with settings_b)"`
+	SettingsA map[string]string `json:"settings_a,omitempty" jsonschema:"Session parameters for variant a, such as {\"enable_seqscan\": \"off\"}, on top of those of set_session_parameter"`
+	SettingsB map[string]string `json:"settings_b,omitempty" jsonschema:"Session parameters for variant b"`
+	Analyze   bool              `json:"analyze,omitempty" jsonschema:"Execute both variants, rolled back, to compare actual rows and timing (default: true). Statements that modify data are only estimated"`
+	Role      string            `json:"role,omitempty" jsonschema:"Run both variants as this role (SET LOCAL ROLE)"`
+}
+
+// planVariant is one side of a comparison.
+type planVariant struct {
+	Query    string
+	Settings map[string]string
+	Output   explainOutput
+}
+
+// flatPlanNode is a node with its time excluding its children's.
+type flatPlanNode struct {
+	Label    string
+	Node     planNode
+	SelfTime float64
+}
+
+// flattenPlan lists the nodes of a plan depth first.
+func flattenPlan(root planNode) []flatPlanNode {
+	var nodes []flatPlanNode
+	var walk func(node planNode)
+	walk = func(node planNode) {
+		self := nodeTime(node)
+		for _, child := range node.Plans {
+			self -= nodeTime(child)
+		}
+		label := node.label()
+		if node.Alias != "" && node.Alias != node.RelationName {
+			label += " " + node.Alias
+		}
+		nodes = append(nodes, flatPlanNode{Label: label, Node: node, SelfTime: math.Max(self, 0)})
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	walk(root)
+	return nodes
+}
+
+// nodeTime is the time a node took across its loops, 0 without ANALYZE.
+func nodeTime(node planNode) float64 {
+	if node.ActualTotalTime == nil || node.ActualLoops == nil {
+		return 0
+	}
+	return *node.ActualTotalTime * *node.ActualLoops
+}
+
+// diffPlans compares two plans node by node. Nodes are paired by label in
+// plan order, the rest only appear in one plan. Paired nodes whose own time
+// (or, without ANALYZE, cost) grew by ratio are regressions.
+func diffPlans(a, b planNode, ratio float64) map[string]interface{} {
+	nodesA, nodesB := flattenPlan(a), flattenPlan(b)
+	used := make([]bool, len(nodesB))
+	var onlyA, onlyB []string
+	var regressions, improvements []map[string]interface{}
+	for _, nodeA := range nodesA {
+		j := -1
+		for k, nodeB := range nodesB {
+			if !used[k] && nodeB.Label == nodeA.Label {
+				j = k
+				break
+			}
+		}
+		if j < 0 {
+			onlyA = append(onlyA, nodeA.Label)
+			continue
+		}
+		used[j] = true
+		nodeB := nodesB[j]
+
+		change := map[string]interface{}{"node": nodeA.Label}
+		var before, after float64
+		if nodeA.Node.ActualLoops != nil && nodeB.Node.ActualLoops != nil {
+			before, after = nodeA.SelfTime, nodeB.SelfTime
+			change["self_time_a_ms"] = round(before, 3)
+			change["self_time_b_ms"] = round(after, 3)
+			if nodeA.Node.ActualRows != nil && nodeB.Node.ActualRows != nil {
+				change["rows_a"] = *nodeA.Node.ActualRows
+				change["rows_b"] = *nodeB.Node.ActualRows
+			}
+			if math.Abs(after-before) < nodeTimeNoise {
+				continue
+			}
+		} else {
+			before, after = nodeA.Node.TotalCost, nodeB.Node.TotalCost
+			change["cost_a"] = before
+			change["cost_b"] = after
+		}
+		switch {
+		case after >= math.Max(before, nodeTimeNoise)*ratio:
+			regressions = append(regressions, change)
+		case before >= math.Max(after, nodeTimeNoise)*ratio:
+			improvements = append(improvements, change)
+		}
+	}
+	for j, nodeB := range nodesB {
+		if !used[j] {
+			onlyB = append(onlyB, nodeB.Label)
+		}
+	}
+
+	diff := map[string]interface{}{
+		"same_shape": len(onlyA) == 0 && len(onlyB) == 0 && len(nodesA) == len(nodesB),
+	}
+	if len(onlyA) > 0 {
+		diff["nodes_only_in_a"] = onlyA
+	}
+	if len(onlyB) > 0 {
+		diff["nodes_only_in_b"] = onlyB
+	}
+	if len(regressions) > 0 {
+		diff["node_regressions"] = regressions
+	}
+	if len(improvements) > 0 {
+		diff["node_improvements"] = improvements
+	}
+	return diff
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
+
+// planVerdict tells which variant is faster by execution time, or by
+// estimated cost without ANALYZE, calling differences under 10% similar.
+func planVerdict(a, b explainOutput) (string, float64) {
+	before, after := a.Plan.TotalCost, b.Plan.TotalCost
+	if a.ExecutionTime != nil && b.ExecutionTime != nil {
+		before, after = *a.ExecutionTime, *b.ExecutionTime
+	}
+	if before <= 0 {
+		return "similar", 0
+	}
+	ratio := after / before
+	switch {
+	case ratio < 0.9:
+		return "b_faster", ratio
+	case ratio > 1.1:
+		return "b_slower", ratio
+	}
+	return "similar", ratio
+}
+
+func (s *serverState) ComparePlans(ctx context.Context, req *mcp.CallToolRequest, args ComparePlansArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return s.returnErrorResult("query is required")
+	}
+	queryB := args.QueryB
+	if queryB == "" {
+		queryB = args.Query
+	}
+	if queryB == args.Query && fmt.Sprint(args.SettingsA) == fmt.Sprint(args.SettingsB) {
+		return s.returnErrorResult("Nothing to compare: set query_b, or different settings_a and settings_b")
+	}
+	for _, settings := range []map[string]string{args.SettingsA, args.SettingsB} {
+		for name := range settings {
+			if !sessionParameterNames[strings.ToLower(name)] {
+				return s.returnErrorResult("Parameter %q cannot be set, allowed parameters are: %s", name, strings.Join(sortedKeys(sessionParameterNames), ", "))
+			}
+		}
+	}
+	for _, query := range []string{args.Query, queryB} {
+		if statementCategory(query) != categoryRead && statementCategory(query) != categoryDML {
+			return s.returnErrorResult("compare_plans explains queries and data changes, not %s statements", statementKeyword(query))
+		}
+	}
+
+	analyze := getExplicitBool(getRawArgs(req), "analyze", args.Analyze, true)
+	var warnings []string
+	if analyze && (statementWrites(args.Query) || statementWrites(queryB)) {
+		analyze = false
+		warnings = append(warnings, s.localize("A variant modifies data, so both plans are estimated without ANALYZE"))
+	}
+
+	variants := []*planVariant{{Query: args.Query, Settings: args.SettingsA}, {Query: queryB, Settings: args.SettingsB}}
+	for i, variant := range variants {
+		output, notices, err := s.explainVariant(ctx, req, variant, analyze, args.Role)
+		if err != nil {
+			return s.returnErrorResult("Variant %c: %v", 'a'+i, err)
+		}
+		variant.Output = output
+		warnings = append(warnings, notices...)
+	}
+
+	a, b := variants[0].Output, variants[1].Output
+	verdict, ratio := planVerdict(a, b)
+	response := map[string]interface{}{
+		"analyzed": analyze,
+		"verdict":  verdict,
+		"diff":     diffPlans(a.Plan, b.Plan, defaultCostIncreaseRatio),
+	}
+	if ratio > 0 {
+		response["b_to_a_ratio"] = round(ratio, 3)
+	}
+	for i, variant := range variants {
+		side := map[string]interface{}{
+			"total_cost": variant.Output.Plan.TotalCost,
+			"plan_rows":  variant.Output.Plan.PlanRows,
+			"tree":       renderPlanTree(variant.Output),
+		}
+		side["query"] = variant.Query
+		if len(variant.Settings) > 0 {
+			side["settings"] = variant.Settings
+		}
+		if variant.Output.ExecutionTime != nil {
+			side["execution_time_ms"] = *variant.Output.ExecutionTime
+		}
+		if variant.Output.PlanningTime != nil {
+			side["planning_time_ms"] = *variant.Output.PlanningTime
+		}
+		response[string(rune('a'+i))] = side
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
+
+// explainVariant explains one variant in its own transaction, which is rolled
+// back so neither the settings nor executed changes outlive it.
+func (s *serverState) explainVariant(ctx context.Context, req *mcp.CallToolRequest, variant *planVariant, analyze bool, role string) (explainOutput, []string, error) {
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, role)
+	if err != nil {
+		return explainOutput{}, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, name := range sortedKeys(variant.Settings) {
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", strings.ToLower(name), variant.Settings[name]); err != nil {
+			return explainOutput{}, nil, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+	if err := s.checkStatementAccess(ctx, tx, variant.Query); err != nil {
+		return explainOutput{}, nil, err
+	}
+
+	options := "FORMAT JSON"
+	if analyze {
+		options += ", ANALYZE, BUFFERS"
+	}
+	var output []byte
+	if err := tx.QueryRow(ctx, fmt.Sprintf("EXPLAIN (%s) %s", options, variant.Query)).Scan(&output); err != nil {
+		return explainOutput{}, nil, fmt.Errorf("EXPLAIN error: %v", err)
+	}
+	recordRowsScanned(ctx, tx)
+	parsed, err := parseExplainOutput(output)
+	return parsed, notices, err
+}
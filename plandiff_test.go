@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiffPlans(t *testing.T) {
+	a, _ := parseExplainOutput([]byte(`[{"Plan": {
+		"Node Type": "Hash Join", "Total Cost": 100, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 12,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "posts", "Alias": "p", "Total Cost": 80, "Actual Rows": 1000, "Actual Loops": 1, "Actual Total Time": 10},
+			{"Node Type": "Hash", "Total Cost": 5, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 1,
+				"Plans": [{"Node Type": "Seq Scan", "Relation Name": "users", "Alias": "users", "Total Cost": 4, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 0.9}]}
+		]}, "Execution Time": 12.5}]`))
+	b, _ := parseExplainOutput([]byte(`[{"Plan": {
+		"Node Type": "Hash Join", "Total Cost": 30, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 2.5,
+		"Plans": [
+			{"Node Type": "Index Scan", "Index Name": "idx_posts_user_id", "Relation Name": "posts", "Alias": "p", "Total Cost": 10, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 0.5},
+			{"Node Type": "Hash", "Total Cost": 5, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 1,
+				"Plans": [{"Node Type": "Seq Scan", "Relation Name": "users", "Alias": "users", "Total Cost": 4, "Actual Rows": 10, "Actual Loops": 1, "Actual Total Time": 0.95}]}
+		]}, "Execution Time": 2.75}]`))
+
+	diff := diffPlans(a.Plan, b.Plan, defaultCostIncreaseRatio)
+	if diff["same_shape"] != false {
+		t.Errorf("Expected a changed shape, got %v", diff)
+	}
+	if only := diff["nodes_only_in_a"].([]string); len(only) != 1 || only[0] != "Seq Scan on posts p" {
+		t.Errorf("Unexpected nodes only in a: %v", only)
+	}
+	if only := diff["nodes_only_in_b"].([]string); len(only) != 1 || only[0] != "Index Scan using idx_posts_user_id on posts p" {
+		t.Errorf("Unexpected nodes only in b: %v", only)
+	}
+	// the join's own time went from 1ms to 1ms, the users scan changed by noise
+	if diff["node_regressions"] != nil || diff["node_improvements"] != nil {
+		t.Errorf("Expected no node-level changes, got %v", diff)
+	}
+
+	if verdict, ratio := planVerdict(a, b); verdict != "b_faster" || ratio != 0.22 {
+		t.Errorf("Unexpected verdict %s %v", verdict, ratio)
+	}
+
+	// the same nodes, the users scan slower
+	c, _ := parseExplainOutput([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "users", "Alias": "users", "Total Cost": 4, "Actual Rows": 10, "Actual Loops": 2, "Actual Total Time": 2}}]`))
+	slower := diffPlans(a.Plan.Plans[1].Plans[0], c.Plan, defaultCostIncreaseRatio)
+	regressions, _ := slower["node_regressions"].([]map[string]interface{})
+	if slower["same_shape"] != true || len(regressions) != 1 || regressions[0]["self_time_b_ms"] != 4.0 {
+		t.Errorf("Expected the users scan to be reported slower, got %v", slower)
+	}
+}
+
+func TestComparePlansTool(t *testing.T) {
+	ctx := context.Background()
+
+	args := ComparePlansArgs{
+		Query:     "SELECT * FROM posts WHERE user_id = 1",
+		SettingsA: map[string]string{"enable_indexscan": "off", "enable_bitmapscan": "off"},
+	}
+	result, data, err := testServer.ComparePlans(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ComparePlans failed: %v %v", err, result)
+	}
+	response := data.(map[string]interface{})
+	for _, key := range []string{"a", "b", "diff", "verdict"} {
+		if response[key] == nil {
+			t.Errorf("Expected %s in %v", key, response)
+		}
+	}
+
+	for _, invalid := range []ComparePlansArgs{
+		{Query: "SELECT 1"},
+		{Query: "SELECT 1", SettingsB: map[string]string{"shared_buffers": "1GB"}},
+		{Query: "SELECT 1", QueryB: "DROP TABLE posts"},
+	} {
+		if result, _, _ := testServer.ComparePlans(ctx, createMockRequest(invalid), invalid); !result.IsError {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultCostIncreaseRatio = 1.5
+
+type CheckPlanRegressionsArgs struct {
+	Names             []string `json:"names,omitempty" jsonschema:"Saved queries to check (default: every query of SAVED_QUERIES_FILE)"`
+	CostIncreaseRatio float64  `json:"cost_increase_ratio,omitempty" jsonschema:"Report a regression when the estimated total cost grows by this factor over the baseline (default: 1.5)"`
+	UpdateBaselines   bool     `json:"update_baselines,omitempty" jsonschema:"Record the current plans as the new baselines of the checked queries, after reporting against the old ones (default: false)"`
+}
+
+// planNode is a node of EXPLAIN (FORMAT JSON) output. The actual and buffer
+// fields are only present with ANALYZE, BUFFERS and WAL.
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	JoinType     string     `json:"Join Type,omitempty"`
+	RelationName string     `json:"Relation Name,omitempty"`
+	Alias        string     `json:"Alias,omitempty"`
+	IndexName    string     `json:"Index Name,omitempty"`
+	StartupCost  float64    `json:"Startup Cost"`
+	TotalCost    float64    `json:"Total Cost"`
+	PlanRows     float64    `json:"Plan Rows"`
+	Plans        []planNode `json:"Plans,omitempty"`
+
+	ActualRows       *float64 `json:"Actual Rows,omitempty"`
+	ActualLoops      *float64 `json:"Actual Loops,omitempty"`
+	ActualTotalTime  *float64 `json:"Actual Total Time,omitempty"`
+	SharedHitBlocks  *int64   `json:"Shared Hit Blocks,omitempty"`
+	SharedReadBlocks *int64   `json:"Shared Read Blocks,omitempty"`
+	WALRecords       *int64   `json:"WAL Records,omitempty"`
+	WALBytes         *int64   `json:"WAL Bytes,omitempty"`
+
+	IndexCond               string   `json:"Index Cond,omitempty"`
+	RecheckCond             string   `json:"Recheck Cond,omitempty"`
+	HashCond                string   `json:"Hash Cond,omitempty"`
+	MergeCond               string   `json:"Merge Cond,omitempty"`
+	JoinFilter              string   `json:"Join Filter,omitempty"`
+	Filter                  string   `json:"Filter,omitempty"`
+	RowsRemovedByFilter     float64  `json:"Rows Removed by Filter,omitempty"`
+	RowsRemovedByJoinFilter float64  `json:"Rows Removed by Join Filter,omitempty"`
+	SortKey                 []string `json:"Sort Key,omitempty"`
+	SortMethod              string   `json:"Sort Method,omitempty"`
+	SortSpaceUsed           int64    `json:"Sort Space Used,omitempty"`
+}
+
+// label names a node the way EXPLAIN's text format does.
+func (n planNode) label() string {
+	label := n.NodeType
+	if n.IndexName != "" {
+		label += " using " + n.IndexName
+	}
+	if n.RelationName != "" {
+		label += " on " + n.RelationName
+	}
+	return label
+}
+
+// planSummary is what a baseline keeps of a plan: enough to tell a changed
+// shape, lost index access and a cost jump, without the full plan.
+type planSummary struct {
+	TotalCost  float64   `json:"total_cost"`
+	PlanRows   float64   `json:"plan_rows"`
+	Nodes      []string  `json:"nodes"`
+	Indexes    []string  `json:"indexes,omitempty"`
+	SeqScans   []string  `json:"seq_scans,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+func summarizePlan(root planNode) planSummary {
+	summary := planSummary{TotalCost: root.TotalCost, PlanRows: root.PlanRows, RecordedAt: time.Now().UTC()}
+	var walk func(node planNode, depth int)
+	walk = func(node planNode, depth int) {
+		summary.Nodes = append(summary.Nodes, strings.Repeat("  ", depth)+node.label())
+		if node.IndexName != "" && !slices.Contains(summary.Indexes, node.IndexName) {
+			summary.Indexes = append(summary.Indexes, node.IndexName)
+		}
+		if node.NodeType == "Seq Scan" && !slices.Contains(summary.SeqScans, node.RelationName) {
+			summary.SeqScans = append(summary.SeqScans, node.RelationName)
+		}
+		for _, child := range node.Plans {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+	sort.Strings(summary.Indexes)
+	sort.Strings(summary.SeqScans)
+	return summary
+}
+
+// comparePlans checks a current plan against its baseline: regressed when an
+// index stopped being used, a table is newly scanned sequentially or the cost
+// grew by costRatio, changed when only the shape differs.
+func comparePlans(baseline, current planSummary, costRatio float64) (string, []string) {
+	var regressions, changes []string
+	for _, index := range baseline.Indexes {
+		if !slices.Contains(current.Indexes, index) {
+			regressions = append(regressions, fmt.Sprintf("Index %s is no longer used", index))
+		}
+	}
+	for _, table := range current.SeqScans {
+		if !slices.Contains(baseline.SeqScans, table) {
+			regressions = append(regressions, fmt.Sprintf("%s is now read with a Seq Scan", table))
+		}
+	}
+	if baseline.TotalCost > 0 && current.TotalCost >= baseline.TotalCost*costRatio {
+		regressions = append(regressions, fmt.Sprintf("Estimated cost grew %.1fx, from %.2f to %.2f", current.TotalCost/baseline.TotalCost, baseline.TotalCost, current.TotalCost))
+	}
+	for _, index := range current.Indexes {
+		if !slices.Contains(baseline.Indexes, index) {
+			changes = append(changes, fmt.Sprintf("Index %s is now used", index))
+		}
+	}
+	if !slices.Equal(baseline.Nodes, current.Nodes) && len(regressions) == 0 {
+		changes = append(changes, "The plan shape changed")
+	}
+
+	switch {
+	case len(regressions) > 0:
+		return "regressed", append(regressions, changes...)
+	case len(changes) > 0:
+		return "changed", changes
+	}
+	return "ok", nil
+}
+
+// explainPlan returns the estimated plan of a statement, without running it.
+func explainPlan(ctx context.Context, tx pgx.Tx, query string) (planNode, error) {
+	var output []byte
+	if err := tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&output); err != nil {
+		return planNode{}, err
+	}
+	parsed, err := parseExplainOutput(output)
+	if err != nil {
+		return planNode{}, err
+	}
+	return parsed.Plan, nil
+}
+
+// parseExplainOutput reads the output of EXPLAIN (FORMAT JSON) for a single
+// statement.
+func parseExplainOutput(output []byte) (explainOutput, error) {
+	var plans []explainOutput
+	if err := json.Unmarshal(output, &plans); err != nil {
+		return explainOutput{}, fmt.Errorf("failed to parse the plan: %v", err)
+	}
+	if len(plans) == 0 {
+		return explainOutput{}, fmt.Errorf("EXPLAIN returned no plan")
+	}
+	return plans[0], nil
+}
+
+// explainInSavepoint checks a statement's access and explains it inside a
+// savepoint, so a statement that fails leaves the transaction usable for the
+// next one.
+func (s *serverState) explainInSavepoint(ctx context.Context, tx pgx.Tx, query string) (planNode, error) {
+	if err := s.checkStatementAccess(ctx, tx, query); err != nil {
+		return planNode{}, err
+	}
+	if _, err := tx.Exec(ctx, "SAVEPOINT plan_check"); err != nil {
+		return planNode{}, fmt.Errorf("failed to create savepoint: %v", err)
+	}
+	plan, err := explainPlan(ctx, tx, query)
+	if err != nil {
+		tx.Exec(ctx, "ROLLBACK TO SAVEPOINT plan_check")
+		return planNode{}, err
+	}
+	return plan, nil
+}
+
+// writeJSONFile rewrites a JSON file the server keeps state in, encrypting
+// it like the other files the server writes when ENCRYPTION_KEY is set.
+func (s *serverState) writeJSONFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = s.writeArtifact(path, append(data, '\n'))
+	return err
+}
+
+// savedQuery is an entry of SAVED_QUERIES_FILE, the library of named
+// queries an application relies on. Baselines are written back by
+// check_plan_regressions.
+type savedQuery struct {
+	Query       string       `json:"query"`
+	Description string       `json:"description,omitempty"`
+	Baseline    *planSummary `json:"baseline,omitempty"`
+}
+
+// savedQueriesMu serializes rewrites of SAVED_QUERIES_FILE.
+var savedQueriesMu sync.Mutex
+
+func (s *serverState) loadSavedQueries(path string) (map[string]savedQuery, error) {
+	data, err := s.readArtifact(path)
+	if err != nil {
+		return nil, err
+	}
+	var queries map[string]savedQuery
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&queries); err != nil {
+		return nil, err
+	}
+	for name, query := range queries {
+		if strings.TrimSpace(query.Query) == "" {
+			return nil, fmt.Errorf("saved query %s has no query", name)
+		}
+	}
+	return queries, nil
+}
+
+func (s *serverState) CheckPlanRegressions(ctx context.Context, req *mcp.CallToolRequest, args CheckPlanRegressionsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if s.config.SavedQueriesFile == "" {
+		return s.returnErrorResult("No saved queries, set SAVED_QUERIES_FILE to a JSON file of named queries")
+	}
+	costRatio := args.CostIncreaseRatio
+	if costRatio <= 0 {
+		costRatio = defaultCostIncreaseRatio
+	}
+
+	savedQueriesMu.Lock()
+	defer savedQueriesMu.Unlock()
+	queries, err := s.loadSavedQueries(s.config.SavedQueriesFile)
+	if err != nil {
+		return s.returnErrorResult("Failed to read SAVED_QUERIES_FILE: %v", err)
+	}
+	names := args.Names
+	if len(names) == 0 {
+		names = sortedKeys(queries)
+	}
+	for _, name := range names {
+		if _, ok := queries[name]; !ok {
+			return s.returnErrorResult("No saved query named %q", name)
+		}
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var results []map[string]interface{}
+	counts := make(map[string]int)
+	for _, name := range names {
+		saved := queries[name]
+		result := map[string]interface{}{"name": name}
+		results = append(results, result)
+
+		plan, err := s.explainInSavepoint(ctx, tx, saved.Query)
+		if err != nil {
+			result["status"] = "error"
+			result["error"] = err.Error()
+			counts["error"]++
+			continue
+		}
+		current := summarizePlan(plan)
+		result["current"] = current
+
+		if saved.Baseline == nil {
+			result["status"] = "no_baseline"
+		} else {
+			status, findings := comparePlans(*saved.Baseline, current, costRatio)
+			result["status"] = status
+			result["baseline"] = saved.Baseline
+			if len(findings) > 0 {
+				result["findings"] = findings
+			}
+		}
+		counts[result["status"].(string)]++
+
+		if args.UpdateBaselines {
+			saved.Baseline = &current
+			queries[name] = saved
+		}
+	}
+
+	response := map[string]interface{}{
+		"checked":   len(names),
+		"summary":   counts,
+		"queries":   results,
+		"regressed": counts["regressed"] > 0,
+	}
+	if args.UpdateBaselines {
+		if err := s.writeJSONFile(s.config.SavedQueriesFile, queries); err != nil {
+			return s.returnErrorResult("Failed to write the baselines to SAVED_QUERIES_FILE: %v", err)
+		}
+		response["baselines_updated"] = true
+	}
+
+	var warnings []string
+	if counts["regressed"] > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("%d of %d saved queries have plan regressions"), counts["regressed"], len(names)))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, append(notices, warnings...)), data, err
+}
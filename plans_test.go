@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComparePlans(t *testing.T) {
+	scans := []planNode{
+		{NodeType: "Index Scan", RelationName: "users", IndexName: "users_pkey", TotalCost: 8},
+		{NodeType: "Index Scan", RelationName: "posts", IndexName: "idx_posts_user_id", TotalCost: 12},
+	}
+	indexed := summarizePlan(planNode{NodeType: "Nested Loop", TotalCost: 20, Plans: scans})
+	if len(indexed.Nodes) != 3 || indexed.Nodes[1] != "  Index Scan using users_pkey on users" {
+		t.Errorf("Unexpected plan nodes %v", indexed.Nodes)
+	}
+
+	if status, findings := comparePlans(indexed, indexed, 1.5); status != "ok" || len(findings) != 0 {
+		t.Errorf("Expected an unchanged plan to be ok, got %s %v", status, findings)
+	}
+
+	scanned := summarizePlan(planNode{NodeType: "Hash Join", TotalCost: 400, Plans: []planNode{
+		{NodeType: "Index Scan", RelationName: "users", IndexName: "users_pkey", TotalCost: 8},
+		{NodeType: "Seq Scan", RelationName: "posts", TotalCost: 380},
+	}})
+	status, findings := comparePlans(indexed, scanned, 1.5)
+	if status != "regressed" || len(findings) != 3 {
+		t.Errorf("Expected a lost index, a new seq scan and a cost jump, got %s %v", status, findings)
+	}
+
+	cheaper := summarizePlan(planNode{NodeType: "Merge Join", TotalCost: 18, Plans: scans})
+	if status, _ := comparePlans(indexed, cheaper, 1.5); status != "changed" {
+		t.Errorf("Expected a new shape at a similar cost to be changed, got %s", status)
+	}
+}
+
+func TestCheckPlanRegressions(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "queries.json")
+	os.WriteFile(path, []byte(`{
+		"user_posts": {"query": "SELECT * FROM posts WHERE user_id = 42", "description": "Posts of a user"},
+		"broken": {"query": "SELECT * FROM no_such_table"}
+	}`), 0o600)
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.SavedQueriesFile = path
+
+	args := CheckPlanRegressionsArgs{UpdateBaselines: true}
+	result, data, err := testServer.CheckPlanRegressions(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("CheckPlanRegressions failed: %v %v", err, result)
+	}
+	if summary := data.(map[string]interface{})["summary"].(map[string]int); summary["no_baseline"] != 1 || summary["error"] != 1 {
+		t.Errorf("Expected one query without baseline and one error, got %v", summary)
+	}
+
+	args = CheckPlanRegressionsArgs{Names: []string{"user_posts"}}
+	_, data, err = testServer.CheckPlanRegressions(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("CheckPlanRegressions failed: %v", err)
+	}
+	if summary := data.(map[string]interface{})["summary"].(map[string]int); summary["ok"] != 1 {
+		t.Errorf("Expected the recorded baseline to match, got %v", summary)
+	}
+}
+
+func TestPlanStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "plans.json")
+
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.PlanStoreFile = path
+
+	saveArgs := SavePlanBaselineArgs{Query: "SELECT * FROM posts WHERE user_id = 42", Description: "Posts of a user"}
+	result, data, err := testServer.SavePlanBaseline(ctx, createMockRequest(saveArgs), saveArgs)
+	if err != nil || result.IsError {
+		t.Fatalf("SavePlanBaseline failed: %v %v", err, result)
+	}
+	fingerprint := data.(map[string]interface{})["fingerprint"].(string)
+	store, err := testServer.loadPlanStore(path)
+	if err != nil || store[fingerprint].Description != "Posts of a user" {
+		t.Fatalf("Expected the baseline to be stored under %s, got %v %v", fingerprint, store, err)
+	}
+
+	args := CheckPlanRegressionArgs{Query: "select * from posts where user_id = 7"}
+	_, data, err = testServer.CheckPlanRegression(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("CheckPlanRegression failed: %v", err)
+	}
+	if summary := data.(map[string]interface{})["summary"].(map[string]int); summary["ok"] != 1 {
+		t.Errorf("Expected the same query with other constants to match the baseline, got %v", summary)
+	}
+
+	args = CheckPlanRegressionArgs{Fingerprint: "0000000000000000"}
+	result, _, _ = testServer.CheckPlanRegression(ctx, createMockRequest(args), args)
+	if !result.IsError {
+		t.Errorf("Expected an unknown fingerprint to be an error")
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type SavePlanBaselineArgs struct {
+	Query       string `json:"query" jsonschema:"Query whose current plan becomes the baseline of its fingerprint"`
+	Description string `json:"description,omitempty" jsonschema:"What the query is for, kept with the baseline"`
+}
+
+type CheckPlanRegressionArgs struct {
+	Query             string  `json:"query,omitempty" jsonschema:"Query to check against the baseline of its fingerprint. The constants may differ from those the baseline was recorded with"`
+	Fingerprint       string  `json:"fingerprint,omitempty" jsonschema:"Fingerprint of a stored baseline to check, re-running the query it was recorded with"`
+	CostIncreaseRatio float64 `json:"cost_increase_ratio,omitempty" jsonschema:"Report a regression when the estimated total cost grows by this factor over the baseline (default: 1.5)"`
+}
+
+// planBaseline is an entry of PLAN_STORE_FILE, keyed by query fingerprint.
+// Query is the statement the baseline was recorded with, so it can be
+// explained again.
+type planBaseline struct {
+	Normalized  string      `json:"normalized"`
+	Query       string      `json:"query"`
+	Description string      `json:"description,omitempty"`
+	Baseline    planSummary `json:"baseline"`
+}
+
+// planStoreMu serializes rewrites of PLAN_STORE_FILE.
+var planStoreMu sync.Mutex
+
+// loadPlanStore reads PLAN_STORE_FILE. A missing file is an empty store,
+// created by the first save_plan_baseline.
+func (s *serverState) loadPlanStore(path string) (map[string]planBaseline, error) {
+	data, err := s.readArtifact(path)
+	if os.IsNotExist(err) {
+		return make(map[string]planBaseline), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	store := make(map[string]planBaseline)
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *serverState) SavePlanBaseline(ctx context.Context, req *mcp.CallToolRequest, args SavePlanBaselineArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return s.returnErrorResult("query is required")
+	}
+	if s.config.PlanStoreFile == "" {
+		return s.returnErrorResult("No plan store, set PLAN_STORE_FILE to the JSON file baselines are kept in")
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	plan, err := s.explainInSavepoint(ctx, tx, args.Query)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	planStoreMu.Lock()
+	defer planStoreMu.Unlock()
+	store, err := s.loadPlanStore(s.config.PlanStoreFile)
+	if err != nil {
+		return s.returnErrorResult("Failed to read PLAN_STORE_FILE: %v", err)
+	}
+	fingerprint := queryFingerprint(args.Query)
+	previous, replaced := store[fingerprint]
+	entry := planBaseline{
+		Normalized:  normalizeQuery(args.Query),
+		Query:       args.Query,
+		Description: args.Description,
+		Baseline:    summarizePlan(plan),
+	}
+	if entry.Description == "" {
+		entry.Description = previous.Description
+	}
+	store[fingerprint] = entry
+	if err := s.writeJSONFile(s.config.PlanStoreFile, store); err != nil {
+		return s.returnErrorResult("Failed to write PLAN_STORE_FILE: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"fingerprint": fingerprint,
+		"normalized":  entry.Normalized,
+		"baseline":    entry.Baseline,
+		"replaced":    replaced,
+	}
+	if replaced {
+		response["previous_baseline"] = previous.Baseline
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, notices), data, err
+}
+
+func (s *serverState) CheckPlanRegression(ctx context.Context, req *mcp.CallToolRequest, args CheckPlanRegressionArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if s.config.PlanStoreFile == "" {
+		return s.returnErrorResult("No plan store, set PLAN_STORE_FILE to the JSON file baselines are kept in")
+	}
+	costRatio := args.CostIncreaseRatio
+	if costRatio <= 0 {
+		costRatio = defaultCostIncreaseRatio
+	}
+	if args.Query != "" && args.Fingerprint != "" && queryFingerprint(args.Query) != args.Fingerprint {
+		return s.returnErrorResult("query has fingerprint %s, not %s: pass one of them", queryFingerprint(args.Query), args.Fingerprint)
+	}
+
+	planStoreMu.Lock()
+	store, err := s.loadPlanStore(s.config.PlanStoreFile)
+	planStoreMu.Unlock()
+	if err != nil {
+		return s.returnErrorResult("Failed to read PLAN_STORE_FILE: %v", err)
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// a query, a stored fingerprint, or every baseline of the store
+	checks := make(map[string]string)
+	switch {
+	case args.Query != "":
+		checks[queryFingerprint(args.Query)] = args.Query
+	case args.Fingerprint != "":
+		entry, ok := store[args.Fingerprint]
+		if !ok {
+			return s.returnErrorResult("No baseline with fingerprint %s", args.Fingerprint)
+		}
+		checks[args.Fingerprint] = entry.Query
+	default:
+		for fingerprint, entry := range store {
+			checks[fingerprint] = entry.Query
+		}
+	}
+
+	var results []map[string]interface{}
+	counts := make(map[string]int)
+	for _, fingerprint := range sortedKeys(checks) {
+		result := map[string]interface{}{"fingerprint": fingerprint}
+		results = append(results, result)
+		entry, ok := store[fingerprint]
+		if ok {
+			result["normalized"] = entry.Normalized
+			if entry.Description != "" {
+				result["description"] = entry.Description
+			}
+		}
+
+		plan, err := s.explainInSavepoint(ctx, tx, checks[fingerprint])
+		if err != nil {
+			result["status"] = "error"
+			result["error"] = err.Error()
+			counts["error"]++
+			continue
+		}
+		current := summarizePlan(plan)
+		result["current"] = current
+		if !ok {
+			result["status"] = "no_baseline"
+			result["normalized"] = normalizeQuery(checks[fingerprint])
+		} else {
+			status, findings := comparePlans(entry.Baseline, current, costRatio)
+			result["status"] = status
+			result["baseline"] = entry.Baseline
+			if len(findings) > 0 {
+				result["findings"] = findings
+			}
+		}
+		counts[result["status"].(string)]++
+	}
+
+	response := map[string]interface{}{
+		"checked":   len(checks),
+		"summary":   counts,
+		"queries":   results,
+		"regressed": counts["regressed"] > 0,
+	}
+	var warnings []string
+	if counts["regressed"] > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("%d of %d checked queries have plan regressions"), counts["regressed"], len(checks)))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, append(notices, warnings...)), data, err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// misestimateFactor is how far actual rows may stray from the estimate
+// before a node is flagged.
+const misestimateFactor = 10
+
+// explainOutput is an element of EXPLAIN (FORMAT JSON) output.
+type explainOutput struct {
+	Plan          planNode          `json:"Plan"`
+	PlanningTime  *float64          `json:"Planning Time,omitempty"`
+	ExecutionTime *float64          `json:"Execution Time,omitempty"`
+	Triggers      []explainTrigger  `json:"Triggers,omitempty"`
+	Settings      map[string]string `json:"Settings,omitempty"`
+	Planning      *struct {
+		MemoryUsed      *int64 `json:"Memory Used,omitempty"`
+		MemoryAllocated *int64 `json:"Memory Allocated,omitempty"`
+	} `json:"Planning,omitempty"`
+}
+
+type explainTrigger struct {
+	Name     string  `json:"Trigger Name"`
+	Relation string  `json:"Relation"`
+	Time     float64 `json:"Time"`
+	Calls    float64 `json:"Calls"`
+}
+
+// renderPlanTree renders an EXPLAIN (FORMAT JSON) plan as an indented tree,
+// one line per node with actual against estimated rows, time and buffers,
+// and the conditions of the node below it.
+func renderPlanTree(output explainOutput) string {
+	var out strings.Builder
+	var render func(node planNode, prefix, childPrefix string)
+	render = func(node planNode, prefix, childPrefix string) {
+		out.WriteString(prefix + planNodeLine(node) + "\n")
+		for _, detail := range planNodeDetails(node) {
+			out.WriteString(childPrefix + "    " + detail + "\n")
+		}
+		for i, child := range node.Plans {
+			if i == len(node.Plans)-1 {
+				render(child, childPrefix+"`- ", childPrefix+"   ")
+			} else {
+				render(child, childPrefix+"|- ", childPrefix+"|  ")
+			}
+		}
+	}
+	render(output.Plan, "", "")
+
+	var totals []string
+	if output.PlanningTime != nil {
+		totals = append(totals, fmt.Sprintf("planning %.3f ms", *output.PlanningTime))
+	}
+	if output.ExecutionTime != nil {
+		totals = append(totals, fmt.Sprintf("execution %.3f ms", *output.ExecutionTime))
+	}
+	if output.Planning != nil && output.Planning.MemoryUsed != nil {
+		totals = append(totals, fmt.Sprintf("planner memory %d kB", *output.Planning.MemoryUsed))
+	}
+	if len(totals) > 0 {
+		out.WriteString(strings.Join(totals, ", ") + "\n")
+	}
+	if len(output.Settings) > 0 {
+		var settings []string
+		for _, name := range sortedKeys(output.Settings) {
+			settings = append(settings, name+"="+output.Settings[name])
+		}
+		out.WriteString("settings: " + strings.Join(settings, ", ") + "\n")
+	}
+	for _, trigger := range output.Triggers {
+		fmt.Fprintf(&out, "trigger %s on %s: %.3f ms, %.0f calls\n", trigger.Name, trigger.Relation, trigger.Time, trigger.Calls)
+	}
+	return out.String()
+}
+
+// planNodeLine is a node's name followed by its measurements. Rows are per
+// loop, as EXPLAIN reports them, time covers all loops.
+func planNodeLine(node planNode) string {
+	name := node.NodeType
+	if node.JoinType != "" && node.JoinType != "Inner" {
+		if strings.HasSuffix(name, " Join") {
+			name = strings.TrimSuffix(name, " Join") + " " + node.JoinType + " Join"
+		} else {
+			name += " " + node.JoinType + " Join"
+		}
+	}
+	if node.IndexName != "" {
+		name += " using " + node.IndexName
+	}
+	if node.RelationName != "" {
+		name += " on " + node.RelationName
+		if node.Alias != "" && node.Alias != node.RelationName {
+			name += " " + node.Alias
+		}
+	}
+
+	var parts []string
+	switch {
+	case node.ActualLoops == nil:
+		parts = append(parts, fmt.Sprintf("est rows=%s cost=%.2f", formatCount(node.PlanRows), node.TotalCost))
+	case *node.ActualLoops == 0:
+		parts = append(parts, "never executed")
+	default:
+		loops := *node.ActualLoops
+		rows := fmt.Sprintf("rows=%s est=%s", formatCount(*node.ActualRows), formatCount(node.PlanRows))
+		if loops > 1 {
+			rows += fmt.Sprintf(" loops=%s", formatCount(loops))
+		}
+		parts = append(parts, rows)
+		if node.ActualTotalTime != nil {
+			parts = append(parts, fmt.Sprintf("time=%.3fms", *node.ActualTotalTime*loops))
+		}
+		if factor := misestimate(*node.ActualRows, node.PlanRows); factor >= misestimateFactor {
+			direction := "under"
+			if node.PlanRows > *node.ActualRows {
+				direction = "over"
+			}
+			parts = append(parts, fmt.Sprintf("(!) %sestimated %.0fx", direction, factor))
+		}
+	}
+	if node.SharedHitBlocks != nil || node.SharedReadBlocks != nil {
+		buffers := fmt.Sprintf("hit=%d", valueOrZero(node.SharedHitBlocks))
+		if read := valueOrZero(node.SharedReadBlocks); read > 0 {
+			buffers += fmt.Sprintf(" read=%d", read)
+		}
+		parts = append(parts, buffers)
+	}
+	if node.WALBytes != nil {
+		parts = append(parts, fmt.Sprintf("wal=%d records %s", valueOrZero(node.WALRecords), formatBytes(float64(*node.WALBytes))))
+	}
+	return name + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// planNodeDetails are the conditions of a node and the rows they removed.
+func planNodeDetails(node planNode) []string {
+	var details []string
+	for _, condition := range []struct {
+		Label string
+		Value string
+	}{
+		{"index cond", node.IndexCond},
+		{"recheck cond", node.RecheckCond},
+		{"hash cond", node.HashCond},
+		{"merge cond", node.MergeCond},
+		{"join filter", node.JoinFilter},
+		{"filter", node.Filter},
+		{"sort key", strings.Join(node.SortKey, ", ")},
+	} {
+		if condition.Value != "" {
+			details = append(details, condition.Label+": "+condition.Value)
+		}
+	}
+	if node.RowsRemovedByFilter > 0 {
+		details = append(details, "rows removed by filter: "+formatCount(node.RowsRemovedByFilter))
+	}
+	if node.RowsRemovedByJoinFilter > 0 {
+		details = append(details, "rows removed by join filter: "+formatCount(node.RowsRemovedByJoinFilter))
+	}
+	if node.SortMethod != "" {
+		details = append(details, fmt.Sprintf("sort method: %s, %d kB", node.SortMethod, node.SortSpaceUsed))
+	}
+	return details
+}
+
+// misestimate is the factor between actual and estimated rows, counting
+// zero rows as one like the planner does.
+func misestimate(actual, estimated float64) float64 {
+	actual, estimated = math.Max(actual, 1), math.Max(estimated, 1)
+	return math.Max(actual/estimated, estimated/actual)
+}
+
+// formatCount prints a row count without a fraction when it has none.
+func formatCount(count float64) string {
+	if count == math.Trunc(count) {
+		return fmt.Sprintf("%.0f", count)
+	}
+	return fmt.Sprintf("%.2f", count)
+}
+
+func valueOrZero(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRenderPlanTree(t *testing.T) {
+	plan, err := parseExplainOutput([]byte(`[{
+		"Plan": {
+			"Node Type": "Hash Join", "Join Type": "Left", "Total Cost": 42.5, "Plan Rows": 10,
+			"Actual Rows": 250, "Actual Loops": 1, "Actual Total Time": 3.25, "Shared Hit Blocks": 12, "Shared Read Blocks": 3,
+			"Hash Cond": "(p.user_id = u.id)",
+			"Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "posts", "Alias": "p", "Total Cost": 20, "Plan Rows": 250,
+					"Actual Rows": 250, "Actual Loops": 1, "Actual Total Time": 1.5, "Filter": "(user_id > 10)", "Rows Removed by Filter": 30},
+				{"Node Type": "Hash", "Total Cost": 10, "Plan Rows": 5, "Actual Rows": 0, "Actual Loops": 0,
+					"Plans": [{"Node Type": "Index Scan", "Index Name": "users_pkey", "Relation Name": "users", "Alias": "users",
+						"Total Cost": 8, "Plan Rows": 5, "Actual Rows": 2.5, "Actual Loops": 4, "Actual Total Time": 0.5}]}
+			]
+		},
+		"Planning Time": 0.125,
+		"Execution Time": 3.5,
+		"Triggers": [{"Trigger Name": "audit", "Relation": "posts", "Time": 0.25, "Calls": 2}]
+	}]`))
+	if err != nil {
+		t.Fatalf("parseExplainOutput failed: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"Hash Left Join (rows=250 est=10, time=3.250ms, (!) underestimated 25x, hit=12 read=3)",
+		"    hash cond: (p.user_id = u.id)",
+		"|- Seq Scan on posts p (rows=250 est=250, time=1.500ms)",
+		"|      filter: (user_id > 10)",
+		"|      rows removed by filter: 30",
+		"`- Hash (never executed)",
+		"   `- Index Scan using users_pkey on users (rows=2.50 est=5 loops=4, time=2.000ms)",
+		"planning 0.125 ms, execution 3.500 ms",
+		"trigger audit on posts: 0.250 ms, 2 calls",
+	}, "\n") + "\n"
+	if got := renderPlanTree(plan); got != want {
+		t.Errorf("renderPlanTree() =\n%s\nwant\n%s", got, want)
+	}
+
+	// without ANALYZE only estimates are shown
+	plan, _ = parseExplainOutput([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "users", "Alias": "users", "Total Cost": 1.5, "Plan Rows": 3}}]`))
+	if got := renderPlanTree(plan); got != "Seq Scan on users (est rows=3 cost=1.50)\n" {
+		t.Errorf("Unexpected estimated tree %q", got)
+	}
+}
+
+func TestExplainAnalyzeRenderTree(t *testing.T) {
+	ctx := context.Background()
+
+	args := ExplainAnalyzeArgs{Query: "SELECT p.title FROM posts p JOIN users u ON u.id = p.user_id", Render: "tree"}
+	result, data, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExplainAnalyze failed: %v %v", err, result)
+	}
+	tree := data.(string)
+	if !strings.Contains(tree, "Join") || !strings.Contains(tree, "rows=") || !strings.Contains(tree, "execution ") {
+		t.Errorf("Unexpected tree:\n%s", tree)
+	}
+
+	args = ExplainAnalyzeArgs{Query: "SELECT 1", Render: "graph"}
+	if result, _, _ := testServer.ExplainAnalyze(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected an unknown render to be rejected")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultQueryPolicy keeps the query tool read-only: reads and session
+// statements run in a read-only transaction, everything else is refused.
+var defaultQueryPolicy = map[string]string{
+	categoryRead:        "allow",
+	categoryDML:         "block",
+	categoryDDL:         "block",
+	categoryMaintenance: "block",
+	categoryOther:       "allow",
+}
+
+// writeCategories run in a read-write transaction when allowed, or wait for
+// approval when set to confirm.
+var writeCategories = map[string]bool{
+	categoryDML:         true,
+	categoryDDL:         true,
+	categoryMaintenance: true,
+}
+
+// parseQueryPolicy reads QUERY_POLICY, e.g. "dml=confirm,maintenance=allow".
+// Categories left out keep their default.
+func parseQueryPolicy(value string) (map[string]string, error) {
+	policy := make(map[string]string)
+	for category, action := range defaultQueryPolicy {
+		policy[category] = action
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		category, action, ok := strings.Cut(entry, "=")
+		category, action = strings.ToLower(strings.TrimSpace(category)), strings.ToLower(strings.TrimSpace(action))
+		if _, known := defaultQueryPolicy[category]; !ok || !known {
+			return nil, fmt.Errorf("unknown category in %q, use read, dml, ddl, maintenance or other", entry)
+		}
+		switch {
+		case action != "allow" && action != "confirm" && action != "block":
+			return nil, fmt.Errorf("unknown action in %q, use allow, confirm or block", entry)
+		case action == "confirm" && !writeCategories[category]:
+			return nil, fmt.Errorf("%s statements can only be allowed or blocked", category)
+		}
+		policy[category] = action
+	}
+	return policy, nil
+}
+
+func (s *serverState) queryPolicyAction(category string) string {
+	if action, ok := s.config.QueryPolicy[category]; ok {
+		return action
+	}
+	return defaultQueryPolicy[category]
+}
+
+// executePolicyWrite runs a dml, ddl or maintenance statement the policy lets
+// through. Confirmed categories (and every write with REQUIRE_APPROVAL) are
+// queued for approval instead. With dry_run the statement is rolled back,
+// but triggers, sequences, NOTIFY and foreign tables still see it run, so it
+// needs ALLOW_WRITES too and is refused where the write needs approval.
+func (s *serverState) executePolicyWrite(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs, category, action string, level pgx.TxIsoLevel) (*mcp.CallToolResult, any, error) {
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("query")
+	}
+
+	if action == "confirm" || s.config.RequireApproval {
+		if args.DryRun {
+			return s.returnErrorResult("dry_run still runs the statement, which needs approval here like the write itself. Call again without dry_run to queue it")
+		}
+		if err := s.checkStatementAccess(ctx, s.pool, args.Query); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if args.Role != "" && outsideTransaction(args.Query) {
+			return s.returnErrorResult("role %s cannot be set for %s, which runs outside a transaction", args.Role, statementKeyword(args.Query))
+		}
+		change, err := s.queuePendingChange(ctx, req, &pendingChange{
+			Tool:      "query",
+			Summary:   fmt.Sprintf("Run %s statement: %s", category, strings.Join(strings.Fields(args.Query), " ")),
+			Statement: args.Query,
+			Role:      args.Role,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	return s.executeWrite(ctx, req, args, category, level, 0)
+}
+
+// executeWrite runs a write statement in a read-write transaction and
+// returns its command tag and RETURNING rows. A write affecting more than
+// maxRows rows, when set, is rolled back.
+func (s *serverState) executeWrite(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs, category string, level pgx.TxIsoLevel, maxRows int64) (*mcp.CallToolResult, any, error) {
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{IsoLevel: level}, args.Role)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.checkStatementAccess(ctx, tx, args.Query); err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	query, params, rewrites := rewriteInLists(args.Query, s.config.InListThreshold)
+	var results []map[string]interface{}
+	rows, err := tx.Query(ctx, query, params...)
+	if err == nil {
+		results, err = collectRows(rows)
+	}
+	if serializationFailure(err) {
+		return nil, nil, err
+	} else if err != nil {
+		return s.returnErrorResult("Query error: %v", err)
+	}
+	tag := rows.CommandTag()
+	if maxRows > 0 && tag.RowsAffected() > maxRows {
+		return s.returnErrorResult("The statement affected %d rows, more than max_rows (%d), and was rolled back", tag.RowsAffected(), maxRows)
+	}
+	fields := slices.Clone(rows.FieldDescriptions())
+	policies, err := s.resultColumnPolicies(ctx, tx, fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+	}
+
+	if args.DryRun {
+		if err := tx.Rollback(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to roll back transaction: %v", err)
+		}
+	} else if err := s.finishWrite(ctx, tx); serializationFailure(err) {
+		return nil, nil, err
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	response := s.labelDryRun(map[string]interface{}{
+		"category":      category,
+		"command_tag":   tag.String(),
+		"rows_affected": tag.RowsAffected(),
+	})
+	if maxRows > 0 {
+		response["max_rows"] = maxRows
+	}
+	if args.DryRun {
+		response["simulated"] = true
+		response["notice"] = s.localize("dry_run: this ran inside a transaction that was rolled back, nothing was persisted")
+	}
+	truncated, omitted := encodeBinaryRows(results, args.BinaryFormat, s.config.MaxBinaryBytes)
+	truncatedJSON, err := shapeJSONRows(results, jsonColumns(fields), args.JSONPaths, args.JSONFormat, s.config.MaxJSONBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyColumnPolicies(results, policies)
+	masked := s.redactRows(results)
+	encodeNumericRows(results, args.NumericFormat)
+	encodeIntervalRows(results, args.IntervalFormat)
+	if len(results) > 0 {
+		response["rows"] = results
+	}
+
+	var sideEffects []string
+	if args.DryRun {
+		if sideEffects, err = s.sideEffectWarnings(ctx, args.Query); err != nil {
+			sideEffects = append(sideEffects, fmt.Sprintf("Could not check triggers and rules: %v", err))
+		}
+	} else {
+		analyzed, err := s.autoAnalyze(ctx, args.Query, tag.RowsAffected())
+		if err != nil {
+			response["analyze_error"] = err.Error()
+		}
+		if len(analyzed) > 0 {
+			response["analyzed"] = analyzed
+		}
+	}
+
+	result, data, err := returnJSONResult(response)
+	warnings := append(notices, sideEffects...)
+	warnings = append(warnings, s.inListWarnings(rewrites)...)
+	warnings = append(warnings, s.binaryWarnings(truncated, omitted)...)
+	warnings = append(warnings, s.jsonWarnings(truncatedJSON)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseQueryPolicy(t *testing.T) {
+	policy, err := parseQueryPolicy(" DML=confirm , maintenance=allow")
+	if err != nil {
+		t.Fatalf("parseQueryPolicy failed: %v", err)
+	}
+	if policy["dml"] != "confirm" || policy["maintenance"] != "allow" || policy["ddl"] != "block" || policy["read"] != "allow" {
+		t.Errorf("Unexpected policy %v", policy)
+	}
+
+	for _, invalid := range []string{"writes=allow", "dml=maybe", "read=confirm", "dml"} {
+		if _, err := parseQueryPolicy(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestQueryPolicy(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	run := func(query string) (*mcp.CallToolResult, any) {
+		t.Helper()
+		args := QueryArgs{Query: query}
+		result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		return result, data
+	}
+
+	t.Run("writes are blocked by default", func(t *testing.T) {
+		testServer.config.QueryPolicy = nil
+		testServer.config.AllowWrites = true
+		result, _ := run("UPDATE users SET bio = bio WHERE id = 1")
+		if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "QUERY_POLICY") {
+			t.Errorf("Expected the update to be blocked by the policy, got %v", result.Content)
+		}
+	})
+
+	t.Run("allowed writes run in a write transaction", func(t *testing.T) {
+		testServer.config.QueryPolicy, _ = parseQueryPolicy("dml=allow")
+		testServer.config.AllowWrites = false
+		testServer.config.DryRun = true
+		result, data := run("UPDATE users SET bio = 'policy test' WHERE id = 1 RETURNING id")
+		if result.IsError {
+			t.Fatalf("Expected the update to run, got %v", result.Content)
+		}
+		response := data.(map[string]interface{})
+		if response["rows_affected"] != int64(1) || response["simulated"] != true || len(response["rows"].([]map[string]interface{})) != 1 {
+			t.Errorf("Unexpected response %v", response)
+		}
+
+		// dry-run rolled it back
+		_, data = run("SELECT count(*) AS changed FROM users WHERE bio = 'policy test'")
+		if changed := data.([]map[string]interface{})[0]["changed"]; changed != int64(0) {
+			t.Errorf("Expected the dry-run update to be rolled back, found %v rows", changed)
+		}
+	})
+
+	t.Run("writes need ALLOW_WRITES", func(t *testing.T) {
+		testServer.config.QueryPolicy, _ = parseQueryPolicy("ddl=allow")
+		testServer.config.AllowWrites = false
+		testServer.config.DryRun = false
+		if result, _ := run("CREATE TABLE policy_test (id int)"); !result.IsError {
+			t.Error("Expected DDL to be refused with writes disabled")
+		}
+	})
+
+	t.Run("dry_run rolls back", func(t *testing.T) {
+		testServer.config.QueryPolicy, _ = parseQueryPolicy("dml=confirm")
+		testServer.config.AllowWrites = false
+		testServer.config.DryRun = false
+		args := QueryArgs{Query: "UPDATE users SET bio = 'dry run' WHERE id = 1 RETURNING id", DryRun: true}
+		if result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+			t.Error("Expected dry_run to be refused without ALLOW_WRITES")
+		}
+		testServer.config.AllowWrites = true
+		if result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+			t.Error("Expected dry_run to be refused for a statement that needs approval")
+		}
+
+		testServer.config.QueryPolicy, _ = parseQueryPolicy("dml=allow")
+		result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		if err != nil || result.IsError {
+			t.Fatalf("Expected the update to run, got %v %v", err, result)
+		}
+		response := data.(map[string]interface{})
+		if response["rows_affected"] != int64(1) || response["simulated"] != true || len(response["rows"].([]map[string]interface{})) != 1 {
+			t.Errorf("Unexpected response %v", response)
+		}
+		_, data = run("SELECT count(*) AS changed FROM users WHERE bio = 'dry run'")
+		if changed := data.([]map[string]interface{})[0]["changed"]; changed != int64(0) {
+			t.Errorf("Expected the update to be rolled back, found %v rows", changed)
+		}
+	})
+
+	t.Run("confirmed categories are queued", func(t *testing.T) {
+		testServer.config.QueryPolicy, _ = parseQueryPolicy("maintenance=confirm")
+		testServer.config.AllowWrites = true
+		result, data := run("VACUUM users")
+		if result.IsError {
+			t.Fatalf("Expected the statement to be queued, got %v", result.Content)
+		}
+		if status := data.(map[string]interface{})["status"]; status != "pending_approval" {
+			t.Errorf("Expected pending_approval, got %v", status)
+		}
+	})
+}
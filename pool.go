@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type PoolStatsArgs struct{}
+
+func (s *serverState) PoolStats(ctx context.Context, req *mcp.CallToolRequest, args PoolStatsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	stat := s.pool.Stat()
+	poolConfig := s.pool.Config()
+
+	// acquires that found no idle connection had to wait for one to be created or released
+	var averageAcquireMs float64
+	if stat.AcquireCount() > 0 {
+		averageAcquireMs = float64(stat.AcquireDuration().Microseconds()) / float64(stat.AcquireCount()) / 1000
+	}
+
+	stats := map[string]interface{}{
+		"acquired_conns":             stat.AcquiredConns(),
+		"idle_conns":                 stat.IdleConns(),
+		"constructing_conns":         stat.ConstructingConns(),
+		"total_conns":                stat.TotalConns(),
+		"max_conns":                  stat.MaxConns(),
+		"acquire_count":              stat.AcquireCount(),
+		"empty_acquire_count":        stat.EmptyAcquireCount(),
+		"canceled_acquire_count":     stat.CanceledAcquireCount(),
+		"acquire_duration_ms":        stat.AcquireDuration().Milliseconds(),
+		"average_acquire_ms":         averageAcquireMs,
+		"new_conns_count":            stat.NewConnsCount(),
+		"max_lifetime_destroy_count": stat.MaxLifetimeDestroyCount(),
+		"max_idle_destroy_count":     stat.MaxIdleDestroyCount(),
+		"settings": map[string]interface{}{
+			"max_conns":          poolConfig.MaxConns,
+			"min_conns":          poolConfig.MinConns,
+			"max_conn_lifetime":  poolConfig.MaxConnLifetime.String(),
+			"max_conn_idle_time": poolConfig.MaxConnIdleTime.String(),
+		},
+	}
+	if s.queue != nil {
+		stats["queue"] = s.queue.stats()
+	}
+	if s.metadata != nil {
+		stats["metadata_cache"] = s.metadata.stats()
+	}
+	return returnJSONResult(stats)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestApplyPoolSettings(t *testing.T) {
+	poolConfig, err := pgxpool.ParseConfig("postgres://localhost/test?pool_max_conns=7")
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	Config{MinConns: 2, MaxConnIdleTime: time.Minute}.applyPoolSettings(poolConfig)
+
+	if poolConfig.MaxConns != 7 {
+		t.Errorf("Expected unset MaxConns to keep the connection string value, got %d", poolConfig.MaxConns)
+	}
+	if poolConfig.MinConns != 2 || poolConfig.MaxConnIdleTime != time.Minute {
+		t.Errorf("Expected MinConns and MaxConnIdleTime to be applied, got %d and %s", poolConfig.MinConns, poolConfig.MaxConnIdleTime)
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	ctx := context.Background()
+
+	args := PoolStatsArgs{}
+	result, data, err := testServer.PoolStats(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("PoolStats failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	stats := data.(map[string]interface{})
+	if stats["max_conns"].(int32) <= 0 {
+		t.Errorf("Expected positive max_conns, got %v", stats["max_conns"])
+	}
+	if stats["acquire_count"].(int64) == 0 {
+		t.Error("Expected earlier tests to have acquired connections")
+	}
+}
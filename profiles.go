@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// profile is one named entry of PROFILES_FILE: a database and the safety
+// policy the tools apply to it. Settings left out keep the value from the
+// environment, so a file only needs what differs between databases.
+type profile struct {
+	DatabaseURL     string   `json:"database_url"`
+	SocketDir       string   `json:"socket_dir"`
+	AllowWrites     *bool    `json:"allow_writes"`
+	RequireApproval *bool    `json:"require_approval"`
+	DryRun          *bool    `json:"dry_run"`
+	RedactPII       *bool    `json:"redact_pii"`
+	Role            *string  `json:"role"`
+	AllowedSchemas  []string `json:"allowed_schemas"`
+	DeniedSchemas   []string `json:"denied_schemas"`
+	AllowedTables   []string `json:"allowed_tables"`
+	DeniedTables    []string `json:"denied_tables"`
+	QueryPolicy     *string  `json:"query_policy"`
+	AllowInsecure   *bool    `json:"allow_insecure"`
+}
+
+// loadProfiles reads a JSON object mapping profile names to profiles. An
+// empty path means no profiles.
+func loadProfiles(path string) (map[string]profile, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]profile
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profiles); err != nil {
+		return nil, err
+	}
+	for name, p := range profiles {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("profile names must not be empty")
+		}
+		if p.DatabaseURL == "" && p.SocketDir == "" {
+			return nil, fmt.Errorf("profile %s has no database_url or socket_dir", name)
+		}
+		if err := validateSocketDir(p.SocketDir); err != nil {
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+		if p.QueryPolicy != nil {
+			if _, err := parseQueryPolicy(*p.QueryPolicy); err != nil {
+				return nil, fmt.Errorf("profile %s: invalid query_policy: %v", name, err)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// forProfile returns the configuration and pool configuration of a profile:
+// this configuration with the profile's policy applied.
+func (c Config) forProfile(name string) (Config, *pgxpool.Config, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return Config{}, nil, fmt.Errorf("unknown profile %q", name)
+	}
+	poolConfig, err := pgxpool.ParseConfig(p.DatabaseURL)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("failed to parse database_url of profile %s: %v", name, err)
+	}
+	if p.SocketDir != "" {
+		if err := applySocketDir(&poolConfig.ConnConfig.Config, p.SocketDir); err != nil {
+			return Config{}, nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+	}
+
+	c.Profile = name
+	if p.AllowWrites != nil {
+		c.AllowWrites = *p.AllowWrites
+	}
+	if p.RequireApproval != nil {
+		c.RequireApproval = *p.RequireApproval
+	}
+	if p.DryRun != nil {
+		c.DryRun = *p.DryRun
+	}
+	if p.RedactPII != nil {
+		c.RedactPII = *p.RedactPII
+	}
+	if p.Role != nil {
+		c.Role = *p.Role
+	}
+	if p.AllowedSchemas != nil {
+		c.AllowedSchemas = p.AllowedSchemas
+	}
+	if p.DeniedSchemas != nil {
+		c.DeniedSchemas = p.DeniedSchemas
+	}
+	if p.AllowedTables != nil {
+		c.AllowedTables = p.AllowedTables
+	}
+	if p.DeniedTables != nil {
+		c.DeniedTables = p.DeniedTables
+	}
+	if p.AllowInsecure != nil {
+		c.AllowInsecure = *p.AllowInsecure
+	}
+	if p.QueryPolicy != nil {
+		// validated by loadProfiles
+		c.QueryPolicy, _ = parseQueryPolicy(*p.QueryPolicy)
+	}
+	return c, poolConfig, nil
+}
+
+// connectProfiles connects to the default database and to every profile.
+// The default is the profile selected with PROFILE or else DATABASE_URL
+// with the environment's policy; the others are reached with the connection
+// argument every tool gets when profiles are configured.
+func connectProfiles(ctx context.Context, config Config, poolConfig *pgxpool.Config) (*serverState, error) {
+	if len(config.Profiles) == 0 {
+		return newServerState(ctx, config, poolConfig)
+	}
+
+	profiles := make(map[string]*serverState, len(config.Profiles))
+	closeAll := func() {
+		for _, state := range profiles {
+			state.Close()
+		}
+	}
+	for _, name := range sortedKeys(config.Profiles) {
+		profileConfig, profilePoolConfig, err := config.forProfile(name)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		state, err := newServerState(ctx, profileConfig, profilePoolConfig)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("profile %s: %v", name, err)
+		}
+		profiles[name] = state
+	}
+
+	s := profiles[config.Profile]
+	if s == nil {
+		var err error
+		if s, err = newServerState(ctx, config, poolConfig); err != nil {
+			closeAll()
+			return nil, err
+		}
+	}
+	s.profiles = profiles
+	// a session's log and parameters follow it across profiles
+	for _, state := range profiles {
+		state.sessions, state.parameters = s.sessions, s.parameters
+	}
+	return s, nil
+}
+
+// forConnection picks the state a tool call runs against from its
+// connection argument, this state when there is none.
+func (s *serverState) forConnection(req *mcp.CallToolRequest) (*serverState, error) {
+	if len(s.profiles) == 0 || req == nil || req.Params == nil || len(req.Params.Arguments) == 0 {
+		return s, nil
+	}
+	var args struct {
+		Connection string `json:"connection"`
+	}
+	if err := json.Unmarshal(req.Params.Arguments, &args); err != nil || args.Connection == "" {
+		return s, nil
+	}
+	state, ok := s.profiles[args.Connection]
+	if !ok {
+		return nil, fmt.Errorf("unknown connection %q, expected one of: %s", args.Connection, strings.Join(sortedKeys(s.profiles), ", "))
+	}
+	return state, nil
+}
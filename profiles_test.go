@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestLoadProfiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "profiles.json")
+		os.WriteFile(path, []byte(content), 0o600)
+		return path
+	}
+
+	profiles, err := loadProfiles(write(`{
+		"prod-replica": {"database_url": "postgres://replica/app", "denied_schemas": ["audit"]},
+		"local": {"database_url": "postgres://localhost/app", "allow_writes": true, "query_policy": "ddl=allow"}
+	}`))
+	if err != nil {
+		t.Fatalf("loadProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 || !*profiles["local"].AllowWrites {
+		t.Errorf("Unexpected profiles: %+v", profiles)
+	}
+
+	for _, invalid := range []string{
+		`{"local": {}}`,
+		`{"local": {"database_url": "postgres://localhost/app", "allow_write": true}}`,
+		`{"local": {"database_url": "postgres://localhost/app", "query_policy": "ddl=maybe"}}`,
+	} {
+		if _, err := loadProfiles(write(invalid)); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestForProfile(t *testing.T) {
+	allowWrites := true
+	config := Config{
+		AllowWrites:   false,
+		DeniedSchemas: []string{"audit"},
+		Locale:        "de",
+		Profiles: map[string]profile{
+			"local": {DatabaseURL: "postgres://localhost/app", AllowWrites: &allowWrites, DeniedSchemas: []string{}},
+			"prod":  {DatabaseURL: "postgres://prod/app"},
+		},
+	}
+
+	local, poolConfig, err := config.forProfile("local")
+	if err != nil {
+		t.Fatalf("forProfile failed: %v", err)
+	}
+	if !local.AllowWrites || len(local.DeniedSchemas) != 0 || local.Locale != "de" || local.Profile != "local" {
+		t.Errorf("Expected the profile's policy over the environment's, got %+v", local)
+	}
+	if poolConfig.ConnConfig.Host != "localhost" {
+		t.Errorf("Expected the profile's database, got %s", poolConfig.ConnConfig.Host)
+	}
+
+	prod, _, _ := config.forProfile("prod")
+	if prod.AllowWrites || len(prod.DeniedSchemas) != 1 {
+		t.Errorf("Expected unset settings to keep the environment's, got %+v", prod)
+	}
+	if _, _, err := config.forProfile("staging"); err == nil {
+		t.Error("Expected an unknown profile to be rejected")
+	}
+}
+
+func TestConnectionArgument(t *testing.T) {
+	ctx := context.Background()
+	restricted := &serverState{config: Config{Profile: "restricted", DeniedTables: []string{"users"}}, pool: testServer.pool, sessions: testServer.sessions, parameters: testServer.parameters}
+	s := &serverState{config: Config{Profile: "main"}, pool: testServer.pool, sessions: testServer.sessions, parameters: testServer.parameters}
+	s.profiles = map[string]*serverState{"main": s, "restricted": restricted}
+
+	server := s.newMCPServer()
+	s.addTools(server)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "test"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+
+	call := func(arguments map[string]any) *mcp.CallToolResult {
+		result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "get_table_schema", Arguments: arguments})
+		if err != nil {
+			t.Fatalf("CallTool(%v) failed: %v", arguments, err)
+		}
+		return result
+	}
+
+	if result := call(map[string]any{"table_name": "users", "schema": "public"}); result.IsError {
+		t.Errorf("Expected the default profile to allow users, got %v", result.Content)
+	}
+	if result := call(map[string]any{"table_name": "users", "schema": "public", "connection": "restricted"}); !result.IsError {
+		t.Error("Expected the restricted profile to deny users")
+	}
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "get_table_schema", Arguments: map[string]any{"table_name": "users", "schema": "public", "connection": "staging"}}); err == nil {
+		t.Error("Expected an unknown connection to be rejected")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressInterval is how many bytes a copy moves between progress
+// notifications.
+const progressInterval = 4 << 20
+
+// progressFunc reports how far a long tool call has got, total being 0 when
+// it isn't known.
+type progressFunc func(progress, total float64, message string)
+
+// progressNotifier sends progress notifications for a tool call, if the
+// client asked for them with a progress token.
+func progressNotifier(ctx context.Context, req *mcp.CallToolRequest) progressFunc {
+	if req == nil || req.Session == nil || req.Params == nil || req.Params.GetProgressToken() == nil {
+		return func(float64, float64, string) {}
+	}
+	token := req.Params.GetProgressToken()
+	return func(progress, total float64, message string) {
+		// progress is best effort, a client that went away fails the call elsewhere
+		req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      progress,
+			Total:         total,
+			Message:       message,
+		})
+	}
+}
+
+// byteCounter reports the bytes copied through it every progressInterval.
+type byteCounter struct {
+	report progressFunc
+	total  int64
+	count  int64
+	next   int64
+}
+
+func (c *byteCounter) add(n int) {
+	c.count += int64(n)
+	if c.count >= c.next {
+		c.next = c.count + progressInterval
+		c.report(float64(c.count), float64(c.total), fmt.Sprintf("%d bytes", c.count))
+	}
+}
+
+type countingWriter struct {
+	io.Writer
+	counter *byteCounter
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.counter.add(n)
+	return n, err
+}
+
+type countingReader struct {
+	io.Reader
+	counter *byteCounter
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.counter.add(n)
+	return n, err
+}
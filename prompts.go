@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// serverPrompt is a canned multi-step workflow built from this server's tools.
+type serverPrompt struct {
+	prompt *mcp.Prompt
+	render func(args map[string]string) string
+}
+
+var serverPrompts = []serverPrompt{
+	{
+		prompt: &mcp.Prompt{
+			Name:        "analyze_slow_query",
+			Title:       "Analyze slow query",
+			Description: "Diagnose why a query is slow and propose indexes or rewrites",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "query", Description: "The slow SQL query", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf(`Analyze why this query is slow and how to make it faster:
+
+%s
+
+Work through these steps:
+1. Run explain_analyze on the query with buffers enabled. Note the slowest nodes, sequential scans on large tables, and row estimates that are far off the actual rows.
+2. For every table in the plan, call estimate_row_count, get_table_schema and get_table_indexes to see its size and the indexes that already exist.
+3. If estimates are far off, say which tables probably need ANALYZE.
+4. Propose specific indexes (with CREATE INDEX statements) or query rewrites, and explain which plan node each one fixes.
+5. If you propose a rewrite, run explain_analyze on it and compare it with the original plan.
+
+Do not run any statement that modifies data.`, args["query"])
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "review_schema_design",
+			Title:       "Review schema design",
+			Description: "Review a schema for missing keys, indexes and constraints",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "schema", Description: "Schema to review (default: public)"},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf(`Review the design of the %q schema.
+
+Work through these steps:
+1. Call list_tables, then get_table_schema, get_table_constraints and get_table_indexes for every table.
+2. Call list_types to resolve USER-DEFINED columns and list_sequences to check for sequences close to exhaustion.
+3. Look for tables without a primary key, foreign key columns without an index, duplicate or redundant indexes, nullable columns that look mandatory, and missing CHECK or UNIQUE constraints.
+4. Call infer_joins on related tables to confirm the foreign key graph matches how the tables are meant to be joined.
+5. Summarize the findings by severity, with the DDL to fix each one.
+
+Do not run any statement that modifies the schema.`, getSchema(args["schema"]))
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "investigate_table_growth",
+			Title:       "Investigate table growth",
+			Description: "Find out what makes a table grow and whether the growth is healthy",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "table_name", Description: "The table that is growing", Required: true},
+				{Name: "schema", Description: "Schema of the table (default: public)"},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf(`Investigate the growth of the table %s.%s.
+
+Work through these steps:
+1. Call estimate_row_count and get_table_schema for the table. If it is partitioned, call get_partitions to see how rows and size are spread across partitions.
+2. Use query to compare the row count with the table and index sizes (pg_total_relation_size, pg_relation_size, pg_indexes_size) and with dead tuples in pg_stat_user_tables, to tell real growth apart from bloat.
+3. If the table has a timestamp column, use query to count rows per day or month and describe the growth trend.
+4. Call get_table_indexes and look for indexes that are large but unused (pg_stat_user_indexes.idx_scan).
+5. Call view_dependencies to list views affected by any retention or partitioning change you propose.
+6. Recommend retention, partitioning, vacuum or index changes, and estimate the space each one saves.
+
+Do not run any statement that modifies data.`, getSchema(args["schema"]), args["table_name"])
+		},
+	},
+}
+
+func promptHandler(p serverPrompt) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		args := req.Params.Arguments
+		var missing []string
+		for _, argument := range p.prompt.Arguments {
+			if argument.Required && strings.TrimSpace(args[argument.Name]) == "" {
+				missing = append(missing, argument.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("missing required arguments: %s", strings.Join(missing, ", "))
+		}
+
+		return &mcp.GetPromptResult{
+			Description: p.prompt.Description,
+			Messages: []*mcp.PromptMessage{
+				{Role: "user", Content: &mcp.TextContent{Text: p.render(args)}},
+			},
+		}, nil
+	}
+}
+
+// addPrompts registers the built-in analysis prompts.
+func addPrompts(server *mcp.Server) {
+	for _, p := range serverPrompts {
+		server.AddPrompt(p.prompt, promptHandler(p))
+	}
+}
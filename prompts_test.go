@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPrompts(t *testing.T) {
+	ctx := context.Background()
+
+	handlers := make(map[string]mcp.PromptHandler)
+	for _, p := range serverPrompts {
+		handlers[p.prompt.Name] = promptHandler(p)
+	}
+
+	t.Run("renders arguments", func(t *testing.T) {
+		req := &mcp.GetPromptRequest{Params: &mcp.GetPromptParams{
+			Name:      "investigate_table_growth",
+			Arguments: map[string]string{"table_name": "posts"},
+		}}
+		result, err := handlers["investigate_table_growth"](ctx, req)
+
+		if err != nil {
+			t.Fatalf("Prompt failed: %v", err)
+		}
+
+		if len(result.Messages) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(result.Messages))
+		}
+
+		text := result.Messages[0].Content.(*mcp.TextContent).Text
+		if !strings.Contains(text, "public.posts") {
+			t.Errorf("Expected prompt to name public.posts, got %s", text)
+		}
+	})
+
+	t.Run("missing required argument", func(t *testing.T) {
+		req := &mcp.GetPromptRequest{Params: &mcp.GetPromptParams{Name: "analyze_slow_query"}}
+		if _, err := handlers["analyze_slow_query"](ctx, req); err == nil {
+			t.Error("Expected error for missing query argument")
+		}
+	})
+
+	t.Run("prompts reference registered tools", func(t *testing.T) {
+		for _, p := range serverPrompts {
+			text := p.render(map[string]string{"query": "SELECT 1", "table_name": "users"})
+			if !strings.Contains(text, "explain_analyze") && !strings.Contains(text, "get_table_schema") {
+				t.Errorf("Expected prompt %s to reference server tools", p.prompt.Name)
+			}
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// backgroundTools are the long-running tools. They wait behind every other
+// call and never take the last slot, so an export doesn't hold up a quick
+// question asked meanwhile.
+var backgroundTools = map[string]bool{
+	"backup_table":              true,
+	"clone_database":            true,
+	"restore_table":             true,
+	"dump_schema":               true,
+	"export_fixture":            true,
+	"explain_analyze":           true,
+	"refresh_materialized_view": true,
+	"search_data":               true,
+	"validate_constraints":      true,
+	"run_data_checks":           true,
+	"column_cardinality":        true,
+	"column_distribution":       true,
+	"run_analyze":               true,
+	"run_vacuum":                true,
+	"run_reindex":               true,
+}
+
+// unqueuedTools don't use a connection and answer even when the queue is
+// full or the database is down, pool_stats being how a full queue is
+// diagnosed and get_activity_history and recent_slow_queries what led up to
+// an outage.
+var unqueuedTools = map[string]bool{"pool_stats": true, "get_activity_history": true, "recent_slow_queries": true}
+
+const (
+	interactivePriority = iota
+	backgroundPriority
+)
+
+// callQueue limits how many tool calls use the database at once, one per
+// pool connection. Waiting calls are started interactive before background,
+// and round-robin across sessions within a priority so one session queueing
+// many calls can't starve another.
+type callQueue struct {
+	mu      sync.Mutex
+	slots   int
+	running [2]int
+	// waiting holds, per priority, the sessions with waiting calls in the
+	// order they get their next turn, and calls holds each one's calls.
+	waiting [2][]string
+	calls   [2]map[string][]chan struct{}
+
+	queuedCount int64
+	waitTotal   time.Duration
+}
+
+func newCallQueue(slots int) *callQueue {
+	if slots < 1 {
+		slots = 1
+	}
+	return &callQueue{
+		slots: slots,
+		calls: [2]map[string][]chan struct{}{{}, {}},
+	}
+}
+
+// available reports whether a call of the priority can start now.
+func (q *callQueue) available(priority int) bool {
+	running := q.running[interactivePriority] + q.running[backgroundPriority]
+	if priority == backgroundPriority {
+		// keep a slot for interactive calls when there is more than one
+		return running < q.slots && (q.slots == 1 || running < q.slots-1) && len(q.waiting[interactivePriority]) == 0
+	}
+	return running < q.slots
+}
+
+// acquire waits for the call's turn and returns the function ending it. A
+// nil queue lets every call through.
+func (q *callQueue) acquire(ctx context.Context, background bool, session string) (func(), error) {
+	if q == nil {
+		return func() {}, nil
+	}
+	priority := interactivePriority
+	if background {
+		priority = backgroundPriority
+	}
+
+	q.mu.Lock()
+	if len(q.waiting[priority]) == 0 && q.available(priority) {
+		q.running[priority]++
+		q.mu.Unlock()
+		return q.releaseFunc(priority), nil
+	}
+	ready := make(chan struct{})
+	if len(q.calls[priority][session]) == 0 {
+		q.waiting[priority] = append(q.waiting[priority], session)
+	}
+	q.calls[priority][session] = append(q.calls[priority][session], ready)
+	q.mu.Unlock()
+
+	start := time.Now()
+	select {
+	case <-ready:
+		q.mu.Lock()
+		q.queuedCount++
+		q.waitTotal += time.Since(start)
+		q.mu.Unlock()
+		return q.releaseFunc(priority), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		select {
+		case <-ready:
+			// started just as the context ended, hand the slot on
+			q.running[priority]--
+			q.dispatch()
+		default:
+			q.remove(priority, session, ready)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (q *callQueue) releaseFunc(priority int) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.running[priority]--
+			q.dispatch()
+		})
+	}
+}
+
+// dispatch starts waiting calls while there are free slots. The caller holds
+// the lock.
+func (q *callQueue) dispatch() {
+	for _, priority := range []int{interactivePriority, backgroundPriority} {
+		for len(q.waiting[priority]) > 0 && q.available(priority) {
+			session := q.waiting[priority][0]
+			calls := q.calls[priority][session]
+			close(calls[0])
+			q.running[priority]++
+
+			q.waiting[priority] = q.waiting[priority][1:]
+			if len(calls) > 1 {
+				q.calls[priority][session] = calls[1:]
+				q.waiting[priority] = append(q.waiting[priority], session)
+			} else {
+				delete(q.calls[priority], session)
+			}
+		}
+	}
+}
+
+// remove drops a call whose context ended while waiting. The caller holds the
+// lock.
+func (q *callQueue) remove(priority int, session string, ready chan struct{}) {
+	calls := q.calls[priority][session]
+	for i, call := range calls {
+		if call == ready {
+			calls = append(calls[:i:i], calls[i+1:]...)
+			break
+		}
+	}
+	if len(calls) > 0 {
+		q.calls[priority][session] = calls
+		return
+	}
+	delete(q.calls[priority], session)
+	for i, waiting := range q.waiting[priority] {
+		if waiting == session {
+			q.waiting[priority] = append(q.waiting[priority][:i:i], q.waiting[priority][i+1:]...)
+			break
+		}
+	}
+	// a removed interactive call may have been what held background calls back
+	q.dispatch()
+}
+
+// stats reports the queue for pool_stats.
+func (q *callQueue) stats() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	waiting := func(priority int) int {
+		count := 0
+		for _, calls := range q.calls[priority] {
+			count += len(calls)
+		}
+		return count
+	}
+	var averageWaitMs float64
+	if q.queuedCount > 0 {
+		averageWaitMs = float64(q.waitTotal.Microseconds()) / float64(q.queuedCount) / 1000
+	}
+	return map[string]interface{}{
+		"slots":               q.slots,
+		"running_interactive": q.running[interactivePriority],
+		"running_background":  q.running[backgroundPriority],
+		"waiting_interactive": waiting(interactivePriority),
+		"waiting_background":  waiting(backgroundPriority),
+		"queued_count":        q.queuedCount,
+		"average_wait_ms":     averageWaitMs,
+	}
+}
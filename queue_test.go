@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type queuedCall struct {
+	name       string
+	background bool
+	session    string
+}
+
+// queueCalls queues each call in turn once the previous one is waiting, and
+// returns the order they started in after hold is released.
+func queueCalls(t *testing.T, q *callQueue, hold func(), calls []queuedCall) []string {
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := q.acquire(context.Background(), call.background, call.session)
+			if err != nil {
+				t.Errorf("acquire failed: %v", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, call.name)
+			mu.Unlock()
+			release()
+		}()
+		waitForQueued(t, q, i+1)
+	}
+	hold()
+	wg.Wait()
+	return order
+}
+
+func waitForQueued(t *testing.T, q *callQueue, count int) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		stats := q.stats()
+		if stats["waiting_interactive"].(int)+stats["waiting_background"].(int) == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d queued calls", count)
+}
+
+func TestCallQueuePriority(t *testing.T) {
+	q := newCallQueue(1)
+	release, _ := q.acquire(context.Background(), false, "a")
+
+	order := queueCalls(t, q, release, []queuedCall{
+		{"export", true, "a"},
+		{"count", false, "b"},
+	})
+	if len(order) != 2 || order[0] != "count" {
+		t.Errorf("Expected the interactive call to go first, got %v", order)
+	}
+}
+
+func TestCallQueueFairness(t *testing.T) {
+	q := newCallQueue(1)
+	release, _ := q.acquire(context.Background(), false, "a")
+
+	order := queueCalls(t, q, release, []queuedCall{
+		{"a1", false, "a"},
+		{"a2", false, "a"},
+		{"a3", false, "a"},
+		{"b1", false, "b"},
+	})
+	expected := []string{"a1", "b1", "a2", "a3"}
+	for i := range expected {
+		if i >= len(order) || order[i] != expected[i] {
+			t.Fatalf("Expected sessions to take turns %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestCallQueueKeepsSlotForInteractive(t *testing.T) {
+	ctx := context.Background()
+	q := newCallQueue(2)
+
+	releaseExport, _ := q.acquire(ctx, true, "a")
+	defer releaseExport()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.acquire(waitCtx, true, "a"); err == nil {
+		t.Error("Expected a second background call to wait for the first")
+	}
+
+	releaseCount, err := q.acquire(ctx, false, "b")
+	if err != nil {
+		t.Fatalf("Expected the interactive call to start, got %v", err)
+	}
+	releaseCount()
+
+	if stats := q.stats(); stats["waiting_background"].(int) != 0 || stats["running_background"].(int) != 1 {
+		t.Errorf("Expected the canceled call to leave the queue, got %v", stats)
+	}
+}
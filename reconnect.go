@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	reconnectBackoff     = 250 * time.Millisecond
+	maxReconnectBackoff  = 5 * time.Second
+	reconnectPingTimeout = 5 * time.Second
+)
+
+// pingWithRetry checks the pool can connect, retrying with exponential
+// backoff for up to timeout so the server survives starting before the
+// database. A zero timeout tries once.
+func pingWithRetry(ctx context.Context, pool *pgxpool.Pool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := reconnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil || time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		log.Printf("Database %s not reachable (attempt %d): %v, retrying in %s", redactConnString(pool.Config().ConnString()), attempt, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// reconnector tracks a lost database. While it is reconnecting, tools fail
+// straight away with a reconnecting error instead of each waiting for a
+// connection that can't be made.
+type reconnector struct {
+	mu       sync.Mutex
+	active   bool
+	attempts int
+	lastErr  error
+	stop     chan struct{}
+}
+
+// status reports whether a reconnect is under way, how many attempts it made
+// and why the last one failed.
+func (r *reconnector) status() (bool, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active, r.attempts, r.lastErr
+}
+
+// startReconnecting begins pinging the database in the background after a
+// lost connection could not be re-established. It does nothing when a
+// reconnect is already under way.
+func (s *serverState) startReconnecting(cause error) {
+	r := &s.reconnect
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active {
+		return
+	}
+	r.active, r.attempts, r.lastErr = true, 0, cause
+	if r.stop == nil {
+		r.stop = make(chan struct{})
+	}
+	log.Printf("Database unreachable, reconnecting in the background: %v", cause)
+	go s.reconnectLoop(r.stop)
+}
+
+func (s *serverState) reconnectLoop(stop chan struct{}) {
+	backoff := reconnectBackoff
+	for {
+		select {
+		case <-time.After(backoff):
+		case <-stop:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), reconnectPingTimeout)
+		err := s.pool.Ping(ctx)
+		cancel()
+
+		s.reconnect.mu.Lock()
+		s.reconnect.attempts++
+		if err == nil {
+			log.Printf("Reconnected to the database after %d attempts", s.reconnect.attempts)
+			s.reconnect.active = false
+			s.reconnect.mu.Unlock()
+			return
+		}
+		s.reconnect.lastErr = err
+		s.reconnect.mu.Unlock()
+		backoff = min(2*backoff, maxReconnectBackoff)
+	}
+}
+
+// stopReconnecting ends a background reconnect, when the pool is closed.
+func (r *reconnector) stopReconnecting() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// reconnectingError describes a reconnect under way, nil when the database
+// is reachable.
+func (s *serverState) reconnectingError() error {
+	active, attempts, lastErr := s.reconnect.status()
+	if !active {
+		return nil
+	}
+	return fmt.Errorf(s.localize("The database is unavailable and the server is reconnecting (%d attempts so far, last error: %v). Try again shortly"), attempts, lastErr)
+}
+
+// returnUnavailable is what tools return while the database can't be
+// reached: an error result with structured content, so clients can tell it
+// from a failing statement and try again later.
+func (s *serverState) returnUnavailable() (*mcp.CallToolResult, any, error) {
+	active, attempts, lastErr := s.reconnect.status()
+	if !active {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	result, _, _ := s.returnErrorResult("The database is unavailable and the server is reconnecting (%d attempts so far, last error: %v). Try again shortly", attempts, lastErr)
+	return result, map[string]interface{}{
+		"error":      "database_unavailable",
+		"attempts":   attempts,
+		"last_error": lastErr.Error(),
+	}, nil
+}
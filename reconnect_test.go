@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestPingWithRetry(t *testing.T) {
+	pool, err := pgxpool.New(context.Background(), "postgres://app@127.0.0.1:1/app?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	start := time.Now()
+	if err := pingWithRetry(context.Background(), pool, time.Second); err == nil {
+		t.Fatal("Expected an unreachable database to fail")
+	}
+	if elapsed := time.Since(start); elapsed < reconnectBackoff {
+		t.Errorf("Expected the ping to be retried, gave up after %s", elapsed)
+	}
+}
+
+func TestReconnecting(t *testing.T) {
+	pool, err := pgxpool.NewWithConfig(context.Background(), testServer.pool.Config())
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	s := &serverState{pool: pool}
+	defer s.Close()
+
+	s.startReconnecting(errors.New("server closed the connection unexpectedly"))
+	if err := s.reconnectingError(); err == nil || !strings.Contains(err.Error(), "reconnecting") {
+		t.Fatalf("Expected a reconnecting error, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.reconnectingError() != nil && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := s.reconnectingError(); err != nil {
+		t.Errorf("Expected the reconnect to succeed, got %v", err)
+	}
+}
+
+func TestLazyConnect(t *testing.T) {
+	ctx := context.Background()
+	poolConfig, err := pgxpool.ParseConfig("postgres://app@127.0.0.1:1/app")
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+	if _, err := newServerState(ctx, Config{AllowInsecure: true}, poolConfig.Copy()); err == nil {
+		t.Fatal("Expected an unreachable database to fail without LAZY_CONNECT")
+	}
+	s, err := newServerState(ctx, Config{AllowInsecure: true, LazyConnect: true}, poolConfig)
+	if err != nil {
+		t.Fatalf("Expected LAZY_CONNECT to start without the database, got %v", err)
+	}
+	defer s.Close()
+
+	server := s.newMCPServer()
+	s.addTools(server)
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer serverSession.Close()
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "test"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "list_tables", Arguments: map[string]any{"schema": "public"}})
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	structured, _ := json.Marshal(result.StructuredContent)
+	if !result.IsError || !strings.Contains(string(structured), `"database_unavailable"`) {
+		t.Errorf("Expected a database_unavailable error, got %v %s", result.Content, structured)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// minSecretLength keeps very short passwords from redacting ordinary words.
+const minSecretLength = 4
+
+// secretRedactor removes connection strings and database passwords from
+// text before it leaves the server. It is process-wide because the log is,
+// every state registers its connection with it.
+type secretRedactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+var connectionSecrets secretRedactor
+
+// add registers secrets, with the percent-encoded forms a password takes in
+// a connection URL.
+func (r *secretRedactor) add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		for _, form := range []string{value, url.QueryEscape(value), url.PathEscape(value)} {
+			if len(form) >= minSecretLength && !slices.Contains(r.secrets, form) {
+				r.secrets = append(r.secrets, form)
+			}
+		}
+	}
+	// longest first, so a connection string goes before the password in it
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+}
+
+func (r *secretRedactor) redact(text string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, redactedSecret)
+	}
+	return text
+}
+
+// registerConnection registers the secrets of a pool configuration: its
+// connection string and password.
+func registerConnection(poolConfig *pgxpool.Config) {
+	connectionSecrets.add(poolConfig.ConnString(), poolConfig.ConnConfig.Password)
+}
+
+var keywordPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactConnString masks the password of a connection string, in URL or
+// keyword/value form, leaving the rest readable.
+func redactConnString(connString string) string {
+	if u, err := url.Parse(connString); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	return keywordPassword.ReplaceAllString(connString, "${1}xxxxx")
+}
+
+// redactingWriter redacts registered secrets from what is written through
+// it, the log in particular.
+type redactingWriter struct {
+	w io.Writer
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, connectionSecrets.redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactMiddleware redacts registered secrets from tool results and
+// protocol errors, wherever in the server they were produced.
+func redactMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		result, err := next(ctx, method, req)
+		if err != nil {
+			if redacted := connectionSecrets.redact(err.Error()); redacted != err.Error() {
+				err = errors.New(redacted)
+			}
+		}
+
+		call, ok := result.(*mcp.CallToolResult)
+		if !ok || call == nil {
+			return result, err
+		}
+		changed := false
+		for _, content := range call.Content {
+			if text, ok := content.(*mcp.TextContent); ok {
+				if redacted := connectionSecrets.redact(text.Text); redacted != text.Text {
+					text.Text, changed = redacted, true
+				}
+			}
+		}
+		// the structured copy holds the same values as the text
+		if changed && call.StructuredContent != nil {
+			if data, marshalErr := json.Marshal(call.StructuredContent); marshalErr == nil {
+				call.StructuredContent = json.RawMessage(connectionSecrets.redact(string(data)))
+			}
+		}
+		return result, err
+	}
+}
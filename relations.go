@@ -0,0 +1,670 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultHierarchyDepth = 10
+	defaultHierarchyLimit = 1000
+)
+
+type TraverseHierarchyArgs struct {
+	TableName    string `json:"table_name" jsonschema:"Name of the self-referencing (or edge) table, optionally schema-qualified"`
+	Schema       string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	ParentColumn string `json:"parent_column" jsonschema:"Column holding the parent key (e.g. manager_id or user_id)"`
+	ChildColumn  string `json:"child_column" jsonschema:"Column holding the row's own key that children point at (e.g. id or friend_id)"`
+	RootValue    string `json:"root_value" jsonschema:"Key of the root node to start from; rows whose parent_column equals this are depth 1"`
+	MaxDepth     int    `json:"max_depth,omitempty" jsonschema:"Maximum depth to traverse (default: 10)"`
+	Limit        int    `json:"limit,omitempty" jsonschema:"Maximum number of nodes to return (default: 1000)"`
+}
+
+func (s *serverState) TraverseHierarchy(ctx context.Context, req *mcp.CallToolRequest, args TraverseHierarchyArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if args.TableName == "" || args.ParentColumn == "" || args.ChildColumn == "" {
+		return s.returnErrorResult("table_name, parent_column and child_column are required")
+	}
+
+	maxDepth := args.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultHierarchyDepth
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultHierarchyLimit
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+
+	table := pgx.Identifier{schema, tableName}.Sanitize()
+	parent := pgx.Identifier{args.ParentColumn}.Sanitize()
+	child := pgx.Identifier{args.ChildColumn}.Sanitize()
+
+	// path carries every key visited on the way down, a node already on its own
+	// path is reported as a cycle and not expanded any further
+	query := fmt.Sprintf(`
+		WITH RECURSIVE tree AS (
+			SELECT
+				t.%[2]s AS node,
+				1 AS depth,
+				ARRAY[$1::text, t.%[2]s::text] AS path,
+				false AS is_cycle,
+				to_jsonb(t) AS row_data
+			FROM %[1]s t
+			WHERE t.%[3]s::text = $1
+			UNION ALL
+			SELECT
+				c.%[2]s,
+				tree.depth + 1,
+				tree.path || c.%[2]s::text,
+				c.%[2]s::text = ANY(tree.path),
+				to_jsonb(c)
+			FROM %[1]s c
+			JOIN tree ON c.%[3]s = tree.node
+			WHERE NOT tree.is_cycle AND tree.depth < $2
+		)
+		SELECT node::text, depth, path, is_cycle, row_data
+		FROM tree
+		ORDER BY path
+		LIMIT $3
+	`, table, child, parent)
+
+	rows, err := s.pool.Query(ctx, query, args.RootValue, maxDepth, limit+1)
+	if err != nil {
+		return s.returnErrorResult("Hierarchy query error: %v", err)
+	}
+	defer rows.Close()
+
+	var nodes []map[string]interface{}
+	deepest, cycles := 0, 0
+	truncated := false
+	for rows.Next() {
+		var node string
+		var depth int
+		var path []string
+		var isCycle bool
+		var rowData map[string]interface{}
+
+		if err := rows.Scan(&node, &depth, &path, &isCycle, &rowData); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		if len(nodes) == limit {
+			truncated = true
+			break
+		}
+
+		if depth > deepest {
+			deepest = depth
+		}
+		if isCycle {
+			cycles++
+		}
+
+		nodes = append(nodes, map[string]interface{}{
+			"key":      node,
+			"depth":    depth,
+			"path":     path,
+			"is_cycle": isCycle,
+			"row":      rowData,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	var rowData []map[string]interface{}
+	for _, node := range nodes {
+		if row, ok := node["row"].(map[string]interface{}); ok {
+			rowData = append(rowData, row)
+		}
+	}
+	policies := s.tableColumnPolicies(schema, tableName, rowData)
+	applyColumnPolicies(rowData, policies)
+	masked := s.redactRows(rowData)
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"root":              args.RootValue,
+		"nodes":             nodes,
+		"node_count":        len(nodes),
+		"deepest_level":     deepest,
+		"max_depth_reached": deepest >= maxDepth,
+		"cycles_detected":   cycles,
+		"truncated":         truncated,
+	})
+	return s.withWarnings(result, append(s.columnPolicyWarnings(policies), s.piiWarnings(masked)...)), data, err
+}
+
+// fkEdge is a single foreign key constraint, pointing from the referencing
+// (child) table to the referenced (parent) table.
+type fkEdge struct {
+	Name        string
+	FromTable   string
+	FromColumns []string
+	ToTable     string
+	ToColumns   []string
+}
+
+// joinStep walks one foreign key edge, either from the referencing table to the
+// referenced table (forward) or the other way around.
+type joinStep struct {
+	Edge    fkEdge
+	Forward bool
+}
+
+func (s joinStep) source() string {
+	if s.Forward {
+		return s.Edge.FromTable
+	}
+	return s.Edge.ToTable
+}
+
+func (s joinStep) target() string {
+	if s.Forward {
+		return s.Edge.ToTable
+	}
+	return s.Edge.FromTable
+}
+
+func (s joinStep) describe() string {
+	return fmt.Sprintf("%s -> %s via %s (%s = %s)", s.source(), s.target(), s.Edge.Name,
+		qualifiedColumns(s.Edge.FromTable, s.Edge.FromColumns), qualifiedColumns(s.Edge.ToTable, s.Edge.ToColumns))
+}
+
+func qualifiedColumns(table string, columns []string) string {
+	return fmt.Sprintf("%s(%s)", table, strings.Join(columns, ", "))
+}
+
+// splitQualifiedName splits a "schema.table" graph node into its parts.
+func splitQualifiedName(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "public", name
+}
+
+func sanitizeQualifiedName(name string) string {
+	schema, table := splitQualifiedName(name)
+	return pgx.Identifier{schema, table}.Sanitize()
+}
+
+func (s *serverState) loadForeignKeys(ctx context.Context) ([]fkEdge, error) {
+	query := `
+		SELECT
+			con.conname,
+			sn.nspname || '.' || sc.relname,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			tn.nspname || '.' || tc.relname,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)
+		FROM pg_constraint con
+		JOIN pg_class sc ON sc.oid = con.conrelid
+		JOIN pg_namespace sn ON sn.oid = sc.relnamespace
+		JOIN pg_class tc ON tc.oid = con.confrelid
+		JOIN pg_namespace tn ON tn.oid = tc.relnamespace
+		WHERE con.contype = 'f'
+			AND sn.nspname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY sn.nspname, sc.relname, con.conname
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load foreign keys: %v", err)
+	}
+	defer rows.Close()
+
+	var edges []fkEdge
+	for rows.Next() {
+		var edge fkEdge
+		if err := rows.Scan(&edge.Name, &edge.FromTable, &edge.FromColumns, &edge.ToTable, &edge.ToColumns); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		// paths never lead through tables the allow/deny lists hide
+		if !s.qualifiedAllowed(edge.FromTable) || !s.qualifiedAllowed(edge.ToTable) {
+			continue
+		}
+		edges = append(edges, edge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return edges, nil
+}
+
+// findJoinPaths returns up to maxPaths simple paths between two tables over the
+// undirected foreign key graph, shortest first.
+func findJoinPaths(edges []fkEdge, from, to string, maxHops, maxPaths int) [][]joinStep {
+	adjacency := make(map[string][]joinStep)
+	for _, edge := range edges {
+		adjacency[edge.FromTable] = append(adjacency[edge.FromTable], joinStep{Edge: edge, Forward: true})
+		adjacency[edge.ToTable] = append(adjacency[edge.ToTable], joinStep{Edge: edge, Forward: false})
+	}
+
+	type partialPath struct {
+		node    string
+		visited []string
+		steps   []joinStep
+	}
+
+	var paths [][]joinStep
+	queue := []partialPath{{node: from, visited: []string{from}}}
+	for len(queue) > 0 && len(paths) < maxPaths {
+		current := queue[0]
+		queue = queue[1:]
+		if len(current.steps) >= maxHops {
+			continue
+		}
+
+		for _, step := range adjacency[current.node] {
+			next := step.target()
+			steps := append(append([]joinStep{}, current.steps...), step)
+			if next == to {
+				paths = append(paths, steps)
+				if len(paths) >= maxPaths {
+					break
+				}
+				continue
+			}
+			if slices.Contains(current.visited, next) {
+				continue
+			}
+			queue = append(queue, partialPath{
+				node:    next,
+				visited: append(append([]string{}, current.visited...), next),
+				steps:   steps,
+			})
+		}
+	}
+	return paths
+}
+
+// joinCondition renders the ON clause for a step joining nextAlias onto prevAlias.
+func joinCondition(step joinStep, prevAlias, nextAlias string) string {
+	prevColumns, nextColumns := step.Edge.FromColumns, step.Edge.ToColumns
+	if !step.Forward {
+		prevColumns, nextColumns = step.Edge.ToColumns, step.Edge.FromColumns
+	}
+
+	conditions := make([]string, len(prevColumns))
+	for i := range prevColumns {
+		conditions[i] = fmt.Sprintf("%s.%s = %s.%s",
+			nextAlias, pgx.Identifier{nextColumns[i]}.Sanitize(),
+			prevAlias, pgx.Identifier{prevColumns[i]}.Sanitize())
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// resolveKeyColumn returns the named column (or the single-column primary key
+// when column is empty) together with its SQL type.
+func (s *serverState) resolveKeyColumn(ctx context.Context, table, column string) (string, string, error) {
+	query := `
+		SELECT a.attname::text, format_type(a.atttypid, a.atttypmod)
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::text::regclass
+			AND a.attnum > 0
+			AND NOT a.attisdropped
+			AND (
+				($2 = '' AND a.attnum = ANY(
+					SELECT unnest(conkey) FROM pg_constraint WHERE conrelid = a.attrelid AND contype = 'p'
+				))
+				OR a.attname = $2
+			)
+	`
+
+	rows, err := s.pool.Query(ctx, query, sanitizeQualifiedName(table), column)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to resolve key column for %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var names, types []string
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return "", "", fmt.Errorf("failed to scan row: %v", err)
+		}
+		names = append(names, name)
+		types = append(types, typ)
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", fmt.Errorf("row iteration error: %v", err)
+	}
+
+	switch {
+	case len(names) == 1:
+		return names[0], types[0], nil
+	case column != "":
+		return "", "", fmt.Errorf("column %s not found on %s", column, table)
+	case len(names) == 0:
+		return "", "", fmt.Errorf("%s has no primary key, specify the key column explicitly", table)
+	default:
+		return "", "", fmt.Errorf("%s has a composite primary key, specify the key column explicitly", table)
+	}
+}
+
+type FindRowPathArgs struct {
+	FromTable  string `json:"from_table" jsonschema:"Table of the starting row"`
+	FromSchema string `json:"from_schema,omitempty" jsonschema:"Schema of the starting table (default: public)"`
+	FromColumn string `json:"from_column,omitempty" jsonschema:"Key column identifying the starting row (default: primary key)"`
+	FromValue  string `json:"from_value" jsonschema:"Key value of the starting row"`
+	ToTable    string `json:"to_table" jsonschema:"Table of the target row"`
+	ToSchema   string `json:"to_schema,omitempty" jsonschema:"Schema of the target table (default: public)"`
+	ToColumn   string `json:"to_column,omitempty" jsonschema:"Key column identifying the target row (default: primary key)"`
+	ToValue    string `json:"to_value" jsonschema:"Key value of the target row"`
+	MaxHops    int    `json:"max_hops,omitempty" jsonschema:"Maximum number of foreign key hops to consider (default: 4)"`
+}
+
+const (
+	defaultMaxHops    = 4
+	maxCandidatePaths = 10
+	maxConnectingRows = 5
+)
+
+func (s *serverState) FindRowPath(ctx context.Context, req *mcp.CallToolRequest, args FindRowPathArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if args.FromTable == "" || args.ToTable == "" {
+		return s.returnErrorResult("from_table and to_table are required")
+	}
+
+	maxHops := args.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultMaxHops
+	}
+
+	fromSchema, fromTable, err := s.resolveTableName(ctx, args.FromSchema, args.FromTable)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	toSchema, toTable, err := s.resolveTableName(ctx, args.ToSchema, args.ToTable)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	from := fromSchema + "." + fromTable
+	to := toSchema + "." + toTable
+	for _, table := range []string{from, to} {
+		if !s.qualifiedAllowed(table) {
+			return s.returnNotAccessible(table)
+		}
+	}
+
+	fromColumn, fromType, err := s.resolveKeyColumn(ctx, from, args.FromColumn)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	toColumn, toType, err := s.resolveKeyColumn(ctx, to, args.ToColumn)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	edges, err := s.loadForeignKeys(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := findJoinPaths(edges, from, to, maxHops, maxCandidatePaths)
+	if len(paths) == 0 {
+		return returnJSONResult(map[string]interface{}{
+			"connected": false,
+			"message":   fmt.Sprintf("No foreign key path between %s and %s within %d hops", from, to, maxHops),
+		})
+	}
+
+	var candidates []map[string]interface{}
+	masked := make(map[string][]string)
+	connected := false
+	for _, path := range paths {
+		aliases := []string{"t0"}
+		selects := []string{"to_jsonb(t0)"}
+		fromClause := sanitizeQualifiedName(from) + " t0"
+		var chain []string
+		for i, step := range path {
+			alias := fmt.Sprintf("t%d", i+1)
+			aliases = append(aliases, alias)
+			selects = append(selects, fmt.Sprintf("to_jsonb(%s)", alias))
+			fromClause += fmt.Sprintf("\n\tJOIN %s %s ON %s", sanitizeQualifiedName(step.target()), alias, joinCondition(step, aliases[i], alias))
+			chain = append(chain, step.describe())
+		}
+		last := aliases[len(aliases)-1]
+
+		// cast through text so the key lookups stay indexable on the real column types
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s.%s = $1::text::%s AND %s.%s = $2::text::%s LIMIT %d",
+			strings.Join(selects, ", "), fromClause,
+			"t0", pgx.Identifier{fromColumn}.Sanitize(), fromType,
+			last, pgx.Identifier{toColumn}.Sanitize(), toType, maxConnectingRows)
+
+		candidate := map[string]interface{}{
+			"hops":      len(path),
+			"join_path": chain,
+			"sql":       query,
+		}
+
+		rows, err := s.pool.Query(ctx, query, args.FromValue, args.ToValue)
+		if err != nil {
+			candidate["error"] = err.Error()
+			candidates = append(candidates, candidate)
+			continue
+		}
+
+		var connecting []map[string]interface{}
+		for rows.Next() {
+			values, err := rowValues(rows)
+			if err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+			}
+			// label each row by the table it came from, the same table may appear twice
+			row := make(map[string]interface{})
+			row[aliases[0]+" "+from] = values[0]
+			for i, step := range path {
+				row[aliases[i+1]+" "+step.target()] = values[i+1]
+			}
+			connecting = append(connecting, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			candidate["error"] = err.Error()
+		}
+
+		for column, kinds := range s.redactRows(connecting) {
+			masked[column] = append(masked[column], kinds...)
+		}
+		candidate["connected"] = len(connecting) > 0
+		candidate["rows"] = connecting
+		if len(connecting) > 0 {
+			connected = true
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"from":      map[string]interface{}{"table": from, "column": fromColumn, "value": args.FromValue},
+		"to":        map[string]interface{}{"table": to, "column": toColumn, "value": args.ToValue},
+		"connected": connected,
+		"paths":     candidates,
+	})
+	return s.withWarnings(result, s.piiWarnings(masked)), data, err
+}
+
+type InferJoinsArgs struct {
+	Tables  []string `json:"tables" jsonschema:"Tables to join, either bare names (public schema) or schema.table, quoting mixed-case names"`
+	MaxHops int      `json:"max_hops,omitempty" jsonschema:"Maximum number of foreign key hops between any two tables (default: 4)"`
+}
+
+// tableAlias builds a short alias from the initials of a table name, e.g.
+// order_items -> oi, falling back to numbered variants when already taken.
+func tableAlias(table string, taken map[string]bool) string {
+	_, name := splitQualifiedName(table)
+	alias := ""
+	for _, part := range strings.Split(name, "_") {
+		if part != "" {
+			alias += strings.ToLower(part[:1])
+		}
+	}
+	if alias == "" {
+		alias = "t"
+	}
+
+	candidate := alias
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", alias, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// qualifyTableName turns a bare, schema-qualified or quoted table name into
+// the "schema.table" form of graph nodes.
+func qualifyTableName(name string) string {
+	schema, table, _ := parseQualifiedName(name)
+	return getSchema(schema) + "." + table
+}
+
+// resolveQualifiedTable is qualifyTableName with the case resolution of
+// resolveTableName.
+func (s *serverState) resolveQualifiedTable(ctx context.Context, name string) (string, error) {
+	schema, table, err := s.resolveTableName(ctx, "", name)
+	if err != nil {
+		return "", err
+	}
+	return schema + "." + table, nil
+}
+
+func (s *serverState) InferJoins(ctx context.Context, req *mcp.CallToolRequest, args InferJoinsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if len(args.Tables) < 2 {
+		return s.returnErrorResult("at least two tables are required")
+	}
+
+	maxHops := args.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultMaxHops
+	}
+
+	edges, err := s.loadForeignKeys(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var requested []string
+	for _, table := range args.Tables {
+		table, err := s.resolveQualifiedTable(ctx, table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.qualifiedAllowed(table) {
+			return s.returnNotAccessible(table)
+		}
+		if !slices.Contains(requested, table) {
+			requested = append(requested, table)
+		}
+	}
+
+	taken := make(map[string]bool)
+	aliases := map[string]string{requested[0]: tableAlias(requested[0], taken)}
+	connected := []string{requested[0]}
+	remaining := slices.Clone(requested[1:])
+
+	var joins, ambiguities []map[string]interface{}
+	var intermediate []string
+	fromClause := fmt.Sprintf("FROM %s %s", sanitizeQualifiedName(requested[0]), aliases[requested[0]])
+
+	// greedily attach whichever remaining table is closest to the tables joined so far
+	for len(remaining) > 0 {
+		var best []joinStep
+		var bestSource, bestTarget string
+		for _, target := range remaining {
+			for _, source := range connected {
+				paths := findJoinPaths(edges, source, target, maxHops, 1)
+				if len(paths) > 0 && (best == nil || len(paths[0]) < len(best)) {
+					best, bestSource, bestTarget = paths[0], source, target
+				}
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		alternatives := findJoinPaths(edges, bestSource, bestTarget, len(best), maxCandidatePaths)
+		if len(alternatives) > 1 {
+			var described []string
+			for _, path := range alternatives {
+				var chain []string
+				for _, step := range path {
+					chain = append(chain, step.describe())
+				}
+				described = append(described, strings.Join(chain, "; "))
+			}
+			ambiguities = append(ambiguities, map[string]interface{}{
+				"from":         bestSource,
+				"to":           bestTarget,
+				"alternatives": described,
+			})
+		}
+
+		for _, step := range best {
+			target := step.target()
+			if _, ok := aliases[target]; ok {
+				continue
+			}
+			aliases[target] = tableAlias(target, taken)
+			condition := joinCondition(step, aliases[step.source()], aliases[target])
+			fromClause += fmt.Sprintf("\nJOIN %s %s ON %s", sanitizeQualifiedName(target), aliases[target], condition)
+			joins = append(joins, map[string]interface{}{
+				"table":      target,
+				"alias":      aliases[target],
+				"on":         condition,
+				"constraint": step.Edge.Name,
+			})
+			connected = append(connected, target)
+			if !slices.Contains(requested, target) {
+				intermediate = append(intermediate, target)
+			}
+		}
+
+		remaining = slices.DeleteFunc(remaining, func(table string) bool {
+			_, ok := aliases[table]
+			return ok
+		})
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"from_clause":         fromClause,
+		"aliases":             aliases,
+		"joins":               joins,
+		"intermediate_tables": intermediate,
+		"unreachable_tables":  remaining,
+		"ambiguities":         ambiguities,
+	})
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTraverseHierarchy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("friendship chain with depth limit", func(t *testing.T) {
+		args := TraverseHierarchyArgs{
+			TableName:    "friendships",
+			ParentColumn: "user_id",
+			ChildColumn:  "friend_id",
+			RootValue:    "1",
+			MaxDepth:     3,
+		}
+		result, data, err := testServer.TraverseHierarchy(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("TraverseHierarchy failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		tree := data.(map[string]interface{})
+		nodes := tree["nodes"].([]map[string]interface{})
+		if len(nodes) == 0 {
+			t.Fatal("Expected nodes below root")
+		}
+
+		for _, node := range nodes {
+			if node["depth"].(int) > 3 {
+				t.Errorf("Node %v exceeds max depth: %v", node["key"], node["depth"])
+			}
+			if node["row"] == nil {
+				t.Errorf("Expected row data for node %v", node["key"])
+			}
+		}
+
+		if !tree["max_depth_reached"].(bool) {
+			t.Error("Expected max depth to be reached on a long chain")
+		}
+	})
+
+	t.Run("missing columns", func(t *testing.T) {
+		args := TraverseHierarchyArgs{TableName: "friendships", RootValue: "1"}
+		result, _, err := testServer.TraverseHierarchy(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("TraverseHierarchy failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result when columns are missing")
+		}
+	})
+}
+
+func TestFindJoinPaths(t *testing.T) {
+	edges := []fkEdge{
+		{Name: "posts_user_id_fkey", FromTable: "public.posts", FromColumns: []string{"user_id"}, ToTable: "public.users", ToColumns: []string{"id"}},
+		{Name: "comments_post_id_fkey", FromTable: "public.comments", FromColumns: []string{"post_id"}, ToTable: "public.posts", ToColumns: []string{"id"}},
+		{Name: "comments_user_id_fkey", FromTable: "public.comments", FromColumns: []string{"user_id"}, ToTable: "public.users", ToColumns: []string{"id"}},
+	}
+
+	paths := findJoinPaths(edges, "public.comments", "public.users", 4, 10)
+	if len(paths) != 2 {
+		t.Fatalf("Expected 2 paths, got %d", len(paths))
+	}
+
+	if len(paths[0]) != 1 || paths[0][0].Edge.Name != "comments_user_id_fkey" {
+		t.Errorf("Expected direct path first, got %v", paths[0])
+	}
+
+	if len(paths[1]) != 2 {
+		t.Errorf("Expected two hop path through posts, got %d hops", len(paths[1]))
+	}
+
+	// walking an edge backwards joins the referencing columns onto the referenced ones
+	condition := joinCondition(joinStep{Edge: edges[0], Forward: false}, "t0", "t1")
+	if condition != `t1."user_id" = t0."id"` {
+		t.Errorf("Unexpected join condition: %s", condition)
+	}
+
+	if paths := findJoinPaths(edges, "public.comments", "public.users", 0, 10); len(paths) != 0 {
+		t.Errorf("Expected no paths with zero hops, got %d", len(paths))
+	}
+}
+
+func TestFindRowPath(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("comment to its author", func(t *testing.T) {
+		args := FindRowPathArgs{
+			FromTable: "comments",
+			FromValue: "1",
+			ToTable:   "users",
+			ToValue:   "1",
+		}
+		result, data, err := testServer.FindRowPath(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("FindRowPath failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		if !response["connected"].(bool) {
+			t.Error("Expected comment 1 to be connected to user 1")
+		}
+
+		paths := response["paths"].([]map[string]interface{})
+		if len(paths) == 0 || paths[0]["hops"].(int) != 1 {
+			t.Errorf("Expected shortest path to be a single hop, got %v", paths)
+		}
+	})
+
+	t.Run("unrelated tables", func(t *testing.T) {
+		args := FindRowPathArgs{
+			FromTable: "listings",
+			FromValue: "1",
+			ToTable:   "comments",
+			ToValue:   "1",
+			MaxHops:   1,
+		}
+		_, data, err := testServer.FindRowPath(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("FindRowPath failed: %v", err)
+		}
+
+		if data.(map[string]interface{})["connected"].(bool) {
+			t.Error("Expected no single hop path between listings and comments")
+		}
+	})
+}
+
+func TestInferJoins(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("direct foreign key", func(t *testing.T) {
+		args := InferJoinsArgs{Tables: []string{"posts", "users"}}
+		result, data, err := testServer.InferJoins(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("InferJoins failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		response := data.(map[string]interface{})
+		fromClause := response["from_clause"].(string)
+		if !strings.Contains(fromClause, `JOIN "public"."users" u ON u."id" = p."user_id"`) {
+			t.Errorf("Unexpected from clause: %s", fromClause)
+		}
+	})
+
+	t.Run("intermediate table", func(t *testing.T) {
+		args := InferJoinsArgs{Tables: []string{"listings", "comments"}}
+		_, data, err := testServer.InferJoins(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("InferJoins failed: %v", err)
+		}
+
+		response := data.(map[string]interface{})
+		intermediate := response["intermediate_tables"].([]string)
+		if !slices.Contains(intermediate, "public.users") {
+			t.Errorf("Expected users as intermediate table, got %v", intermediate)
+		}
+
+		if len(response["unreachable_tables"].([]string)) != 0 {
+			t.Errorf("Expected all tables to be reachable, got %v", response["unreachable_tables"])
+		}
+	})
+
+	t.Run("too few tables", func(t *testing.T) {
+		args := InferJoinsArgs{Tables: []string{"users"}}
+		result, _, err := testServer.InferJoins(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("InferJoins failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for a single table")
+		}
+	})
+}
+
+func TestTableAlias(t *testing.T) {
+	taken := make(map[string]bool)
+	if alias := tableAlias("public.order_items", taken); alias != "oi" {
+		t.Errorf("Expected oi, got %s", alias)
+	}
+	if alias := tableAlias("sales.order_imports", taken); alias != "oi2" {
+		t.Errorf("Expected oi2 for clashing alias, got %s", alias)
+	}
+}
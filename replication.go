@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// defaultRetainedWALWarning is how much WAL an inactive slot may hold back
+// before it is flagged.
+const defaultRetainedWALWarning = 1 << 30
+
+type LogicalReplicationInfoArgs struct {
+	RetainedWALWarningBytes int64 `json:"retained_wal_warning_bytes,omitempty" jsonschema:"Flag inactive slots retaining more WAL than this many bytes (default: 1 GiB)"`
+}
+
+func (s *serverState) LogicalReplicationInfo(ctx context.Context, req *mcp.CallToolRequest, args LogicalReplicationInfoArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get server version: %v", err)
+	}
+	warnBytes := args.RetainedWALWarningBytes
+	if warnBytes <= 0 {
+		warnBytes = defaultRetainedWALWarning
+	}
+
+	var walLevel, maxSlotWALKeepSize string
+	var inRecovery bool
+	err = s.pool.QueryRow(ctx, "SELECT current_setting('wal_level'), COALESCE(current_setting('max_slot_wal_keep_size', true), ''), pg_is_in_recovery()").Scan(&walLevel, &maxSlotWALKeepSize, &inRecovery)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read replication settings: %v", err)
+	}
+
+	// wal_status and safe_wal_size came with max_slot_wal_keep_size in 13
+	walStatus := "NULL::text, NULL::bigint"
+	if versionNum >= 130000 {
+		walStatus = "wal_status, safe_wal_size"
+	}
+	// the current LSN can't be read on a standby, replay position stands in
+	currentLSN := "pg_current_wal_lsn()"
+	if inRecovery {
+		currentLSN = "pg_last_wal_replay_lsn()"
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT slot_name::text, slot_type, COALESCE(plugin::text, ''), COALESCE(database::text, ''),
+			active, active_pid, temporary,
+			pg_wal_lsn_diff(%[1]s, restart_lsn)::bigint,
+			pg_wal_lsn_diff(%[1]s, confirmed_flush_lsn)::bigint,
+			%[2]s
+		FROM pg_replication_slots
+		ORDER BY slot_name
+	`, currentLSN, walStatus))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list replication slots: %v", err)
+	}
+	defer rows.Close()
+
+	var slots []map[string]interface{}
+	var pinning []string
+	for rows.Next() {
+		var name, slotType, plugin, database string
+		var active, temporary bool
+		var activePID *int32
+		var retained, lag, safeWALSize *int64
+		var status *string
+		if err := rows.Scan(&name, &slotType, &plugin, &database, &active, &activePID, &temporary, &retained, &lag, &status, &safeWALSize); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		slot := map[string]interface{}{
+			"slot_name": name,
+			"slot_type": slotType,
+			"active":    active,
+			"temporary": temporary,
+		}
+		if plugin != "" {
+			slot["plugin"] = plugin
+			slot["database"] = database
+		}
+		if activePID != nil {
+			slot["active_pid"] = *activePID
+		}
+		if retained != nil {
+			slot["retained_wal_bytes"] = *retained
+		}
+		if lag != nil {
+			slot["unconfirmed_bytes"] = *lag
+		}
+		if status != nil {
+			slot["wal_status"] = *status
+		}
+		if safeWALSize != nil {
+			slot["safe_wal_size_bytes"] = *safeWALSize
+		}
+
+		var notes []string
+		if !active && retained != nil && *retained >= warnBytes {
+			notes = append(notes, "Inactive and pinning WAL: the server keeps every WAL segment since restart_lsn until a consumer reconnects or the slot is dropped (SELECT pg_drop_replication_slot(...))")
+			pinning = append(pinning, name)
+		}
+		if status != nil && *status == "lost" {
+			notes = append(notes, "The WAL this slot needs was removed (max_slot_wal_keep_size), its consumer has to be resynchronized")
+		} else if status != nil && *status == "unreserved" {
+			notes = append(notes, "Retains more WAL than max_slot_wal_keep_size allows, the WAL is removed at the next checkpoint unless the consumer catches up")
+		}
+		slot["notes"] = notes
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	publications, err := s.listPublications(ctx, versionNum)
+	if err != nil {
+		return nil, nil, err
+	}
+	subscriptions, err := s.listSubscriptions(ctx, versionNum)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := map[string]interface{}{
+		"wal_level":     walLevel,
+		"in_recovery":   inRecovery,
+		"slots":         slots,
+		"publications":  publications,
+		"subscriptions": subscriptions,
+	}
+	if maxSlotWALKeepSize != "" {
+		response["max_slot_wal_keep_size"] = maxSlotWALKeepSize
+	}
+
+	var warnings []string
+	if len(pinning) > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("Inactive replication slots are retaining WAL and growing the WAL directory: %s"), strings.Join(pinning, ", ")))
+	}
+	if walLevel != "logical" && len(publications) > 0 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("wal_level is %s, publications only replicate with wal_level = logical"), walLevel))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
+
+// listPublications returns the publications of the database with the tables
+// they publish, leaving out tables outside the allow/deny lists.
+func (s *serverState) listPublications(ctx context.Context, versionNum int) ([]map[string]interface{}, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT pubname::text, puballtables, pubinsert, pubupdate, pubdelete, pubtruncate
+		FROM pg_publication
+		ORDER BY pubname
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list publications: %v", err)
+	}
+	var publications []map[string]interface{}
+	for rows.Next() {
+		var name string
+		var allTables, insert, update, del, truncate bool
+		if err := rows.Scan(&name, &allTables, &insert, &update, &del, &truncate); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		var operations []string
+		for _, operation := range []struct {
+			Name    string
+			Enabled bool
+		}{{"insert", insert}, {"update", update}, {"delete", del}, {"truncate", truncate}} {
+			if operation.Enabled {
+				operations = append(operations, operation.Name)
+			}
+		}
+		publications = append(publications, map[string]interface{}{
+			"name":       name,
+			"all_tables": allTables,
+			"operations": operations,
+		})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	// row filters and column lists came in 15
+	filters := "NULL::text[], NULL::text"
+	if versionNum >= 150000 {
+		filters = "attnames::text[], rowfilter"
+	}
+	for _, publication := range publications {
+		rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+			SELECT schemaname::text, tablename::text, %s
+			FROM pg_publication_tables
+			WHERE pubname = $1
+			ORDER BY 1, 2
+		`, filters), publication["name"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to list published tables: %v", err)
+		}
+		var tables []map[string]interface{}
+		for rows.Next() {
+			var schema, table string
+			var columns []string
+			var rowFilter *string
+			if err := rows.Scan(&schema, &table, &columns, &rowFilter); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan row: %v", err)
+			}
+			if !s.relationAllowed(schema, table) {
+				continue
+			}
+			entry := map[string]interface{}{"table": qualifiedName(schema, table)}
+			if len(columns) > 0 {
+				entry["columns"] = columns
+			}
+			if rowFilter != nil {
+				entry["row_filter"] = *rowFilter
+			}
+			tables = append(tables, entry)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("row iteration error: %v", err)
+		}
+		publication["tables"] = tables
+	}
+	return publications, nil
+}
+
+// listSubscriptions returns the subscriptions of the current database with
+// the state of their apply workers. subconninfo is left out, it holds
+// credentials and is not readable without superuser.
+func (s *serverState) listSubscriptions(ctx context.Context, versionNum int) ([]map[string]interface{}, error) {
+	// error counters came in 15
+	errorCounts := "NULL::bigint, NULL::bigint"
+	errorsJoin := ""
+	if versionNum >= 150000 {
+		errorCounts = "e.apply_error_count, e.sync_error_count"
+		errorsJoin = "LEFT JOIN pg_stat_subscription_stats e ON e.subid = sub.oid"
+	}
+	// the leader apply worker, not table sync or (16+) parallel apply workers
+	worker := "st.relid IS NULL"
+	if versionNum >= 160000 {
+		worker += " AND st.leader_pid IS NULL"
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT sub.subname::text, sub.subenabled, COALESCE(sub.subslotname::text, ''), sub.subpublications::text[],
+			st.pid, st.received_lsn::text, st.latest_end_lsn::text, st.last_msg_receipt_time, st.latest_end_time,
+			%s
+		FROM pg_subscription sub
+		LEFT JOIN pg_stat_subscription st ON st.subid = sub.oid AND %s
+		%s
+		WHERE sub.subdbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		ORDER BY sub.subname
+	`, errorCounts, worker, errorsJoin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %v", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []map[string]interface{}
+	for rows.Next() {
+		var name, slot string
+		var enabled bool
+		var publications []string
+		var pid *int32
+		var receivedLSN, latestEndLSN *string
+		var lastMessage, latestEnd *time.Time
+		var applyErrors, syncErrors *int64
+		if err := rows.Scan(&name, &enabled, &slot, &publications, &pid, &receivedLSN, &latestEndLSN, &lastMessage, &latestEnd, &applyErrors, &syncErrors); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		status := "streaming"
+		switch {
+		case !enabled:
+			status = "disabled"
+		case pid == nil:
+			status = "not running"
+		}
+		subscription := map[string]interface{}{
+			"name":         name,
+			"enabled":      enabled,
+			"status":       status,
+			"slot_name":    slot,
+			"publications": publications,
+		}
+		if pid != nil {
+			subscription["worker_pid"] = *pid
+		}
+		addOptionalString(subscription, "received_lsn", receivedLSN)
+		addOptionalString(subscription, "latest_end_lsn", latestEndLSN)
+		if lastMessage != nil {
+			subscription["last_message_at"] = *lastMessage
+		}
+		if latestEnd != nil {
+			subscription["latest_end_at"] = *latestEnd
+		}
+		if applyErrors != nil {
+			subscription["apply_error_count"] = *applyErrors
+			subscription["sync_error_count"] = *syncErrors
+		}
+		if enabled && pid == nil {
+			subscription["notes"] = []string{"Enabled but no apply worker is running, check the server log for replication errors"}
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return subscriptions, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLogicalReplicationInfo(t *testing.T) {
+	args := LogicalReplicationInfoArgs{}
+	result, data, err := testServer.LogicalReplicationInfo(context.Background(), createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("LogicalReplicationInfo failed: %v %v", err, result)
+	}
+
+	// the test database replicates nothing
+	info := data.(map[string]interface{})
+	if info["wal_level"] == "" || info["slots"] != nil && len(info["slots"].([]map[string]interface{})) != 0 {
+		t.Errorf("Expected the settings and no slots, got %v", info)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	serializationBackoff    = 20 * time.Millisecond
+	maxSerializationBackoff = time.Second
+)
+
+// serializationFailure reports whether err is a serialization failure, which
+// REPEATABLE READ and SERIALIZABLE transactions report when running them
+// concurrently could not be made equivalent to running them one at a time.
+// The whole transaction has to be retried.
+func serializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+// txIsoLevel maps an isolation_level argument to the transaction option,
+// empty keeping the server's default.
+func txIsoLevel(name string) (pgx.TxIsoLevel, bool) {
+	if name == "" {
+		return "", true
+	}
+	level, ok := isolationLevels[name]
+	return pgx.TxIsoLevel(strings.ToLower(level)), ok
+}
+
+// retrySerializable runs a transaction and, when it fails with a
+// serialization failure at an isolation level that causes them, runs it
+// again up to SERIALIZATION_RETRIES times with capped, jittered exponential
+// backoff. attempt returns serialization failures as its error rather than
+// as an error result, so they can be told apart.
+func (s *serverState) retrySerializable(ctx context.Context, level pgx.TxIsoLevel, attempt func() (*mcp.CallToolResult, any, error)) (*mcp.CallToolResult, any, error) {
+	result, data, err := attempt()
+	if level != pgx.RepeatableRead && level != pgx.Serializable {
+		return result, data, err
+	}
+
+	backoff := serializationBackoff
+	retries := 0
+	for ; serializationFailure(err) && retries < s.config.SerializationRetries; retries++ {
+		select {
+		case <-time.After(rand.N(backoff) + backoff/2):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		backoff = min(2*backoff, maxSerializationBackoff)
+		result, data, err = attempt()
+	}
+
+	if serializationFailure(err) {
+		return s.returnErrorResult("The transaction still failed to serialize after %d retries: %v", retries, err)
+	}
+	if err == nil && retries > 0 {
+		result = s.withWarnings(result, []string{fmt.Sprintf(s.localize("Retried %d times after serialization failures"), retries)})
+	}
+	return result, data, err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestTxIsoLevel(t *testing.T) {
+	if level, ok := txIsoLevel("serializable"); !ok || level != pgx.Serializable {
+		t.Errorf("Expected serializable, got %q", level)
+	}
+	if level, ok := txIsoLevel(""); !ok || level != "" {
+		t.Errorf("Expected the server default, got %q", level)
+	}
+	if _, ok := txIsoLevel("snapshot"); ok {
+		t.Error("Expected an unknown level to be rejected")
+	}
+	if !serializationFailure(&pgconn.PgError{Code: "40001"}) || serializationFailure(&pgconn.PgError{Code: "40P01"}) {
+		t.Error("Expected only 40001 to count as a serialization failure")
+	}
+}
+
+func TestSerializationRetry(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.QueryPolicy, _ = parseQueryPolicy("dml=allow")
+	testServer.config.DryRun = true
+	testServer.config.SerializationRetries = 3
+
+	// hold the row lock so the tool's update waits, then commit a change to
+	// the row so its snapshot is stale
+	blocker, err := testServer.pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	defer blocker.Rollback(ctx)
+	if _, err := blocker.Exec(ctx, "UPDATE users SET bio = bio WHERE id = 2"); err != nil {
+		t.Fatalf("Failed to lock the row: %v", err)
+	}
+
+	type outcome struct {
+		result *mcp.CallToolResult
+		err    error
+	}
+	done := make(chan outcome)
+	go func() {
+		args := QueryArgs{Query: "UPDATE users SET bio = bio WHERE id = 2", IsolationLevel: "serializable"}
+		result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		done <- outcome{result, err}
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for waiting := 0; waiting == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		testServer.pool.QueryRow(ctx, "SELECT count(*) FROM pg_stat_activity WHERE wait_event_type = 'Lock' AND query LIKE 'UPDATE users SET bio = bio WHERE id = 2%'").Scan(&waiting)
+	}
+	if err := blocker.Commit(ctx); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	got := <-done
+	if got.err != nil || got.result.IsError {
+		t.Fatalf("Expected the update to succeed after a retry, got %v %v", got.err, got.result)
+	}
+	last := got.result.Content[len(got.result.Content)-1].(*mcp.TextContent).Text
+	if !strings.Contains(last, "Retried 1 times") {
+		t.Errorf("Expected the retry to be reported, got %q", last)
+	}
+}
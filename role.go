@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const roleCheckTimeout = 5 * time.Second
+
+// setConnectionRole switches every new pool connection to the configured
+// role before it is handed out.
+func setConnectionRole(role string) func(context.Context, *pgx.Conn) error {
+	return func(ctx context.Context, conn *pgx.Conn) error {
+		if _, err := conn.Exec(ctx, "SET ROLE "+pgx.Identifier{role}.Sanitize()); err != nil {
+			return fmt.Errorf("failed to SET ROLE %s: %v", role, err)
+		}
+		return nil
+	}
+}
+
+// checkConnectionRole drops connections whose role was changed by a query
+// (SET ROLE, set_config), so the change can't carry over to later calls.
+func checkConnectionRole(role string) func(*pgx.Conn) bool {
+	return func(conn *pgx.Conn) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), roleCheckTimeout)
+		defer cancel()
+
+		var current string
+		if err := conn.QueryRow(ctx, "SELECT current_user::text").Scan(&current); err != nil {
+			return false
+		}
+		if current != role {
+			log.Printf("Discarding connection that switched from role %s to %s", role, current)
+			return false
+		}
+		return true
+	}
+}
+
+// applyRole switches tx to the role a tool call asked for with SET LOCAL
+// ROLE. With ROLE configured only roles it is a member of can be used.
+// Statements that switch roles themselves are refused by
+// checkStatementAccess, but one run by a function goes unseen until
+// checkConnectionRole discards the connection after the call.
+func (s *serverState) applyRole(ctx context.Context, tx pgx.Tx, role string) error {
+	if role == "" {
+		return nil
+	}
+
+	if s.config.Role != "" {
+		var member bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $2)
+				AND pg_has_role($1, $2, 'MEMBER')
+		`, s.config.Role, role).Scan(&member)
+		if err != nil {
+			return fmt.Errorf("failed to check role %s: %w", role, err)
+		}
+		if !member {
+			return fmt.Errorf("role %s is not granted to the server role %s", role, s.config.Role)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "SET LOCAL ROLE "+pgx.Identifier{role}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to switch to role %s: %w", role, err)
+	}
+	return nil
+}
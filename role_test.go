@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRole(t *testing.T) {
+	ctx := context.Background()
+
+	setup := []string{
+		"CREATE ROLE mcp_reader NOLOGIN",
+		"GRANT SELECT ON users TO mcp_reader",
+	}
+	for _, statement := range setup {
+		if _, err := testServer.pool.Exec(ctx, statement); err != nil {
+			t.Fatalf("Failed to set up role: %v", err)
+		}
+	}
+	defer testServer.pool.Exec(ctx, "DROP OWNED BY mcp_reader; DROP ROLE mcp_reader")
+
+	t.Run("per-call role", func(t *testing.T) {
+		args := QueryArgs{Query: "SELECT current_user::text AS role, count(*) AS users FROM users", Role: "mcp_reader"}
+		result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		if role := data.([]map[string]interface{})[0]["role"]; role != "mcp_reader" {
+			t.Errorf("Expected query to run as mcp_reader, got %v", role)
+		}
+
+		args = QueryArgs{Query: "SELECT count(*) FROM posts", Role: "mcp_reader"}
+		result, _, err = testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		if result == nil || !result.IsError {
+			t.Error("Expected permission error for a table mcp_reader cannot read")
+		}
+	})
+
+	t.Run("per-call role cannot escalate", func(t *testing.T) {
+		savedConfig := testServer.config
+		defer func() { testServer.config = savedConfig }()
+		testServer.config.Role = "mcp_reader"
+
+		args := ExplainAnalyzeArgs{Query: "SELECT 1", Role: "testuser"}
+		result, _, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExplainAnalyze failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for a role not granted to the server role")
+		}
+	})
+
+	t.Run("statements cannot switch role", func(t *testing.T) {
+		for _, query := range []string{"RESET ROLE", "SET ROLE testuser", "SELECT set_config('role', 'testuser', true)"} {
+			args := QueryArgs{Query: query, Role: "mcp_reader"}
+			result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+			if err != nil {
+				t.Fatalf("ExecuteQuery failed: %v", err)
+			}
+			if result == nil || !result.IsError {
+				t.Errorf("Expected %q to be refused", query)
+			}
+		}
+	})
+
+	t.Run("connection role", func(t *testing.T) {
+		conn, err := testServer.pool.Acquire(ctx)
+		if err != nil {
+			t.Fatalf("Failed to acquire connection: %v", err)
+		}
+		defer conn.Release()
+		defer conn.Exec(ctx, "RESET ROLE")
+
+		if err := setConnectionRole("mcp_reader")(ctx, conn.Conn()); err != nil {
+			t.Fatalf("setConnectionRole failed: %v", err)
+		}
+
+		check := checkConnectionRole("mcp_reader")
+		if !check(conn.Conn()) {
+			t.Error("Expected connection with the configured role to be kept")
+		}
+
+		if _, err := conn.Exec(ctx, "RESET ROLE"); err != nil {
+			t.Fatalf("Failed to reset role: %v", err)
+		}
+		if check(conn.Conn()) {
+			t.Error("Expected connection that left the configured role to be discarded")
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RowCondition is one column/op/value triple of a structured where clause.
+type RowCondition struct {
+	Column string `json:"column" jsonschema:"Column to compare"`
+	Op     string `json:"op" jsonschema:"Comparison: =, <>, <, <=, >, >=, like, ilike, in, is_null or is_not_null"`
+	Value  any    `json:"value,omitempty" jsonschema:"Value to compare with: an array for in, nothing for is_null and is_not_null"`
+}
+
+// conditionOperators map the ops of a RowCondition to SQL.
+var conditionOperators = map[string]string{
+	"=": "=", "<>": "<>", "!=": "<>", "<": "<", "<=": "<=", ">": ">", ">=": ">=",
+	"like": "LIKE", "ilike": "ILIKE", "in": "IN", "is_null": "IS NULL", "is_not_null": "IS NOT NULL",
+}
+
+// tableColumnTypes returns the types of a table's columns, without type
+// modifiers so casting a value can't silently truncate it.
+func (s *serverState) tableColumnTypes(ctx context.Context, schema, table string) (map[string]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT a.attname::text, format_type(a.atttypid, NULL)
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::text::regclass AND a.attnum > 0 AND NOT a.attisdropped
+	`, pgx.Identifier{schema, table}.Sanitize())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, typeName string
+		if err := rows.Scan(&name, &typeName); err != nil {
+			return nil, err
+		}
+		columns[name] = typeName
+	}
+	return columns, rows.Err()
+}
+
+// valueLiteral renders a JSON argument as a SQL literal cast to the column's
+// type, so statements can be shown and queued for approval as they run.
+// Objects and arrays are written as JSON, for json columns.
+func valueLiteral(value any, typeName string) (string, error) {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		text = v
+	case bool:
+		text = strconv.FormatBool(v)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		text = string(encoded)
+	}
+	return quoteLiteral(text) + "::" + typeName, nil
+}
+
+// buildWhere renders structured conditions as a WHERE clause over columns,
+// joined with AND.
+func buildWhere(conditions []RowCondition, columns map[string]string) (string, error) {
+	clauses := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		typeName, ok := columns[condition.Column]
+		if !ok {
+			return "", fmt.Errorf("column %q does not exist", condition.Column)
+		}
+		op := strings.ToLower(strings.TrimSpace(condition.Op))
+		operator, ok := conditionOperators[op]
+		if !ok {
+			return "", fmt.Errorf("unknown op %q, use =, <>, <, <=, >, >=, like, ilike, in, is_null or is_not_null", condition.Op)
+		}
+		column := quoteIdentifier(condition.Column)
+
+		switch op {
+		case "is_null", "is_not_null":
+			clauses = append(clauses, column+" "+operator)
+		case "in":
+			values, ok := condition.Value.([]any)
+			if !ok || len(values) == 0 {
+				return "", fmt.Errorf("in on %s needs a non-empty array of values", condition.Column)
+			}
+			literals := make([]string, len(values))
+			for i, value := range values {
+				var err error
+				if literals[i], err = valueLiteral(value, typeName); err != nil {
+					return "", err
+				}
+			}
+			clauses = append(clauses, fmt.Sprintf("%s IN (%s)", column, strings.Join(literals, ", ")))
+		case "like", "ilike":
+			pattern, ok := condition.Value.(string)
+			if !ok {
+				return "", fmt.Errorf("%s on %s needs a string pattern", op, condition.Column)
+			}
+			clauses = append(clauses, fmt.Sprintf("%s::text %s %s", column, operator, quoteLiteral(pattern)))
+		default:
+			if condition.Value == nil {
+				return "", fmt.Errorf("%s on %s needs a value, use is_null to match NULL", condition.Op, condition.Column)
+			}
+			literal, err := valueLiteral(condition.Value, typeName)
+			if err != nil {
+				return "", err
+			}
+			clauses = append(clauses, fmt.Sprintf("%s %s %s", column, operator, literal))
+		}
+	}
+	return strings.Join(clauses, " AND "), nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuildWhere(t *testing.T) {
+	columns := map[string]string{"id": "integer", "status": "text", "Deleted At": "timestamp with time zone"}
+	where, err := buildWhere([]RowCondition{
+		{Column: "id", Op: "in", Value: []any{1.0, 2.0}},
+		{Column: "status", Op: "ILIKE", Value: "o'pen%"},
+		{Column: "Deleted At", Op: "is_null"},
+	}, columns)
+	expected := `id IN ('1'::integer, '2'::integer) AND status::text ILIKE 'o''pen%' AND "Deleted At" IS NULL`
+	if err != nil || where != expected {
+		t.Errorf("Expected %s, got %s %v", expected, where, err)
+	}
+
+	for _, conditions := range [][]RowCondition{
+		{{Column: "missing", Op: "=", Value: 1.0}},
+		{{Column: "id", Op: "between", Value: 1.0}},
+		{{Column: "id", Op: "=", Value: nil}},
+		{{Column: "id", Op: "in", Value: []any{}}},
+	} {
+		if _, err := buildWhere(conditions, columns); err == nil {
+			t.Errorf("Expected %+v to be rejected", conditions)
+		}
+	}
+}
+
+func TestUpdateRows(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+
+	args := UpdateRowsArgs{TableName: "users", Set: map[string]any{"bio": "updated"}}
+	if result, _, _ := testServer.UpdateRows(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected an update without where to be refused")
+	}
+
+	args.Where = []RowCondition{{Column: "id", Op: "=", Value: 1.0}}
+	result, data, err := testServer.UpdateRows(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("UpdateRows failed: %v %v", err, result)
+	}
+	response := data.(map[string]interface{})
+	if response["matching_rows"] != int64(1) || response["committed"] != false {
+		t.Errorf("Expected one matching row and nothing committed, got %v", response)
+	}
+	var bio string
+	if err := testServer.pool.QueryRow(ctx, "SELECT COALESCE(bio, '') FROM users WHERE id = 1").Scan(&bio); err != nil {
+		t.Fatalf("Failed to read bio: %v", err)
+	}
+	if bio == "updated" {
+		t.Error("Expected the preview not to run the update")
+	}
+
+	args.Set = map[string]any{"id": "not a number"}
+	if result, _, _ := testServer.UpdateRows(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected the preview to catch an invalid value")
+	}
+	args.Set = map[string]any{"bio": "updated"}
+
+	testServer.config.AllowWrites = false
+	args.Commit = true
+	if result, _, _ := testServer.UpdateRows(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected committing to need ALLOW_WRITES")
+	}
+}
+
+func TestDeleteRows(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.AllowWrites = true
+	testServer.config.DryRun = true
+
+	args := DeleteRowsArgs{TableName: "users"}
+	if result, _, _ := testServer.DeleteRows(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a delete without where to be refused")
+	}
+
+	args.Where = []RowCondition{{Column: "id", Op: "in", Value: []any{1.0, 2.0}}}
+	result, data, err := testServer.DeleteRows(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("DeleteRows failed: %v %v", err, result)
+	}
+	response := data.(map[string]interface{})
+	if response["matching_rows"] != int64(2) || len(response["preview"].([]map[string]interface{})) != 2 {
+		t.Errorf("Expected a preview of two rows, got %v", response)
+	}
+
+	wrong := int64(1)
+	args.ExpectedCount = &wrong
+	if result, _, _ := testServer.DeleteRows(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a delete of another number of rows to be rolled back")
+	}
+}
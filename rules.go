@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// firingModes name the enabled states of triggers, event triggers and
+// rules, which session_replication_role picks between.
+var firingModes = map[string]string{
+	"O": "enabled",
+	"D": "disabled",
+	"R": "replica",
+	"A": "always",
+}
+
+// ruleEvents name the pg_rewrite.ev_type codes.
+var ruleEvents = map[string]string{
+	"1": "SELECT",
+	"2": "UPDATE",
+	"3": "INSERT",
+	"4": "DELETE",
+}
+
+type ListEventTriggersArgs struct{}
+
+// ListEventTriggers lists the database's event triggers, which run on DDL
+// rather than on rows, with what their functions do. They fire for schema
+// changes made through any tool, so a refused or altered ALTER TABLE may
+// be their doing.
+func (s *serverState) ListEventTriggers(ctx context.Context, req *mcp.CallToolRequest, args ListEventTriggersArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT e.evtname::text, e.evtevent::text, pg_get_userbyid(e.evtowner)::text, e.evtenabled::text,
+			COALESCE(e.evttags, '{}'), p.oid::regprocedure::text, l.lanname::text, p.prosrc
+		FROM pg_event_trigger e
+		JOIN pg_proc p ON p.oid = e.evtfoid
+		JOIN pg_language l ON l.oid = p.prolang
+		ORDER BY e.evtevent, e.evtname
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list event triggers: %v", err)
+	}
+	defer rows.Close()
+
+	triggers := []map[string]interface{}{}
+	for rows.Next() {
+		var name, event, owner, enabled, function, language, source string
+		var tags []string
+		if err := rows.Scan(&name, &event, &owner, &enabled, &tags, &function, &language, &source); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		trigger := map[string]interface{}{
+			"name":     name,
+			"event":    event,
+			"owner":    owner,
+			"enabled":  firingModes[enabled],
+			"function": function,
+			"language": language,
+		}
+		// without tags it fires for every command of the event
+		if len(tags) > 0 {
+			trigger["command_tags"] = tags
+		}
+		if effects, permanent := codeSideEffects(language, source); len(effects) > 0 {
+			trigger["effects"] = effects
+			if len(permanent) > 0 {
+				trigger["survives_rollback"] = permanent
+			}
+		}
+		triggers = append(triggers, trigger)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{"event_triggers": triggers})
+}
+
+type ListRulesArgs struct {
+	TableName string `json:"table_name,omitempty" jsonschema:"Only list the rules of this table or view, optionally schema-qualified (default: every table)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Only list the rules in this schema (default: every schema but the system ones)"`
+}
+
+// ListRules lists the rewrite rules on tables and views, leaving out the
+// _RETURN rules that implement views. A rule rewrites a statement before it
+// runs, so a write can land somewhere else, do more, or do nothing at all.
+func (s *serverState) ListRules(ctx context.Context, req *mcp.CallToolRequest, args ListRulesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	schema, tableName := args.Schema, ""
+	if args.TableName != "" {
+		resolvedSchema, resolvedTable, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(resolvedSchema, resolvedTable) {
+			return s.returnNotAccessible(qualifiedName(resolvedSchema, resolvedTable))
+		}
+		schema, tableName = resolvedSchema, resolvedTable
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT n.nspname::text, c.relname::text, r.rulename::text, r.ev_type::text, r.is_instead,
+			r.ev_enabled::text, pg_get_ruledef(r.oid, true)
+		FROM pg_rewrite r
+		JOIN pg_class c ON c.oid = r.ev_class
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE r.rulename <> '_RETURN'
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND ($1 = '' OR n.nspname = $1)
+			AND ($2 = '' OR c.relname = $2)
+		ORDER BY n.nspname, c.relname, r.rulename
+	`, schema, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list rules: %v", err)
+	}
+	defer rows.Close()
+
+	rules := []map[string]interface{}{}
+	for rows.Next() {
+		var ruleSchema, table, name, event, enabled, definition string
+		var instead bool
+		if err := rows.Scan(&ruleSchema, &table, &name, &event, &instead, &enabled, &definition); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(ruleSchema, table) {
+			continue
+		}
+		rule := map[string]interface{}{
+			"table":      qualifiedName(ruleSchema, table),
+			"name":       name,
+			"event":      ruleEvents[event],
+			"instead":    instead,
+			"enabled":    firingModes[enabled],
+			"definition": definition,
+		}
+		// the rule's own ON ... DO prefix names the event, only its actions matter
+		action := definition
+		if index := strings.Index(strings.ToUpper(definition), " DO "); index >= 0 {
+			action = definition[index+4:]
+		}
+		if effects, permanent := codeSideEffects("sql", action); len(effects) > 0 {
+			rule["effects"] = effects
+			if len(permanent) > 0 {
+				rule["survives_rollback"] = permanent
+			}
+		}
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{"rules": rules})
+}
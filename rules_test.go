@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListRules(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE ruled_items (id int, note text);
+		CREATE TABLE ruled_log (id int);
+		CREATE RULE ruled_items_log AS ON INSERT TO ruled_items DO ALSO INSERT INTO ruled_log VALUES (NEW.id);
+		CREATE RULE ruled_items_keep AS ON DELETE TO ruled_items DO INSTEAD NOTHING;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create rules: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE ruled_items, ruled_log")
+
+	args := ListRulesArgs{TableName: "ruled_items"}
+	result, data, err := testServer.ListRules(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ListRules failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	rules := data.(map[string]interface{})["rules"].([]map[string]interface{})
+	if len(rules) != 2 {
+		t.Fatalf("Expected two rules, got %v", rules)
+	}
+	if rules[0]["name"] != "ruled_items_keep" || rules[0]["event"] != "DELETE" || rules[0]["instead"] != true {
+		t.Errorf("Expected the INSTEAD NOTHING delete rule first, got %v", rules[0])
+	}
+	if effects, _ := rules[1]["effects"].([]string); len(effects) == 0 || effects[0] != "writes data" {
+		t.Errorf("Expected the logging rule to write data, got %v", rules[1])
+	}
+}
+
+func TestListEventTriggers(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE FUNCTION guard_drops() RETURNS event_trigger LANGUAGE plpgsql AS $$
+		BEGIN
+			RAISE NOTICE 'dropping';
+		END $$;
+		CREATE EVENT TRIGGER guard_table_drops ON sql_drop WHEN TAG IN ('DROP TABLE') EXECUTE FUNCTION guard_drops();
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create event trigger: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP EVENT TRIGGER guard_table_drops; DROP FUNCTION guard_drops()")
+
+	args := ListEventTriggersArgs{}
+	_, data, err := testServer.ListEventTriggers(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ListEventTriggers failed: %v", err)
+	}
+	var found bool
+	for _, trigger := range data.(map[string]interface{})["event_triggers"].([]map[string]interface{}) {
+		if trigger["name"] == "guard_table_drops" {
+			found = true
+			if trigger["event"] != "sql_drop" || trigger["enabled"] != "enabled" || trigger["function"] != "guard_drops()" {
+				t.Errorf("Unexpected event trigger %v", trigger)
+			}
+		}
+	}
+	if !found {
+		t.Error("Expected guard_table_drops to be listed")
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const anomalyDemoTimeout = 30 * time.Second
+
+var isolationLevels = map[string]string{
+	"read_committed":  "READ COMMITTED",
+	"repeatable_read": "REPEATABLE READ",
+	"serializable":    "SERIALIZABLE",
+}
+
+var anomalyExplanations = map[string]string{
+	"lost_update": "Both sessions read the balance, compute a new value and write it back. " +
+		"Under READ COMMITTED the second write silently overwrites the first, so one deposit is lost. " +
+		"REPEATABLE READ and SERIALIZABLE abort the second writer with a serialization failure instead, and the application must retry.",
+	"non_repeatable_read": "Session A reads the same row twice while session B updates it in between. " +
+		"READ COMMITTED takes a new snapshot per statement and sees the new value, REPEATABLE READ and SERIALIZABLE keep the first snapshot.",
+	"phantom_read": "Session A counts matching rows twice while session B inserts a new matching row in between. " +
+		"READ COMMITTED sees the new row appear, REPEATABLE READ and SERIALIZABLE do not (PostgreSQL's REPEATABLE READ is stricter than the SQL standard requires).",
+	"write_skew": "Both sessions check that the combined balance covers a withdrawal, then withdraw from different accounts. " +
+		"Neither write conflicts with the other, so READ COMMITTED and REPEATABLE READ let the total go negative. Only SERIALIZABLE detects the dependency and aborts one session.",
+}
+
+type DemonstrateAnomalyArgs struct {
+	Anomaly        string `json:"anomaly" jsonschema:"Anomaly to demonstrate: lost_update, non_repeatable_read, phantom_read or write_skew"`
+	IsolationLevel string `json:"isolation_level,omitempty" jsonschema:"Isolation level for both sessions: read_committed, repeatable_read or serializable (default: read_committed)"`
+}
+
+// anomalyDemo runs scripted statements on two connections and records each step.
+type anomalyDemo struct {
+	ctx   context.Context
+	conns map[string]*pgxpool.Conn
+	table string
+	steps []map[string]interface{}
+}
+
+func (d *anomalyDemo) exec(session, statement string) error {
+	statement = fmt.Sprintf(statement, d.table)
+	step := map[string]interface{}{"session": session, "statement": statement}
+	d.steps = append(d.steps, step)
+
+	tag, err := d.conns[session].Exec(d.ctx, statement)
+	if err != nil {
+		step["error"] = err.Error()
+		return err
+	}
+	step["result"] = tag.String()
+	return nil
+}
+
+func (d *anomalyDemo) queryInt(session, statement string) (int64, error) {
+	statement = fmt.Sprintf(statement, d.table)
+	step := map[string]interface{}{"session": session, "statement": statement}
+	d.steps = append(d.steps, step)
+
+	var value int64
+	if err := d.conns[session].QueryRow(d.ctx, statement).Scan(&value); err != nil {
+		step["error"] = err.Error()
+		return 0, err
+	}
+	step["result"] = value
+	return value, nil
+}
+
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+// runAnomaly plays the anomaly's script and reports whether it occurred.
+func (d *anomalyDemo) runAnomaly(anomaly, begin string) (bool, error) {
+	switch anomaly {
+	case "lost_update":
+		d.exec("A", begin)
+		d.exec("B", begin)
+		balanceA, _ := d.queryInt("A", "SELECT balance FROM %s WHERE id = 1")
+		balanceB, _ := d.queryInt("B", "SELECT balance FROM %s WHERE id = 1")
+		d.exec("A", fmt.Sprintf("UPDATE %%s SET balance = %d WHERE id = 1", balanceA+10))
+		d.exec("A", "COMMIT")
+		if err := d.exec("B", fmt.Sprintf("UPDATE %%s SET balance = %d WHERE id = 1", balanceB+20)); err != nil {
+			d.exec("B", "ROLLBACK")
+			return false, ignoreSerializationFailure(err)
+		}
+		d.exec("B", "COMMIT")
+		final, err := d.queryInt("A", "SELECT balance FROM %s WHERE id = 1")
+		return final != 130, err
+
+	case "non_repeatable_read":
+		d.exec("A", begin)
+		first, _ := d.queryInt("A", "SELECT balance FROM %s WHERE id = 1")
+		d.exec("B", "UPDATE %s SET balance = balance + 50 WHERE id = 1")
+		second, err := d.queryInt("A", "SELECT balance FROM %s WHERE id = 1")
+		d.exec("A", "COMMIT")
+		return first != second, err
+
+	case "phantom_read":
+		d.exec("A", begin)
+		first, _ := d.queryInt("A", "SELECT COUNT(*) FROM %s WHERE balance >= 50")
+		d.exec("B", "INSERT INTO %s (id, balance) VALUES (3, 300)")
+		second, err := d.queryInt("A", "SELECT COUNT(*) FROM %s WHERE balance >= 50")
+		d.exec("A", "COMMIT")
+		return first != second, err
+
+	case "write_skew":
+		d.exec("A", begin)
+		d.exec("B", begin)
+		d.queryInt("A", "SELECT SUM(balance) FROM %s")
+		d.queryInt("B", "SELECT SUM(balance) FROM %s")
+		d.exec("A", "UPDATE %s SET balance = balance - 250 WHERE id = 1")
+		d.exec("B", "UPDATE %s SET balance = balance - 250 WHERE id = 2")
+		d.exec("A", "COMMIT")
+		if err := d.exec("B", "COMMIT"); err != nil {
+			return false, ignoreSerializationFailure(err)
+		}
+		total, err := d.queryInt("A", "SELECT SUM(balance) FROM %s")
+		return total < 0, err
+	}
+	return false, fmt.Errorf("unknown anomaly %q", anomaly)
+}
+
+// ignoreSerializationFailure treats a serialization failure as the expected
+// outcome of a prevented anomaly rather than an error.
+func ignoreSerializationFailure(err error) error {
+	if isSerializationFailure(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *serverState) DemonstrateAnomaly(ctx context.Context, req *mcp.CallToolRequest, args DemonstrateAnomalyArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	// the two sessions commit real transactions, so dry-run mode does not enable this
+	if !s.config.AllowWrites {
+		return s.returnWritesDisabled("demonstrate_anomaly")
+	}
+
+	explanation, ok := anomalyExplanations[args.Anomaly]
+	if !ok {
+		return s.returnErrorResult("Unknown anomaly %q, use lost_update, non_repeatable_read, phantom_read or write_skew", args.Anomaly)
+	}
+
+	levelName := args.IsolationLevel
+	if levelName == "" {
+		levelName = "read_committed"
+	}
+	level, ok := isolationLevels[levelName]
+	if !ok {
+		return s.returnErrorResult("Unknown isolation level %q, use read_committed, repeatable_read or serializable", levelName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, anomalyDemoTimeout)
+	defer cancel()
+
+	schema := pgx.Identifier{s.config.SandboxSchema}.Sanitize()
+	table := pgx.Identifier{s.config.SandboxSchema, fmt.Sprintf("anomaly_demo_%d", time.Now().UnixNano())}.Sanitize()
+	setup := fmt.Sprintf(`
+		CREATE SCHEMA IF NOT EXISTS %s;
+		CREATE TABLE %s (id INTEGER PRIMARY KEY, balance INTEGER NOT NULL);
+		INSERT INTO %s VALUES (1, 100), (2, 200);
+	`, schema, table, table)
+	if _, err := s.pool.Exec(ctx, setup); err != nil {
+		return s.returnErrorResult("Failed to set up sandbox table: %v", err)
+	}
+	defer s.pool.Exec(context.Background(), fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+
+	demo := &anomalyDemo{ctx: ctx, table: table, conns: make(map[string]*pgxpool.Conn)}
+	for _, session := range []string{"A", "B"} {
+		conn, err := s.pool.Acquire(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to acquire connection: %v", err)
+		}
+		defer conn.Release()
+		// leave no transaction open on a connection going back to the pool
+		defer conn.Exec(context.Background(), "ROLLBACK")
+		demo.conns[session] = conn
+	}
+
+	occurred, err := demo.runAnomaly(args.Anomaly, "BEGIN ISOLATION LEVEL "+level)
+	if err != nil {
+		return s.returnErrorResult("Demonstration failed: %v", err)
+	}
+
+	var finalBalances []int64
+	rows, err := s.pool.Query(ctx, fmt.Sprintf("SELECT balance FROM %s ORDER BY id", table))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read final state: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var balance int64
+		if err := rows.Scan(&balance); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		finalBalances = append(finalBalances, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"anomaly":          args.Anomaly,
+		"isolation_level":  level,
+		"anomaly_occurred": occurred,
+		"initial_balances": []int64{100, 200},
+		"final_balances":   finalBalances,
+		"steps":            demo.steps,
+		"explanation":      explanation,
+	})
+}
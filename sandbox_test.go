@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDemonstrateAnomaly(t *testing.T) {
+	ctx := context.Background()
+
+	previous := testServer.config
+	testServer.config.AllowWrites = true
+	testServer.config.SandboxSchema = "mcp_sandbox"
+	defer func() { testServer.config = previous }()
+
+	cases := []struct {
+		anomaly  string
+		level    string
+		occurred bool
+	}{
+		{"lost_update", "read_committed", true},
+		{"lost_update", "repeatable_read", false},
+		{"non_repeatable_read", "read_committed", true},
+		{"non_repeatable_read", "repeatable_read", false},
+		{"phantom_read", "read_committed", true},
+		{"phantom_read", "repeatable_read", false},
+		{"write_skew", "repeatable_read", true},
+		{"write_skew", "serializable", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.anomaly+" under "+c.level, func(t *testing.T) {
+			args := DemonstrateAnomalyArgs{Anomaly: c.anomaly, IsolationLevel: c.level}
+			result, data, err := testServer.DemonstrateAnomaly(ctx, createMockRequest(args), args)
+
+			if err != nil {
+				t.Fatalf("DemonstrateAnomaly failed: %v", err)
+			}
+
+			if result == nil || result.IsError {
+				t.Fatalf("Expected successful result, got %v", result)
+			}
+
+			response := data.(map[string]interface{})
+			if response["anomaly_occurred"] != c.occurred {
+				t.Errorf("Expected anomaly_occurred=%t, got %v with steps %v", c.occurred, response["anomaly_occurred"], response["steps"])
+			}
+		})
+	}
+
+	t.Run("sandbox tables are dropped", func(t *testing.T) {
+		var count int
+		testServer.pool.QueryRow(ctx, "SELECT COUNT(*) FROM pg_tables WHERE schemaname = 'mcp_sandbox'").Scan(&count)
+		if count != 0 {
+			t.Errorf("Expected no leftover sandbox tables, got %d", count)
+		}
+	})
+
+	t.Run("requires writes", func(t *testing.T) {
+		testServer.config.AllowWrites = false
+		defer func() { testServer.config.AllowWrites = true }()
+
+		args := DemonstrateAnomalyArgs{Anomaly: "lost_update"}
+		result, _, err := testServer.DemonstrateAnomaly(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("DemonstrateAnomaly failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected demonstration to be blocked without ALLOW_WRITES")
+		}
+	})
+}
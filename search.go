@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchTables    = 200
+)
+
+// matchedColumnsKey carries the names of the matching columns out of a
+// search query, next to the row's own columns.
+const matchedColumnsKey = "__matched_columns"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+type SearchDataArgs struct {
+	Value         string   `json:"value" jsonschema:"The value to look for, taken literally"`
+	Tables        []string `json:"tables,omitempty" jsonschema:"Tables to search, either bare names (in schema) or schema.table (default: every table of schema)"`
+	Schema        string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Match         string   `json:"match,omitempty" jsonschema:"contains (the value anywhere in a column) or exact (the whole column) (default: contains)"`
+	CaseSensitive bool     `json:"case_sensitive,omitempty" jsonschema:"Match case exactly (default: false)"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"Maximum matching rows to return per table (default: 10)"`
+	SamplePercent float64  `json:"sample_percent,omitempty" jsonschema:"Only search this percentage of each table's pages (TABLESAMPLE SYSTEM), for a quick look at large tables"`
+}
+
+// searchTable is a table to search and its text-like columns.
+type searchTable struct {
+	Schema  string
+	Name    string
+	Columns []string
+}
+
+// SearchData looks for a value in every text, varchar and JSON column of
+// one or more tables, for questions like "where does this email appear".
+// Each match names the columns it was found in.
+func (s *serverState) SearchData(ctx context.Context, req *mcp.CallToolRequest, args SearchDataArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if args.Value == "" {
+		return s.returnErrorResult("value is required")
+	}
+	match := strings.ToLower(args.Match)
+	if match == "" {
+		match = "contains"
+	}
+	if match != "contains" && match != "exact" {
+		return s.returnErrorResult("Unknown match %q, use contains or exact", args.Match)
+	}
+	if args.SamplePercent < 0 || args.SamplePercent > 100 {
+		return s.returnErrorResult("sample_percent must be between 0 and 100")
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	schema := getSchema(args.Schema)
+	tableNames := []string{}
+	for _, table := range args.Tables {
+		if !strings.Contains(table, ".") {
+			table = pgx.Identifier{schema}.Sanitize() + "." + table
+		}
+		name, err := s.resolveQualifiedTable(ctx, table)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.qualifiedAllowed(name) {
+			return s.returnNotAccessible(name)
+		}
+		if !slices.Contains(tableNames, name) {
+			tableNames = append(tableNames, name)
+		}
+	}
+	if len(tableNames) == 0 && !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+
+	tables, err := s.searchTables(ctx, schema, tableNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	var warnings []string
+	if len(tables) > maxSearchTables {
+		warnings = append(warnings, fmt.Sprintf(s.localize("Only the first %d of %d tables were searched, list tables to search the others"), maxSearchTables, len(tables)))
+		tables = tables[:maxSearchTables]
+	}
+
+	// one pattern compared with every column, as text
+	pattern := args.Value
+	operator := "="
+	if match == "contains" {
+		pattern = "%" + likeEscaper.Replace(args.Value) + "%"
+		operator = "LIKE"
+	}
+	if !args.CaseSensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	sample := ""
+	if args.SamplePercent > 0 {
+		sample = fmt.Sprintf(" TABLESAMPLE SYSTEM (%g)", args.SamplePercent)
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	matches := []map[string]interface{}{}
+	var failed []map[string]interface{}
+	policies := make(map[string]columnPolicy)
+	masked := make(map[string][]string)
+	var truncated []string
+	for _, table := range tables {
+		var conditions, labels []string
+		for _, column := range table.Columns {
+			value := quoteIdentifier(column) + "::text"
+			if !args.CaseSensitive {
+				value = "lower(" + value + ")"
+			}
+			condition := fmt.Sprintf("%s %s $1", value, operator)
+			conditions = append(conditions, condition)
+			labels = append(labels, fmt.Sprintf("CASE WHEN %s THEN %s END", condition, quoteLiteral(column)))
+		}
+		query := fmt.Sprintf("SELECT *, array_remove(ARRAY[%s], NULL) AS %s FROM %s%s WHERE %s LIMIT %d",
+			strings.Join(labels, ", "), matchedColumnsKey, qualifiedName(table.Schema, table.Name), sample,
+			strings.Join(conditions, " OR "), limit)
+
+		// a table the role can't read is reported, the search goes on
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin savepoint: %v", err)
+		}
+		rows, err := savepoint.Query(ctx, query, pattern)
+		var found []map[string]interface{}
+		if err == nil {
+			found, err = collectRows(rows)
+		}
+		if err != nil {
+			savepoint.Rollback(ctx)
+			failed = append(failed, map[string]interface{}{"table": table.Schema + "." + table.Name, "error": err.Error()})
+			continue
+		}
+		if err := savepoint.Commit(ctx); err != nil {
+			return nil, nil, fmt.Errorf("failed to release savepoint: %v", err)
+		}
+
+		matchedColumns := make([]interface{}, len(found))
+		for i, row := range found {
+			matchedColumns[i] = row[matchedColumnsKey]
+			delete(row, matchedColumnsKey)
+		}
+		tablePolicies := s.tableColumnPolicies(table.Schema, table.Name, found)
+		binaryTruncated, _ := encodeBinaryRows(found, "base64", s.config.MaxBinaryBytes)
+		truncated = append(truncated, binaryTruncated...)
+		applyColumnPolicies(found, tablePolicies)
+		for column, kinds := range s.redactRows(found) {
+			masked[column] = append(masked[column], kinds...)
+		}
+		encodeNumericRows(found, "string")
+		encodeIntervalRows(found, "iso8601")
+		for column, policy := range tablePolicies {
+			policies[column] = policy
+		}
+
+		for i, row := range found {
+			matches = append(matches, map[string]interface{}{
+				"table":           table.Schema + "." + table.Name,
+				"matched_columns": matchedColumns[i],
+				"row":             row,
+			})
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	response := map[string]interface{}{
+		"value":           args.Value,
+		"match":           match,
+		"tables_searched": len(tables),
+		"matches":         matches,
+	}
+	if len(failed) > 0 {
+		response["failed_tables"] = failed
+	}
+	if args.SamplePercent > 0 {
+		response["sample_percent"] = args.SamplePercent
+	}
+	result, data, err := returnJSONResult(response)
+	warnings = append(append(notices, warnings...), s.binaryWarnings(truncated, nil)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
+
+// searchTables lists the tables of a schema, or the given tables, that have
+// text-like columns, leaving out partitions, which their parents cover, and
+// tables hidden by the allow/deny lists.
+func (s *serverState) searchTables(ctx context.Context, schema string, tables []string) ([]searchTable, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT n.nspname::text, c.relname::text, array_agg(a.attname::text ORDER BY a.attnum)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE c.relkind IN ('r', 'p', 'm')
+			AND CASE WHEN cardinality($2::text[]) > 0
+				THEN n.nspname || '.' || c.relname = ANY($2)
+				ELSE n.nspname = $1 AND NOT c.relispartition END
+			AND (t.typcategory = 'S' OR t.typname IN ('json', 'jsonb'))
+		GROUP BY n.nspname, c.relname
+		ORDER BY n.nspname, c.relname
+	`, schema, tables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %v", err)
+	}
+	defer rows.Close()
+
+	var found []searchTable
+	for rows.Next() {
+		var table searchTable
+		if err := rows.Scan(&table.Schema, &table.Name, &table.Columns); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if s.relationAllowed(table.Schema, table.Name) {
+			found = append(found, table)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return found, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchData(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE searched_accounts (id serial PRIMARY KEY, email text, backup_email varchar(100));
+		CREATE TABLE searched_events (id serial PRIMARY KEY, payload jsonb, amount int);
+		INSERT INTO searched_accounts (email, backup_email) VALUES
+			('Finder@Example.com', NULL), ('other@example.com', 'finder@example.com'), ('nobody@example.com', NULL);
+		INSERT INTO searched_events (payload, amount) VALUES ('{"to": "finder@example.com"}', 1), ('{"to": "x"}', 2);
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE searched_accounts, searched_events")
+
+	args := SearchDataArgs{Value: "finder@example.com", Tables: []string{"searched_accounts", "searched_events"}}
+	result, data, err := testServer.SearchData(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("SearchData failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	matches := data.(map[string]interface{})["matches"].([]map[string]interface{})
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 matches, got %v", matches)
+	}
+	for _, match := range matches {
+		if match["table"] == "public.searched_events" {
+			if columns := match["matched_columns"].([]interface{}); len(columns) != 1 || columns[0] != "payload" {
+				t.Errorf("Expected a match in payload, got %v", columns)
+			}
+		}
+	}
+
+	args = SearchDataArgs{Value: "finder@example.com", Tables: []string{"searched_accounts"}, Match: "exact", CaseSensitive: true}
+	_, data, err = testServer.SearchData(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("SearchData failed: %v", err)
+	}
+	matches = data.(map[string]interface{})["matches"].([]map[string]interface{})
+	if len(matches) != 1 || matches[0]["matched_columns"].([]interface{})[0] != "backup_email" {
+		t.Errorf("Expected only the exact backup_email match, got %v", matches)
+	}
+
+	args = SearchDataArgs{Value: "%", Tables: []string{"searched_accounts"}}
+	_, data, _ = testServer.SearchData(ctx, createMockRequest(args), args)
+	if matches := data.(map[string]interface{})["matches"].([]map[string]interface{}); len(matches) != 0 {
+		t.Errorf("Expected %% to be matched literally, got %v", matches)
+	}
+}
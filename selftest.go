@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type selfTestQuery struct {
+	Tool  string
+	Query string
+}
+
+// selfTestQueries lists the catalog reads the tools depend on that vary
+// between server versions and Postgres flavors. They are only prepared,
+// never executed, so parsing and name resolution are checked without cost.
+func selfTestQueries(versionNum int) []selfTestQuery {
+	queries := []selfTestQuery{
+		{"get_table_schema", "SELECT column_name, data_type, udt_schema, udt_name, character_maximum_length FROM information_schema.columns LIMIT 0"},
+		{"list_tables", "SELECT table_name, table_type FROM information_schema.tables LIMIT 0"},
+		{"get_table_indexes", "SELECT indexname, indexdef FROM pg_indexes LIMIT 0"},
+		{"list_sequences", "SELECT sequencename, data_type, start_value, min_value, max_value, increment_by, cycle, last_value FROM pg_sequences LIMIT 0"},
+		{"export_fixture", "SELECT attidentity, attgenerated FROM pg_attribute LIMIT 0"},
+		{"dump_schema", "SELECT relispartition, pg_get_expr(relpartbound, oid), pg_get_partkeydef(oid) FROM pg_class LIMIT 0"},
+		{"list_materialized_views", "SELECT matviewname, ispopulated, definition FROM pg_matviews LIMIT 0"},
+		{"list_types", "SELECT enumlabel, enumsortorder FROM pg_enum LIMIT 0"},
+		{"list_types", "SELECT rngsubtype FROM pg_range LIMIT 0"},
+		{"get_partitions", "SELECT partstrat, pg_get_partkeydef(partrelid) FROM pg_partitioned_table LIMIT 0"},
+		{"get_partitions", "SELECT relid, parentrelid, level FROM pg_partition_tree('pg_class'::regclass) LIMIT 0"},
+		{"meta_command", "SELECT prokind, pg_get_function_arguments(oid), pg_get_function_result(oid) FROM pg_proc LIMIT 0"},
+		{"meta_command", "SELECT datcollate, pg_encoding_to_char(encoding) FROM pg_database LIMIT 0"},
+		{"explain_analyze", "SELECT tgenabled, tgtype, tgfoid FROM pg_trigger LIMIT 0"},
+		{"explain_analyze", "SELECT ev_type, pg_get_ruledef(oid) FROM pg_rewrite LIMIT 0"},
+		{"get_usage", "SELECT seq_tup_read, idx_tup_fetch FROM pg_stat_xact_user_tables LIMIT 0"},
+		{"set_session_parameter", "SELECT name, setting, unit FROM pg_settings LIMIT 0"},
+		{"get_event_timeline", checkpointQuery(versionNum)},
+		{"get_memory_usage", tempUsageQuery},
+		{"get_memory_usage", "SELECT name, ident, parent, level, total_bytes, used_bytes FROM pg_backend_memory_contexts LIMIT 0"},
+		{"get_connection_info", "SELECT ssl, version FROM pg_stat_ssl LIMIT 0"},
+		{"diff_dataset", "SELECT * FROM jsonb_populate_recordset(NULL::pg_namespace, '[]'::jsonb) LIMIT 0"},
+		{"get_activity_history", "SELECT backend_type, wait_event_type, pg_blocking_pids(pid) FROM pg_stat_activity LIMIT 0"},
+		{"estimate_type_change", "SELECT castsource, casttarget, castmethod, castcontext FROM pg_cast LIMIT 0"},
+		{"estimate_type_change", "SELECT blks_read, blk_read_time FROM pg_stat_database LIMIT 0"},
+		{"vector_index_info", "SELECT amname, reloptions, indclass FROM pg_am, pg_class, pg_index LIMIT 0"},
+		{"spatial_info", "SELECT extversion, extnamespace FROM pg_extension LIMIT 0"},
+		{"logical_replication_info", "SELECT slot_name, active, restart_lsn, confirmed_flush_lsn FROM pg_replication_slots LIMIT 0"},
+		{"logical_replication_info", "SELECT pubname, schemaname, tablename FROM pg_publication_tables LIMIT 0"},
+		{"logical_replication_info", "SELECT subname, subenabled, subslotname, subpublications FROM pg_subscription LIMIT 0"},
+		{"check_plan_regressions", "EXPLAIN (FORMAT JSON) SELECT 1"},
+		{"suggest_indexes", "SELECT seq_scan, seq_tup_read, idx_scan, n_live_tup, n_tup_upd, n_tup_del FROM pg_stat_user_tables LIMIT 0"},
+		{"suggest_indexes", "SELECT conkey, confrelid, indkey, indnkeyatts, indpred FROM pg_constraint, pg_index LIMIT 0"},
+	}
+	for _, source := range timelineSources {
+		queries = append(queries, selfTestQuery{"get_event_timeline", source.Query})
+	}
+	for _, view := range []string{"pg_stat_progress_vacuum", "pg_stat_progress_analyze", "pg_stat_progress_create_index", "pg_stat_progress_cluster", "pg_stat_progress_copy"} {
+		queries = append(queries, selfTestQuery{"explain_analyze", "SELECT pid, relid FROM " + view + " LIMIT 0"})
+	}
+	return queries
+}
+
+type selfTestFailure struct {
+	Tool  string `json:"tool"`
+	Query string `json:"query"`
+	Error string `json:"error"`
+}
+
+// runSelfTest prepares every self-test query on one connection and returns
+// those the connected server rejects.
+func (s *serverState) runSelfTest(ctx context.Context) ([]selfTestFailure, error) {
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server version: %v", err)
+	}
+
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	var failures []selfTestFailure
+	for _, query := range selfTestQueries(versionNum) {
+		// the unnamed statement is replaced by the next prepare, nothing to clean up
+		if _, err := conn.Conn().Prepare(ctx, "", query.Query); err != nil {
+			failures = append(failures, selfTestFailure{
+				Tool:  query.Tool,
+				Query: strings.Join(strings.Fields(query.Query), " "),
+				Error: err.Error(),
+			})
+		}
+	}
+	return failures, nil
+}
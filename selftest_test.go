@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	ctx := context.Background()
+
+	failures, err := testServer.runSelfTest(ctx)
+	if err != nil {
+		t.Fatalf("runSelfTest failed: %v", err)
+	}
+
+	for _, failure := range failures {
+		t.Errorf("Expected %s to be supported by the test server: %s (%s)", failure.Tool, failure.Error, failure.Query)
+	}
+
+	// the test server is PostgreSQL 17, where the old checkpoint counters are gone
+	conn, err := testServer.pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Conn().Prepare(ctx, "", checkpointQuery(160000)); err == nil {
+		t.Error("Expected the pre-17 checkpoint query to be rejected by the test server")
+	}
+}
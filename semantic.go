@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// semanticTypes are what a column policy can say a column holds. Emails,
+// phone numbers and secrets are masked unless the policy says otherwise.
+var semanticTypes = map[string]bool{
+	"email":      true,
+	"phone":      true,
+	"money":      true,
+	"timestamp":  true,
+	"free_text":  true,
+	"secret":     true,
+	"identifier": true,
+}
+
+const defaultMoneyDecimals = 2
+
+// columnPolicy describes one column of COLUMN_POLICY_FILE, given either as
+// its semantic type alone ("email") or as an object with the type's options.
+type columnPolicy struct {
+	Type        string `json:"type"`
+	Mask        *bool  `json:"mask,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	// money: the currency appended to amounts, the decimals shown and
+	// whether amounts are stored in minor units (cents)
+	Currency   string `json:"currency,omitempty"`
+	Decimals   *int   `json:"decimals,omitempty"`
+	MinorUnits bool   `json:"minor_units,omitempty"`
+
+	// timestamp: the unit of columns holding epoch numbers, s or ms
+	EpochUnit string `json:"epoch_unit,omitempty"`
+}
+
+func (p *columnPolicy) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		*p = columnPolicy{}
+		return json.Unmarshal(data, &p.Type)
+	}
+	type plain columnPolicy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode((*plain)(p))
+}
+
+func (p columnPolicy) validate() error {
+	if !semanticTypes[p.Type] {
+		return fmt.Errorf("unknown semantic type %q, expected one of %s", p.Type, strings.Join(sortedKeys(semanticTypes), ", "))
+	}
+	if p.Type == "secret" && p.Mask != nil && !*p.Mask {
+		return fmt.Errorf("secret columns are always masked")
+	}
+	if p.Decimals != nil && (*p.Decimals < 0 || *p.Decimals > 20) {
+		return fmt.Errorf("decimals must be between 0 and 20")
+	}
+	if p.EpochUnit != "" && p.EpochUnit != "s" && p.EpochUnit != "ms" {
+		return fmt.Errorf("epoch_unit must be s or ms")
+	}
+	return nil
+}
+
+// masked reports whether values are hidden rather than shown.
+func (p columnPolicy) masked() bool {
+	if p.Mask != nil {
+		return *p.Mask
+	}
+	return p.Type == "email" || p.Type == "phone" || p.Type == "secret"
+}
+
+// sensitive columns are rewritten when data leaves the database, masked or not.
+func (p columnPolicy) sensitive() bool {
+	return p.masked() || p.Type == "free_text"
+}
+
+// columnPolicies maps schemas to table.column patterns (path.Match syntax in
+// both parts) and their policies.
+type columnPolicies map[string]map[string]columnPolicy
+
+func loadColumnPolicies(path string) (columnPolicies, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies columnPolicies
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policies); err != nil {
+		return nil, err
+	}
+	for schema, columns := range policies {
+		for pattern, policy := range columns {
+			if !strings.Contains(pattern, ".") {
+				return nil, fmt.Errorf("%s: %q is not a table.column pattern", schema, pattern)
+			}
+			if err := policy.validate(); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", schema, pattern, err)
+			}
+		}
+	}
+	return policies, nil
+}
+
+// lookup finds the policy of a column. Exact names win over patterns, which
+// are tried in sorted order.
+func (c columnPolicies) lookup(schema, table, column string) (columnPolicy, bool) {
+	if policy, ok := c[schema][table+"."+column]; ok {
+		return policy, true
+	}
+	for _, schemaPattern := range sortedKeys(c) {
+		if !matchesName([]string{schemaPattern}, schema) {
+			continue
+		}
+		for _, pattern := range sortedKeys(c[schemaPattern]) {
+			i := strings.LastIndex(pattern, ".")
+			if matchesName([]string{pattern[:i]}, table) && matchesName([]string{pattern[i+1:]}, column) {
+				return c[schemaPattern][pattern], true
+			}
+		}
+	}
+	return columnPolicy{}, false
+}
+
+// maskEmail keeps the first character and the domain: j***@example.com.
+func maskEmail(email string) string {
+	i := strings.LastIndex(email, "@")
+	if i <= 0 {
+		return "[REDACTED email]"
+	}
+	first := []rune(email[:i])[0]
+	return string(first) + "***" + email[i:]
+}
+
+// maskPhone keeps the last four digits: ***4567.
+func maskPhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 7 {
+		return "[REDACTED phone]"
+	}
+	return "***" + string(digits[len(digits)-4:])
+}
+
+// moneyAmount reads the numeric values a money column can hold exactly.
+func moneyAmount(value interface{}) (*big.Rat, bool) {
+	switch v := value.(type) {
+	case pgtype.Numeric:
+		if !v.Valid || v.NaN || v.InfinityModifier != pgtype.Finite || v.Int == nil {
+			return nil, false
+		}
+		amount := new(big.Rat).SetInt(v.Int)
+		if v.Exp < 0 {
+			return amount.Quo(amount, new(big.Rat).SetInt(powerOfTen(-v.Exp))), true
+		}
+		return amount.Mul(amount, new(big.Rat).SetInt(powerOfTen(v.Exp))), true
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int16:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case float64:
+		amount := new(big.Rat).SetFloat64(v)
+		return amount, amount != nil
+	case float32:
+		amount := new(big.Rat).SetFloat64(float64(v))
+		return amount, amount != nil
+	}
+	return nil, false
+}
+
+func powerOfTen(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// formatMoney shows an amount with fixed decimals and its currency. Values
+// of the money type already come formatted by the server and are kept.
+func (p columnPolicy) formatMoney(value interface{}) interface{} {
+	amount, ok := moneyAmount(value)
+	if !ok {
+		return value
+	}
+	decimals := defaultMoneyDecimals
+	if p.Decimals != nil {
+		decimals = *p.Decimals
+	}
+	if p.MinorUnits {
+		amount.Quo(amount, new(big.Rat).SetInt(powerOfTen(int32(decimals))))
+	}
+	text := amount.FloatString(decimals)
+	if p.Currency != "" {
+		text += " " + p.Currency
+	}
+	return text
+}
+
+// formatTimestamp shows times, and epoch numbers, as RFC 3339 in UTC.
+func (p columnPolicy) formatTimestamp(value interface{}) interface{} {
+	var seconds float64
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case int64:
+		seconds = float64(v)
+	case int32:
+		seconds = float64(v)
+	case float64:
+		seconds = v
+	default:
+		return value
+	}
+	if p.EpochUnit == "ms" {
+		return time.UnixMilli(int64(seconds)).UTC().Format(time.RFC3339Nano)
+	}
+	whole := int64(seconds)
+	return time.Unix(whole, int64((seconds-float64(whole))*1e9)).UTC().Format(time.RFC3339Nano)
+}
+
+// apply masks or formats one value of the column.
+func (p columnPolicy) apply(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if p.masked() {
+		switch p.Type {
+		case "email":
+			return maskEmail(expandedValue(value))
+		case "phone":
+			return maskPhone(expandedValue(value))
+		}
+		return "[REDACTED " + p.Type + "]"
+	}
+	switch p.Type {
+	case "money":
+		return p.formatMoney(value)
+	case "timestamp":
+		return p.formatTimestamp(value)
+	case "free_text":
+		// free text is scanned for PII whether REDACT_PII is on or not
+		redacted, _ := redactValue(value)
+		return redacted
+	}
+	return value
+}
+
+// label names what a policy did to a column, for warnings.
+func (p columnPolicy) label() string {
+	if p.masked() {
+		return p.Type + ", masked"
+	}
+	return p.Type
+}
+
+// resultColumnPolicies finds the policies of the result columns read straight
+// from a table column. Computed columns have no table and get no policy.
+func (s *serverState) resultColumnPolicies(ctx context.Context, resolver relationResolver, fields []pgconn.FieldDescription) (map[string]columnPolicy, error) {
+	if len(s.config.ColumnPolicies) == 0 {
+		return nil, nil
+	}
+	var tables []uint32
+	for _, field := range fields {
+		if field.TableOID != 0 {
+			tables = append(tables, field.TableOID)
+		}
+	}
+	if len(tables) == 0 {
+		return nil, nil
+	}
+
+	rows, err := resolver.Query(ctx, `
+		SELECT a.attrelid, a.attnum, n.nspname::text, c.relname::text, a.attname::text
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE a.attrelid = ANY($1) AND a.attnum > 0
+	`, tables)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type attribute struct {
+		table  uint32
+		number int16
+	}
+	found := make(map[attribute]columnPolicy)
+	for rows.Next() {
+		var attr attribute
+		var schema, table, column string
+		if err := rows.Scan(&attr.table, &attr.number, &schema, &table, &column); err != nil {
+			return nil, err
+		}
+		if policy, ok := s.config.ColumnPolicies.lookup(schema, table, column); ok {
+			found[attr] = policy
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]columnPolicy)
+	for _, field := range fields {
+		if policy, ok := found[attribute{field.TableOID, int16(field.TableAttributeNumber)}]; ok {
+			policies[field.Name] = policy
+		}
+	}
+	return policies, nil
+}
+
+// tableColumnPolicies is resultColumnPolicies for rows of a known table.
+func (s *serverState) tableColumnPolicies(schema, table string, rows []map[string]interface{}) map[string]columnPolicy {
+	policies := make(map[string]columnPolicy)
+	for _, row := range rows {
+		for column := range row {
+			if policy, ok := s.config.ColumnPolicies.lookup(schema, table, column); ok {
+				policies[column] = policy
+			}
+		}
+	}
+	return policies
+}
+
+// applyColumnPolicies masks and formats result rows in place, before PII
+// redaction sees them.
+func applyColumnPolicies(rows []map[string]interface{}, policies map[string]columnPolicy) {
+	if len(policies) == 0 {
+		return
+	}
+	for _, row := range rows {
+		for column, policy := range policies {
+			if value, ok := row[column]; ok {
+				row[column] = policy.apply(value)
+			}
+		}
+	}
+}
+
+// columnPolicyWarnings reports the columns shaped by COLUMN_POLICY_FILE.
+func (s *serverState) columnPolicyWarnings(policies map[string]columnPolicy) []string {
+	if len(policies) == 0 {
+		return nil
+	}
+	var columns []string
+	for column, policy := range policies {
+		columns = append(columns, fmt.Sprintf("%s (%s)", column, policy.label()))
+	}
+	sort.Strings(columns)
+	return []string{fmt.Sprintf(s.localize("COLUMN_POLICY_FILE masked or formatted: %s"), strings.Join(columns, "; "))}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestLoadColumnPolicies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "columns.json")
+		os.WriteFile(path, []byte(content), 0o600)
+		return path
+	}
+
+	policies, err := loadColumnPolicies(write(`{
+		"public": {
+			"users.email": "email",
+			"orders.total_cents": {"type": "money", "currency": "EUR", "minor_units": true},
+			"*.notes": {"type": "free_text", "description": "Support notes"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("loadColumnPolicies failed: %v", err)
+	}
+	if policy, ok := policies.lookup("public", "users", "email"); !ok || policy.Type != "email" || !policy.masked() {
+		t.Errorf("Expected users.email to be a masked email, got %+v", policy)
+	}
+	if policy, ok := policies.lookup("public", "tickets", "notes"); !ok || policy.Description != "Support notes" {
+		t.Errorf("Expected the pattern to match tickets.notes, got %+v", policy)
+	}
+	if _, ok := policies.lookup("sales", "users", "email"); ok {
+		t.Error("Expected policies to apply to their own schema only")
+	}
+
+	for _, invalid := range []string{
+		`{"public": {"users.email": "e-mail"}}`,
+		`{"public": {"email": "email"}}`,
+		`{"public": {"users.token": {"type": "secret", "mask": false}}}`,
+		`{"public": {"users.email": {"type": "email", "masked": true}}}`,
+	} {
+		if _, err := loadColumnPolicies(write(invalid)); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestColumnPolicyApply(t *testing.T) {
+	unmasked := false
+	decimals := 3
+	tests := []struct {
+		policy   columnPolicy
+		value    interface{}
+		expected interface{}
+	}{
+		{columnPolicy{Type: "email"}, "jane.doe@example.com", "j***@example.com"},
+		{columnPolicy{Type: "email", Mask: &unmasked}, "jane.doe@example.com", "jane.doe@example.com"},
+		{columnPolicy{Type: "phone"}, "+1 (555) 123-4567", "***4567"},
+		{columnPolicy{Type: "secret"}, "hunter2", "[REDACTED secret]"},
+		{columnPolicy{Type: "money", Currency: "USD"}, pgtype.Numeric{Int: big.NewInt(12345), Exp: -1, Valid: true}, "1234.50 USD"},
+		{columnPolicy{Type: "money", MinorUnits: true}, int64(1999), "19.99"},
+		{columnPolicy{Type: "money", Decimals: &decimals}, 2.5, "2.500"},
+		{columnPolicy{Type: "timestamp"}, time.Date(2024, 5, 1, 16, 30, 0, 0, time.FixedZone("CEST", 2*3600)), "2024-05-01T14:30:00Z"},
+		{columnPolicy{Type: "timestamp", EpochUnit: "ms"}, int64(1714573800000), "2024-05-01T14:30:00Z"},
+		{columnPolicy{Type: "identifier"}, int32(7), int32(7)},
+		{columnPolicy{Type: "email"}, nil, nil},
+	}
+	for _, test := range tests {
+		if got := test.policy.apply(test.value); got != test.expected {
+			t.Errorf("%s policy on %v: expected %v, got %v", test.policy.Type, test.value, test.expected, got)
+		}
+	}
+
+	if got := (columnPolicy{Type: "free_text"}).apply("call me at 555-123-4567").(string); !strings.Contains(got, "[REDACTED phone]") {
+		t.Errorf("Expected free text to be scanned for PII, got %q", got)
+	}
+}
+
+func TestQueryColumnPolicies(t *testing.T) {
+	ctx := context.Background()
+	saved := testServer.config.ColumnPolicies
+	defer func() { testServer.config.ColumnPolicies = saved }()
+	testServer.config.ColumnPolicies = columnPolicies{"public": {"users.email": {Type: "email"}}}
+
+	args := QueryArgs{Query: "SELECT email, lower(email) AS computed FROM users ORDER BY id LIMIT 1"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	row := data.([]map[string]interface{})[0]
+	if email := row["email"].(string); !strings.Contains(email, "***@") {
+		t.Errorf("Expected the email column to be masked, got %q", email)
+	}
+	if computed := row["computed"].(string); strings.Contains(computed, "***") {
+		t.Errorf("Expected computed columns to be left alone, got %q", computed)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxRecordedOutputBytes = 64 << 10
+	// maxSessionEvents bounds a session's transcript, older calls only
+	// stay in its usage totals
+	maxSessionEvents = 1000
+	// sessionIdleTTL is how long an HTTP session's log outlives its last call
+	sessionIdleTTL = 24 * time.Hour
+)
+
+// toolCategories groups tools for the session transcript. Anything not
+// listed here is treated as a change, so its output is kept in full rather
+// than a new writing tool passing as a metadata lookup.
+var toolCategories = map[string]string{
+	"query":       "query",
+	"batch_query": "query",
+	"search_data": "query",
+
+	"explain_analyze":        "plan",
+	"compare_plans":          "plan",
+	"save_plan_baseline":     "plan",
+	"check_plan_regression":  "plan",
+	"check_plan_regressions": "plan",
+
+	"execute_write":             "change",
+	"update_rows":               "change",
+	"delete_rows":               "change",
+	"backup_table":              "change",
+	"restore_table":             "change",
+	"anonymize_table":           "change",
+	"clone_database":            "change",
+	"drop_clone":                "change",
+	"set_comment":               "change",
+	"refresh_materialized_view": "change",
+	"run_analyze":               "change",
+	"run_vacuum":                "change",
+	"run_reindex":               "change",
+	"approve_change":            "change",
+	"reject_change":             "change",
+	"demonstrate_anomaly":       "change",
+	"set_session_parameter":     "change",
+
+	"get_table_schema":         "metadata",
+	"list_tables":              "metadata",
+	"get_table_constraints":    "metadata",
+	"get_table_indexes":        "metadata",
+	"estimate_row_count":       "metadata",
+	"traverse_hierarchy":       "metadata",
+	"find_row_path":            "metadata",
+	"list_sequences":           "metadata",
+	"infer_joins":              "metadata",
+	"export_fixture":           "metadata",
+	"list_materialized_views":  "metadata",
+	"view_dependencies":        "metadata",
+	"list_types":               "metadata",
+	"get_partitions":           "metadata",
+	"export_session":           "metadata",
+	"list_pending_changes":     "metadata",
+	"get_usage":                "metadata",
+	"pool_stats":               "metadata",
+	"meta_command":             "metadata",
+	"get_event_timeline":       "metadata",
+	"get_memory_usage":         "metadata",
+	"verify_installation":      "metadata",
+	"get_connection_info":      "metadata",
+	"diff_dataset":             "metadata",
+	"get_activity_history":     "metadata",
+	"estimate_type_change":     "metadata",
+	"vector_index_info":        "metadata",
+	"spatial_info":             "metadata",
+	"logical_replication_info": "metadata",
+	"suggest_indexes":          "metadata",
+	"recent_slow_queries":      "metadata",
+	"normalize_query":          "metadata",
+	"dump_schema":              "metadata",
+	"describe_table":           "metadata",
+	"get_dependencies":         "metadata",
+	"validate_constraints":     "metadata",
+	"run_data_checks":          "metadata",
+	"column_cardinality":       "metadata",
+	"column_distribution":      "metadata",
+	"storage_info":             "metadata",
+	"list_tablespaces":         "metadata",
+	"list_foreign_tables":      "metadata",
+	"list_event_triggers":      "metadata",
+	"list_rules":               "metadata",
+	"recent_errors":            "metadata",
+}
+
+// sessionEvent is a single tool call recorded for the session transcript.
+type sessionEvent struct {
+	Time       time.Time       `json:"time"`
+	Tool       string          `json:"tool"`
+	Category   string          `json:"category"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+	IsError    bool            `json:"is_error"`
+	Summary    string          `json:"summary"`
+	Output     string          `json:"output,omitempty"`
+
+	RowsScanned   int64 `json:"rows_scanned"`
+	BytesReturned int64 `json:"bytes_returned"`
+}
+
+type sessionLog struct {
+	mu         sync.Mutex
+	started    time.Time
+	lastActive time.Time
+	events     []sessionEvent
+	// dropped counts the events evicted past maxSessionEvents, whose usage
+	// is kept in droppedUsage and droppedByTool
+	dropped       int
+	droppedUsage  usageTotals
+	droppedByTool map[string]*usageTotals
+}
+
+// sessionLogs are the logs of the MCP sessions a server has seen. Profiles
+// and clones share the default state's, since a session's calls span them.
+type sessionLogs struct {
+	mu     sync.Mutex
+	logs   map[string]*sessionLog
+	pruned time.Time
+}
+
+func newSessionLogs() *sessionLogs {
+	return &sessionLogs{logs: make(map[string]*sessionLog)}
+}
+
+// sessionKey identifies the MCP session a request belongs to. Stdio has a
+// single unnamed session, as do direct handler calls from tests.
+func sessionKey(req *mcp.CallToolRequest) string {
+	if req == nil || req.Session == nil {
+		return ""
+	}
+	return req.Session.ID()
+}
+
+func (l *sessionLogs) get(key string) *sessionLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if time.Since(l.pruned) > time.Minute {
+		l.prune(time.Now())
+	}
+	history, ok := l.logs[key]
+	if !ok {
+		history = &sessionLog{started: time.Now(), lastActive: time.Now()}
+		l.logs[key] = history
+	}
+	return history
+}
+
+// prune drops the logs of HTTP sessions idle for longer than
+// sessionIdleTTL, which have most likely disconnected. The stdio session is
+// kept. Callers hold l.mu.
+func (l *sessionLogs) prune(now time.Time) {
+	l.pruned = now
+	for key, history := range l.logs {
+		history.mu.Lock()
+		idle := now.Sub(history.lastActive)
+		history.mu.Unlock()
+		if key != "" && idle > sessionIdleTTL {
+			delete(l.logs, key)
+		}
+	}
+}
+
+func (l *sessionLog) record(event sessionEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastActive = time.Now()
+	l.events = append(l.events, event)
+	if len(l.events) > maxSessionEvents {
+		oldest := l.events[0]
+		l.events = slices.Delete(l.events, 0, 1)
+		l.dropped++
+		l.droppedUsage.add(oldest)
+		if l.droppedByTool == nil {
+			l.droppedByTool = make(map[string]*usageTotals)
+		}
+		if l.droppedByTool[oldest.Tool] == nil {
+			l.droppedByTool[oldest.Tool] = &usageTotals{}
+		}
+		l.droppedByTool[oldest.Tool].add(oldest)
+	}
+}
+
+func (l *sessionLog) snapshot() []sessionEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]sessionEvent{}, l.events...)
+}
+
+// droppedEvents returns how many of the oldest events were evicted.
+func (l *sessionLog) droppedEvents() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.dropped
+}
+
+// summarizeResult describes a tool result in one line: row counts for
+// result sets, top-level keys for objects, and the message for errors.
+func summarizeResult(result *mcp.CallToolResult) string {
+	if result == nil {
+		return "no result"
+	}
+
+	text := ""
+	for _, content := range result.Content {
+		if textContent, ok := content.(*mcp.TextContent); ok {
+			text += textContent.Text
+		}
+	}
+
+	if result.IsError {
+		return "error: " + text
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		return fmt.Sprintf("%d bytes of text", len(text))
+	}
+
+	switch value := decoded.(type) {
+	case []interface{}:
+		if len(value) == 0 {
+			return "0 rows"
+		}
+		if row, ok := value[0].(map[string]interface{}); ok {
+			return fmt.Sprintf("%d rows, columns: %s", len(value), strings.Join(sortedKeys(row), ", "))
+		}
+		return fmt.Sprintf("%d items", len(value))
+	case map[string]interface{}:
+		return "fields: " + strings.Join(sortedKeys(value), ", ")
+	case nil:
+		return "0 rows"
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sessionMiddleware records every tool call into the calling session's log.
+func (s *serverState) sessionMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		callReq, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok || callReq.Params.Name == "export_session" {
+			return next(ctx, method, req)
+		}
+
+		ctx, stats := withCallStats(ctx)
+		start := time.Now()
+		result, err := next(ctx, method, req)
+
+		category, ok := toolCategories[callReq.Params.Name]
+		if !ok {
+			category = "change"
+		}
+		event := sessionEvent{
+			Time:       start,
+			Tool:       callReq.Params.Name,
+			Category:   category,
+			Arguments:  callReq.Params.Arguments,
+			DurationMs: time.Since(start).Milliseconds(),
+
+			RowsScanned: stats.rowsScanned,
+		}
+
+		toolResult, _ := result.(*mcp.CallToolResult)
+		if toolResult != nil {
+			for _, content := range toolResult.Content {
+				if textContent, ok := content.(*mcp.TextContent); ok {
+					event.BytesReturned += int64(len(textContent.Text))
+				}
+			}
+		}
+		switch {
+		case err != nil:
+			event.IsError = true
+			event.Summary = "error: " + err.Error()
+		default:
+			event.IsError = toolResult != nil && toolResult.IsError
+			event.Summary = summarizeResult(toolResult)
+		}
+
+		// plans and changes are kept in full, query results only as summaries
+		if (category == "plan" || category == "change") && toolResult != nil {
+			for _, content := range toolResult.Content {
+				if textContent, ok := content.(*mcp.TextContent); ok {
+					event.Output += textContent.Text
+				}
+			}
+			if len(event.Output) > maxRecordedOutputBytes {
+				event.Output = event.Output[:maxRecordedOutputBytes] + "\n... (truncated)"
+			}
+		}
+
+		s.sessions.get(sessionKey(callReq)).record(event)
+		s.writeAudit(sessionKey(callReq), event)
+		return result, err
+	}
+}
+
+type ExportSessionArgs struct {
+	Format     string `json:"format,omitempty" jsonschema:"Output format: markdown or json (default: markdown)"`
+	OutputPath string `json:"output_path,omitempty" jsonschema:"Write the transcript to this file in EXPORT_DIR, relative to it, instead of returning it inline"`
+}
+
+func renderSessionMarkdown(started time.Time, events []sessionEvent, dropped int) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# postgres-mcp session transcript\n\n")
+	fmt.Fprintf(&out, "- Started: %s\n", started.Format(time.RFC3339))
+	fmt.Fprintf(&out, "- Exported: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&out, "- Tool calls: %d\n", len(events))
+	if dropped > 0 {
+		fmt.Fprintf(&out, "- Earlier tool calls not kept: %d\n", dropped)
+	}
+
+	counts := make(map[string]int)
+	for _, event := range events {
+		counts[event.Category]++
+	}
+	for _, category := range []string{"query", "plan", "change", "metadata"} {
+		fmt.Fprintf(&out, "- %s: %d\n", category, counts[category])
+	}
+
+	for i, event := range events {
+		status := "ok"
+		if event.IsError {
+			status = "error"
+		}
+		fmt.Fprintf(&out, "\n## %d. %s (%s, %s)\n\n", i+1, event.Tool, event.Category, status)
+		fmt.Fprintf(&out, "- Time: %s\n- Duration: %d ms\n- Result: %s\n", event.Time.Format(time.RFC3339), event.DurationMs, event.Summary)
+		if len(event.Arguments) > 0 {
+			fmt.Fprintf(&out, "\nArguments:\n\n```json\n%s\n```\n", event.Arguments)
+		}
+		if event.Output != "" {
+			fmt.Fprintf(&out, "\nOutput:\n\n```\n%s\n```\n", event.Output)
+		}
+	}
+	return out.String()
+}
+
+func (s *serverState) ExportSession(ctx context.Context, req *mcp.CallToolRequest, args ExportSessionArgs) (*mcp.CallToolResult, any, error) {
+	format := args.Format
+	if format == "" {
+		format = "markdown"
+	}
+	if format != "markdown" && format != "json" {
+		return s.returnErrorResult("Unsupported format %q, use markdown or json", format)
+	}
+
+	history := s.sessions.get(sessionKey(req))
+	events := history.snapshot()
+	dropped := history.droppedEvents()
+
+	var artifact string
+	if format == "json" {
+		transcript := map[string]interface{}{
+			"started":  history.started,
+			"exported": time.Now(),
+			"events":   events,
+		}
+		if dropped > 0 {
+			transcript["dropped_events"] = dropped
+		}
+		jsonData, err := json.MarshalIndent(transcript, "", "  ")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal transcript: %v", err)
+		}
+		artifact = string(jsonData)
+	} else {
+		artifact = renderSessionMarkdown(history.started, events, dropped)
+	}
+
+	if args.OutputPath != "" {
+		path, err := s.exportPath(args.OutputPath)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		encrypted, err := s.writeArtifact(path, []byte(artifact))
+		if err != nil {
+			return s.returnErrorResult("Failed to write transcript: %v", err)
+		}
+		return returnJSONResult(map[string]interface{}{
+			"output_path": args.OutputPath,
+			"format":      format,
+			"tool_calls":  len(events),
+			"bytes":       len(artifact),
+			"encrypted":   encrypted,
+		})
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: artifact},
+		},
+	}, artifact, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	ctx := context.Background()
+
+	testServer.sessions.mu.Lock()
+	delete(testServer.sessions.logs, "")
+	testServer.sessions.mu.Unlock()
+
+	next := func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: `[{"id": 1, "username": "a"}, {"id": 2, "username": "b"}]`},
+			},
+		}, nil
+	}
+	handler := testServer.sessionMiddleware(next)
+
+	req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{
+		Name:      "query",
+		Arguments: json.RawMessage(`{"query": "SELECT id, username FROM users LIMIT 2"}`),
+	}}
+	if _, err := handler(ctx, "tools/call", req); err != nil {
+		t.Fatalf("Middleware returned error: %v", err)
+	}
+
+	events := testServer.sessions.get("").snapshot()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 recorded event, got %d", len(events))
+	}
+
+	if events[0].Category != "query" || events[0].Summary != "2 rows, columns: id, username" {
+		t.Errorf("Unexpected event: %+v", events[0])
+	}
+
+	t.Run("export as json", func(t *testing.T) {
+		args := ExportSessionArgs{Format: "json"}
+		result, data, err := testServer.ExportSession(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportSession failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		var transcript map[string]interface{}
+		if err := json.Unmarshal([]byte(data.(string)), &transcript); err != nil {
+			t.Fatalf("Expected valid JSON transcript: %v", err)
+		}
+
+		if len(transcript["events"].([]interface{})) != 1 {
+			t.Errorf("Expected 1 event in transcript, got %v", transcript["events"])
+		}
+	})
+
+	t.Run("export as markdown", func(t *testing.T) {
+		args := ExportSessionArgs{}
+		_, data, err := testServer.ExportSession(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExportSession failed: %v", err)
+		}
+
+		if !strings.Contains(data.(string), "SELECT id, username FROM users LIMIT 2") {
+			t.Error("Expected markdown transcript to include the query")
+		}
+	})
+
+	t.Run("export to a file in EXPORT_DIR", func(t *testing.T) {
+		savedConfig := testServer.config
+		defer func() { testServer.config = savedConfig }()
+		testServer.config.ExportDir = t.TempDir()
+
+		args := ExportSessionArgs{OutputPath: "../session.md"}
+		if result, _, err := testServer.ExportSession(ctx, createMockRequest(args), args); err != nil || !result.IsError {
+			t.Error("Expected an output_path leaving EXPORT_DIR to be refused")
+		}
+
+		args.OutputPath = "session.md"
+		if result, _, err := testServer.ExportSession(ctx, createMockRequest(args), args); err != nil || result.IsError {
+			t.Fatalf("ExportSession failed: %v %v", err, result)
+		}
+		contents, err := os.ReadFile(filepath.Join(testServer.config.ExportDir, "session.md"))
+		if err != nil || !strings.Contains(string(contents), "SELECT id, username FROM users LIMIT 2") {
+			t.Errorf("Expected the transcript in EXPORT_DIR, got %q, %v", contents, err)
+		}
+	})
+}
+
+func TestSessionLogBounds(t *testing.T) {
+	history := &sessionLog{started: time.Now()}
+	for i := 0; i < maxSessionEvents+10; i++ {
+		history.record(sessionEvent{Tool: "query", DurationMs: 1})
+	}
+	if events := history.snapshot(); len(events) != maxSessionEvents || history.droppedEvents() != 10 {
+		t.Errorf("Expected %d events and 10 dropped, got %d and %d", maxSessionEvents, len(events), history.droppedEvents())
+	}
+	if totals, byTool := history.usage(); totals.Calls != maxSessionEvents+10 || byTool["query"].DurationMs != maxSessionEvents+10 {
+		t.Errorf("Expected usage to count dropped calls, got %+v", totals)
+	}
+
+	sessions := newSessionLogs()
+	idle := &sessionLog{lastActive: time.Now().Add(-sessionIdleTTL - time.Minute)}
+	sessions.logs["idle-session"] = idle
+	sessions.logs["active-session"] = history
+	sessions.prune(time.Now())
+	if _, ok := sessions.logs["idle-session"]; ok {
+		t.Error("Expected the idle session to be pruned")
+	}
+	if _, ok := sessions.logs["active-session"]; !ok {
+		t.Error("Expected the active session to be kept")
+	}
+}
+
+func TestToolCategoriesRegistered(t *testing.T) {
+	tools, err := (&serverState{}).listTools(context.Background())
+	if err != nil {
+		t.Fatalf("listTools failed: %v", err)
+	}
+	for _, tool := range tools {
+		if _, ok := toolCategories[tool.Name]; !ok {
+			t.Errorf("%s has no category in toolCategories", tool.Name)
+		}
+	}
+	for _, name := range []string{"execute_write", "update_rows", "delete_rows", "restore_table", "anonymize_table", "clone_database", "drop_clone", "set_comment", "run_analyze"} {
+		if toolCategories[name] != "change" {
+			t.Errorf("Expected %s to be a change, got %q", name, toolCategories[name])
+		}
+	}
+}
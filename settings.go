@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionParameterNames are the settings a session may change. They only
+// affect planning and resource limits of its own statements.
+var sessionParameterNames = map[string]bool{
+	"work_mem":                        true,
+	"statement_timeout":               true,
+	"lock_timeout":                    true,
+	"random_page_cost":                true,
+	"seq_page_cost":                   true,
+	"cpu_tuple_cost":                  true,
+	"effective_cache_size":            true,
+	"enable_seqscan":                  true,
+	"enable_indexscan":                true,
+	"enable_indexonlyscan":            true,
+	"enable_bitmapscan":               true,
+	"enable_hashjoin":                 true,
+	"enable_mergejoin":                true,
+	"enable_nestloop":                 true,
+	"enable_hashagg":                  true,
+	"enable_sort":                     true,
+	"enable_partitionwise_join":       true,
+	"enable_partitionwise_aggregate":  true,
+	"join_collapse_limit":             true,
+	"from_collapse_limit":             true,
+	"max_parallel_workers_per_gather": true,
+	"jit":                             true,
+	"plan_cache_mode":                 true,
+
+	// pgvector search settings, see vector_index_info
+	"ivfflat.probes":         true,
+	"ivfflat.iterative_scan": true,
+	"hnsw.ef_search":         true,
+	"hnsw.iterative_scan":    true,
+}
+
+// sessionParameters are the set_session_parameter values of each MCP
+// session, shared by profiles and clones like sessionLogs.
+type sessionParameters struct {
+	mu     sync.Mutex
+	values map[string]map[string]string
+}
+
+func newSessionParameters() *sessionParameters {
+	return &sessionParameters{values: make(map[string]map[string]string)}
+}
+
+func (p *sessionParameters) get(key string) map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	values := make(map[string]string)
+	for name, value := range p.values[key] {
+		values[name] = value
+	}
+	return values
+}
+
+func (p *sessionParameters) set(key, name, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.values[key] == nil {
+		p.values[key] = make(map[string]string)
+	}
+	p.values[key][name] = value
+}
+
+func (p *sessionParameters) reset(key, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if name == "" {
+		delete(p.values, key)
+		return
+	}
+	delete(p.values[key], name)
+}
+
+// applySessionParameters sets the session's parameters locally in tx, so
+// they end with the transaction and never leak to other pool users.
+func (s *serverState) applySessionParameters(ctx context.Context, req *mcp.CallToolRequest, tx pgx.Tx) error {
+	values := s.parameters.get(sessionKey(req))
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := tx.Exec(ctx, "SELECT set_config($1, $2, true)", name, values[name]); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+type SetSessionParameterArgs struct {
+	Name  string `json:"name,omitempty" jsonschema:"Parameter to set, such as work_mem, enable_seqscan or statement_timeout"`
+	Value string `json:"value,omitempty" jsonschema:"Value to set, in any form SET accepts (e.g. 64MB, off, 5s)"`
+	Reset bool   `json:"reset,omitempty" jsonschema:"Reset the parameter to the server default, or every parameter when name is empty (default: false)"`
+}
+
+func (s *serverState) SetSessionParameter(ctx context.Context, req *mcp.CallToolRequest, args SetSessionParameterArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	key := sessionKey(req)
+	name := strings.ToLower(strings.TrimSpace(args.Name))
+	if name != "" && !sessionParameterNames[name] {
+		allowed := make([]string, 0, len(sessionParameterNames))
+		for allowedName := range sessionParameterNames {
+			allowed = append(allowed, allowedName)
+		}
+		sort.Strings(allowed)
+		return s.returnErrorResult("Parameter %q cannot be set, allowed parameters are: %s", args.Name, strings.Join(allowed, ", "))
+	}
+
+	switch {
+	case args.Reset:
+		s.parameters.reset(key, name)
+	case name == "":
+		return s.returnErrorResult("name is required unless reset is set")
+	default:
+		// let the server validate and normalize the value before keeping it
+		tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		defer tx.Rollback(ctx)
+
+		var value string
+		if err := tx.QueryRow(ctx, "SELECT set_config($1, $2, true)", name, args.Value).Scan(&value); err != nil {
+			return s.returnErrorResult("Invalid value for %s: %v", name, err)
+		}
+		s.parameters.set(key, name, value)
+	}
+
+	values := s.parameters.get(key)
+	names := make([]string, 0, len(values))
+	for parameterName := range values {
+		names = append(names, parameterName)
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT name, setting, unit
+		FROM pg_settings
+		WHERE name = ANY($1)
+		ORDER BY name
+	`, names)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query settings: %v", err)
+	}
+	defer rows.Close()
+
+	var parameters []map[string]interface{}
+	for rows.Next() {
+		var name, setting string
+		var unit *string
+		if err := rows.Scan(&name, &setting, &unit); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		parameter := map[string]interface{}{
+			"name":           name,
+			"value":          values[name],
+			"server_setting": setting,
+		}
+		addOptionalString(parameter, "unit", unit)
+		parameters = append(parameters, parameter)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"parameters": parameters,
+		"applies_to": "query and explain_analyze calls in this session, set locally inside each call's transaction",
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSetSessionParameter(t *testing.T) {
+	ctx := context.Background()
+	defer testServer.parameters.reset("", "")
+
+	t.Run("applies to later queries", func(t *testing.T) {
+		args := SetSessionParameterArgs{Name: "work_mem", Value: "64MB"}
+		result, _, err := testServer.SetSessionParameter(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("SetSessionParameter failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		queryArgs := QueryArgs{Query: "SELECT current_setting('work_mem') AS work_mem"}
+		_, data, err := testServer.ExecuteQuery(ctx, createMockRequest(queryArgs), queryArgs)
+		if err != nil {
+			t.Fatalf("ExecuteQuery failed: %v", err)
+		}
+		if value := data.([]map[string]interface{})[0]["work_mem"]; value != "64MB" {
+			t.Errorf("Expected work_mem 64MB in later queries, got %v", value)
+		}
+
+		// parameters are local to each call's transaction, not the pooled connection
+		var poolValue string
+		if err := testServer.pool.QueryRow(ctx, "SELECT current_setting('work_mem')").Scan(&poolValue); err != nil {
+			t.Fatalf("Failed to read work_mem: %v", err)
+		}
+		if poolValue == "64MB" {
+			t.Error("Expected work_mem not to leak to other pool users")
+		}
+	})
+
+	t.Run("rejects parameters outside the allowlist", func(t *testing.T) {
+		args := SetSessionParameterArgs{Name: "default_transaction_read_only", Value: "off"}
+		result, _, err := testServer.SetSessionParameter(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("SetSessionParameter failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for a parameter outside the allowlist")
+		}
+	})
+
+	t.Run("rejects invalid values", func(t *testing.T) {
+		args := SetSessionParameterArgs{Name: "enable_seqscan", Value: "sometimes"}
+		result, _, err := testServer.SetSessionParameter(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("SetSessionParameter failed: %v", err)
+		}
+
+		if result == nil || !result.IsError {
+			t.Error("Expected error result for an invalid value")
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		args := SetSessionParameterArgs{Reset: true}
+		result, data, err := testServer.SetSessionParameter(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("SetSessionParameter failed: %v", err)
+		}
+
+		if result == nil || result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+
+		if parameters := data.(map[string]interface{})["parameters"].([]map[string]interface{}); len(parameters) != 0 {
+			t.Errorf("Expected no parameters after reset, got %v", parameters)
+		}
+	})
+}
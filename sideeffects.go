@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// triggerEventBits are the pg_trigger.tgtype bits for each write verb.
+var triggerEventBits = map[string]int{
+	"INSERT": 1 << 2,
+	"DELETE": 1 << 3,
+	"UPDATE": 1 << 4,
+	"MERGE":  1<<2 | 1<<3 | 1<<4,
+}
+
+// ruleEventTypes are the pg_rewrite.ev_type codes for each write verb.
+var ruleEventTypes = map[string]string{
+	"UPDATE": "2",
+	"INSERT": "3",
+	"DELETE": "4",
+}
+
+// codeSideEffects lists what a trigger function or rule action does beyond
+// the row being written, and which of those effects survive a rollback.
+func codeSideEffects(language, source string) (effects []string, permanent []string) {
+	if language != "plpgsql" && language != "sql" {
+		effect := fmt.Sprintf("runs %s code that cannot be inspected", language)
+		return []string{effect}, []string{effect}
+	}
+
+	seen := make(map[string]bool)
+	add := func(effect string, survivesRollback bool) {
+		if seen[effect] {
+			return
+		}
+		seen[effect] = true
+		effects = append(effects, effect)
+		if survivesRollback {
+			permanent = append(permanent, effect)
+		}
+	}
+
+	for _, token := range sqlTokens(source) {
+		switch {
+		case !token.Word:
+		case dataModifyingKeywords[token.Text]:
+			add("writes data", false)
+		case token.Text == "NOTIFY" || token.Text == "PG_NOTIFY":
+			add("sends notifications", false)
+		case token.Text == "DBLINK" || token.Text == "DBLINK_EXEC":
+			add("calls dblink", true)
+		case token.Text == "NEXTVAL" || token.Text == "SETVAL":
+			add("advances sequences", true)
+		case token.Text == "EXECUTE":
+			add("runs dynamic SQL", false)
+		}
+	}
+	return effects, permanent
+}
+
+func describeSideEffects(kind, name, table, detail string, effects, permanent []string) string {
+	warning := fmt.Sprintf("%s %s on %s (%s) fires for this statement", kind, name, table, detail)
+	if len(effects) > 0 {
+		warning += ": " + strings.Join(effects, ", ")
+	}
+	if len(permanent) > 0 {
+		warning += ". Not undone by rollback: " + strings.Join(permanent, ", ")
+	}
+	return warning
+}
+
+// sideEffectWarnings lists the triggers and rules that fire on the tables a
+// statement writes to, so a rolled back statement isn't mistaken for one
+// without side effects.
+func (s *serverState) sideEffectWarnings(ctx context.Context, query string) ([]string, error) {
+	var warnings []string
+	for _, target := range statementTargets(query) {
+		relation := pgx.Identifier(target.Name).Sanitize()
+
+		var table *string
+		if err := s.pool.QueryRow(ctx, "SELECT to_regclass($1)::text", relation).Scan(&table); err != nil {
+			return warnings, fmt.Errorf("failed to resolve %s: %v", relation, err)
+		}
+		if table == nil {
+			continue
+		}
+
+		triggerQuery := `
+			SELECT
+				t.tgname::text,
+				p.oid::regprocedure::text,
+				l.lanname::text,
+				p.prosrc
+			FROM pg_trigger t
+			JOIN pg_proc p ON p.oid = t.tgfoid
+			JOIN pg_language l ON l.oid = p.prolang
+			WHERE t.tgrelid = $1::text::regclass
+				AND NOT t.tgisinternal
+				AND t.tgenabled <> 'D'
+				AND (t.tgtype::int & $2) <> 0
+			ORDER BY t.tgname
+		`
+
+		rows, err := s.pool.Query(ctx, triggerQuery, *table, triggerEventBits[target.Verb])
+		if err != nil {
+			return warnings, fmt.Errorf("failed to load triggers for %s: %v", *table, err)
+		}
+		for rows.Next() {
+			var name, function, language, source string
+			if err := rows.Scan(&name, &function, &language, &source); err != nil {
+				rows.Close()
+				return warnings, fmt.Errorf("failed to scan row: %v", err)
+			}
+			effects, permanent := codeSideEffects(language, source)
+			warnings = append(warnings, describeSideEffects("Trigger", name, *table,
+				fmt.Sprintf("%s function %s", language, function), effects, permanent))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return warnings, fmt.Errorf("row iteration error: %v", err)
+		}
+
+		ruleQuery := `
+			SELECT r.rulename::text, pg_get_ruledef(r.oid, true)
+			FROM pg_rewrite r
+			WHERE r.ev_class = $1::text::regclass
+				AND r.rulename <> '_RETURN'
+				AND ($2 = '' OR r.ev_type::text = $2)
+			ORDER BY r.rulename
+		`
+
+		rows, err = s.pool.Query(ctx, ruleQuery, *table, ruleEventTypes[target.Verb])
+		if err != nil {
+			return warnings, fmt.Errorf("failed to load rules for %s: %v", *table, err)
+		}
+		for rows.Next() {
+			var name, definition string
+			if err := rows.Scan(&name, &definition); err != nil {
+				rows.Close()
+				return warnings, fmt.Errorf("failed to scan row: %v", err)
+			}
+			// the rule's own ON ... DO prefix names the event, only its actions matter
+			action := definition
+			if index := strings.Index(strings.ToUpper(definition), " DO "); index >= 0 {
+				action = definition[index+4:]
+			}
+			effects, permanent := codeSideEffects("sql", action)
+			if strings.Contains(strings.ToUpper(action), "INSTEAD") {
+				effects = append([]string{"replaces the statement"}, effects...)
+			}
+			warnings = append(warnings, describeSideEffects("Rule", name, *table, definition, effects, permanent))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return warnings, fmt.Errorf("row iteration error: %v", err)
+		}
+	}
+	return warnings, nil
+}
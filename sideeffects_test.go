@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestCodeSideEffects(t *testing.T) {
+	effects, permanent := codeSideEffects("plpgsql", `
+		BEGIN
+			INSERT INTO audit_log (table_name) VALUES (TG_TABLE_NAME);
+			PERFORM pg_notify('changes', NEW.id::text);
+			PERFORM dblink_exec('remote', 'DELETE FROM cache');
+			RETURN NEW;
+		END;
+	`)
+
+	if strings.Join(effects, ", ") != "writes data, sends notifications, calls dblink" {
+		t.Errorf("Unexpected effects: %v", effects)
+	}
+	if len(permanent) != 1 || permanent[0] != "calls dblink" {
+		t.Errorf("Expected only dblink to survive rollback, got %v", permanent)
+	}
+
+	if _, permanent := codeSideEffects("plpython3u", "plpy.execute('x')"); len(permanent) != 1 {
+		t.Error("Expected code in other languages to be treated as permanent")
+	}
+}
+
+func TestSideEffectWarnings(t *testing.T) {
+	ctx := context.Background()
+
+	setup := `
+		CREATE TABLE side_effect_log (id SERIAL PRIMARY KEY, note TEXT);
+		CREATE FUNCTION log_listing_change() RETURNS trigger AS $$
+		BEGIN
+			INSERT INTO side_effect_log (note) VALUES ('listing changed');
+			PERFORM pg_notify('listings', NEW.id::text);
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql;
+		CREATE TRIGGER listings_changed AFTER UPDATE ON listings
+			FOR EACH ROW EXECUTE FUNCTION log_listing_change();
+		CREATE RULE listings_delete_log AS ON DELETE TO listings
+			DO ALSO INSERT INTO side_effect_log (note) VALUES ('listing deleted');
+	`
+	if _, err := testServer.pool.Exec(ctx, setup); err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, `
+		DROP RULE listings_delete_log ON listings;
+		DROP TRIGGER listings_changed ON listings;
+		DROP FUNCTION log_listing_change();
+		DROP TABLE side_effect_log;
+	`)
+
+	t.Run("trigger on updated table", func(t *testing.T) {
+		warnings, err := testServer.sideEffectWarnings(ctx, "UPDATE listings SET price = price + 1 WHERE id = 1")
+		if err != nil {
+			t.Fatalf("sideEffectWarnings failed: %v", err)
+		}
+
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "listings_changed") || !strings.Contains(warnings[0], "sends notifications") {
+			t.Errorf("Expected a warning for listings_changed, got %v", warnings)
+		}
+	})
+
+	t.Run("rule on deleted table", func(t *testing.T) {
+		warnings, err := testServer.sideEffectWarnings(ctx, "DELETE FROM listings WHERE id = 1")
+		if err != nil {
+			t.Fatalf("sideEffectWarnings failed: %v", err)
+		}
+
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "listings_delete_log") || !strings.Contains(warnings[0], "writes data") {
+			t.Errorf("Expected a warning for listings_delete_log, got %v", warnings)
+		}
+	})
+
+	t.Run("insert fires nothing", func(t *testing.T) {
+		warnings, err := testServer.sideEffectWarnings(ctx, "INSERT INTO listings (user_id, title, price) VALUES (1, 'x', 1)")
+		if err != nil {
+			t.Fatalf("sideEffectWarnings failed: %v", err)
+		}
+
+		if len(warnings) != 0 {
+			t.Errorf("Expected no warnings for INSERT, got %v", warnings)
+		}
+	})
+
+	t.Run("explain includes warnings", func(t *testing.T) {
+		args := ExplainAnalyzeArgs{Query: "UPDATE listings SET price = price + 1 WHERE id = 1", Format: "text"}
+		result, _, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+
+		if err != nil {
+			t.Fatalf("ExplainAnalyze failed: %v", err)
+		}
+
+		text := result.Content[len(result.Content)-1].(*mcp.TextContent).Text
+		if !strings.Contains(text, "listings_changed") {
+			t.Errorf("Expected explain warnings to mention the trigger, got %s", text)
+		}
+	})
+}
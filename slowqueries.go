@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxSlowQueries     = 1000
+	defaultSlowQueries = 20
+)
+
+type RecentSlowQueriesArgs struct {
+	MinDurationMs int    `json:"min_duration_ms,omitempty" jsonschema:"Only return queries seen running at least this long (default: SLOW_QUERY_THRESHOLD)"`
+	Since         string `json:"since,omitempty" jsonschema:"Only return queries last seen after this time: RFC 3339, YYYY-MM-DD HH:MM[:SS] or HH:MM[:SS]"`
+	Limit         int    `json:"limit,omitempty" jsonschema:"Maximum queries to return, the longest first (default: 20)"`
+}
+
+// slowQuery is one execution seen running longer than the threshold by
+// consecutive activity samples. Its duration is a lower bound: the query ran
+// at least until the last sample that saw it.
+type slowQuery struct {
+	PID             int            `json:"pid"`
+	User            string         `json:"user,omitempty"`
+	Database        string         `json:"database,omitempty"`
+	ApplicationName string         `json:"application_name,omitempty"`
+	Query           string         `json:"query"`
+	QueryStart      time.Time      `json:"query_start"`
+	FirstSeen       time.Time      `json:"first_seen"`
+	LastSeen        time.Time      `json:"last_seen"`
+	DurationMs      int64          `json:"duration_ms"`
+	Samples         int            `json:"samples"`
+	WaitEvents      map[string]int `json:"wait_events"`
+	BlockedBy       []int          `json:"blocked_by,omitempty"`
+}
+
+// slowQueryLog keeps the executions activity samples caught running past
+// threshold, an auto_explain-style log for servers without
+// pg_stat_statements. Executions are told apart by backend and start time.
+type slowQueryLog struct {
+	threshold time.Duration
+
+	mu      sync.Mutex
+	queries []*slowQuery
+	byKey   map[string]*slowQuery
+}
+
+func newSlowQueryLog(threshold time.Duration) *slowQueryLog {
+	return &slowQueryLog{threshold: threshold, byKey: make(map[string]*slowQuery)}
+}
+
+// observe records the active sessions of a sample that have run past the
+// threshold. The oldest executions are dropped beyond maxSlowQueries.
+func (l *slowQueryLog) observe(sample activitySample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, session := range sample.Sessions {
+		if session.State != "active" || session.QueryStart == nil {
+			continue
+		}
+		running := sample.Time.Sub(*session.QueryStart)
+		if running < l.threshold {
+			continue
+		}
+		key := fmt.Sprintf("%d/%d", session.PID, session.QueryStart.UnixNano())
+		query, ok := l.byKey[key]
+		if !ok {
+			query = &slowQuery{
+				PID:             session.PID,
+				User:            session.User,
+				Database:        session.Database,
+				ApplicationName: session.ApplicationName,
+				Query:           session.Query,
+				QueryStart:      *session.QueryStart,
+				FirstSeen:       sample.Time,
+				WaitEvents:      make(map[string]int),
+			}
+			l.byKey[key] = query
+			l.queries = append(l.queries, query)
+		}
+		query.LastSeen = sample.Time
+		query.DurationMs = running.Milliseconds()
+		query.Samples++
+		wait := "CPU"
+		if session.WaitEventType != "" {
+			wait = session.WaitEventType + ":" + session.WaitEvent
+		}
+		query.WaitEvents[wait]++
+		for _, pid := range session.BlockedBy {
+			if !slices.Contains(query.BlockedBy, pid) {
+				query.BlockedBy = append(query.BlockedBy, pid)
+			}
+		}
+	}
+	for len(l.queries) > maxSlowQueries {
+		oldest := l.queries[0]
+		delete(l.byKey, fmt.Sprintf("%d/%d", oldest.PID, oldest.QueryStart.UnixNano()))
+		l.queries = l.queries[1:]
+	}
+}
+
+// snapshot returns copies of the executions recorded, in the order they
+// were first seen.
+func (l *slowQueryLog) snapshot() []slowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queries := make([]slowQuery, 0, len(l.queries))
+	for _, query := range l.queries {
+		copied := *query
+		copied.WaitEvents = maps.Clone(query.WaitEvents)
+		copied.BlockedBy = append([]int(nil), query.BlockedBy...)
+		queries = append(queries, copied)
+	}
+	return queries
+}
+
+func (s *serverState) RecentSlowQueries(ctx context.Context, req *mcp.CallToolRequest, args RecentSlowQueriesArgs) (*mcp.CallToolResult, any, error) {
+	if s.activity == nil || s.activity.slow == nil {
+		return s.returnErrorResult("Slow query sampling is off. Set ACTIVITY_SAMPLE_INTERVAL (e.g. 1s) to sample pg_stat_activity for queries running longer than SLOW_QUERY_THRESHOLD")
+	}
+	slow := s.activity.slow
+
+	var since time.Time
+	if args.Since != "" {
+		var err error
+		if since, err = parseSampleTime(args.Since, time.Now()); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+	minDuration := slow.threshold
+	if args.MinDurationMs > 0 {
+		minDuration = time.Duration(args.MinDurationMs) * time.Millisecond
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSlowQueries
+	}
+
+	var matched []slowQuery
+	for _, query := range slow.snapshot() {
+		if query.LastSeen.Before(since) || time.Duration(query.DurationMs)*time.Millisecond < minDuration {
+			continue
+		}
+		matched = append(matched, query)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].DurationMs > matched[j].DurationMs })
+
+	response := map[string]interface{}{
+		"threshold_ms": slow.threshold.Milliseconds(),
+		"interval":     s.activity.interval.String(),
+		"matched":      len(matched),
+		"truncated":    len(matched) > limit,
+		"queries":      matched[:min(len(matched), limit)],
+	}
+	var warnings []string
+	if s.activity.interval > slow.threshold {
+		warnings = append(warnings, fmt.Sprintf(s.localize("ACTIVITY_SAMPLE_INTERVAL (%s) is longer than SLOW_QUERY_THRESHOLD (%s), queries shorter than the interval are mostly missed"), s.activity.interval, slow.threshold))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
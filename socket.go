@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// validateSocketDir checks that DB_SOCKET_DIR names an existing directory.
+// Whether the server listens there is only known once the port is.
+func validateSocketDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if !filepath.IsAbs(dir) {
+		return fmt.Errorf("DB_SOCKET_DIR must be an absolute path, got %q", dir)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid DB_SOCKET_DIR: %v", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid DB_SOCKET_DIR: %s is not a directory", dir)
+	}
+	return nil
+}
+
+// socketPath is where a server with unix_socket_directories = dir listens
+// for the given port.
+func socketPath(dir string, port uint16) string {
+	return filepath.Join(dir, ".s.PGSQL."+strconv.Itoa(int(port)))
+}
+
+// applySocketDir points a connection at the Unix socket of dir, replacing the
+// hosts of the connection string. The port only picks the socket file. With
+// peer authentication no password is needed: the user defaults to the name of
+// the operating system user the server runs as.
+func applySocketDir(connConfig *pgconn.Config, dir string) error {
+	path := socketPath(dir, connConfig.Port)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("no PostgreSQL socket at %s, check that the server is running and lists %s in unix_socket_directories", path, dir)
+	}
+	connConfig.Host = dir
+	connConfig.TLSConfig = nil
+	connConfig.Fallbacks = nil
+	return nil
+}
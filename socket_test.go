@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestValidateSocketDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, nil, 0o600)
+
+	if err := validateSocketDir(dir); err != nil {
+		t.Errorf("Expected %s to be accepted: %v", dir, err)
+	}
+	for _, invalid := range []string{"var/run/postgresql", file, filepath.Join(dir, "missing")} {
+		if err := validateSocketDir(invalid); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestApplySocketDir(t *testing.T) {
+	dir := t.TempDir()
+	connConfig, err := pgconn.ParseConfig("host=db1.example.com,db2.example.com port=5433 user=app dbname=app sslmode=require")
+	if err != nil {
+		t.Fatalf("ParseConfig failed: %v", err)
+	}
+
+	if err := applySocketDir(connConfig, dir); err == nil {
+		t.Error("Expected a directory without a server socket to be rejected")
+	}
+
+	// the port of the connection string picks the socket file
+	os.WriteFile(filepath.Join(dir, ".s.PGSQL.5433"), nil, 0o600)
+	if err := applySocketDir(connConfig, dir); err != nil {
+		t.Fatalf("applySocketDir failed: %v", err)
+	}
+	if network, address := pgconn.NetworkAddress(connConfig.Host, connConfig.Port); network != "unix" || address != socketPath(dir, 5433) {
+		t.Errorf("Expected the socket in %s, got %s %s", dir, network, address)
+	}
+	if connConfig.TLSConfig != nil || len(connConfig.Fallbacks) != 0 || connConfig.User != "app" || connConfig.Database != "app" {
+		t.Errorf("Unexpected configuration %+v", connConfig)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var geometryFormats = map[string]bool{"geojson": true, "wkt": true}
+
+type SpatialInfoArgs struct {
+	TableName string `json:"table_name,omitempty" jsonschema:"Only report the spatial columns of this table, optionally schema-qualified (default: every table)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+// geometryColumn is a result column of a PostGIS type, with the schema
+// PostGIS is installed in.
+type geometryColumn struct {
+	Schema string
+	Type   string
+}
+
+// geometryColumns finds the result columns of PostGIS types. pgx does not
+// know them and returns their values as hex EWKB text.
+func geometryColumns(ctx context.Context, resolver relationResolver, typeMap *pgtype.Map, fields []pgconn.FieldDescription) (map[string]geometryColumn, error) {
+	var unknown []uint32
+	for _, field := range fields {
+		if _, ok := typeMap.TypeForOID(field.DataTypeOID); !ok {
+			unknown = append(unknown, field.DataTypeOID)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	rows, err := resolver.Query(ctx, `
+		SELECT t.oid, n.nspname::text, t.typname::text
+		FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.oid = ANY($1) AND t.typname IN ('geometry', 'geography')
+	`, unknown)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := make(map[uint32]geometryColumn)
+	for rows.Next() {
+		var oid uint32
+		var column geometryColumn
+		if err := rows.Scan(&oid, &column.Schema, &column.Type); err != nil {
+			return nil, err
+		}
+		types[oid] = column
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]geometryColumn)
+	for _, field := range fields {
+		if column, ok := types[field.DataTypeOID]; ok {
+			columns[field.Name] = column
+		}
+	}
+	return columns, nil
+}
+
+// renderGeometries converts hex EWKB values with PostGIS itself, in one round
+// trip per column: GeoJSON objects, or WKT strings.
+func renderGeometries(ctx context.Context, resolver relationResolver, column geometryColumn, format string, values []interface{}) ([]interface{}, error) {
+	texts := make([]*string, len(values))
+	for i, value := range values {
+		if text, ok := value.(string); ok {
+			texts[i] = &text
+		}
+	}
+
+	function := "st_asgeojson"
+	if format == "wkt" {
+		function = "st_astext"
+	}
+	rows, err := resolver.Query(ctx, fmt.Sprintf("SELECT %s(v::%s) FROM unnest($1::text[]) WITH ORDINALITY AS u(v, i) ORDER BY i",
+		pgx.Identifier{column.Schema, function}.Sanitize(), pgx.Identifier{column.Schema, column.Type}.Sanitize()), texts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rendered := make([]interface{}, 0, len(values))
+	for rows.Next() {
+		var text *string
+		if err := rows.Scan(&text); err != nil {
+			return nil, err
+		}
+		switch {
+		case text == nil:
+			rendered = append(rendered, nil)
+		case format == "wkt":
+			rendered = append(rendered, *text)
+		default:
+			var geoJSON interface{}
+			if err := json.Unmarshal([]byte(*text), &geoJSON); err != nil {
+				return nil, err
+			}
+			rendered = append(rendered, geoJSON)
+		}
+	}
+	return rendered, rows.Err()
+}
+
+// renderGeometryRows replaces the geometry values of result rows in place.
+func renderGeometryRows(ctx context.Context, resolver relationResolver, columns map[string]geometryColumn, format string, results []map[string]interface{}) error {
+	for name, column := range columns {
+		values := make([]interface{}, len(results))
+		for i, row := range results {
+			values[i] = row[name]
+		}
+		rendered, err := renderGeometries(ctx, resolver, column, format, values)
+		if err != nil {
+			return err
+		}
+		for i, row := range results {
+			row[name] = rendered[i]
+		}
+	}
+	return nil
+}
+
+func (s *serverState) SpatialInfo(ctx context.Context, req *mcp.CallToolRequest, args SpatialInfoArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	var version, extensionSchema *string
+	err := s.pool.QueryRow(ctx, `
+		SELECT e.extversion, n.nspname::text
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname = 'postgis'
+	`).Scan(&version, &extensionSchema)
+	if err == pgx.ErrNoRows {
+		return returnJSONResult(map[string]interface{}{
+			"installed": false,
+			"message":   "The PostGIS extension is not installed in this database (CREATE EXTENSION postgis)",
+		})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up the postgis extension: %v", err)
+	}
+
+	filter := ""
+	queryArgs := []interface{}{}
+	if args.TableName != "" {
+		schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(schema, tableName) {
+			return s.returnNotAccessible(qualifiedName(schema, tableName))
+		}
+		filter = "WHERE schema_name = $1 AND table_name = $2"
+		queryArgs = append(queryArgs, schema, tableName)
+	}
+
+	geometryView := pgx.Identifier{*extensionSchema, "geometry_columns"}.Sanitize()
+	geographyView := pgx.Identifier{*extensionSchema, "geography_columns"}.Sanitize()
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT schema_name, table_name, column_name, kind, geometry_type, srid, dimensions,
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'index', c.relname::text,
+					'method', am.amname::text,
+					'size_bytes', pg_relation_size(c.oid),
+					'definition', pg_get_indexdef(c.oid)
+				) ORDER BY c.relname)
+				FROM pg_index i
+				JOIN pg_class c ON c.oid = i.indexrelid
+				JOIN pg_am am ON am.oid = c.relam
+				JOIN pg_class t ON t.oid = i.indrelid
+				JOIN pg_namespace tn ON tn.oid = t.relnamespace
+				JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(i.indkey)
+				WHERE tn.nspname = schema_name AND t.relname = table_name AND a.attname = column_name
+					AND am.amname IN ('gist', 'spgist', 'brin')
+			), '[]')
+		FROM (
+			SELECT f_table_schema::text AS schema_name, f_table_name::text AS table_name,
+				f_geometry_column::text AS column_name, 'geometry' AS kind, type::text AS geometry_type,
+				srid, coord_dimension AS dimensions
+			FROM %s
+			UNION ALL
+			SELECT f_table_schema::text, f_table_name::text, f_geography_column::text, 'geography', type::text,
+				srid, coord_dimension
+			FROM %s
+		) spatial
+		%s
+		ORDER BY 1, 2, 3
+	`, geometryView, geographyView, filter), queryArgs...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list spatial columns: %v", err)
+	}
+	defer rows.Close()
+
+	var columns []map[string]interface{}
+	for rows.Next() {
+		var schema, table, column, kind, geometryType string
+		var srid, dimensions int
+		var indexes []map[string]interface{}
+		if err := rows.Scan(&schema, &table, &column, &kind, &geometryType, &srid, &dimensions, &indexes); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, table) {
+			continue
+		}
+
+		var notes []string
+		if srid == 0 {
+			notes = append(notes, "No SRID is set, so distances and areas are in the units of the raw coordinates")
+		}
+		if len(indexes) == 0 {
+			notes = append(notes, fmt.Sprintf("No spatial index, filters such as ST_Intersects or ST_DWithin on it scan the whole table. CREATE INDEX ON %s USING gist (%s)",
+				pgx.Identifier{schema, table}.Sanitize(), pgx.Identifier{column}.Sanitize()))
+		}
+		columns = append(columns, map[string]interface{}{
+			"table":         qualifiedName(schema, table),
+			"column":        column,
+			"kind":          kind,
+			"geometry_type": geometryType,
+			"srid":          srid,
+			"dimensions":    dimensions,
+			"indexes":       indexes,
+			"notes":         notes,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"installed": true,
+		"version":   *version,
+		"schema":    *extensionSchema,
+		"columns":   columns,
+	})
+}
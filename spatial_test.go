@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSpatialInfo(t *testing.T) {
+	args := SpatialInfoArgs{}
+	result, data, err := testServer.SpatialInfo(context.Background(), createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("SpatialInfo failed: %v %v", err, result)
+	}
+
+	// the test database has no PostGIS
+	if info := data.(map[string]interface{}); info["installed"] != false {
+		t.Errorf("Expected PostGIS to be reported missing, got %v", info)
+	}
+}
+
+func TestQueryGeometryFormat(t *testing.T) {
+	ctx := context.Background()
+
+	args := QueryArgs{Query: "SELECT id FROM users LIMIT 1", GeometryFormat: "wkb"}
+	if result, _, _ := testServer.ExecuteQuery(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected an unknown geometry_format to be rejected")
+	}
+
+	// results without PostGIS types are returned unchanged
+	args = QueryArgs{Query: "SELECT id, point(1, 2) AS location FROM users ORDER BY id LIMIT 1", GeometryFormat: "wkt"}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	if rows := data.([]map[string]interface{}); len(rows) != 1 || rows[0]["location"] == nil {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a keyword, identifier or punctuation mark outside of string
+// literals and comments. Words are upper-cased, quoted identifiers keep their
+// quotes so they never match a keyword. A string literal is a single ' token
+// with its contents in Raw.
+type sqlToken struct {
+	Text  string
+	Raw   string
+	Word  bool
+	Depth int
+}
+
+// sqlTokens splits a statement into tokens, skipping comments and the
+// contents of string literals, quoted identifiers and dollar-quoted bodies.
+func sqlTokens(query string) []sqlToken {
+	var tokens []sqlToken
+	runes := []rune(query)
+	depth := 0
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i = skipBlockComment(runes, i)
+
+		case r == '\'':
+			escapes := len(tokens) > 0 && tokens[len(tokens)-1].Text == "E" && i > 0 && (runes[i-1] == 'E' || runes[i-1] == 'e')
+			if escapes {
+				tokens = tokens[:len(tokens)-1]
+			}
+			start := i
+			i = skipQuoted(runes, i, '\'', escapes)
+			tokens = append(tokens, sqlToken{Text: "'", Raw: literalContents(runes[start:i]), Depth: depth})
+
+		case r == '"':
+			start := i
+			i = skipQuoted(runes, i, '"', false)
+			tokens = append(tokens, sqlToken{Text: string(runes[start:i]), Depth: depth})
+
+		case r == '$':
+			// $1 parameters or $tag$ ... $tag$ bodies
+			j := i + 1
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			if j < len(runes) && runes[j] == '$' && (j == i+1 || !unicode.IsDigit(runes[i+1])) {
+				i = skipDollarQuoted(runes, j+1, runes[i:j+1])
+				tokens = append(tokens, sqlToken{Text: "$$", Depth: depth})
+			} else {
+				tokens = append(tokens, sqlToken{Text: string(runes[i:j]), Depth: depth})
+				i = j
+			}
+
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			raw := string(runes[start:i])
+			tokens = append(tokens, sqlToken{Text: strings.ToUpper(raw), Raw: raw, Word: true, Depth: depth})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{Text: string(runes[start:i]), Depth: depth})
+
+		default:
+			if r == ')' && depth > 0 {
+				depth--
+			}
+			tokens = append(tokens, sqlToken{Text: string(r), Depth: depth})
+			if r == '(' {
+				depth++
+			}
+			i++
+		}
+	}
+	return tokens
+}
+
+// literalContents returns what a quoted string literal spells, keeping
+// backslash escapes as they are.
+func literalContents(quoted []rune) string {
+	text := string(quoted[1:])
+	text = strings.TrimSuffix(text, "'")
+	return strings.ReplaceAll(text, "''", "'")
+}
+
+// skipBlockComment returns the index just past a block comment, which nest
+// in PostgreSQL.
+func skipBlockComment(runes []rune, start int) int {
+	nesting := 0
+	for i := start; i < len(runes); {
+		if runes[i] == '/' && i+1 < len(runes) && runes[i+1] == '*' {
+			nesting++
+			i += 2
+		} else if runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/' {
+			nesting--
+			i += 2
+			if nesting == 0 {
+				return i
+			}
+		} else {
+			i++
+		}
+	}
+	return len(runes)
+}
+
+// skipQuoted returns the index just past the closing quote, doubled quotes
+// (and backslashes in E-prefixed strings) escape it.
+func skipQuoted(runes []rune, start int, quote rune, backslashEscapes bool) int {
+	for i := start + 1; i < len(runes); i++ {
+		switch {
+		case backslashEscapes && runes[i] == '\\':
+			i++
+		case runes[i] == quote && i+1 < len(runes) && runes[i+1] == quote:
+			i++
+		case runes[i] == quote:
+			return i + 1
+		}
+	}
+	return len(runes)
+}
+
+// skipDollarQuoted returns the index just past the closing tag.
+func skipDollarQuoted(runes []rune, start int, tag []rune) int {
+	for i := start; i+len(tag) <= len(runes); i++ {
+		if string(runes[i:i+len(tag)]) == string(tag) {
+			return i + len(tag)
+		}
+	}
+	return len(runes)
+}
+
+var dataModifyingKeywords = map[string]bool{
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+	"MERGE":  true,
+}
+
+// statementKeyword returns the first keyword of a statement, e.g. SELECT.
+func statementKeyword(query string) string {
+	for _, token := range sqlTokens(query) {
+		if token.Word {
+			return token.Text
+		}
+	}
+	return ""
+}
+
+// statementPosition reports whether tokens[i] starts a statement: the first
+// token, a parenthesized (CTE) body, or the main statement after WITH
+// clauses. FOR UPDATE or ON CONFLICT DO UPDATE are not statements.
+func statementPosition(tokens []sqlToken, i int) bool {
+	if i == 0 {
+		return true
+	}
+	previous := tokens[i-1].Text
+	return previous == "(" || (previous == ")" && tokens[i].Depth == 0)
+}
+
+// statementWrites reports whether executing the statement modifies data:
+// DML, data-modifying CTEs, CREATE TABLE AS, SELECT INTO and EXECUTE of a
+// prepared statement that may do either.
+func statementWrites(query string) bool {
+	tokens := sqlTokens(query)
+	first := statementKeyword(query)
+	if dataModifyingKeywords[first] || first == "CREATE" || first == "EXECUTE" {
+		return true
+	}
+
+	for i, token := range tokens {
+		if !token.Word {
+			continue
+		}
+		if dataModifyingKeywords[token.Text] && statementPosition(tokens, i) {
+			return true
+		}
+		if token.Text == "INTO" && token.Depth == 0 && (first == "SELECT" || first == "WITH") {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlTarget is a table a statement writes to, with the verb writing to it.
+// Name holds the identifier parts as PostgreSQL resolves them: unquoted
+// parts folded to lower case, quoted parts verbatim.
+type sqlTarget struct {
+	Verb string
+	Name []string
+}
+
+// statementTargets finds the tables written by INSERT INTO, UPDATE, DELETE
+// FROM and MERGE INTO, including those inside data-modifying CTEs.
+func statementTargets(query string) []sqlTarget {
+	tokens := sqlTokens(query)
+	var targets []sqlTarget
+	for i, token := range tokens {
+		if !token.Word || !dataModifyingKeywords[token.Text] {
+			continue
+		}
+		if !statementPosition(tokens, i) {
+			continue
+		}
+
+		j := i + 1
+		for j < len(tokens) && tokens[j].Word && (tokens[j].Text == "INTO" || tokens[j].Text == "FROM" || tokens[j].Text == "ONLY") {
+			j++
+		}
+		if name := identifierAt(tokens, j); len(name) > 0 {
+			targets = append(targets, sqlTarget{Verb: token.Text, Name: name})
+		}
+	}
+	return targets
+}
+
+// identifierAt reads a possibly qualified identifier starting at tokens[i].
+func identifierAt(tokens []sqlToken, i int) []string {
+	var parts []string
+	for i < len(tokens) {
+		token := tokens[i]
+		switch {
+		case token.Word:
+			parts = append(parts, strings.ToLower(token.Raw))
+		case strings.HasPrefix(token.Text, `"`) && len(token.Text) >= 2:
+			parts = append(parts, strings.ReplaceAll(token.Text[1:len(token.Text)-1], `""`, `"`))
+		default:
+			return parts
+		}
+		if i+1 >= len(tokens) || tokens[i+1].Text != "." {
+			return parts
+		}
+		i += 2
+	}
+	return parts
+}
+
+// relationKeywords are followed by the name of a relation a statement reads
+// or writes.
+var relationKeywords = map[string]bool{
+	"FROM":  true,
+	"JOIN":  true,
+	"INTO":  true,
+	"TABLE": true,
+	"USING": true,
+}
+
+// fromListEndKeywords end a comma-separated FROM (or USING) list.
+var fromListEndKeywords = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "WINDOW": true, "ORDER": true,
+	"LIMIT": true, "OFFSET": true, "FETCH": true, "FOR": true, "UNION": true,
+	"INTERSECT": true, "EXCEPT": true, "RETURNING": true, "SET": true,
+	"SELECT": true, "VALUES": true, "WHEN": true,
+}
+
+// fromArgumentFunctions take FROM inside their parentheses, as in
+// EXTRACT(year FROM created_at).
+var fromArgumentFunctions = map[string]bool{
+	"EXTRACT": true, "SUBSTRING": true, "TRIM": true, "OVERLAY": true, "POSITION": true,
+}
+
+// functionArgumentFrom reports whether the FROM at tokens[i] is part of a
+// function's arguments or of IS DISTINCT FROM rather than a FROM clause.
+func functionArgumentFrom(tokens []sqlToken, i int) bool {
+	if i > 0 && tokens[i-1].Text == "DISTINCT" {
+		return true
+	}
+	for j := i - 1; j > 0; j-- {
+		if tokens[j].Text == "(" && tokens[j].Depth == tokens[i].Depth-1 {
+			return tokens[j-1].Word && fromArgumentFunctions[tokens[j-1].Text]
+		}
+	}
+	return false
+}
+
+// statementRelations finds the relation names a statement references after
+// FROM, JOIN, INTO, TABLE, USING and UPDATE, in comma-separated FROM lists
+// and in subqueries. Function calls in FROM are skipped. CTE names are
+// returned like tables since telling them apart needs name resolution.
+func statementRelations(query string) [][]string {
+	tokens := sqlTokens(query)
+	var names [][]string
+	// fromList tracks, per parenthesis depth, whether a comma starts
+	// another FROM item
+	fromList := make(map[int]bool)
+	for i, token := range tokens {
+		for depth := range fromList {
+			if depth > token.Depth {
+				delete(fromList, depth)
+			}
+		}
+
+		start := -1
+		fromItem := false
+		switch {
+		case token.Text == "," && fromList[token.Depth]:
+			start, fromItem = i+1, true
+		case !token.Word:
+			continue
+		case token.Text == "FROM" && functionArgumentFrom(tokens, i):
+			continue
+		case relationKeywords[token.Text]:
+			start = i + 1
+			fromItem = token.Text == "FROM" || token.Text == "JOIN"
+			fromList[token.Depth] = fromItem || token.Text == "USING"
+		case token.Text == "UPDATE" && statementPosition(tokens, i):
+			start = i + 1
+		case fromListEndKeywords[token.Text]:
+			fromList[token.Depth] = false
+		}
+		if start < 0 {
+			continue
+		}
+
+		for start < len(tokens) && tokens[start].Word && (tokens[start].Text == "ONLY" || tokens[start].Text == "LATERAL") {
+			start++
+		}
+		name := identifierAt(tokens, start)
+		// a FROM item followed by parentheses is a function call, a table
+		// after INTO or TABLE can be followed by its column list
+		next := start + 2*len(name) - 1
+		if len(name) == 0 || (fromItem && next < len(tokens) && tokens[next].Text == "(") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Statement categories the query tool's policy is set per.
+const (
+	categoryRead        = "read"
+	categoryDML         = "dml"
+	categoryDDL         = "ddl"
+	categoryMaintenance = "maintenance"
+	categoryOther       = "other"
+)
+
+var ddlKeywords = map[string]bool{
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "COMMENT": true,
+	"GRANT": true, "REVOKE": true, "SECURITY": true, "REASSIGN": true, "IMPORT": true,
+}
+
+var maintenanceKeywords = map[string]bool{
+	"VACUUM": true, "ANALYZE": true, "CLUSTER": true, "REINDEX": true, "REFRESH": true, "CHECKPOINT": true,
+}
+
+// explainOptionWords can follow EXPLAIN before the explained statement.
+var explainOptionWords = map[string]bool{
+	"ANALYZE": true, "VERBOSE": true, "COSTS": true, "SETTINGS": true, "BUFFERS": true,
+	"WAL": true, "TIMING": true, "SUMMARY": true, "FORMAT": true, "TRUE": true,
+	"FALSE": true, "ON": true, "OFF": true, "TEXT": true, "JSON": true, "XML": true, "YAML": true,
+}
+
+// statementCategory classifies a statement as read, dml, ddl, maintenance or
+// other (session and transaction control). EXPLAIN is a read unless ANALYZE
+// makes it execute the explained statement, which is classified instead.
+func statementCategory(query string) string {
+	tokens := sqlTokens(query)
+	start := 0
+	for start < len(tokens) && !tokens[start].Word {
+		start++
+	}
+	if start == len(tokens) {
+		return categoryOther
+	}
+
+	if tokens[start].Text == "EXPLAIN" {
+		analyze := false
+		i := start + 1
+		for ; i < len(tokens); i++ {
+			token := tokens[i]
+			if token.Depth == 0 && token.Text != "(" && token.Text != ")" && !explainOptionWords[token.Text] {
+				break
+			}
+			next := ""
+			if i+1 < len(tokens) {
+				next = tokens[i+1].Text
+			}
+			if token.Text == "ANALYZE" && next != "FALSE" && next != "OFF" {
+				analyze = true
+			}
+		}
+		if !analyze || i == len(tokens) {
+			return categoryRead
+		}
+		start = i
+	}
+
+	keyword := tokens[start].Text
+	switch {
+	case ddlKeywords[keyword]:
+		return categoryDDL
+	case maintenanceKeywords[keyword]:
+		return categoryMaintenance
+	case dataModifyingKeywords[keyword] || keyword == "CALL" || keyword == "EXECUTE" || keyword == "DO":
+		return categoryDML
+	case keyword == "COPY":
+		// copies to a server file or program are refused by copiesServerSide
+		for _, token := range tokens[start:] {
+			if token.Depth == 0 && token.Text == "FROM" {
+				return categoryDML
+			}
+		}
+		return categoryRead
+	case keyword == "SHOW":
+		return categoryRead
+	case keyword != "SELECT" && keyword != "WITH" && keyword != "VALUES" && keyword != "TABLE":
+		return categoryOther
+	}
+
+	// data-modifying CTEs write, SELECT INTO creates a table
+	for i := start + 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Word && dataModifyingKeywords[token.Text] && statementPosition(tokens, i) {
+			return categoryDML
+		}
+		if token.Text == "INTO" && token.Depth == 0 {
+			return categoryDDL
+		}
+	}
+	return categoryRead
+}
+
+// roleSettings are the settings that switch the role a session runs as.
+var roleSettings = map[string]bool{"role": true, "session_authorization": true}
+
+// settingName returns the setting a SET or RESET names, unquoting it.
+func settingName(token sqlToken) string {
+	if token.Word {
+		return strings.ToLower(token.Raw)
+	}
+	return strings.ToLower(strings.Trim(token.Text, `"`))
+}
+
+// changesRole reports whether a statement switches the session's role: SET
+// or RESET of ROLE or SESSION AUTHORIZATION, also as the SET clause of a
+// function, DISCARD ALL, or a set_config call that names either setting or
+// whose setting isn't a plain literal. Statements inside function bodies are
+// not seen.
+func changesRole(query string) bool {
+	tokens := sqlTokens(query)
+	// in CREATE or ALTER a SET clause configures a function or role, elsewhere
+	// it can be UPDATE's
+	definition := statementKeyword(query) == "CREATE" || statementKeyword(query) == "ALTER"
+	for i, token := range tokens {
+		if !token.Word {
+			continue
+		}
+		next := func(offset int) sqlToken {
+			if i+offset < len(tokens) {
+				return tokens[i+offset]
+			}
+			return sqlToken{}
+		}
+		switch token.Text {
+		case "SET", "RESET":
+			if i > 0 && tokens[i-1].Text != ";" && !definition {
+				continue
+			}
+			name := next(1)
+			if name.Text == "SESSION" || name.Text == "LOCAL" {
+				name = next(2)
+			}
+			if roleSettings[settingName(name)] || name.Text == "AUTHORIZATION" || (name.Text == "SESSION" && next(3).Text == "AUTHORIZATION") {
+				return true
+			}
+		case "DISCARD":
+			if (i == 0 || tokens[i-1].Text == ";") && next(1).Text == "ALL" {
+				return true
+			}
+		case "SET_CONFIG":
+			if next(1).Text != "(" {
+				continue
+			}
+			setting := next(2)
+			if setting.Text != "'" || (next(3).Text != "," && next(3).Text != ")") || roleSettings[strings.ToLower(strings.TrimSpace(setting.Raw))] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// copiesServerSide reports whether a COPY reads or writes a file on the
+// database server or runs a program there, rather than streaming through
+// STDIN or STDOUT.
+func copiesServerSide(query string) bool {
+	if statementKeyword(query) != "COPY" {
+		return false
+	}
+	tokens := sqlTokens(query)
+	for i, token := range tokens {
+		if token.Depth == 0 && (token.Text == "TO" || token.Text == "FROM") && i+1 < len(tokens) {
+			if next := tokens[i+1].Text; next == "'" || next == "PROGRAM" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// dynamicSQLFunctions run SQL or read whole tables, schemas or databases
+// named by their arguments, which the tokenizer cannot see into.
+var dynamicSQLFunctions = map[string]bool{
+	"QUERY_TO_XML": true, "QUERY_TO_XMLSCHEMA": true, "QUERY_TO_XML_AND_XMLSCHEMA": true,
+	"CURSOR_TO_XML": true, "CURSOR_TO_XMLSCHEMA": true,
+	"TABLE_TO_XML": true, "TABLE_TO_XMLSCHEMA": true, "TABLE_TO_XML_AND_XMLSCHEMA": true,
+	"SCHEMA_TO_XML": true, "SCHEMA_TO_XMLSCHEMA": true, "SCHEMA_TO_XML_AND_XMLSCHEMA": true,
+	"DATABASE_TO_XML": true, "DATABASE_TO_XMLSCHEMA": true, "DATABASE_TO_XML_AND_XMLSCHEMA": true,
+	"TS_STAT": true, "DBLINK": true, "DBLINK_EXEC": true, "DBLINK_OPEN": true, "DBLINK_SEND_QUERY": true,
+}
+
+// dynamicSQLCalls returns the functions of dynamicSQLFunctions a statement
+// calls, lower-cased and in order.
+func dynamicSQLCalls(query string) []string {
+	var calls []string
+	tokens := sqlTokens(query)
+	for i, token := range tokens {
+		if token.Word && dynamicSQLFunctions[token.Text] && i+1 < len(tokens) && tokens[i+1].Text == "(" {
+			if name := strings.ToLower(token.Text); !slices.Contains(calls, name) {
+				calls = append(calls, name)
+			}
+		}
+	}
+	return calls
+}
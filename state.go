@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// serverState is everything one server instance runs with: its
+// configuration, connection pool, approval queue and audit log. Tool
+// handlers, middleware and the helpers they share are its methods, so
+// several instances (or tests) can run side by side with their own pools.
+type serverState struct {
+	config    Config
+	pool      *pgxpool.Pool
+	approvals approvalQueue
+	audit     auditWriter
+	queue     *callQueue
+	reconnect reconnector
+	activity  *activitySampler
+	metadata  *metadataCache
+
+	// profiles are the states of every configured profile, possibly
+	// including this one, keyed by name.
+	profiles map[string]*serverState
+
+	// clones are the databases clone_database made from this one, cloneOf
+	// the state a clone was made from.
+	clones  databaseClones
+	cloneOf *serverState
+
+	// sessions and parameters hold each MCP session's log and
+	// set_session_parameter values, shared with profiles and clones.
+	sessions   *sessionLogs
+	parameters *sessionParameters
+}
+
+// newServerState connects to the database described by poolConfig, with the
+// pool settings of config applied, and checks that the connection works.
+func newServerState(ctx context.Context, config Config, poolConfig *pgxpool.Config) (*serverState, error) {
+	registerConnection(poolConfig)
+	config.applyPoolSettings(poolConfig)
+	if err := config.applyDatabaseTLS(&poolConfig.ConnConfig.Config); err != nil {
+		return nil, err
+	}
+	if err := config.checkDatabaseTLS(&poolConfig.ConnConfig.Config); err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %v", err)
+	}
+	retryTimeout := config.ConnectRetryTimeout
+	if config.LazyConnect {
+		retryTimeout = 0
+	}
+	pingErr := pingWithRetry(ctx, pool, retryTimeout)
+	if pingErr != nil && !config.LazyConnect {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %v", pingErr)
+	}
+
+	s := &serverState{
+		config:     config,
+		pool:       pool,
+		queue:      newCallQueue(int(poolConfig.MaxConns)),
+		sessions:   newSessionLogs(),
+		parameters: newSessionParameters(),
+	}
+	if err := s.openAuditLog(config.AuditLog); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	if pingErr != nil {
+		// LAZY_CONNECT: serve anyway, tools report the database unavailable
+		s.startReconnecting(pingErr)
+	}
+	if config.MetadataCacheInterval > 0 {
+		s.metadata = newMetadataCache(config.MetadataCacheInterval)
+	}
+	if config.ActivitySampleInterval > 0 {
+		s.activity = newActivitySampler(config.ActivitySampleInterval, config.ActivitySampleRetention)
+		s.activity.slow = newSlowQueryLog(config.SlowQueryThreshold)
+		s.startActivitySampler()
+	}
+	return s, nil
+}
+
+// Close releases the pool's connections, and those of the profiles.
+func (s *serverState) Close() {
+	s.reconnect.stopReconnecting()
+	if s.activity != nil {
+		s.activity.stopSampling()
+	}
+	if s.pool != nil {
+		s.pool.Close()
+	}
+	for _, state := range s.profiles {
+		if state != s {
+			state.Close()
+		}
+	}
+	s.clones.mu.Lock()
+	defer s.clones.mu.Unlock()
+	for _, clone := range s.clones.states {
+		clone.Close()
+	}
+}
+
+// newMCPServer builds an MCP server whose middleware runs against this
+// state. Tools and prompts are registered by the caller.
+func (s *serverState) newMCPServer() *mcp.Server {
+	var serverOptions *mcp.ServerOptions
+	if s.config.DryRun {
+		serverOptions = &mcp.ServerOptions{Instructions: s.localize(dryRunInstructions)}
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "postgres-mcp",
+		Version: "v1.0.0",
+	}, serverOptions)
+	server.AddReceivingMiddleware(redactMiddleware, s.sessionMiddleware, s.localeMiddleware)
+	return server
+}
+
+// addTool registers a tool handler that runs against the state picked by
+// forConnection, or the clone its session targets, once the call's turn in
+// that state's queue has come. With
+// profiles configured the tool's input schema gains the connection argument
+// listing them.
+func addTool[In any](s *serverState, server *mcp.Server, tool *mcp.Tool, handler func(*serverState, context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error)) {
+	extra := make(map[string]*jsonschema.Schema)
+	if len(s.profiles) > 0 {
+		names := sortedKeys(s.profiles)
+		connection := &jsonschema.Schema{
+			Type:        "string",
+			Description: "Profile to run against instead of the default (" + s.config.Profile + ")",
+		}
+		if s.config.Profile == "" {
+			connection.Description = "Profile to run against instead of the default database"
+		}
+		for _, name := range names {
+			connection.Enum = append(connection.Enum, name)
+		}
+		extra["connection"] = connection
+	}
+	if unitTools[tool.Name] {
+		extra["units"] = unitsProperty()
+	}
+	if len(extra) > 0 {
+		schema, err := jsonschema.For[In](nil)
+		if err != nil {
+			panic(fmt.Sprintf("input schema of %s: %v", tool.Name, err))
+		}
+		if schema.Properties == nil {
+			schema.Properties = make(map[string]*jsonschema.Schema)
+		}
+		for name, property := range extra {
+			schema.Properties[name] = property
+		}
+		tool.InputSchema = schema
+	}
+
+	mcp.AddTool(server, tool, func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		state, err := s.forConnection(req)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		state = state.cloneFor(req)
+		if !unqueuedTools[tool.Name] {
+			if state.reconnectingError() != nil {
+				return state.returnUnavailable()
+			}
+			release, err := state.queue.acquire(ctx, backgroundTools[tool.Name], sessionKey(req))
+			if err != nil {
+				return nil, nil, err
+			}
+			defer release()
+		}
+		result, data, err := handler(state, ctx, req, args)
+		if err == nil && unitTools[tool.Name] {
+			return state.withUnits(req, result, data)
+		}
+		return result, data, err
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServerStatesAreIndependent(t *testing.T) {
+	ctx := context.Background()
+
+	restricted, err := newServerState(ctx, Config{DeniedTables: []string{"posts"}, AllowWrites: true, RequireApproval: true, AllowInsecure: true},
+		testServer.pool.Config())
+	if err != nil {
+		t.Fatalf("newServerState failed: %v", err)
+	}
+	defer restricted.Close()
+
+	hasPosts := func(s *serverState) bool {
+		args := TableListArgs{}
+		_, data, err := s.ListTables(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ListTables failed: %v", err)
+		}
+		for _, table := range data.([]map[string]interface{}) {
+			if table["table_name"] == "posts" {
+				return true
+			}
+		}
+		return false
+	}
+	if hasPosts(restricted) || !hasPosts(testServer) {
+		t.Error("Expected the deny list to apply only to the instance configured with it")
+	}
+
+	if _, err := restricted.queueChange(ctx, nil, "query", "Delete nothing", "DELETE FROM users WHERE false"); err != nil {
+		t.Fatalf("queueChange failed: %v", err)
+	}
+	args := ListPendingChangesArgs{}
+	_, data, err := testServer.ListPendingChanges(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ListPendingChanges failed: %v", err)
+	}
+	changes, _ := data.([]map[string]interface{})
+	for _, change := range changes {
+		if change["summary"] == "Delete nothing" {
+			t.Error("Expected another instance's pending changes to stay out of the queue")
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// hotHintUpdates is how many updates a table needs before storage_info
+// judges its share of HOT updates.
+const hotHintUpdates = 1000
+
+// storageStrategies name pg_attribute.attstorage and pg_type.typstorage.
+var storageStrategies = map[string]string{
+	"p": "plain",
+	"e": "external",
+	"m": "main",
+	"x": "extended",
+}
+
+// compressionMethods name pg_attribute.attcompression, empty being
+// default_toast_compression.
+var compressionMethods = map[string]string{
+	"p": "pglz",
+	"l": "lz4",
+}
+
+// indexFillfactors are the fillfactors of the index methods that have one,
+// when the index doesn't set its own.
+var indexFillfactors = map[string]int{
+	"btree":  90,
+	"hash":   75,
+	"gist":   90,
+	"spgist": 80,
+}
+
+type StorageInfoArgs struct {
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+}
+
+// StorageInfo shows how a table is laid out on disk: its heap, TOAST and
+// index sizes, the storage strategy and compression of each column, and the
+// fillfactor and other reloptions of the table, its TOAST table and its
+// indexes. With the share of HOT updates it explains most update bloat.
+func (s *serverState) StorageInfo(ctx context.Context, req *mcp.CallToolRequest, args StorageInfoArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := qualifiedName(schema, tableName)
+	regclass := pgx.Identifier{schema, tableName}.Sanitize()
+
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get server version: %v", err)
+	}
+
+	var kind, toastName string
+	var options, toastOptions []string
+	var heapBytes, indexBytes, totalBytes, toastBytes, updates, hotUpdates int64
+	var hasToast bool
+	err = s.pool.QueryRow(ctx, `
+		SELECT c.relkind::text, COALESCE(c.reloptions, '{}'),
+			pg_relation_size(c.oid), pg_indexes_size(c.oid), pg_total_relation_size(c.oid),
+			t.oid IS NOT NULL, COALESCE(t.relname::text, ''), COALESCE(pg_total_relation_size(t.oid), 0),
+			COALESCE(t.reloptions, '{}'), COALESCE(st.n_tup_upd, 0), COALESCE(st.n_tup_hot_upd, 0)
+		FROM pg_class c
+		LEFT JOIN pg_class t ON t.oid = c.reltoastrelid
+		LEFT JOIN pg_stat_all_tables st ON st.relid = c.oid
+		WHERE c.oid = $1::text::regclass
+	`, regclass).Scan(&kind, &options, &heapBytes, &indexBytes, &totalBytes,
+		&hasToast, &toastName, &toastBytes, &toastOptions, &updates, &hotUpdates)
+	if err != nil {
+		return s.returnErrorResult("Failed to look up %s: %v", table, err)
+	}
+
+	// attcompression came with lz4 TOAST compression in 14
+	compression := "''"
+	if versionNum >= 140000 {
+		compression = "a.attcompression::text"
+	}
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT a.attname::text, format_type(a.atttypid, a.atttypmod), a.attstorage::text, t.typstorage::text,
+			%s, s.avg_width
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_stats s ON s.schemaname = $1 AND s.tablename = $2 AND s.attname = a.attname
+			AND s.inherited = (c.relkind = 'p')
+		WHERE a.attrelid = $3::text::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`, compression), schema, tableName, regclass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read columns: %v", err)
+	}
+	columns := []map[string]interface{}{}
+	for rows.Next() {
+		var name, typeName, storage, typeStorage, method string
+		var avgWidth *int32
+		if err := rows.Scan(&name, &typeName, &storage, &typeStorage, &method, &avgWidth); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		column := map[string]interface{}{
+			"name":         name,
+			"type":         typeName,
+			"storage":      storageStrategies[storage],
+			"type_default": storage == typeStorage,
+		}
+		if method, ok := compressionMethods[method]; ok {
+			column["compression"] = method
+		}
+		if avgWidth != nil {
+			column["average_width"] = *avgWidth
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	rows, err = s.pool.Query(ctx, `
+		SELECT ic.relname::text, am.amname::text, COALESCE(ic.reloptions, '{}'), pg_relation_size(ic.oid)
+		FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		WHERE i.indrelid = $1::text::regclass
+		ORDER BY ic.relname
+	`, regclass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read indexes: %v", err)
+	}
+	indexes := []map[string]interface{}{}
+	for rows.Next() {
+		var name, method string
+		var indexOptions []string
+		var size int64
+		if err := rows.Scan(&name, &method, &indexOptions, &size); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		parsed := parseReloptions(indexOptions)
+		index := map[string]interface{}{
+			"name":       name,
+			"method":     method,
+			"size_bytes": size,
+			"options":    parsed,
+		}
+		if fillfactor, ok := indexFillfactors[method]; ok {
+			index["fillfactor"] = reloptionInt(parsed, "fillfactor", fillfactor)
+		}
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	parsed := parseReloptions(options)
+	fillfactor := reloptionInt(parsed, "fillfactor", 100)
+	response := map[string]interface{}{
+		"table":      table,
+		"kind":       relationKinds[kind],
+		"fillfactor": fillfactor,
+		"options":    parsed,
+		"size": map[string]interface{}{
+			"heap_bytes":  heapBytes,
+			"toast_bytes": toastBytes,
+			"index_bytes": indexBytes,
+			"total_bytes": totalBytes,
+		},
+		"updates": map[string]interface{}{
+			"total":     updates,
+			"hot":       hotUpdates,
+			"hot_ratio": ratio(float64(hotUpdates), float64(updates)),
+		},
+		"columns": columns,
+		"indexes": indexes,
+	}
+	if hasToast {
+		response["toast"] = map[string]interface{}{
+			"name":    qualifiedName("pg_toast", toastName),
+			"options": parseReloptions(toastOptions),
+		}
+	}
+
+	var warnings []string
+	if updates >= hotHintUpdates && fillfactor == 100 && ratio(float64(hotUpdates), float64(updates)) < 0.5 {
+		warnings = append(warnings, fmt.Sprintf(s.localize("Only %.0f%% of the updates to %s were HOT, a fillfactor below 100 leaves room on each page for the new row versions"),
+			100*ratio(float64(hotUpdates), float64(updates)), table))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStorageInfo(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE stored_docs (id int PRIMARY KEY, body text) WITH (fillfactor = 70);
+		ALTER TABLE stored_docs ALTER COLUMN body SET STORAGE EXTERNAL;
+		INSERT INTO stored_docs SELECT i, repeat(md5(i::text), 500) FROM generate_series(1, 20) i;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE stored_docs")
+
+	args := StorageInfoArgs{TableName: "stored_docs"}
+	result, data, err := testServer.StorageInfo(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("StorageInfo failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+	response := data.(map[string]interface{})
+	if response["fillfactor"] != 70 {
+		t.Errorf("Expected fillfactor 70, got %v", response["fillfactor"])
+	}
+	if toast := response["size"].(map[string]interface{})["toast_bytes"].(int64); toast == 0 {
+		t.Error("Expected the uncompressed bodies to be stored in TOAST")
+	}
+	for _, column := range response["columns"].([]map[string]interface{}) {
+		if column["name"] == "body" && (column["storage"] != "external" || column["type_default"] != false) {
+			t.Errorf("Expected body to use external storage, got %v", column)
+		}
+		if column["name"] == "id" && column["storage"] != "plain" {
+			t.Errorf("Expected id to use plain storage, got %v", column)
+		}
+	}
+	indexes := response["indexes"].([]map[string]interface{})
+	if len(indexes) != 1 || indexes[0]["fillfactor"] != 90 {
+		t.Errorf("Expected the primary key with the btree default fillfactor, got %v", indexes)
+	}
+}
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultSuggestMinRows = 1000
+	defaultSuggestLimit   = 20
+	// suggestStatementLimit is how many of the most expensive statements of
+	// pg_stat_statements are read for predicates.
+	suggestStatementLimit = 200
+)
+
+type SuggestIndexesArgs struct {
+	Schema            string `json:"schema,omitempty" jsonschema:"Only suggest indexes for tables of this schema (default: every schema)"`
+	MinRows           int64  `json:"min_rows,omitempty" jsonschema:"Leave out tables with fewer live rows than this, sequential scans of small tables are cheap (default: 1000)"`
+	IncludeStatements bool   `json:"include_statements,omitempty" jsonschema:"Also read the WHERE and JOIN predicates of the most expensive pg_stat_statements entries to suggest indexes on the columns they filter (default: false)"`
+	Limit             int    `json:"limit,omitempty" jsonschema:"Maximum number of suggestions (default: 20)"`
+}
+
+// sqlPredicate is a column compared with a parameter or constant in a
+// statement. Qualifier is the table name or alias written before the column.
+type sqlPredicate struct {
+	Qualifier string
+	Column    string
+	Equality  bool
+}
+
+// predicateOperators can compare an indexed column with a value. Equality
+// operators let further index columns be used, the others end the usable
+// prefix of an index.
+var predicateOperators = map[string]bool{
+	"=": true, "<": false, ">": false, "<=": false, ">=": false,
+	"IN": true, "BETWEEN": false, "IS": true,
+}
+
+// statementPredicates finds the columns a statement compares with parameters
+// or constants, as in WHERE a.email = $1 AND created_at > $2. Comparisons
+// between two columns, expressions over columns and the assignments of SET
+// are left out: no plain column index serves them.
+func statementPredicates(query string) []sqlPredicate {
+	tokens := sqlTokens(query)
+	var predicates []sqlPredicate
+	assigning := false
+	for i, token := range tokens {
+		switch token.Text {
+		case "SET":
+			assigning = true
+		case "WHERE", "FROM", "RETURNING":
+			assigning = false
+		}
+		// a column starts an operand: not the second part of a name, the type
+		// of a cast or an assignment
+		if assigning || (i > 0 && (tokens[i-1].Text == "." || tokens[i-1].Text == ":")) {
+			continue
+		}
+		name := identifierAt(tokens, i)
+		if len(name) == 0 || len(name) > 2 || predicateKeywords[name[len(name)-1]] {
+			continue
+		}
+		end := i + 2*len(name) - 1
+		if end >= len(tokens) {
+			continue
+		}
+
+		operator := tokens[end].Text
+		valueAt := end + 1
+		if (operator == "<" || operator == ">") && valueAt < len(tokens) && tokens[valueAt].Text == "=" {
+			operator += "="
+			valueAt++
+		}
+		equality, ok := predicateOperators[operator]
+		if !ok || valueAt >= len(tokens) {
+			continue
+		}
+		value := tokens[valueAt]
+		switch {
+		case operator == "IS" && value.Text != "NULL":
+			continue
+		case operator == "IN" && value.Text == "(" && valueAt+1 < len(tokens):
+			value = tokens[valueAt+1]
+		case operator == "=" && value.Text == "ANY" && valueAt+2 < len(tokens) && tokens[valueAt+1].Text == "(":
+			// = ANY($1) is the array form of IN
+			value = tokens[valueAt+2]
+		}
+		if !predicateValue(value) {
+			continue
+		}
+
+		predicate := sqlPredicate{Column: name[len(name)-1], Equality: equality}
+		if len(name) == 2 {
+			predicate.Qualifier = name[0]
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates
+}
+
+// predicateKeywords read like columns before an operator but aren't.
+var predicateKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "where": true, "on": true, "when": true,
+	"then": true, "else": true, "select": true, "set": true, "case": true,
+}
+
+// predicateValue reports whether a token is a parameter (as pg_stat_statements
+// normalizes constants to) or a constant.
+func predicateValue(token sqlToken) bool {
+	switch {
+	case strings.HasPrefix(token.Text, "$") && token.Text != "$$":
+		return true
+	case token.Text == "'":
+		return true
+	case token.Text != "" && token.Text[0] >= '0' && token.Text[0] <= '9':
+		return true
+	}
+	return token.Word && (token.Text == "NULL" || token.Text == "TRUE" || token.Text == "FALSE")
+}
+
+// statementAliases maps the names a statement refers to its relations by,
+// their aliases and unqualified table names, to the relation names.
+func statementAliases(query string) map[string][]string {
+	relations := statementRelations(query)
+	tokens := sqlTokens(query)
+	aliases := make(map[string][]string)
+	for _, relation := range relations {
+		aliases[relation[len(relation)-1]] = relation
+	}
+	for i := range tokens {
+		name := identifierAt(tokens, i)
+		if len(name) == 0 || !slices.ContainsFunc(relations, func(relation []string) bool { return slices.Equal(relation, name) }) {
+			continue
+		}
+		if i == 0 || !(tokens[i-1].Text == "FROM" || tokens[i-1].Text == "JOIN" || tokens[i-1].Text == "," || tokens[i-1].Text == "ONLY" || tokens[i-1].Text == "UPDATE") {
+			continue
+		}
+		j := i + 2*len(name) - 1
+		if j < len(tokens) && tokens[j].Text == "AS" {
+			j++
+		}
+		if alias := identifierAt(tokens, j); len(alias) == 1 && (!tokens[j].Word || !aliasEndKeywords[tokens[j].Text]) {
+			aliases[alias[0]] = name
+		}
+	}
+	return aliases
+}
+
+// aliasEndKeywords can follow a relation name in place of an alias.
+var aliasEndKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true, "ON": true, "USING": true, "SET": true, "GROUP": true,
+	"ORDER": true, "LIMIT": true, "OFFSET": true, "FOR": true, "UNION": true, "INTERSECT": true,
+	"EXCEPT": true, "RETURNING": true, "WINDOW": true, "HAVING": true, "TABLESAMPLE": true,
+	"LATERAL": true, "FETCH": true,
+}
+
+// indexCandidate is a suggested index: its columns in index order and why.
+type indexCandidate struct {
+	Schema  string
+	Table   string
+	Columns []string
+	Score   float64
+	Sources []string
+	Reasons []string
+}
+
+func (c *indexCandidate) key() string {
+	return c.Schema + "." + c.Table + "(" + strings.Join(c.Columns, ",") + ")"
+}
+
+// candidateCovered reports whether an index with these leading columns serves
+// the candidate: equality columns may come in any order, as long as they all
+// lead the index.
+func candidateCovered(columns []string, equalityColumns int, index []string) bool {
+	if len(index) < len(columns) {
+		return false
+	}
+	for i, column := range columns[equalityColumns:] {
+		if index[equalityColumns+i] != column {
+			return false
+		}
+	}
+	for _, column := range columns[:equalityColumns] {
+		if !slices.Contains(index[:equalityColumns], column) {
+			return false
+		}
+	}
+	return true
+}
+
+// tableStats are the pg_stat_user_tables counters a suggestion is ranked by.
+type tableStats struct {
+	Schema       string
+	Table        string
+	LiveRows     int64
+	SeqScans     int64
+	SeqRowsRead  int64
+	IndexScans   int64
+	Updates      int64
+	Deletes      int64
+	SizeBytes    int64
+	Columns      []string
+	IndexColumns [][]string
+}
+
+func (s *serverState) SuggestIndexes(ctx context.Context, req *mcp.CallToolRequest, args SuggestIndexesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	minRows := args.MinRows
+	if minRows <= 0 {
+		minRows = defaultSuggestMinRows
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+
+	tables, err := s.suggestTableStats(ctx, args.Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make(map[string]*indexCandidate)
+	add := func(candidate indexCandidate, equalityColumns int) {
+		stats := tables[candidate.Schema+"."+candidate.Table]
+		if stats == nil || stats.LiveRows < minRows {
+			return
+		}
+		for _, index := range stats.IndexColumns {
+			if candidateCovered(candidate.Columns, equalityColumns, index) {
+				return
+			}
+		}
+		if existing, ok := candidates[candidate.key()]; ok {
+			existing.Score += candidate.Score
+			for _, source := range candidate.Sources {
+				if !slices.Contains(existing.Sources, source) {
+					existing.Sources = append(existing.Sources, source)
+				}
+			}
+			existing.Reasons = append(existing.Reasons, candidate.Reasons...)
+			return
+		}
+		candidates[candidate.key()] = &candidate
+	}
+
+	foreignKeys, err := s.unindexedForeignKeys(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, fk := range foreignKeys {
+		stats := tables[fk.Schema+"."+fk.Table]
+		if stats == nil {
+			continue
+		}
+		// each delete or key update of a referenced row looks for referencing rows
+		var parentChanges int64
+		if parent := tables[fk.ReferencedSchema+"."+fk.ReferencedTable]; parent != nil {
+			parentChanges = parent.Updates + parent.Deletes
+		}
+		add(indexCandidate{
+			Schema:  fk.Schema,
+			Table:   fk.Table,
+			Columns: fk.Columns,
+			Score:   float64(parentChanges) * float64(stats.LiveRows),
+			Sources: []string{"foreign_key"},
+			Reasons: []string{fmt.Sprintf("Foreign key %s references %s without an index on its columns: every delete or key update there (%d so far) scans %s, and joins on the key can't use an index",
+				fk.Name, qualifiedName(fk.ReferencedSchema, fk.ReferencedTable), parentChanges, qualifiedName(fk.Schema, fk.Table))},
+		}, len(fk.Columns))
+	}
+
+	var warnings []string
+	statementsAnalyzed := 0
+	if args.IncludeStatements {
+		statements, warning, err := s.expensiveStatements(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if warning != "" {
+			warnings = append(warnings, s.localize(warning))
+		}
+		statementsAnalyzed = len(statements)
+		for _, statement := range statements {
+			for _, candidate := range statementCandidates(statement, tables) {
+				add(candidate.indexCandidate, candidate.equalityColumns)
+			}
+		}
+	}
+
+	suggestions := make([]*indexCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		suggestions = append(suggestions, candidate)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].key() < suggestions[j].key()
+	})
+	truncated := len(suggestions) > limit
+	if truncated {
+		suggestions = suggestions[:limit]
+	}
+
+	var results []map[string]interface{}
+	for rank, candidate := range suggestions {
+		quoted := make([]string, len(candidate.Columns))
+		for i, column := range candidate.Columns {
+			quoted[i] = pgx.Identifier{column}.Sanitize()
+		}
+		stats := tables[candidate.Schema+"."+candidate.Table]
+		results = append(results, map[string]interface{}{
+			"rank":             rank + 1,
+			"table":            qualifiedName(candidate.Schema, candidate.Table),
+			"columns":          candidate.Columns,
+			"create_index":     fmt.Sprintf("CREATE INDEX CONCURRENTLY ON %s (%s)", pgx.Identifier{candidate.Schema, candidate.Table}.Sanitize(), strings.Join(quoted, ", ")),
+			"sources":          candidate.Sources,
+			"reasons":          candidate.Reasons,
+			"score":            candidate.Score,
+			"live_rows":        stats.LiveRows,
+			"table_size_bytes": stats.SizeBytes,
+		})
+	}
+
+	// tables read mostly by sequential scans, whatever their predicates
+	var seqScanned []map[string]interface{}
+	for _, key := range sortedKeys(tables) {
+		stats := tables[key]
+		if stats.LiveRows < minRows || stats.SeqScans == 0 || stats.SeqScans <= stats.IndexScans {
+			continue
+		}
+		seqScanned = append(seqScanned, map[string]interface{}{
+			"table":             qualifiedName(stats.Schema, stats.Table),
+			"seq_scans":         stats.SeqScans,
+			"seq_rows_read":     stats.SeqRowsRead,
+			"index_scans":       stats.IndexScans,
+			"avg_rows_per_scan": stats.SeqRowsRead / stats.SeqScans,
+			"live_rows":         stats.LiveRows,
+			"table_size_bytes":  stats.SizeBytes,
+		})
+	}
+	sort.SliceStable(seqScanned, func(i, j int) bool {
+		return seqScanned[i]["seq_rows_read"].(int64) > seqScanned[j]["seq_rows_read"].(int64)
+	})
+
+	response := map[string]interface{}{
+		"suggestions":     results,
+		"seq_scan_tables": seqScanned,
+		"min_rows":        minRows,
+		"ranking":         "score estimates the rows read by the sequential scans an index would avoid: referenced-row changes times table rows for foreign keys, calls (capped at the table's sequential scans) times table rows for statement predicates",
+	}
+	if args.IncludeStatements {
+		response["statements_analyzed"] = statementsAnalyzed
+	}
+	if truncated {
+		response["truncated"] = true
+	}
+	if len(seqScanned) > 0 && !args.IncludeStatements {
+		warnings = append(warnings, s.localize("Some tables are mostly read by sequential scans, include_statements finds the columns their queries filter on"))
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, warnings), data, err
+}
+
+// suggestTableStats reads the statistics and index columns of the allowed
+// tables, keyed by schema.table.
+func (s *serverState) suggestTableStats(ctx context.Context, schema string) (map[string]*tableStats, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT st.schemaname::text, st.relname::text, st.n_live_tup, COALESCE(st.seq_scan, 0), COALESCE(st.seq_tup_read, 0),
+			COALESCE(st.idx_scan, 0), st.n_tup_upd, st.n_tup_del, pg_table_size(st.relid),
+			ARRAY(
+				SELECT array_to_string(ARRAY(
+					SELECT COALESCE(a.attname::text, '')
+					FROM unnest(i.indkey::int2[]) WITH ORDINALITY k(attnum, ord)
+					LEFT JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum
+					WHERE k.ord <= i.indnkeyatts
+					ORDER BY k.ord
+				), ',')
+				FROM pg_index i
+				WHERE i.indrelid = st.relid AND i.indisvalid AND i.indpred IS NULL
+			),
+			ARRAY(
+				SELECT attname::text FROM pg_attribute
+				WHERE attrelid = st.relid AND attnum > 0 AND NOT attisdropped
+			)
+		FROM pg_stat_user_tables st
+		WHERE $1 = '' OR st.schemaname = $1
+	`, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %v", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*tableStats)
+	for rows.Next() {
+		var stats tableStats
+		var indexes []string
+		if err := rows.Scan(&stats.Schema, &stats.Table, &stats.LiveRows, &stats.SeqScans, &stats.SeqRowsRead, &stats.IndexScans, &stats.Updates, &stats.Deletes, &stats.SizeBytes, &indexes, &stats.Columns); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(stats.Schema, stats.Table) {
+			continue
+		}
+		// expression columns have no name and end the usable prefix
+		for _, index := range indexes {
+			columns := strings.Split(index, ",")
+			if i := slices.Index(columns, ""); i >= 0 {
+				columns = columns[:i]
+			}
+			stats.IndexColumns = append(stats.IndexColumns, columns)
+		}
+		tables[stats.Schema+"."+stats.Table] = &stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return tables, nil
+}
+
+type foreignKey struct {
+	Name             string
+	Schema           string
+	Table            string
+	Columns          []string
+	ReferencedSchema string
+	ReferencedTable  string
+}
+
+// unindexedForeignKeys lists the foreign keys whose columns lead no valid,
+// non-partial index of the referencing table.
+func (s *serverState) unindexedForeignKeys(ctx context.Context) ([]foreignKey, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT c.conname::text, n.nspname::text, t.relname::text,
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(c.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			rn.nspname::text, r.relname::text
+		FROM pg_constraint c
+		JOIN pg_class t ON t.oid = c.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class r ON r.oid = c.confrelid
+		JOIN pg_namespace rn ON rn.oid = r.relnamespace
+		WHERE c.contype = 'f'
+			AND NOT EXISTS (
+				SELECT 1 FROM pg_index i
+				WHERE i.indrelid = c.conrelid AND i.indisvalid AND i.indpred IS NULL
+					AND (i.indkey::int2[])[0:cardinality(c.conkey) - 1] @> c.conkey
+					AND (i.indkey::int2[])[0:cardinality(c.conkey) - 1] <@ c.conkey
+			)
+		ORDER BY 2, 3, 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %v", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []foreignKey
+	for rows.Next() {
+		var fk foreignKey
+		if err := rows.Scan(&fk.Name, &fk.Schema, &fk.Table, &fk.Columns, &fk.ReferencedSchema, &fk.ReferencedTable); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return foreignKeys, nil
+}
+
+// expensiveStatement is a pg_stat_statements entry.
+type expensiveStatement struct {
+	Query       string
+	Calls       int64
+	TotalTimeMs float64
+}
+
+// expensiveStatements reads the statements of the current database taking
+// the most time. Without pg_stat_statements it returns a warning instead.
+func (s *serverState) expensiveStatements(ctx context.Context) ([]expensiveStatement, string, error) {
+	var extensionSchema string
+	err := s.pool.QueryRow(ctx, `
+		SELECT n.nspname::text
+		FROM pg_extension e
+		JOIN pg_namespace n ON n.oid = e.extnamespace
+		WHERE e.extname = 'pg_stat_statements'
+	`).Scan(&extensionSchema)
+	if err == pgx.ErrNoRows {
+		return nil, "pg_stat_statements is not installed, suggestions only use table statistics and foreign keys", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up the pg_stat_statements extension: %v", err)
+	}
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get server version: %v", err)
+	}
+	// total_time was split into planning and execution time in 13
+	totalTime := "total_exec_time"
+	if versionNum < 130000 {
+		totalTime = "total_time"
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT query, calls, %[1]s
+		FROM %[2]s
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		ORDER BY %[1]s DESC
+		LIMIT %[3]d
+	`, totalTime, pgx.Identifier{extensionSchema, "pg_stat_statements"}.Sanitize(), suggestStatementLimit))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read pg_stat_statements: %v", err)
+	}
+	defer rows.Close()
+
+	var statements []expensiveStatement
+	for rows.Next() {
+		var statement expensiveStatement
+		var query *string
+		if err := rows.Scan(&query, &statement.Calls, &statement.TotalTimeMs); err != nil {
+			return nil, "", fmt.Errorf("failed to scan row: %v", err)
+		}
+		// other users' statements read as <insufficient privilege> without pg_read_all_stats
+		if query == nil || strings.HasPrefix(*query, "<") {
+			continue
+		}
+		statement.Query = *query
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("row iteration error: %v", err)
+	}
+	return statements, "", nil
+}
+
+// statementCandidate is an indexCandidate whose first equalityColumns
+// columns are compared for equality.
+type statementCandidate struct {
+	indexCandidate
+	equalityColumns int
+}
+
+// statementCandidates turns the predicates of a statement into one candidate
+// per table: its equality columns followed by at most one range column, the
+// longest prefix a B-tree index serves. Unqualified columns are attributed
+// to the table of the statement that has them.
+func statementCandidates(statement expensiveStatement, tables map[string]*tableStats) []statementCandidate {
+	if category := statementCategory(statement.Query); category != categoryRead && category != categoryDML {
+		return nil
+	}
+	predicates := statementPredicates(statement.Query)
+	if len(predicates) == 0 {
+		return nil
+	}
+
+	// resolve each name the statement refers to a relation by
+	relations := make(map[string]*tableStats)
+	var unique []*tableStats
+	for alias, name := range statementAliases(statement.Query) {
+		if stats := statementTable(name, tables); stats != nil {
+			relations[alias] = stats
+			if !slices.Contains(unique, stats) {
+				unique = append(unique, stats)
+			}
+		}
+	}
+
+	columns := make(map[*tableStats]map[string]bool)
+	for _, predicate := range predicates {
+		var stats *tableStats
+		if predicate.Qualifier != "" {
+			stats = relations[predicate.Qualifier]
+		} else {
+			var owners []*tableStats
+			for _, candidate := range unique {
+				if slices.Contains(candidate.Columns, predicate.Column) {
+					owners = append(owners, candidate)
+				}
+			}
+			if len(owners) == 1 {
+				stats = owners[0]
+			}
+		}
+		if stats == nil {
+			continue
+		}
+		if columns[stats] == nil {
+			columns[stats] = make(map[string]bool)
+		}
+		// a column compared both ways counts as equality
+		columns[stats][predicate.Column] = columns[stats][predicate.Column] || predicate.Equality
+	}
+
+	var candidates []statementCandidate
+	for stats, compared := range columns {
+		if stats.SeqScans == 0 {
+			continue
+		}
+		var equality, ranges []string
+		for column, isEquality := range compared {
+			if isEquality {
+				equality = append(equality, column)
+			} else {
+				ranges = append(ranges, column)
+			}
+		}
+		sort.Strings(equality)
+		sort.Strings(ranges)
+		indexColumns := equality
+		if len(ranges) > 0 {
+			indexColumns = append(indexColumns, ranges[0])
+		}
+		candidates = append(candidates, statementCandidate{
+			indexCandidate: indexCandidate{
+				Schema:  stats.Schema,
+				Table:   stats.Table,
+				Columns: indexColumns,
+				Score:   float64(min(statement.Calls, stats.SeqScans)) * float64(stats.LiveRows),
+				Sources: []string{"statements"},
+				Reasons: []string{fmt.Sprintf("Filtered on by a statement called %d times (%.0f ms in total): %s", statement.Calls, statement.TotalTimeMs, truncateStatement(statement.Query))},
+			},
+			equalityColumns: len(equality),
+		})
+	}
+	return candidates
+}
+
+// statementTable finds the table a relation name of a statement refers to.
+// Unqualified names are looked up in public first, then in the one other
+// schema that has such a table; the statement's search_path is not known.
+func statementTable(name []string, tables map[string]*tableStats) *tableStats {
+	if len(name) >= 2 {
+		return tables[name[len(name)-2]+"."+name[len(name)-1]]
+	}
+	if stats := tables["public."+name[0]]; stats != nil {
+		return stats
+	}
+	var found *tableStats
+	for _, stats := range tables {
+		if stats.Table == name[0] {
+			if found != nil {
+				return nil
+			}
+			found = stats
+		}
+	}
+	return found
+}
+
+// truncateStatement shortens a statement quoted in a reason.
+func truncateStatement(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	if runes := []rune(query); len(runes) > 200 {
+		return string(runes[:200]) + "..."
+	}
+	return query
+}
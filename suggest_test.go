@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStatementPredicates(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []sqlPredicate
+	}{
+		{
+			"SELECT * FROM users u WHERE u.email = $1 AND created_at >= $2",
+			[]sqlPredicate{{Qualifier: "u", Column: "email", Equality: true}, {Column: "created_at"}},
+		},
+		{
+			"SELECT p.title FROM posts p JOIN users u ON u.id = p.user_id WHERE p.user_id IN ($1, $2) AND lower(u.username) = $3",
+			[]sqlPredicate{{Qualifier: "p", Column: "user_id", Equality: true}},
+		},
+		{
+			"UPDATE orders SET status = $1, paid_at = now() WHERE customer_id = ANY($2) AND deleted_at IS NULL",
+			[]sqlPredicate{{Column: "customer_id", Equality: true}, {Column: "deleted_at", Equality: true}},
+		},
+		{
+			"SELECT * FROM events WHERE kind <> $1 AND payload IS NOT NULL AND at BETWEEN $2 AND $3",
+			[]sqlPredicate{{Column: "at"}},
+		},
+	}
+	for _, test := range tests {
+		if got := statementPredicates(test.query); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("statementPredicates(%q) = %+v, want %+v", test.query, got, test.want)
+		}
+	}
+}
+
+func TestStatementAliases(t *testing.T) {
+	aliases := statementAliases("SELECT * FROM public.posts AS p JOIN users u ON u.id = p.user_id, comments WHERE true")
+	want := map[string][]string{
+		"posts":    {"public", "posts"},
+		"p":        {"public", "posts"},
+		"users":    {"users"},
+		"u":        {"users"},
+		"comments": {"comments"},
+	}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("Unexpected aliases %v", aliases)
+	}
+}
+
+func TestCandidateCovered(t *testing.T) {
+	tests := []struct {
+		columns  []string
+		equality int
+		index    []string
+		want     bool
+	}{
+		{[]string{"user_id"}, 1, []string{"user_id", "created_at"}, true},
+		{[]string{"a", "b"}, 2, []string{"b", "a"}, true},
+		{[]string{"a", "b", "c"}, 2, []string{"b", "a", "c"}, true},
+		{[]string{"a", "c"}, 1, []string{"a", "b", "c"}, false},
+		{[]string{"user_id"}, 1, []string{"created_at", "user_id"}, false},
+		{[]string{"a", "b"}, 2, []string{"a"}, false},
+	}
+	for _, test := range tests {
+		if got := candidateCovered(test.columns, test.equality, test.index); got != test.want {
+			t.Errorf("candidateCovered(%v, %d, %v) = %v, want %v", test.columns, test.equality, test.index, got, test.want)
+		}
+	}
+}
+
+func TestStatementCandidates(t *testing.T) {
+	tables := map[string]*tableStats{
+		"public.orders":    {Schema: "public", Table: "orders", LiveRows: 10000, SeqScans: 50, Columns: []string{"id", "customer_id", "status", "created_at"}},
+		"public.customers": {Schema: "public", Table: "customers", LiveRows: 500, SeqScans: 0, Columns: []string{"id", "name"}},
+	}
+	statement := expensiveStatement{
+		Query: "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE status = $1 AND o.customer_id = $2 AND created_at > $3 AND c.name = $4",
+		Calls: 200,
+	}
+
+	candidates := statementCandidates(statement, tables)
+	// customers is never scanned sequentially
+	if len(candidates) != 1 {
+		t.Fatalf("Expected one candidate, got %+v", candidates)
+	}
+	candidate := candidates[0]
+	if candidate.Table != "orders" || !reflect.DeepEqual(candidate.Columns, []string{"customer_id", "status", "created_at"}) || candidate.equalityColumns != 2 {
+		t.Errorf("Expected equality columns before the range column, got %+v", candidate)
+	}
+	// calls are capped at the sequential scans
+	if candidate.Score != 50*10000 {
+		t.Errorf("Unexpected score %v", candidate.Score)
+	}
+
+	if candidates := statementCandidates(expensiveStatement{Query: "CREATE INDEX ON orders (status) WHERE status = 'open'"}, tables); len(candidates) != 0 {
+		t.Errorf("Expected DDL to be skipped, got %+v", candidates)
+	}
+}
+
+func TestSuggestIndexes(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE orders_unindexed (
+			id SERIAL PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users(id)
+		);
+		INSERT INTO orders_unindexed (user_id) SELECT (SELECT min(id) FROM users) FROM generate_series(1, 2000);
+		ANALYZE orders_unindexed;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE orders_unindexed")
+
+	args := SuggestIndexesArgs{}
+	result, data, err := testServer.SuggestIndexes(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("SuggestIndexes failed: %v %v", err, result)
+	}
+
+	found := false
+	for _, suggestion := range data.(map[string]interface{})["suggestions"].([]map[string]interface{}) {
+		if suggestion["table"] == "public.orders_unindexed" {
+			found = true
+			if suggestion["create_index"] != `CREATE INDEX CONCURRENTLY ON "public"."orders_unindexed" ("user_id")` {
+				t.Errorf("Unexpected statement %v", suggestion["create_index"])
+			}
+		}
+		// the test schema indexes its other foreign keys
+		if suggestion["table"] == "public.posts" {
+			t.Errorf("Unexpected suggestion %v", suggestion)
+		}
+	}
+	if !found {
+		t.Errorf("Expected an index on the foreign key of orders_unindexed, got %v", data)
+	}
+}
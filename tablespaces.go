@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultTablespaceRelations = 5
+
+type ListTablespacesArgs struct {
+	TopRelations int `json:"top_relations,omitempty" jsonschema:"Largest tables, materialized views and indexes to list per tablespace (default: 5)"`
+}
+
+// ListTablespaces lists the tablespaces with their location, size and cost
+// options, and the largest relations of the current database stored in
+// each, to see what sits on fast and slow storage. Relations without a
+// tablespace of their own are counted under the database's default.
+func (s *serverState) ListTablespaces(ctx context.Context, req *mcp.CallToolRequest, args ListTablespacesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	top := args.TopRelations
+	if top <= 0 {
+		top = defaultTablespaceRelations
+	}
+
+	// pg_tablespace_size needs CREATE on the tablespace or pg_read_all_stats,
+	// except for the database's default tablespace
+	rows, err := s.pool.Query(ctx, `
+		SELECT t.oid::bigint, t.spcname::text, pg_get_userbyid(t.spcowner)::text,
+			pg_tablespace_location(t.oid), COALESCE(t.spcoptions, '{}'),
+			t.oid = d.dattablespace,
+			CASE WHEN t.oid = d.dattablespace OR has_tablespace_privilege(t.oid, 'CREATE')
+				OR pg_has_role('pg_read_all_stats', 'USAGE')
+				THEN pg_tablespace_size(t.oid) END
+		FROM pg_tablespace t
+		CROSS JOIN pg_database d
+		WHERE d.datname = current_database()
+		ORDER BY t.spcname
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tablespaces: %v", err)
+	}
+	tablespaces := []map[string]interface{}{}
+	byOID := make(map[int64]map[string]interface{})
+	for rows.Next() {
+		var oid int64
+		var name, owner, location string
+		var options []string
+		var isDefault bool
+		var size *int64
+		if err := rows.Scan(&oid, &name, &owner, &location, &options, &isDefault, &size); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		tablespace := map[string]interface{}{
+			"name":             name,
+			"owner":            owner,
+			"location":         location,
+			"options":          parseReloptions(options),
+			"database_default": isDefault,
+			"relation_count":   0,
+			"relations_bytes":  int64(0),
+			"relations":        []map[string]interface{}{},
+		}
+		// pg_default and pg_global live in the data directory
+		if location == "" {
+			tablespace["location"] = "data directory"
+		}
+		if size != nil {
+			tablespace["size_bytes"] = *size
+		}
+		tablespaces = append(tablespaces, tablespace)
+		byOID[oid] = tablespace
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	// reltablespace 0 is the database's default tablespace
+	rows, err = s.pool.Query(ctx, `
+		SELECT COALESCE(NULLIF(c.reltablespace, 0), d.dattablespace)::bigint, n.nspname::text, c.relname::text, c.relkind::text,
+			CASE WHEN c.relkind = 'i' THEN pg_relation_size(c.oid) ELSE pg_table_size(c.oid) END AS size
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		CROSS JOIN pg_database d
+		WHERE d.datname = current_database()
+			AND c.relkind IN ('r', 'm', 'i')
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND n.nspname NOT LIKE 'pg_toast%'
+		ORDER BY size DESC, n.nspname, c.relname
+	`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list relations: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var oid, size int64
+		var schema, name, kind string
+		if err := rows.Scan(&oid, &schema, &name, &kind, &size); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		tablespace := byOID[oid]
+		if tablespace == nil || !s.relationAllowed(schema, name) {
+			continue
+		}
+		tablespace["relation_count"] = tablespace["relation_count"].(int) + 1
+		tablespace["relations_bytes"] = tablespace["relations_bytes"].(int64) + size
+		if relations := tablespace["relations"].([]map[string]interface{}); len(relations) < top {
+			kindName := relationKinds[kind]
+			if kind == "i" {
+				kindName = "index"
+			}
+			tablespace["relations"] = append(relations, map[string]interface{}{
+				"name":       qualifiedName(schema, name),
+				"kind":       kindName,
+				"size_bytes": size,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	return returnJSONResult(map[string]interface{}{
+		"tablespaces": tablespaces,
+		"note":        "relation_count, relations_bytes and relations cover the current database only, size_bytes every database",
+	})
+}
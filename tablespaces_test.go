@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestListTablespaces(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE spaced_items (id int PRIMARY KEY, payload text);
+		INSERT INTO spaced_items SELECT i, repeat('x', 200) FROM generate_series(1, 20000) i;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE spaced_items")
+
+	args := ListTablespacesArgs{TopRelations: 50}
+	result, data, err := testServer.ListTablespaces(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("ListTablespaces failed: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	var found bool
+	for _, tablespace := range data.(map[string]interface{})["tablespaces"].([]map[string]interface{}) {
+		if tablespace["name"] != "pg_default" {
+			continue
+		}
+		if tablespace["database_default"] != true || tablespace["size_bytes"].(int64) == 0 {
+			t.Errorf("Expected pg_default to be the sized default tablespace, got %v", tablespace)
+		}
+		for _, relation := range tablespace["relations"].([]map[string]interface{}) {
+			found = found || relation["name"] == "public.spaced_items"
+		}
+	}
+	if !found {
+		t.Error("Expected spaced_items among the relations in pg_default")
+	}
+}
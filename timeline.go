@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultTimelineHours  = 24
+	defaultTimelineEvents = 200
+)
+
+type EventTimelineArgs struct {
+	Hours int `json:"hours,omitempty" jsonschema:"How many hours back to look (default: 24)"`
+	Limit int `json:"limit,omitempty" jsonschema:"Maximum number of events, the most recent are kept (default: 200)"`
+}
+
+type timelineEvent struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Source string    `json:"source"`
+	Detail string    `json:"detail"`
+}
+
+// timelineSources are catalog queries returning (time, type, detail) rows
+// for events since $1. Postgres only keeps the latest occurrence of most of
+// them, so the timeline is as detailed as the statistics views allow.
+var timelineSources = []struct {
+	Name  string
+	Query string
+}{
+	{"server", `
+		SELECT pg_postmaster_start_time(), 'server_start', 'PostgreSQL ' || current_setting('server_version') || ' started'
+		WHERE pg_postmaster_start_time() >= $1
+		UNION ALL
+		SELECT pg_conf_load_time(), 'config_reload',
+			'Configuration reloaded' || COALESCE(', pending restart: ' || (
+				SELECT string_agg(name, ', ' ORDER BY name) FROM pg_settings WHERE pending_restart
+			), '')
+		WHERE pg_conf_load_time() >= $1
+			AND pg_conf_load_time() > pg_postmaster_start_time() + interval '1 second'
+	`},
+	{"maintenance", `
+		SELECT event_time, event_type, schemaname || '.' || relname
+		FROM pg_stat_user_tables,
+			LATERAL (VALUES
+				(last_autovacuum, 'autovacuum'),
+				(last_autoanalyze, 'autoanalyze'),
+				(last_vacuum, 'vacuum'),
+				(last_analyze, 'analyze')
+			) AS runs(event_time, event_type)
+		WHERE event_time >= $1
+	`},
+	{"replication", `
+		SELECT backend_start, 'replica_connected',
+			COALESCE(application_name, '') || ' from ' || COALESCE(client_addr::text, 'local socket') || ' (' || state || ', ' || sync_state || ')'
+		FROM pg_stat_replication
+		WHERE backend_start >= $1
+		UNION ALL
+		SELECT last_msg_receipt_time, 'wal_receiver', 'Last message from primary ' || COALESCE(sender_host, '') || ' (' || status || ')'
+		FROM pg_stat_wal_receiver
+		WHERE last_msg_receipt_time >= $1
+	`},
+	{"archiver", `
+		SELECT last_failed_time, 'archive_failed', 'Failed to archive ' || COALESCE(last_failed_wal, 'WAL segment')
+		FROM pg_stat_archiver
+		WHERE last_failed_time >= $1
+	`},
+	{"statistics", `
+		SELECT stats_reset, 'stats_reset', 'Statistics reset for database ' || datname
+		FROM pg_stat_database
+		WHERE datname = current_database() AND stats_reset >= $1
+	`},
+}
+
+// checkpointQuery reads checkpoint counters, which moved from pg_stat_bgwriter
+// to pg_stat_checkpointer in PostgreSQL 17.
+func checkpointQuery(versionNum int) string {
+	if versionNum >= 170000 {
+		return "SELECT num_timed, num_requested, stats_reset FROM pg_stat_checkpointer"
+	}
+	return "SELECT checkpoints_timed, checkpoints_req, stats_reset FROM pg_stat_bgwriter"
+}
+
+func (s *serverState) GetEventTimeline(ctx context.Context, req *mcp.CallToolRequest, args EventTimelineArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	hours := args.Hours
+	if hours <= 0 {
+		hours = defaultTimelineHours
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultTimelineEvents
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var events []timelineEvent
+	var unavailable []string
+	for _, source := range timelineSources {
+		rows, err := s.pool.Query(ctx, source.Query, since)
+		if err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("%s: %v", source.Name, err))
+			continue
+		}
+		for rows.Next() {
+			event := timelineEvent{Source: source.Name}
+			if err := rows.Scan(&event.Time, &event.Type, &event.Detail); err != nil {
+				rows.Close()
+				return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+			}
+			// maintenance events name the table they ran on
+			if source.Name == "maintenance" && !s.qualifiedAllowed(event.Detail) {
+				continue
+			}
+			events = append(events, event)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			unavailable = append(unavailable, fmt.Sprintf("%s: %v", source.Name, err))
+		}
+	}
+
+	// changes applied through this server are the audit feed for DDL and writes
+	s.sessions.mu.Lock()
+	logs := make([]*sessionLog, 0, len(s.sessions.logs))
+	for _, history := range s.sessions.logs {
+		logs = append(logs, history)
+	}
+	s.sessions.mu.Unlock()
+	for _, history := range logs {
+		for _, event := range history.snapshot() {
+			if event.Category != "change" || event.Time.Before(since) {
+				continue
+			}
+			events = append(events, timelineEvent{
+				Time:   event.Time,
+				Type:   event.Tool,
+				Source: "mcp_changes",
+				Detail: event.Summary,
+			})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	truncated := len(events) > limit
+	if truncated {
+		events = events[len(events)-limit:]
+	}
+
+	response := map[string]interface{}{
+		"since":     since,
+		"events":    events,
+		"truncated": truncated,
+	}
+
+	// checkpoints have no per-event timestamps, only counters since the last reset
+	var timed, requested int64
+	var statsReset *time.Time
+	versionNum, err := s.serverVersionNum(ctx)
+	if err == nil {
+		err = s.pool.QueryRow(ctx, checkpointQuery(versionNum)).Scan(&timed, &requested, &statsReset)
+	}
+	if err != nil {
+		unavailable = append(unavailable, fmt.Sprintf("checkpoints: %v", err))
+	} else {
+		checkpoints := map[string]interface{}{
+			"timed":       timed,
+			"requested":   requested,
+			"stats_reset": statsReset,
+		}
+		if requested > timed {
+			checkpoints["warning"] = "Most checkpoints were requested rather than timed, WAL volume is forcing them (max_wal_size may be too small for the write load)"
+		}
+		response["checkpoints"] = checkpoints
+	}
+
+	if len(unavailable) > 0 {
+		response["unavailable"] = unavailable
+	}
+	return returnJSONResult(response)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetEventTimeline(t *testing.T) {
+	ctx := context.Background()
+
+	if _, err := testServer.pool.Exec(ctx, "ANALYZE users"); err != nil {
+		t.Fatalf("Failed to analyze users: %v", err)
+	}
+
+	args := EventTimelineArgs{Hours: 1}
+	result, data, err := testServer.GetEventTimeline(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("GetEventTimeline failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	response := data.(map[string]interface{})
+	events := response["events"].([]timelineEvent)
+
+	foundStart, foundAnalyze := false, false
+	for i, event := range events {
+		if i > 0 && event.Time.Before(events[i-1].Time) {
+			t.Errorf("Expected events in chronological order, got %v before %v", events[i-1], event)
+		}
+		foundStart = foundStart || event.Type == "server_start"
+		foundAnalyze = foundAnalyze || (event.Type == "analyze" && event.Detail == "public.users")
+	}
+	if !foundStart {
+		t.Error("Expected the embedded server start within the last hour")
+	}
+	if !foundAnalyze {
+		t.Error("Expected the manual ANALYZE of public.users")
+	}
+
+	if _, ok := response["checkpoints"]; !ok {
+		t.Errorf("Expected checkpoint counters, unavailable: %v", response["unavailable"])
+	}
+
+	if checkpointQuery(160000) == checkpointQuery(170000) {
+		t.Error("Expected checkpoint counters to be read from pg_stat_bgwriter before PostgreSQL 17")
+	}
+}
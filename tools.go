@@ -4,15 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-var pool *pgxpool.Pool
-
 func getExplicitBool(rawArgs map[string]interface{}, key string, argValue, defaultValue bool) bool {
 	if _, exists := rawArgs[key]; exists {
 		return argValue
@@ -20,6 +18,19 @@ func getExplicitBool(rawArgs map[string]interface{}, key string, argValue, defau
 	return defaultValue
 }
 
+// getRawArgs returns the untyped request arguments, used to tell an explicit
+// false apart from an omitted boolean
+func getRawArgs(req *mcp.CallToolRequest) map[string]interface{} {
+	var rawArgs map[string]interface{}
+	if req != nil {
+		json.Unmarshal(req.Params.Arguments, &rawArgs)
+	}
+	if rawArgs == nil {
+		rawArgs = make(map[string]interface{})
+	}
+	return rawArgs
+}
+
 func getSchema(schema string) string {
 	if schema == "" {
 		return "public"
@@ -27,6 +38,14 @@ func getSchema(schema string) string {
 	return schema
 }
 
+// serverVersionNum returns the connected server's version as a number such
+// as 170005, for picking between catalog layouts.
+func (s *serverState) serverVersionNum(ctx context.Context) (int, error) {
+	var versionNum int
+	err := s.pool.QueryRow(ctx, "SELECT current_setting('server_version_num')::int").Scan(&versionNum)
+	return versionNum, err
+}
+
 func returnJSONResult(data interface{}) (*mcp.CallToolResult, any, error) {
 	jsonData, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
@@ -39,6 +58,31 @@ func returnJSONResult(data interface{}) (*mcp.CallToolResult, any, error) {
 	}, data, nil
 }
 
+func (s *serverState) returnErrorResult(format string, a ...interface{}) (*mcp.CallToolResult, any, error) {
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf(s.localize(format), a...)},
+		},
+		IsError: true,
+	}, nil, nil
+}
+
+func (s *serverState) returnWritesDisabled(tool string) (*mcp.CallToolResult, any, error) {
+	return s.returnErrorResult("%s modifies the database and is disabled. Set ALLOW_WRITES=true to enable it", tool)
+}
+
+// withWarnings appends warnings as a separate text block, leaving the result
+// itself untouched for clients that parse it
+func (s *serverState) withWarnings(result *mcp.CallToolResult, warnings []string) *mcp.CallToolResult {
+	if result == nil || len(warnings) == 0 {
+		return result
+	}
+	result.Content = append(result.Content, &mcp.TextContent{
+		Text: s.localize("Warnings:") + "\n- " + strings.Join(warnings, "\n- "),
+	})
+	return result
+}
+
 func addOptionalString(m map[string]interface{}, key string, value *string) {
 	if value != nil {
 		m[key] = *value
@@ -46,7 +90,30 @@ func addOptionalString(m map[string]interface{}, key string, value *string) {
 }
 
 type QueryArgs struct {
-	Query string `json:"query" jsonschema:"SQL query to execute"`
+	Query    string `json:"query" jsonschema:"SQL query to execute"`
+	Expanded bool   `json:"expanded,omitempty" jsonschema:"Return a single row in expanded key/value layout, one column per line with long values in full (default: false)"`
+	Role     string `json:"role,omitempty" jsonschema:"Run the query as this role (SET LOCAL ROLE), to check what a less privileged role can see"`
+
+	GeometryFormat string `json:"geometry_format,omitempty" jsonschema:"How PostGIS geometry and geography values are returned: geojson or wkt (default: geojson)"`
+
+	Dedupe           bool     `json:"dedupe,omitempty" jsonschema:"Group duplicate rows and return the first row of each group with the group's size in a _count column (default: false)"`
+	DedupeColumns    []string `json:"dedupe_columns,omitempty" jsonschema:"Rows are duplicates when they agree on these columns (default: every column). Implies dedupe"`
+	DedupeSimilarity float64  `json:"dedupe_similarity,omitempty" jsonschema:"Also group text values at least this similar, from 0 to 1 (e.g. 0.9), ignoring case, punctuation and spacing. Implies dedupe (default: 1, exact matches)"`
+
+	IsolationLevel string `json:"isolation_level,omitempty" jsonschema:"Transaction isolation level: read_committed, repeatable_read or serializable (default: the server's). Serialization failures at the last two are retried automatically"`
+
+	JSONFormat string   `json:"json_format,omitempty" jsonschema:"How json and jsonb values are returned: nested, decoded into the result, or string, as their JSON text (default: nested). Values over MAX_JSON_BYTES are cut off either way"`
+	JSONPaths  []string `json:"json_paths,omitempty" jsonschema:"Only return these paths of json and jsonb values, such as [\"$.id\", \"$.items[0].status\"], as an object keyed by path"`
+
+	NumericFormat string `json:"numeric_format,omitempty" jsonschema:"How numeric values are returned: string, exact to the last digit, or float, which loses precision beyond about 15 significant digits (default: string)"`
+
+	IntervalFormat string `json:"interval_format,omitempty" jsonschema:"How interval values are returned: iso8601, as durations such as P1Y2M3DT4H5M6S, or seconds, as their total length with months of 30 days (default: iso8601)"`
+
+	BinaryFormat string `json:"binary_format,omitempty" jsonschema:"How bytea values are returned: base64 or hex, as an object with the value's length and data cut off at MAX_BINARY_BYTES, or omit to leave binary columns out (default: base64)"`
+
+	DryRun bool `json:"dry_run,omitempty" jsonschema:"Run a write statement the query policy allows in a transaction that is rolled back, returning its affected row count and RETURNING rows. Triggers still fire, sequences advance and locks are taken until the rollback, so it requires ALLOW_WRITES and is refused for statements that need approval (default: false)"`
+
+	ChunkRows int `json:"chunk_rows,omitempty" jsonschema:"Read the rows through a cursor this many at a time and return each chunk as its own JSON array, followed by a summary, for results too large to collect at once. Reading stops at MAX_RESULT_BYTES (default: off)"`
 }
 
 type TableListArgs struct {
@@ -54,17 +121,17 @@ type TableListArgs struct {
 }
 
 type TableSchemaArgs struct {
-	TableName string `json:"table_name" jsonschema:"Name of the table"`
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
 	Schema    string `json:"schema" jsonschema:"Schema name (default: public)"`
 }
 
 type TableConstraintsArgs struct {
-	TableName string `json:"table_name" jsonschema:"Name of the table"`
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
 	Schema    string `json:"schema" jsonschema:"Schema name (default: public)"`
 }
 
 type TableIndexesArgs struct {
-	TableName string `json:"table_name" jsonschema:"Name of the table"`
+	TableName string `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
 	Schema    string `json:"schema" jsonschema:"Schema name (default: public)"`
 }
 
@@ -77,26 +144,112 @@ type ExplainAnalyzeArgs struct {
 	Timing  bool   `json:"timing,omitempty" jsonschema:"Include actual timing information (default: true)"`
 	Summary bool   `json:"summary,omitempty" jsonschema:"Include summary information (default: true)"`
 	Format  string `json:"format,omitempty" jsonschema:"Output format: text, json, xml, or yaml (default: json)"`
+	Render  string `json:"render,omitempty" jsonschema:"Set to tree to get a compact text tree of the plan instead of raw EXPLAIN output: one line per node with actual against estimated rows, time and buffer hits, flagging misestimates. Takes precedence over format"`
+
+	// options of newer servers, left out with a warning on older ones
+	GenericPlan bool `json:"generic_plan,omitempty" jsonschema:"Plan the statement without parameter values, so queries with $1 placeholders can be explained as prepared statements would run them. Implies analyze false (PostgreSQL 16+, default: false)"`
+	Settings    bool `json:"settings,omitempty" jsonschema:"Include the planner settings that differ from their defaults (PostgreSQL 12+, default: false)"`
+	WAL         bool `json:"wal,omitempty" jsonschema:"Include WAL records, full page images and bytes generated, with analyze (PostgreSQL 13+, default: false)"`
+	Memory      bool `json:"memory,omitempty" jsonschema:"Include the memory used by the planner (PostgreSQL 17+, default: false)"`
+
+	Role              string `json:"role,omitempty" jsonschema:"Run the statement as this role (SET LOCAL ROLE)"`
+	AllowWriteAnalyze bool   `json:"allow_write_analyze,omitempty" jsonschema:"Run ANALYZE on statements that modify data. They are still rolled back but fire triggers and take locks (default: false, plain EXPLAIN)"`
 }
 
-func ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
+	level, ok := txIsoLevel(args.IsolationLevel)
+	if !ok {
+		return s.returnErrorResult("Unknown isolation level %q, use read_committed, repeatable_read or serializable", args.IsolationLevel)
+	}
+	if args.GeometryFormat == "" {
+		args.GeometryFormat = "geojson"
+	}
+	if !geometryFormats[args.GeometryFormat] {
+		return s.returnErrorResult("Unknown geometry_format %q, use geojson or wkt", args.GeometryFormat)
+	}
+	if args.JSONFormat == "" {
+		args.JSONFormat = "nested"
+	}
+	if !jsonFormats[args.JSONFormat] {
+		return s.returnErrorResult("Unknown json_format %q, use nested or string", args.JSONFormat)
+	}
+	for _, path := range args.JSONPaths {
+		if _, err := parseJSONPath(path); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+	if args.NumericFormat == "" {
+		args.NumericFormat = "string"
+	}
+	if !numericFormats[args.NumericFormat] {
+		return s.returnErrorResult("Unknown numeric_format %q, use string or float", args.NumericFormat)
+	}
+	if args.IntervalFormat == "" {
+		args.IntervalFormat = "iso8601"
+	}
+	if !intervalFormats[args.IntervalFormat] {
+		return s.returnErrorResult("Unknown interval_format %q, use iso8601 or seconds", args.IntervalFormat)
+	}
+	if args.BinaryFormat == "" {
+		args.BinaryFormat = "base64"
+	}
+	if !binaryFormats[args.BinaryFormat] {
+		return s.returnErrorResult("Unknown binary_format %q, use base64, hex or omit", args.BinaryFormat)
+	}
+	if args.DedupeSimilarity < 0 || args.DedupeSimilarity > 1 {
+		return s.returnErrorResult("dedupe_similarity must be between 0 and 1")
+	}
+	if args.ChunkRows < 0 {
+		return s.returnErrorResult("chunk_rows must be positive")
+	}
+	if args.ChunkRows > 0 && (args.Expanded || args.Dedupe || len(args.DedupeColumns) > 0 || args.DedupeSimilarity > 0) {
+		return s.returnErrorResult("chunk_rows cannot be combined with expanded or dedupe, which need every row at once")
+	}
+
+	category := statementCategory(args.Query)
+	switch action := s.queryPolicyAction(category); {
+	case action == "block":
+		return s.returnErrorResult("%s statements are blocked by QUERY_POLICY, the query tool only runs them when the policy allows it", category)
+	case writeCategories[category]:
+		return s.retrySerializable(ctx, level, func() (*mcp.CallToolResult, any, error) {
+			return s.executePolicyWrite(ctx, req, args, category, action, level)
+		})
+	}
+	return s.retrySerializable(ctx, level, func() (*mcp.CallToolResult, any, error) {
+		return s.executeRead(ctx, req, args, level)
+	})
+}
+
+// executeRead runs a statement the policy lets through in a read-only
+// transaction.
+func (s *serverState) executeRead(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs, level pgx.TxIsoLevel) (*mcp.CallToolResult, any, error) {
 	// Start a read-only transaction to ensure only SELECT queries can be executed
-	tx, err := pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: level}, args.Role)
 	if err != nil {
-		return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: fmt.Sprintf("Failed to start read-only transaction: %v", err)},
-			},
-			IsError: true,
-		}, nil, nil
+		return s.returnErrorResult("%v", err)
 	}
 	defer tx.Rollback(ctx)
 
-	rows, err := tx.Query(ctx, args.Query)
+	if err := s.checkStatementAccess(ctx, tx, args.Query); err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+
+	query, params, rewrites := rewriteInLists(args.Query, s.config.InListThreshold)
+	if args.ChunkRows > 0 {
+		return s.readChunks(ctx, tx, args, query, params, append(notices, s.inListWarnings(rewrites)...))
+	}
+	rows, err := tx.Query(ctx, query, params...)
+	if connectionLost(err) {
+		s.pool.Reset()
+		return s.returnErrorResult("The connection to the database was lost while the statement ran (%v). Run it again to use a new connection", err)
+	}
+	if serializationFailure(err) {
+		return nil, nil, err
+	}
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -107,10 +260,14 @@ func ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs)
 	}
 	defer rows.Close()
 
+	if args.Expanded {
+		return s.expandedRow(ctx, tx, rows, args.GeometryFormat)
+	}
+
 	var results []map[string]interface{}
 	fieldDescriptions := rows.FieldDescriptions()
 	for rows.Next() {
-		values, err := rows.Values()
+		values, err := rowValues(rows)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
 		}
@@ -121,31 +278,94 @@ func ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args QueryArgs)
 		results = append(results, row)
 	}
 
-	if err := rows.Err(); err != nil {
+	if err := rows.Err(); serializationFailure(err) {
+		return nil, nil, err
+	} else if err != nil {
 		return nil, nil, fmt.Errorf("row iteration error: %v", err)
 	}
+	recordRowsScanned(ctx, tx)
+	policies, err := s.resultColumnPolicies(ctx, tx, fieldDescriptions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+	}
+	geometries, err := geometryColumns(ctx, tx, tx.Conn().TypeMap(), fieldDescriptions)
+	if err == nil {
+		err = renderGeometryRows(ctx, tx, geometries, args.GeometryFormat, results)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert geometry values: %v", err)
+	}
+	composites, err := compositeColumns(ctx, tx, tx.Conn().TypeMap(), fieldDescriptions)
+	if err == nil {
+		err = renderCompositeRows(ctx, tx, composites, results)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to convert composite values: %v", err)
+	}
 
 	// Commit the read-only transaction
-	if err := tx.Commit(ctx); err != nil {
+	if err := tx.Commit(ctx); serializationFailure(err) {
+		return nil, nil, err
+	} else if err != nil {
 		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	return returnJSONResult(results)
+	if args.Dedupe || len(args.DedupeColumns) > 0 || args.DedupeSimilarity > 0 {
+		var names []string
+		for _, field := range fieldDescriptions {
+			names = append(names, field.Name)
+		}
+		columns := args.DedupeColumns
+		if len(columns) == 0 {
+			columns = names
+		}
+		for _, column := range columns {
+			if !slices.Contains(names, column) {
+				return s.returnErrorResult("dedupe column %q is not a column of the result", column)
+			}
+		}
+		similarity := args.DedupeSimilarity
+		if similarity == 0 {
+			similarity = 1
+		}
+		// grouped on the real values, only the representatives are masked
+		results = dedupeRows(results, columns, similarity)
+	}
+
+	truncated, omitted := encodeBinaryRows(results, args.BinaryFormat, s.config.MaxBinaryBytes)
+	truncatedJSON, err := shapeJSONRows(results, jsonColumns(fieldDescriptions), args.JSONPaths, args.JSONFormat, s.config.MaxJSONBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	applyColumnPolicies(results, policies)
+	masked := s.redactRows(results)
+	encodeNumericRows(results, args.NumericFormat)
+	encodeIntervalRows(results, args.IntervalFormat)
+	result, data, err := returnJSONResult(results)
+	warnings := append(notices, s.inListWarnings(rewrites)...)
+	warnings = append(warnings, s.binaryWarnings(truncated, omitted)...)
+	warnings = append(warnings, s.jsonWarnings(truncatedJSON)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
 }
 
-func ListTables(ctx context.Context, req *mcp.CallToolRequest, args TableListArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) ListTables(ctx context.Context, req *mcp.CallToolRequest, args TableListArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
 	query := `
-		SELECT table_name, table_type
+		SELECT table_name, table_type, obj_description(format('%I.%I', table_schema, table_name)::regclass, 'pg_class')
 		FROM information_schema.tables
 		WHERE table_schema = $1
 		ORDER BY table_name
 	`
 
-	rows, err := pool.Query(ctx, query, getSchema(args.Schema))
+	schema := getSchema(args.Schema)
+	if !s.schemaAllowed(schema) {
+		return s.returnNotAccessible(schema)
+	}
+	rows, err := s.pool.Query(ctx, query, schema)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to list tables: %v", err)
 	}
@@ -154,36 +374,59 @@ func ListTables(ctx context.Context, req *mcp.CallToolRequest, args TableListArg
 	var tables []map[string]interface{}
 	for rows.Next() {
 		var tableName, tableType string
-		if err := rows.Scan(&tableName, &tableType); err != nil {
+		var comment *string
+		if err := rows.Scan(&tableName, &tableType, &comment); err != nil {
 			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
 		}
-		tables = append(tables, map[string]interface{}{
-			"table_name": tableName,
-			"table_type": tableType,
-		})
+		if !s.relationAllowed(schema, tableName) {
+			continue
+		}
+		table := map[string]interface{}{
+			"table_name":     tableName,
+			"table_type":     tableType,
+			"schema":         schema,
+			"qualified_name": qualifiedName(schema, tableName),
+		}
+		addOptionalString(table, "comment", comment)
+		tables = append(tables, table)
 	}
 
 	return returnJSONResult(tables)
 }
 
-func GetTableSchema(ctx context.Context, req *mcp.CallToolRequest, args TableSchemaArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) GetTableSchema(ctx context.Context, req *mcp.CallToolRequest, args TableSchemaArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
+	schema, table, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, table) {
+		return s.returnNotAccessible(qualifiedName(schema, table))
+	}
+
 	query := `
 		SELECT 
 			column_name,
 			data_type,
 			character_maximum_length,
 			is_nullable,
-			column_default
+			column_default,
+			COALESCE(udt_schema || '.' || udt_name, ''),
+			col_description(format('%I.%I', table_schema, table_name)::regclass, ordinal_position),
+			COALESCE((
+				SELECT a.attfdwoptions
+				FROM pg_attribute a
+				WHERE a.attrelid = format('%I.%I', table_schema, table_name)::regclass AND a.attname = column_name
+			), '{}')
 		FROM information_schema.columns
 		WHERE table_schema = $1 AND table_name = $2
 		ORDER BY ordinal_position
 	`
 
-	rows, err := pool.Query(ctx, query, getSchema(args.Schema), args.TableName)
+	rows, err := s.pool.Query(ctx, query, schema, table)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get table schema: %v", err)
 	}
@@ -192,9 +435,11 @@ func GetTableSchema(ctx context.Context, req *mcp.CallToolRequest, args TableSch
 	var columns []map[string]interface{}
 	for rows.Next() {
 		var columnName, dataType, isNullable string
-		var maxLength, columnDefault *string
+		var maxLength, columnDefault, comment *string
+		var udtName string
+		var fdwOptionList []string
 
-		if err := rows.Scan(&columnName, &dataType, &maxLength, &isNullable, &columnDefault); err != nil {
+		if err := rows.Scan(&columnName, &dataType, &maxLength, &isNullable, &columnDefault, &udtName, &comment, &fdwOptionList); err != nil {
 			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
 		}
 
@@ -205,6 +450,23 @@ func GetTableSchema(ctx context.Context, req *mcp.CallToolRequest, args TableSch
 		}
 		addOptionalString(column, "max_length", maxLength)
 		addOptionalString(column, "default", columnDefault)
+		addOptionalString(column, "comment", comment)
+		// enums, composites and domains over them only say USER-DEFINED, name the type for list_types
+		if dataType == "USER-DEFINED" {
+			column["type_name"] = udtName
+		}
+		// a foreign table's column options, such as the remote column_name
+		if len(fdwOptionList) > 0 {
+			column["fdw_options"] = fdwOptions(fdwOptionList)
+		}
+		// what COLUMN_POLICY_FILE says the column holds, so values are read the way tools show them
+		if policy, ok := s.config.ColumnPolicies.lookup(schema, table, columnName); ok {
+			column["semantic_type"] = policy.Type
+			column["masked"] = policy.masked()
+			if policy.Description != "" {
+				column["description"] = policy.Description
+			}
+		}
 
 		columns = append(columns, column)
 	}
@@ -212,11 +474,19 @@ func GetTableSchema(ctx context.Context, req *mcp.CallToolRequest, args TableSch
 	return returnJSONResult(columns)
 }
 
-func GetTableConstraints(ctx context.Context, req *mcp.CallToolRequest, args TableConstraintsArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) GetTableConstraints(ctx context.Context, req *mcp.CallToolRequest, args TableConstraintsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
+	schema, table, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, table) {
+		return s.returnNotAccessible(qualifiedName(schema, table))
+	}
+
 	query := `
 		SELECT 
 			tc.constraint_name,
@@ -244,7 +514,7 @@ func GetTableConstraints(ctx context.Context, req *mcp.CallToolRequest, args Tab
 		ORDER BY tc.constraint_type, tc.constraint_name, kcu.ordinal_position
 	`
 
-	rows, err := pool.Query(ctx, query, getSchema(args.Schema), args.TableName)
+	rows, err := s.pool.Query(ctx, query, schema, table)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get table constraints: %v", err)
 	}
@@ -276,30 +546,46 @@ func GetTableConstraints(ctx context.Context, req *mcp.CallToolRequest, args Tab
 	return returnJSONResult(constraints)
 }
 
-func GetTableIndexes(ctx context.Context, req *mcp.CallToolRequest, args TableIndexesArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) GetTableIndexes(ctx context.Context, req *mcp.CallToolRequest, args TableIndexesArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
+	schema, table, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, table) {
+		return s.returnNotAccessible(qualifiedName(schema, table))
+	}
+
+	// one row per column, INCLUDE columns after the key columns (k >= indnkeyatts)
+	// and expressions with indkey 0
 	query := `
 		SELECT 
-			i.indexname,
-			i.indexdef,
+			c.relname::text AS indexname,
+			pg_get_indexdef(idx.indexrelid) AS indexdef,
 			a.amname AS index_type,
 			idx.indisunique AS is_unique,
 			idx.indisprimary AS is_primary,
 			pg_get_indexdef(idx.indexrelid, k + 1, true) AS column_name,
-			k AS column_position
-		FROM pg_indexes i
-		JOIN pg_class c ON c.relname = i.indexname
-		JOIN pg_index idx ON idx.indexrelid = c.oid
+			k AS column_position,
+			k >= idx.indnkeyatts AS is_included,
+			idx.indkey[k] = 0 AS is_expression,
+			pg_get_expr(idx.indpred, idx.indrelid, true) AS predicate,
+			idx.indisvalid AS is_valid,
+			pg_relation_size(idx.indexrelid) AS size_bytes
+		FROM pg_index idx
+		JOIN pg_class c ON c.oid = idx.indexrelid
+		JOIN pg_class t ON t.oid = idx.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
 		JOIN pg_am a ON a.oid = c.relam
 		CROSS JOIN LATERAL generate_series(0, idx.indnatts - 1) AS k
-		WHERE i.schemaname = $1 AND i.tablename = $2
-		ORDER BY i.indexname, k
+		WHERE n.nspname = $1 AND t.relname = $2
+		ORDER BY c.relname, k
 	`
 
-	rows, err := pool.Query(ctx, query, getSchema(args.Schema), args.TableName)
+	rows, err := s.pool.Query(ctx, query, schema, table)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get table indexes: %v", err)
 	}
@@ -308,40 +594,54 @@ func GetTableIndexes(ctx context.Context, req *mcp.CallToolRequest, args TableIn
 	var indexes []map[string]interface{}
 	for rows.Next() {
 		var indexName, indexDef, indexType, columnName string
-		var isUnique, isPrimary bool
+		var isUnique, isPrimary, isIncluded, isExpression, isValid bool
 		var columnPosition int
+		var predicate *string
+		var sizeBytes int64
 
-		if err := rows.Scan(&indexName, &indexDef, &indexType, &isUnique, &isPrimary, &columnName, &columnPosition); err != nil {
+		if err := rows.Scan(&indexName, &indexDef, &indexType, &isUnique, &isPrimary, &columnName, &columnPosition,
+			&isIncluded, &isExpression, &predicate, &isValid, &sizeBytes); err != nil {
 			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
 		}
 
-		indexes = append(indexes, map[string]interface{}{
+		index := map[string]interface{}{
 			"index_name":       indexName,
 			"index_type":       indexType,
 			"is_unique":        isUnique,
 			"is_primary":       isPrimary,
 			"column_name":      columnName,
 			"column_position":  columnPosition,
+			"is_included":      isIncluded,
+			"is_expression":    isExpression,
+			"is_valid":         isValid,
+			"size_bytes":       sizeBytes,
 			"index_definition": indexDef,
-		})
+		}
+		// the WHERE clause of a partial index
+		addOptionalString(index, "predicate", predicate)
+		if !isValid {
+			index["note"] = "Invalid, likely a failed CREATE INDEX CONCURRENTLY: it is maintained on writes but never used. Drop it and build it again"
+		}
+		indexes = append(indexes, index)
 	}
 
 	return returnJSONResult(indexes)
 }
 
-func ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainAnalyzeArgs) (*mcp.CallToolResult, any, error) {
-	if pool == nil {
+func (s *serverState) ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainAnalyzeArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
 		return nil, nil, fmt.Errorf("database not connected")
 	}
 
-	var rawArgs map[string]interface{}
-	if req != nil {
-		json.Unmarshal(req.Params.Arguments, &rawArgs)
-	}
-	if rawArgs == nil {
-		rawArgs = make(map[string]interface{})
+	if utility := classifyUtility(args.Query); utility != nil {
+		if err := s.checkStatementAccess(ctx, s.pool, args.Query); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		return s.explainUtility(ctx, utility)
 	}
 
+	rawArgs := getRawArgs(req)
+
 	analyze := getExplicitBool(rawArgs, "analyze", args.Analyze, true)
 	costs := getExplicitBool(rawArgs, "costs", args.Costs, true)
 	timing := getExplicitBool(rawArgs, "timing", args.Timing, true)
@@ -349,6 +649,21 @@ func ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainA
 	buffers := getExplicitBool(rawArgs, "buffers", args.Buffers, false)
 	verbose := getExplicitBool(rawArgs, "verbose", args.Verbose, false)
 
+	// ANALYZE executes the statement, so writes only run when explicitly allowed
+	var warnings []string
+	if analyze && !args.AllowWriteAnalyze && statementWrites(args.Query) {
+		analyze = false
+		warnings = append(warnings, s.localize("The statement modifies data, so ANALYZE was skipped and only the estimated plan is shown. "+
+			"Even though it would be rolled back, running it fires triggers and takes locks. Set allow_write_analyze to true to run it anyway"))
+	}
+	if statementWrites(args.Query) {
+		triggerWarnings, err := s.sideEffectWarnings(ctx, args.Query)
+		if err != nil {
+			triggerWarnings = append(triggerWarnings, fmt.Sprintf("Could not check triggers and rules: %v", err))
+		}
+		warnings = append(warnings, triggerWarnings...)
+	}
+
 	format := args.Format
 	if format == "" {
 		format = "json"
@@ -359,6 +674,20 @@ func ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainA
 	if !validFormats[format] {
 		format = "json"
 	}
+	switch args.Render {
+	case "":
+	case "tree":
+		// the tree is rendered from the JSON plan
+		format = "json"
+	default:
+		return s.returnErrorResult("Unknown render %q, use tree", args.Render)
+	}
+
+	versioned, analyze, versionWarnings, err := s.versionedExplainOptions(ctx, args, analyze)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings = append(warnings, versionWarnings...)
 
 	options := []string{
 		fmt.Sprintf("ANALYZE %t", analyze),
@@ -376,16 +705,22 @@ func ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainA
 	if analyze {
 		options = append(options, fmt.Sprintf("TIMING %t", timing))
 	}
+	options = append(options, versioned...)
 
-	tx, err := pool.Begin(ctx)
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, args.Role)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+		return s.returnErrorResult("%v", err)
 	}
 	// always rollback, no inserts / updates / any side effects should be enabled
 	defer tx.Rollback(ctx)
+	warnings = append(notices, warnings...)
+
+	if err := s.checkStatementAccess(ctx, tx, args.Query); err != nil {
+		return s.returnErrorResult("%v", err)
+	}
 
 	explainQuery := fmt.Sprintf("EXPLAIN (%s) %s", strings.Join(options, ", "), args.Query)
-	rows, err := tx.Query(ctx, explainQuery)
+	lines, err := explainLines(ctx, tx, explainQuery, args.GenericPlan)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
@@ -394,47 +729,45 @@ func ExplainAnalyze(ctx context.Context, req *mcp.CallToolRequest, args ExplainA
 			IsError: true,
 		}, nil, nil
 	}
-	defer rows.Close()
+	recordRowsScanned(ctx, tx)
+
+	if args.Render == "tree" && len(lines) > 0 {
+		plan, err := parseExplainOutput(lines[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		tree := renderPlanTree(plan)
+		return s.withWarnings(&mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: tree},
+			},
+		}, warnings), tree, nil
+	}
 
 	if format == "json" {
 		var results []map[string]interface{}
-		fieldDescriptions := rows.FieldDescriptions()
-		for rows.Next() {
-			values, err := rows.Values()
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to scan row: %v", err)
-			}
-			row := make(map[string]interface{})
-			for i, field := range fieldDescriptions {
-				row[string(field.Name)] = values[i]
+		for _, line := range lines {
+			var plan interface{}
+			if err := json.Unmarshal(line, &plan); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse the plan: %v", err)
 			}
-			results = append(results, row)
+			results = append(results, map[string]interface{}{"QUERY PLAN": plan})
 		}
-		if err := rows.Err(); err != nil {
-			return nil, nil, fmt.Errorf("row iteration error: %v", err)
-		}
-		return returnJSONResult(results)
+		result, data, err := returnJSONResult(results)
+		return s.withWarnings(result, warnings), data, err
 	}
 
 	// the rest of the formats, concatenate the rows
 	var output strings.Builder
-	for rows.Next() {
-		var line string
-		if err := rows.Scan(&line); err != nil {
-			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
-		}
-		output.WriteString(line)
+	for _, line := range lines {
+		output.Write(line)
 		output.WriteString("\n")
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, nil, fmt.Errorf("row iteration error: %v", err)
-	}
-
 	result := output.String()
-	return &mcp.CallToolResult{
+	return s.withWarnings(&mcp.CallToolResult{
 		Content: []mcp.Content{
 			&mcp.TextContent{Text: result},
 		},
-	}, result, nil
+	}, warnings), result, nil
 }
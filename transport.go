@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// transports are the ways clients can reach the server: stdio for a local
+// client that spawns it, streamable HTTP or SSE for remote ones.
+var transports = map[string]bool{"stdio": true, "http": true, "sse": true}
+
+// validateTLS checks that the TLS settings are complete, so a typo doesn't
+// silently serve plain HTTP.
+func (c Config) validateTLS() error {
+	switch {
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	case c.TLSClientCAFile != "" && c.TLSCertFile == "":
+		return fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	case c.TLSCertFile != "" && c.Transport == "stdio":
+		return fmt.Errorf("TLS settings require TRANSPORT=http or TRANSPORT=sse")
+	}
+	return nil
+}
+
+// validateListenAddr keeps the http and sse transports on loopback unless
+// they are served over TLS and clients authenticate, with a bearer token or
+// a client certificate, since every tool including the writes is reachable
+// through them.
+func (c Config) validateListenAddr() error {
+	if c.Transport == "" || c.Transport == "stdio" {
+		if c.HTTPAuthToken != "" {
+			return fmt.Errorf("HTTP_AUTH_TOKEN requires TRANSPORT=http or TRANSPORT=sse")
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(c.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("invalid HTTP_ADDR: %v", err)
+	}
+	if isLoopbackHost(host) {
+		return nil
+	}
+	if c.TLSCertFile == "" || (c.HTTPAuthToken == "" && c.TLSClientCAFile == "") {
+		return fmt.Errorf("HTTP_ADDR %s is reachable from other hosts, which requires TLS_CERT_FILE and either HTTP_AUTH_TOKEN or TLS_CLIENT_CA_FILE; listen on 127.0.0.1 otherwise", c.HTTPAddr)
+	}
+	return nil
+}
+
+// isLoopbackHost reports whether a listen host only accepts local
+// connections. An empty host listens on every interface.
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireBearerToken refuses requests that don't carry HTTP_AUTH_TOKEN as
+// their bearer token.
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="postgres-mcp"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tlsConfig loads the server certificate and, with TLS_CLIENT_CA_FILE,
+// requires clients to present a certificate signed by one of those CAs.
+// It returns nil when TLS is not configured.
+func (c Config) tlsConfig() (*tls.Config, error) {
+	if c.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if c.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(c.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA file: %v", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.TLSClientCAFile)
+		}
+		config.ClientCAs = clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// httpServer builds the HTTP server for the http and sse transports, every
+// client session shares this state's tools and pool.
+func (s *serverState) httpServer(server *mcp.Server) (*http.Server, error) {
+	tlsConfig, err := s.config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	getServer := func(*http.Request) *mcp.Server { return server }
+	var handler http.Handler = mcp.NewStreamableHTTPHandler(getServer, nil)
+	if s.config.Transport == "sse" {
+		handler = mcp.NewSSEHandler(getServer, nil)
+	}
+	if s.config.HTTPAuthToken != "" {
+		handler = requireBearerToken(s.config.HTTPAuthToken, handler)
+	}
+
+	return &http.Server{
+		Addr:      s.config.HTTPAddr,
+		Handler:   handler,
+		TLSConfig: tlsConfig,
+	}, nil
+}
+
+// serve runs the server on the configured transport until the client
+// disconnects (stdio) or the listener fails.
+func (s *serverState) serve(ctx context.Context, server *mcp.Server) error {
+	if s.config.Transport == "" || s.config.Transport == "stdio" {
+		return server.Run(ctx, &mcp.StdioTransport{})
+	}
+
+	httpServer, err := s.httpServer(server)
+	if err != nil {
+		return err
+	}
+	if httpServer.TLSConfig != nil {
+		log.Printf("Serving %s over HTTPS on %s", s.config.Transport, httpServer.Addr)
+		err = httpServer.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("Serving %s over plain HTTP on %s", s.config.Transport, httpServer.Addr)
+		err = httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// testCertificate issues a certificate signed by parent (self-signed when
+// parent is nil) and writes it and its key as PEM files to dir.
+func testCertificate(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	certificate, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certificate, key, certFile, keyFile
+}
+
+func TestValidateTLS(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{Transport: "stdio"}, true},
+		{Config{Transport: "http", TLSCertFile: "server.crt", TLSKeyFile: "server.key"}, true},
+		{Config{Transport: "http", TLSCertFile: "server.crt"}, false},
+		{Config{Transport: "http", TLSClientCAFile: "ca.crt"}, false},
+		{Config{Transport: "stdio", TLSCertFile: "server.crt", TLSKeyFile: "server.key"}, false},
+	}
+	for _, test := range tests {
+		if err := test.config.validateTLS(); (err == nil) != test.valid {
+			t.Errorf("validateTLS(%+v) = %v, expected valid=%t", test.config, err, test.valid)
+		}
+	}
+}
+
+func TestValidateListenAddr(t *testing.T) {
+	tests := []struct {
+		config Config
+		valid  bool
+	}{
+		{Config{Transport: "stdio"}, true},
+		{Config{Transport: "stdio", HTTPAuthToken: "secret"}, false},
+		{Config{Transport: "http", HTTPAddr: "127.0.0.1:8080"}, true},
+		{Config{Transport: "http", HTTPAddr: "localhost:8080"}, true},
+		{Config{Transport: "sse", HTTPAddr: "[::1]:8080"}, true},
+		{Config{Transport: "http", HTTPAddr: ":8080"}, false},
+		{Config{Transport: "http", HTTPAddr: "0.0.0.0:8080", HTTPAuthToken: "secret"}, false},
+		{Config{Transport: "http", HTTPAddr: "0.0.0.0:8080", TLSCertFile: "server.crt", TLSKeyFile: "server.key"}, false},
+		{Config{Transport: "http", HTTPAddr: "0.0.0.0:8080", TLSCertFile: "server.crt", TLSKeyFile: "server.key", HTTPAuthToken: "secret"}, true},
+		{Config{Transport: "http", HTTPAddr: ":8080", TLSCertFile: "server.crt", TLSKeyFile: "server.key", TLSClientCAFile: "ca.crt"}, true},
+		{Config{Transport: "http", HTTPAddr: "8080"}, false},
+	}
+	for _, test := range tests {
+		if err := test.config.validateListenAddr(); (err == nil) != test.valid {
+			t.Errorf("validateListenAddr(%+v) = %v, expected valid=%t", test.config, err, test.valid)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	state := &serverState{config: Config{Transport: "http", HTTPAddr: "127.0.0.1:0", HTTPAuthToken: "secret"}}
+	server := mcp.NewServer(&mcp.Implementation{Name: "postgres-mcp", Version: "test"}, nil)
+	httpServer, err := state.httpServer(server)
+	if err != nil {
+		t.Fatalf("httpServer failed: %v", err)
+	}
+	listener := httptest.NewServer(httpServer.Handler)
+	defer listener.Close()
+
+	for token, expected := range map[string]int{"": http.StatusUnauthorized, "Bearer wrong": http.StatusUnauthorized} {
+		req, _ := http.NewRequest("GET", listener.URL, nil)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != expected {
+			t.Errorf("Expected %d for %q, got %d", expected, token, resp.StatusCode)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", listener.URL, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		t.Error("Expected the bearer token to be accepted")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caFile, _ := testCertificate(t, dir, "ca", nil, nil)
+	_, _, serverCert, serverKey := testCertificate(t, dir, "server", ca, caKey)
+	_, _, clientCert, clientKey := testCertificate(t, dir, "client", ca, caKey)
+
+	state := &serverState{config: Config{
+		Transport:       "http",
+		HTTPAddr:        "127.0.0.1:0",
+		TLSCertFile:     serverCert,
+		TLSKeyFile:      serverKey,
+		TLSClientCAFile: caFile,
+	}}
+	server := mcp.NewServer(&mcp.Implementation{Name: "postgres-mcp", Version: "test"}, nil)
+	httpServer, err := state.httpServer(server)
+	if err != nil {
+		t.Fatalf("httpServer failed: %v", err)
+	}
+	if httpServer.TLSConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatal("Expected client certificates to be required")
+	}
+
+	listener := httptest.NewUnstartedServer(httpServer.Handler)
+	listener.TLS = httpServer.TLSConfig
+	listener.StartTLS()
+	defer listener.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	client := func(certificates ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certificates}}}
+	}
+
+	if resp, err := client().Get(listener.URL); err == nil {
+		resp.Body.Close()
+		t.Error("Expected a client without a certificate to be refused")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	resp, err := client(certificate).Get(listener.URL)
+	if err != nil {
+		t.Fatalf("Expected a client with a certificate to connect, got %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// assumedIOThroughput is the rewrite speed assumed, in bytes per second, when
+// the database has no read timings to go by (track_io_timing is off).
+const assumedIOThroughput = 100 << 20
+
+type TypeChangeArgs struct {
+	TableName string `json:"table_name" jsonschema:"Table holding the column, optionally schema-qualified"`
+	Schema    string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Column    string `json:"column" jsonschema:"Column whose type would change"`
+	NewType   string `json:"new_type" jsonschema:"Proposed type, as in ALTER COLUMN ... TYPE (e.g. bigint, varchar(200), numeric(12,2))"`
+	Using     string `json:"using,omitempty" jsonschema:"USING expression converting the old values, if the ALTER would have one"`
+}
+
+// typeChange is a proposed column type change, with types resolved to their
+// OID and type modifier (-1 for none) and the cast between them.
+type typeChange struct {
+	OldOID, NewOID       uint32
+	OldTypmod, NewTypmod int32
+	OldName, NewName     string
+	OldCategory          string
+	NewCategory          string
+	CastMethod           string
+	DomainConstraints    bool
+	Using                bool
+	TimeZone             string
+}
+
+// typmodAtMost reports whether a precision or length modifier is unchanged or
+// only relaxed, which PostgreSQL applies without touching the data.
+func typmodAtMost(oldTypmod, newTypmod int32) bool {
+	return newTypmod == -1 || (oldTypmod != -1 && newTypmod >= oldTypmod)
+}
+
+// numericTypmod splits a numeric type modifier into precision and scale.
+func numericTypmod(typmod int32) (int, int) {
+	return int((typmod-4)>>16) & 0xffff, int(typmod-4) & 0xffff
+}
+
+// numericRelaxed reports whether a numeric modifier change keeps the scale
+// and doesn't lower the precision, so every value still fits unchanged.
+func numericRelaxed(oldTypmod, newTypmod int32) bool {
+	if newTypmod == -1 {
+		return true
+	}
+	if oldTypmod == -1 {
+		return false
+	}
+	oldPrecision, oldScale := numericTypmod(oldTypmod)
+	newPrecision, newScale := numericTypmod(newTypmod)
+	return newScale == oldScale && newPrecision >= oldPrecision
+}
+
+func isUTC(timeZone string) bool {
+	switch strings.ToUpper(timeZone) {
+	case "UTC", "ETC/UTC", "GMT", "ETC/GMT", "UCT", "ZULU":
+		return true
+	}
+	return false
+}
+
+// requiresRewrite follows ATColumnChangeRequiresRewrite in PostgreSQL: data
+// stays in place when the new type is binary coercible and any modifier
+// change only relaxes a limit, everything else rewrites the table.
+func (c typeChange) requiresRewrite() (bool, string) {
+	switch {
+	case c.Using:
+		return true, "A USING expression computes every value anew"
+	case c.DomainConstraints:
+		return true, "The domain's constraints are checked by rewriting the table"
+	}
+
+	if c.OldOID == c.NewOID {
+		switch {
+		case c.OldTypmod == c.NewTypmod:
+			return false, "The type does not change"
+		case c.OldOID == pgtype.VarcharOID || c.OldOID == pgtype.VarbitOID:
+			if typmodAtMost(c.OldTypmod, c.NewTypmod) {
+				return false, "Raising or removing the length limit only changes the catalog"
+			}
+			return true, "Lowering the length limit checks and rewrites every value"
+		case c.OldOID == pgtype.NumericOID:
+			if numericRelaxed(c.OldTypmod, c.NewTypmod) {
+				return false, "Raising or removing the precision at the same scale only changes the catalog"
+			}
+			return true, "Changing the scale or lowering the precision rounds and rewrites every value"
+		case c.OldOID == pgtype.TimestampOID || c.OldOID == pgtype.TimestamptzOID || c.OldOID == pgtype.TimeOID || c.OldOID == pgtype.TimetzOID:
+			if typmodAtMost(c.OldTypmod, c.NewTypmod) {
+				return false, "Raising or removing the fractional second precision only changes the catalog"
+			}
+			return true, "Lowering the fractional second precision rounds and rewrites every value"
+		case c.OldOID == pgtype.IntervalOID && c.NewTypmod == -1:
+			return false, "Removing the interval restrictions only changes the catalog"
+		}
+		return true, "Changing the type modifier rewrites every value"
+	}
+
+	timestamps := map[uint32]bool{pgtype.TimestampOID: true, pgtype.TimestamptzOID: true}
+	if timestamps[c.OldOID] && timestamps[c.NewOID] && typmodAtMost(c.OldTypmod, c.NewTypmod) {
+		if isUTC(c.TimeZone) {
+			return false, "Between timestamp and timestamptz values are unchanged when the session TimeZone is UTC (PostgreSQL 12+)"
+		}
+		return true, fmt.Sprintf("Between timestamp and timestamptz values are shifted by the session TimeZone (%s), run the ALTER with TimeZone set to UTC to avoid the rewrite", c.TimeZone)
+	}
+	if c.CastMethod == "b" && c.NewTypmod == -1 {
+		return false, fmt.Sprintf("%s is binary coercible to %s, only the catalog changes", c.OldName, c.NewName)
+	}
+	return true, fmt.Sprintf("Every value is converted from %s to %s", c.OldName, c.NewName)
+}
+
+// castRisks lists what applications may notice about values after the
+// change, or what makes the ALTER itself fail on some data.
+func (c typeChange) castRisks() []string {
+	var risks []string
+	integerWidth := map[uint32]int{pgtype.Int2OID: 2, pgtype.Int4OID: 4, pgtype.Int8OID: 8}
+	oldWidth, newWidth := integerWidth[c.OldOID], integerWidth[c.NewOID]
+
+	switch {
+	case oldWidth > 0 && newWidth > 0 && newWidth < oldWidth:
+		risks = append(risks, fmt.Sprintf("Values outside the range of %s make the ALTER fail", c.NewName))
+	case oldWidth == 0 && newWidth > 0 && c.OldCategory == "N":
+		risks = append(risks, "Fractional values are rounded to whole numbers")
+	case c.OldOID == pgtype.Float8OID && c.NewOID == pgtype.Float4OID:
+		risks = append(risks, "real keeps about 6 significant digits, values lose precision")
+	case c.NewOID == pgtype.NumericOID && c.NewTypmod != -1 && !(c.OldOID == c.NewOID && numericRelaxed(c.OldTypmod, c.NewTypmod)):
+		newPrecision, newScale := numericTypmod(c.NewTypmod)
+		risks = append(risks, fmt.Sprintf("Values are rounded to %d decimal places, and values needing more than %d digits make the ALTER fail", newScale, newPrecision))
+	case (c.NewOID == pgtype.VarcharOID || c.NewOID == pgtype.BPCharOID) && c.NewTypmod != -1 && !(c.OldOID == c.NewOID && typmodAtMost(c.OldTypmod, c.NewTypmod)):
+		risks = append(risks, fmt.Sprintf("Values longer than %d characters make the ALTER fail", c.NewTypmod-4))
+	}
+	if c.NewOID == pgtype.BPCharOID && c.OldOID != pgtype.BPCharOID {
+		risks = append(risks, "character(n) pads values with trailing spaces, which clients receive")
+	}
+	if c.OldCategory == "S" && c.NewCategory != "S" {
+		risks = append(risks, fmt.Sprintf("Values that don't parse as %s make the ALTER fail", c.NewName))
+	}
+	if c.OldCategory != c.NewCategory {
+		risks = append(risks, fmt.Sprintf("Clients receive %s instead of %s values, drivers may map the column to a different type", c.NewName, c.OldName))
+	}
+	switch {
+	case c.OldOID == pgtype.TimestampOID && c.NewOID == pgtype.TimestamptzOID:
+		risks = append(risks, fmt.Sprintf("Existing values are taken to be in the session TimeZone (%s)", c.TimeZone))
+	case c.OldOID == pgtype.TimestamptzOID && c.NewOID == pgtype.TimestampOID:
+		risks = append(risks, fmt.Sprintf("Values lose their time zone and are stored as local times in the session TimeZone (%s)", c.TimeZone))
+	case c.OldOID == pgtype.JSONOID && c.NewOID == pgtype.JSONBOID:
+		risks = append(risks, "jsonb drops duplicate keys, whitespace and key order, clients get the normalized text")
+	case c.OldOID == c.NewOID && !typmodAtMost(c.OldTypmod, c.NewTypmod) &&
+		(c.OldOID == pgtype.TimestampOID || c.OldOID == pgtype.TimestamptzOID || c.OldOID == pgtype.TimeOID || c.OldOID == pgtype.TimetzOID):
+		risks = append(risks, "Fractional seconds are rounded to the new precision")
+	}
+	return risks
+}
+
+func (s *serverState) EstimateTypeChange(ctx context.Context, req *mcp.CallToolRequest, args TypeChangeArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if args.TableName == "" || args.Column == "" || args.NewType == "" {
+		return s.returnErrorResult("table_name, column and new_type are required")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := pgx.Identifier{schema, tableName}.Sanitize()
+	column := pgx.Identifier{args.Column}.Sanitize()
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	change := typeChange{Using: strings.TrimSpace(args.Using) != ""}
+	var attnum int
+	var estimatedRows, tableBytes, indexBytes, blockSize int64
+	var relkind string
+	err = tx.QueryRow(ctx, `
+		SELECT a.attnum, a.atttypid, a.atttypmod, format_type(a.atttypid, a.atttypmod), t.typcategory::text, c.relkind::text,
+			current_setting('TimeZone'), current_setting('block_size')::bigint
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_type t ON t.oid = a.atttypid
+		WHERE a.attrelid = $1::text::regclass AND a.attname = $2 AND a.attnum > 0 AND NOT a.attisdropped
+	`, table, args.Column).Scan(&attnum, &change.OldOID, &change.OldTypmod, &change.OldName, &change.OldCategory, &relkind,
+		&change.TimeZone, &blockSize)
+	if err == pgx.ErrNoRows {
+		return s.returnErrorResult("Column %s does not exist in %s", args.Column, qualifiedName(schema, tableName))
+	}
+	if err != nil {
+		return s.returnErrorResult("Failed to look up %s: %v", qualifiedName(schema, tableName), err)
+	}
+
+	// partitioned tables are altered (and rewritten) partition by partition
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(sum(GREATEST(c.reltuples, 0)), 0)::bigint, COALESCE(sum(pg_table_size(c.oid)), 0)::bigint,
+			COALESCE(sum(pg_indexes_size(c.oid)), 0)::bigint
+		FROM pg_partition_tree($1::text::regclass) p
+		JOIN pg_class c ON c.oid = p.relid
+		WHERE p.isleaf
+	`, table).Scan(&estimatedRows, &tableBytes, &indexBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the size of %s: %v", table, err)
+	}
+
+	// the parser resolves the new type, with its modifier, without running anything
+	described, err := tx.Prepare(ctx, "", fmt.Sprintf("SELECT CAST(NULL AS %s)", args.NewType))
+	if err != nil || len(described.Fields) != 1 {
+		return s.returnErrorResult("%q is not a type: %v", args.NewType, err)
+	}
+	change.NewOID, change.NewTypmod = described.Fields[0].DataTypeOID, described.Fields[0].TypeModifier
+	if change.Using {
+		if _, err := tx.Prepare(ctx, "", fmt.Sprintf("SELECT CAST((%s) AS %s) FROM %s", args.Using, args.NewType, table)); err != nil {
+			return s.returnErrorResult("The USING expression does not work: %v", err)
+		}
+	}
+	// domains are described as their base type, their constraints come from the catalog
+	err = tx.QueryRow(ctx, `
+		SELECT format_type($1, $2), t.typcategory::text,
+			EXISTS (SELECT 1 FROM pg_constraint WHERE contypid = to_regtype($3)),
+			COALESCE((SELECT castmethod::text FROM pg_cast WHERE castsource = $4 AND casttarget = $1), '')
+		FROM pg_type t
+		WHERE t.oid = $1
+	`, change.NewOID, change.NewTypmod, args.NewType, change.OldOID).Scan(&change.NewName, &change.NewCategory,
+		&change.DomainConstraints, &change.CastMethod)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up type %s: %v", args.NewType, err)
+	}
+	// ALTER converts with assignment casts, any type converts to a string type through text
+	var assignable bool
+	err = tx.QueryRow(ctx, `
+		SELECT $1 = $2 OR $3 = 'S'
+			OR EXISTS (SELECT 1 FROM pg_cast WHERE castsource = $1 AND casttarget = $2 AND castcontext IN ('a', 'i'))
+	`, change.OldOID, change.NewOID, change.NewCategory).Scan(&assignable)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up casts to %s: %v", change.NewName, err)
+	}
+
+	rewrite, reason := change.requiresRewrite()
+	response := map[string]interface{}{
+		"table":            qualifiedName(schema, tableName),
+		"column":           args.Column,
+		"current_type":     change.OldName,
+		"new_type":         change.NewName,
+		"rewrite_required": rewrite,
+		"reason":           reason,
+		"lock_mode":        "ACCESS EXCLUSIVE",
+		"lock_impact":      lockImpact["ACCESS EXCLUSIVE"],
+		"estimated_rows":   estimatedRows,
+		"table_bytes":      tableBytes,
+		"index_bytes":      indexBytes,
+		"risks":            change.castRisks(),
+	}
+	if relkind == "p" {
+		response["partitioned"] = true
+	}
+
+	var blockers []string
+	if !assignable && !change.Using {
+		blockers = append(blockers, fmt.Sprintf("There is no assignment cast from %s to %s, the ALTER needs a USING expression", change.OldName, change.NewName))
+	}
+
+	// indexes on the column are rebuilt even without a rewrite, unless the
+	// new type keeps the operator family (such as varchar to text)
+	rows, err := tx.Query(ctx, `
+		SELECT i.indexrelid::regclass::text, pg_get_indexdef(i.indexrelid), pg_relation_size(i.indexrelid),
+			EXISTS (
+				SELECT 1 FROM pg_depend d
+				WHERE d.classid = 'pg_class'::regclass AND d.objid = i.indexrelid
+					AND d.refobjid = i.indrelid AND d.refobjsubid = $2
+			)
+		FROM pg_index i
+		WHERE i.indrelid = $1::text::regclass
+		ORDER BY 1
+	`, table, attnum)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list indexes: %v", err)
+	}
+	var rebuilt []map[string]interface{}
+	rebuildBytes := int64(0)
+	for rows.Next() {
+		var name, definition string
+		var size int64
+		var onColumn bool
+		if err := rows.Scan(&name, &definition, &size, &onColumn); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !rewrite && !onColumn {
+			continue
+		}
+		rebuilt = append(rebuilt, map[string]interface{}{"name": name, "definition": definition, "size_bytes": size})
+		rebuildBytes += size
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	response["indexes_rebuilt"] = rebuilt
+
+	// views and rules on the column make the ALTER fail outright
+	rows, err = tx.Query(ctx, `
+		SELECT DISTINCT r.ev_class::regclass::text
+		FROM pg_depend d
+		JOIN pg_rewrite r ON r.oid = d.objid
+		WHERE d.classid = 'pg_rewrite'::regclass AND d.refobjid = $1::text::regclass AND d.refobjsubid = $2
+			AND r.ev_class <> $1::text::regclass
+		ORDER BY 1
+	`, table, attnum)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list dependent views: %v", err)
+	}
+	for rows.Next() {
+		var view string
+		if err := rows.Scan(&view); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		blockers = append(blockers, fmt.Sprintf("View %s uses the column, PostgreSQL refuses to change its type until the view is dropped", view))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	response["blockers"] = blockers
+
+	var foreignKeys []string
+	rows, err = tx.Query(ctx, `
+		SELECT conname::text, conrelid::regclass::text, confrelid::regclass::text
+		FROM pg_constraint
+		WHERE contype = 'f'
+			AND ((conrelid = $1::text::regclass AND $2 = ANY(conkey)) OR (confrelid = $1::text::regclass AND $2 = ANY(confkey)))
+		ORDER BY 1
+	`, table, attnum)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list foreign keys: %v", err)
+	}
+	for rows.Next() {
+		var name, from, to string
+		if err := rows.Scan(&name, &from, &to); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		foreignKeys = append(foreignKeys, fmt.Sprintf("%s (%s -> %s) pairs the column with one that should change type too, and is rechecked", name, from, to))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	if len(foreignKeys) > 0 {
+		response["foreign_keys"] = foreignKeys
+	}
+
+	// read timings only exist with track_io_timing, and include reads served
+	// by the OS cache, so the estimate is rough either way
+	var blocksRead int64
+	var readMillis float64
+	tx.QueryRow(ctx, "SELECT blks_read, blk_read_time FROM pg_stat_database WHERE datname = current_database()").Scan(&blocksRead, &readMillis)
+	throughput, throughputSource := float64(assumedIOThroughput), "assumed (track_io_timing is off or no reads were timed)"
+	if readMillis > 0 && blocksRead > 1000 {
+		throughput = float64(blocksRead*blockSize) / (readMillis / 1000)
+		throughputSource = "pg_stat_database read timings"
+	}
+
+	// a rewrite reads and writes the table, then builds every index; an
+	// in-place change only scans the table for the indexes it rebuilds
+	workBytes := int64(0)
+	switch {
+	case rewrite:
+		workBytes = 2*tableBytes + rebuildBytes
+	case len(rebuilt) > 0:
+		workBytes = tableBytes + rebuildBytes
+	}
+	response["estimated_seconds"] = float64(workBytes) / throughput
+	response["throughput_bytes_per_second"] = int64(throughput)
+	response["throughput_source"] = throughputSource
+
+	statement := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s", table, column, args.NewType)
+	if change.Using {
+		statement += " USING " + args.Using
+	}
+	response["statement"] = statement + ";"
+
+	return returnJSONResult(response)
+}
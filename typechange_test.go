@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRequiresRewrite(t *testing.T) {
+	varchar := func(n int32) int32 { return n + 4 }
+	numeric := func(p, s int32) int32 { return (p<<16 | s) + 4 }
+	tests := []struct {
+		name    string
+		change  typeChange
+		rewrite bool
+	}{
+		{"longer varchar", typeChange{OldOID: pgtype.VarcharOID, NewOID: pgtype.VarcharOID, OldTypmod: varchar(10), NewTypmod: varchar(20)}, false},
+		{"shorter varchar", typeChange{OldOID: pgtype.VarcharOID, NewOID: pgtype.VarcharOID, OldTypmod: varchar(20), NewTypmod: varchar(10)}, true},
+		{"varchar to text", typeChange{OldOID: pgtype.VarcharOID, NewOID: pgtype.TextOID, OldTypmod: varchar(10), NewTypmod: -1, CastMethod: "b"}, false},
+		{"text to varchar(10)", typeChange{OldOID: pgtype.TextOID, NewOID: pgtype.VarcharOID, OldTypmod: -1, NewTypmod: varchar(10), CastMethod: "b"}, true},
+		{"more numeric precision", typeChange{OldOID: pgtype.NumericOID, NewOID: pgtype.NumericOID, OldTypmod: numeric(10, 2), NewTypmod: numeric(12, 2)}, false},
+		{"more numeric scale", typeChange{OldOID: pgtype.NumericOID, NewOID: pgtype.NumericOID, OldTypmod: numeric(10, 2), NewTypmod: numeric(12, 3)}, true},
+		{"int to bigint", typeChange{OldOID: pgtype.Int4OID, NewOID: pgtype.Int8OID, OldTypmod: -1, NewTypmod: -1, CastMethod: "f"}, true},
+		{"timestamptz in UTC", typeChange{OldOID: pgtype.TimestampOID, NewOID: pgtype.TimestamptzOID, OldTypmod: -1, NewTypmod: -1, TimeZone: "Etc/UTC"}, false},
+		{"timestamptz elsewhere", typeChange{OldOID: pgtype.TimestampOID, NewOID: pgtype.TimestamptzOID, OldTypmod: -1, NewTypmod: -1, TimeZone: "Europe/Berlin"}, true},
+		{"using", typeChange{OldOID: pgtype.VarcharOID, NewOID: pgtype.TextOID, OldTypmod: -1, NewTypmod: -1, CastMethod: "b", Using: true}, true},
+	}
+	for _, test := range tests {
+		if rewrite, reason := test.change.requiresRewrite(); rewrite != test.rewrite {
+			t.Errorf("%s: expected rewrite %t, got %t (%s)", test.name, test.rewrite, rewrite, reason)
+		}
+	}
+
+	narrowing := typeChange{OldOID: pgtype.Int8OID, NewOID: pgtype.Int4OID, OldName: "bigint", NewName: "integer", OldCategory: "N", NewCategory: "N"}
+	if risks := narrowing.castRisks(); len(risks) != 1 || !strings.Contains(risks[0], "outside the range of integer") {
+		t.Errorf("Expected an overflow risk, got %v", risks)
+	}
+	relaxed := typeChange{OldOID: pgtype.NumericOID, NewOID: pgtype.NumericOID, OldTypmod: numeric(10, 2), NewTypmod: numeric(12, 2), OldCategory: "N", NewCategory: "N"}
+	if risks := relaxed.castRisks(); len(risks) != 0 {
+		t.Errorf("Expected no risks for more precision, got %v", risks)
+	}
+}
+
+func TestEstimateTypeChange(t *testing.T) {
+	ctx := context.Background()
+
+	args := TypeChangeArgs{TableName: "users", Column: "username", NewType: "varchar(100)"}
+	result, data, err := testServer.EstimateTypeChange(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("EstimateTypeChange failed: %v %v", err, result)
+	}
+	estimate := data.(map[string]interface{})
+	if estimate["rewrite_required"] != false || len(estimate["indexes_rebuilt"].([]map[string]interface{})) != 1 {
+		t.Errorf("Expected a longer varchar to only rebuild the username index, got %v", estimate)
+	}
+
+	args = TypeChangeArgs{TableName: "users", Column: "id", NewType: "bigint"}
+	_, data, err = testServer.EstimateTypeChange(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("EstimateTypeChange failed: %v", err)
+	}
+	estimate = data.(map[string]interface{})
+	if estimate["rewrite_required"] != true || estimate["foreign_keys"] == nil {
+		t.Errorf("Expected int to bigint to rewrite and report the referencing foreign keys, got %v", estimate)
+	}
+
+	args = TypeChangeArgs{TableName: "users", Column: "created_at", NewType: "integer"}
+	_, data, _ = testServer.EstimateTypeChange(ctx, createMockRequest(args), args)
+	if blockers := data.(map[string]interface{})["blockers"].([]string); len(blockers) == 0 {
+		t.Error("Expected timestamp to integer to need a USING expression")
+	}
+
+	args = TypeChangeArgs{TableName: "users", Column: "id", NewType: "no_such_type"}
+	if result, _, _ := testServer.EstimateTypeChange(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected an unknown type to be rejected")
+	}
+}
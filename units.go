@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// unitTools report sizes and durations. They take a units argument choosing
+// between raw numbers, humanized strings or both, so responses can be shown
+// as they are without converting bytes to GiB by hand.
+var unitTools = map[string]bool{
+	"describe_table":            true,
+	"get_table_indexes":         true,
+	"explain_analyze":           true,
+	"list_materialized_views":   true,
+	"refresh_materialized_view": true,
+	"get_partitions":            true,
+	"get_usage":                 true,
+	"pool_stats":                true,
+	"meta_command":              true,
+	"get_memory_usage":          true,
+	"estimate_type_change":      true,
+	"vector_index_info":         true,
+	"spatial_info":              true,
+	"logical_replication_info":  true,
+	"suggest_indexes":           true,
+	"compare_plans":             true,
+	"run_vacuum":                true,
+	"run_reindex":               true,
+	"storage_info":              true,
+	"list_tablespaces":          true,
+}
+
+// unitSuffixes name the unit of a numeric field by the end of its name,
+// longest suffix first.
+var unitSuffixes = []struct {
+	Suffix string
+	Format func(float64) string
+}{
+	{"_bytes_per_second", func(v float64) string { return formatBytes(v) + "/s" }},
+	{"_bytes", formatBytes},
+	{"_ms", func(v float64) string { return formatDuration(time.Duration(v * float64(time.Millisecond))) }},
+	{"_seconds", func(v float64) string { return formatDuration(time.Duration(v * float64(time.Second))) }},
+}
+
+func unitsProperty() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: "How sizes and durations are reported: raw (numbers of bytes, ms, seconds), human (strings such as 1.5 GiB or 2.3s) or both, adding a _human field next to each number (default: raw)",
+		Enum:        []any{"raw", "human", "both"},
+	}
+}
+
+// formatBytes uses binary units, as pg_size_pretty does.
+func formatBytes(bytes float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for (bytes >= 1024 || bytes <= -1024) && i < len(units)-1 {
+		bytes /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", bytes)
+	}
+	return fmt.Sprintf("%.1f %s", bytes, units[i])
+}
+
+// formatDuration keeps three or four significant digits below a minute and
+// whole seconds above.
+func formatDuration(d time.Duration) string {
+	switch {
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(10 * time.Microsecond).String()
+	case d < time.Minute:
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// humanizeUnits rewrites the numeric fields named by unitSuffixes in a
+// decoded JSON value, in place.
+func humanizeUnits(value interface{}, both bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			number, ok := nested.(float64)
+			if !ok {
+				v[key] = humanizeUnits(nested, both)
+				continue
+			}
+			for _, unit := range unitSuffixes {
+				if !strings.HasSuffix(key, unit.Suffix) {
+					continue
+				}
+				if both {
+					v[key+"_human"] = unit.Format(number)
+				} else {
+					v[key] = unit.Format(number)
+				}
+				break
+			}
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = humanizeUnits(nested, both)
+		}
+	}
+	return value
+}
+
+// withUnits applies the units argument to a successful result, replacing its
+// JSON text and structured content. Further content blocks (warnings) are kept.
+func (s *serverState) withUnits(req *mcp.CallToolRequest, result *mcp.CallToolResult, data any) (*mcp.CallToolResult, any, error) {
+	units, _ := getRawArgs(req)["units"].(string)
+	if units == "" || units == "raw" || result == nil || result.IsError || data == nil {
+		return result, data, nil
+	}
+	if units != "human" && units != "both" {
+		return s.returnErrorResult("Unknown units %q, use raw, human or both", units)
+	}
+	// text output, such as EXPLAIN in text format, has no fields to convert
+	if _, ok := data.(string); ok {
+		return result, data, nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal results: %v", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal results: %v", err)
+	}
+	decoded = humanizeUnits(decoded, units == "both")
+
+	converted, data, err := returnJSONResult(decoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(result.Content) > 0 {
+		if _, ok := result.Content[0].(*mcp.TextContent); ok {
+			result.Content[0] = converted.Content[0]
+		}
+	}
+	return result, data, nil
+}
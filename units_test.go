@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestFormatUnits(t *testing.T) {
+	bytes := map[float64]string{512: "512 B", 1536: "1.5 KiB", 5 * 1 << 30: "5.0 GiB"}
+	for value, expected := range bytes {
+		if got := formatBytes(value); got != expected {
+			t.Errorf("formatBytes(%v) = %q, expected %q", value, got, expected)
+		}
+	}
+	durations := map[time.Duration]string{
+		1234567 * time.Nanosecond:  "1.23ms",
+		2345678 * time.Microsecond: "2.346s",
+		(3*60 + 4) * time.Second:   "3m4s",
+	}
+	for value, expected := range durations {
+		if got := formatDuration(value); got != expected {
+			t.Errorf("formatDuration(%v) = %q, expected %q", value, got, expected)
+		}
+	}
+}
+
+func TestWithUnits(t *testing.T) {
+	call := func(units string) map[string]interface{} {
+		arguments, _ := json.Marshal(map[string]string{"units": units})
+		req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "pool_stats", Arguments: arguments}}
+		result, data, err := returnJSONResult(map[string]interface{}{
+			"size_bytes":                  1536,
+			"average_acquire_ms":          2.5,
+			"indexes":                     []map[string]interface{}{{"size_bytes": 2048}},
+			"estimated_seconds":           90,
+			"throughput_bytes_per_second": 1 << 20,
+			"rows":                        100,
+		})
+		result, data, err = testServer.withUnits(req, result, data)
+		if err != nil || result.IsError {
+			t.Fatalf("withUnits failed: %v %v", err, result)
+		}
+		return data.(map[string]interface{})
+	}
+
+	human := call("human")
+	if human["size_bytes"] != "1.5 KiB" || human["average_acquire_ms"] != "2.5ms" || human["estimated_seconds"] != "1m30s" ||
+		human["throughput_bytes_per_second"] != "1.0 MiB/s" || human["rows"] != 100.0 {
+		t.Errorf("Unexpected humanized result %v", human)
+	}
+	if nested := human["indexes"].([]interface{})[0].(map[string]interface{}); nested["size_bytes"] != "2.0 KiB" {
+		t.Errorf("Expected nested sizes to be humanized, got %v", nested)
+	}
+
+	both := call("both")
+	if both["size_bytes"] != 1536.0 || both["size_bytes_human"] != "1.5 KiB" {
+		t.Errorf("Expected raw and humanized values, got %v", both)
+	}
+	if raw := call("raw"); raw["size_bytes"] != 1536 {
+		t.Errorf("Expected raw values to be left alone, got %v", raw)
+	}
+}
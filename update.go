@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type UpdateRowsArgs struct {
+	TableName string         `json:"table_name" jsonschema:"Name of the table, optionally schema-qualified (quote mixed-case names)"`
+	Schema    string         `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Set       map[string]any `json:"set" jsonschema:"New values by column name, such as {\"status\": \"archived\"}. null sets NULL"`
+	Where     []RowCondition `json:"where" jsonschema:"Conditions the rows must all meet, such as [{\"column\": \"id\", \"op\": \"=\", \"value\": 7}]. Required"`
+	Commit    bool           `json:"commit,omitempty" jsonschema:"Run and commit the update. Without it nothing is written and only the statement and the number of matching rows are reported (default: false)"`
+}
+
+// UpdateRows runs an UPDATE built from structured arguments, which can't
+// leave out the WHERE clause the way hand-written SQL can. Without commit it
+// only explains the statement and counts the matching rows, so the caller
+// can check the count before applying it without firing triggers or taking
+// locks.
+func (s *serverState) UpdateRows(ctx context.Context, req *mcp.CallToolRequest, args UpdateRowsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if len(args.Set) == 0 {
+		return s.returnErrorResult("set is required: the columns to update and their new values")
+	}
+	if len(args.Where) == 0 {
+		return s.returnErrorResult("where is required, update_rows never updates every row of a table")
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	columns, err := s.tableColumnTypes(ctx, schema, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up columns: %v", err)
+	}
+
+	assignments := make([]string, 0, len(args.Set))
+	for _, column := range sortedKeys(args.Set) {
+		typeName, ok := columns[column]
+		if !ok {
+			return s.returnErrorResult("column %q does not exist", column)
+		}
+		literal, err := valueLiteral(args.Set[column], typeName)
+		if err != nil {
+			return s.returnErrorResult("invalid value for %s: %v", column, err)
+		}
+		assignments = append(assignments, quoteIdentifier(column)+" = "+literal)
+	}
+	where, err := buildWhere(args.Where, columns)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	table := qualifiedName(schema, tableName)
+	statement := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(assignments, ", "), where)
+
+	if !args.Commit {
+		return s.previewRowWrite(ctx, req, statement, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", table, where), true)
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("update_rows")
+	}
+	if s.config.RequireApproval {
+		change, err := s.queueChange(ctx, req, "update_rows", fmt.Sprintf("Update rows of %s", table), statement)
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+	return s.runRowWrite(ctx, req, statement, -1)
+}
+
+// previewRowWrite reports what a statement built by update_rows or
+// anonymize_table would touch without running it: countQuery counts the
+// rows, and with explain the statement is planned in the same read-only
+// transaction, which catches bad values and columns.
+func (s *serverState) previewRowWrite(ctx context.Context, req *mcp.CallToolRequest, statement, countQuery string, explain bool) (*mcp.CallToolResult, any, error) {
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if explain {
+		if _, err := tx.Exec(ctx, "EXPLAIN "+statement); err != nil {
+			return s.returnErrorResult("Statement error: %v", err)
+		}
+	}
+	var count int64
+	if err := tx.QueryRow(ctx, countQuery).Scan(&count); err != nil {
+		return s.returnErrorResult("Query error: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"statement":     statement,
+		"matching_rows": count,
+		"committed":     false,
+		"notice":        s.localize("Nothing was changed. Call again with commit set to true to apply the statement"),
+	})
+	return s.withWarnings(result, notices), data, err
+}
+
+// runRowWrite runs and commits a statement built by update_rows,
+// delete_rows or anonymize_table. A statement affecting other than
+// expectedRows rows, when not negative, is rolled back.
+func (s *serverState) runRowWrite(ctx context.Context, req *mcp.CallToolRequest, statement string, expectedRows int64) (*mcp.CallToolResult, any, error) {
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, statement)
+	if err != nil {
+		return s.returnErrorResult("Statement error: %v", err)
+	}
+	if expectedRows >= 0 && tag.RowsAffected() != expectedRows {
+		return s.returnErrorResult("The statement affected %d rows instead of the expected %d and was rolled back", tag.RowsAffected(), expectedRows)
+	}
+	if err := s.finishWrite(ctx, tx); err != nil {
+		return nil, nil, fmt.Errorf("failed to finish transaction: %v", err)
+	}
+
+	response := s.labelDryRun(map[string]interface{}{
+		"statement":     statement,
+		"rows_affected": tag.RowsAffected(),
+		"committed":     !s.config.DryRun,
+	})
+	if analyzed, err := s.autoAnalyze(ctx, statement, tag.RowsAffected()); err != nil {
+		response["analyze_error"] = err.Error()
+	} else if len(analyzed) > 0 {
+		response["analyzed"] = analyzed
+	}
+	result, data, err := returnJSONResult(response)
+	return s.withWarnings(result, notices), data, err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type callStatsKey struct{}
+
+// callStats collects usage a handler reports about its own call, the session
+// middleware attaches one to the context of every tool call.
+type callStats struct {
+	rowsScanned int64
+}
+
+func withCallStats(ctx context.Context) (context.Context, *callStats) {
+	stats := &callStats{}
+	return context.WithValue(ctx, callStatsKey{}, stats), stats
+}
+
+// recordRowsScanned adds the rows the transaction has read so far, taken from
+// the backend-local counters behind pg_stat_xact_user_tables. It is best
+// effort and must be called once all result rows have been read.
+func recordRowsScanned(ctx context.Context, tx pgx.Tx) {
+	stats, ok := ctx.Value(callStatsKey{}).(*callStats)
+	if !ok {
+		return
+	}
+
+	var scanned int64
+	err := tx.QueryRow(ctx, `
+		SELECT COALESCE(SUM(seq_tup_read + COALESCE(idx_tup_fetch, 0)), 0)::bigint
+		FROM pg_stat_xact_user_tables
+	`).Scan(&scanned)
+	if err == nil {
+		stats.rowsScanned += scanned
+	}
+}
+
+// usageTotals is the cost of a group of tool calls.
+type usageTotals struct {
+	Calls         int   `json:"calls"`
+	Errors        int   `json:"errors"`
+	DurationMs    int64 `json:"duration_ms"`
+	RowsScanned   int64 `json:"rows_scanned"`
+	BytesReturned int64 `json:"bytes_returned"`
+}
+
+func (u *usageTotals) add(event sessionEvent) {
+	u.Calls++
+	if event.IsError {
+		u.Errors++
+	}
+	u.DurationMs += event.DurationMs
+	u.RowsScanned += event.RowsScanned
+	u.BytesReturned += event.BytesReturned
+}
+
+func (u *usageTotals) merge(other usageTotals) {
+	u.Calls += other.Calls
+	u.Errors += other.Errors
+	u.DurationMs += other.DurationMs
+	u.RowsScanned += other.RowsScanned
+	u.BytesReturned += other.BytesReturned
+}
+
+func summarizeUsage(events []sessionEvent) (usageTotals, map[string]*usageTotals) {
+	var totals usageTotals
+	byTool := make(map[string]*usageTotals)
+	for _, event := range events {
+		totals.add(event)
+		if byTool[event.Tool] == nil {
+			byTool[event.Tool] = &usageTotals{}
+		}
+		byTool[event.Tool].add(event)
+	}
+	return totals, byTool
+}
+
+// usage sums a session's calls, including those evicted from its log.
+func (l *sessionLog) usage() (usageTotals, map[string]*usageTotals) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	totals, byTool := summarizeUsage(l.events)
+	totals.merge(l.droppedUsage)
+	for tool, dropped := range l.droppedByTool {
+		if byTool[tool] == nil {
+			byTool[tool] = &usageTotals{}
+		}
+		byTool[tool].merge(*dropped)
+	}
+	return totals, byTool
+}
+
+// logUsageTotals writes every session's totals to the server log, called
+// when the server shuts down.
+func (s *serverState) logUsageTotals() {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+
+	for key, history := range s.sessions.logs {
+		totals, _ := history.usage()
+		if totals.Calls == 0 {
+			continue
+		}
+		if key == "" {
+			key = "stdio"
+		}
+		log.Printf("Session %s usage: %d calls (%d errors), %d ms, %d rows scanned, %d bytes returned",
+			key, totals.Calls, totals.Errors, totals.DurationMs, totals.RowsScanned, totals.BytesReturned)
+	}
+}
+
+type GetUsageArgs struct {
+	AllSessions bool `json:"all_sessions,omitempty" jsonschema:"Report every session on the server instead of only the current one (default: false)"`
+}
+
+func (s *serverState) GetUsage(ctx context.Context, req *mcp.CallToolRequest, args GetUsageArgs) (*mcp.CallToolResult, any, error) {
+	keys := []string{sessionKey(req)}
+	if args.AllSessions {
+		s.sessions.mu.Lock()
+		keys = keys[:0]
+		for key := range s.sessions.logs {
+			keys = append(keys, key)
+		}
+		s.sessions.mu.Unlock()
+		sort.Strings(keys)
+	}
+
+	var reports []map[string]interface{}
+	for _, key := range keys {
+		history := s.sessions.get(key)
+		totals, byTool := history.usage()
+		reports = append(reports, map[string]interface{}{
+			"session":    key,
+			"started":    history.started,
+			"elapsed_ms": time.Since(history.started).Milliseconds(),
+			"totals":     totals,
+			"by_tool":    byTool,
+		})
+	}
+
+	if !args.AllSessions {
+		if len(reports) != 1 {
+			return nil, nil, fmt.Errorf("failed to load session usage")
+		}
+		return returnJSONResult(reports[0])
+	}
+	return returnJSONResult(reports)
+}
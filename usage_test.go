@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestRecordRowsScanned(t *testing.T) {
+	ctx, stats := withCallStats(context.Background())
+
+	args := QueryArgs{Query: "SELECT COUNT(*) FROM users"}
+	result, _, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	if stats.rowsScanned < 1000 {
+		t.Errorf("Expected at least 1000 rows scanned, got %d", stats.rowsScanned)
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	ctx := context.Background()
+
+	testServer.sessions.mu.Lock()
+	delete(testServer.sessions.logs, "")
+	testServer.sessions.mu.Unlock()
+
+	handler := testServer.sessionMiddleware(func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		callReq := req.(*mcp.CallToolRequest)
+		var args QueryArgs
+		json.Unmarshal(callReq.Params.Arguments, &args)
+		return executeQueryResult(testServer.ExecuteQuery(ctx, callReq, args))
+	})
+
+	for _, query := range []string{"SELECT * FROM users LIMIT 10", "SELECT * FROM missing_table"} {
+		arguments, _ := json.Marshal(QueryArgs{Query: query})
+		req := &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Name: "query", Arguments: arguments}}
+		if _, err := handler(ctx, "tools/call", req); err != nil {
+			t.Fatalf("Middleware returned error: %v", err)
+		}
+	}
+
+	args := GetUsageArgs{}
+	result, data, err := testServer.GetUsage(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("GetUsage failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	report := data.(map[string]interface{})
+	totals := report["totals"].(usageTotals)
+	if totals.Calls != 2 || totals.Errors != 1 {
+		t.Errorf("Expected 2 calls with 1 error, got %+v", totals)
+	}
+	if totals.RowsScanned == 0 || totals.BytesReturned == 0 {
+		t.Errorf("Expected rows scanned and bytes returned to be tracked, got %+v", totals)
+	}
+
+	byTool := report["by_tool"].(map[string]*usageTotals)
+	if byTool["query"] == nil || byTool["query"].Calls != 2 {
+		t.Errorf("Expected 2 query calls, got %v", byTool)
+	}
+}
+
+func executeQueryResult(result *mcp.CallToolResult, _ any, err error) (mcp.Result, error) {
+	return result, err
+}
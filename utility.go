@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// explainableKeywords are the statements EXPLAIN accepts, apart from
+// CREATE TABLE AS and CREATE MATERIALIZED VIEW AS which are checked separately.
+var explainableKeywords = map[string]bool{
+	"SELECT":  true,
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"MERGE":   true,
+	"VALUES":  true,
+	"TABLE":   true,
+	"WITH":    true,
+	"EXECUTE": true,
+	"DECLARE": true,
+}
+
+// lockImpact describes what each table lock mode blocks while it is held.
+var lockImpact = map[string]string{
+	"ACCESS SHARE":           "Only blocks ACCESS EXCLUSIVE locks (ALTER TABLE, DROP, TRUNCATE, VACUUM FULL). Reads and writes continue",
+	"ROW EXCLUSIVE":          "Blocks SHARE and stronger locks such as CREATE INDEX without CONCURRENTLY. Reads and writes continue",
+	"SHARE UPDATE EXCLUSIVE": "Blocks VACUUM, ANALYZE, CREATE INDEX CONCURRENTLY and most schema changes. Reads and writes continue",
+	"SHARE":                  "Blocks INSERT, UPDATE and DELETE for the duration. Reads continue",
+	"SHARE ROW EXCLUSIVE":    "Blocks INSERT, UPDATE and DELETE for the duration. Reads continue",
+	"EXCLUSIVE":              "Blocks INSERT, UPDATE and DELETE for the duration. Plain reads continue",
+	"ACCESS EXCLUSIVE":       "Blocks every other access to the table, including SELECT, for the duration",
+}
+
+// utilityStatement describes a statement EXPLAIN cannot process.
+type utilityStatement struct {
+	Command      string
+	LockMode     string
+	ProgressView string
+	Note         string
+	Target       []string
+}
+
+// utilityOptionWords are skipped when looking for the table a utility
+// statement operates on.
+var utilityOptionWords = map[string]bool{
+	"FULL": true, "FREEZE": true, "VERBOSE": true, "ANALYZE": true, "ONLY": true,
+	"TABLE": true, "CONCURRENTLY": true, "MATERIALIZED": true, "VIEW": true,
+	"IF": true, "EXISTS": true, "INDEX": true,
+}
+
+// utilityTarget finds the first identifier from tokens[start], skipping
+// option keywords and parenthesized option lists.
+func utilityTarget(tokens []sqlToken, start int) []string {
+	for i := start; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Depth > 0 || token.Text == "(" || token.Text == ")" || (token.Word && utilityOptionWords[token.Text]) {
+			continue
+		}
+		return identifierAt(tokens, i)
+	}
+	return nil
+}
+
+// classifyUtility returns nil for statements EXPLAIN can process, and the
+// lock impact and progress view of the ones it cannot.
+func classifyUtility(query string) *utilityStatement {
+	tokens := sqlTokens(query)
+	words := make(map[string]bool)
+	first := -1
+	for i, token := range tokens {
+		if token.Word {
+			words[token.Text] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	keyword := tokens[first].Text
+	if explainableKeywords[keyword] {
+		return nil
+	}
+
+	switch keyword {
+	case "COPY":
+		if first+1 < len(tokens) && tokens[first+1].Text == "(" {
+			return &utilityStatement{Command: "COPY", LockMode: "ACCESS SHARE", ProgressView: "pg_stat_progress_copy",
+				Note: "EXPLAIN the query inside COPY (...) TO to see how the exported rows are read"}
+		}
+		statement := &utilityStatement{Command: "COPY", LockMode: "ACCESS SHARE", ProgressView: "pg_stat_progress_copy",
+			Note: "Reads the whole table, use estimate_row_count to size the export", Target: utilityTarget(tokens, first+1)}
+		if words["FROM"] {
+			statement.LockMode = "ROW EXCLUSIVE"
+			statement.Note = "Loads rows into the table, firing its insert triggers and updating every index"
+		}
+		return statement
+	case "VACUUM":
+		if words["FULL"] {
+			return &utilityStatement{Command: "VACUUM FULL", LockMode: "ACCESS EXCLUSIVE", ProgressView: "pg_stat_progress_cluster",
+				Note: "Rewrites the whole table and its indexes, needing free disk space roughly equal to their size", Target: utilityTarget(tokens, first+1)}
+		}
+		return &utilityStatement{Command: "VACUUM", LockMode: "SHARE UPDATE EXCLUSIVE", ProgressView: "pg_stat_progress_vacuum",
+			Target: utilityTarget(tokens, first+1)}
+	case "ANALYZE":
+		return &utilityStatement{Command: "ANALYZE", LockMode: "SHARE UPDATE EXCLUSIVE", ProgressView: "pg_stat_progress_analyze",
+			Note: "Reads a sample of rows rather than the whole table", Target: utilityTarget(tokens, first+1)}
+	case "CLUSTER":
+		return &utilityStatement{Command: "CLUSTER", LockMode: "ACCESS EXCLUSIVE", ProgressView: "pg_stat_progress_cluster",
+			Note: "Rewrites the whole table and its indexes", Target: utilityTarget(tokens, first+1)}
+	case "REINDEX":
+		if words["CONCURRENTLY"] {
+			return &utilityStatement{Command: "REINDEX CONCURRENTLY", LockMode: "SHARE UPDATE EXCLUSIVE", ProgressView: "pg_stat_progress_create_index",
+				Target: utilityTarget(tokens, first+1)}
+		}
+		return &utilityStatement{Command: "REINDEX", LockMode: "SHARE", ProgressView: "pg_stat_progress_create_index",
+			Note: "Also takes ACCESS EXCLUSIVE on each index being rebuilt, blocking queries that would use it", Target: utilityTarget(tokens, first+1)}
+	case "TRUNCATE":
+		return &utilityStatement{Command: "TRUNCATE", LockMode: "ACCESS EXCLUSIVE", Target: utilityTarget(tokens, first+1)}
+	case "DROP":
+		return &utilityStatement{Command: "DROP", LockMode: "ACCESS EXCLUSIVE"}
+	case "ALTER":
+		return &utilityStatement{Command: "ALTER", LockMode: "ACCESS EXCLUSIVE",
+			Note: "Most ALTER TABLE forms take ACCESS EXCLUSIVE, some (such as VALIDATE CONSTRAINT) take weaker locks. Changing a column type rewrites the table", Target: utilityTarget(tokens, first+1)}
+	case "REFRESH":
+		if words["CONCURRENTLY"] {
+			return &utilityStatement{Command: "REFRESH MATERIALIZED VIEW CONCURRENTLY", LockMode: "EXCLUSIVE", Target: utilityTarget(tokens, first+1)}
+		}
+		return &utilityStatement{Command: "REFRESH MATERIALIZED VIEW", LockMode: "ACCESS EXCLUSIVE", Target: utilityTarget(tokens, first+1)}
+	case "CREATE":
+		return classifyCreate(tokens, first, words)
+	}
+
+	return &utilityStatement{Command: keyword}
+}
+
+func classifyCreate(tokens []sqlToken, first int, words map[string]bool) *utilityStatement {
+	// CREATE TABLE ... AS and CREATE MATERIALIZED VIEW ... AS run a query EXPLAIN can plan
+	if words["TABLE"] || words["MATERIALIZED"] {
+		for i := first; i+1 < len(tokens); i++ {
+			if tokens[i].Text == "AS" && tokens[i].Depth == 0 && (tokens[i+1].Text == "(" || explainableKeywords[tokens[i+1].Text]) {
+				return nil
+			}
+		}
+	}
+
+	if words["INDEX"] {
+		var target []string
+		for i := first; i < len(tokens); i++ {
+			if tokens[i].Text == "ON" && tokens[i].Depth == 0 {
+				target = utilityTarget(tokens, i+1)
+				break
+			}
+		}
+		if words["CONCURRENTLY"] {
+			return &utilityStatement{Command: "CREATE INDEX CONCURRENTLY", LockMode: "SHARE UPDATE EXCLUSIVE", ProgressView: "pg_stat_progress_create_index",
+				Note: "Scans the table twice and waits for running transactions, but does not block writes", Target: target}
+		}
+		return &utilityStatement{Command: "CREATE INDEX", LockMode: "SHARE", ProgressView: "pg_stat_progress_create_index",
+			Note: "Use CREATE INDEX CONCURRENTLY to avoid blocking writes", Target: target}
+	}
+
+	return &utilityStatement{Command: "CREATE", Note: "Creates a new object, only the objects it references are locked"}
+}
+
+// explainUtility answers an explain request for a statement EXPLAIN cannot
+// process with what is known about its impact instead.
+func (s *serverState) explainUtility(ctx context.Context, utility *utilityStatement) (*mcp.CallToolResult, any, error) {
+	response := map[string]interface{}{
+		"explainable": false,
+		"command":     utility.Command,
+		"message": fmt.Sprintf("EXPLAIN only supports SELECT, INSERT, UPDATE, DELETE, MERGE, VALUES, EXECUTE, DECLARE, "+
+			"CREATE TABLE AS and CREATE MATERIALIZED VIEW AS, not %s", utility.Command),
+	}
+	if utility.LockMode != "" {
+		response["lock_mode"] = utility.LockMode
+		response["lock_impact"] = lockImpact[utility.LockMode]
+	}
+	if utility.ProgressView != "" {
+		response["progress_view"] = utility.ProgressView
+		response["progress_query"] = fmt.Sprintf("SELECT * FROM %s", utility.ProgressView)
+	}
+	if utility.Note != "" {
+		response["note"] = utility.Note
+	}
+
+	if len(utility.Target) > 0 {
+		var table *string
+		var estimatedRows, totalBytes *int64
+		err := s.pool.QueryRow(ctx, `
+			SELECT c.oid::regclass::text, GREATEST(c.reltuples, 0)::bigint, pg_total_relation_size(c.oid)
+			FROM pg_class c
+			WHERE c.oid = to_regclass($1)
+		`, pgx.Identifier(utility.Target).Sanitize()).Scan(&table, &estimatedRows, &totalBytes)
+		if err != nil && err != pgx.ErrNoRows {
+			return nil, nil, fmt.Errorf("failed to look up %v: %v", utility.Target, err)
+		}
+		if err == nil {
+			response["table"] = *table
+			response["estimated_rows"] = *estimatedRows
+			response["total_size_bytes"] = *totalBytes
+		}
+	}
+
+	return returnJSONResult(response)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestClassifyUtility(t *testing.T) {
+	cases := []struct {
+		query    string
+		command  string
+		lockMode string
+		target   string
+	}{
+		{"SELECT * FROM users", "", "", ""},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", "", "", ""},
+		{"CREATE TABLE user_copy AS SELECT * FROM users", "", "", ""},
+		{"CREATE MATERIALIZED VIEW mv AS (SELECT 1)", "", "", ""},
+		{"VACUUM (VERBOSE, ANALYZE) users", "VACUUM", "SHARE UPDATE EXCLUSIVE", "users"},
+		{"vacuum full public.users", "VACUUM FULL", "ACCESS EXCLUSIVE", "public.users"},
+		{"CREATE INDEX idx_posts_title ON posts (title)", "CREATE INDEX", "SHARE", "posts"},
+		{"CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx ON ONLY public.posts USING btree (id)", "CREATE INDEX CONCURRENTLY", "SHARE UPDATE EXCLUSIVE", "public.posts"},
+		{"COPY users TO STDOUT", "COPY", "ACCESS SHARE", "users"},
+		{"COPY users FROM STDIN", "COPY", "ROW EXCLUSIVE", "users"},
+		{"COPY (SELECT * FROM users) TO STDOUT", "COPY", "ACCESS SHARE", ""},
+		{"ALTER TABLE IF EXISTS ONLY users ADD COLUMN age int", "ALTER", "ACCESS EXCLUSIVE", "users"},
+		{"CREATE VIEW v AS SELECT 1", "CREATE", "", ""},
+		{"LISTEN changes", "LISTEN", "", ""},
+	}
+
+	for _, c := range cases {
+		utility := classifyUtility(c.query)
+		if c.command == "" {
+			if utility != nil {
+				t.Errorf("Expected %q to be explainable, got %+v", c.query, utility)
+			}
+			continue
+		}
+		if utility == nil {
+			t.Errorf("Expected %q to be classified as %s", c.query, c.command)
+			continue
+		}
+		target := strings.Join(utility.Target, ".")
+		if utility.Command != c.command || utility.LockMode != c.lockMode || target != c.target {
+			t.Errorf("classifyUtility(%q) = %s/%s/%s, expected %s/%s/%s",
+				c.query, utility.Command, utility.LockMode, target, c.command, c.lockMode, c.target)
+		}
+	}
+}
+
+func TestExplainUtilityStatement(t *testing.T) {
+	ctx := context.Background()
+
+	args := ExplainAnalyzeArgs{Query: "CREATE INDEX idx_posts_title ON posts (title)"}
+	result, data, err := testServer.ExplainAnalyze(ctx, createMockRequest(args), args)
+
+	if err != nil {
+		t.Fatalf("ExplainAnalyze failed: %v", err)
+	}
+
+	if result == nil || result.IsError {
+		t.Fatalf("Expected a structured alternative instead of an error, got %v", result)
+	}
+
+	response := data.(map[string]interface{})
+	if response["explainable"] != false || response["progress_view"] != "pg_stat_progress_create_index" {
+		t.Errorf("Unexpected response: %v", response)
+	}
+	if response["table"] != "posts" || response["estimated_rows"] == nil {
+		t.Errorf("Expected the target table to be resolved, got %v", response)
+	}
+
+	var exists bool
+	testServer.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_class WHERE relname = 'idx_posts_title')").Scan(&exists)
+	if exists {
+		t.Error("Expected the index not to be created")
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultViolationSamples = 10
+
+type ValidateConstraintsArgs struct {
+	TableName         string   `json:"table_name,omitempty" jsonschema:"Table to check, optionally schema-qualified. Without it the NOT VALID constraints of the schema are listed"`
+	Schema            string   `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	Type              string   `json:"type,omitempty" jsonschema:"A proposed constraint to check the table's rows against: unique, foreign_key, check or not_null. Without it the table's NOT VALID constraints are checked"`
+	Columns           []string `json:"columns,omitempty" jsonschema:"Columns of a proposed unique, foreign_key or not_null constraint"`
+	Check             string   `json:"check,omitempty" jsonschema:"Boolean expression of a proposed check constraint"`
+	ReferencesTable   string   `json:"references_table,omitempty" jsonschema:"Table a proposed foreign key references, optionally schema-qualified"`
+	ReferencesColumns []string `json:"references_columns,omitempty" jsonschema:"Referenced columns of a proposed foreign key (default: the referenced table's primary key)"`
+	Limit             int      `json:"limit,omitempty" jsonschema:"Maximum offending rows to return per constraint (default: 10)"`
+}
+
+// constraintCheck is a constraint, existing or proposed, and the rows of its
+// table that break it.
+type constraintCheck struct {
+	Table      string
+	Name       string
+	Type       string
+	Definition string
+	// Violations selects the offending rows, from the table aliased as v
+	Violations string
+	// Statement adds or validates the constraint once nothing violates it
+	Statement string
+}
+
+// ValidateConstraints finds the rows that would stop a constraint from
+// being added, or a NOT VALID one from being validated, so they can be
+// fixed before the DDL is suggested.
+func (s *serverState) ValidateConstraints(ctx context.Context, req *mcp.CallToolRequest, args ValidateConstraintsArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	if args.TableName == "" {
+		if args.Type != "" {
+			return s.returnErrorResult("table_name is required to check a proposed constraint")
+		}
+		schema := getSchema(args.Schema)
+		if !s.schemaAllowed(schema) {
+			return s.returnNotAccessible(schema)
+		}
+		checks, err := s.notValidConstraints(ctx, schema, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		constraints := []map[string]interface{}{}
+		for _, check := range checks {
+			constraints = append(constraints, map[string]interface{}{
+				"table":      check.Table,
+				"constraint": check.Name,
+				"type":       check.Type,
+				"definition": check.Definition,
+			})
+		}
+		return returnJSONResult(map[string]interface{}{
+			"schema":                schema,
+			"not_valid_constraints": constraints,
+		})
+	}
+
+	schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	if !s.relationAllowed(schema, tableName) {
+		return s.returnNotAccessible(qualifiedName(schema, tableName))
+	}
+	table := qualifiedName(schema, tableName)
+
+	var checks []constraintCheck
+	if args.Type == "" {
+		if checks, err = s.notValidConstraints(ctx, schema, tableName); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		check, err := s.proposedConstraint(ctx, schema, tableName, args)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		checks = []constraintCheck{check}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultViolationSamples
+	}
+
+	tx, notices, err := s.beginSession(ctx, req, pgx.TxOptions{AccessMode: pgx.ReadOnly}, "")
+	if err != nil {
+		return s.returnErrorResult("%v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// a proposed check is an expression from the caller, which can read
+	// other relations through subqueries and functions like a query can
+	if args.Type != "" {
+		if err := s.checkStatementAccess(ctx, tx, fmt.Sprintf("SELECT FROM %s AS v WHERE %s", table, checks[0].Violations)); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+	}
+
+	results := []map[string]interface{}{}
+	policies := make(map[string]columnPolicy)
+	masked := make(map[string][]string)
+	var truncated []string
+	for _, check := range checks {
+		var count int64
+		from := fmt.Sprintf("FROM %s AS v WHERE %s", table, check.Violations)
+		if err := tx.QueryRow(ctx, "SELECT count(*) "+from).Scan(&count); err != nil {
+			return s.returnErrorResult("Validation query for %s failed: %v", check.Definition, err)
+		}
+		rows, err := tx.Query(ctx, fmt.Sprintf("SELECT v.* %s LIMIT %d", from, limit))
+		if err != nil {
+			return s.returnErrorResult("Validation query for %s failed: %v", check.Definition, err)
+		}
+		fields := slices.Clone(rows.FieldDescriptions())
+		sample, err := collectRows(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		samplePolicies, err := s.resultColumnPolicies(ctx, tx, fields)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to look up column policies: %v", err)
+		}
+
+		binaryTruncated, _ := encodeBinaryRows(sample, "base64", s.config.MaxBinaryBytes)
+		truncated = append(truncated, binaryTruncated...)
+		applyColumnPolicies(sample, samplePolicies)
+		for column, kinds := range s.redactRows(sample) {
+			masked[column] = append(masked[column], kinds...)
+		}
+		encodeNumericRows(sample, "string")
+		encodeIntervalRows(sample, "iso8601")
+		for column, policy := range samplePolicies {
+			policies[column] = policy
+		}
+
+		result := map[string]interface{}{
+			"type":           check.Type,
+			"definition":     check.Definition,
+			"violating_rows": count,
+			"sample":         sample,
+			"would_pass":     count == 0,
+		}
+		if check.Name != "" {
+			result["constraint"] = check.Name
+		}
+		if count == 0 {
+			result["statement"] = check.Statement
+		}
+		results = append(results, result)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	result, data, err := returnJSONResult(map[string]interface{}{
+		"table":       table,
+		"constraints": results,
+	})
+	warnings := append(notices, s.binaryWarnings(truncated, nil)...)
+	warnings = append(warnings, s.columnPolicyWarnings(policies)...)
+	return s.withWarnings(result, append(warnings, s.piiWarnings(masked)...)), data, err
+}
+
+// notValidConstraints lists the check and foreign key constraints added
+// NOT VALID to a table, or to every table of a schema when table is empty.
+func (s *serverState) notValidConstraints(ctx context.Context, schema, table string) ([]constraintCheck, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT
+			con.conname::text,
+			con.contype::text,
+			pg_get_constraintdef(con.oid),
+			c.relname::text,
+			COALESCE(pg_get_expr(con.conbin, con.conrelid), ''),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			COALESCE(rn.nspname::text, ''),
+			COALESCE(rc.relname::text, ''),
+			ARRAY(
+				SELECT a.attname::text
+				FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum
+				ORDER BY k.ord
+			)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_class rc ON rc.oid = con.confrelid
+		LEFT JOIN pg_namespace rn ON rn.oid = rc.relnamespace
+		WHERE NOT con.convalidated AND con.contype IN ('c', 'f')
+			AND n.nspname = $1 AND ($2 = '' OR c.relname = $2)
+		ORDER BY c.relname, con.conname
+	`, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list constraints: %v", err)
+	}
+	defer rows.Close()
+
+	var checks []constraintCheck
+	for rows.Next() {
+		var name, contype, definition, relname, expression, refSchema, refTable string
+		var columns, refColumns []string
+		if err := rows.Scan(&name, &contype, &definition, &relname, &expression, &columns, &refSchema, &refTable, &refColumns); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, relname) {
+			continue
+		}
+		check := constraintCheck{
+			Table:      qualifiedName(schema, relname),
+			Name:       name,
+			Definition: definition,
+			Statement:  fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", qualifiedName(schema, relname), quoteIdentifier(name)),
+		}
+		if contype == "c" {
+			check.Type = "check"
+			check.Violations = checkViolations(expression)
+		} else {
+			check.Type = "foreign_key"
+			check.Violations = foreignKeyViolations(columns, qualifiedName(refSchema, refTable), refColumns)
+		}
+		checks = append(checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %v", err)
+	}
+	return checks, nil
+}
+
+// proposedConstraint checks the arguments describing a constraint to add
+// and builds the query for the rows breaking it.
+func (s *serverState) proposedConstraint(ctx context.Context, schema, tableName string, args ValidateConstraintsArgs) (constraintCheck, error) {
+	table := qualifiedName(schema, tableName)
+
+	check := constraintCheck{Type: strings.ToLower(args.Type)}
+	if check.Type == "check" {
+		if strings.TrimSpace(args.Check) == "" {
+			return constraintCheck{}, fmt.Errorf("check is required for a check constraint")
+		}
+		check.Definition = fmt.Sprintf("CHECK (%s)", args.Check)
+		check.Violations = checkViolations(args.Check)
+		check.Statement = fmt.Sprintf("ALTER TABLE %s ADD %s", table, check.Definition)
+		return check, nil
+	}
+
+	if len(args.Columns) == 0 {
+		return constraintCheck{}, fmt.Errorf("columns are required for a %s constraint", args.Type)
+	}
+	types, err := s.tableColumnTypes(ctx, schema, tableName)
+	if err != nil {
+		return constraintCheck{}, fmt.Errorf("failed to look up columns: %v", err)
+	}
+	columns := make([]string, len(args.Columns))
+	for i, column := range args.Columns {
+		if _, ok := types[column]; !ok {
+			return constraintCheck{}, fmt.Errorf("column %q does not exist in %s", column, table)
+		}
+		columns[i] = quoteIdentifier(column)
+	}
+
+	switch check.Type {
+	case "unique":
+		list := strings.Join(columns, ", ")
+		check.Definition = fmt.Sprintf("UNIQUE (%s)", list)
+		check.Violations = fmt.Sprintf("(%s) IN (SELECT %s FROM %s GROUP BY %s HAVING count(*) > 1)", prefixColumns("v", columns), list, table, list)
+		check.Statement = fmt.Sprintf("ALTER TABLE %s ADD %s", table, check.Definition)
+	case "not_null":
+		var conditions, alters []string
+		for _, column := range columns {
+			conditions = append(conditions, "v."+column+" IS NULL")
+			alters = append(alters, "ALTER COLUMN "+column+" SET NOT NULL")
+		}
+		check.Definition = fmt.Sprintf("NOT NULL (%s)", strings.Join(columns, ", "))
+		check.Violations = strings.Join(conditions, " OR ")
+		check.Statement = fmt.Sprintf("ALTER TABLE %s %s", table, strings.Join(alters, ", "))
+	case "foreign_key":
+		if args.ReferencesTable == "" {
+			return constraintCheck{}, fmt.Errorf("references_table is required for a foreign key")
+		}
+		refSchema, refName, err := s.resolveTableName(ctx, "", args.ReferencesTable)
+		if err != nil {
+			return constraintCheck{}, err
+		}
+		if !s.relationAllowed(refSchema, refName) {
+			return constraintCheck{}, fmt.Errorf("%s is not accessible under the configured allow/deny lists", qualifiedName(refSchema, refName))
+		}
+		refColumns := args.ReferencesColumns
+		if len(refColumns) == 0 {
+			err := s.pool.QueryRow(ctx, `
+				SELECT ARRAY(
+					SELECT a.attname::text
+					FROM pg_constraint con
+					CROSS JOIN LATERAL unnest(con.conkey) WITH ORDINALITY k(attnum, ord)
+					JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+					WHERE con.conrelid = $1::text::regclass AND con.contype = 'p'
+					ORDER BY k.ord
+				)
+			`, pgx.Identifier{refSchema, refName}.Sanitize()).Scan(&refColumns)
+			if err != nil {
+				return constraintCheck{}, fmt.Errorf("failed to look up the primary key of %s: %v", qualifiedName(refSchema, refName), err)
+			}
+			if len(refColumns) == 0 {
+				return constraintCheck{}, fmt.Errorf("%s has no primary key, pass references_columns", qualifiedName(refSchema, refName))
+			}
+		}
+		if len(refColumns) != len(columns) {
+			return constraintCheck{}, fmt.Errorf("%d columns can't reference %d columns", len(columns), len(refColumns))
+		}
+		refTable := qualifiedName(refSchema, refName)
+		quotedRefs := make([]string, len(refColumns))
+		for i, column := range refColumns {
+			quotedRefs[i] = quoteIdentifier(column)
+		}
+		check.Definition = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", strings.Join(columns, ", "), refTable, strings.Join(quotedRefs, ", "))
+		check.Violations = foreignKeyViolations(args.Columns, refTable, refColumns)
+		check.Statement = fmt.Sprintf("ALTER TABLE %s ADD %s", table, check.Definition)
+	default:
+		return constraintCheck{}, fmt.Errorf("unknown type %q, use unique, foreign_key, check or not_null", args.Type)
+	}
+	return check, nil
+}
+
+// checkViolations selects the rows a check expression rejects. A NULL
+// result passes, as it does for the constraint.
+func checkViolations(expression string) string {
+	return fmt.Sprintf("NOT (%s)", expression)
+}
+
+// foreignKeyViolations selects the rows whose key isn't in the referenced
+// table. With MATCH SIMPLE, a key with any NULL column isn't checked.
+func foreignKeyViolations(columns []string, refTable string, refColumns []string) string {
+	var conditions, matches []string
+	for i, column := range columns {
+		conditions = append(conditions, "v."+quoteIdentifier(column)+" IS NOT NULL")
+		matches = append(matches, fmt.Sprintf("r.%s = v.%s", quoteIdentifier(refColumns[i]), quoteIdentifier(column)))
+	}
+	return fmt.Sprintf("%s AND NOT EXISTS (SELECT 1 FROM %s AS r WHERE %s)",
+		strings.Join(conditions, " AND "), refTable, strings.Join(matches, " AND "))
+}
+
+// prefixColumns qualifies quoted column names with a table alias.
+func prefixColumns(alias string, columns []string) string {
+	prefixed := make([]string, len(columns))
+	for i, column := range columns {
+		prefixed[i] = alias + "." + column
+	}
+	return strings.Join(prefixed, ", ")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValidateConstraints(t *testing.T) {
+	ctx := context.Background()
+	_, err := testServer.pool.Exec(ctx, `
+		CREATE TABLE validated_parents (id int PRIMARY KEY);
+		CREATE TABLE validated (id serial PRIMARY KEY, parent_id int, code text, quantity int);
+		INSERT INTO validated_parents VALUES (1), (2);
+		INSERT INTO validated (parent_id, code, quantity) VALUES (1, 'a', 1), (3, 'a', -1), (NULL, 'b', 2), (2, NULL, 0);
+		ALTER TABLE validated ADD CONSTRAINT quantity_positive CHECK (quantity > 0) NOT VALID;
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create tables: %v", err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TABLE validated, validated_parents")
+
+	check := func(args ValidateConstraintsArgs) map[string]interface{} {
+		t.Helper()
+		result, data, err := testServer.ValidateConstraints(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ValidateConstraints failed: %v", err)
+		}
+		if result.IsError {
+			t.Fatalf("Expected successful result, got %v", result)
+		}
+		constraints := data.(map[string]interface{})["constraints"].([]map[string]interface{})
+		if len(constraints) != 1 {
+			t.Fatalf("Expected one constraint, got %v", constraints)
+		}
+		return constraints[0]
+	}
+
+	tests := []struct {
+		args       ValidateConstraintsArgs
+		violations int64
+	}{
+		{ValidateConstraintsArgs{TableName: "validated", Type: "unique", Columns: []string{"code"}}, 2},
+		{ValidateConstraintsArgs{TableName: "validated", Type: "foreign_key", Columns: []string{"parent_id"}, ReferencesTable: "validated_parents"}, 1},
+		{ValidateConstraintsArgs{TableName: "validated", Type: "check", Check: "quantity >= 0"}, 1},
+		{ValidateConstraintsArgs{TableName: "validated", Type: "not_null", Columns: []string{"parent_id", "code"}}, 2},
+		{ValidateConstraintsArgs{TableName: "validated"}, 2},
+	}
+	for _, tt := range tests {
+		constraint := check(tt.args)
+		if constraint["violating_rows"] != tt.violations {
+			t.Errorf("Expected %d violations of %v, got %v", tt.violations, constraint["definition"], constraint["violating_rows"])
+		}
+		if sample := constraint["sample"].([]map[string]interface{}); int64(len(sample)) != tt.violations {
+			t.Errorf("Expected a sample of %d rows, got %v", tt.violations, sample)
+		}
+	}
+
+	constraint := check(ValidateConstraintsArgs{TableName: "validated", Type: "unique", Columns: []string{"id"}})
+	if constraint["would_pass"] != true || constraint["statement"] != `ALTER TABLE public.validated ADD UNIQUE (id)` {
+		t.Errorf("Expected a passing unique constraint with its statement, got %v", constraint)
+	}
+
+	args := ValidateConstraintsArgs{TableName: "validated", Type: "unique", Columns: []string{"missing"}}
+	if result, _, _ := testServer.ValidateConstraints(ctx, createMockRequest(args), args); !result.IsError {
+		t.Error("Expected a missing column to be refused")
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// rowValues decodes the current row like rows.Values, then turns the values
+// pgx decodes into its own types into plain JSON structures: ranges become
+// {lower, upper, bounds} objects, and multidimensional arrays, which
+// Values flattens, are nested again. Unlike Values, a column pgx can't
+// decode doesn't fail the whole result, see decodeValue.
+func rowValues(rows pgx.Rows) ([]interface{}, error) {
+	fields := rows.FieldDescriptions()
+	raw := rows.RawValues()
+	typeMap := rows.Conn().TypeMap()
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value := decodeValue(typeMap, field.DataTypeOID, field.Format, raw[i])
+		// anonymous records decode to slices too, but don't scan as arrays
+		if flat, ok := value.([]interface{}); ok && len(flat) > 0 {
+			var array pgtype.Array[any]
+			if err := typeMap.Scan(field.DataTypeOID, field.Format, raw[i], &array); err == nil && len(array.Dims) > 1 {
+				value = nestArray(flat, array.Dims)
+			}
+		}
+		values[i] = plainValue(value)
+	}
+	return values, rows.Err()
+}
+
+// decodeValue decodes a value with the codec pgx has for its type, falling
+// back to the text PostgreSQL sent for types pgx doesn't know, such as
+// enums and extension types, or fails to decode. Records are decoded field
+// by field, since one field of an unknown type makes pgx reject the whole
+// record. Binary values without a codec are returned as text when they are
+// valid UTF-8, as the binary form of most text-like types is their text,
+// and as bytes otherwise.
+func decodeValue(typeMap *pgtype.Map, oid uint32, format int16, src []byte) interface{} {
+	if src == nil {
+		return nil
+	}
+	if dataType, ok := typeMap.TypeForOID(oid); ok {
+		if value, err := dataType.Codec.DecodeValue(typeMap, oid, format, src); err == nil {
+			return value
+		}
+	}
+	switch {
+	case format == pgtype.TextFormatCode:
+		return string(src)
+	case oid == pgtype.RecordOID:
+		scanner := pgtype.NewCompositeBinaryScanner(typeMap, src)
+		fields := make([]interface{}, 0, scanner.FieldCount())
+		for scanner.Next() {
+			fields = append(fields, decodeValue(typeMap, scanner.OID(), format, scanner.Bytes()))
+		}
+		if scanner.Err() == nil {
+			return fields
+		}
+	case utf8.Valid(src):
+		return string(src)
+	}
+	return slices.Clone(src)
+}
+
+// nestArray splits the elements of a multidimensional array, in row-major
+// order, into nested slices.
+func nestArray(flat []interface{}, dims []pgtype.ArrayDimension) interface{} {
+	if len(dims) <= 1 {
+		return flat
+	}
+	size := 1
+	for _, dim := range dims[1:] {
+		size *= int(dim.Length)
+	}
+	nested := make([]interface{}, 0, dims[0].Length)
+	for start := 0; start+size <= len(flat); start += size {
+		nested = append(nested, nestArray(flat[start:start+size], dims[1:]))
+	}
+	return nested
+}
+
+// plainValue converts ranges and multiranges, also inside arrays.
+func plainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case pgtype.Range[any]:
+		return rangeObject(v)
+	case pgtype.Multirange[pgtype.Range[any]]:
+		ranges := make([]interface{}, len(v))
+		for i, r := range v {
+			ranges[i] = rangeObject(r)
+		}
+		return ranges
+	case []interface{}:
+		for i, element := range v {
+			v[i] = plainValue(element)
+		}
+		return v
+	}
+	return value
+}
+
+// rangeObject renders a range with its bounds in PostgreSQL's notation,
+// such as [) for an inclusive lower and exclusive upper bound. An unbounded
+// side has a nil value and an exclusive bound.
+func rangeObject(r pgtype.Range[any]) interface{} {
+	if !r.Valid {
+		return nil
+	}
+	if r.LowerType == pgtype.Empty {
+		return map[string]interface{}{"empty": true}
+	}
+	bounds := []byte("()")
+	if r.LowerType == pgtype.Inclusive {
+		bounds[0] = '['
+	}
+	if r.UpperType == pgtype.Inclusive {
+		bounds[1] = ']'
+	}
+	return map[string]interface{}{
+		"lower":  r.Lower,
+		"upper":  r.Upper,
+		"bounds": string(bounds),
+	}
+}
+
+// compositeColumns finds the result columns of composite types, or arrays
+// of them, with the type names to cast to. pgx does not know them and
+// returns their text form.
+func compositeColumns(ctx context.Context, resolver relationResolver, typeMap *pgtype.Map, fields []pgconn.FieldDescription) (map[string]string, error) {
+	var unknown []uint32
+	for _, field := range fields {
+		if _, ok := typeMap.TypeForOID(field.DataTypeOID); !ok {
+			unknown = append(unknown, field.DataTypeOID)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil, nil
+	}
+
+	rows, err := resolver.Query(ctx, `
+		SELECT t.oid, format_type(t.oid, NULL)
+		FROM pg_type t
+		LEFT JOIN pg_type e ON e.oid = t.typelem AND t.typcategory = 'A'
+		WHERE t.oid = ANY($1) AND (t.typtype = 'c' OR e.typtype = 'c')
+	`, unknown)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types := make(map[uint32]string)
+	for rows.Next() {
+		var oid uint32
+		var name string
+		if err := rows.Scan(&oid, &name); err != nil {
+			return nil, err
+		}
+		types[oid] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string)
+	for _, field := range fields {
+		if name, ok := types[field.DataTypeOID]; ok {
+			columns[field.Name] = name
+		}
+	}
+	return columns, nil
+}
+
+// renderCompositeRows replaces the composite values of result rows in place
+// with JSON objects keyed by attribute, converted by PostgreSQL in one round
+// trip per column so nested values keep their types.
+func renderCompositeRows(ctx context.Context, resolver relationResolver, columns map[string]string, results []map[string]interface{}) error {
+	for name, typeName := range columns {
+		texts := make([]*string, len(results))
+		for i, row := range results {
+			if text, ok := row[name].(string); ok {
+				texts[i] = &text
+			}
+		}
+		rows, err := resolver.Query(ctx, fmt.Sprintf("SELECT to_json(v::%s)::text FROM unnest($1::text[]) WITH ORDINALITY AS u(v, i) ORDER BY i", typeName), texts)
+		if err != nil {
+			return err
+		}
+		i := 0
+		for rows.Next() {
+			var text *string
+			if err := rows.Scan(&text); err != nil {
+				rows.Close()
+				return err
+			}
+			if text != nil {
+				var value interface{}
+				if err := json.Unmarshal([]byte(*text), &value); err != nil {
+					rows.Close()
+					return err
+				}
+				results[i][name] = value
+			}
+			i++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
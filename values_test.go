@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestNestArray(t *testing.T) {
+	flat := []interface{}{1, 2, 3, 4, 5, 6}
+	nested := nestArray(flat, []pgtype.ArrayDimension{{Length: 3, LowerBound: 1}, {Length: 2, LowerBound: 1}})
+	encoded, _ := json.Marshal(nested)
+	if string(encoded) != "[[1,2],[3,4],[5,6]]" {
+		t.Errorf("Expected a 3x2 array, got %s", encoded)
+	}
+	nested = nestArray(flat, []pgtype.ArrayDimension{{Length: 1, LowerBound: 1}, {Length: 2, LowerBound: 1}, {Length: 3, LowerBound: 1}})
+	encoded, _ = json.Marshal(nested)
+	if string(encoded) != "[[[1,2,3],[4,5,6]]]" {
+		t.Errorf("Expected a 1x2x3 array, got %s", encoded)
+	}
+}
+
+func TestPlainValue(t *testing.T) {
+	value := plainValue([]interface{}{
+		pgtype.Range[any]{Lower: 1, Upper: 5, LowerType: pgtype.Inclusive, UpperType: pgtype.Exclusive, Valid: true},
+		pgtype.Range[any]{Upper: 5, LowerType: pgtype.Unbounded, UpperType: pgtype.Inclusive, Valid: true},
+		pgtype.Range[any]{LowerType: pgtype.Empty, UpperType: pgtype.Empty, Valid: true},
+	})
+	encoded, _ := json.Marshal(value)
+	expected := `[{"bounds":"[)","lower":1,"upper":5},{"bounds":"(]","lower":null,"upper":5},{"empty":true}]`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+}
+
+func TestQueryStructuredValues(t *testing.T) {
+	ctx := context.Background()
+	if _, err := testServer.pool.Exec(ctx, "CREATE TYPE value_test_point AS (x int, label text)"); err != nil {
+		t.Fatal(err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TYPE value_test_point")
+
+	args := QueryArgs{Query: `SELECT ARRAY[[1,2],[3,4]] AS grid, int4range(1, 5) AS span,
+		ROW(1, 'a')::value_test_point AS point, ARRAY[ROW(2, 'b')::value_test_point] AS points`}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	encoded, _ := json.Marshal(data.([]map[string]interface{})[0])
+	expected := `{"grid":[[1,2],[3,4]],"point":{"label":"a","x":1},"points":[{"label":"b","x":2}],"span":{"bounds":"[)","lower":1,"upper":5}}`
+	if string(encoded) != expected {
+		t.Errorf("Expected %s, got %s", expected, encoded)
+	}
+}
+
+func TestDecodeValueFallback(t *testing.T) {
+	typeMap := pgtype.NewMap()
+	if value := decodeValue(typeMap, 999999, pgtype.TextFormatCode, []byte("open")); value != "open" {
+		t.Errorf("Expected the text of an unknown type, got %#v", value)
+	}
+
+	// ROW(1, 'open'::some_enum) in binary: field count, then oid, length and
+	// data of each field
+	record := []byte{0, 0, 0, 2, 0, 0, 0, 23, 0, 0, 0, 4, 0, 0, 0, 1, 0, 0x0f, 0x42, 0x3f, 0, 0, 0, 4, 'o', 'p', 'e', 'n'}
+	encoded, _ := json.Marshal(decodeValue(typeMap, pgtype.RecordOID, pgtype.BinaryFormatCode, record))
+	if string(encoded) != `[1,"open"]` {
+		t.Errorf("Expected the record's fields, got %s", encoded)
+	}
+
+	if value, ok := decodeValue(typeMap, 999999, pgtype.BinaryFormatCode, []byte{0xff, 0xfe}).([]byte); !ok || len(value) != 2 {
+		t.Errorf("Expected the bytes of a binary value, got %#v", value)
+	}
+}
+
+func TestQueryUnknownTypes(t *testing.T) {
+	ctx := context.Background()
+	if _, err := testServer.pool.Exec(ctx, "CREATE TYPE value_test_mood AS ENUM ('happy', 'sad')"); err != nil {
+		t.Fatal(err)
+	}
+	defer testServer.pool.Exec(ctx, "DROP TYPE value_test_mood")
+
+	args := QueryArgs{Query: `SELECT 'happy'::value_test_mood AS mood, ROW(1, 'sad'::value_test_mood) AS pair`}
+	result, data, err := testServer.ExecuteQuery(ctx, createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("ExecuteQuery failed: %v %v", err, result)
+	}
+	encoded, _ := json.Marshal(data.([]map[string]interface{})[0])
+	if string(encoded) != `{"mood":"happy","pair":[1,"sad"]}` {
+		t.Errorf("Expected the enum labels, got %s", encoded)
+	}
+}
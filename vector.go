@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// pgvector defaults for indexes built without options and for the search
+// settings of a session that never set them.
+const (
+	defaultIVFFlatLists      = 100
+	defaultHNSWM             = 16
+	defaultHNSWEfConstruct   = 64
+	defaultHNSWEfSearch      = 40
+	defaultVectorSearchLimit = 10
+)
+
+type VectorIndexInfoArgs struct {
+	TableName        string `json:"table_name,omitempty" jsonschema:"Only report the vector indexes of this table, optionally schema-qualified (default: every table)"`
+	Schema           string `json:"schema,omitempty" jsonschema:"Schema name (default: public)"`
+	SearchLimit      int    `json:"search_limit,omitempty" jsonschema:"How many nearest neighbours searches ask for (their LIMIT), which ef_search has to cover (default: 10)"`
+	ApplyRecommended bool   `json:"apply_recommended,omitempty" jsonschema:"Set the recommended ivfflat.probes and hnsw.ef_search for later query calls in this session, as set_session_parameter would (default: false)"`
+}
+
+// parseReloptions reads an index's reloptions (lists=100, m=16, ...) into a map.
+func parseReloptions(options []string) map[string]string {
+	parsed := make(map[string]string)
+	for _, option := range options {
+		if name, value, ok := strings.Cut(option, "="); ok {
+			parsed[name] = value
+		}
+	}
+	return parsed
+}
+
+func reloptionInt(options map[string]string, name string, defaultValue int) int {
+	if value, err := strconv.Atoi(options[name]); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// distanceOperator is the operator a search has to ORDER BY for the index of
+// an operator class to be used.
+func distanceOperator(opclass string) string {
+	switch {
+	case strings.Contains(opclass, "cosine"):
+		return "<=>"
+	case strings.Contains(opclass, "_ip_"):
+		return "<#>"
+	case strings.Contains(opclass, "l1"):
+		return "<+>"
+	case strings.Contains(opclass, "hamming"):
+		return "<~>"
+	case strings.Contains(opclass, "jaccard"):
+		return "<%>"
+	}
+	return "<->"
+}
+
+// recommendedLists is pgvector's advice for ivfflat: rows / 1000 up to a
+// million rows, sqrt(rows) beyond.
+func recommendedLists(rows int64) int {
+	if rows > 1000000 {
+		return int(math.Sqrt(float64(rows)))
+	}
+	return max(1, int(rows/1000))
+}
+
+// recommendedProbes starts at sqrt(lists), pgvector's suggested balance of
+// recall and speed. probes = lists is an exact search.
+func recommendedProbes(lists int) int {
+	return max(1, int(math.Ceil(math.Sqrt(float64(lists)))))
+}
+
+// recommendedEfSearch covers the search's LIMIT with room to spare, HNSW
+// returning at most ef_search rows.
+func recommendedEfSearch(limit int) int {
+	return max(defaultHNSWEfSearch, 2*limit)
+}
+
+func (s *serverState) VectorIndexInfo(ctx context.Context, req *mcp.CallToolRequest, args VectorIndexInfoArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	var version *string
+	err := s.pool.QueryRow(ctx, "SELECT (SELECT extversion FROM pg_extension WHERE extname = 'vector')").Scan(&version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up the vector extension: %v", err)
+	}
+	if version == nil {
+		return returnJSONResult(map[string]interface{}{
+			"installed": false,
+			"message":   "The pgvector extension is not installed in this database (CREATE EXTENSION vector)",
+		})
+	}
+
+	searchLimit := args.SearchLimit
+	if searchLimit <= 0 {
+		searchLimit = defaultVectorSearchLimit
+	}
+
+	filter := ""
+	queryArgs := []interface{}{}
+	if args.TableName != "" {
+		schema, tableName, err := s.resolveTableName(ctx, args.Schema, args.TableName)
+		if err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if !s.relationAllowed(schema, tableName) {
+			return s.returnNotAccessible(qualifiedName(schema, tableName))
+		}
+		filter = "AND n.nspname = $1 AND t.relname = $2"
+		queryArgs = append(queryArgs, schema, tableName)
+	}
+
+	rows, err := s.pool.Query(ctx, fmt.Sprintf(`
+		SELECT n.nspname::text, t.relname::text, c.relname::text, am.amname::text, COALESCE(c.reloptions, '{}'),
+			pg_relation_size(c.oid), GREATEST(t.reltuples, 0)::bigint, COALESCE(a.attname::text, ''),
+			COALESCE(format_type(a.atttypid, a.atttypmod), ''), COALESCE(opc.opcname::text, ''), pg_get_indexdef(c.oid)
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = c.relam
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = i.indkey[0]
+		LEFT JOIN pg_opclass opc ON opc.oid = i.indclass[0]
+		WHERE am.amname IN ('ivfflat', 'hnsw') %s
+		ORDER BY 1, 2, 3
+	`, filter), queryArgs...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list vector indexes: %v", err)
+	}
+	defer rows.Close()
+
+	var indexes []map[string]interface{}
+	probes, efSearch := 0, 0
+	for rows.Next() {
+		var schema, table, name, method, column, columnType, opclass, definition string
+		var options []string
+		var size, tableRows int64
+		if err := rows.Scan(&schema, &table, &name, &method, &options, &size, &tableRows, &column, &columnType, &opclass, &definition); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		if !s.relationAllowed(schema, table) {
+			continue
+		}
+
+		parsed := parseReloptions(options)
+		index := map[string]interface{}{
+			"index":          qualifiedName(schema, name),
+			"table":          qualifiedName(schema, table),
+			"column":         column,
+			"column_type":    columnType,
+			"method":         method,
+			"operator_class": opclass,
+			"size_bytes":     size,
+			"table_rows":     tableRows,
+			"definition":     definition,
+			"search_pattern": fmt.Sprintf("ORDER BY %s %s $1 LIMIT %d", quoteIdentifier(column), distanceOperator(opclass), searchLimit),
+		}
+
+		var notes []string
+		switch method {
+		case "ivfflat":
+			lists := reloptionInt(parsed, "lists", defaultIVFFlatLists)
+			index["lists"] = lists
+			index["recommended_probes"] = recommendedProbes(lists)
+			index["recommended_lists"] = recommendedLists(tableRows)
+			probes = max(probes, recommendedProbes(lists))
+			if recommended := recommendedLists(tableRows); lists > 4*recommended || 4*lists < recommended {
+				notes = append(notes, fmt.Sprintf("Built with %d lists, %d suit %d rows. Rebuild the index once the table holds its usual data, ivfflat lists are fixed at build time", lists, recommended, tableRows))
+			}
+			notes = append(notes, "Raise ivfflat.probes for recall and lower it for speed, probes equal to lists is an exact search")
+		case "hnsw":
+			index["m"] = reloptionInt(parsed, "m", defaultHNSWM)
+			index["ef_construction"] = reloptionInt(parsed, "ef_construction", defaultHNSWEfConstruct)
+			index["recommended_ef_search"] = recommendedEfSearch(searchLimit)
+			efSearch = max(efSearch, recommendedEfSearch(searchLimit))
+			notes = append(notes, "hnsw.ef_search has to be at least the LIMIT of a search, higher values raise recall at the cost of speed")
+		}
+		index["notes"] = notes
+		indexes = append(indexes, index)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %v", err)
+	}
+
+	key := sessionKey(req)
+	var applied []string
+	if args.ApplyRecommended {
+		if probes > 0 {
+			s.parameters.set(key, "ivfflat.probes", strconv.Itoa(probes))
+			applied = append(applied, fmt.Sprintf("ivfflat.probes = %d", probes))
+		}
+		if efSearch > 0 {
+			s.parameters.set(key, "hnsw.ef_search", strconv.Itoa(efSearch))
+			applied = append(applied, fmt.Sprintf("hnsw.ef_search = %d", efSearch))
+		}
+	}
+
+	// what later query calls of this session run with
+	session := s.parameters.get(key)
+	settings := map[string]interface{}{
+		"ivfflat.probes": "1 (server default)",
+		"hnsw.ef_search": fmt.Sprintf("%d (server default)", defaultHNSWEfSearch),
+	}
+	for _, name := range []string{"ivfflat.probes", "hnsw.ef_search"} {
+		var value *string
+		if err := s.pool.QueryRow(ctx, "SELECT current_setting($1, true)", name).Scan(&value); err == nil && value != nil && *value != "" {
+			settings[name] = *value + " (server default)"
+		}
+		if value, ok := session[name]; ok {
+			settings[name] = value + " (set for this session)"
+		}
+	}
+
+	response := map[string]interface{}{
+		"installed":        true,
+		"version":          *version,
+		"indexes":          indexes,
+		"session_settings": settings,
+	}
+	if len(applied) > 0 {
+		response["applied"] = applied
+	}
+	return returnJSONResult(response)
+}
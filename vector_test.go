@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVectorIndexRecommendations(t *testing.T) {
+	options := parseReloptions([]string{"m=32", "ef_construction=128"})
+	if reloptionInt(options, "m", defaultHNSWM) != 32 || reloptionInt(options, "lists", defaultIVFFlatLists) != defaultIVFFlatLists {
+		t.Errorf("Unexpected reloptions %v", options)
+	}
+
+	for opclass, expected := range map[string]string{
+		"vector_l2_ops":        "<->",
+		"vector_cosine_ops":    "<=>",
+		"halfvec_ip_ops":       "<#>",
+		"bit_hamming_ops":      "<~>",
+		"sparsevec_l1_ops":     "<+>",
+		"vector_something_ops": "<->",
+	} {
+		if operator := distanceOperator(opclass); operator != expected {
+			t.Errorf("distanceOperator(%q) = %q, expected %q", opclass, operator, expected)
+		}
+	}
+
+	if lists := recommendedLists(500000); lists != 500 {
+		t.Errorf("Expected 500 lists for 500k rows, got %d", lists)
+	}
+	if lists := recommendedLists(4000000); lists != 2000 {
+		t.Errorf("Expected 2000 lists for 4M rows, got %d", lists)
+	}
+	if probes := recommendedProbes(100); probes != 10 {
+		t.Errorf("Expected 10 probes for 100 lists, got %d", probes)
+	}
+	if efSearch := recommendedEfSearch(100); efSearch != 200 {
+		t.Errorf("Expected ef_search to cover the limit, got %d", efSearch)
+	}
+	if !sessionParameterNames["hnsw.ef_search"] || !sessionParameterNames["ivfflat.probes"] {
+		t.Error("Expected the pgvector search settings to be settable per session")
+	}
+}
+
+func TestVectorIndexInfo(t *testing.T) {
+	args := VectorIndexInfoArgs{}
+	result, data, err := testServer.VectorIndexInfo(context.Background(), createMockRequest(args), args)
+	if err != nil || result.IsError {
+		t.Fatalf("VectorIndexInfo failed: %v %v", err, result)
+	}
+
+	// the test database has no pgvector
+	if info := data.(map[string]interface{}); info["installed"] != false {
+		t.Errorf("Expected pgvector to be reported missing, got %v", info)
+	}
+}
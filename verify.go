@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// optionalExtensions are not needed by any tool but widen what can be
+// diagnosed, so missing ones are reported as warnings.
+var optionalExtensions = map[string]string{
+	"pg_stat_statements": "per-statement execution statistics",
+	"pgstattuple":        "exact table and index bloat measurements",
+	"pg_buffercache":     "shared buffer cache contents",
+}
+
+type VerifyInstallationArgs struct{}
+
+type verifyCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// VerifyInstallation runs a trimmed version of the integration tests against
+// the connected server: connectivity, the privileges each tool's catalog
+// queries need, extensions and read-only enforcement.
+func (s *serverState) VerifyInstallation(ctx context.Context, req *mcp.CallToolRequest, args VerifyInstallationArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+
+	var checks []verifyCheck
+	add := func(name, status, detail string) {
+		checks = append(checks, verifyCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	var version, user, database string
+	err := s.pool.QueryRow(ctx, "SELECT version(), current_user::text, current_database()::text").Scan(&version, &user, &database)
+	if err != nil {
+		add("connectivity", "fail", err.Error())
+		return verifyReport(checks)
+	}
+	add("connectivity", "pass", fmt.Sprintf("Connected to %s as %s: %s", database, user, version))
+
+	if s.config.Role != "" {
+		if user == s.config.Role {
+			add("role", "pass", fmt.Sprintf("Running as %s", s.config.Role))
+		} else {
+			add("role", "fail", fmt.Sprintf("Expected to run as %s, connections run as %s", s.config.Role, user))
+		}
+	}
+
+	failures, err := s.catalogPrivilegeFailures(ctx)
+	if err != nil {
+		add("catalog_queries", "fail", err.Error())
+	} else {
+		failed := make(map[string][]string)
+		var tools []string
+		for _, failure := range failures {
+			if failed[failure.Tool] == nil {
+				tools = append(tools, failure.Tool)
+			}
+			failed[failure.Tool] = append(failed[failure.Tool], failure.Error)
+		}
+		for _, tool := range tools {
+			status := "fail"
+			// get_memory_usage falls back to pg_stat_activity without pg_ls_tmpdir
+			if tool == "get_memory_usage" {
+				status = "warn"
+			}
+			add("catalog_queries: "+tool, status, strings.Join(failed[tool], "; "))
+		}
+		if len(failures) == 0 {
+			add("catalog_queries", "pass", "Every tool's catalog queries run with the current privileges")
+		}
+	}
+
+	checks = append(checks, s.extensionChecks(ctx)...)
+
+	// go through the query tool itself, so the check covers its transaction
+	// setup. lo_create classifies as a read but writes, only the read-only
+	// transaction stops it
+	queryArgs := QueryArgs{Query: "SELECT lo_create(0)"}
+	result, _, err := s.ExecuteQuery(ctx, req, queryArgs)
+	switch {
+	case err != nil:
+		add("read_only_query", "fail", err.Error())
+	case result.IsError:
+		add("read_only_query", "pass", "The query tool rejected a write")
+	default:
+		add("read_only_query", "fail", "The query tool executed a write, its transaction is not read-only")
+	}
+
+	if s.writesEnabled() {
+		add("writes", "warn", "Write tools are enabled (ALLOW_WRITES or DRY_RUN)")
+	} else {
+		add("writes", "pass", "Write tools are disabled")
+	}
+
+	return verifyReport(checks)
+}
+
+func verifyReport(checks []verifyCheck) (*mcp.CallToolResult, any, error) {
+	counts := map[string]int{"pass": 0, "warn": 0, "fail": 0}
+	for _, check := range checks {
+		counts[check.Status]++
+	}
+	return returnJSONResult(map[string]interface{}{
+		"passed":  counts["fail"] == 0,
+		"summary": counts,
+		"checks":  checks,
+	})
+}
+
+// catalogPrivilegeFailures executes the self-test queries without returning
+// rows. Unlike preparing them, executing checks the privileges on every
+// relation and function they use.
+func (s *serverState) catalogPrivilegeFailures(ctx context.Context) ([]selfTestFailure, error) {
+	versionNum, err := s.serverVersionNum(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read server version: %v", err)
+	}
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var failures []selfTestFailure
+	for _, query := range selfTestQueries(versionNum) {
+		if err := runWithoutRows(ctx, tx, query.Query); err != nil {
+			failures = append(failures, selfTestFailure{
+				Tool:  query.Tool,
+				Query: strings.Join(strings.Fields(query.Query), " "),
+				Error: err.Error(),
+			})
+		}
+	}
+	return failures, nil
+}
+
+// runWithoutRows executes a query under a savepoint with NULL for each of its
+// parameters and LIMIT 0, so nothing is read.
+func runWithoutRows(ctx context.Context, tx pgx.Tx, query string) error {
+	wrapped := fmt.Sprintf("SELECT * FROM (%s) AS q LIMIT 0", query)
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer savepoint.Rollback(ctx)
+
+	described, err := savepoint.Prepare(ctx, "", wrapped)
+	if err != nil {
+		return err
+	}
+	rows, err := savepoint.Query(ctx, wrapped, make([]any, len(described.ParamOIDs))...)
+	if err != nil {
+		return err
+	}
+	rows.Close()
+	return rows.Err()
+}
+
+// extensionChecks reports the optional extensions, which are installed in
+// the database or only available to install.
+func (s *serverState) extensionChecks(ctx context.Context) []verifyCheck {
+	rows, err := s.pool.Query(ctx, `
+		SELECT a.name::text, e.extversion
+		FROM pg_available_extensions a
+		LEFT JOIN pg_extension e ON e.extname = a.name
+	`)
+	if err != nil {
+		return []verifyCheck{{Name: "extensions", Status: "fail", Detail: err.Error()}}
+	}
+	defer rows.Close()
+
+	installed := make(map[string]string)
+	available := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var version *string
+		if err := rows.Scan(&name, &version); err != nil {
+			return []verifyCheck{{Name: "extensions", Status: "fail", Detail: err.Error()}}
+		}
+		available[name] = true
+		if version != nil {
+			installed[name] = *version
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return []verifyCheck{{Name: "extensions", Status: "fail", Detail: err.Error()}}
+	}
+
+	var checks []verifyCheck
+	for _, name := range sortedKeys(optionalExtensions) {
+		check := verifyCheck{Name: "extension: " + name}
+		switch {
+		case installed[name] != "":
+			check.Status = "pass"
+			check.Detail = fmt.Sprintf("Version %s is installed", installed[name])
+		case available[name]:
+			check.Status = "warn"
+			check.Detail = fmt.Sprintf("Available but not installed, CREATE EXTENSION %s enables %s", name, optionalExtensions[name])
+		default:
+			check.Status = "warn"
+			check.Detail = fmt.Sprintf("Not available on this server, %s cannot be inspected", optionalExtensions[name])
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestVerifyInstallation(t *testing.T) {
+	ctx := context.Background()
+
+	args := VerifyInstallationArgs{}
+	result, data, err := testServer.VerifyInstallation(ctx, createMockRequest(args), args)
+	if err != nil {
+		t.Fatalf("VerifyInstallation failed: %v", err)
+	}
+	if result == nil || result.IsError {
+		t.Fatalf("Expected successful result, got %v", result)
+	}
+
+	report := data.(map[string]interface{})
+	statuses := make(map[string]string)
+	for _, check := range report["checks"].([]verifyCheck) {
+		statuses[check.Name] = check.Status
+		if check.Status == "fail" {
+			t.Errorf("Expected %s to pass against the test server: %s", check.Name, check.Detail)
+		}
+	}
+	if report["passed"] != true {
+		t.Error("Expected the report to pass")
+	}
+	for _, name := range []string{"connectivity", "catalog_queries", "read_only_query", "extension: pg_stat_statements"} {
+		if _, ok := statuses[name]; !ok {
+			t.Errorf("Expected a %s check", name)
+		}
+	}
+}
+
+func TestRunWithoutRows(t *testing.T) {
+	ctx := context.Background()
+	tx, err := testServer.pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := runWithoutRows(ctx, tx, "SELECT id FROM users WHERE created_at >= $1"); err != nil {
+		t.Errorf("Expected a parameterized query to run without rows: %v", err)
+	}
+	if err := runWithoutRows(ctx, tx, "SELECT missing_column FROM users"); err == nil {
+		t.Error("Expected an invalid query to fail")
+	}
+	// the savepoint keeps the transaction usable after a failure
+	if err := runWithoutRows(ctx, tx, "SELECT 1"); err != nil {
+		t.Errorf("Expected the transaction to survive a failed check: %v", err)
+	}
+}
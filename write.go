@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ExecuteWriteArgs struct {
+	Statement string `json:"statement" jsonschema:"The INSERT, UPDATE, DELETE, DDL or maintenance statement to run"`
+	Confirm   bool   `json:"confirm" jsonschema:"Must be true: confirms the statement is meant to change the database"`
+	MaxRows   int64  `json:"max_rows" jsonschema:"The most rows the statement may affect. A statement affecting more is rolled back"`
+	Role      string `json:"role,omitempty" jsonschema:"Run the statement as this role (SET LOCAL ROLE)"`
+}
+
+// ExecuteWrite runs a write outside the query tool's policy, behind an
+// explicit confirmation and a bound on the rows it may affect, so a
+// misjudged WHERE clause is rolled back rather than committed.
+func (s *serverState) ExecuteWrite(ctx context.Context, req *mcp.CallToolRequest, args ExecuteWriteArgs) (*mcp.CallToolResult, any, error) {
+	if s.pool == nil {
+		return nil, nil, fmt.Errorf("database not connected")
+	}
+	if !s.writesEnabled() {
+		return s.returnWritesDisabled("execute_write")
+	}
+	if !args.Confirm {
+		return s.returnErrorResult("execute_write only runs with confirm set to true")
+	}
+	if args.MaxRows <= 0 {
+		return s.returnErrorResult("max_rows is required: the most rows the statement may affect")
+	}
+	category := statementCategory(args.Statement)
+	if !writeCategories[category] {
+		return s.returnErrorResult("execute_write runs dml, ddl and maintenance statements, use query for %s statements", category)
+	}
+
+	if s.config.RequireApproval {
+		if err := s.checkStatementAccess(ctx, s.pool, args.Statement); err != nil {
+			return s.returnErrorResult("%v", err)
+		}
+		if args.Role != "" && outsideTransaction(args.Statement) {
+			return s.returnErrorResult("role %s cannot be set for %s, which runs outside a transaction", args.Role, statementKeyword(args.Statement))
+		}
+		summary := fmt.Sprintf("Run %s statement affecting at most %d rows: %s", category, args.MaxRows, strings.Join(strings.Fields(args.Statement), " "))
+		change, err := s.queuePendingChange(ctx, req, &pendingChange{
+			Tool:      "execute_write",
+			Summary:   summary,
+			Statement: args.Statement,
+			MaxRows:   args.MaxRows,
+			Role:      args.Role,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return returnQueuedChange(change)
+	}
+
+	return s.executeWrite(ctx, req, QueryArgs{Query: args.Statement, Role: args.Role}, category, "", args.MaxRows)
+}
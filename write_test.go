@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExecuteWrite(t *testing.T) {
+	ctx := context.Background()
+	savedConfig := testServer.config
+	defer func() { testServer.config = savedConfig }()
+	testServer.config.AllowWrites = true
+	testServer.config.DryRun = true
+
+	run := func(args ExecuteWriteArgs) (bool, any) {
+		t.Helper()
+		result, data, err := testServer.ExecuteWrite(ctx, createMockRequest(args), args)
+		if err != nil {
+			t.Fatalf("ExecuteWrite failed: %v", err)
+		}
+		return result.IsError, data
+	}
+
+	if failed, _ := run(ExecuteWriteArgs{Statement: "UPDATE users SET bio = bio", MaxRows: 100}); !failed {
+		t.Error("Expected a write without confirm to be refused")
+	}
+	if failed, _ := run(ExecuteWriteArgs{Statement: "SELECT 1", Confirm: true, MaxRows: 1}); !failed {
+		t.Error("Expected a read to be refused")
+	}
+	if failed, _ := run(ExecuteWriteArgs{Statement: "UPDATE users SET bio = bio", Confirm: true, MaxRows: 1}); !failed {
+		t.Error("Expected an update of every user to exceed max_rows")
+	}
+	failed, data := run(ExecuteWriteArgs{Statement: "UPDATE users SET bio = bio WHERE id = 1 RETURNING id", Confirm: true, MaxRows: 1})
+	if failed {
+		t.Fatal("Expected the update of one user to run")
+	}
+	if response := data.(map[string]interface{}); response["rows_affected"] != int64(1) || response["max_rows"] != int64(1) {
+		t.Errorf("Unexpected response %v", response)
+	}
+}